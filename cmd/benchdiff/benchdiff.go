@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tokenized/smart-contract/internal/benchmark"
+)
+
+// Benchmark Diff
+//
+// Compares the output of "go test -bench" against a stored baseline,
+// failing if any benchmark got slower by more than the threshold.
+func main() {
+	threshold := flag.Float64("threshold", 10,
+		"percent increase in ns/op that fails a benchmark")
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: %v [-threshold percent] <baseline> <current|->\n",
+			os.Args[0])
+		os.Exit(2)
+	}
+
+	baseline, err := parseFile(flag.Arg(0))
+	if err != nil {
+		panic(err)
+	}
+
+	current, err := parseFile(flag.Arg(1))
+	if err != nil {
+		panic(err)
+	}
+
+	regressed := 0
+
+	for _, d := range benchmark.Compare(baseline, current, *threshold) {
+		switch {
+		case d.Old == nil:
+			fmt.Printf("NEW  %v %.0f ns/op\n", d.Name, d.New.NsPerOp)
+		case d.New == nil:
+			fmt.Printf("GONE %v\n", d.Name)
+		case d.Regressed:
+			regressed++
+			fmt.Printf("FAIL %v %.0f -> %.0f ns/op (%+.1f%%)\n",
+				d.Name, d.Old.NsPerOp, d.New.NsPerOp, d.Delta)
+		default:
+			fmt.Printf("ok   %v %.0f -> %.0f ns/op (%+.1f%%)\n",
+				d.Name, d.Old.NsPerOp, d.New.NsPerOp, d.Delta)
+		}
+	}
+
+	fmt.Printf("%v regressed more than %v%%\n", regressed, *threshold)
+
+	if regressed > 0 {
+		os.Exit(1)
+	}
+}
+
+// parseFile parses the benchmark results in a file, or stdin for "-".
+func parseFile(name string) (map[string]benchmark.Result, error) {
+	var r io.Reader = os.Stdin
+
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	return benchmark.Parse(r)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/internal/conformance"
+)
+
+// Protocol Conformance Runner
+//
+// Runs each of the scenarios in a directory through the smart contract,
+// reporting pass or fail for each.
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: %v <scenario dir>\n", os.Args[0])
+		os.Exit(2)
+	}
+
+	ctx, _ := logger.NewLoggerWithContext()
+
+	config, err := config.NewConfig()
+	if err != nil {
+		panic(err)
+	}
+
+	scenarios, err := conformance.LoadScenarios(os.Args[1])
+	if err != nil {
+		panic(err)
+	}
+
+	runner := conformance.NewRunner(*config)
+
+	failed := 0
+
+	for _, result := range runner.RunAll(ctx, scenarios) {
+		if result.Passed {
+			fmt.Printf("PASS %v\n", result.Name)
+			continue
+		}
+
+		failed++
+		fmt.Printf("FAIL %v : %v\n", result.Name, result.Reason)
+	}
+
+	fmt.Printf("%v passed, %v failed\n", len(scenarios)-failed, failed)
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/rpcnode"
+	"github.com/tokenized/smart-contract/internal/app/wallet"
+	"github.com/tokenized/smart-contract/pkg/spvnode"
+	"github.com/tokenized/smart-contract/pkg/storage"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+// daemonConfig holds the settings of the daemon, other than the settings
+// of the smart contract held by config.Config.
+type daemonConfig struct {
+	SPV             spvnode.Config
+	SPVStorage      storage.Config
+	ContractStorage storage.Config
+	RPC             rpcnode.Config
+
+	// PrivateKey is the WIF of the key of the contract.
+	PrivateKey string
+
+	// Quorum is the Quorum of a multisig contract that has its responses
+	// signed offline, and is nil for any other contract.
+	Quorum *wallet.Quorum
+
+	// TxFilter holds the rule of the relevant TX's, or is nil if only the
+	// TX's of the contract are relevant.
+	TxFilter spvnode.TxFilter
+
+	// FetchInputValues is true if the fees of unconfirmed TX's are found
+	// from the values of their inputs.
+	FetchInputValues bool
+
+	// CacheAddress is the Redis server invalidating the cache of the
+	// contract storage, which is not cached if it is empty.
+	CacheAddress string
+	CacheChannel string
+
+	VoteWebhooks      []string
+	OperationWebhooks []string
+
+	// The APIs are served on each of the addresses that is set.
+	QueryAddress string
+	QueryToken   string
+	QuerySecret  []byte
+	AdminAddress string
+	AdminToken   string
+	SyncAddress  string
+
+	// SyncSource is the node changes are fetched from on startup.
+	SyncSource string
+
+	// IntegrityRepair is true if the node starts even though an integrity
+	// check failed.
+	IntegrityRepair bool
+}
+
+// newDaemonConfig returns a new daemonConfig populated from environment
+// variables.
+func newDaemonConfig() (*daemonConfig, error) {
+	c := daemonConfig{
+		SPVStorage:      newStorageConfig("NODE_STORAGE"),
+		ContractStorage: newStorageConfig("CONTRACT_STORAGE"),
+		RPC: rpcnode.NewConfig(os.Getenv("RPC_HOST"),
+			os.Getenv("RPC_USERNAME"),
+			os.Getenv("RPC_PASSWORD")),
+		PrivateKey:        os.Getenv("PRIV_KEY"),
+		FetchInputValues:  parseBool("NODE_FETCH_INPUT_VALUES"),
+		CacheAddress:      os.Getenv("CACHE_REDIS_ADDRESS"),
+		CacheChannel:      os.Getenv("CACHE_REDIS_CHANNEL"),
+		VoteWebhooks:      parseList("VOTE_WEBHOOKS"),
+		OperationWebhooks: parseList("OPERATION_WEBHOOKS"),
+		QueryAddress:      os.Getenv("QUERY_ADDRESS"),
+		QueryToken:        os.Getenv("QUERY_TOKEN"),
+		QuerySecret:       []byte(os.Getenv("QUERY_SECRET")),
+		AdminAddress:      os.Getenv("ADMIN_ADDRESS"),
+		AdminToken:        os.Getenv("ADMIN_TOKEN"),
+		SyncAddress:       os.Getenv("SYNC_ADDRESS"),
+		SyncSource:        os.Getenv("SYNC_SOURCE"),
+		IntegrityRepair:   parseBool("INTEGRITY_REPAIR"),
+	}
+
+	if c.CacheChannel == "" {
+		c.CacheChannel = "smartcontract/invalidate"
+	}
+
+	spvConfig, err := newSPVConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	c.SPV = *spvConfig
+
+	if parseBool("OFFLINE_SIGNING") {
+		redeemScript, err := hex.DecodeString(os.Getenv("CONTRACT_REDEEM_SCRIPT"))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CONTRACT_REDEEM_SCRIPT : %v", err)
+		}
+
+		if c.Quorum, err = wallet.NewOfflineQuorum(redeemScript); err != nil {
+			return nil, fmt.Errorf("Invalid CONTRACT_REDEEM_SCRIPT : %v", err)
+		}
+	}
+
+	if rule := os.Getenv("TX_FILTER"); rule != "" {
+		m, err := spvnode.CompileMatcher(rule)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid TX_FILTER : %v", err)
+		}
+
+		c.TxFilter = m
+	}
+
+	return &c, nil
+}
+
+// newSPVConfig returns the spvnode.Config of the trusted peer node,
+// populated from environment variables.
+func newSPVConfig() (*spvnode.Config, error) {
+	c := spvnode.NewConfig(os.Getenv("NODE_ADDRESS"),
+		os.Getenv("NODE_USER_AGENT"))
+
+	c.FailoverAddresses = parseList("NODE_FAILOVER_ADDRESSES")
+	c.Seeds = parseList("NODE_SEEDS")
+	c.PinnedPeers = parseList("NODE_PINNED_PEERS")
+
+	c.HeadersFirst = parseBool("NODE_HEADERS_FIRST")
+	c.CompactBlocks = parseBool("NODE_COMPACT_BLOCKS")
+	c.PersistMempool = parseBool("NODE_PERSIST_MEMPOOL")
+	c.DisableRelay = parseBool("NODE_DISABLE_RELAY")
+	c.KeepRelevantTxs = parseBool("NODE_KEEP_RELEVANT_TXS")
+
+	c.Proxy = os.Getenv("NODE_PROXY")
+	c.ProxyUsername = os.Getenv("NODE_PROXY_USERNAME")
+	c.ProxyPassword = os.Getenv("NODE_PROXY_PASSWORD")
+
+	c.Listen = os.Getenv("NODE_LISTEN")
+
+	var err error
+
+	if v := os.Getenv("NODE_CHECKPOINTS"); v != "" {
+		if c.Checkpoints, err = spvnode.ParseCheckpoints(v); err != nil {
+			return nil, fmt.Errorf("Invalid NODE_CHECKPOINTS : %v", err)
+		}
+	}
+
+	if c.MempoolEviction, err = spvnode.ParseEviction(os.Getenv("NODE_MEMPOOL_EVICTION")); err != nil {
+		return nil, fmt.Errorf("Invalid NODE_MEMPOOL_EVICTION : %v", err)
+	}
+
+	if c.Network, err = spvnode.ParseNetwork(os.Getenv("NODE_NETWORK")); err != nil {
+		return nil, fmt.Errorf("Invalid NODE_NETWORK : %v", err)
+	}
+
+	counts := []struct {
+		key   string
+		value *int
+	}{
+		{"NODE_MEMPOOL_MAX_TXS", &c.MempoolMaxTxs},
+		{"NODE_MEMPOOL_MAX_BYTES", &c.MempoolMaxBytes},
+		{"NODE_KEEP_BLOCKS", &c.KeepBlocks},
+		{"NODE_LISTENER_WORKERS", &c.ListenerWorkers},
+		{"NODE_MAX_TXS_IN_FLIGHT", &c.MaxTxsInFlight},
+		{"NODE_SEND_MESSAGES_PER_SECOND", &c.SendMessagesPerSecond},
+		{"NODE_SEND_BYTES_PER_SECOND", &c.SendBytesPerSecond},
+		{"NODE_MAX_MESSAGE_SIZE", &c.MaxMessageSize},
+		{"NODE_MAX_INV_PER_MSG", &c.MaxInvPerMsg},
+		{"NODE_MAX_HEADERS_PER_MSG", &c.MaxHeadersPerMsg},
+		{"NODE_MAX_ADDRS_PER_MSG", &c.MaxAddrsPerMsg},
+		{"NODE_REBROADCAST_MAX_ATTEMPTS", &c.RebroadcastMaxAttempts},
+		{"NODE_PEERS", &c.Peers},
+		{"NODE_MAX_PEERS_PER_GROUP", &c.MaxPeersPerGroup},
+		{"NODE_PEER_ROTATION_COUNT", &c.PeerRotationCount},
+		{"NODE_MAX_INBOUND", &c.MaxInbound},
+		{"NODE_MAX_INBOUND_MESSAGE_SIZE", &c.MaxInboundMessageSize},
+	}
+
+	for _, count := range counts {
+		if err := parseInt(count.key, count.value); err != nil {
+			return nil, err
+		}
+	}
+
+	durations := []struct {
+		key   string
+		value *time.Duration
+	}{
+		{"NODE_MEMPOOL_EXPIRY", &c.MempoolExpiry},
+		{"NODE_REBROADCAST_INTERVAL", &c.RebroadcastInterval},
+		{"NODE_PEER_ROTATION_INTERVAL", &c.PeerRotationInterval},
+		{"NODE_HANDSHAKE_TIMEOUT", &c.HandshakeTimeout},
+	}
+
+	for _, d := range durations {
+		if err := parseMilliseconds(d.key, d.value); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Services, err = parseServices("NODE_SERVICES", c.Services); err != nil {
+		return nil, err
+	}
+
+	if c.RequiredServices, err = parseServices("NODE_REQUIRED_SERVICES", c.RequiredServices); err != nil {
+		return nil, err
+	}
+
+	if v := os.Getenv("NODE_FEE_FILTER"); v != "" {
+		if c.FeeFilter, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("Invalid NODE_FEE_FILTER : %v", err)
+		}
+	}
+
+	if v := os.Getenv("NODE_MIN_PEER_VERSION"); v != "" {
+		version, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid NODE_MIN_PEER_VERSION : %v", err)
+		}
+
+		c.MinPeerVersion = uint32(version)
+	}
+
+	if v := os.Getenv("NODE_START_HEIGHT"); v != "" {
+		height, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid NODE_START_HEIGHT : %v", err)
+		}
+
+		c.StartHeight = int32(height)
+	}
+
+	return &c, nil
+}
+
+// newStorageConfig returns the storage.Config held by the environment
+// variables with the prefix.
+func newStorageConfig(prefix string) storage.Config {
+	return storage.NewConfig(os.Getenv(prefix+"_REGION"),
+		os.Getenv(prefix+"_ACCESS_KEY"),
+		os.Getenv(prefix+"_SECRET"),
+		os.Getenv(prefix+"_BUCKET"),
+		os.Getenv(prefix+"_ROOT"))
+}
+
+// newStorage returns the Storage of the config, which is on the local
+// filesystem if the bucket is "standalone".
+func newStorage(config storage.Config) storage.Storage {
+	if strings.ToLower(config.Bucket) == "standalone" {
+		return storage.NewFilesystemStorage(config)
+	}
+
+	return storage.NewS3Storage(config)
+}
+
+// parseBool returns true if an environment variable is "true".
+func parseBool(key string) bool {
+	return strings.ToLower(os.Getenv(key)) == "true"
+}
+
+// parseList returns the non-empty items of the comma separated list held
+// by an environment variable.
+func parseList(key string) []string {
+	items := []string{}
+
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// parseInt sets the value to the integer held by an environment variable,
+// leaving it unchanged if the variable is unset.
+func parseInt(key string, value *int) error {
+	v := os.Getenv(key)
+	if v == "" {
+		return nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("Invalid %v : %v", key, err)
+	}
+
+	*value = i
+
+	return nil
+}
+
+// parseMilliseconds sets the value to the duration in milliseconds held by
+// an environment variable, leaving it unchanged if the variable is unset.
+func parseMilliseconds(key string, value *time.Duration) error {
+	v := os.Getenv(key)
+	if v == "" {
+		return nil
+	}
+
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid %v : %v", key, err)
+	}
+
+	*value = time.Duration(ms) * time.Millisecond
+
+	return nil
+}
+
+// parseServices returns the service flags held by an environment variable,
+// in decimal or with a 0x prefix, or the default if the variable is unset.
+func parseServices(key string,
+	def wire.ServiceFlag) (wire.ServiceFlag, error) {
+
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+
+	services, err := strconv.ParseUint(v, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid %v : %v", key, err)
+	}
+
+	return wire.ServiceFlag(services), nil
+}
@@ -4,15 +4,22 @@ import (
 	"context"
 	"errors"
 
+	"github.com/tokenized/smart-contract/internal/activation"
 	"github.com/tokenized/smart-contract/internal/app/config"
 	"github.com/tokenized/smart-contract/internal/app/inspector"
 	"github.com/tokenized/smart-contract/internal/app/logger"
 	"github.com/tokenized/smart-contract/internal/app/network"
 	"github.com/tokenized/smart-contract/internal/broadcaster"
+	"github.com/tokenized/smart-contract/internal/feebump"
+	"github.com/tokenized/smart-contract/internal/latency"
 	"github.com/tokenized/smart-contract/internal/request"
 	"github.com/tokenized/smart-contract/internal/response"
+	"github.com/tokenized/smart-contract/internal/spool"
 	"github.com/tokenized/smart-contract/internal/validator"
+	"github.com/tokenized/smart-contract/pkg/spvnode"
 	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 )
 
 // BlockHandler exists to handle the Block command.
@@ -24,6 +31,11 @@ type BlockHandler struct {
 	Validator   validator.ValidatorService
 	Request     request.RequestService
 	Response    response.ResponseService
+	Latency     latency.LatencyService
+	FeeBump     feebump.FeeBumpService
+	Activation  activation.ActivationService
+	Spool       spool.SpoolService
+	Requests    network.Listener
 }
 
 // NewBlockHandler returns a new BlockHandler with the given Config.
@@ -33,8 +45,13 @@ func NewBlockHandler(config config.Config,
 	broadcaster broadcaster.BroadcastService,
 	validator validator.ValidatorService,
 	request request.RequestService,
-	response response.ResponseService) TXHandler {
-	return TXHandler{
+	response response.ResponseService,
+	latency latency.LatencyService,
+	feeBump feebump.FeeBumpService,
+	activation activation.ActivationService,
+	spool spool.SpoolService,
+	requests network.Listener) BlockHandler {
+	return BlockHandler{
 		Config:      config,
 		Network:     network,
 		Inspector:   inspector,
@@ -42,6 +59,11 @@ func NewBlockHandler(config config.Config,
 		Validator:   validator,
 		Request:     request,
 		Response:    response,
+		Latency:     latency,
+		FeeBump:     feeBump,
+		Activation:  activation,
+		Spool:       spool,
+		Requests:    requests,
 	}
 }
 
@@ -58,25 +80,109 @@ func (h BlockHandler) Handle(ctx context.Context, m wire.Message) error {
 	return h.handle(ctx, tx)
 }
 
+// HandleReorg implements the Listener interface.
+//
+// The blocks of the new chain follow, and are handled as any other block,
+// so the confirmed requests of the new chain are handled then. Requests
+// that were confirmed in a disconnected block are logged, as their
+// responses may have to be checked.
+func (h BlockHandler) HandleReorg(ctx context.Context, r spvnode.Reorg) error {
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+	log.Warnf("Chain reorganized back to block %s at height %v",
+		r.AncestorHash, r.AncestorHeight)
+
+	for _, hash := range r.Disconnected {
+		log.Warnf("Block disconnected : %s", hash)
+	}
+
+	for _, hash := range r.Unconfirmed {
+		log.Warnf("Transaction unconfirmed : %s", hash)
+	}
+
+	return nil
+}
+
+// HandleExpired implements the Listener interface.
+//
+// Expired TX's are only passed to the TX Listener.
+func (h BlockHandler) HandleExpired(ctx context.Context,
+	hashes []chainhash.Hash) error {
+
+	return nil
+}
+
+// HandleDoubleSpend implements the Listener interface.
+//
+// Double spends are only passed to the TX Listener.
+func (h BlockHandler) HandleDoubleSpend(ctx context.Context,
+	d spvnode.DoubleSpend) error {
+
+	return nil
+}
+
 // handle processes the MsgBlock
 func (h BlockHandler) handle(ctx context.Context, b *wire.MsgBlock) error {
 	log := logger.NewLoggerFromContext(ctx).Sugar()
 	log.Infof("Received block : %s", b.BlockHash())
 
+	// move the chain point used to activate scheduled rules
+	if err := h.Activation.Connect(ctx, b); err != nil {
+		log.Error(err)
+	}
+
+	// replay requests received while storage was unavailable
+	if err := h.Spool.Recover(withSource(ctx, sourceSpool), h.Requests); err != nil {
+		log.Error(err)
+	}
+
+	// responses confirmed in this block
 	for _, tx := range b.Transactions {
+		if err := h.Latency.Confirmed(ctx, tx.TxHash().String()); err != nil {
+			log.Error(err)
+		}
 
-		// Inspector: Does this transaction concern the protocol?
-		itx, err := h.Inspector.MakeTransaction(tx)
-		if err != nil || itx == nil {
-			// log.Error(err)
-			return nil
+		if err := h.FeeBump.Confirmed(ctx, tx.TxHash().String()); err != nil {
+			log.Error(err)
 		}
+	}
 
-		// if _, err := h.Service.ProcessMessage(ctx, itx); err != nil {
-		// 	logger.Errorw("Failed to process message", zap.Error(err))
-		// 	return err
-		// }
+	// requests of contracts that wait for confirmation
+	for _, tx := range b.Transactions {
+		if err := h.Requests.Handle(withSource(ctx, sourceBlock), tx); err != nil {
+			log.Error(err)
+		}
 	}
 
 	return nil
 }
+
+// sourceKey is the Context key of where a TX being handled came from.
+type sourceKey struct{}
+
+const (
+	// sourceMempool is a TX seen before it is confirmed.
+	sourceMempool = iota
+
+	// sourceBlock is a TX confirmed in a block.
+	sourceBlock
+
+	// sourceSpool is a TX replayed from the spool.
+	sourceSpool
+)
+
+// withSource returns a copy of the Context, recording where the TX being
+// handled came from.
+func withSource(ctx context.Context, source int) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// sourceOf returns where the TX being handled came from, which is the
+// mempool unless the Context says otherwise.
+func sourceOf(ctx context.Context) int {
+	source, ok := ctx.Value(sourceKey{}).(int)
+	if !ok {
+		return sourceMempool
+	}
+
+	return source
+}
@@ -1,17 +1,31 @@
 package node
 
 import (
+	"context"
+	"errors"
 	"net"
+	"time"
 
+	"github.com/tokenized/smart-contract/internal/activation"
 	"github.com/tokenized/smart-contract/internal/app/config"
 	"github.com/tokenized/smart-contract/internal/app/inspector"
+	"github.com/tokenized/smart-contract/internal/app/logger"
 	"github.com/tokenized/smart-contract/internal/app/network"
 	"github.com/tokenized/smart-contract/internal/app/state"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
 	"github.com/tokenized/smart-contract/internal/app/wallet"
+	"github.com/tokenized/smart-contract/internal/audit"
 	"github.com/tokenized/smart-contract/internal/broadcaster"
+	"github.com/tokenized/smart-contract/internal/feature"
+	"github.com/tokenized/smart-contract/internal/feebump"
+	"github.com/tokenized/smart-contract/internal/latency"
+	"github.com/tokenized/smart-contract/internal/offline"
+	"github.com/tokenized/smart-contract/internal/receipt"
 	"github.com/tokenized/smart-contract/internal/request"
 	"github.com/tokenized/smart-contract/internal/response"
+	"github.com/tokenized/smart-contract/internal/spool"
 	"github.com/tokenized/smart-contract/internal/validator"
+	"github.com/tokenized/smart-contract/pkg/spvnode"
 	"github.com/tokenized/smart-contract/pkg/storage"
 	"github.com/tokenized/smart-contract/pkg/wire"
 )
@@ -22,14 +36,26 @@ const (
 	RegTestBch wire.BitcoinNet = 0xfabfb5da
 )
 
+// refundInterval is how often transfers awaiting the acceptance of their
+// receiver are checked for expiry.
+const refundInterval = time.Minute
+
+// errNothingExpired stops an update that has no expired transfers to
+// refund from being written.
+var errNothingExpired = errors.New("Nothing expired")
+
 type Node struct {
-	Config   config.Config
-	Network  network.NetworkInterface
-	State    state.StateInterface
-	Wallet   wallet.Wallet
-	conn     net.Conn
-	messages chan wire.Message
-	storage  storage.Storage
+	Config     config.Config
+	Network    network.NetworkInterface
+	State      state.StateInterface
+	Wallet     wallet.Wallet
+	Activation activation.ActivationService
+	Indexers   []state.Indexer
+	TxFilters  []spvnode.TxFilter
+	conn       net.Conn
+	messages   chan wire.Message
+	storage    storage.Storage
+	mapLock    mapLock
 }
 
 func NewNode(config config.Config,
@@ -40,23 +66,60 @@ func NewNode(config config.Config,
 	contractState := state.NewStateService(storage)
 
 	a := Node{
-		Config:   config,
-		Network:  network,
-		Wallet:   wallet,
-		messages: make(chan wire.Message),
-		storage:  storage,
-		State:    contractState,
+		Config:     config,
+		Network:    network,
+		Wallet:     wallet,
+		Activation: activation.NewActivationService(config.Activations, storage),
+		messages:   make(chan wire.Message),
+		storage:    storage,
+		State:      contractState,
+		mapLock:    newMapLock(),
 	}
 
 	return a
 }
 
+// AddTxFilter adds a filter of the TX's that are inspected. TX's that no
+// filter finds relevant are ignored. Filters must be added before the Node
+// is started.
+func (n *Node) AddTxFilter(f spvnode.TxFilter) {
+	n.TxFilters = append(n.TxFilters, f)
+}
+
+// RegisterIndexer adds an Indexer to be invoked after each mutation of
+// Contract state. Indexers must be registered before the Node is started.
+func (n *Node) RegisterIndexer(indexer state.Indexer) {
+	n.Indexers = append(n.Indexers, indexer)
+}
+
 func (n Node) Start() error {
-	inspector := inspector.NewInspectorService(n.Network)
+	inspector := inspector.NewInspectorService(n.Network, n.TxFilters...)
 	broadcaster := broadcaster.NewBroadcastService(n.Network)
 	validator := validator.NewValidatorService(n.Config, n.Wallet, n.State)
-	request := request.NewRequestService(n.Config, n.Wallet, n.State, inspector)
-	response := response.NewResponseService(n.Config, n.State)
+	audit := audit.NewAuditService(n.storage)
+	request := request.NewRequestService(n.Config, n.Wallet, n.State, inspector, audit)
+	response := response.NewResponseService(n.Config, n.State, n.Indexers...)
+	latency := latency.NewLatencyService(n.storage, n.Config.SLA)
+	offline := offline.NewOfflineService(n.storage, n.Network)
+	feeBump := feebump.NewFeeBumpService(n.Config.FeeBump, n.storage, n.Network, n.Wallet)
+	receipts := receipt.NewReceiptService(n.storage, n.Wallet)
+	features := feature.NewFeatureService(n.storage)
+
+	if err := n.Activation.Load(context.Background()); err != nil {
+		return err
+	}
+
+	var spoolStorage storage.Storage
+	if n.Config.Spool.Root != "" {
+		spoolStorage = storage.NewFilesystemStorage(
+			storage.NewConfig("", "", "", spool.SpoolPrefix, n.Config.Spool.Root))
+	}
+
+	spool := spool.NewSpoolService(n.Config.Spool, n.storage, spoolStorage)
+
+	if err := spool.Load(context.Background()); err != nil {
+		return err
+	}
 
 	txHandler := NewTXHandler(n.Config,
 		n.Network,
@@ -65,12 +128,97 @@ func (n Node) Start() error {
 		broadcaster,
 		validator,
 		request,
-		response)
+		response,
+		latency,
+		offline,
+		feeBump,
+		receipts,
+		spool,
+		features)
+
+	// requests and operator updates to a contract are serialized
+	txHandler.mapLock = n.mapLock
 
 	n.Network.RegisterTxListener(txHandler)
 
-	// blockHandler := contract.NewBlockHandler(n.Config, service)
-	// network.RegisterBlockListener(blockHandler)
+	blockHandler := NewBlockHandler(n.Config,
+		n.Network,
+		inspector,
+		broadcaster,
+		validator,
+		request,
+		response,
+		latency,
+		feeBump,
+		n.Activation,
+		spool,
+		txHandler)
+
+	n.Network.RegisterBlockListener(blockHandler)
+
+	// bump the fee of responses still unconfirmed when last stopped
+	if err := feeBump.Load(context.Background()); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go feeBump.Run(ctx)
+	go n.refundExpired(ctx)
 
 	return n.Network.Start()
 }
+
+// Update implements the state.Updater interface.
+//
+// The contract is locked as it is while a request to it is processed, and
+// the change is passed to the registered Indexers.
+func (n Node) Update(ctx context.Context,
+	contractID string,
+	action string,
+	f func(*contract.Contract) error) (*contract.Contract, error) {
+
+	mtx := n.mapLock.get(contractID)
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	response := response.NewResponseService(n.Config, n.State, n.Indexers...)
+
+	return response.Update(ctx, contractID, action, f)
+}
+
+// refundExpired returns the tokens of transfers that were not accepted in
+// time to their senders, until the context is done.
+func (n Node) refundExpired(ctx context.Context) {
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(refundInterval):
+		}
+
+		_, err := n.Update(ctx, n.Wallet.PublicAddress,
+			contract.ActionRefundExpired,
+			func(c *contract.Contract) error {
+				if len(c.RefundExpired(time.Now())) == 0 {
+					return errNothingExpired
+				}
+
+				return nil
+			})
+
+		if err != nil && err != errNothingExpired &&
+			err != state.ErrContractNotFound {
+
+			log.Error(err)
+		}
+	}
+}
+
+// Stop stops the Node, and Start returns once the network has stopped.
+func (n Node) Stop() {
+	n.Network.Stop()
+}
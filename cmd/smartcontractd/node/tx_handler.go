@@ -1,6 +1,7 @@
 package node
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"time"
@@ -9,12 +10,23 @@ import (
 	"github.com/tokenized/smart-contract/internal/app/inspector"
 	"github.com/tokenized/smart-contract/internal/app/logger"
 	"github.com/tokenized/smart-contract/internal/app/network"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
 	"github.com/tokenized/smart-contract/internal/app/wallet"
 	"github.com/tokenized/smart-contract/internal/broadcaster"
+	"github.com/tokenized/smart-contract/internal/feature"
+	"github.com/tokenized/smart-contract/internal/feebump"
+	"github.com/tokenized/smart-contract/internal/latency"
+	"github.com/tokenized/smart-contract/internal/offline"
+	"github.com/tokenized/smart-contract/internal/receipt"
 	"github.com/tokenized/smart-contract/internal/request"
 	"github.com/tokenized/smart-contract/internal/response"
+	"github.com/tokenized/smart-contract/internal/spool"
 	"github.com/tokenized/smart-contract/internal/validator"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+	"github.com/tokenized/smart-contract/pkg/spvnode"
 	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 )
 
 // TXHandler exists to handle the TX command.
@@ -27,9 +39,25 @@ type TXHandler struct {
 	Validator   validator.ValidatorService
 	Request     request.RequestService
 	Response    response.ResponseService
+	Latency     latency.LatencyService
+	Offline     offline.OfflineService
+	FeeBump     feebump.FeeBumpService
+	Receipts    receipt.ReceiptService
+	Spool       spool.SpoolService
+	Features    feature.FeatureService
 	mapLock     mapLock
 }
 
+// adminActions are the requests of the issuer or operator, which are
+// rejected rather than spooled while storage is unavailable.
+var adminActions = map[string]bool{
+	protocol.CodeContractOffer:     true,
+	protocol.CodeContractAmendment: true,
+	protocol.CodeAssetDefinition:   true,
+	protocol.CodeAssetModification: true,
+	protocol.CodeOrder:             true,
+}
+
 // NewTXHandler returns a new TXHandler with the given Config.
 func NewTXHandler(config config.Config,
 	network network.NetworkInterface,
@@ -38,7 +66,13 @@ func NewTXHandler(config config.Config,
 	broadcaster broadcaster.BroadcastService,
 	validator validator.ValidatorService,
 	request request.RequestService,
-	response response.ResponseService) TXHandler {
+	response response.ResponseService,
+	latency latency.LatencyService,
+	offline offline.OfflineService,
+	feeBump feebump.FeeBumpService,
+	receipts receipt.ReceiptService,
+	spool spool.SpoolService,
+	features feature.FeatureService) TXHandler {
 	return TXHandler{
 		Config:      config,
 		Network:     network,
@@ -48,6 +82,12 @@ func NewTXHandler(config config.Config,
 		Validator:   validator,
 		Request:     request,
 		Response:    response,
+		Latency:     latency,
+		Offline:     offline,
+		FeeBump:     feeBump,
+		Receipts:    receipts,
+		Spool:       spool,
+		Features:    features,
 		mapLock:     newMapLock(),
 	}
 }
@@ -65,6 +105,50 @@ func (h TXHandler) Handle(ctx context.Context, m wire.Message) error {
 	return h.handle(ctx, msg)
 }
 
+// HandleReorg implements the Listener interface.
+//
+// Reorgs are only passed to the block Listener.
+func (h TXHandler) HandleReorg(ctx context.Context, r spvnode.Reorg) error {
+	return nil
+}
+
+// HandleExpired implements the Listener interface.
+//
+// A request that expired from the mempool may never be confirmed, so it is
+// logged.
+func (h TXHandler) HandleExpired(ctx context.Context,
+	hashes []chainhash.Hash) error {
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	for _, hash := range hashes {
+		log.Debugf("Transaction expired unconfirmed : %s", hash)
+	}
+
+	return nil
+}
+
+// HandleDoubleSpend implements the Listener interface.
+//
+// A request whose inputs were double spent may never be confirmed, or may
+// be replaced by the conflicting TX, so the conflict is logged with the TX
+// that won, once it is confirmed.
+func (h TXHandler) HandleDoubleSpend(ctx context.Context,
+	d spvnode.DoubleSpend) error {
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	if d.Confirmed == nil {
+		log.Warnf("Double spend of %v detected : %v", d.OutPoint, d.Txs)
+		return nil
+	}
+
+	log.Warnf("Double spend of %v resolved : %s confirmed in block %s, conflicting %v",
+		d.OutPoint, d.Confirmed, d.Block, d.Txs)
+
+	return nil
+}
+
 // handle processes the MsgTx.
 //
 // There is no response for this handler.
@@ -88,10 +172,27 @@ func (h TXHandler) handle(ctx context.Context, tx *wire.MsgTx) error {
 		return nil
 	}
 
+	// Features: process the request when it is seen, or once it is
+	// confirmed, as the contract is set up to
+	if !h.due(ctx, itx) {
+		return nil
+	}
+
 	// we don't care about non-Tokenized tx's, so taking metrics here. The
 	// ts was taken at the beginning of the function.
 	defer logger.Elapsed(ctx, ts, "TXHandler.handle")
 
+	txID := tx.TxHash().String()
+
+	if err := h.Latency.Observed(ctx, txID, itx.MsgProto.Type()); err != nil {
+		log.Error(err)
+	}
+
+	// Spool: replay requests received while storage was unavailable
+	if err := h.Spool.Recover(withSource(ctx, sourceSpool), h); err != nil {
+		log.Error(err)
+	}
+
 	// Introduce Inputs and UTXOs in the Transaction
 	itx, err = h.Inspector.PromoteTransaction(itx)
 	if err != nil {
@@ -106,16 +207,44 @@ func (h TXHandler) handle(ctx context.Context, tx *wire.MsgTx) error {
 	mtx.Lock()
 	defer mtx.Unlock()
 
+	// Spool: storage is unavailable, keep the request for later
+	if h.Spool.Degraded() {
+		h.degrade(ctx, itx)
+		return nil
+	}
+
+	// Features: reject a payload that doesn't encode its message exactly
+	if h.enabled(ctx, itx, feature.FlagStrictProtocol) && !canonical(itx) {
+		rejectTx, err := h.Validator.Reject(ctx, itx,
+			protocol.RejectionCodeMalformed)
+		if err != nil {
+			log.Error(err)
+			return nil
+		}
+
+		log.Infof("Rejecting message : Payload is not canonical")
+
+		if err := h.announce(ctx, itx, rejectTx, -1); err != nil {
+			log.Error(err)
+		}
+
+		return nil
+	}
+
 	// Validator: Check this request, return the related Contract
 	rejectTx, contract, err := h.Validator.CheckAndFetch(ctx, itx)
 	if err != nil {
 		log.Error(err)
+		h.checkStorage(ctx, itx)
 		return nil
 	}
 
 	// Validator: Message is a reject
 	if rejectTx != nil {
-		_, _ = h.Broadcaster.Announce(ctx, rejectTx)
+		if err := h.announce(ctx, itx, rejectTx, -1); err != nil {
+			log.Error(err)
+		}
+
 		return nil
 	}
 	if contract == nil {
@@ -133,12 +262,17 @@ func (h TXHandler) handle(ctx context.Context, tx *wire.MsgTx) error {
 	err = h.Response.Process(ctx, resItx, contract)
 	if err != nil {
 		log.Error(err)
+		h.checkStorage(ctx, itx)
 		return nil
 	}
 
+	// Receipts: Issue a receipt for an accepted ballot
+	if err := h.issueReceipt(ctx, itx, contract); err != nil {
+		log.Error(err)
+	}
+
 	// Broadcaster: Broadcast response
-	_, err = h.Broadcaster.Announce(ctx, resItx.MsgTx)
-	if err != nil {
+	if err := h.announce(ctx, itx, resItx.MsgTx, resItx.ChangeIndex); err != nil {
 		log.Error(err)
 		return nil
 	}
@@ -147,3 +281,163 @@ func (h TXHandler) handle(ctx context.Context, tx *wire.MsgTx) error {
 	// messages back to the peer. Any messaging was handled by the Service.
 	return nil
 }
+
+// checkStorage handles a request that failed as if storage is unavailable,
+// if it is.
+func (h TXHandler) checkStorage(ctx context.Context,
+	itx *inspector.Transaction) {
+
+	if err := h.Spool.Probe(ctx); err != nil && h.Spool.Degraded() {
+		h.degrade(ctx, itx)
+	}
+}
+
+// degrade handles a request while storage is unavailable.
+//
+// Admin requests are rejected, so the issuer can send them again once the
+// contract is available. Other requests are spooled to be processed when
+// storage recovers.
+func (h TXHandler) degrade(ctx context.Context,
+	itx *inspector.Transaction) {
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	if adminActions[itx.MsgProto.Type()] {
+		rejectTx, err := h.Validator.Reject(ctx, itx,
+			protocol.RejectionCodeUnavailable)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+
+		log.Infof("Rejecting message : Storage unavailable")
+
+		if err := h.announce(ctx, itx, rejectTx, -1); err != nil {
+			log.Error(err)
+		}
+
+		return
+	}
+
+	if err := h.Spool.Spool(ctx, itx.MsgTx); err != nil {
+		log.Errorf("Failed to spool request : %v", err)
+		return
+	}
+
+	log.Infof("Spooled request until storage recovers")
+}
+
+// issueReceipt issues a receipt to the voter if the request is a ballot
+// cast.
+func (h TXHandler) issueReceipt(ctx context.Context,
+	itx *inspector.Transaction,
+	c *contract.Contract) error {
+
+	m, ok := itx.MsgProto.(*protocol.BallotCast)
+	if !ok {
+		return nil
+	}
+
+	vo, ok := c.Votes[string(m.VoteTxnID)]
+	if !ok {
+		return nil
+	}
+
+	ballot := vo.NewBallot(itx.InputAddrs[0], m, time.Now())
+
+	_, err := h.Receipts.Issue(ctx, *c, ballot, itx.MsgTx.TxHash().String())
+	return err
+}
+
+// announce broadcasts the response to a request, with the index of its
+// change output, or -1.
+//
+// If the contract signs offline, the unsigned response is exported to be
+// signed and broadcast later instead.
+func (h TXHandler) announce(ctx context.Context,
+	itx *inspector.Transaction,
+	tx *wire.MsgTx,
+	changeIndex int) error {
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	txID := itx.MsgTx.TxHash().String()
+	contractAddress := itx.Outputs[0].Address
+
+	if h.Wallet.IsOffline(contractAddress.EncodeAddress()) {
+		q, err := h.Wallet.GetQuorum(contractAddress.EncodeAddress())
+		if err != nil {
+			return err
+		}
+
+		_, err = h.Offline.Export(ctx, txID, tx, itx.UTXOs, q.RedeemScript)
+		return err
+	}
+
+	hash, err := h.Broadcaster.Announce(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if h.enabled(ctx, itx, feature.FlagFeeBump) {
+		if err := h.FeeBump.Track(ctx, txID, contractAddress, tx, itx.UTXOs,
+			changeIndex); err != nil {
+			log.Error(err)
+		}
+	}
+
+	return h.Latency.Broadcast(ctx, txID, hash.String())
+}
+
+// enabled returns true if the feature is enabled for the contract the
+// request was sent to.
+func (h TXHandler) enabled(ctx context.Context,
+	itx *inspector.Transaction,
+	flag string) bool {
+
+	return h.Features.Enabled(ctx, itx.Outputs[0].Address.EncodeAddress(), flag)
+}
+
+// due returns true if the request is to be processed now.
+//
+// With 0-conf processing a request is processed when it is seen, otherwise
+// when it is confirmed in a block. Requests replayed from the spool were
+// already due when they were spooled.
+func (h TXHandler) due(ctx context.Context, itx *inspector.Transaction) bool {
+	switch sourceOf(ctx) {
+	case sourceSpool:
+		return true
+	case sourceBlock:
+		return !h.enabled(ctx, itx, feature.FlagZeroConf)
+	default:
+		return h.enabled(ctx, itx, feature.FlagZeroConf)
+	}
+}
+
+// canonical returns true if the protocol OP_RETURN of the request is exactly
+// the encoding of the message it was decoded to, with no trailing or
+// malformed data.
+func canonical(itx *inspector.Transaction) bool {
+	m, ok := itx.MsgProto.(interface {
+		Bytes() ([]byte, error)
+	})
+	if !ok {
+		return false
+	}
+
+	b, err := m.Bytes()
+	if err != nil {
+		return false
+	}
+
+	for _, txOut := range itx.MsgTx.TxOut {
+		code, err := protocol.Code(txOut.PkScript)
+		if err != nil || code != itx.MsgProto.Type() {
+			continue
+		}
+
+		return bytes.Equal(txOut.PkScript, b)
+	}
+
+	return false
+}
@@ -1,18 +1,37 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
-	"strings"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/tokenized/smart-contract/cmd/smartcontractd/node"
+	"github.com/tokenized/smart-contract/internal/activation"
+	"github.com/tokenized/smart-contract/internal/admin"
 	"github.com/tokenized/smart-contract/internal/app/config"
 	"github.com/tokenized/smart-contract/internal/app/logger"
 	"github.com/tokenized/smart-contract/internal/app/network"
-	"github.com/tokenized/smart-contract/internal/app/rpcnode"
+	"github.com/tokenized/smart-contract/internal/app/state"
 	"github.com/tokenized/smart-contract/internal/app/wallet"
+	"github.com/tokenized/smart-contract/internal/archive"
+	"github.com/tokenized/smart-contract/internal/document"
+	"github.com/tokenized/smart-contract/internal/feature"
+	"github.com/tokenized/smart-contract/internal/integrity"
+	"github.com/tokenized/smart-contract/internal/offline"
+	"github.com/tokenized/smart-contract/internal/operation"
+	"github.com/tokenized/smart-contract/internal/query"
+	"github.com/tokenized/smart-contract/internal/receipt"
+	"github.com/tokenized/smart-contract/internal/statesync"
+	"github.com/tokenized/smart-contract/internal/vote"
 	"github.com/tokenized/smart-contract/pkg/spvnode"
 	"github.com/tokenized/smart-contract/pkg/storage"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
 )
 
 var (
@@ -25,71 +44,282 @@ var (
 //
 func main() {
 	// Logger
-	_, log := logger.NewLoggerWithContext()
+	ctx, log := logger.NewLoggerWithContext()
 
 	// Configuration
 	config, err := config.NewConfig()
 	if err != nil {
-		panic(err)
+		log.Fatalf("Invalid configuration : %v", err)
+	}
+
+	dc, err := newDaemonConfig()
+	if err != nil {
+		log.Fatalf("Invalid configuration : %v", err)
 	}
 
 	// Trusted Peer Node
-	spvStorageConfig := storage.NewConfig(os.Getenv("NODE_STORAGE_REGION"),
-		os.Getenv("NODE_STORAGE_ACCESS_KEY"),
-		os.Getenv("NODE_STORAGE_SECRET"),
-		os.Getenv("NODE_STORAGE_BUCKET"),
-		os.Getenv("NODE_STORAGE_ROOT"))
-
-	var spvStorage storage.Storage
-	if strings.ToLower(spvStorageConfig.Bucket) == "standalone" {
-		spvStorage = storage.NewFilesystemStorage(spvStorageConfig)
-	} else {
-		spvStorage = storage.NewS3Storage(spvStorageConfig)
+	spvStorage := newStorage(dc.SPVStorage)
+	spvNode := spvnode.NewNode(dc.SPV, spvStorage)
+
+	// Wallet
+	wallet, err := wallet.NewWallet(dc.PrivateKey)
+	if err != nil {
+		log.Fatalf("Invalid PRIV_KEY : %v", err)
 	}
 
-	spvConfig := spvnode.NewConfig(os.Getenv("NODE_ADDRESS"),
-		os.Getenv("NODE_USER_AGENT"))
+	// Multisig contract, with responses signed offline
+	if dc.Quorum != nil {
+		wallet.AddQuorum(dc.Quorum)
+	}
 
-	spvNode := spvnode.NewNode(spvConfig, spvStorage)
+	// Only TX's of the contract are downloaded from untrusted peers, unless
+	// a rule of the relevant TX's is set, which also limits the TX's that
+	// are inspected
+	if dc.TxFilter != nil {
+		spvNode.AddTxFilter(dc.TxFilter)
+	} else {
+		contractAddress, err := btcutil.DecodeAddress(wallet.PublicAddress,
+			&chaincfg.MainNetParams)
+		if err != nil {
+			panic(err)
+		}
 
-	// Network
-	rpcConfig := rpcnode.NewConfig(os.Getenv("RPC_HOST"),
-		os.Getenv("RPC_USERNAME"),
-		os.Getenv("RPC_PASSWORD"))
+		spvNode.AddTxFilter(spvnode.NewAddressFilter(contractAddress))
+	}
 
-	network, err := network.NewNetwork(rpcConfig, spvNode)
+	// Network
+	network, err := network.NewNetwork(dc.RPC, spvNode)
 	if err != nil {
 		panic(err)
 	}
 
-	// Wallet
-	wallet, err := wallet.NewWallet(os.Getenv("PRIV_KEY"))
-	if err != nil {
-		panic(err)
+	// Fees of unconfirmed TX's, from the values of their inputs
+	if dc.FetchInputValues {
+		network.TrustedNode.PeerNode.SetInputValuer(network)
 	}
 
 	// Contract Storage
-	contractStorageConfig := storage.NewConfig(os.Getenv("CONTRACT_STORAGE_REGION"),
-		os.Getenv("CONTRACT_STORAGE_ACCESS_KEY"),
-		os.Getenv("CONTRACT_STORAGE_SECRET"),
-		os.Getenv("CONTRACT_STORAGE_BUCKET"),
-		os.Getenv("CONTRACT_STORAGE_ROOT"))
-
-	var contractStorage storage.Storage
-	if strings.ToLower(contractStorageConfig.Bucket) == "standalone" {
-		contractStorage = storage.NewFilesystemStorage(contractStorageConfig)
-	} else {
-		contractStorage = storage.NewS3Storage(contractStorageConfig)
+	contractStorage := newStorage(dc.ContractStorage)
+
+	// Read-through cache of the contract storage, invalidated over Redis
+	// pub/sub when another process sharing the storage writes to it. Reads
+	// go straight to the storage while the subscription is down.
+	if dc.CacheAddress != "" {
+		cache := storage.NewCachedStorage(contractStorage,
+			storage.NewRedisInvalidator(dc.CacheAddress, dc.CacheChannel))
+
+		go func() {
+			for {
+				if err := cache.Listen(ctx); err != nil {
+					log.Errorf("Cache invalidation stopped : %v", err)
+				}
+
+				time.Sleep(5 * time.Second)
+			}
+		}()
+
+		contractStorage = cache
 	}
 
 	// Log startup sequence
 	log.Infof("Started %v with config %s", buildDetails(), *config)
 	log.Infof("Running contract %s", wallet.PublicAddress)
 
+	// Optional behaviors, enabled per contract
+	features := feature.NewFeatureService(contractStorage)
+
+	// Archive of completed votes
+	archive := archive.NewArchiveService(contractStorage, vote.NewVoteService())
+
+	// Notifications of vote activity, posted to each webhook from a queue,
+	// so a slow webhook doesn't hold up the contract
+	notifiers := []vote.Notifier{}
+	for _, url := range dc.VoteWebhooks {
+		q := vote.NewQueuedNotifier(vote.NewWebhookNotifier(url), 1000)
+		go q.Run(ctx)
+
+		notifiers = append(notifiers, q)
+	}
+
+	notifications := vote.NewNotificationService(contractStorage,
+		vote.NewVoteService(),
+		features,
+		notifiers...)
+
+	// Progress of long running operations, posted to each webhook
+	opNotifiers := []operation.Notifier{}
+	for _, url := range dc.OperationWebhooks {
+		opNotifiers = append(opNotifiers, operation.NewWebhookNotifier(url))
+	}
+
+	operations := operation.NewOperationService(opNotifiers...)
+
+	documents := document.NewDocumentService(contractStorage)
+
+	// Query API
+	if dc.QueryAddress != "" {
+		qs := query.NewQueryService(dc.QueryToken,
+			dc.QuerySecret,
+			state.NewStateService(contractStorage),
+			vote.NewVoteService(),
+			archive,
+			documents,
+			receipt.NewReceiptService(contractStorage, *wallet))
+
+		go func() {
+			if err := http.ListenAndServe(dc.QueryAddress, qs); err != nil {
+				log.Errorf("Query API stopped : %v", err)
+			}
+		}()
+	}
+
+	// Integrity of the contract and block state, checked against the
+	// manifests sealed on the last clean shutdown. The block state covers
+	// the tip of the block repository the state points to.
+	blockManifest := integrity.NewIntegrityService(spvStorage, "spvnode",
+		spvnode.StateKey)
+	blockManifest.KeysFunc = spvnode.TipKeys
+
+	manifests := []integrity.IntegrityService{
+		integrity.NewIntegrityService(contractStorage, "contract",
+			fmt.Sprintf("%v/%v", state.ContractPrefix, wallet.PublicAddress)),
+		blockManifest,
+	}
+
+	if err := openManifests(ctx, manifests, dc.IntegrityRepair); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	// Changes made while this node was down, fetched from another node
+	if dc.SyncSource != "" {
+		chain := activation.NewActivationService(config.Activations, contractStorage)
+		if err := chain.Load(ctx); err != nil {
+			panic(err)
+		}
+
+		client := statesync.NewSyncClient(dc.SyncSource)
+		client.Progress = operations.Start(ctx, operation.KindSync, 0)
+
+		count, err := client.CatchUp(ctx,
+			state.NewStateService(contractStorage),
+			wallet.PublicAddress,
+			chain.ChainPoint().Height)
+		client.Progress.Finish(ctx, err)
+		if err != nil {
+			panic(err)
+		}
+
+		log.Infof("Caught up %v changes from %v", count, dc.SyncSource)
+	}
+
 	// Smart Contract Node
 	n := node.NewNode(*config, network, *wallet, contractStorage)
-	if err := n.Start(); err != nil {
-		panic(err)
+
+	if dc.TxFilter != nil {
+		n.AddTxFilter(dc.TxFilter)
+	}
+
+	n.RegisterIndexer(archive)
+	n.RegisterIndexer(notifications)
+
+	// Changes served to standby nodes
+	if dc.SyncAddress != "" {
+		sync := statesync.NewSyncService(contractStorage, n.Activation,
+			wallet.PrivateKey)
+		n.RegisterIndexer(sync)
+
+		go func() {
+			if err := http.ListenAndServe(dc.SyncAddress, sync); err != nil {
+				log.Errorf("Sync API stopped : %v", err)
+			}
+		}()
+	}
+
+	// Admin API, changing contracts through the node once the indexers
+	// are registered
+	if dc.AdminAddress != "" {
+		as := admin.NewAdminService(dc.AdminToken,
+			features,
+			operations,
+			n,
+			offline.NewOfflineService(contractStorage, network),
+			documents)
+
+		go func() {
+			if err := http.ListenAndServe(dc.AdminAddress, as); err != nil {
+				log.Errorf("Admin API stopped : %v", err)
+			}
+		}()
+	}
+
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		if err := n.Start(); err != nil {
+			panic(err)
+		}
+	}()
+
+	// Shut down cleanly on a signal, sealing the manifests
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
+
+	<-shutdown
+
+	log.Infof("Shutting down")
+
+	n.Stop()
+	<-stopped
+
+	for _, m := range manifests {
+		if err := m.Seal(ctx); err != nil {
+			log.Errorf("Failed to seal manifest %v : %v", m.Name, err)
+		}
+	}
+}
+
+// openManifests verifies the manifests, and opens them for this run.
+//
+// A failed check is returned with what it means and how to start anyway,
+// unless repair is true, when it is only logged.
+func openManifests(ctx context.Context,
+	manifests []integrity.IntegrityService,
+	repair bool) error {
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	for _, m := range manifests {
+		if err := m.Verify(ctx); err != nil {
+			if !repair {
+				return fmt.Errorf("Integrity check of %v failed : %v. %v", m.Name,
+					err, integrityAdvice(err))
+			}
+
+			log.Warnf("Integrity check of %v failed, starting in repair mode : %v", m.Name, err)
+		}
+
+		if err := m.Open(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// integrityAdvice returns what an operator can do about a failed integrity
+// check.
+func integrityAdvice(err error) string {
+	switch err {
+	case integrity.ErrUncleanShutdown:
+		return "The node was stopped without sealing its records, as after a crash or kill. " +
+			"Rescan the chain, or check the records are complete, then set INTEGRITY_REPAIR=true to start anyway"
+	case integrity.ErrCorruptRecord:
+		return "The records logged above changed or went missing since the last clean shutdown. " +
+			"Restore them from a backup, then set INTEGRITY_REPAIR=true to start anyway"
+	default:
+		return "Set INTEGRITY_REPAIR=true to start anyway"
 	}
 }
 
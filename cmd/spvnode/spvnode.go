@@ -1,13 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/tokenized/smart-contract/internal/app/logger"
 	"github.com/tokenized/smart-contract/pkg/spvnode"
 	"github.com/tokenized/smart-contract/pkg/storage"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcutil"
 )
 
 var (
@@ -20,7 +29,7 @@ var (
 //
 func main() {
 	// Logger
-	_, log := logger.NewLoggerWithContext()
+	ctx, log := logger.NewLoggerWithContext()
 
 	storeConfig := storage.NewConfig(os.Getenv("NODE_STORAGE_REGION"),
 		os.Getenv("NODE_STORAGE_ACCESS_KEY"),
@@ -38,15 +47,471 @@ func main() {
 	config := spvnode.NewConfig(os.Getenv("NODE_ADDRESS"),
 		os.Getenv("NODE_USER_AGENT"))
 
+	for _, address := range strings.Split(os.Getenv("NODE_FAILOVER_ADDRESSES"), ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			config.FailoverAddresses = append(config.FailoverAddresses, address)
+		}
+	}
+
+	for _, seed := range strings.Split(os.Getenv("NODE_SEEDS"), ",") {
+		if seed = strings.TrimSpace(seed); seed != "" {
+			config.Seeds = append(config.Seeds, seed)
+		}
+	}
+
+	for _, address := range strings.Split(os.Getenv("NODE_PINNED_PEERS"), ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			config.PinnedPeers = append(config.PinnedPeers, address)
+		}
+	}
+
+	config.HeadersFirst = strings.ToLower(os.Getenv("NODE_HEADERS_FIRST")) == "true"
+	config.HeadersOnly = strings.ToLower(os.Getenv("NODE_HEADERS_ONLY")) == "true"
+	config.CompactBlocks = strings.ToLower(os.Getenv("NODE_COMPACT_BLOCKS")) == "true"
+	config.PersistMempool = strings.ToLower(os.Getenv("NODE_PERSIST_MEMPOOL")) == "true"
+	config.DisableRelay = strings.ToLower(os.Getenv("NODE_DISABLE_RELAY")) == "true"
+	config.KeepRelevantTxs = strings.ToLower(os.Getenv("NODE_KEEP_RELEVANT_TXS")) == "true"
+
+	config.Proxy = os.Getenv("NODE_PROXY")
+	config.ProxyUsername = os.Getenv("NODE_PROXY_USERNAME")
+	config.ProxyPassword = os.Getenv("NODE_PROXY_PASSWORD")
+
+	config.Listen = os.Getenv("NODE_LISTEN")
+
+	if c := os.Getenv("NODE_CHECKPOINTS"); c != "" {
+		checkpoints, err := spvnode.ParseCheckpoints(c)
+		if err != nil {
+			panic(err)
+		}
+
+		config.Checkpoints = checkpoints
+	}
+
+	if m := os.Getenv("NODE_MEMPOOL_MAX_TXS"); m != "" {
+		count, err := strconv.Atoi(m)
+		if err != nil {
+			panic(err)
+		}
+
+		config.MempoolMaxTxs = count
+	}
+
+	if m := os.Getenv("NODE_MEMPOOL_MAX_BYTES"); m != "" {
+		size, err := strconv.Atoi(m)
+		if err != nil {
+			panic(err)
+		}
+
+		config.MempoolMaxBytes = size
+	}
+
+	if m := os.Getenv("NODE_MEMPOOL_EXPIRY"); m != "" {
+		ms, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+
+		config.MempoolExpiry = time.Duration(ms) * time.Millisecond
+	}
+
+	if m := os.Getenv("NODE_KEEP_BLOCKS"); m != "" {
+		count, err := strconv.Atoi(m)
+		if err != nil {
+			panic(err)
+		}
+
+		config.KeepBlocks = count
+	}
+
+	if m := os.Getenv("NODE_LISTENER_WORKERS"); m != "" {
+		count, err := strconv.Atoi(m)
+		if err != nil {
+			panic(err)
+		}
+
+		config.ListenerWorkers = count
+	}
+
+	if m := os.Getenv("NODE_MAX_TXS_IN_FLIGHT"); m != "" {
+		count, err := strconv.Atoi(m)
+		if err != nil {
+			panic(err)
+		}
+
+		config.MaxTxsInFlight = count
+	}
+
+	if m := os.Getenv("NODE_SEND_MESSAGES_PER_SECOND"); m != "" {
+		count, err := strconv.Atoi(m)
+		if err != nil {
+			panic(err)
+		}
+
+		config.SendMessagesPerSecond = count
+	}
+
+	if b := os.Getenv("NODE_SEND_BYTES_PER_SECOND"); b != "" {
+		count, err := strconv.Atoi(b)
+		if err != nil {
+			panic(err)
+		}
+
+		config.SendBytesPerSecond = count
+	}
+
+	if m := os.Getenv("NODE_MAX_MESSAGE_SIZE"); m != "" {
+		count, err := strconv.Atoi(m)
+		if err != nil {
+			panic(err)
+		}
+
+		config.MaxMessageSize = count
+	}
+
+	if m := os.Getenv("NODE_MAX_INV_PER_MSG"); m != "" {
+		count, err := strconv.Atoi(m)
+		if err != nil {
+			panic(err)
+		}
+
+		config.MaxInvPerMsg = count
+	}
+
+	if m := os.Getenv("NODE_MAX_HEADERS_PER_MSG"); m != "" {
+		count, err := strconv.Atoi(m)
+		if err != nil {
+			panic(err)
+		}
+
+		config.MaxHeadersPerMsg = count
+	}
+
+	if m := os.Getenv("NODE_MAX_ADDRS_PER_MSG"); m != "" {
+		count, err := strconv.Atoi(m)
+		if err != nil {
+			panic(err)
+		}
+
+		config.MaxAddrsPerMsg = count
+	}
+
+	if m := os.Getenv("NODE_REBROADCAST_INTERVAL"); m != "" {
+		ms, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+
+		config.RebroadcastInterval = time.Duration(ms) * time.Millisecond
+	}
+
+	if m := os.Getenv("NODE_REBROADCAST_MAX_ATTEMPTS"); m != "" {
+		attempts, err := strconv.Atoi(m)
+		if err != nil {
+			panic(err)
+		}
+
+		config.RebroadcastMaxAttempts = attempts
+	}
+
+	eviction, err := spvnode.ParseEviction(os.Getenv("NODE_MEMPOOL_EVICTION"))
+	if err != nil {
+		panic(err)
+	}
+
+	config.MempoolEviction = eviction
+
+	network, err := spvnode.ParseNetwork(os.Getenv("NODE_NETWORK"))
+	if err != nil {
+		panic(err)
+	}
+
+	config.Network = network
+
+	if p := os.Getenv("NODE_PEERS"); p != "" {
+		count, err := strconv.Atoi(p)
+		if err != nil {
+			panic(err)
+		}
+
+		config.Peers = count
+	}
+
+	if p := os.Getenv("NODE_MAX_PEERS_PER_GROUP"); p != "" {
+		count, err := strconv.Atoi(p)
+		if err != nil {
+			panic(err)
+		}
+
+		config.MaxPeersPerGroup = count
+	}
+
+	if m := os.Getenv("NODE_PEER_ROTATION_INTERVAL"); m != "" {
+		ms, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+
+		config.PeerRotationInterval = time.Duration(ms) * time.Millisecond
+	}
+
+	if m := os.Getenv("NODE_PEER_ROTATION_COUNT"); m != "" {
+		count, err := strconv.Atoi(m)
+		if err != nil {
+			panic(err)
+		}
+
+		config.PeerRotationCount = count
+	}
+
+	if m := os.Getenv("NODE_MAX_INBOUND"); m != "" {
+		count, err := strconv.Atoi(m)
+		if err != nil {
+			panic(err)
+		}
+
+		config.MaxInbound = count
+	}
+
+	if m := os.Getenv("NODE_MAX_INBOUND_MESSAGE_SIZE"); m != "" {
+		count, err := strconv.Atoi(m)
+		if err != nil {
+			panic(err)
+		}
+
+		config.MaxInboundMessageSize = count
+	}
+
+	if m := os.Getenv("NODE_SERVICES"); m != "" {
+		services, err := strconv.ParseUint(m, 0, 64)
+		if err != nil {
+			panic(err)
+		}
+
+		config.Services = wire.ServiceFlag(services)
+	}
+
+	if m := os.Getenv("NODE_FEE_FILTER"); m != "" {
+		fee, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+
+		config.FeeFilter = fee
+	}
+
+	if m := os.Getenv("NODE_HANDSHAKE_TIMEOUT"); m != "" {
+		ms, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+
+		config.HandshakeTimeout = time.Duration(ms) * time.Millisecond
+	}
+
+	if m := os.Getenv("NODE_MIN_PEER_VERSION"); m != "" {
+		version, err := strconv.ParseUint(m, 10, 32)
+		if err != nil {
+			panic(err)
+		}
+
+		config.MinPeerVersion = uint32(version)
+	}
+
+	if m := os.Getenv("NODE_REQUIRED_SERVICES"); m != "" {
+		services, err := strconv.ParseUint(m, 0, 64)
+		if err != nil {
+			panic(err)
+		}
+
+		config.RequiredServices = wire.ServiceFlag(services)
+	}
+
+	if h := os.Getenv("NODE_START_HEIGHT"); h != "" {
+		height, err := strconv.ParseInt(h, 10, 32)
+		if err != nil {
+			panic(err)
+		}
+
+		config.StartHeight = int32(height)
+	}
+
+	// Peer address book, imported from or exported to a file
+	//
+	//	spvnode peers import {file}
+	//	spvnode peers export {file}
+	if len(os.Args) == 4 && os.Args[1] == "peers" {
+		if err := peers(ctx, spvStorage, config.Network, os.Args[2], os.Args[3]); err != nil {
+			panic(err)
+		}
+
+		return
+	}
+
 	// Log startup sequence
 	log.Infof("Started %v with config %s", buildDetails(), config)
 
 	n := spvnode.NewNode(config, spvStorage)
+
+	// Addresses whose TX's are downloaded from untrusted peers, through a
+	// bloom filter
+	addresses := []btcutil.Address{}
+	for _, a := range strings.Split(os.Getenv("NODE_FILTER_ADDRESSES"), ",") {
+		if a = strings.TrimSpace(a); a == "" {
+			continue
+		}
+
+		address, err := btcutil.DecodeAddress(a, spvnode.ChainParams(config.Network))
+		if err != nil {
+			panic(err)
+		}
+
+		addresses = append(addresses, address)
+	}
+
+	if len(addresses) > 0 {
+		n.AddTxFilter(spvnode.NewAddressFilter(addresses...))
+	}
+
+	// Rule of the relevant TX's, see spvnode.Matcher
+	if rule := os.Getenv("NODE_FILTER_RULE"); rule != "" {
+		m, err := spvnode.CompileMatcher(rule)
+		if err != nil {
+			panic(err)
+		}
+
+		n.AddTxFilter(m)
+	}
+
+	// Addresses whose unspent outputs are tracked
+	watched := []btcutil.Address{}
+	for _, a := range strings.Split(os.Getenv("NODE_WATCH_ADDRESSES"), ",") {
+		if a = strings.TrimSpace(a); a == "" {
+			continue
+		}
+
+		address, err := btcutil.DecodeAddress(a, spvnode.ChainParams(config.Network))
+		if err != nil {
+			panic(err)
+		}
+
+		watched = append(watched, address)
+	}
+
+	if len(watched) > 0 {
+		utxos, err := spvnode.NewUTXOTracker(watched, n.BlockService, spvStorage)
+		if err != nil {
+			panic(err)
+		}
+
+		if err := utxos.Load(ctx); err != nil {
+			panic(err)
+		}
+
+		n.AddTxFilter(utxos.Filter())
+		n.RegisterListener(spvnode.ListenerTX, utxos)
+		n.RegisterListener(spvnode.ListenerBlock, utxos)
+	}
+
+	// Rescan of the stored blocks against the filters, such as after an
+	// address is added
+	//
+	//	spvnode reindex
+	if len(os.Args) == 2 && os.Args[1] == "reindex" {
+		stats, err := n.Reindex(ctx)
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("Scanned %v blocks, found %v relevant TX's, changed %v blocks\n",
+			stats.Blocks, stats.Txs, stats.Changed)
+		return
+	}
+
+	// WebSocket stream of the relevant TX's and blocks
+	var server *http.Server
+	if address := os.Getenv("NODE_EVENTS_ADDRESS"); address != "" {
+		stream := spvnode.NewEventStream([]spvnode.TxFilter{n.TxFilters},
+			n.BlockService)
+
+		n.RegisterListener(spvnode.ListenerTX, stream)
+		n.RegisterListener(spvnode.ListenerBlock, stream)
+
+		server = &http.Server{
+			Addr:    address,
+			Handler: stream,
+		}
+
+		go func() {
+			log.Infof("Streaming events on %v", address)
+
+			if err := server.ListenAndServe(); err != http.ErrServerClosed {
+				log.Errorf("Event stream stopped : %v", err)
+			}
+
+			stream.Close()
+		}()
+	}
+
+	// Stop cleanly on a signal
+	go func() {
+		shutdown := make(chan os.Signal, 1)
+		signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
+
+		<-shutdown
+
+		log.Infof("Shutting down")
+		n.Stop()
+
+		if server != nil {
+			server.Close()
+		}
+	}()
+
 	if err := n.Start(); err != nil {
 		panic(err)
 	}
 }
 
+// peers imports or exports the peer address book. The format of the file
+// is chosen by its name, see spvnode.FormatOf.
+func peers(ctx context.Context,
+	store storage.Storage,
+	network wire.BitcoinNet,
+	command string,
+	path string) error {
+
+	book := spvnode.NewPeerBook(spvnode.NewPeerRepository(store), network)
+
+	switch command {
+	case "import":
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		count, err := book.Import(ctx, f, spvnode.FormatOf(path))
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Imported %v peers\n", count)
+		return nil
+
+	case "export":
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		return book.Export(ctx, f, spvnode.FormatOf(path))
+
+	default:
+		return fmt.Errorf("Unknown peers command %v", command)
+	}
+}
+
 // buildDetails returns a string that describes the details of the build.
 func buildDetails() string {
 	return fmt.Sprintf("%v (%v on %v)", buildVersion, buildUser, buildDate)
@@ -0,0 +1,158 @@
+package activation
+
+/**
+ * Activation Service
+ *
+ * What is my purpose?
+ * - You follow the height and median time of the chain
+ * - You tell me which rules are active at that point
+ * - You let every node change behaviour at the same block
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/pkg/storage"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+const (
+	// ActivationPrefix is the storage path that the ChainPoint is written to.
+	ActivationPrefix = "activation"
+
+	// chainPointKey is the name of the stored ChainPoint.
+	chainPointKey = "chain_point"
+)
+
+// Schedule is the set of scheduled Activation's.
+type Schedule []config.Activation
+
+// Active returns true if the rule is active at the height and median time.
+//
+// A rule that is not scheduled is never active.
+func (s Schedule) Active(rule string, height int32, medianTime int64) bool {
+	for _, a := range s {
+		if a.Rule != rule {
+			continue
+		}
+
+		if a.MedianTime != 0 {
+			if medianTime != 0 && medianTime >= a.MedianTime {
+				return true
+			}
+
+			continue
+		}
+
+		if height >= a.Height {
+			return true
+		}
+	}
+
+	return false
+}
+
+type ActivationService struct {
+	Storage  storage.ReadWriter
+	Schedule Schedule
+
+	mu    *sync.Mutex
+	point *ChainPoint
+}
+
+func NewActivationService(activations []config.Activation,
+	store storage.ReadWriter) ActivationService {
+
+	return ActivationService{
+		Storage:  store,
+		Schedule: Schedule(activations),
+		mu:       &sync.Mutex{},
+		point:    &ChainPoint{},
+	}
+}
+
+// Load reads the last ChainPoint from storage, so rules remain active across
+// restarts.
+func (s ActivationService) Load(ctx context.Context) error {
+	b, err := s.Storage.Read(ctx, s.buildPath())
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil
+		}
+
+		return err
+	}
+
+	p := ChainPoint{}
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	*s.point = p
+
+	return nil
+}
+
+// Connect moves the chain point to a new block.
+func (s ActivationService) Connect(ctx context.Context,
+	b *wire.MsgBlock) error {
+
+	height, err := blockHeight(b)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.point.connect(b, height)
+
+	for _, a := range s.Schedule {
+		if !s.Schedule.Active(a.Rule, s.point.Height, s.point.MedianTime()) &&
+			s.Schedule.Active(a.Rule, p.Height, p.MedianTime()) {
+
+			log := logger.NewLoggerFromContext(ctx).Sugar()
+			log.Infof("Rule %v active from block %v", a.Rule, p.Hash)
+		}
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Storage.Write(ctx, s.buildPath(), data, nil); err != nil {
+		return err
+	}
+
+	*s.point = p
+
+	return nil
+}
+
+// ChainPoint returns the point the chain has reached.
+func (s ActivationService) ChainPoint() ChainPoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return *s.point
+}
+
+// Active returns true if the rule is active at the current chain point.
+func (s ActivationService) Active(rule string) bool {
+	p := s.ChainPoint()
+
+	return s.Schedule.Active(rule, p.Height, p.MedianTime())
+}
+
+func (s ActivationService) buildPath() string {
+	return fmt.Sprintf("%v/%v", ActivationPrefix, chainPointKey)
+}
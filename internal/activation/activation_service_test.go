@@ -0,0 +1,223 @@
+package activation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/pkg/storage"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+type memoryStorage map[string][]byte
+
+func (m memoryStorage) Read(ctx context.Context, key string) ([]byte, error) {
+	b, ok := m[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	return b, nil
+}
+
+func (m memoryStorage) Write(ctx context.Context,
+	key string,
+	body []byte,
+	opts *storage.Options) error {
+
+	m[key] = body
+	return nil
+}
+
+// newBlock returns a block at the height, with the height pushed onto the
+// coinbase script.
+func newBlock(height int32, timestamp int64) *wire.MsgBlock {
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(&wire.TxIn{
+		SignatureScript: []byte{
+			3, byte(height), byte(height >> 8), byte(height >> 16),
+		},
+	})
+
+	b := wire.NewMsgBlock(&wire.BlockHeader{
+		Timestamp: time.Unix(timestamp, 0),
+		Nonce:     uint32(height),
+	})
+	b.AddTransaction(coinbase)
+
+	return b
+}
+
+func TestSchedule_Active(t *testing.T) {
+	schedule := Schedule{
+		config.Activation{Rule: "height", Height: 100},
+		config.Activation{Rule: "time", MedianTime: 1500000000},
+	}
+
+	tests := []struct {
+		name       string
+		rule       string
+		height     int32
+		medianTime int64
+		want       bool
+	}{
+		{
+			name:   "before height",
+			rule:   "height",
+			height: 99,
+			want:   false,
+		},
+		{
+			name:   "at height",
+			rule:   "height",
+			height: 100,
+			want:   true,
+		},
+		{
+			name:       "before median time",
+			rule:       "time",
+			height:     1000,
+			medianTime: 1499999999,
+			want:       false,
+		},
+		{
+			name:       "at median time",
+			rule:       "time",
+			medianTime: 1500000000,
+			want:       true,
+		},
+		{
+			name:       "not scheduled",
+			rule:       "other",
+			height:     1000,
+			medianTime: 1500000000,
+			want:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := schedule.Active(tt.rule, tt.height, tt.medianTime)
+
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActivationService(t *testing.T) {
+	ctx := context.Background()
+	store := memoryStorage{}
+
+	activations := []config.Activation{
+		config.Activation{Rule: "height", Height: 600005},
+		config.Activation{Rule: "time", MedianTime: 1500000005},
+	}
+
+	s := NewActivationService(activations, store)
+
+	for i := int32(0); i < 10; i++ {
+		b := newBlock(600000+i, 1500000000+int64(i))
+
+		if err := s.Connect(ctx, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := s.ChainPoint()
+
+	if p.Height != 600009 {
+		t.Errorf("got height %v, want %v", p.Height, 600009)
+	}
+
+	// 10 blocks are not enough for a median time
+	if s.Active("time") {
+		t.Errorf("time rule active before median time is known")
+	}
+
+	if !s.Active("height") {
+		t.Errorf("height rule not active")
+	}
+
+	if err := s.Connect(ctx, newBlock(600010, 1500000010)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := s.ChainPoint().MedianTime(); got != 1500000005 {
+		t.Errorf("got median time %v, want %v", got, 1500000005)
+	}
+
+	if !s.Active("time") {
+		t.Errorf("time rule not active")
+	}
+
+	// a reorg back below the activation height
+	if err := s.Connect(ctx, newBlock(600004, 1500000004)); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Active("height") || s.Active("time") {
+		t.Errorf("rules still active after reorg")
+	}
+
+	// the chain point survives a restart
+	loaded := NewActivationService(activations, store)
+	if err := loaded.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := loaded.ChainPoint().Height; got != 600004 {
+		t.Errorf("got loaded height %v, want %v", got, 600004)
+	}
+}
+
+func TestBlockHeight(t *testing.T) {
+	tests := []struct {
+		name   string
+		script []byte
+		want   int32
+		err    error
+	}{
+		{
+			name:   "push",
+			script: []byte{3, 0x40, 0x42, 0x0f, 0xff},
+			want:   1000000,
+		},
+		{
+			name:   "small",
+			script: []byte{0x55},
+			want:   5,
+		},
+		{
+			name:   "empty",
+			script: []byte{},
+			err:    ErrNoHeight,
+		},
+		{
+			name:   "short",
+			script: []byte{3, 0x40},
+			err:    ErrNoHeight,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coinbase := wire.NewMsgTx(1)
+			coinbase.AddTxIn(&wire.TxIn{SignatureScript: tt.script})
+
+			b := wire.NewMsgBlock(&wire.BlockHeader{})
+			b.AddTransaction(coinbase)
+
+			got, err := blockHeight(b)
+			if err != tt.err {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
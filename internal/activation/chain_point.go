@@ -0,0 +1,130 @@
+package activation
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+const (
+	// medianTimeBlocks is the number of blocks the median time past is taken
+	// over.
+	medianTimeBlocks = 11
+)
+
+// ErrNoHeight is returned when the height of a block cannot be read from its
+// coinbase.
+var ErrNoHeight = errors.New("No height in coinbase")
+
+// BlockTime is the timestamp of a block at a height.
+type BlockTime struct {
+	Height    int32 `json:"height"`
+	Timestamp int64 `json:"timestamp"`
+}
+
+// ChainPoint is the point the chain has reached, as seen by the node.
+type ChainPoint struct {
+	Hash   string `json:"hash"`
+	Height int32  `json:"height"`
+
+	// Recent holds the timestamps of the most recent blocks, in order of
+	// height, ending with the block at Height.
+	Recent []BlockTime `json:"recent"`
+}
+
+// MedianTime returns the median time past of the chain point, which is the
+// median timestamp of the last 11 blocks.
+//
+// It returns 0 until 11 consecutive blocks have been seen, so every node
+// agrees on the median time of a chain point.
+func (p ChainPoint) MedianTime() int64 {
+	if len(p.Recent) < medianTimeBlocks {
+		return 0
+	}
+
+	timestamps := []int64{}
+	for _, bt := range p.Recent[len(p.Recent)-medianTimeBlocks:] {
+		timestamps = append(timestamps, bt.Timestamp)
+	}
+
+	sort.Slice(timestamps, func(i, j int) bool {
+		return timestamps[i] < timestamps[j]
+	})
+
+	return timestamps[medianTimeBlocks/2]
+}
+
+// connect returns the chain point after the block, which is at the height.
+//
+// A block that does not follow on from the recent blocks, such as after a
+// reorg or a gap, discards the timestamps that no longer lead up to it.
+func (p ChainPoint) connect(b *wire.MsgBlock, height int32) ChainPoint {
+	recent := []BlockTime{}
+
+	for _, bt := range p.Recent {
+		if bt.Height < height {
+			recent = append(recent, bt)
+		}
+	}
+
+	if len(recent) > 0 && recent[len(recent)-1].Height != height-1 {
+		recent = []BlockTime{}
+	}
+
+	recent = append(recent, BlockTime{
+		Height:    height,
+		Timestamp: b.Header.Timestamp.Unix(),
+	})
+
+	if len(recent) > medianTimeBlocks {
+		recent = recent[len(recent)-medianTimeBlocks:]
+	}
+
+	return ChainPoint{
+		Hash:   b.BlockHash().String(),
+		Height: height,
+		Recent: recent,
+	}
+}
+
+// blockHeight returns the height of a block, which is the first push of the
+// coinbase script as required by BIP34.
+func blockHeight(b *wire.MsgBlock) (int32, error) {
+	if len(b.Transactions) == 0 || len(b.Transactions[0].TxIn) == 0 {
+		return 0, ErrNoHeight
+	}
+
+	script := b.Transactions[0].TxIn[0].SignatureScript
+	if len(script) == 0 {
+		return 0, ErrNoHeight
+	}
+
+	op := script[0]
+
+	// small heights are pushed with OP_0 and OP_1 to OP_16
+	if op == 0x00 {
+		return 0, nil
+	}
+
+	if op >= 0x51 && op <= 0x60 {
+		return int32(op - 0x50), nil
+	}
+
+	n := int(op)
+	if n < 1 || n > 4 || len(script) < n+1 {
+		return 0, ErrNoHeight
+	}
+
+	// little endian, with the sign in the top bit of the last byte
+	var height int64
+	for i := 0; i < n; i++ {
+		height |= int64(script[1+i]) << uint(8*i)
+	}
+
+	if script[n]&0x80 != 0 {
+		return 0, ErrNoHeight
+	}
+
+	return int32(height), nil
+}
@@ -0,0 +1,782 @@
+package admin
+
+/**
+ * Admin Service
+ *
+ * What is my purpose?
+ * - You let an operator change how the node treats each contract
+ * - You let me turn feature flags on and off, one contract at a time
+ * - You let me set how the transfer fee of an asset is split
+ * - You let me require receivers of an asset to accept their transfers
+ * - You let me set who is eligible to vote, and where each holder is
+ * - You let me set how a contract handles outputs too small to pay
+ * - You let me attach documents to assets
+ * - You show me how long running jobs are going, and let me control them
+ * - You take signatures made offline, and broadcast what they sign
+ * - You turn away anyone without the admin token
+ */
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/internal/app/state"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/internal/document"
+	"github.com/tokenized/smart-contract/internal/feature"
+	"github.com/tokenized/smart-contract/internal/offline"
+	"github.com/tokenized/smart-contract/internal/operation"
+	"github.com/tokenized/smart-contract/pkg/errs"
+)
+
+// The actions that changes made through the admin API are indexed as.
+const (
+	ActionTransferFee      = "admin/transfer_fee"
+	ActionReceiverApproval = "admin/receiver_approval"
+	ActionDustPolicy       = "admin/dust_policy"
+	ActionEligibility      = "admin/eligibility"
+	ActionJurisdiction     = "admin/jurisdiction"
+	ActionDocument         = "admin/document"
+)
+
+var (
+	// ErrAssetNotFound is returned when the asset of a request is not one
+	// of the contract.
+	ErrAssetNotFound = errs.New(errs.NotFound, "Asset not found")
+
+	// ErrHolderNotFound is returned when the address of a request holds no
+	// asset of the contract.
+	ErrHolderNotFound = errs.New(errs.NotFound, "Holder not found")
+)
+
+// AdminService serves the admin API over HTTP. Each request must carry the
+// token in the header
+//
+//	Authorization: Bearer {token}
+//
+// The feature flags of a contract are read and set with the endpoints
+//
+//	GET /contracts/{contract}/features
+//	PUT /contracts/{contract}/features/{flag}  {"enabled": true}
+//
+// The transfer fee of an asset, and how it is split between payees, is set
+// with the endpoint
+//
+//	PUT /contracts/{contract}/assets/{asset}/transfer_fee
+//	  {"value": 1000, "payees": [{"role": "issuer", "address": "1...", "percent": 100}]}
+//
+// Whether the receiver of a transfer of an asset must accept it before it
+// settles, and how many seconds they have to accept it, is set with the
+// endpoint
+//
+//	PUT /contracts/{contract}/assets/{asset}/receiver_approval
+//	  {"required": true, "timeout": 86400}
+//
+// A zero timeout uses the default of seven days.
+//
+// The dust policy of a contract, which is how outputs below its minimum
+// output value are handled, is set with the endpoint
+//
+//	PUT /contracts/{contract}/dust_policy  {"minimum_output": 1000, "handling": "absorb"}
+//
+// The rules of who is eligible to vote on a contract are set with the
+// endpoint
+//
+//	PUT /contracts/{contract}/eligibility
+//	  {"minimum_holding": 100, "jurisdictions": ["AUS", "USA"]}
+//
+// The jurisdiction of a holder, as known to the issuer from the holder's
+// KYC, is set with the endpoint
+//
+//	PUT /contracts/{contract}/holders/{address}/jurisdiction  {"jurisdiction": "AUS"}
+//
+// A document, such as the prospectus or terms of an asset, is attached to
+// the asset with the endpoint
+//
+//	PUT /contracts/{contract}/assets/{asset}/documents/{name}  {content}
+//
+// with the content of the document as the body. The hash of the document
+// is recorded in the asset, replacing any document of the same name.
+//
+// Long running operations are read and controlled with the endpoints
+//
+//	GET  /operations
+//	GET  /operations/{id}
+//	POST /operations/{id}/pause
+//	POST /operations/{id}/resume
+//	POST /operations/{id}/cancel
+//
+// Responses exported to be signed offline are read, signed and broadcast
+// with the endpoints
+//
+//	GET  /unsigned
+//	GET  /unsigned/{id}
+//	POST /unsigned/{id}/signatures  {"public_key": "02...", "signatures": ["3044..."]}
+//	POST /unsigned/{id}/broadcast
+//
+// with one hex encoded signature for each input of the TX, in order.
+//
+// Changes to the state of a contract are made through the Updater, so they
+// don't race the processing of requests, and are indexed as responses are.
+type AdminService struct {
+	Token      string
+	Features   feature.FeatureService
+	Operations operation.OperationService
+	Contracts  state.Updater
+	Offline    offline.OfflineService
+	Documents  document.DocumentService
+}
+
+// NewAdminService returns a new AdminService, accepting requests with the
+// token.
+func NewAdminService(token string,
+	features feature.FeatureService,
+	operations operation.OperationService,
+	contracts state.Updater,
+	offline offline.OfflineService,
+	documents document.DocumentService) AdminService {
+
+	return AdminService{
+		Token:      token,
+		Features:   features,
+		Operations: operations,
+		Contracts:  contracts,
+		Offline:    offline,
+		Documents:  documents,
+	}
+}
+
+// featureUpdate is the body of a request to set a feature flag.
+type featureUpdate struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// signaturesUpdate is the body of a request to add the signatures made
+// offline by a key. The key and signatures are hex encoded.
+type signaturesUpdate struct {
+	PublicKey  string   `json:"public_key"`
+	Signatures []string `json:"signatures"`
+}
+
+// broadcastResult is the body of the response to a broadcast.
+type broadcastResult struct {
+	TxID string `json:"txid"`
+}
+
+// receiverApprovalUpdate is the body of a request to set whether the
+// receivers of an asset must accept their transfers. The timeout is in
+// seconds.
+type receiverApprovalUpdate struct {
+	Required *bool `json:"required"`
+	Timeout  int64 `json:"timeout"`
+}
+
+// jurisdictionUpdate is the body of a request to set the jurisdiction of a
+// holder.
+type jurisdictionUpdate struct {
+	Jurisdiction string `json:"jurisdiction"`
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s AdminService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	if parts[0] == "operations" {
+		s.serveOperations(w, r, parts[1:])
+		return
+	}
+
+	if parts[0] == "unsigned" {
+		s.serveUnsigned(w, r, parts[1:])
+		return
+	}
+
+	if len(parts) == 5 && parts[0] == "contracts" && parts[2] == "assets" &&
+		parts[4] == "transfer_fee" {
+
+		if r.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		s.setTransferFee(w, r, parts[1], parts[3])
+		return
+	}
+
+	if len(parts) == 5 && parts[0] == "contracts" && parts[2] == "assets" &&
+		parts[4] == "receiver_approval" {
+
+		if r.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		s.setReceiverApproval(w, r, parts[1], parts[3])
+		return
+	}
+
+	if len(parts) == 6 && parts[0] == "contracts" && parts[2] == "assets" &&
+		parts[4] == "documents" {
+
+		if r.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		s.attachDocument(w, r, parts[1], parts[3], parts[5])
+		return
+	}
+
+	if len(parts) == 3 && parts[0] == "contracts" && parts[2] == "dust_policy" {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		s.setDustPolicy(w, r, parts[1])
+		return
+	}
+
+	if len(parts) == 3 && parts[0] == "contracts" && parts[2] == "eligibility" {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		s.setEligibility(w, r, parts[1])
+		return
+	}
+
+	if len(parts) == 5 && parts[0] == "contracts" && parts[2] == "holders" &&
+		parts[4] == "jurisdiction" {
+
+		if r.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		s.setHolderJurisdiction(w, r, parts[1], parts[3])
+		return
+	}
+
+	if len(parts) < 3 || parts[0] != "contracts" || parts[2] != "features" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case len(parts) == 3 && r.Method == http.MethodGet:
+		s.serveFeatures(w, r, parts[1])
+
+	case len(parts) == 4 && r.Method == http.MethodPut:
+		s.setFeature(w, r, parts[1], parts[3])
+
+	case len(parts) <= 4:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveFeatures writes the feature flags of a contract.
+func (s AdminService) serveFeatures(w http.ResponseWriter,
+	r *http.Request,
+	contractID string) {
+
+	flags, err := s.Features.Flags(r.Context(), contractID)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeBody(w, r, flags)
+}
+
+// setFeature enables or disables a feature flag of a contract, writing the
+// resulting flags.
+func (s AdminService) setFeature(w http.ResponseWriter,
+	r *http.Request,
+	contractID string,
+	flag string) {
+
+	var u featureUpdate
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil || u.Enabled == nil {
+		http.Error(w, "Body must be {\"enabled\": true|false}",
+			http.StatusBadRequest)
+		return
+	}
+
+	if err := s.Features.Set(r.Context(), contractID, flag, *u.Enabled); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	log := logger.NewLoggerFromContext(r.Context()).Sugar()
+	log.Infof("Set feature %v of %v to %v", flag, contractID, *u.Enabled)
+
+	s.serveFeatures(w, r, contractID)
+}
+
+// setTransferFee sets the transfer fee of an asset, writing the resulting
+// asset.
+func (s AdminService) setTransferFee(w http.ResponseWriter,
+	r *http.Request,
+	contractID string,
+	assetID string) {
+
+	var fee contract.TransferFee
+	if err := json.NewDecoder(r.Body).Decode(&fee); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := fee.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var asset contract.Asset
+
+	_, err := s.Contracts.Update(r.Context(), contractID, ActionTransferFee,
+		func(c *contract.Contract) error {
+			a, ok := c.Assets[assetID]
+			if !ok {
+				return ErrAssetNotFound
+			}
+
+			a.TransferFee = &fee
+			c.Assets[assetID] = a
+			asset = a
+
+			return nil
+		})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	log := logger.NewLoggerFromContext(r.Context()).Sugar()
+	log.Infof("Set transfer fee of %v %v to %+v", contractID, assetID, fee)
+
+	s.writeBody(w, r, asset)
+}
+
+// setReceiverApproval sets whether the receivers of an asset must accept
+// their transfers, writing the resulting asset.
+//
+// Transfers already held for acceptance are settled or refunded as before.
+func (s AdminService) setReceiverApproval(w http.ResponseWriter,
+	r *http.Request,
+	contractID string,
+	assetID string) {
+
+	var u receiverApprovalUpdate
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil ||
+		u.Required == nil || u.Timeout < 0 {
+
+		http.Error(w, "Body must be {\"required\": true|false, \"timeout\": seconds}",
+			http.StatusBadRequest)
+		return
+	}
+
+	var asset contract.Asset
+
+	_, err := s.Contracts.Update(r.Context(), contractID, ActionReceiverApproval,
+		func(c *contract.Contract) error {
+			a, ok := c.Assets[assetID]
+			if !ok {
+				return ErrAssetNotFound
+			}
+
+			a.ReceiverApproval = *u.Required
+			a.ApprovalTimeout = int64(time.Duration(u.Timeout) * time.Second)
+			c.Assets[assetID] = a
+			asset = a
+
+			return nil
+		})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	log := logger.NewLoggerFromContext(r.Context()).Sugar()
+	log.Infof("Set receiver approval of %v %v to %v, timeout %vs", contractID,
+		assetID, *u.Required, u.Timeout)
+
+	s.writeBody(w, r, asset)
+}
+
+// attachDocument attaches the body of the request to an asset as the
+// document of the name, writing the resulting Document.
+func (s AdminService) attachDocument(w http.ResponseWriter,
+	r *http.Request,
+	contractID string,
+	assetID string,
+	name string) {
+
+	// read one byte more than allowed, so a document that is too large is
+	// rejected rather than cut short
+	content, err := ioutil.ReadAll(io.LimitReader(r.Body,
+		document.MaxDocumentSize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var d contract.Document
+
+	_, err = s.Contracts.Update(r.Context(), contractID, ActionDocument,
+		func(c *contract.Contract) error {
+			a, ok := c.Assets[assetID]
+			if !ok {
+				return ErrAssetNotFound
+			}
+
+			a, err := s.Documents.Attach(r.Context(), a, name, content)
+			if err != nil {
+				return err
+			}
+
+			c.Assets[assetID] = a
+			d = a.Documents[name]
+
+			return nil
+		})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeBody(w, r, d)
+}
+
+// setDustPolicy sets the dust policy of a contract, writing the resulting
+// policy.
+func (s AdminService) setDustPolicy(w http.ResponseWriter,
+	r *http.Request,
+	contractID string) {
+
+	var p contract.DustPolicy
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := p.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err := s.Contracts.Update(r.Context(), contractID, ActionDustPolicy,
+		func(c *contract.Contract) error {
+			c.DustPolicy = p
+			return nil
+		})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	log := logger.NewLoggerFromContext(r.Context()).Sugar()
+	log.Infof("Set dust policy of %v to %+v", contractID, p)
+
+	s.writeBody(w, r, p)
+}
+
+// setEligibility sets the rules of who is eligible to vote on a contract,
+// writing the resulting rules.
+func (s AdminService) setEligibility(w http.ResponseWriter,
+	r *http.Request,
+	contractID string) {
+
+	var e contract.Eligibility
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := e.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err := s.Contracts.Update(r.Context(), contractID, ActionEligibility,
+		func(c *contract.Contract) error {
+			c.Eligibility = e
+			return nil
+		})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	log := logger.NewLoggerFromContext(r.Context()).Sugar()
+	log.Infof("Set eligibility of %v to %+v", contractID, e)
+
+	s.writeBody(w, r, e)
+}
+
+// setHolderJurisdiction sets the jurisdiction of a holder on each of their
+// holdings of a contract.
+func (s AdminService) setHolderJurisdiction(w http.ResponseWriter,
+	r *http.Request,
+	contractID string,
+	address string) {
+
+	var u jurisdictionUpdate
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if u.Jurisdiction == "" {
+		http.Error(w, contract.ErrEmptyJurisdiction.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err := s.Contracts.Update(r.Context(), contractID, ActionJurisdiction,
+		func(c *contract.Contract) error {
+			if !c.SetHolderJurisdiction(address, u.Jurisdiction) {
+				return ErrHolderNotFound
+			}
+
+			return nil
+		})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	log := logger.NewLoggerFromContext(r.Context()).Sugar()
+	log.Infof("Set jurisdiction of %v on %v to %v", address, contractID, u.Jurisdiction)
+
+	s.writeBody(w, r, u)
+}
+
+// serveOperations serves the endpoints of the long running operations,
+// given the parts of the path after "operations".
+func (s AdminService) serveOperations(w http.ResponseWriter,
+	r *http.Request,
+	parts []string) {
+
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		s.writeBody(w, r, s.Operations.All())
+
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		op, err := s.Operations.Get(parts[0])
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+
+		s.writeBody(w, r, op)
+
+	case len(parts) == 2 && r.Method == http.MethodPost:
+		s.controlOperation(w, r, parts[0], parts[1])
+
+	case len(parts) <= 2:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveUnsigned serves the endpoints of the responses to be signed
+// offline, given the parts of the path after "unsigned".
+func (s AdminService) serveUnsigned(w http.ResponseWriter,
+	r *http.Request,
+	parts []string) {
+
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		pending, err := s.Offline.Pending(r.Context())
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+
+		s.writeBody(w, r, pending)
+
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		u, err := s.Offline.Find(r.Context(), parts[0])
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+
+		s.writeBody(w, r, u)
+
+	case len(parts) == 2 && parts[1] == "signatures" && r.Method == http.MethodPost:
+		s.addSignatures(w, r, parts[0])
+
+	case len(parts) == 2 && parts[1] == "broadcast" && r.Method == http.MethodPost:
+		s.broadcast(w, r, parts[0])
+
+	case len(parts) <= 2:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// addSignatures adds the signatures made offline by a key to an unsigned
+// response, writing the resulting unsigned response.
+func (s AdminService) addSignatures(w http.ResponseWriter,
+	r *http.Request,
+	id string) {
+
+	var u signaturesUpdate
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pubKey, err := hex.DecodeString(u.PublicKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sigs := [][]byte{}
+	for _, sig := range u.Signatures {
+		b, err := hex.DecodeString(sig)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sigs = append(sigs, b)
+	}
+
+	if err := s.Offline.AddSignatures(r.Context(), id, pubKey, sigs); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	log := logger.NewLoggerFromContext(r.Context()).Sugar()
+	log.Infof("Added signatures of %v to unsigned TX %v", u.PublicKey, id)
+
+	unsigned, err := s.Offline.Find(r.Context(), id)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeBody(w, r, unsigned)
+}
+
+// broadcast finalizes and broadcasts an unsigned response once it has been
+// signed, writing the TxID.
+func (s AdminService) broadcast(w http.ResponseWriter,
+	r *http.Request,
+	id string) {
+
+	hash, err := s.Offline.Broadcast(r.Context(), id)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	log := logger.NewLoggerFromContext(r.Context()).Sugar()
+	log.Infof("Broadcast signed TX %v as %v", id, hash)
+
+	s.writeBody(w, r, broadcastResult{TxID: hash.String()})
+}
+
+// controlOperation pauses, resumes or cancels an operation, writing the
+// resulting operation.
+func (s AdminService) controlOperation(w http.ResponseWriter,
+	r *http.Request,
+	id string,
+	action string) {
+
+	var control func(context.Context, string) (operation.Operation, error)
+
+	switch action {
+	case "pause":
+		control = s.Operations.Pause
+	case "resume":
+		control = s.Operations.Resume
+	case "cancel":
+		control = s.Operations.Cancel
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	op, err := control(r.Context(), id)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	log := logger.NewLoggerFromContext(r.Context()).Sugar()
+	log.Infof("Operation %v %v : %v", id, action, op.State)
+
+	s.writeBody(w, r, op)
+}
+
+// authorized returns true if the request carries the admin token.
+//
+// No request is authorized if the token is not set.
+func (s AdminService) authorized(r *http.Request) bool {
+	if s.Token == "" {
+		return false
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+
+	got := strings.TrimPrefix(auth, "Bearer ")
+
+	return subtle.ConstantTimeCompare([]byte(got), []byte(s.Token)) == 1
+}
+
+// writeBody writes the body of a response as JSON.
+func (s AdminService) writeBody(w http.ResponseWriter,
+	r *http.Request,
+	body interface{}) {
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log := logger.NewLoggerFromContext(r.Context()).Sugar()
+		log.Errorf("Failed to write response : %v", err)
+	}
+}
+
+// writeError writes the HTTP status for an error.
+func (s AdminService) writeError(w http.ResponseWriter,
+	r *http.Request,
+	err error) {
+
+	if status := errs.StatusCode(err); status != http.StatusInternalServerError {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	log := logger.NewLoggerFromContext(r.Context()).Sugar()
+	log.Errorf("Failed to serve %v : %v", r.URL.Path, err)
+
+	http.Error(w, "Internal error", http.StatusInternalServerError)
+}
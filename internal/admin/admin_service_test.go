@@ -0,0 +1,632 @@
+package admin
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/internal/app/network"
+	"github.com/tokenized/smart-contract/internal/app/state"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/internal/document"
+	"github.com/tokenized/smart-contract/internal/feature"
+	"github.com/tokenized/smart-contract/internal/offline"
+	"github.com/tokenized/smart-contract/internal/operation"
+	"github.com/tokenized/smart-contract/internal/response"
+	"github.com/tokenized/smart-contract/pkg/storage"
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+	"github.com/tokenized/smart-contract/pkg/txscript"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// fakeNetwork records the TX's sent.
+type fakeNetwork struct {
+	network.NetworkInterface
+	sent []*wire.MsgTx
+}
+
+func (n *fakeNetwork) SendTX(ctx context.Context,
+	tx *wire.MsgTx) (*chainhash.Hash, error) {
+
+	n.sent = append(n.sent, tx)
+	hash := tx.TxHash()
+	return &hash, nil
+}
+
+// fakeIndexer counts the Events of each action.
+type fakeIndexer struct {
+	actions map[string]int
+}
+
+func (i *fakeIndexer) Index(ctx context.Context, e state.Event) error {
+	i.actions[e.Action]++
+	return nil
+}
+
+func TestAdminService_ServeHTTP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := storage.NewFilesystemStorage(storage.Config{
+		Root:   dir,
+		Bucket: "test",
+	})
+
+	contractID := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	holderAddr := "1HQ2ULuD7T5ykaucZ3KmTo4i29925Qa6ic"
+	features := feature.NewFeatureService(store)
+
+	operations := operation.NewOperationService()
+	op := operations.Start(context.Background(), operation.KindRescan, 10)
+	opID := op.Operation().ID
+
+	contracts := state.NewStateService(store)
+
+	c := contract.Contract{
+		ID: contractID,
+		Assets: map[string]contract.Asset{
+			"asset": contract.Asset{
+				ID: "asset",
+				Holdings: map[string]contract.Holding{
+					holderAddr: contract.NewHolding(holderAddr, 100),
+				},
+			},
+		},
+	}
+
+	if err := contracts.Write(context.Background(), c); err != nil {
+		t.Fatal(err)
+	}
+
+	indexer := &fakeIndexer{actions: map[string]int{}}
+	updater := response.NewResponseService(config.Config{}, contracts, indexer)
+
+	s := NewAdminService("secret", features, operations, updater,
+		offline.NewOfflineService(store, nil),
+		document.NewDocumentService(store))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		status int
+	}{
+		{
+			name:   "no token",
+			method: http.MethodGet,
+			path:   "/contracts/" + contractID + "/features",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "wrong token",
+			method: http.MethodGet,
+			path:   "/contracts/" + contractID + "/features",
+			token:  "guess",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "features",
+			method: http.MethodGet,
+			path:   "/contracts/" + contractID + "/features",
+			token:  "secret",
+			status: http.StatusOK,
+		},
+		{
+			name:   "set feature",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/features/fee_bump",
+			token:  "secret",
+			body:   `{"enabled": false}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "unknown feature",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/features/teleport",
+			token:  "secret",
+			body:   `{"enabled": true}`,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "missing enabled",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/features/fee_bump",
+			token:  "secret",
+			body:   `{}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "wrong method",
+			method: http.MethodDelete,
+			path:   "/contracts/" + contractID + "/features",
+			token:  "secret",
+			status: http.StatusMethodNotAllowed,
+		},
+		{
+			name:   "set transfer fee",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/assets/asset/transfer_fee",
+			token:  "secret",
+			body:   `{"value": 1000, "payees": [{"role": "issuer", "address": "` + contractID + `", "percent": 100}]}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "invalid transfer fee",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/assets/asset/transfer_fee",
+			token:  "secret",
+			body:   `{"value": 1000, "payees": [{"role": "issuer", "address": "` + contractID + `", "percent": 50}]}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "transfer fee of unknown asset",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/assets/missing/transfer_fee",
+			token:  "secret",
+			body:   `{"value": 0}`,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "set receiver approval",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/assets/asset/receiver_approval",
+			token:  "secret",
+			body:   `{"required": true, "timeout": 3600}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "missing receiver approval",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/assets/asset/receiver_approval",
+			token:  "secret",
+			body:   `{"timeout": 3600}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "receiver approval of unknown asset",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/assets/missing/receiver_approval",
+			token:  "secret",
+			body:   `{"required": true}`,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "set dust policy",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/dust_policy",
+			token:  "secret",
+			body:   `{"minimum_output": 1000, "handling": "absorb"}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "unknown dust handling",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/dust_policy",
+			token:  "secret",
+			body:   `{"minimum_output": 1000, "handling": "donate"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "dust policy of unknown contract",
+			method: http.MethodPut,
+			path:   "/contracts/missing/dust_policy",
+			token:  "secret",
+			body:   `{"handling": "reject"}`,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "set eligibility",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/eligibility",
+			token:  "secret",
+			body:   `{"minimum_holding": 10, "jurisdictions": ["AUS", "USA"]}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "invalid eligibility",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/eligibility",
+			token:  "secret",
+			body:   `{"jurisdictions": [""]}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "eligibility wrong method",
+			method: http.MethodGet,
+			path:   "/contracts/" + contractID + "/eligibility",
+			token:  "secret",
+			status: http.StatusMethodNotAllowed,
+		},
+		{
+			name:   "set holder jurisdiction",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/holders/" + holderAddr + "/jurisdiction",
+			token:  "secret",
+			body:   `{"jurisdiction": "AUS"}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "empty holder jurisdiction",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/holders/" + holderAddr + "/jurisdiction",
+			token:  "secret",
+			body:   `{"jurisdiction": ""}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "jurisdiction of unknown holder",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/holders/" + contractID + "/jurisdiction",
+			token:  "secret",
+			body:   `{"jurisdiction": "AUS"}`,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "attach document",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/assets/asset/documents/terms",
+			token:  "secret",
+			body:   "the terms of the asset",
+			status: http.StatusOK,
+		},
+		{
+			name:   "empty document",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/assets/asset/documents/terms",
+			token:  "secret",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "document of unknown asset",
+			method: http.MethodPut,
+			path:   "/contracts/" + contractID + "/assets/missing/documents/terms",
+			token:  "secret",
+			body:   "the terms of the asset",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "document wrong method",
+			method: http.MethodGet,
+			path:   "/contracts/" + contractID + "/assets/asset/documents/terms",
+			token:  "secret",
+			status: http.StatusMethodNotAllowed,
+		},
+		{
+			name:   "operations",
+			method: http.MethodGet,
+			path:   "/operations",
+			token:  "secret",
+			status: http.StatusOK,
+		},
+		{
+			name:   "unknown operation",
+			method: http.MethodGet,
+			path:   "/operations/missing",
+			token:  "secret",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "pause operation",
+			method: http.MethodPost,
+			path:   "/operations/" + opID + "/pause",
+			token:  "secret",
+			status: http.StatusOK,
+		},
+		{
+			name:   "cancel operation",
+			method: http.MethodPost,
+			path:   "/operations/" + opID + "/cancel",
+			token:  "secret",
+			status: http.StatusOK,
+		},
+		{
+			name:   "resume cancelled operation",
+			method: http.MethodPost,
+			path:   "/operations/" + opID + "/resume",
+			token:  "secret",
+			status: http.StatusConflict,
+		},
+		{
+			name:   "unknown control",
+			method: http.MethodPost,
+			path:   "/operations/" + opID + "/rewind",
+			token:  "secret",
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			w := httptest.NewRecorder()
+
+			s.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("got status %v, want %v", w.Code, tt.status)
+			}
+		})
+	}
+
+	if err := op.Step(context.Background(), "block"); err != operation.ErrCancelled {
+		t.Errorf("got step error %v, want %v", err, operation.ErrCancelled)
+	}
+
+	got, err := contracts.Read(context.Background(), contractID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fee := got.Assets["asset"].TransferFee; fee == nil || fee.Value != 1000 {
+		t.Errorf("got transfer fee %#+v, want 1000", fee)
+	}
+
+	if a := got.Assets["asset"]; !a.ReceiverApproval || a.ApprovalTimeout != int64(time.Hour) {
+		t.Errorf("got receiver approval %v timeout %v, want true %v",
+			a.ReceiverApproval, a.ApprovalTimeout, int64(time.Hour))
+	}
+
+	if p := got.DustPolicy; p.MinimumOutput != 1000 || p.Handling != contract.DustAbsorb {
+		t.Errorf("got dust policy %#+v, want 1000 absorbed", p)
+	}
+
+	if e := got.Eligibility; e.MinimumHolding != 10 || len(e.Jurisdictions) != 2 {
+		t.Errorf("got eligibility %#+v, want minimum 10 in 2 jurisdictions", e)
+	}
+
+	if j := got.HolderJurisdiction(holderAddr); j != "AUS" {
+		t.Errorf("got holder jurisdiction %q, want %q", j, "AUS")
+	}
+
+	d, ok := got.Assets["asset"].Documents["terms"]
+	if !ok || d.Size != len("the terms of the asset") {
+		t.Errorf("got document %#+v, want the terms", d)
+	}
+
+	if features.Enabled(context.Background(), contractID, feature.FlagFeeBump) {
+		t.Errorf("got fee bump enabled, want disabled")
+	}
+
+	// each accepted change is indexed once, and a rejected one not at all
+	wantIndexed := map[string]int{
+		ActionTransferFee:      1,
+		ActionReceiverApproval: 1,
+		ActionDustPolicy:       1,
+		ActionEligibility:      1,
+		ActionJurisdiction:     1,
+		ActionDocument:         1,
+	}
+
+	for action, want := range wantIndexed {
+		if got := indexer.actions[action]; got != want {
+			t.Errorf("got %v indexed %v, want %v", got, action, want)
+		}
+	}
+}
+
+func TestAdminService_unsigned(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := storage.NewFilesystemStorage(storage.Config{
+		Root:   dir,
+		Bucket: "test",
+	})
+
+	keys := []*btcec.PrivateKey{}
+	pubKeys := []*btcec.PublicKey{}
+
+	for i := 0; i < 3; i++ {
+		key, err := btcec.NewPrivateKey(btcec.S256())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		keys = append(keys, key)
+		pubKeys = append(pubKeys, key.PubKey())
+	}
+
+	// 2 of 3 multisig
+	redeemScript, address, err := txbuilder.NewMultiSigScript(pubKeys, 2,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	utxo := txbuilder.UTXO{
+		Hash:     chainhash.DoubleHashH([]byte("utxo")),
+		PkScript: pkScript,
+		Value:    10000,
+	}
+
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&utxo.Hash, utxo.Index), nil))
+	tx.AddTxOut(wire.NewTxOut(9000, pkScript))
+
+	net := &fakeNetwork{}
+	offlines := offline.NewOfflineService(store, net)
+
+	u, err := offlines.Export(ctx, "request", tx, txbuilder.UTXOs{utxo}, redeemScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewAdminService("secret",
+		feature.NewFeatureService(store),
+		operation.NewOperationService(),
+		nil,
+		offlines,
+		document.NewDocumentService(store))
+
+	// signatures posted for a key
+	signatures := func(key *btcec.PrivateKey, signer *btcec.PrivateKey) string {
+		sig, err := txbuilder.SignMultiSigInput(tx, 0, redeemScript, signer, utxo)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return fmt.Sprintf(`{"public_key": "%x", "signatures": ["%x"]}`,
+			key.PubKey().SerializeCompressed(), sig)
+	}
+
+	foreign, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{
+			name:   "pending",
+			method: http.MethodGet,
+			path:   "/unsigned",
+			status: http.StatusOK,
+		},
+		{
+			name:   "unsigned",
+			method: http.MethodGet,
+			path:   "/unsigned/" + u.ID,
+			status: http.StatusOK,
+		},
+		{
+			name:   "unknown unsigned",
+			method: http.MethodGet,
+			path:   "/unsigned/missing",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "foreign key",
+			method: http.MethodPost,
+			path:   "/unsigned/" + u.ID + "/signatures",
+			body:   signatures(foreign, foreign),
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "signature of another key",
+			method: http.MethodPost,
+			path:   "/unsigned/" + u.ID + "/signatures",
+			body:   signatures(keys[0], keys[1]),
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "not hex",
+			method: http.MethodPost,
+			path:   "/unsigned/" + u.ID + "/signatures",
+			body:   `{"public_key": "zz", "signatures": []}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "first signature",
+			method: http.MethodPost,
+			path:   "/unsigned/" + u.ID + "/signatures",
+			body:   signatures(keys[0], keys[0]),
+			status: http.StatusOK,
+		},
+		{
+			name:   "broadcast without enough signatures",
+			method: http.MethodPost,
+			path:   "/unsigned/" + u.ID + "/broadcast",
+			status: http.StatusConflict,
+		},
+		{
+			name:   "second signature",
+			method: http.MethodPost,
+			path:   "/unsigned/" + u.ID + "/signatures",
+			body:   signatures(keys[2], keys[2]),
+			status: http.StatusOK,
+		},
+		{
+			name:   "broadcast",
+			method: http.MethodPost,
+			path:   "/unsigned/" + u.ID + "/broadcast",
+			status: http.StatusOK,
+		},
+		{
+			name:   "broadcast again",
+			method: http.MethodPost,
+			path:   "/unsigned/" + u.ID + "/broadcast",
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.Header.Set("Authorization", "Bearer secret")
+
+			w := httptest.NewRecorder()
+
+			s.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("got status %v, want %v : %s", w.Code, tt.status, w.Body)
+			}
+
+			if tt.name != "broadcast" {
+				return
+			}
+
+			var result struct {
+				TxID string `json:"txid"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(net.sent) != 1 || result.TxID != net.sent[0].TxHash().String() {
+				t.Errorf("got txid %v, want the TX sent", result.TxID)
+			}
+		})
+	}
+
+	if len(net.sent) != 1 {
+		t.Fatalf("got %v TX's sent, want 1", len(net.sent))
+	}
+
+	pushes, err := txscript.PushedData(net.sent[0].TxIn[0].SignatureScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// OP_FALSE, 2 signatures and the redeem script
+	if len(pushes) != 4 || hex.EncodeToString(pushes[3]) != hex.EncodeToString(redeemScript) {
+		t.Errorf("got unlocking script pushes %x", pushes)
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LockTimeThreshold is the value below which an activation point is a block
+// height, and at or above which it is a median time, as with the lock time
+// of a TX.
+const LockTimeThreshold = 500000000
+
+// Activation schedules a change of protocol behaviour, identified by Rule,
+// from a point in the chain.
+//
+// Exactly one of Height and MedianTime is set.
+type Activation struct {
+	// Rule is the name of the behaviour being activated.
+	Rule string
+
+	// Height is the first block height the rule applies at.
+	Height int32
+
+	// MedianTime is the first median time past, in seconds since the Unix
+	// epoch, the rule applies at.
+	MedianTime int64
+}
+
+// parseActivations returns the Activation's held by a list of rule:point
+// pairs, such as "envelope-v2:620000,strict-fees:1577836800".
+//
+// Points below LockTimeThreshold are block heights, and the rest are median
+// times.
+func parseActivations(v string) ([]Activation, error) {
+	activations := []Activation{}
+
+	if v == "" {
+		return activations, nil
+	}
+
+	for _, pair := range strings.Split(v, ",") {
+		parts := strings.Split(strings.TrimSpace(pair), ":")
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Invalid activation %q", pair)
+		}
+
+		point, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || point < 0 {
+			return nil, fmt.Errorf("Invalid activation point %q", pair)
+		}
+
+		a := Activation{
+			Rule: parts[0],
+		}
+
+		if point < LockTimeThreshold {
+			a.Height = int32(point)
+		} else {
+			a.MedianTime = point
+		}
+
+		activations = append(activations, a)
+	}
+
+	return activations, nil
+}
@@ -14,6 +14,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcutil"
@@ -24,6 +25,10 @@ type Config struct {
 	ContractProviderID string
 	Version            string
 	Fee                Fee
+	SLA                SLA
+	FeeBump            FeeBump
+	Activations        []Activation
+	Spool              Spool
 }
 
 // NewConfig returns a new Config populated from environment variables.
@@ -55,6 +60,44 @@ func NewConfig() (*Config, error) {
 		return nil, errors.New("Fee is set to 0 sats")
 	}
 
+	// Latency SLA's, in milliseconds
+	if c.SLA.Broadcast, err = parseMilliseconds("SLA_BROADCAST"); err != nil {
+		return nil, err
+	}
+
+	if c.SLA.Confirmation, err = parseMilliseconds("SLA_CONFIRMATION"); err != nil {
+		return nil, err
+	}
+
+	// Fee bumping of unconfirmed responses
+	if c.FeeBump.After, err = parseMilliseconds("FEE_BUMP_AFTER"); err != nil {
+		return nil, err
+	}
+
+	if v := os.Getenv("FEE_BUMP_INCREASE"); v != "" {
+		increase, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		c.FeeBump.Increase = increase
+	}
+
+	// Spooling of requests while storage is unavailable
+	c.Spool.Root = os.Getenv("SPOOL_ROOT")
+	c.Spool.Limit = defaultSpoolLimit
+
+	if v := os.Getenv("SPOOL_LIMIT"); v != "" {
+		if c.Spool.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("Invalid SPOOL_LIMIT : %v", err)
+		}
+	}
+
+	// Rules scheduled to activate at a height or median time
+	if c.Activations, err = parseActivations(os.Getenv("ACTIVATIONS")); err != nil {
+		return nil, err
+	}
+
 	return &c, nil
 }
 
@@ -66,6 +109,10 @@ func (c Config) String() string {
 		"ContractProviderID": c.ContractProviderID,
 		"Version":            c.Version,
 		"Fee":                fmt.Sprintf("%+v", c.Fee),
+		"SLA":                fmt.Sprintf("%+v", c.SLA),
+		"FeeBump":            fmt.Sprintf("%+v", c.FeeBump),
+		"Activations":        fmt.Sprintf("%+v", c.Activations),
+		"Spool":              fmt.Sprintf("%+v", c.Spool),
 	}
 
 	parts := []string{}
@@ -76,3 +123,19 @@ func (c Config) String() string {
 
 	return fmt.Sprintf("{%v}", strings.Join(parts, " "))
 }
+
+// parseMilliseconds returns the duration in milliseconds held by an
+// environment variable. An unset variable is a zero duration.
+func parseMilliseconds(key string) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return 0, nil
+	}
+
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid %v : %v", key, err)
+	}
+
+	return time.Duration(ms) * time.Millisecond, nil
+}
@@ -0,0 +1,19 @@
+package config
+
+import (
+	"time"
+)
+
+// FeeBump holds the settings for increasing the fee of responses that have
+// not been confirmed.
+//
+// A zero After disables fee bumping.
+type FeeBump struct {
+	// After is how long a response may remain unconfirmed before its fee
+	// is increased, and again after each increase.
+	After time.Duration
+
+	// Increase is the fee, in satoshis, paid by each child TX. It covers
+	// the size of the child as well as the increase for the response.
+	Increase uint64
+}
@@ -0,0 +1,18 @@
+package config
+
+import (
+	"time"
+)
+
+// SLA holds the maximum latencies expected for responding to requests.
+//
+// A zero value disables the SLA.
+type SLA struct {
+	// Broadcast is the time from first observing a request to broadcasting
+	// the response.
+	Broadcast time.Duration
+
+	// Confirmation is the time from first observing a request to the
+	// response being confirmed in a block.
+	Confirmation time.Duration
+}
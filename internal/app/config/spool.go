@@ -0,0 +1,17 @@
+package config
+
+// defaultSpoolLimit is the number of requests spooled if no limit is set.
+const defaultSpoolLimit = 10000
+
+// Spool holds the settings for spooling requests to local disk while the
+// contract storage is unavailable.
+//
+// An empty Root disables spooling.
+type Spool struct {
+	// Root is the directory spooled requests are written to.
+	Root string
+
+	// Limit is the number of requests the spool holds. Requests received
+	// when the spool is full are dropped.
+	Limit int
+}
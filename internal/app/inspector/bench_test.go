@@ -0,0 +1,80 @@
+package inspector
+
+import (
+	"testing"
+
+	"github.com/tokenized/smart-contract/pkg/protocol"
+	"github.com/tokenized/smart-contract/pkg/spvnode"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+)
+
+// newBenchTx returns a TX paying the contract with a Send payload.
+func newBenchTx(b *testing.B) *wire.MsgTx {
+	contractAddress, err := btcutil.DecodeAddress(
+		"1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv", &chaincfg.MainNetParams)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	script, err := txscript.PayToAddrScript(contractAddress)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	m := protocol.NewSend()
+	m.AssetType = []byte("SHC")
+	m.AssetID = []byte("w840mxhrhupngqthd9quwtgsocaonv2f")
+	m.TokenQty = 1
+
+	payload, err := m.Bytes()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	tx := wire.NewMsgTx(2)
+	tx.AddTxOut(wire.NewTxOut(1000, script))
+	tx.AddTxOut(wire.NewTxOut(0, payload))
+
+	return tx
+}
+
+// BenchmarkMakeTransaction performs a benchmark on how long it takes to
+// parse the Tokenized message and outputs of a TX.
+func BenchmarkMakeTransaction(b *testing.B) {
+	tx := newBenchTx(b)
+	s := NewInspectorService(nil)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := s.MakeTransaction(tx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkMakeTransactionFiltered performs a benchmark on how long it takes
+// to parse a TX when it has to be matched by a filter first.
+func BenchmarkMakeTransactionFiltered(b *testing.B) {
+	tx := newBenchTx(b)
+
+	m, err := spvnode.CompileMatcher(
+		"p2pkh(1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv) and op_return(T1)")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	s := NewInspectorService(nil, m)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := s.MakeTransaction(tx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package inspector
+
+import (
+	"github.com/btcsuite/btcutil"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+)
+
+var (
+	// ErrNoFeeSchedule is returned when the fee schedule has no fee for the
+	// action of a TX.
+	ErrNoFeeSchedule = errs.New(errs.Invalid, "No fee for action")
+
+	// ErrInsufficientFee is returned when a TX pays less than the fee
+	// required for its action.
+	ErrInsufficientFee = errs.New(errs.Invalid, "Insufficient fee paid")
+)
+
+// FeePaid returns the value a TX pays to the fee address, checking it is at
+// least the fee the schedule requires for the action of the TX.
+//
+// The schedule holds the fee of each action, keyed by action code, such as
+// protocol.Minimum. The value paid is returned with ErrInsufficientFee if it
+// is below the fee.
+func FeePaid(itx *Transaction,
+	address btcutil.Address,
+	schedule map[string]uint64) (uint64, error) {
+
+	required, ok := schedule[itx.MsgProto.Type()]
+	if !ok {
+		return 0, ErrNoFeeSchedule
+	}
+
+	utxos, err := itx.UTXOs.ForAddress(address)
+	if err != nil {
+		return 0, err
+	}
+
+	paid := utxos.Value()
+
+	if paid < required {
+		return paid, ErrInsufficientFee
+	}
+
+	return paid, nil
+}
@@ -0,0 +1,107 @@
+package inspector
+
+import (
+	"testing"
+
+	"github.com/tokenized/smart-contract/pkg/protocol"
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+)
+
+func TestFeePaid(t *testing.T) {
+	contractAddress, err := btcutil.DecodeAddress(
+		"1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv", &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherAddress, err := btcutil.DecodeAddress(
+		"13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg", &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newUTXO := func(address btcutil.Address, value uint64) txbuilder.UTXO {
+		script, err := txscript.PayToAddrScript(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return txbuilder.UTXO{
+			PkScript: script,
+			Value:    value,
+		}
+	}
+
+	m := protocol.NewSend()
+
+	schedule := map[string]uint64{
+		protocol.CodeSend: 1000,
+	}
+
+	tests := []struct {
+		name     string
+		utxos    txbuilder.UTXOs
+		schedule map[string]uint64
+		want     uint64
+		err      error
+	}{
+		{
+			name: "paid",
+			utxos: txbuilder.UTXOs{
+				newUTXO(contractAddress, 600),
+				newUTXO(otherAddress, 5000),
+				newUTXO(contractAddress, 600),
+			},
+			schedule: schedule,
+			want:     1200,
+		},
+		{
+			name: "insufficient",
+			utxos: txbuilder.UTXOs{
+				newUTXO(contractAddress, 999),
+				newUTXO(otherAddress, 5000),
+			},
+			schedule: schedule,
+			want:     999,
+			err:      ErrInsufficientFee,
+		},
+		{
+			name: "not paid",
+			utxos: txbuilder.UTXOs{
+				newUTXO(otherAddress, 5000),
+			},
+			schedule: schedule,
+			err:      ErrInsufficientFee,
+		},
+		{
+			name: "no fee for action",
+			utxos: txbuilder.UTXOs{
+				newUTXO(contractAddress, 5000),
+			},
+			schedule: map[string]uint64{},
+			err:      ErrNoFeeSchedule,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			itx := Transaction{
+				UTXOs:    tt.utxos,
+				MsgProto: &m,
+			}
+
+			got, err := FeePaid(&itx, contractAddress, tt.schedule)
+			if err != tt.err {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -11,10 +11,11 @@ package inspector
 
 import (
 	"bytes"
-	"errors"
 
 	"github.com/tokenized/smart-contract/internal/app/network"
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/protocol"
+	"github.com/tokenized/smart-contract/pkg/spvnode"
 	"github.com/tokenized/smart-contract/pkg/txbuilder"
 	"github.com/tokenized/smart-contract/pkg/wire"
 
@@ -29,14 +30,20 @@ var (
 type InspectorService struct {
 	Network network.NetworkInterface
 	Builder txbuilder.UTXOSetBuilder
+	Filters []spvnode.TxFilter
 }
 
-func NewInspectorService(network network.NetworkInterface) InspectorService {
+// NewInspectorService returns a new InspectorService. If any filters are
+// given, only the transactions relevant to one of them are inspected.
+func NewInspectorService(network network.NetworkInterface,
+	filters ...spvnode.TxFilter) InspectorService {
+
 	builder := txbuilder.NewUTXOSetBuilder(network)
 
 	return InspectorService{
 		Network: network,
 		Builder: builder,
+		Filters: filters,
 	}
 }
 
@@ -47,9 +54,10 @@ func (s InspectorService) CreateTransaction(inputs txbuilder.UTXOs,
 	msg protocol.OpReturnMessage) *Transaction {
 
 	t := &Transaction{
-		Inputs:   inputs,
-		Outputs:  outputs,
-		MsgProto: msg,
+		Inputs:      inputs,
+		Outputs:     outputs,
+		MsgProto:    msg,
+		ChangeIndex: -1,
 	}
 
 	return t
@@ -58,6 +66,10 @@ func (s InspectorService) CreateTransaction(inputs txbuilder.UTXOs,
 // Primary purpose of this service. Does the supplied transaction concern us?
 //
 func (s InspectorService) MakeTransaction(tx *wire.MsgTx) (*Transaction, error) {
+	if !s.isRelevant(tx) {
+		return nil, nil
+	}
+
 	msg, err := s.findTokenizedProtocol(tx)
 	if err != nil {
 		return nil, err
@@ -111,6 +123,22 @@ func (s InspectorService) PromoteTransaction(tx *Transaction) (*Transaction, err
 	return tx, nil
 }
 
+// isRelevant returns true if the transaction is relevant to any of the
+// filters, or there are no filters.
+func (s InspectorService) isRelevant(tx *wire.MsgTx) bool {
+	if len(s.Filters) == 0 {
+		return true
+	}
+
+	for _, f := range s.Filters {
+		if f.IsRelevant(tx) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (s InspectorService) getOutputs(tx *wire.MsgTx) ([]txbuilder.TxOutput, error) {
 	outputs := []txbuilder.TxOutput{}
 
@@ -165,7 +193,7 @@ func (s InspectorService) findTokenizedProtocol(tx *wire.MsgTx) (protocol.OpRetu
 // Returns a Tokenized protocol instance
 func (s InspectorService) newProtocolMessage(txOut *wire.TxOut) (protocol.OpReturnMessage, error) {
 	if txOut.PkScript[0] != txscript.OP_RETURN {
-		return nil, errors.New("Payload is not an OP_RETURN")
+		return nil, errs.New(errs.Invalid, "Payload is not an OP_RETURN")
 	}
 
 	return protocol.New(txOut.PkScript)
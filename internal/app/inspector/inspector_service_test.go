@@ -0,0 +1,94 @@
+package inspector
+
+import (
+	"testing"
+
+	"github.com/tokenized/smart-contract/pkg/protocol"
+	"github.com/tokenized/smart-contract/pkg/spvnode"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+)
+
+func TestMakeTransactionFilters(t *testing.T) {
+	contractAddress, err := btcutil.DecodeAddress(
+		"1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv", &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := txscript.PayToAddrScript(contractAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := protocol.NewSend()
+
+	payload, err := m.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := wire.NewMsgTx(2)
+	tx.AddTxOut(wire.NewTxOut(1000, script))
+	tx.AddTxOut(wire.NewTxOut(0, payload))
+
+	newMatcher := func(rule string) spvnode.TxFilter {
+		m, err := spvnode.CompileMatcher(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return m
+	}
+
+	tests := []struct {
+		name    string
+		filters []spvnode.TxFilter
+		want    bool
+	}{
+		{
+			name: "no filters",
+			want: true,
+		},
+		{
+			name: "matched",
+			filters: []spvnode.TxFilter{
+				newMatcher("p2pkh(1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv) and op_return(T1)"),
+			},
+			want: true,
+		},
+		{
+			name: "other action",
+			filters: []spvnode.TxFilter{
+				newMatcher("p2pkh(1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv) and op_return(C1, A1)"),
+			},
+			want: false,
+		},
+		{
+			name: "any filter",
+			filters: []spvnode.TxFilter{
+				newMatcher("p2pkh(13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg)"),
+				newMatcher("not op_return(C1) or spends(13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg)"),
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewInspectorService(nil, tt.filters...)
+
+			itx, err := s.MakeTransaction(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := itx != nil; got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package inspector
 
 import (
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
 	"github.com/tokenized/smart-contract/pkg/protocol"
 	"github.com/tokenized/smart-contract/pkg/txbuilder"
 	"github.com/tokenized/smart-contract/pkg/wire"
@@ -16,4 +17,15 @@ type Transaction struct {
 	Outputs    []txbuilder.TxOutput
 	MsgTx      *wire.MsgTx
 	MsgProto   protocol.OpReturnMessage
+
+	// ChangeIndex is the index of the change output of a TX built by the
+	// contract, or -1 if there is no change.
+	ChangeIndex int
+
+	// Escrow is a transfer that a response holds until the receiver
+	// accepts it, and Release the ID of a held transfer that a response
+	// settles. They are applied to the Contract when the response is
+	// processed.
+	Escrow  *contract.PendingTransfer
+	Release string
 }
@@ -3,9 +3,15 @@ package network
 import (
 	"context"
 
+	"github.com/tokenized/smart-contract/pkg/spvnode"
 	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 )
 
 type Listener interface {
 	Handle(context.Context, wire.Message) error
+	HandleReorg(context.Context, spvnode.Reorg) error
+	HandleExpired(context.Context, []chainhash.Hash) error
+	HandleDoubleSpend(context.Context, spvnode.DoubleSpend) error
 }
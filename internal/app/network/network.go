@@ -2,7 +2,9 @@ package network
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/tokenized/smart-contract/internal/app/logger"
 	"github.com/tokenized/smart-contract/internal/app/rpcnode"
 	"github.com/tokenized/smart-contract/pkg/spvnode"
 	"github.com/tokenized/smart-contract/pkg/wire"
@@ -56,16 +58,54 @@ func (n Network) Start() error {
 	return n.TrustedNode.PeerNode.Start()
 }
 
+func (n Network) Stop() {
+	n.TrustedNode.PeerNode.Stop()
+}
+
 //
 // RPC Node proxies
 //
 
+// GetTX returns the TX from the peer node if it has it, or can get it from
+// its peers, and otherwise from the RPC node.
 func (n Network) GetTX(ctx context.Context, id *chainhash.Hash) (*wire.MsgTx, error) {
+	tx, err := n.TrustedNode.PeerNode.GetTX(ctx, id)
+	if err == nil {
+		return tx, nil
+	}
+
 	return n.TrustedNode.RpcNode.GetTX(ctx, id)
 }
 
+// SendTX sends the TX through the RPC node, and keeps it to be rebroadcast
+// by the peer node until it is confirmed.
 func (n Network) SendTX(ctx context.Context, tx *wire.MsgTx) (*chainhash.Hash, error) {
-	return n.TrustedNode.RpcNode.SendTX(ctx, tx)
+	hash, err := n.TrustedNode.RpcNode.SendTX(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := n.TrustedNode.PeerNode.Rebroadcast(tx); err != nil {
+		log := logger.NewLoggerFromContext(ctx).Sugar()
+		log.Warnf("TX %v won't be rebroadcast : %v", hash, err)
+	}
+
+	return hash, nil
+}
+
+// OutputValue returns the value of an output, from the TX fetched from the
+// RPC node.
+func (n Network) OutputValue(ctx context.Context, out wire.OutPoint) (int64, error) {
+	tx, err := n.TrustedNode.RpcNode.GetTX(ctx, &out.Hash)
+	if err != nil {
+		return 0, err
+	}
+
+	if int(out.Index) >= len(tx.TxOut) {
+		return 0, fmt.Errorf("Output %v not found", out)
+	}
+
+	return tx.TxOut[out.Index].Value, nil
 }
 
 func (n Network) ListTransactions(ctx context.Context, address btcutil.Address) ([]btcjson.ListTransactionsResult, error) {
@@ -12,6 +12,7 @@ import (
 
 type NetworkInterface interface {
 	Start() error
+	Stop()
 	RegisterTxListener(Listener)
 	RegisterBlockListener(Listener)
 	GetTX(context.Context, *chainhash.Hash) (*wire.MsgTx, error)
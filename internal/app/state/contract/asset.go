@@ -7,19 +7,23 @@ import (
 )
 
 type Asset struct {
-	ID                 string             `json:"id"`
-	Type               string             `json:"type"`
-	Revision           uint16             `json:"revision"`
-	AuthorizationFlags []byte             `json:"auth_flags"`
-	VotingSystem       byte               `json:"voting_system"`
-	VoteMultiplier     uint8              `json:"vote_multiplier"`
-	Qty                uint64             `json:"qty"`
-	TxnFeeType         byte               `json:"txn_fee_type"`
-	TxnFeeCurrency     string             `json:"txn_fee_currency"`
-	TxnFeeVar          float32            `json:"txn_fee_var,omitempty"`
-	TxnFeeFixed        float32            `json:"txn_fee_fixed,omitempty"`
-	Holdings           map[string]Holding `json:"holdings"`
-	CreatedAt          int64              `json:"created_at"`
+	ID                 string              `json:"id"`
+	Type               string              `json:"type"`
+	Revision           uint16              `json:"revision"`
+	AuthorizationFlags []byte              `json:"auth_flags"`
+	VotingSystem       byte                `json:"voting_system"`
+	VoteMultiplier     uint8               `json:"vote_multiplier"`
+	Qty                uint64              `json:"qty"`
+	TxnFeeType         byte                `json:"txn_fee_type"`
+	TxnFeeCurrency     string              `json:"txn_fee_currency"`
+	TxnFeeVar          float32             `json:"txn_fee_var,omitempty"`
+	TxnFeeFixed        float32             `json:"txn_fee_fixed,omitempty"`
+	TransferFee        *TransferFee        `json:"transfer_fee,omitempty"`
+	Holdings           map[string]Holding  `json:"holdings"`
+	Documents          map[string]Document `json:"documents,omitempty"`
+	ReceiverApproval   bool                `json:"receiver_approval,omitempty"`
+	ApprovalTimeout    int64               `json:"approval_timeout,omitempty"`
+	CreatedAt          int64               `json:"created_at"`
 }
 
 func NewAsset(am *protocol.AssetCreation, holding Holding) Asset {
@@ -14,6 +14,13 @@ type Ballot struct {
 	VoteTxnID string `json:"vote_txn_id"`
 	Vote      []byte `json:"vote"`
 	CreatedAt int64  `json:"created_at"`
+
+	// Commitment is the hex encoded commitment of a ballot on a
+	// commit-reveal Vote, cast while the vote is open.
+	Commitment string `json:"commitment,omitempty"`
+
+	// Salt is the hex encoded salt of a ballot that reveals a commitment.
+	Salt string `json:"salt,omitempty"`
 }
 
 func NewBallotFromBallotCast(address btcutil.Address,
@@ -0,0 +1,170 @@
+package contract
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/protocol"
+
+	"github.com/btcsuite/btcutil"
+)
+
+const (
+	// CommitmentSize is the size of a commitment, which is a truncated hash
+	// so that it fits in the Vote of a BallotCast.
+	CommitmentSize = 16
+
+	// SaltSize is the size of the salt that follows the choices of a
+	// revealed ballot. As the Vote of a BallotCast is trimmed of trailing
+	// zeros, the last byte of the salt must not be zero.
+	SaltSize = 8
+)
+
+// CommitReveal holds the ballots of a Vote secret until the vote closes.
+//
+// While the vote is open, each ballot commits to its choices by sending a
+// hash of them. Once the vote closes, each voter reveals their choices,
+// which are only counted if they match the commitment.
+//
+// The protocol has no field for this, so it is carried as a JSON object in
+// the ProposalDescription, such as
+//
+//	{"commit_reveal": {"reveal_period": 86400}}
+type CommitReveal struct {
+	// RevealPeriod is the number of seconds after the vote closes that
+	// ballots can be revealed.
+	RevealPeriod int64 `json:"reveal_period"`
+}
+
+// ParseCommitReveal returns the CommitReveal held by the description of a
+// Vote, or nil if the Vote does not use commit-reveal ballots.
+func ParseCommitReveal(description []byte) *CommitReveal {
+	d := struct {
+		CommitReveal *CommitReveal `json:"commit_reveal"`
+	}{}
+
+	if err := json.Unmarshal(description, &d); err != nil {
+		return nil
+	}
+
+	if d.CommitReveal == nil || d.CommitReveal.RevealPeriod <= 0 {
+		return nil
+	}
+
+	return d.CommitReveal
+}
+
+// NewCommitment returns the commitment of a voter to the choices, hidden
+// by the salt.
+//
+// The address is part of the commitment, so a voter can't copy the
+// commitment of another and reveal it once the other has. The commitment
+// is trimmed of trailing zeros, as it is when read from a BallotCast.
+func NewCommitment(address string, choices []byte, salt []byte) string {
+	h := sha256.New()
+	h.Write([]byte(address))
+	h.Write(choices)
+	h.Write(salt)
+
+	b := bytes.TrimRight(h.Sum(nil)[:CommitmentSize], "\x00")
+
+	return hex.EncodeToString(b)
+}
+
+// NewBallot returns the Ballot cast by the BallotCast at the time.
+//
+// A BallotCast on a commit-reveal Vote holds the commitment while the vote
+// is open, and the choices followed by the salt once it has closed. Both
+// must fit in the 16 bytes of the Vote, so up to 8 choices can be revealed.
+func (v Vote) NewBallot(address btcutil.Address,
+	m *protocol.BallotCast,
+	ts time.Time) Ballot {
+
+	b := NewBallotFromBallotCast(address, m)
+
+	if v.CommitReveal == nil {
+		return b
+	}
+
+	if v.IsOpen(ts) {
+		b.Commitment = hex.EncodeToString(m.Vote)
+		b.Vote = []byte{}
+
+		return b
+	}
+
+	if len(m.Vote) <= SaltSize {
+		b.Vote = []byte{}
+		return b
+	}
+
+	split := len(m.Vote) - SaltSize
+	b.Vote = m.Vote[:split]
+	b.Salt = hex.EncodeToString(m.Vote[split:])
+
+	return b
+}
+
+// RevealCutOff returns the time ballots must be revealed by, in
+// nanoseconds. It is the cut off time of a Vote without commit-reveal
+// ballots.
+func (v Vote) RevealCutOff() int64 {
+	if v.CommitReveal == nil {
+		return v.VoteCutOffTimestamp
+	}
+
+	return v.VoteCutOffTimestamp + v.CommitReveal.RevealPeriod*int64(time.Second)
+}
+
+// IsRevealing returns true if the Vote has closed, but ballots can still be
+// revealed.
+func (v Vote) IsRevealing(ts time.Time) bool {
+	return !v.IsOpen(ts) && ts.UnixNano() < v.RevealCutOff()
+}
+
+// Commitment returns the latest commitment of the voter, or an empty string
+// if the voter made none.
+func (v Vote) Commitment(address string) string {
+	for i := len(v.Ballots) - 1; i >= 0; i-- {
+		b := v.Ballots[i]
+
+		if b.Address == address && b.Commitment != "" {
+			return b.Commitment
+		}
+	}
+
+	return ""
+}
+
+// Reveals returns true if the Ballot reveals the choices of the latest
+// commitment of the voter.
+func (v Vote) Reveals(b Ballot) bool {
+	if b.Salt == "" {
+		return false
+	}
+
+	commitment := v.Commitment(b.Address)
+	if commitment == "" {
+		return false
+	}
+
+	salt, err := hex.DecodeString(b.Salt)
+	if err != nil {
+		return false
+	}
+
+	return NewCommitment(b.Address, b.Vote, salt) == commitment
+}
+
+// Countable returns true if the choices of the Ballot can be counted. On a
+// commit-reveal Vote, only ballots that reveal a commitment are counted.
+func (v Vote) Countable(b Ballot) bool {
+	if v.CommitReveal == nil {
+		return true
+	}
+
+	return v.Reveals(b)
+}
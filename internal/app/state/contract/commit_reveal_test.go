@@ -0,0 +1,158 @@
+package contract
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+func TestParseCommitReveal(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		want        *CommitReveal
+	}{
+		{
+			name:        "commit reveal",
+			description: `{"commit_reveal": {"reveal_period": 3600}}`,
+			want:        &CommitReveal{RevealPeriod: 3600},
+		},
+		{
+			name:        "no reveal period",
+			description: `{"commit_reveal": {}}`,
+		},
+		{
+			name:        "plain text",
+			description: "Change the name of the contract",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseCommitReveal([]byte(tt.description))
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%#+v\nwant\n%#+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVote_commitReveal(t *testing.T) {
+	contractAddr := "1CWjudGPuj1sHs3GuMkAGPEUP5YaJNqu8U"
+	userAddr := "1L9Vr7BCEeczDtSJiX3fHLG5VVQgHtB22o"
+	otherAddr := "1HwvXtVEMDuvbrNHQCwWaV97ucBLr3zCgJ"
+	assetID := "1v2mwouuzz2x73ulv6o57llbx5udym6l"
+
+	c := Contract{
+		ID: contractAddr,
+		Assets: map[string]Asset{
+			assetID: Asset{
+				ID: assetID,
+				Holdings: map[string]Holding{
+					userAddr: Holding{
+						Address: userAddr,
+						Balance: 10,
+					},
+					otherAddr: Holding{
+						Address: otherAddr,
+						Balance: 10,
+					},
+				},
+			},
+		},
+	}
+
+	choices := []byte{65}
+	salt := []byte("01234567")
+	commitment := NewCommitment(userAddr, choices, salt)
+
+	now := time.Now()
+
+	vo := Vote{
+		AssetID:             assetID,
+		VoteCutOffTimestamp: now.Add(time.Hour).UnixNano(),
+		CommitReveal:        &CommitReveal{RevealPeriod: 3600},
+	}
+
+	hash, err := hex.DecodeString(commitment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the ballots are read back from the protocol, which pads and trims
+	// the Vote
+	commit := roundTrip(t, protocol.BallotCast{
+		AssetID: []byte(assetID),
+		Vote:    hash,
+	})
+
+	reveal := roundTrip(t, protocol.BallotCast{
+		AssetID: []byte(assetID),
+		Vote:    append(append([]byte{}, choices...), salt...),
+	})
+
+	// while open, the ballot is a commitment
+	b := vo.NewBallot(decodeAddress(userAddr), &commit, now)
+	if b.Commitment != commitment || len(b.Vote) != 0 {
+		t.Fatalf("got commitment %v vote %v, want %v", b.Commitment, b.Vote, commitment)
+	}
+
+	if code := c.CanVote(vo, b); code != protocol.RejectionCodeOK {
+		t.Fatalf("got %v, want commitment accepted", code)
+	}
+
+	vo.Ballots = append(vo.Ballots, b)
+
+	// once closed, the ballot reveals the choices
+	closed := now.Add(90 * time.Minute)
+	if !vo.IsRevealing(closed) {
+		t.Fatal("want vote revealing")
+	}
+
+	b = vo.NewBallot(decodeAddress(userAddr), &reveal, closed)
+	if !reflect.DeepEqual(b.Vote, []byte{65}) {
+		t.Fatalf("got vote %v, want revealed choices", b.Vote)
+	}
+
+	if !vo.Reveals(b) {
+		t.Error("want ballot to reveal the commitment")
+	}
+
+	// another voter can't reveal the commitment
+	other := vo.NewBallot(decodeAddress(otherAddr), &reveal, closed)
+	if vo.Reveals(other) {
+		t.Error("want ballot of another voter not to reveal the commitment")
+	}
+
+	// a reveal of other choices does not match
+	b.Vote = []byte{66}
+	if vo.Reveals(b) {
+		t.Error("want other choices not to reveal the commitment")
+	}
+
+	if vo.IsRevealing(now.Add(3 * time.Hour)) {
+		t.Error("want reveal period over")
+	}
+}
+
+// roundTrip returns the BallotCast as read back from its encoding.
+func roundTrip(t *testing.T, m protocol.BallotCast) protocol.BallotCast {
+	m.Header = []byte{0x6a, 0x4c, 0x5a}
+	m.ActionPrefix = []byte(protocol.CodeBallotCast)
+
+	b, err := m.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := protocol.BallotCast{}
+	if _, err := got.Write(b); err != nil {
+		t.Fatal(err)
+	}
+
+	return got
+}
@@ -15,28 +15,33 @@ import (
 
 // Contract represents a Smart Contract.
 type Contract struct {
-	ID                          string           `json:"id"`
-	CreatedAt                   int64            `json:"created_at"`
-	IssuerAddress               string           `json:"issuer_address"`
-	OperatorAddress             string           `json:"operator_address"`
-	Revision                    uint16           `json:"revision"`
-	ContractName                string           `json:"name"`
-	ContractFileHash            string           `json:"hash"`
-	GoverningLaw                string           `json:"law"`
-	Jurisdiction                string           `json:"jurisdiction"`
-	ContractExpiration          uint64           `json:"contract_expiration"`
-	URI                         string           `json:"uri"`
-	IssuerID                    string           `json:"issuer_id"`
-	IssuerType                  string           `json:"issuer_type"`
-	ContractOperatorID          string           `json:"tokenizer_id"`
-	AuthorizationFlags          []byte           `json:"authorization_flags"`
-	VotingSystem                string           `json:"voting_system"`
-	InitiativeThreshold         float32          `json:"initiative_threshold"`
-	InitiativeThresholdCurrency string           `json:"initiative_threshold_currency"`
-	Qty                         uint64           `json:"qty"`
-	Assets                      map[string]Asset `json:"assets"`
-	Votes                       map[string]Vote  `json:"votes"`
-	Hashes                      []string         `json:"hashes"`
+	ID                          string                     `json:"id"`
+	CreatedAt                   int64                      `json:"created_at"`
+	IssuerAddress               string                     `json:"issuer_address"`
+	OperatorAddress             string                     `json:"operator_address"`
+	Revision                    uint16                     `json:"revision"`
+	ContractName                string                     `json:"name"`
+	ContractFileHash            string                     `json:"hash"`
+	GoverningLaw                string                     `json:"law"`
+	Jurisdiction                string                     `json:"jurisdiction"`
+	ContractExpiration          uint64                     `json:"contract_expiration"`
+	URI                         string                     `json:"uri"`
+	IssuerID                    string                     `json:"issuer_id"`
+	IssuerType                  string                     `json:"issuer_type"`
+	ContractOperatorID          string                     `json:"tokenizer_id"`
+	AuthorizationFlags          []byte                     `json:"authorization_flags"`
+	VotingSystem                string                     `json:"voting_system"`
+	TieBreak                    string                     `json:"tie_break"`
+	AbstentionsInQuorum         bool                       `json:"abstentions_in_quorum"`
+	DustPolicy                  DustPolicy                 `json:"dust_policy"`
+	Eligibility                 Eligibility                `json:"eligibility"`
+	InitiativeThreshold         float32                    `json:"initiative_threshold"`
+	InitiativeThresholdCurrency string                     `json:"initiative_threshold_currency"`
+	Qty                         uint64                     `json:"qty"`
+	Assets                      map[string]Asset           `json:"assets"`
+	Votes                       map[string]Vote            `json:"votes"`
+	PendingTransfers            map[string]PendingTransfer `json:"pending_transfers,omitempty"`
+	Hashes                      []string                   `json:"hashes"`
 }
 
 // NewContract returns a new Contract. Must come from an Offer because
@@ -139,12 +144,145 @@ func (c Contract) IsOwner(address string) bool {
 	return false
 }
 
+// HolderJurisdiction returns the jurisdiction recorded for the holder of
+// the address, or "" if none is recorded.
+func (c Contract) HolderJurisdiction(address string) string {
+	for _, asset := range c.Assets {
+		if holding, ok := asset.Holdings[address]; ok && holding.Jurisdiction != "" {
+			return holding.Jurisdiction
+		}
+	}
+
+	return ""
+}
+
+// SetHolderJurisdiction records the jurisdiction of the holder of the
+// address on each of their holdings, returning false if the address holds
+// none of the assets of the Contract.
+func (c *Contract) SetHolderJurisdiction(address, jurisdiction string) bool {
+	found := false
+
+	for id, asset := range c.Assets {
+		holding, ok := asset.Holdings[address]
+		if !ok {
+			continue
+		}
+
+		holding.Jurisdiction = jurisdiction
+		asset.Holdings[address] = holding
+		c.Assets[id] = asset
+
+		found = true
+	}
+
+	return found
+}
+
+// IsOwnerOf returns true if the address holds any of the assets, false
+// otherwise. A nil list of assets matches all assets of the Contract.
+func (c Contract) IsOwnerOf(address string, assetIDs []string) bool {
+	return c.HoldingsOf(address, assetIDs) > 0
+}
+
+// HoldingsOf returns the balance held by the address, aggregated across the
+// assets. A nil list of assets matches all assets of the Contract.
+func (c Contract) HoldingsOf(address string, assetIDs []string) uint64 {
+	balance := uint64(0)
+
+	for _, asset := range c.scopedAssets(assetIDs) {
+		if holding, ok := asset.Holdings[address]; ok {
+			balance += holding.Balance
+		}
+	}
+
+	return balance
+}
+
+// TokensHeld returns the total balance held by all holders, aggregated
+// across the assets. A nil list of assets matches all assets of the
+// Contract.
+func (c Contract) TokensHeld(assetIDs []string) uint64 {
+	balance := uint64(0)
+
+	for _, asset := range c.scopedAssets(assetIDs) {
+		for _, holding := range asset.Holdings {
+			balance += holding.Balance
+		}
+	}
+
+	return balance
+}
+
+// HoldersOf returns the addresses holding a balance of any of the assets. A
+// nil list of assets matches all assets of the Contract.
+func (c Contract) HoldersOf(assetIDs []string) []string {
+	holders := []string{}
+	seen := map[string]bool{}
+
+	for _, asset := range c.scopedAssets(assetIDs) {
+		for address, holding := range asset.Holdings {
+			if holding.Balance == 0 || seen[address] {
+				continue
+			}
+
+			seen[address] = true
+			holders = append(holders, address)
+		}
+	}
+
+	return holders
+}
+
+// scopedAssets returns the assets of the Contract with the given ID's, or
+// all assets if the list is nil.
+func (c Contract) scopedAssets(assetIDs []string) []Asset {
+	assets := []Asset{}
+
+	if assetIDs == nil {
+		for _, asset := range c.Assets {
+			assets = append(assets, asset)
+		}
+
+		return assets
+	}
+
+	for _, id := range assetIDs {
+		if asset, ok := c.Assets[id]; ok {
+			assets = append(assets, asset)
+		}
+	}
+
+	return assets
+}
+
 func (c Contract) CanVote(v Vote, b Ballot) uint8 {
-	if !c.IsOwner(b.Address) {
+	if !v.InScope(b.AssetID) || !c.IsOwnerOf(b.Address, v.Scope()) {
 		return protocol.RejectionCodeUnknownAddress
 	}
 
-	if !v.IsOpen(time.Now()) {
+	now := time.Now()
+
+	if v.CommitReveal == nil {
+		if !v.IsOpen(now) {
+			return protocol.RejectionCodeVoteClosed
+		}
+
+		return protocol.RejectionCodeOK
+	}
+
+	// commitments are cast while the vote is open, and revealed after
+	switch {
+	case v.IsOpen(now):
+		if b.Commitment == "" || len(b.Commitment) > CommitmentSize*2 {
+			return protocol.RejectionCodeCommitment
+		}
+
+	case v.IsRevealing(now):
+		if !v.Reveals(b) {
+			return protocol.RejectionCodeCommitment
+		}
+
+	default:
 		return protocol.RejectionCodeVoteClosed
 	}
 
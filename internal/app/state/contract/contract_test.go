@@ -277,3 +277,42 @@ func TestContract_getUsers(t *testing.T) {
 		t.Fatalf("got\n%+v\nwant\n%+v", got, want)
 	}
 }
+
+func TestContract_SetHolderJurisdiction(t *testing.T) {
+	holder := "1DNTgNSWtTestKs7j1DwaoxmSc4q9sEUsb"
+	other := "1Cessj8TyzEypaVzp9V8oZhiMLokVDNSR5"
+
+	c := Contract{
+		Assets: map[string]Asset{
+			"a": Asset{
+				Holdings: map[string]Holding{
+					holder: NewHolding(holder, 10),
+				},
+			},
+			"b": Asset{
+				Holdings: map[string]Holding{
+					other: NewHolding(other, 10),
+				},
+			},
+		},
+	}
+
+	if c.SetHolderJurisdiction(other+"x", "AUS") {
+		t.Fatalf("got jurisdiction set for an address with no holdings")
+	}
+
+	if !c.SetHolderJurisdiction(holder, "AUS") {
+		t.Fatalf("got jurisdiction not set for a holder")
+	}
+
+	// A later holding of another asset takes the jurisdiction of the holder.
+	c.Assets["b"].Holdings[holder] = NewHolding(holder, 5)
+
+	if got := c.HolderJurisdiction(holder); got != "AUS" {
+		t.Errorf("got %q, want %q", got, "AUS")
+	}
+
+	if got := c.HolderJurisdiction(other); got != "" {
+		t.Errorf("got %q, want %q", got, "")
+	}
+}
@@ -0,0 +1,37 @@
+package contract
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"time"
+)
+
+// Document is a document attached to an Asset, such as a prospectus or the
+// terms of the asset.
+//
+// Only the hash of the document is held in the state. The content is held
+// separately, and can be checked against the hash by holders.
+type Document struct {
+	Name      string `json:"name"`
+	Hash      []byte `json:"hash"`
+	Size      int    `json:"size"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+func NewDocument(name string, content []byte) Document {
+	hash := sha256.Sum256(content)
+
+	return Document{
+		Name:      name,
+		Hash:      hash[:],
+		Size:      len(content),
+		CreatedAt: time.Now().UnixNano(),
+	}
+}
+
+// Matches returns true if the content hashes to the hash of the Document.
+func (d Document) Matches(content []byte) bool {
+	hash := sha256.Sum256(content)
+
+	return bytes.Equal(hash[:], d.Hash)
+}
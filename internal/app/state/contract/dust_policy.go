@@ -0,0 +1,112 @@
+package contract
+
+import (
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+)
+
+const (
+	// DustReject rejects a request that would need an output below the
+	// minimum output value.
+	DustReject = "reject"
+
+	// DustAbsorb drops an output below the minimum output value, leaving its
+	// value to the miner as fee.
+	DustAbsorb = "absorb"
+
+	// DustRoundUp raises an output below the minimum output value to the
+	// minimum.
+	DustRoundUp = "round_up"
+)
+
+var (
+	// ErrDustOutput is returned when an output is below the minimum output
+	// value of a contract that rejects dust.
+	ErrDustOutput = errs.New(errs.Invalid, "Output below minimum value")
+
+	// ErrDustHandling is returned for a DustPolicy with an unknown way of
+	// handling outputs below the minimum.
+	ErrDustHandling = errs.New(errs.Invalid, "Dust handling must be reject, absorb or round_up")
+)
+
+// DustPolicy is the minimum output value of a contract, and how outputs
+// below it are handled.
+//
+// A zero value uses the network dust limit, and rounds outputs up to it.
+type DustPolicy struct {
+	MinimumOutput uint64 `json:"minimum_output,omitempty"`
+	Handling      string `json:"handling,omitempty"`
+}
+
+// Validate returns an error if the policy has an unknown handling. An empty
+// handling rounds up.
+func (p DustPolicy) Validate() error {
+	switch p.Handling {
+	case "", DustReject, DustAbsorb, DustRoundUp:
+		return nil
+	}
+
+	return ErrDustHandling
+}
+
+// Minimum returns the minimum output value.
+func (p DustPolicy) Minimum() uint64 {
+	if p.MinimumOutput < txbuilder.DustMinimumOutput {
+		return txbuilder.DustMinimumOutput
+	}
+
+	return p.MinimumOutput
+}
+
+// IsDust returns true if the value is below the minimum output value, false
+// otherwise.
+func (p DustPolicy) IsDust(value uint64) bool {
+	return value < p.Minimum()
+}
+
+// Rejects returns true if a request paying the value to an output must be
+// rejected, false otherwise.
+func (p DustPolicy) Rejects(value uint64) bool {
+	return p.Handling == DustReject && p.IsDust(value)
+}
+
+// Apply returns the outputs with the policy applied to any output below the
+// minimum output value.
+func (p DustPolicy) Apply(outs []txbuilder.TxOutput) ([]txbuilder.TxOutput, error) {
+	applied := []txbuilder.TxOutput{}
+
+	for _, out := range outs {
+		value, err := p.ApplyValue(out.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		if value == 0 {
+			continue
+		}
+
+		out.Value = value
+		applied = append(applied, out)
+	}
+
+	return applied, nil
+}
+
+// ApplyValue returns the value actually paid to an output of the value once
+// the policy is applied, which is 0 for an output that is dropped.
+func (p DustPolicy) ApplyValue(value uint64) (uint64, error) {
+	if !p.IsDust(value) {
+		return value, nil
+	}
+
+	switch p.Handling {
+	case DustReject:
+		return 0, ErrDustOutput
+
+	case DustAbsorb:
+		return 0, nil
+
+	default:
+		return p.Minimum(), nil
+	}
+}
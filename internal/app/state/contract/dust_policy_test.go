@@ -0,0 +1,101 @@
+package contract
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+)
+
+func TestDustPolicy_Apply(t *testing.T) {
+	outs := []txbuilder.TxOutput{
+		txbuilder.TxOutput{
+			Value: 1000,
+		},
+		txbuilder.TxOutput{
+			Value: 600,
+		},
+	}
+
+	tests := []struct {
+		name   string
+		policy DustPolicy
+		want   []uint64
+		err    error
+	}{
+		{
+			name:   "network dust limit",
+			policy: DustPolicy{},
+			want:   []uint64{1000, 600},
+		},
+		{
+			name: "round up",
+			policy: DustPolicy{
+				MinimumOutput: 800,
+				Handling:      DustRoundUp,
+			},
+			want: []uint64{1000, 800},
+		},
+		{
+			name: "absorb",
+			policy: DustPolicy{
+				MinimumOutput: 800,
+				Handling:      DustAbsorb,
+			},
+			want: []uint64{1000},
+		},
+		{
+			name: "reject",
+			policy: DustPolicy{
+				MinimumOutput: 800,
+				Handling:      DustReject,
+			},
+			err: ErrDustOutput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied, err := tt.policy.Apply(outs)
+			if err != tt.err {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			got := []uint64{}
+			for _, out := range applied {
+				got = append(got, out.Value)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%#+v\nwant\n%#+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDustPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		handling string
+		err      error
+	}{
+		{handling: "", err: nil},
+		{handling: DustReject, err: nil},
+		{handling: DustAbsorb, err: nil},
+		{handling: DustRoundUp, err: nil},
+		{handling: "donate", err: ErrDustHandling},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.handling, func(t *testing.T) {
+			p := DustPolicy{Handling: tt.handling}
+
+			if err := p.Validate(); err != tt.err {
+				t.Errorf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package contract
+
+import (
+	"github.com/tokenized/smart-contract/pkg/errs"
+)
+
+var (
+	// ErrHoldingAge is returned for an Eligibility with a negative minimum
+	// holding age.
+	ErrHoldingAge = errs.New(errs.Invalid, "Minimum holding age can't be negative")
+
+	// ErrEmptyJurisdiction is returned for an Eligibility, or a holder,
+	// with an empty jurisdiction.
+	ErrEmptyJurisdiction = errs.New(errs.Invalid, "Jurisdiction can't be empty")
+)
+
+// Eligibility restricts which holders of a contract are eligible to vote.
+//
+// A zero value places no restriction on voting beyond holding tokens.
+type Eligibility struct {
+	// MinimumHolding is the fewest tokens a voter must hold on the assets of
+	// a vote.
+	MinimumHolding uint64 `json:"minimum_holding,omitempty"`
+
+	// MinimumHoldingAge is the time in nanoseconds a voter must have held
+	// the asset a ballot is cast on before the vote was created.
+	MinimumHoldingAge int64 `json:"minimum_holding_age,omitempty"`
+
+	// Jurisdictions are the jurisdictions voters must be in. An empty list
+	// allows voters in any jurisdiction.
+	//
+	// The jurisdiction of a voter is the one recorded for the holder with
+	// Contract.SetHolderJurisdiction.
+	Jurisdictions []string `json:"jurisdictions,omitempty"`
+}
+
+// Validate returns an error if the rules can't be applied to a vote.
+func (e Eligibility) Validate() error {
+	if e.MinimumHoldingAge < 0 {
+		return ErrHoldingAge
+	}
+
+	for _, j := range e.Jurisdictions {
+		if j == "" {
+			return ErrEmptyJurisdiction
+		}
+	}
+
+	return nil
+}
@@ -8,6 +8,7 @@ type Holding struct {
 	Address       string         `json:"address"`
 	Balance       uint64         `json:"balance"`
 	HoldingStatus *HoldingStatus `json:"order_status,omitempty"`
+	Jurisdiction  string         `json:"jurisdiction,omitempty"`
 	CreatedAt     int64          `json:"created_at"`
 }
 
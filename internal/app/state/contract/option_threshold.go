@@ -0,0 +1,47 @@
+package contract
+
+import (
+	"encoding/json"
+)
+
+// OptionThreshold is the minimum support an option must attract to win a
+// Vote, in addition to winning under the voting system of the Vote.
+//
+// A zero value places no minimum on the support.
+//
+// The protocol has no field for this, so the thresholds of each option are
+// carried as a JSON object in the ProposalDescription, such as
+//
+//	{"thresholds": {"66": {"tokens": 1000}}}
+type OptionThreshold struct {
+	Ballots uint64 `json:"ballots,omitempty"`
+	Tokens  uint64 `json:"tokens,omitempty"`
+}
+
+// ParseThresholds returns the OptionThreshold's held by the description of
+// a Vote, keyed by option, or nil if the description places no minimum on
+// the support of any option.
+func ParseThresholds(description []byte) map[uint8]OptionThreshold {
+	d := struct {
+		Thresholds map[uint8]OptionThreshold `json:"thresholds"`
+	}{}
+
+	if err := json.Unmarshal(description, &d); err != nil {
+		return nil
+	}
+
+	thresholds := map[uint8]OptionThreshold{}
+	for option, t := range d.Thresholds {
+		if t == (OptionThreshold{}) {
+			continue
+		}
+
+		thresholds[option] = t
+	}
+
+	if len(thresholds) == 0 {
+		return nil
+	}
+
+	return thresholds
+}
@@ -0,0 +1,63 @@
+package contract
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+func TestParseThresholds(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		want        map[uint8]OptionThreshold
+	}{
+		{
+			name:        "thresholds",
+			description: `{"thresholds": {"65": {"ballots": 2}, "66": {"tokens": 1000}}}`,
+			want: map[uint8]OptionThreshold{
+				65: OptionThreshold{Ballots: 2},
+				66: OptionThreshold{Tokens: 1000},
+			},
+		},
+		{
+			name:        "zero threshold",
+			description: `{"thresholds": {"65": {}}}`,
+		},
+		{
+			name:        "option out of range",
+			description: `{"thresholds": {"256": {"tokens": 1}}}`,
+		},
+		{
+			name:        "plain text",
+			description: "Change the name of the contract",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseThresholds([]byte(tt.description))
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%#+v\nwant\n%#+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewVoteFromProtocolVote_thresholds(t *testing.T) {
+	m := protocol.NewVote()
+	m.VoteOptions = []byte{65, 66}
+	m.ProposalDescription = []byte(`{"thresholds": {"66": {"tokens": 1000}}}`)
+
+	v := NewVoteFromProtocolVote("1CWjudGPuj1sHs3GuMkAGPEUP5YaJNqu8U", &m)
+
+	want := map[uint8]OptionThreshold{
+		66: OptionThreshold{Tokens: 1000},
+	}
+
+	if !reflect.DeepEqual(v.Thresholds, want) {
+		t.Errorf("got\n%#+v\nwant\n%#+v", v.Thresholds, want)
+	}
+}
@@ -0,0 +1,127 @@
+package contract
+
+import (
+	"time"
+)
+
+const (
+	// MessageTypeTransferPending is the type of the Message sent to the
+	// receiver of a transfer that is awaiting their acceptance. The Message
+	// holds the ID of the transfer.
+	MessageTypeTransferPending = "TP"
+
+	// MessageTypeTransferAccept is the type of the Message sent by the
+	// receiver of a transfer to accept it. The Message holds the ID of the
+	// transfer.
+	MessageTypeTransferAccept = "TA"
+
+	// DefaultApprovalTimeout is the time a receiver has to accept a transfer
+	// if the asset does not set a timeout.
+	DefaultApprovalTimeout = 7 * 24 * time.Hour
+
+	// ActionRefundExpired is the action that the refund of transfers not
+	// accepted in time is indexed as.
+	ActionRefundExpired = "refund_expired"
+)
+
+// PendingTransfer is a transfer of an asset requiring receiver approval,
+// held in escrow until the receiver accepts it.
+//
+// The tokens are removed from the holding of the sender while the transfer
+// is pending, and refunded if the transfer is not accepted before it
+// expires.
+type PendingTransfer struct {
+	ID        string `json:"id"`
+	AssetType string `json:"asset_type"`
+	AssetID   string `json:"asset_id"`
+	Sender    string `json:"sender"`
+	Receiver  string `json:"receiver"`
+	TokenQty  uint64 `json:"token_qty"`
+	CreatedAt int64  `json:"created_at"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// NewPendingTransfer returns a new PendingTransfer of the asset, expiring
+// after the approval timeout of the asset.
+func NewPendingTransfer(id string,
+	a Asset,
+	sender string,
+	receiver string,
+	qty uint64) PendingTransfer {
+
+	now := time.Now()
+
+	timeout := time.Duration(a.ApprovalTimeout)
+	if timeout == 0 {
+		timeout = DefaultApprovalTimeout
+	}
+
+	return PendingTransfer{
+		ID:        id,
+		AssetType: a.Type,
+		AssetID:   a.ID,
+		Sender:    sender,
+		Receiver:  receiver,
+		TokenQty:  qty,
+		CreatedAt: now.UnixNano(),
+		ExpiresAt: now.Add(timeout).UnixNano(),
+	}
+}
+
+// Expired returns true if the transfer can no longer be accepted, false
+// otherwise.
+func (p PendingTransfer) Expired(ts time.Time) bool {
+	return ts.UnixNano() >= p.ExpiresAt
+}
+
+// Escrow holds the tokens of a transfer until it is accepted or expires.
+func (c *Contract) Escrow(p PendingTransfer) {
+	c.adjustHolding(p.AssetID, p.Sender, -int64(p.TokenQty))
+
+	if c.PendingTransfers == nil {
+		c.PendingTransfers = map[string]PendingTransfer{}
+	}
+
+	c.PendingTransfers[p.ID] = p
+}
+
+// Release removes a transfer from escrow once it has been settled.
+func (c *Contract) Release(id string) {
+	delete(c.PendingTransfers, id)
+}
+
+// RefundExpired returns the tokens of expired transfers to their senders,
+// returning the refunded transfers.
+func (c *Contract) RefundExpired(ts time.Time) []PendingTransfer {
+	refunded := []PendingTransfer{}
+
+	for id, p := range c.PendingTransfers {
+		if !p.Expired(ts) {
+			continue
+		}
+
+		c.adjustHolding(p.AssetID, p.Sender, int64(p.TokenQty))
+		delete(c.PendingTransfers, id)
+
+		refunded = append(refunded, p)
+	}
+
+	return refunded
+}
+
+// adjustHolding adds the quantity, which may be negative, to the holding of
+// the address.
+func (c *Contract) adjustHolding(assetID, address string, qty int64) {
+	a, ok := c.Assets[assetID]
+	if !ok {
+		return
+	}
+
+	h, ok := a.Holdings[address]
+	if !ok {
+		h = NewHolding(address, 0)
+	}
+
+	h.Balance = uint64(int64(h.Balance) + qty)
+	a.Holdings[address] = h
+}
@@ -0,0 +1,72 @@
+package contract
+
+import (
+	"testing"
+	"time"
+)
+
+func TestContract_Escrow(t *testing.T) {
+	sender := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	receiver := "123h2RL1DT4AuYyJUseGxcXSAe5imPSeLV"
+
+	tests := []struct {
+		name    string
+		after   time.Duration
+		accept  bool
+		sender  uint64
+		pending int
+	}{
+		{
+			name:    "pending",
+			sender:  15,
+			pending: 1,
+		},
+		{
+			name:    "accepted",
+			accept:  true,
+			sender:  15,
+			pending: 0,
+		},
+		{
+			name:    "expired",
+			after:   DefaultApprovalTimeout,
+			sender:  20,
+			pending: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Asset{
+				ID:               "foo",
+				ReceiverApproval: true,
+				Holdings: map[string]Holding{
+					sender: NewHolding(sender, 20),
+				},
+			}
+
+			c := Contract{
+				Assets: map[string]Asset{
+					a.ID: a,
+				},
+			}
+
+			p := NewPendingTransfer("1", a, sender, receiver, 5)
+			c.Escrow(p)
+
+			if tt.accept {
+				c.Release(p.ID)
+			}
+
+			c.RefundExpired(time.Now().Add(tt.after))
+
+			if got := c.Assets[a.ID].Holdings[sender].Balance; got != tt.sender {
+				t.Errorf("got sender balance %v, want %v", got, tt.sender)
+			}
+
+			if got := len(c.PendingTransfers); got != tt.pending {
+				t.Errorf("got %v pending transfers, want %v", got, tt.pending)
+			}
+		})
+	}
+}
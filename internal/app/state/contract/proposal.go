@@ -0,0 +1,43 @@
+package contract
+
+import (
+	"encoding/json"
+)
+
+// Proposal is the amendment a Vote decides on, made when the Option wins.
+//
+// Referendums and initiatives have no fields for an amendment, so a
+// Proposal is carried as a JSON object in the ProposalDescription.
+type Proposal struct {
+	// Option is the option that must win for the amendment to be made.
+	Option uint8 `json:"option"`
+
+	// AssetID is the asset amended. The contract is amended if it is empty.
+	AssetID string `json:"asset_id,omitempty"`
+
+	// Amendments are the changes made to the contract or asset.
+	Amendments []Amendment `json:"amendments"`
+}
+
+// Amendment is the new value of a field of the contract or an asset, named
+// as in the ContractFormation or AssetCreation action.
+type Amendment struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+// ParseProposal returns the Proposal held by the description of a Vote, or
+// nil if the description is not a Proposal.
+func ParseProposal(description []byte) *Proposal {
+	p := Proposal{}
+
+	if err := json.Unmarshal(description, &p); err != nil {
+		return nil
+	}
+
+	if p.Option == 0 || len(p.Amendments) == 0 {
+		return nil
+	}
+
+	return &p
+}
@@ -0,0 +1,127 @@
+package contract
+
+import (
+	"math"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+)
+
+const (
+	// FeeRoleIssuer is the role of a payee that issued the asset.
+	FeeRoleIssuer = "issuer"
+
+	// FeeRolePlatform is the role of a payee operating the platform the
+	// asset is traded on.
+	FeeRolePlatform = "platform"
+
+	// FeeRoleBroker is the role of a payee that brokered the transfer.
+	FeeRoleBroker = "broker"
+)
+
+var (
+	// ErrFeeShares is returned for a TransferFee with shares that don't add
+	// up to 100 percent.
+	ErrFeeShares = errs.New(errs.Invalid, "Transfer fee shares must add up to 100 percent")
+
+	// ErrFeePayees is returned for a TransferFee with a value and no
+	// payees.
+	ErrFeePayees = errs.New(errs.Invalid, "Transfer fee has no payees")
+)
+
+// TransferFee is the fee paid on each transfer of an asset, split between
+// payees by percentage.
+type TransferFee struct {
+	// Value is the fee of a transfer, in satoshis.
+	Value uint64 `json:"value"`
+
+	Payees []FeePayee `json:"payees"`
+}
+
+// FeePayee is paid a share of a TransferFee.
+type FeePayee struct {
+	Role    string `json:"role"`
+	Address string `json:"address"`
+
+	// Percent is the share of the fee paid to the payee.
+	Percent float64 `json:"percent"`
+}
+
+// FeeSplit is the part of a TransferFee paid to a payee.
+type FeeSplit struct {
+	Role    string `json:"role"`
+	Address string `json:"address"`
+	Value   uint64 `json:"value"`
+}
+
+// Validate returns an error if the fee can't be split between the payees.
+func (f TransferFee) Validate() error {
+	if f.Value == 0 {
+		return nil
+	}
+
+	if len(f.Payees) == 0 {
+		return ErrFeePayees
+	}
+
+	total := 0.0
+
+	for _, p := range f.Payees {
+		if p.Percent <= 0 {
+			return ErrFeeShares
+		}
+
+		if _, err := btcutil.DecodeAddress(p.Address, &chaincfg.MainNetParams); err != nil {
+			return err
+		}
+
+		total += p.Percent
+	}
+
+	// allow for shares such as 33.33 that can't be represented exactly
+	if math.Abs(total-100) > 0.000001 {
+		return ErrFeeShares
+	}
+
+	return nil
+}
+
+// Split returns the part of the fee paid to each payee, in the order of the
+// payees.
+//
+// Each part is rounded down to a whole satoshi, and the satoshis left over
+// are paid to the first payee, so the parts always add up to the Value.
+func (f TransferFee) Split() ([]FeeSplit, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	splits := []FeeSplit{}
+
+	if f.Value == 0 {
+		return splits, nil
+	}
+
+	paid := uint64(0)
+
+	for _, p := range f.Payees {
+		value := uint64(float64(f.Value) * p.Percent / 100)
+		if value > f.Value-paid {
+			value = f.Value - paid
+		}
+
+		splits = append(splits, FeeSplit{
+			Role:    p.Role,
+			Address: p.Address,
+			Value:   value,
+		})
+
+		paid += value
+	}
+
+	splits[0].Value += f.Value - paid
+
+	return splits, nil
+}
@@ -0,0 +1,85 @@
+package contract
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTransferFee_Split(t *testing.T) {
+	issuer := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	platform := "1BoatSLRHtKNngkdXEeobR76b53LETtpyT"
+	broker := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+
+	tests := []struct {
+		name string
+		fee  TransferFee
+		want []FeeSplit
+		err  error
+	}{
+		{
+			name: "no fee",
+			fee:  TransferFee{},
+			want: []FeeSplit{},
+		},
+		{
+			name: "even",
+			fee: TransferFee{
+				Value: 1000,
+				Payees: []FeePayee{
+					{Role: FeeRoleIssuer, Address: issuer, Percent: 70},
+					{Role: FeeRolePlatform, Address: platform, Percent: 30},
+				},
+			},
+			want: []FeeSplit{
+				{Role: FeeRoleIssuer, Address: issuer, Value: 700},
+				{Role: FeeRolePlatform, Address: platform, Value: 300},
+			},
+		},
+		{
+			name: "remainder to first payee",
+			fee: TransferFee{
+				Value: 1000,
+				Payees: []FeePayee{
+					{Role: FeeRoleIssuer, Address: issuer, Percent: 33.34},
+					{Role: FeeRolePlatform, Address: platform, Percent: 33.33},
+					{Role: FeeRoleBroker, Address: broker, Percent: 33.33},
+				},
+			},
+			want: []FeeSplit{
+				{Role: FeeRoleIssuer, Address: issuer, Value: 334},
+				{Role: FeeRolePlatform, Address: platform, Value: 333},
+				{Role: FeeRoleBroker, Address: broker, Value: 333},
+			},
+		},
+		{
+			name: "shares under 100",
+			fee: TransferFee{
+				Value: 1000,
+				Payees: []FeePayee{
+					{Role: FeeRoleIssuer, Address: issuer, Percent: 50},
+				},
+			},
+			err: ErrFeeShares,
+		},
+		{
+			name: "no payees",
+			fee: TransferFee{
+				Value: 1000,
+			},
+			err: ErrFeePayees,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.fee.Split()
+			if err != tt.err {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%#+v\nwant\n%#+v", got, tt.want)
+			}
+		})
+	}
+}
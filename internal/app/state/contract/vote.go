@@ -8,21 +8,29 @@ import (
 )
 
 type Vote struct {
-	Address              string         `json:"address"`
-	AssetType            string         `json:"asset_type"`
-	AssetID              string         `json:"asset_id"`
-	VoteType             byte           `json:"vote_type"`
-	VoteOptions          []byte         `json:"vote_options"`
-	VoteMax              uint8          `json:"vote_max"`
-	VoteLogic            byte           `json:"vote_logic"`
-	ProposalDescription  string         `json:"proposal_description"`
-	ProposalDocumentHash string         `json:"proposal_document_hash"`
-	VoteCutOffTimestamp  int64          `json:"vote_cut_off_timestamp"`
-	RefTxnIDHash         string         `json:"ref_txn_id_hash"`
-	Ballots              []Ballot       `json:"ballots"`
-	UTXO                 txbuilder.UTXO `json:"utxo"`
-	Result               *BallotResult  `json:"result,omitempty"`
-	CreatedAt            int64          `json:"created_at"`
+	Address              string                    `json:"address"`
+	AssetType            string                    `json:"asset_type"`
+	AssetID              string                    `json:"asset_id"`
+	AssetIDs             []string                  `json:"asset_ids,omitempty"`
+	LatestBallotOnly     bool                      `json:"latest_ballot_only,omitempty"`
+	VoteType             byte                      `json:"vote_type"`
+	VoteOptions          []byte                    `json:"vote_options"`
+	VoteMax              uint8                     `json:"vote_max"`
+	VoteLogic            byte                      `json:"vote_logic"`
+	AbstainOption        byte                      `json:"abstain_option,omitempty"`
+	Thresholds           map[uint8]OptionThreshold `json:"thresholds,omitempty"`
+	ProposalDescription  string                    `json:"proposal_description"`
+	ProposalDocumentHash string                    `json:"proposal_document_hash"`
+	Proposal             *Proposal                 `json:"proposal,omitempty"`
+	CommitReveal         *CommitReveal             `json:"commit_reveal,omitempty"`
+	VoteCutOffTimestamp  int64                     `json:"vote_cut_off_timestamp"`
+	RefTxnIDHash         string                    `json:"ref_txn_id_hash"`
+	Ballots              []Ballot                  `json:"ballots"`
+	UTXO                 txbuilder.UTXO            `json:"utxo"`
+	Result               *BallotResult             `json:"result,omitempty"`
+	Winners              []uint8                   `json:"winners,omitempty"`
+	Ineligible           map[string]string         `json:"ineligible,omitempty"`
+	CreatedAt            int64                     `json:"created_at"`
 }
 
 func NewVote() Vote {
@@ -43,6 +51,9 @@ func NewVoteFromProtocolVote(address string, m *protocol.Vote) Vote {
 	v.VoteLogic = m.VoteLogic
 	v.ProposalDescription = string(m.ProposalDescription)
 	v.ProposalDocumentHash = string(v.ProposalDocumentHash)
+	v.Proposal = ParseProposal(m.ProposalDescription)
+	v.CommitReveal = ParseCommitReveal(m.ProposalDescription)
+	v.Thresholds = ParseThresholds(m.ProposalDescription)
 	v.VoteCutOffTimestamp = v.VoteCutOffTimestamp
 
 	return v
@@ -51,3 +62,64 @@ func NewVoteFromProtocolVote(address string, m *protocol.Vote) Vote {
 func (v Vote) IsOpen(ts time.Time) bool {
 	return ts.UnixNano() < v.VoteCutOffTimestamp
 }
+
+// Scope returns the IDs of the assets the Vote is held on.
+//
+// A Vote may be held on a single asset, a subset of the assets of the
+// contract, or all of them. A nil result means the Vote is a contract level
+// vote, held on all assets.
+func (v Vote) Scope() []string {
+	if len(v.AssetIDs) > 0 {
+		return v.AssetIDs
+	}
+
+	if v.AssetID != "" {
+		return []string{v.AssetID}
+	}
+
+	return nil
+}
+
+// BallotHoldings returns the IDs of the assets whose holdings a Ballot
+// counts for.
+//
+// If LatestBallotOnly is set, the latest Ballot of a voter counts for their
+// holdings across all assets of the Vote. Otherwise each Ballot counts for
+// the holding of the asset it is cast on.
+func (v Vote) BallotHoldings(b Ballot) []string {
+	if v.LatestBallotOnly {
+		return v.Scope()
+	}
+
+	return []string{b.AssetID}
+}
+
+// InScope returns true if the asset is one of the assets the Vote is held
+// on, false otherwise.
+func (v Vote) InScope(assetID string) bool {
+	scope := v.Scope()
+	if scope == nil {
+		return true
+	}
+
+	for _, id := range scope {
+		if id == assetID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsAbstention returns true if the Ballot abstains from the Vote, false
+// otherwise.
+//
+// A Ballot abstains by choosing the AbstainOption first. A Vote without an
+// AbstainOption has no explicit abstentions.
+func (v Vote) IsAbstention(b Ballot) bool {
+	if v.AbstainOption == 0 || len(b.Vote) == 0 {
+		return false
+	}
+
+	return b.Vote[0] == v.AbstainOption
+}
@@ -0,0 +1,40 @@
+package state
+
+import (
+	"context"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+// Event describes a mutation of Contract state.
+type Event struct {
+	// TxID is the hash of the TX that caused the mutation. It is empty for
+	// a change made by an operator, or by the node on a schedule.
+	TxID string
+
+	// Action is the protocol code of the message in the TX, or what an
+	// operator or the node changed.
+	Action string
+
+	// ContractID is the address of the mutated Contract.
+	ContractID string
+
+	// Message is the protocol message in the TX. It is nil for a change
+	// made by an operator.
+	Message protocol.OpReturnMessage
+
+	// Contract is the Contract state after the mutation.
+	Contract contract.Contract
+
+	// Timestamp is when the mutation was written, in nanoseconds.
+	Timestamp int64
+}
+
+// Indexer is invoked after each mutation of Contract state has been
+// written, allowing external indexes to be kept in line with the state.
+//
+// An Indexer must not modify the Event.
+type Indexer interface {
+	Index(context.Context, Event) error
+}
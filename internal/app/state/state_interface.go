@@ -10,3 +10,13 @@ type StateInterface interface {
 	Write(context.Context, contract.Contract) error
 	Read(context.Context, string) (*contract.Contract, error)
 }
+
+// Updater changes Contract state outside of the processing of a request,
+// as an operator does. The change is serialized with the processing of
+// requests to the contract, and indexed as the responses to them are.
+type Updater interface {
+	// Update reads the contract, changes it with f, and writes it, unless
+	// f returns an error. The action describes the change to indexers.
+	Update(ctx context.Context, contractID string, action string,
+		f func(*contract.Contract) error) (*contract.Contract, error)
+}
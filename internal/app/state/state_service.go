@@ -11,12 +11,12 @@ package state
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"time"
 
 	"github.com/tokenized/smart-contract/internal/app/logger"
 	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/storage"
 )
 
@@ -24,7 +24,7 @@ const (
 	ContractPrefix = "contracts"
 )
 
-var ErrContractNotFound = errors.New("Contract not found")
+var ErrContractNotFound = errs.New(errs.NotFound, "Contract not found")
 
 type StateService struct {
 	Storage storage.ReadWriter
@@ -0,0 +1,233 @@
+package wallet
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+	"github.com/tokenized/smart-contract/pkg/txscript"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+)
+
+var (
+	ErrQuorumNotFound   = errors.New("Quorum not found")
+	ErrQuorumNotReached = errors.New("Quorum not reached")
+	ErrOfflineQuorum    = errors.New("Quorum signs offline")
+)
+
+// Signer produces signatures for the inputs of a TX spending from a
+// multisig contract address.
+//
+// A Signer will usually be external to the smart contract, such as a
+// signing service run by one of the key holders of the contract.
+type Signer interface {
+	// PublicKey returns the serialized compressed public key of the Signer.
+	PublicKey() []byte
+
+	// Sign returns a signature for each input of the TX, in order.
+	Sign(context.Context, *wire.MsgTx, txbuilder.UTXOs, []byte) ([][]byte, error)
+}
+
+// KeySigner is a Signer with a locally held private key.
+type KeySigner struct {
+	PrivateKey *btcec.PrivateKey
+}
+
+// NewKeySigner returns a new KeySigner.
+func NewKeySigner(key *btcec.PrivateKey) KeySigner {
+	return KeySigner{
+		PrivateKey: key,
+	}
+}
+
+// PublicKey implements the Signer interface.
+func (s KeySigner) PublicKey() []byte {
+	return s.PrivateKey.PubKey().SerializeCompressed()
+}
+
+// Sign implements the Signer interface.
+func (s KeySigner) Sign(ctx context.Context,
+	tx *wire.MsgTx,
+	utxos txbuilder.UTXOs,
+	redeemScript []byte) ([][]byte, error) {
+
+	sigs := [][]byte{}
+
+	for i, txIn := range tx.TxIn {
+		utxo, ok := utxos.ForOutPoint(txIn.PreviousOutPoint)
+		if !ok {
+			return nil, fmt.Errorf("No UTXO for input %v", i)
+		}
+
+		sig, err := txbuilder.SignMultiSigInput(tx, i, redeemScript,
+			s.PrivateKey, utxo)
+		if err != nil {
+			return nil, err
+		}
+
+		sigs = append(sigs, sig)
+	}
+
+	return sigs, nil
+}
+
+// Quorum assembles the signatures of the Signers of a P2SH multisig
+// contract address.
+//
+// An Offline Quorum has no Signers. Its TX's are left unsigned, to be
+// signed elsewhere, such as on an air-gapped machine.
+type Quorum struct {
+	Address      btcutil.Address
+	RedeemScript []byte
+	Required     int
+	Signers      []Signer
+	Offline      bool
+}
+
+// NewQuorum returns a new Quorum for the redeem script.
+func NewQuorum(redeemScript []byte, signers []Signer) (*Quorum, error) {
+	_, required, err := txscript.CalcMultiSigStats(redeemScript)
+	if err != nil {
+		return nil, err
+	}
+
+	address, err := btcutil.NewAddressScriptHash(redeemScript,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		return nil, err
+	}
+
+	q := Quorum{
+		Address:      address,
+		RedeemScript: redeemScript,
+		Required:     required,
+		Signers:      signers,
+	}
+
+	return &q, nil
+}
+
+// NewOfflineQuorum returns a new Quorum for the redeem script, with TX's
+// signed offline.
+func NewOfflineQuorum(redeemScript []byte) (*Quorum, error) {
+	q, err := NewQuorum(redeemScript, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	q.Offline = true
+
+	return q, nil
+}
+
+// Sign collects signatures from the Signers until the required number
+// have signed, then adds the unlocking scripts to the inputs of the TX.
+//
+// A Signer that fails to sign, whose public key is not one of the keys of
+// the RedeemScript, or already signed, or whose signatures don't verify, is
+// skipped, as other Signers may still be able to form a quorum.
+func (q Quorum) Sign(ctx context.Context,
+	tx *wire.MsgTx,
+	utxos txbuilder.UTXOs) error {
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	// signatures for each input, keyed by public key
+	sigs := make([]map[string][]byte, len(tx.TxIn))
+	for i := range sigs {
+		sigs[i] = map[string][]byte{}
+	}
+
+	// the public keys that signed
+	signed := map[string]bool{}
+
+	for _, signer := range q.Signers {
+		if len(signed) == q.Required {
+			break
+		}
+
+		pubKey := hex.EncodeToString(signer.PublicKey())
+
+		if signed[pubKey] {
+			log.Errorf("Signer %v already signed", pubKey)
+			continue
+		}
+
+		ok, err := txbuilder.IsMultiSigKey(q.RedeemScript, signer.PublicKey())
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			log.Errorf("Signer %v is not a key of the quorum", pubKey)
+			continue
+		}
+
+		inputSigs, err := signer.Sign(ctx, tx, utxos, q.RedeemScript)
+		if err != nil {
+			log.Errorf("Signer %v failed to sign : %v", pubKey, err)
+			continue
+		}
+
+		if len(inputSigs) != len(tx.TxIn) {
+			log.Errorf("Signer %v returned %v signatures for %v inputs",
+				pubKey, len(inputSigs), len(tx.TxIn))
+			continue
+		}
+
+		if err := q.verify(tx, utxos, signer.PublicKey(), inputSigs); err != nil {
+			log.Errorf("Signer %v returned invalid signatures : %v", pubKey, err)
+			continue
+		}
+
+		for i, sig := range inputSigs {
+			sigs[i][pubKey] = sig
+		}
+
+		signed[pubKey] = true
+	}
+
+	if len(signed) < q.Required {
+		return ErrQuorumNotReached
+	}
+
+	for i, txIn := range tx.TxIn {
+		script, err := txbuilder.MultiSigUnlockingScript(q.RedeemScript, sigs[i])
+		if err != nil {
+			return err
+		}
+
+		txIn.SignatureScript = script
+	}
+
+	return nil
+}
+
+// verify returns an error unless each signature is a valid signature by the
+// public key of the input of the TX at its index.
+func (q Quorum) verify(tx *wire.MsgTx,
+	utxos txbuilder.UTXOs,
+	pubKey []byte,
+	sigs [][]byte) error {
+
+	for i, txIn := range tx.TxIn {
+		utxo, ok := utxos.ForOutPoint(txIn.PreviousOutPoint)
+		if !ok {
+			return fmt.Errorf("No UTXO for input %v", i)
+		}
+
+		if err := txbuilder.VerifyMultiSigInput(tx, i, q.RedeemScript, pubKey,
+			sigs[i], utxo); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package wallet
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+	"github.com/tokenized/smart-contract/pkg/txscript"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// badSigner is a Signer with a key of the quorum that returns signatures
+// that don't verify.
+type badSigner struct {
+	key *btcec.PrivateKey
+}
+
+func (s badSigner) PublicKey() []byte {
+	return s.key.PubKey().SerializeCompressed()
+}
+
+func (s badSigner) Sign(ctx context.Context,
+	tx *wire.MsgTx,
+	utxos txbuilder.UTXOs,
+	redeemScript []byte) ([][]byte, error) {
+
+	sigs := [][]byte{}
+	for range tx.TxIn {
+		sigs = append(sigs, []byte{0x30, 0x01, 0x41})
+	}
+
+	return sigs, nil
+}
+
+func TestQuorum_Sign(t *testing.T) {
+	keys := []*btcec.PrivateKey{}
+	pubKeys := []*btcec.PublicKey{}
+
+	for i := 0; i < 4; i++ {
+		key, err := btcec.NewPrivateKey(btcec.S256())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		keys = append(keys, key)
+		pubKeys = append(pubKeys, key.PubKey())
+	}
+
+	// 2 of 4 multisig
+	redeemScript, address, err := txbuilder.NewMultiSigScript(pubKeys, 2,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	foreign, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		signers []Signer
+		signed  []*btcec.PrivateKey
+		err     error
+	}{
+		{
+			name: "valid signers",
+			signers: []Signer{
+				NewKeySigner(keys[0]),
+				NewKeySigner(keys[1]),
+			},
+			signed: []*btcec.PrivateKey{keys[0], keys[1]},
+		},
+		{
+			name: "foreign and bad signers first",
+			signers: []Signer{
+				NewKeySigner(foreign),
+				badSigner{key: keys[0]},
+				NewKeySigner(keys[1]),
+				NewKeySigner(keys[1]),
+				NewKeySigner(keys[2]),
+				NewKeySigner(keys[3]),
+			},
+			signed: []*btcec.PrivateKey{keys[1], keys[2]},
+		},
+		{
+			name: "not enough valid signers",
+			signers: []Signer{
+				NewKeySigner(foreign),
+				badSigner{key: keys[0]},
+				NewKeySigner(keys[1]),
+			},
+			err: ErrQuorumNotReached,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkScript, err := txscript.PayToAddrScript(address)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			utxo := txbuilder.UTXO{
+				Hash:     chainhash.DoubleHashH([]byte(tt.name)),
+				PkScript: pkScript,
+				Value:    10000,
+			}
+
+			tx := wire.NewMsgTx(2)
+			tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&utxo.Hash, utxo.Index), nil))
+			tx.AddTxOut(wire.NewTxOut(9000, pkScript))
+
+			q, err := NewQuorum(redeemScript, tt.signers)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = q.Sign(context.Background(), tx, txbuilder.UTXOs{utxo})
+			if err != tt.err {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+
+			if tt.err != nil {
+				return
+			}
+
+			sigs := map[string][]byte{}
+			for _, key := range tt.signed {
+				sig, err := txbuilder.SignMultiSigInput(tx, 0, redeemScript, key, utxo)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				sigs[hex.EncodeToString(key.PubKey().SerializeCompressed())] = sig
+			}
+
+			want, err := txbuilder.MultiSigUnlockingScript(redeemScript, sigs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if hex.EncodeToString(tx.TxIn[0].SignatureScript) != hex.EncodeToString(want) {
+				t.Fatalf("got unlocking script %x, want %x",
+					tx.TxIn[0].SignatureScript, want)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ package wallet
  */
 
 import (
+	"context"
 	"encoding/hex"
 	"errors"
 
@@ -23,6 +24,7 @@ import (
 
 type Wallet struct {
 	KeyStore      *KeyStore
+	Quorums       map[string]*Quorum
 	PublicAddress string
 	PrivateKey    *btcec.PrivateKey
 	PublicKey     *btcec.PublicKey
@@ -60,6 +62,7 @@ func NewWallet(secret string) (*Wallet, error) {
 
 	w := Wallet{
 		KeyStore:      keystore,
+		Quorums:       map[string]*Quorum{},
 		PublicAddress: pubaddr,
 		PrivateKey:    priv,
 		PublicKey:     pub,
@@ -72,11 +75,87 @@ func (w Wallet) Get(address string) (*btcec.PrivateKey, error) {
 	return w.KeyStore.Get(address)
 }
 
+// AddQuorum adds the Quorum for a multisig contract address.
+func (w Wallet) AddQuorum(q *Quorum) {
+	w.Quorums[q.Address.EncodeAddress()] = q
+}
+
+// GetQuorum returns the Quorum for a multisig contract address.
+func (w Wallet) GetQuorum(address string) (*Quorum, error) {
+	q, ok := w.Quorums[address]
+	if !ok {
+		return nil, ErrQuorumNotFound
+	}
+
+	return q, nil
+}
+
+// IsOffline returns true if TX's from the contract address are signed
+// offline, false otherwise.
+func (w Wallet) IsOffline(address string) bool {
+	q, err := w.GetQuorum(address)
+	if err != nil {
+		return false
+	}
+
+	return q.Offline
+}
+
+// BuildContractTX builds a TX spending from the contract address.
+//
+// A TX from a multisig contract address is signed by the Quorum for the
+// address, otherwise it is signed with the key for the address. The TX is
+// left unsigned if the Quorum signs offline.
+func (w Wallet) BuildContractTX(ctx context.Context,
+	address btcutil.Address,
+	utxos txbuilder.UTXOs,
+	outs []txbuilder.TxOutput,
+	changeAddress btcutil.Address,
+	m protocol.OpReturnMessage) (*txbuilder.Tx, error) {
+
+	if q, err := w.GetQuorum(address.EncodeAddress()); err == nil {
+		return w.BuildMultiSigTX(ctx, q, utxos, outs, changeAddress, m)
+	}
+
+	key, err := w.Get(address.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return w.BuildTX(key, utxos, outs, changeAddress, m)
+}
+
+// SignContractTX signs each input of a TX spending from the contract
+// address, replacing any existing signatures.
+//
+// A TX from a multisig contract address is signed by the Quorum for the
+// address, otherwise it is signed with the key for the address.
+func (w Wallet) SignContractTX(ctx context.Context,
+	address btcutil.Address,
+	tx *wire.MsgTx,
+	utxos txbuilder.UTXOs) error {
+
+	if q, err := w.GetQuorum(address.EncodeAddress()); err == nil {
+		if q.Offline {
+			return ErrOfflineQuorum
+		}
+
+		return q.Sign(ctx, tx, utxos)
+	}
+
+	key, err := w.Get(address.String())
+	if err != nil {
+		return err
+	}
+
+	return txbuilder.SignTX(tx, key, utxos)
+}
+
 func (w Wallet) BuildTX(key *btcec.PrivateKey,
 	utxos txbuilder.UTXOs,
 	outs []txbuilder.TxOutput,
 	changeAddress btcutil.Address,
-	m protocol.OpReturnMessage) (*wire.MsgTx, error) {
+	m protocol.OpReturnMessage) (*txbuilder.Tx, error) {
 
 	outputs := w.buildOutputs(outs)
 
@@ -87,7 +166,41 @@ func (w Wallet) BuildTX(key *btcec.PrivateKey,
 
 	builder := txbuilder.NewTxBuilder(key)
 
-	return builder.Build(utxos, outputs, changeAddress, payload)
+	return builder.BuildTx(utxos, outputs, changeAddress, payload)
+}
+
+// BuildMultiSigTX builds a TX spending from a multisig contract address,
+// signed by the Quorum unless it signs offline.
+func (w Wallet) BuildMultiSigTX(ctx context.Context,
+	q *Quorum,
+	utxos txbuilder.UTXOs,
+	outs []txbuilder.TxOutput,
+	changeAddress btcutil.Address,
+	m protocol.OpReturnMessage) (*txbuilder.Tx, error) {
+
+	outputs := w.buildOutputs(outs)
+
+	payload := make([]byte, m.Len(), m.Len())
+	if _, err := m.Read(payload); err != nil {
+		return nil, err
+	}
+
+	builder := txbuilder.NewMultiSigTxBuilder(q.RedeemScript)
+
+	tx, err := builder.BuildTx(utxos, outputs, changeAddress, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.Offline {
+		return tx, nil
+	}
+
+	if err := q.Sign(ctx, tx.MsgTx, utxos); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
 }
 
 func (w Wallet) buildOutputs(outs []txbuilder.TxOutput) []txbuilder.PayAddress {
@@ -1,6 +1,8 @@
 package wallet
 
 import (
+	"context"
+
 	"github.com/tokenized/smart-contract/pkg/protocol"
 	"github.com/tokenized/smart-contract/pkg/txbuilder"
 	"github.com/tokenized/smart-contract/pkg/wire"
@@ -15,5 +17,16 @@ type WalletInterface interface {
 		txbuilder.UTXOs,
 		[]txbuilder.TxOutput,
 		btcutil.Address,
-		protocol.OpReturnMessage) (*wire.MsgTx, error)
+		protocol.OpReturnMessage) (*txbuilder.Tx, error)
+	GetQuorum(string) (*Quorum, error)
+	BuildContractTX(context.Context,
+		btcutil.Address,
+		txbuilder.UTXOs,
+		[]txbuilder.TxOutput,
+		btcutil.Address,
+		protocol.OpReturnMessage) (*txbuilder.Tx, error)
+	SignContractTX(context.Context,
+		btcutil.Address,
+		*wire.MsgTx,
+		txbuilder.UTXOs) error
 }
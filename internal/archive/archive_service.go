@@ -0,0 +1,152 @@
+package archive
+
+/**
+ * Archive Service
+ *
+ * What is my purpose?
+ * - You keep a record of each completed vote
+ * - You find completed votes by contract, asset, date and outcome
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/tokenized/smart-contract/internal/app/state"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/internal/vote"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+const (
+	// ArchivePrefix is the storage path that Record's are written to.
+	ArchivePrefix = "archive"
+)
+
+var ErrNoContract = errors.New("Query has no contract")
+
+// ArchiveService archives votes as they are resulted. It is registered as
+// a state.Indexer.
+type ArchiveService struct {
+	Storage storage.Storage
+	Votes   vote.VoteService
+}
+
+func NewArchiveService(store storage.Storage,
+	votes vote.VoteService) ArchiveService {
+
+	return ArchiveService{
+		Storage: store,
+		Votes:   votes,
+	}
+}
+
+// Index implements the state.Indexer interface.
+//
+// Each resulted vote of the contract that has not been archived is written
+// as a Record.
+func (s ArchiveService) Index(ctx context.Context, e state.Event) error {
+	c := e.Contract
+
+	for id, vo := range c.Votes {
+		if vo.Result == nil {
+			continue
+		}
+
+		path := s.buildPath(c.ID, id)
+
+		if _, err := s.Storage.Read(ctx, path); err == nil {
+			// already archived
+			continue
+		} else if err != storage.ErrNotFound {
+			return err
+		}
+
+		r, err := s.newRecord(c, id, vo, e.Timestamp)
+		if err != nil {
+			return err
+		}
+
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+
+		if err := s.Storage.Write(ctx, path, b, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Find returns the archived votes selected by the Query, oldest first.
+func (s ArchiveService) Find(ctx context.Context, q Query) ([]Record, error) {
+	if q.ContractID == "" {
+		return nil, ErrNoContract
+	}
+
+	query := map[string]string{
+		"path": fmt.Sprintf("%v/%v", ArchivePrefix, q.ContractID),
+	}
+
+	objects, err := s.Storage.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []Record{}
+
+	for _, b := range objects {
+		r := Record{}
+		if err := json.Unmarshal(b, &r); err != nil {
+			return nil, err
+		}
+
+		if q.Matches(r) {
+			records = append(records, r)
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ResultedAt < records[j].ResultedAt
+	})
+
+	return records, nil
+}
+
+// newRecord returns the Record of a resulted vote.
+func (s ArchiveService) newRecord(c contract.Contract,
+	id string,
+	vo contract.Vote,
+	resultedAt int64) (Record, error) {
+
+	code, err := vote.GetVotingSystemCode(c, vo.Scope())
+	if err != nil {
+		return Record{}, err
+	}
+
+	outcome, err := s.Votes.Outcome(c, vo)
+	if err != nil {
+		return Record{}, err
+	}
+
+	r := Record{
+		ContractID:   c.ID,
+		VoteTxnID:    id,
+		AssetIDs:     vo.Scope(),
+		VotingSystem: code,
+		Vote:         vo,
+		Outcome:      outcome,
+		ResultedAt:   resultedAt,
+	}
+
+	return r, nil
+}
+
+func (s ArchiveService) buildPath(contractID, voteTxnID string) string {
+	return fmt.Sprintf("%v/%v/%v", ArchivePrefix, contractID, voteTxnID)
+}
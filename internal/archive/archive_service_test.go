@@ -0,0 +1,196 @@
+package archive
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/state"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/internal/vote"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+func TestArchiveService_Find(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := storage.NewFilesystemStorage(storage.Config{
+		Root:   dir,
+		Bucket: "test",
+	})
+
+	contractID := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+	otherAssetID := "u0fjvf4wjzrmsacnmonmgekatfqyv0qx"
+	issuerAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	otherUserAddr := "1DnoezsMcKZeQrXVW7eqU5v8HRKmnPSYd2"
+
+	ballot := func(address, assetID string, option uint8) contract.Ballot {
+		return contract.Ballot{
+			Address: address,
+			AssetID: assetID,
+			Vote:    []byte{option},
+		}
+	}
+
+	s := NewArchiveService(store, vote.NewVoteService())
+
+	// each event results another vote, the earlier votes are already
+	// archived
+	events := []struct {
+		id   string
+		vote contract.Vote
+	}{
+		{
+			id: "passed",
+			vote: contract.Vote{
+				AssetID:     assetID,
+				VoteOptions: []byte{65, 66},
+				VoteMax:     1,
+				Ballots: []contract.Ballot{
+					ballot(issuerAddr, assetID, 65),
+				},
+				Result: &contract.BallotResult{65: 10},
+			},
+		},
+		{
+			id: "draw",
+			vote: contract.Vote{
+				AssetID:     otherAssetID,
+				VoteOptions: []byte{65, 66},
+				VoteMax:     1,
+				Ballots: []contract.Ballot{
+					ballot(userAddr, otherAssetID, 65),
+					ballot(otherUserAddr, otherAssetID, 66),
+				},
+				Result: &contract.BallotResult{65: 10, 66: 10},
+			},
+		},
+		{
+			id: "contract",
+			vote: contract.Vote{
+				VoteOptions: []byte{65, 66},
+				VoteMax:     1,
+				Ballots: []contract.Ballot{
+					ballot(userAddr, otherAssetID, 66),
+				},
+				Result: &contract.BallotResult{66: 10},
+			},
+		},
+		{
+			id: "open",
+			vote: contract.Vote{
+				AssetID:     assetID,
+				VoteOptions: []byte{65, 66},
+			},
+		},
+	}
+
+	c := contract.Contract{
+		ID: contractID,
+		Assets: map[string]contract.Asset{
+			assetID: contract.Asset{
+				Holdings: map[string]contract.Holding{
+					issuerAddr: contract.Holding{
+						Address: issuerAddr,
+						Balance: 10,
+					},
+				},
+			},
+			otherAssetID: contract.Asset{
+				Holdings: map[string]contract.Holding{
+					userAddr: contract.Holding{
+						Address: userAddr,
+						Balance: 10,
+					},
+					otherUserAddr: contract.Holding{
+						Address: otherUserAddr,
+						Balance: 10,
+					},
+				},
+			},
+		},
+		Votes: map[string]contract.Vote{},
+	}
+
+	for i, e := range events {
+		c.Votes[e.id] = e.vote
+
+		if err := s.Index(ctx, state.Event{
+			Contract:  c,
+			Timestamp: int64(i + 1),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+		err   error
+	}{
+		{
+			name:  "contract",
+			query: Query{ContractID: contractID},
+			want:  []string{"passed", "draw", "contract"},
+		},
+		{
+			name:  "asset",
+			query: Query{ContractID: contractID, AssetID: assetID},
+			want:  []string{"passed", "contract"},
+		},
+		{
+			name:  "date range",
+			query: Query{ContractID: contractID, From: 2, To: 3},
+			want:  []string{"draw"},
+		},
+		{
+			name:  "passed",
+			query: Query{ContractID: contractID, Outcome: OutcomePassed},
+			want:  []string{"passed", "contract"},
+		},
+		{
+			name:  "failed",
+			query: Query{ContractID: contractID, Outcome: OutcomeFailed},
+			want:  []string{"draw"},
+		},
+		{
+			name:  "no contract",
+			query: Query{},
+			err:   ErrNoContract,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := s.Find(ctx, tt.query)
+			if err != tt.err {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+
+			got := []string{}
+			for _, r := range records {
+				got = append(got, r.VoteTxnID)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got\n%#+v\nwant\n%#+v", got, tt.want)
+			}
+
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got\n%#+v\nwant\n%#+v", got, tt.want)
+				}
+			}
+		})
+	}
+}
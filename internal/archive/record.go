@@ -0,0 +1,76 @@
+package archive
+
+import (
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/internal/vote"
+)
+
+const (
+	// OutcomePassed matches votes that were decided with a single winner.
+	OutcomePassed = "passed"
+
+	// OutcomeFailed matches votes that were not decided, such as a vote
+	// without quorum or a draw.
+	OutcomeFailed = "failed"
+)
+
+// Record is a completed vote, as archived when it was resulted.
+//
+// The Vote holds the definition, ballots and result of the vote.
+type Record struct {
+	ContractID   string           `json:"contract_id"`
+	VoteTxnID    string           `json:"vote_txn_id"`
+	AssetIDs     []string         `json:"asset_ids,omitempty"`
+	VotingSystem byte             `json:"voting_system"`
+	Vote         contract.Vote    `json:"vote"`
+	Outcome      vote.VoteOutcome `json:"outcome"`
+	ResultedAt   int64            `json:"resulted_at"`
+}
+
+// Query selects archived votes of a contract.
+//
+// The zero value of each field other than ContractID matches all votes.
+type Query struct {
+	ContractID string
+
+	// AssetID matches votes held on the asset, including contract level
+	// votes.
+	AssetID string
+
+	// From and To match votes resulted in the range, in nanoseconds. To is
+	// exclusive.
+	From int64
+	To   int64
+
+	// Outcome is OutcomePassed or OutcomeFailed.
+	Outcome string
+}
+
+// Matches returns true if the Record is selected by the Query, false
+// otherwise.
+func (q Query) Matches(r Record) bool {
+	if q.ContractID != r.ContractID {
+		return false
+	}
+
+	if q.AssetID != "" && !r.Vote.InScope(q.AssetID) {
+		return false
+	}
+
+	if q.From != 0 && r.ResultedAt < q.From {
+		return false
+	}
+
+	if q.To != 0 && r.ResultedAt >= q.To {
+		return false
+	}
+
+	switch q.Outcome {
+	case OutcomePassed:
+		return r.Outcome.Passed
+	case OutcomeFailed:
+		return !r.Outcome.Passed
+	}
+
+	return true
+}
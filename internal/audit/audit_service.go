@@ -0,0 +1,109 @@
+package audit
+
+/**
+ * Audit Service
+ *
+ * What is my purpose?
+ * - You write down where the money from each request went
+ * - You keep it, so anyone can check it later
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+const (
+	// AuditPrefix is the storage path that Entry's are written to.
+	AuditPrefix = "audit"
+
+	// KindTransferFee is an Entry of the split of a transfer fee between
+	// the payees of an asset.
+	KindTransferFee = "transfer_fee"
+)
+
+// Entry is a record of a payment made by a response to a request.
+type Entry struct {
+	Kind         string              `json:"kind"`
+	ContractID   string              `json:"contract_id"`
+	AssetID      string              `json:"asset_id"`
+	RequestTxID  string              `json:"request_txid"`
+	ResponseTxID string              `json:"response_txid"`
+	Splits       []contract.FeeSplit `json:"splits,omitempty"`
+	Timestamp    int64               `json:"timestamp"`
+}
+
+// Recorder records audit Entry's.
+type Recorder interface {
+	Record(context.Context, Entry) error
+}
+
+// AuditService stores the audit Entry's of each request.
+type AuditService struct {
+	Storage storage.ReadWriter
+
+	// mu serializes the read, append and write of the Entry's of a request.
+	mu *sync.Mutex
+}
+
+// NewAuditService returns a new AuditService.
+func NewAuditService(store storage.ReadWriter) AuditService {
+	return AuditService{
+		Storage: store,
+		mu:      &sync.Mutex{},
+	}
+}
+
+// Record implements the Recorder interface, adding the Entry to those of
+// the request.
+func (s AuditService) Record(ctx context.Context, e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.Find(ctx, e.ContractID, e.RequestTxID)
+	if err != nil {
+		return err
+	}
+
+	entries = append(entries, e)
+
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	return s.Storage.Write(ctx, s.buildPath(e.ContractID, e.RequestTxID), b, nil)
+}
+
+// Find returns the Entry's of a request, oldest first.
+func (s AuditService) Find(ctx context.Context,
+	contractID string,
+	requestTxID string) ([]Entry, error) {
+
+	entries := []Entry{}
+
+	b, err := s.Storage.Read(ctx, s.buildPath(contractID, requestTxID))
+	if err == storage.ErrNotFound {
+		return entries, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// buildPath returns the path the Entry's of a request are stored at.
+func (s AuditService) buildPath(contractID, requestTxID string) string {
+	return fmt.Sprintf("%v/%v/%v", AuditPrefix, contractID, requestTxID)
+}
@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+func TestAuditService(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := storage.NewFilesystemStorage(storage.Config{
+		Root:   dir,
+		Bucket: "test",
+	})
+
+	s := NewAuditService(store)
+
+	contractID := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	txID := "d5a8b2c3a8b2c3a8b2c3a8b2c3a8b2c3a8b2c3a8b2c3a8b2c3a8b2c3a8b2c3a8"
+
+	entries, err := s.Find(ctx, contractID, txID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("got %v entries, want none", len(entries))
+	}
+
+	want := []Entry{
+		{
+			Kind:        KindTransferFee,
+			ContractID:  contractID,
+			AssetID:     "asset1",
+			RequestTxID: txID,
+			Splits: []contract.FeeSplit{
+				{
+					Role:    contract.FeeRoleIssuer,
+					Address: "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg",
+					Value:   700,
+				},
+				{
+					Role:    contract.FeeRolePlatform,
+					Address: "1BoatSLRHtKNngkdXEeobR76b53LETtpyT",
+					Value:   300,
+				},
+			},
+			Timestamp: 1,
+		},
+		{
+			Kind:        KindTransferFee,
+			ContractID:  contractID,
+			AssetID:     "asset2",
+			RequestTxID: txID,
+			Timestamp:   2,
+		},
+	}
+
+	for _, e := range want {
+		if err := s.Record(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := s.Find(ctx, contractID, txID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%#+v\nwant\n%#+v", got, want)
+	}
+}
@@ -0,0 +1,180 @@
+package benchmark
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Result is the result of a benchmark, averaged over each time it was run.
+type Result struct {
+	// Name is the name of the benchmark, qualified by its package, such as
+	// "github.com/tokenized/smart-contract/pkg/wire.BenchmarkTxHash".
+	Name string
+
+	NsPerOp     float64
+	BytesPerOp  float64
+	AllocsPerOp float64
+
+	// Runs is the number of times the benchmark was run, as with -count.
+	Runs int
+}
+
+// Diff is the change in a benchmark from the baseline.
+type Diff struct {
+	Name string
+
+	// Old and New are nil when the benchmark is missing from the baseline,
+	// or no longer run.
+	Old *Result
+	New *Result
+
+	// Delta is the change in ns/op, as a percentage of the baseline.
+	Delta float64
+
+	// Regressed is true if the benchmark got slower by more than the
+	// threshold.
+	Regressed bool
+}
+
+// Parse reads the output of "go test -bench", returning the Result of each
+// benchmark by name.
+//
+// Benchmarks are qualified by the package of the preceding "pkg:" line, and
+// the GOMAXPROCS suffix, such as "-8", is dropped so results from machines
+// with a different number of CPUs can be compared.
+func Parse(r io.Reader) (map[string]Result, error) {
+	results := map[string]Result{}
+	pkg := ""
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "pkg:" && len(fields) == 2 {
+			pkg = fields[1]
+			continue
+		}
+
+		if !strings.HasPrefix(fields[0], "Benchmark") || len(fields) < 4 {
+			continue
+		}
+
+		name := trimProcs(fields[0])
+		if pkg != "" {
+			name = pkg + "." + name
+		}
+
+		run := Result{
+			Name: name,
+			Runs: 1,
+		}
+
+		// the iterations are followed by pairs of value and unit
+		for i := 2; i+1 < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid value %v of %v", fields[i], name)
+			}
+
+			switch fields[i+1] {
+			case "ns/op":
+				run.NsPerOp = v
+			case "B/op":
+				run.BytesPerOp = v
+			case "allocs/op":
+				run.AllocsPerOp = v
+			}
+		}
+
+		results[name] = average(results[name], run)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Compare returns the Diff of each benchmark in the baseline or current
+// results, sorted by name.
+//
+// A benchmark regressed if its ns/op increased by more than threshold
+// percent.
+func Compare(baseline, current map[string]Result, threshold float64) []Diff {
+	names := map[string]bool{}
+	for name := range baseline {
+		names[name] = true
+	}
+
+	for name := range current {
+		names[name] = true
+	}
+
+	diffs := []Diff{}
+
+	for name := range names {
+		d := Diff{
+			Name: name,
+		}
+
+		if r, ok := baseline[name]; ok {
+			d.Old = &r
+		}
+
+		if r, ok := current[name]; ok {
+			d.New = &r
+		}
+
+		if d.Old != nil && d.New != nil && d.Old.NsPerOp > 0 {
+			d.Delta = (d.New.NsPerOp - d.Old.NsPerOp) / d.Old.NsPerOp * 100
+			d.Regressed = d.Delta > threshold
+		}
+
+		diffs = append(diffs, d)
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Name < diffs[j].Name
+	})
+
+	return diffs
+}
+
+// trimProcs removes the GOMAXPROCS suffix from a benchmark name.
+func trimProcs(name string) string {
+	i := strings.LastIndex(name, "-")
+	if i == -1 {
+		return name
+	}
+
+	if _, err := strconv.Atoi(name[i+1:]); err != nil {
+		return name
+	}
+
+	return name[:i]
+}
+
+// average adds a run to the Result of a benchmark.
+func average(r, run Result) Result {
+	if r.Runs == 0 {
+		return run
+	}
+
+	n := float64(r.Runs)
+
+	r.NsPerOp = (r.NsPerOp*n + run.NsPerOp) / (n + 1)
+	r.BytesPerOp = (r.BytesPerOp*n + run.BytesPerOp) / (n + 1)
+	r.AllocsPerOp = (r.AllocsPerOp*n + run.AllocsPerOp) / (n + 1)
+	r.Runs++
+
+	return r
+}
@@ -0,0 +1,95 @@
+package benchmark
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	output := `goos: linux
+goarch: amd64
+pkg: github.com/tokenized/smart-contract/pkg/wire
+BenchmarkTxHash-8          	 2000000	       600 ns/op
+BenchmarkTxHash-8          	 2000000	       800 ns/op
+BenchmarkDecodeBlock       	  200000	      1164 ns/op	     512 B/op	       4 allocs/op
+PASS
+ok  	github.com/tokenized/smart-contract/pkg/wire	3.142s
+pkg: github.com/tokenized/smart-contract/internal/vote
+BenchmarkGenerateResult-8  	     300	    834165 ns/op
+PASS
+`
+
+	got, err := Parse(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]Result{
+		"github.com/tokenized/smart-contract/pkg/wire.BenchmarkTxHash": Result{
+			Name:    "github.com/tokenized/smart-contract/pkg/wire.BenchmarkTxHash",
+			NsPerOp: 700,
+			Runs:    2,
+		},
+		"github.com/tokenized/smart-contract/pkg/wire.BenchmarkDecodeBlock": Result{
+			Name:        "github.com/tokenized/smart-contract/pkg/wire.BenchmarkDecodeBlock",
+			NsPerOp:     1164,
+			BytesPerOp:  512,
+			AllocsPerOp: 4,
+			Runs:        1,
+		},
+		"github.com/tokenized/smart-contract/internal/vote.BenchmarkGenerateResult": Result{
+			Name:    "github.com/tokenized/smart-contract/internal/vote.BenchmarkGenerateResult",
+			NsPerOp: 834165,
+			Runs:    1,
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%#+v\nwant\n%#+v", got, want)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := map[string]Result{
+		"a": Result{Name: "a", NsPerOp: 100},
+		"b": Result{Name: "b", NsPerOp: 100},
+		"c": Result{Name: "c", NsPerOp: 100},
+	}
+
+	current := map[string]Result{
+		"a": Result{Name: "a", NsPerOp: 105},
+		"b": Result{Name: "b", NsPerOp: 150},
+		"d": Result{Name: "d", NsPerOp: 100},
+	}
+
+	got := Compare(baseline, current, 10)
+
+	want := []Diff{
+		{
+			Name:  "a",
+			Old:   &Result{Name: "a", NsPerOp: 100},
+			New:   &Result{Name: "a", NsPerOp: 105},
+			Delta: 5,
+		},
+		{
+			Name:      "b",
+			Old:       &Result{Name: "b", NsPerOp: 100},
+			New:       &Result{Name: "b", NsPerOp: 150},
+			Delta:     50,
+			Regressed: true,
+		},
+		{
+			Name: "c",
+			Old:  &Result{Name: "c", NsPerOp: 100},
+		},
+		{
+			Name: "d",
+			New:  &Result{Name: "d", NsPerOp: 100},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%#+v\nwant\n%#+v", got, want)
+	}
+}
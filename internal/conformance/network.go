@@ -0,0 +1,73 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tokenized/smart-contract/internal/app/network"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+)
+
+// ErrTXNotFound is returned when a Scenario does not include a TX.
+var ErrTXNotFound = errors.New("TX not found in scenario")
+
+// scenarioNetwork is a network holding only the TX's of a Scenario.
+//
+// TX's sent to the network are kept, and are not broadcast.
+type scenarioNetwork struct {
+	txs  map[chainhash.Hash]*wire.MsgTx
+	sent *[]*wire.MsgTx
+}
+
+func newScenarioNetwork(txs []*wire.MsgTx) scenarioNetwork {
+	n := scenarioNetwork{
+		txs:  map[chainhash.Hash]*wire.MsgTx{},
+		sent: &[]*wire.MsgTx{},
+	}
+
+	for _, tx := range txs {
+		n.txs[tx.TxHash()] = tx
+	}
+
+	return n
+}
+
+func (n scenarioNetwork) Start() error {
+	return nil
+}
+
+func (n scenarioNetwork) Stop() {}
+
+func (n scenarioNetwork) RegisterTxListener(network.Listener) {}
+
+func (n scenarioNetwork) RegisterBlockListener(network.Listener) {}
+
+func (n scenarioNetwork) GetTX(ctx context.Context,
+	id *chainhash.Hash) (*wire.MsgTx, error) {
+
+	tx, ok := n.txs[*id]
+	if !ok {
+		return nil, ErrTXNotFound
+	}
+
+	return tx, nil
+}
+
+func (n scenarioNetwork) SendTX(ctx context.Context,
+	tx *wire.MsgTx) (*chainhash.Hash, error) {
+
+	*n.sent = append(*n.sent, tx)
+
+	hash := tx.TxHash()
+	return &hash, nil
+}
+
+func (n scenarioNetwork) ListTransactions(context.Context,
+	btcutil.Address) ([]btcjson.ListTransactionsResult, error) {
+
+	return nil, nil
+}
@@ -0,0 +1,241 @@
+package conformance
+
+/**
+ * Conformance Runner
+ *
+ * What is my purpose?
+ * - You feed specification scenarios through the TX handler
+ * - You compare the response and state with what the scenario expects
+ * - You tell me which scenarios passed, and why the others failed
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tokenized/smart-contract/cmd/smartcontractd/node"
+	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/internal/app/inspector"
+	"github.com/tokenized/smart-contract/internal/app/wallet"
+	"github.com/tokenized/smart-contract/internal/broadcaster"
+	"github.com/tokenized/smart-contract/internal/feature"
+	"github.com/tokenized/smart-contract/internal/feebump"
+	"github.com/tokenized/smart-contract/internal/latency"
+	"github.com/tokenized/smart-contract/internal/offline"
+	"github.com/tokenized/smart-contract/internal/receipt"
+	"github.com/tokenized/smart-contract/internal/request"
+	"github.com/tokenized/smart-contract/internal/response"
+	"github.com/tokenized/smart-contract/internal/spool"
+	"github.com/tokenized/smart-contract/internal/validator"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+// Result is the outcome of running a Scenario.
+type Result struct {
+	Name   string
+	Passed bool
+
+	// Reason describes why the Scenario failed.
+	Reason string
+}
+
+type Runner struct {
+	Config config.Config
+}
+
+func NewRunner(config config.Config) Runner {
+	return Runner{
+		Config: config,
+	}
+}
+
+// RunAll runs each of the Scenario's, in order.
+func (r Runner) RunAll(ctx context.Context, scenarios []Scenario) []Result {
+	results := []Result{}
+
+	for _, s := range scenarios {
+		results = append(results, r.Run(ctx, s))
+	}
+
+	return results
+}
+
+// Run processes the request of the Scenario through the pipeline, with
+// the initial state of the Scenario, and checks the response and resulting
+// state.
+func (r Runner) Run(ctx context.Context, s Scenario) Result {
+	response, st, err := r.process(ctx, s)
+	if err != nil {
+		return Result{
+			Name:   s.Name,
+			Reason: err.Error(),
+		}
+	}
+
+	if response != s.Response {
+		return Result{
+			Name:   s.Name,
+			Reason: fmt.Sprintf("got response %q, want %q", response, s.Response),
+		}
+	}
+
+	if len(s.State) > 0 {
+		if err := matchState(st, s.State); err != nil {
+			return Result{
+				Name:   s.Name,
+				Reason: err.Error(),
+			}
+		}
+	}
+
+	return Result{
+		Name:   s.Name,
+		Passed: true,
+	}
+}
+
+// process returns the action code of the response to the request of the
+// Scenario, and the Contract state after it was processed.
+//
+// The request is handled by the TX handler of the smart contract, as it is
+// when seen on the network, and the response is the last TX it broadcast.
+// There is no response to a request the handler ignored.
+func (r Runner) process(ctx context.Context,
+	s Scenario) (string, memoryState, error) {
+
+	txs := []*wire.MsgTx{}
+	for _, raw := range s.Inputs {
+		tx, err := decodeTX(raw)
+		if err != nil {
+			return "", nil, err
+		}
+
+		txs = append(txs, tx)
+	}
+
+	tx, err := decodeTX(s.Request)
+	if err != nil {
+		return "", nil, err
+	}
+
+	w, err := wallet.NewWallet(s.Key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	network := newScenarioNetwork(txs)
+	store := memoryStorage{}
+
+	st := memoryState{}
+	if s.Contract != nil {
+		st[s.Contract.ID] = *s.Contract
+	}
+
+	inspectorService := inspector.NewInspectorService(network)
+
+	h := node.NewTXHandler(r.Config,
+		network,
+		*w,
+		inspectorService,
+		broadcaster.NewBroadcastService(network),
+		validator.NewValidatorService(r.Config, *w, st),
+		request.NewRequestService(r.Config, *w, st, inspectorService),
+		response.NewResponseService(r.Config, st),
+		latency.NewLatencyService(store, r.Config.SLA),
+		offline.NewOfflineService(store, network),
+		feebump.NewFeeBumpService(r.Config.FeeBump, store, network, *w),
+		receipt.NewReceiptService(store, *w),
+		spool.NewSpoolService(r.Config.Spool, store, nil),
+		feature.NewFeatureService(store))
+
+	if err := h.Handle(ctx, tx); err != nil {
+		return "", nil, err
+	}
+
+	if len(*network.sent) == 0 {
+		return "", st, nil
+	}
+
+	sent := *network.sent
+	code, err := r.actionCode(inspectorService, sent[len(sent)-1])
+
+	return code, st, err
+}
+
+// actionCode returns the action code of the protocol message in the TX.
+func (r Runner) actionCode(s inspector.InspectorService,
+	tx *wire.MsgTx) (string, error) {
+
+	itx, err := s.MakeTransaction(tx)
+	if err != nil {
+		return "", err
+	}
+
+	if itx == nil {
+		return "", fmt.Errorf("No protocol message in response")
+	}
+
+	return itx.MsgProto.Type(), nil
+}
+
+// matchState returns an error if the state does not hold the fields
+// expected.
+func matchState(st memoryState, expected json.RawMessage) error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	var got, want interface{}
+
+	if err := json.Unmarshal(b, &got); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(expected, &want); err != nil {
+		return err
+	}
+
+	return match("state", got, want)
+}
+
+// match returns an error if got does not hold the values of want. Objects
+// in got may have fields that are not in want.
+func match(path string, got, want interface{}) error {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%v : got %v, want object", path, got)
+		}
+
+		for k, v := range w {
+			if err := match(path+"."+k, g[k], v); err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			return fmt.Errorf("%v : got %v, want %v", path, got, want)
+		}
+
+		for i := range w {
+			if err := match(fmt.Sprintf("%v[%v]", path, i), g[i], w[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if got != want {
+		return fmt.Errorf("%v : got %v, want %v", path, got, want)
+	}
+
+	return nil
+}
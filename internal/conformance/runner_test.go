@@ -0,0 +1,188 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+	"github.com/tokenized/smart-contract/pkg/txscript"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"go.uber.org/zap"
+)
+
+func TestRunner_Run(t *testing.T) {
+	ctx := logger.ContextWithLogger(logger.NewContext(), zap.NewNop())
+
+	issuerKey := newKey(t)
+	contractKey := newKey(t)
+
+	issuerAddr := newAddress(t, issuerKey)
+	contractAddr := newAddress(t, contractKey)
+
+	// the TX funding the issuer
+	funding := wire.NewMsgTx(wire.TxVersion)
+	funding.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, 0), nil))
+	funding.AddTxOut(wire.NewTxOut(100000, payTo(t, issuerAddr)))
+
+	offer := protocol.NewContractOffer()
+	offer.ContractName = []byte("Conformance")
+
+	payload := make([]byte, offer.Len())
+	if _, err := offer.Read(payload); err != nil {
+		t.Fatal(err)
+	}
+
+	fundingHash := funding.TxHash()
+
+	request := wire.NewMsgTx(wire.TxVersion)
+	request.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&fundingHash, 0), nil))
+	request.AddTxOut(wire.NewTxOut(50000, payTo(t, contractAddr)))
+	request.AddTxOut(wire.NewTxOut(0, payload))
+
+	wif, err := btcutil.NewWIF(contractKey, &chaincfg.MainNetParams, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := fmt.Sprintf(`{"%v": {"issuer_address": "%v", "name": "Conformance"}}`,
+		contractAddr.EncodeAddress(), issuerAddr.EncodeAddress())
+
+	tests := []struct {
+		name     string
+		response string
+		state    string
+		passed   bool
+	}{
+		{
+			name:     "contract formed",
+			response: protocol.CodeContractFormation,
+			state:    state,
+			passed:   true,
+		},
+		{
+			name:     "wrong response",
+			response: protocol.CodeRejection,
+			passed:   false,
+		},
+		{
+			name:     "wrong state",
+			response: protocol.CodeContractFormation,
+			state:    `{"foo": {}}`,
+			passed:   false,
+		},
+	}
+
+	config := config.Config{
+		Fee: config.Fee{
+			Address: issuerAddr,
+			Value:   546,
+		},
+	}
+
+	runner := NewRunner(config)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Scenario{
+				Name:     tt.name,
+				Key:      wif.String(),
+				Inputs:   []string{encodeTX(t, funding)},
+				Request:  encodeTX(t, request),
+				Response: tt.response,
+			}
+
+			if tt.state != "" {
+				s.State = json.RawMessage(tt.state)
+			}
+
+			result := runner.Run(ctx, s)
+
+			if result.Passed != tt.passed {
+				t.Errorf("got passed %v, want %v : %v", result.Passed, tt.passed,
+					result.Reason)
+			}
+		})
+	}
+}
+
+// TestScenarios runs the scenarios committed with the runner. They expect
+// the contract fee to be paid to the fee address.
+func TestScenarios(t *testing.T) {
+	ctx := logger.ContextWithLogger(logger.NewContext(), zap.NewNop())
+
+	scenarios, err := LoadScenarios("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(scenarios) == 0 {
+		t.Fatal("got no scenarios")
+	}
+
+	feeAddr, err := btcutil.DecodeAddress("13ipLCuc5HaMRe5G8i5kKqn4phGDt3SDPA",
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := config.Config{
+		Fee: config.Fee{
+			Address: feeAddr,
+			Value:   546,
+		},
+	}
+
+	for _, result := range NewRunner(config).RunAll(ctx, scenarios) {
+		if !result.Passed {
+			t.Errorf("%v failed : %v", result.Name, result.Reason)
+		}
+	}
+}
+
+func newKey(t *testing.T) *btcec.PrivateKey {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func newAddress(t *testing.T, key *btcec.PrivateKey) btcutil.Address {
+	address, err := btcutil.NewAddressPubKeyHash(
+		btcutil.Hash160(key.PubKey().SerializeCompressed()),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return address
+}
+
+func payTo(t *testing.T, address btcutil.Address) []byte {
+	script, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return script
+}
+
+func encodeTX(t *testing.T, tx *wire.MsgTx) string {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	return hex.EncodeToString(buf.Bytes())
+}
@@ -0,0 +1,91 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+// Scenario is a request defined by the protocol specification, with the
+// response and Contract state expected from processing it.
+type Scenario struct {
+	Name string `json:"name"`
+
+	// Key is the WIF of the private key for the contract address.
+	Key string `json:"key"`
+
+	// Contract is the state of the Contract before the request. It is
+	// omitted for requests that create a Contract.
+	Contract *contract.Contract `json:"contract,omitempty"`
+
+	// Inputs are the raw hex TX's spent by the request.
+	Inputs []string `json:"inputs"`
+
+	// Request is the raw hex request TX.
+	Request string `json:"request"`
+
+	// Response is the action code of the expected response.
+	Response string `json:"response"`
+
+	// State holds the fields expected in the Contract state after the
+	// request, keyed by contract address. Fields that are not present are
+	// not checked.
+	State json.RawMessage `json:"state,omitempty"`
+}
+
+// LoadScenarios returns the Scenario's in the .json files of a directory,
+// ordered by file name.
+//
+// A Scenario without a name is named after its file.
+func LoadScenarios(dir string) ([]Scenario, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+
+	scenarios := []Scenario{}
+
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		s := Scenario{}
+		if err := json.Unmarshal(b, &s); err != nil {
+			return nil, err
+		}
+
+		if s.Name == "" {
+			s.Name = strings.TrimSuffix(filepath.Base(file), ".json")
+		}
+
+		scenarios = append(scenarios, s)
+	}
+
+	return scenarios, nil
+}
+
+// decodeTX returns the TX from raw hex.
+func decodeTX(raw string) (*wire.MsgTx, error) {
+	b, err := hex.DecodeString(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	tx := wire.MsgTx{}
+	if err := tx.Deserialize(bytes.NewReader(b)); err != nil {
+		return nil, err
+	}
+
+	return &tx, nil
+}
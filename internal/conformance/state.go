@@ -0,0 +1,74 @@
+package conformance
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tokenized/smart-contract/internal/app/state"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+// memoryState holds Contract state for a single Scenario.
+type memoryState map[string]contract.Contract
+
+func (m memoryState) Write(ctx context.Context, c contract.Contract) error {
+	m[c.ID] = c
+	return nil
+}
+
+func (m memoryState) Read(ctx context.Context,
+	id string) (*contract.Contract, error) {
+
+	c, ok := m[id]
+	if !ok {
+		return nil, state.ErrContractNotFound
+	}
+
+	return &c, nil
+}
+
+// memoryStorage holds the records the services of the TX handler write for
+// a single Scenario.
+type memoryStorage map[string][]byte
+
+func (m memoryStorage) Read(ctx context.Context, key string) ([]byte, error) {
+	b, ok := m[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	return b, nil
+}
+
+func (m memoryStorage) Write(ctx context.Context,
+	key string,
+	body []byte,
+	opts *storage.Options) error {
+
+	m[key] = body
+	return nil
+}
+
+func (m memoryStorage) Remove(ctx context.Context, key string) error {
+	delete(m, key)
+	return nil
+}
+
+// Search returns the records directly under the path of the query.
+func (m memoryStorage) Search(ctx context.Context,
+	query map[string]string) ([][]byte, error) {
+
+	prefix := query["path"] + "/"
+
+	objects := [][]byte{}
+	for key, b := range m {
+		if strings.HasPrefix(key, prefix) &&
+			!strings.Contains(strings.TrimPrefix(key, prefix), "/") {
+
+			objects = append(objects, b)
+		}
+	}
+
+	return objects, nil
+}
@@ -0,0 +1,120 @@
+package document
+
+/**
+ * Document Service
+ *
+ * What is my purpose?
+ * - You store the documents attached to assets
+ * - You record the hash of each document in the asset
+ * - You tell me if a retrieved document does not match its hash
+ */
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+const (
+	// DocumentPrefix is the storage path that documents are written to.
+	DocumentPrefix = "documents"
+
+	// MaxDocumentSize is the largest document that can be attached, in
+	// bytes.
+	MaxDocumentSize = 10 << 20
+)
+
+var (
+	ErrDocumentNotFound = errs.New(errs.NotFound, "Document not found")
+	ErrDocumentEmpty    = errs.New(errs.Invalid, "Document is empty")
+	ErrDocumentTooLarge = errs.New(errs.Invalid, "Document is too large")
+	ErrHashMismatch     = errors.New("Document does not match hash")
+)
+
+type DocumentService struct {
+	Storage storage.ReadWriter
+}
+
+func NewDocumentService(store storage.ReadWriter) DocumentService {
+	return DocumentService{
+		Storage: store,
+	}
+}
+
+// Attach stores the content of a document, and returns the Asset with the
+// hash of the document recorded under the name.
+//
+// A document previously attached with the same name is replaced. The
+// caller is responsible for writing the Asset to the state.
+func (s DocumentService) Attach(ctx context.Context,
+	a contract.Asset,
+	name string,
+	content []byte) (contract.Asset, error) {
+
+	if len(content) == 0 {
+		return a, ErrDocumentEmpty
+	}
+
+	if len(content) > MaxDocumentSize {
+		return a, ErrDocumentTooLarge
+	}
+
+	d := contract.NewDocument(name, content)
+
+	if err := s.Storage.Write(ctx, s.buildPath(a.ID, d), content, nil); err != nil {
+		return a, err
+	}
+
+	documents := map[string]contract.Document{}
+	for n, existing := range a.Documents {
+		documents[n] = existing
+	}
+
+	documents[name] = d
+	a.Documents = documents
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+	log.Infof("Attached document %v to asset %v : %x", name, a.ID, d.Hash)
+
+	return a, nil
+}
+
+// Retrieve returns the content of a document attached to the Asset.
+//
+// ErrHashMismatch is returned if the stored content does not match the hash
+// recorded in the Asset.
+func (s DocumentService) Retrieve(ctx context.Context,
+	a contract.Asset,
+	name string) ([]byte, error) {
+
+	d, ok := a.Documents[name]
+	if !ok {
+		return nil, ErrDocumentNotFound
+	}
+
+	content, err := s.Storage.Read(ctx, s.buildPath(a.ID, d))
+	if err != nil {
+		if err == storage.ErrNotFound {
+			err = ErrDocumentNotFound
+		}
+
+		return nil, err
+	}
+
+	if !d.Matches(content) {
+		return nil, ErrHashMismatch
+	}
+
+	return content, nil
+}
+
+// buildPath returns the storage path of a document. Documents are stored by
+// hash, so earlier versions of a document are not overwritten.
+func (s DocumentService) buildPath(assetID string, d contract.Document) string {
+	return fmt.Sprintf("%v/%v/%x", DocumentPrefix, assetID, d.Hash)
+}
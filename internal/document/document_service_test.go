@@ -0,0 +1,97 @@
+package document
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+type memoryStorage map[string][]byte
+
+func (m memoryStorage) Read(ctx context.Context, key string) ([]byte, error) {
+	b, ok := m[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	return b, nil
+}
+
+func (m memoryStorage) Write(ctx context.Context,
+	key string,
+	body []byte,
+	opts *storage.Options) error {
+
+	m[key] = body
+	return nil
+}
+
+func TestDocumentService_Retrieve(t *testing.T) {
+	ctx := context.Background()
+	content := []byte("Terms of the asset")
+
+	tests := []struct {
+		name   string
+		before func(memoryStorage)
+		doc    string
+		err    error
+	}{
+		{
+			name:   "attached",
+			before: func(store memoryStorage) {},
+			doc:    "terms",
+		},
+		{
+			name:   "not attached",
+			before: func(store memoryStorage) {},
+			doc:    "prospectus",
+			err:    ErrDocumentNotFound,
+		},
+		{
+			name: "content missing",
+			before: func(store memoryStorage) {
+				for key := range store {
+					delete(store, key)
+				}
+			},
+			doc: "terms",
+			err: ErrDocumentNotFound,
+		},
+		{
+			name: "content changed",
+			before: func(store memoryStorage) {
+				for key := range store {
+					store[key] = []byte("Other terms")
+				}
+			},
+			doc: "terms",
+			err: ErrHashMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memoryStorage{}
+			s := NewDocumentService(store)
+
+			a, err := s.Attach(ctx, contract.Asset{ID: "1"}, "terms", content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.before(store)
+
+			got, err := s.Retrieve(ctx, a, tt.doc)
+			if err != tt.err {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+
+			if err == nil && !bytes.Equal(got, content) {
+				t.Errorf("got %q, want %q", got, content)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package feature
+
+/**
+ * Feature Service
+ *
+ * What is my purpose?
+ * - You remember which optional behaviors are turned on for each contract
+ * - You let an operator turn them on and off, one contract at a time
+ * - You fall back to the defaults for anything not set
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+const (
+	// FeaturePrefix is the storage path the flags of each contract are
+	// written to.
+	FeaturePrefix = "features"
+
+	// FlagZeroConf processes requests as soon as they are seen, rather than
+	// once they are confirmed in a block.
+	FlagZeroConf = "zero_conf"
+
+	// FlagFeeBump replaces responses that are slow to confirm with ones
+	// paying a higher fee.
+	FlagFeeBump = "fee_bump"
+
+	// FlagStrictProtocol rejects requests with an OP_RETURN payload that is
+	// not exactly the encoding of the message it decodes to.
+	FlagStrictProtocol = "strict_protocol"
+
+	// FlagWebhooks posts vote notifications to the configured webhooks.
+	FlagWebhooks = "webhooks"
+)
+
+// ErrUnknownFlag is returned when setting a flag that does not exist.
+var ErrUnknownFlag = errs.New(errs.NotFound, "Unknown feature flag")
+
+// Flags holds whether each feature is enabled, by flag name.
+type Flags map[string]bool
+
+// Defaults are the flags of a contract that an operator has not changed.
+var Defaults = Flags{
+	FlagZeroConf:       true,
+	FlagFeeBump:        true,
+	FlagStrictProtocol: false,
+	FlagWebhooks:       true,
+}
+
+// FeatureService stores the feature flags of each contract.
+type FeatureService struct {
+	Storage storage.ReadWriter
+}
+
+// NewFeatureService returns a new FeatureService.
+func NewFeatureService(store storage.ReadWriter) FeatureService {
+	return FeatureService{
+		Storage: store,
+	}
+}
+
+// Flags returns all flags of a contract, with the Defaults for any that
+// have not been set.
+func (s FeatureService) Flags(ctx context.Context,
+	contractID string) (Flags, error) {
+
+	flags := Flags{}
+	for flag, enabled := range Defaults {
+		flags[flag] = enabled
+	}
+
+	b, err := s.Storage.Read(ctx, s.buildPath(contractID))
+	if err == storage.ErrNotFound {
+		return flags, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	stored := Flags{}
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, err
+	}
+
+	for flag, enabled := range stored {
+		if _, ok := Defaults[flag]; ok {
+			flags[flag] = enabled
+		}
+	}
+
+	return flags, nil
+}
+
+// Enabled returns true if the flag is enabled for the contract.
+//
+// If the flags can't be read the default is returned, so a storage failure
+// doesn't change how requests are handled.
+func (s FeatureService) Enabled(ctx context.Context,
+	contractID string,
+	flag string) bool {
+
+	flags, err := s.Flags(ctx, contractID)
+	if err != nil {
+		log := logger.NewLoggerFromContext(ctx).Sugar()
+		log.Errorf("Failed to read features of %v : %v", contractID, err)
+
+		return Defaults[flag]
+	}
+
+	return flags[flag]
+}
+
+// Set enables or disables a flag for the contract.
+func (s FeatureService) Set(ctx context.Context,
+	contractID string,
+	flag string,
+	enabled bool) error {
+
+	if _, ok := Defaults[flag]; !ok {
+		return ErrUnknownFlag
+	}
+
+	flags, err := s.Flags(ctx, contractID)
+	if err != nil {
+		return err
+	}
+
+	flags[flag] = enabled
+
+	b, err := json.Marshal(flags)
+	if err != nil {
+		return err
+	}
+
+	return s.Storage.Write(ctx, s.buildPath(contractID), b, nil)
+}
+
+// buildPath returns the path the flags of a contract are stored at.
+func (s FeatureService) buildPath(contractID string) string {
+	return fmt.Sprintf("%v/%v", FeaturePrefix, contractID)
+}
@@ -0,0 +1,84 @@
+package feature
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+func TestFeatureService(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := storage.NewFilesystemStorage(storage.Config{
+		Root:   dir,
+		Bucket: "test",
+	})
+
+	contractID := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	otherID := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+
+	s := NewFeatureService(store)
+
+	if err := s.Set(ctx, contractID, FlagZeroConf, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Set(ctx, contractID, FlagStrictProtocol, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Set(ctx, contractID, "teleport", true); err != ErrUnknownFlag {
+		t.Fatalf("got %v, want %v", err, ErrUnknownFlag)
+	}
+
+	tests := []struct {
+		name       string
+		contractID string
+		want       Flags
+	}{
+		{
+			name:       "set",
+			contractID: contractID,
+			want: Flags{
+				FlagZeroConf:       false,
+				FlagFeeBump:        true,
+				FlagStrictProtocol: true,
+				FlagWebhooks:       true,
+			},
+		},
+		{
+			name:       "defaults",
+			contractID: otherID,
+			want:       Defaults,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Flags(ctx, tt.contractID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%#+v\nwant\n%#+v", got, tt.want)
+			}
+
+			for flag, want := range tt.want {
+				if got := s.Enabled(ctx, tt.contractID, flag); got != want {
+					t.Errorf("%v : got %v, want %v", flag, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package feebump
+
+/**
+ * Fee Bump Service
+ *
+ * What is my purpose?
+ * - You watch responses until they are confirmed
+ * - You pay a higher fee for stuck responses with a child TX spending
+ *   their change (CPFP)
+ * - You record the chain of children
+ */
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/internal/app/network"
+	"github.com/tokenized/smart-contract/internal/app/wallet"
+	"github.com/tokenized/smart-contract/pkg/storage"
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+)
+
+const (
+	// FeeBumpPrefix is the storage path that TrackedTX's are written to.
+	FeeBumpPrefix = "feebump"
+
+	// bumpInterval is how often tracked responses are checked.
+	bumpInterval = 30 * time.Second
+)
+
+// ErrNoChange is returned when the fee of a response cannot be increased
+// as there is not enough change left to pay it from.
+var ErrNoChange = errors.New("Not enough change to increase fee")
+
+type FeeBumpService struct {
+	Config  config.FeeBump
+	Storage storage.Storage
+	Network network.NetworkInterface
+	Wallet  wallet.WalletInterface
+
+	mu *sync.Mutex
+
+	// unconfirmed responses, keyed by the TX ID of every broadcast in their
+	// chain
+	tracked map[string]*TrackedTX
+}
+
+func NewFeeBumpService(config config.FeeBump,
+	store storage.Storage,
+	network network.NetworkInterface,
+	wallet wallet.WalletInterface) FeeBumpService {
+
+	return FeeBumpService{
+		Config:  config,
+		Storage: store,
+		Network: network,
+		Wallet:  wallet,
+		mu:      &sync.Mutex{},
+		tracked: map[string]*TrackedTX{},
+	}
+}
+
+// Load reads the responses that were still unconfirmed when the service
+// was last stopped, and resumes tracking them.
+func (s FeeBumpService) Load(ctx context.Context) error {
+	query := map[string]string{
+		"path": FeeBumpPrefix,
+	}
+
+	objects, err := s.Storage.Search(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, b := range objects {
+		t := &TrackedTX{}
+		if err := json.Unmarshal(b, t); err != nil {
+			return err
+		}
+
+		if t.ConfirmedTxID != "" || len(t.Broadcasts) == 0 {
+			continue
+		}
+
+		for _, b := range t.Broadcasts {
+			s.tracked[b.TxID] = t
+		}
+	}
+
+	return nil
+}
+
+// Run bumps the fee of stuck responses until the context is done.
+func (s FeeBumpService) Run(ctx context.Context) {
+	if s.Config.After == 0 {
+		return
+	}
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(bumpInterval):
+		}
+
+		if err := s.Bump(ctx); err != nil {
+			log.Error(err)
+		}
+	}
+}
+
+// Track starts watching a broadcast response until it is confirmed.
+//
+// Only responses that pay change back to the contract are tracked, as the
+// child paying the higher fee must be signed by the contract. Responses
+// without change, or that pay the change to the requester, are not
+// tracked.
+func (s FeeBumpService) Track(ctx context.Context,
+	requestTxID string,
+	contractAddress btcutil.Address,
+	tx *wire.MsgTx,
+	utxos txbuilder.UTXOs,
+	changeIndex int) error {
+
+	if s.Config.After == 0 || changeIndex < 0 || changeIndex >= len(tx.TxOut) {
+		return nil
+	}
+
+	inputs := txbuilder.UTXOs{}
+
+	for i, txIn := range tx.TxIn {
+		utxo, ok := utxos.ForOutPoint(txIn.PreviousOutPoint)
+		if !ok {
+			return fmt.Errorf("No UTXO for input %v", i)
+		}
+
+		inputs = append(inputs, utxo)
+	}
+
+	// the inputs are spent from the contract address, so the change must
+	// pay the same script to be spendable by the contract
+	if len(inputs) == 0 ||
+		!bytes.Equal(tx.TxOut[changeIndex].PkScript, inputs[0].PkScript) {
+		return nil
+	}
+
+	b, err := newBroadcast(tx, inputs, time.Now().UnixNano())
+	if err != nil {
+		return err
+	}
+
+	t := &TrackedTX{
+		RequestTxID:     requestTxID,
+		ContractAddress: contractAddress.EncodeAddress(),
+		ChangeIndex:     changeIndex,
+		Broadcasts:      []Broadcast{b},
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tracked[b.TxID] = t
+
+	return s.write(ctx, *t)
+}
+
+// Confirmed stops watching a response when it, or any of its children, is
+// confirmed.
+func (s FeeBumpService) Confirmed(ctx context.Context, txID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tracked[txID]
+	if !ok {
+		return nil
+	}
+
+	for _, b := range t.Broadcasts {
+		delete(s.tracked, b.TxID)
+	}
+
+	t.ConfirmedTxID = txID
+
+	return s.write(ctx, *t)
+}
+
+// Bump increases the fee of each response that has been unconfirmed for
+// longer than the configured time since its last broadcast.
+//
+// The fee is paid by a child TX spending the change of the response, or
+// the output of the previous child, back to the contract. Miners include
+// the response to collect the fee of the child.
+func (s FeeBumpService) Bump(ctx context.Context) error {
+	if s.Config.After == 0 {
+		return nil
+	}
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	for txID, t := range s.tracked {
+		latest := t.Latest()
+
+		if txID != latest.TxID {
+			// an earlier broadcast, the latest is handled separately
+			continue
+		}
+
+		if now.Sub(time.Unix(0, latest.BroadcastAt)) < s.Config.After {
+			continue
+		}
+
+		if err := s.bump(ctx, t, now); err != nil {
+			log.Errorf("Failed to bump %v : %v", latest.TxID, err)
+		}
+	}
+
+	return nil
+}
+
+// bump broadcasts a child of the latest TX of a response, paying the fee
+// increase.
+func (s FeeBumpService) bump(ctx context.Context,
+	t *TrackedTX,
+	now time.Time) error {
+
+	utxo, err := t.spendable()
+	if err != nil {
+		return err
+	}
+
+	if utxo.Value < s.Config.Increase+txbuilder.DustMinimumOutput {
+		return ErrNoChange
+	}
+
+	tx := &wire.MsgTx{
+		Version: wire.TxVersion,
+	}
+
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{
+		Hash:  utxo.Hash,
+		Index: utxo.Index,
+	}, nil))
+
+	tx.AddTxOut(wire.NewTxOut(int64(utxo.Value-s.Config.Increase), utxo.PkScript))
+
+	address, err := btcutil.DecodeAddress(t.ContractAddress,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		return err
+	}
+
+	inputs := txbuilder.UTXOs{utxo}
+
+	if err := s.Wallet.SignContractTX(ctx, address, tx, inputs); err != nil {
+		return err
+	}
+
+	if _, err := s.Network.SendTX(ctx, tx); err != nil {
+		return err
+	}
+
+	b, err := newBroadcast(tx, inputs, now.UnixNano())
+	if err != nil {
+		return err
+	}
+
+	latest := t.Latest()
+
+	t.Broadcasts = append(t.Broadcasts, b)
+	s.tracked[b.TxID] = t
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+	log.Infof("Bumped %v with child %v for request %v, fee %v",
+		latest.TxID, b.TxID, t.RequestTxID, b.Fee)
+
+	return s.write(ctx, *t)
+}
+
+func (s FeeBumpService) write(ctx context.Context, t TrackedTX) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	return s.Storage.Write(ctx, s.buildPath(t.RequestTxID), b, nil)
+}
+
+func (s FeeBumpService) buildPath(txID string) string {
+	return fmt.Sprintf("%v/%v", FeeBumpPrefix, txID)
+}
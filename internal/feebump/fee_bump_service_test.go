@@ -0,0 +1,352 @@
+package feebump
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/internal/app/network"
+	"github.com/tokenized/smart-contract/internal/app/wallet"
+	"github.com/tokenized/smart-contract/pkg/storage"
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+	"github.com/tokenized/smart-contract/pkg/txscript"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+)
+
+type memoryStorage map[string][]byte
+
+func (m memoryStorage) Read(ctx context.Context, key string) ([]byte, error) {
+	b, ok := m[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	return b, nil
+}
+
+func (m memoryStorage) Write(ctx context.Context,
+	key string,
+	body []byte,
+	opts *storage.Options) error {
+
+	m[key] = body
+	return nil
+}
+
+func (m memoryStorage) Remove(ctx context.Context, key string) error {
+	delete(m, key)
+	return nil
+}
+
+func (m memoryStorage) Search(ctx context.Context,
+	query map[string]string) ([][]byte, error) {
+
+	objects := [][]byte{}
+	for key, b := range m {
+		if strings.HasPrefix(key, query["path"]+"/") {
+			objects = append(objects, b)
+		}
+	}
+
+	return objects, nil
+}
+
+// testNetwork records the TX's sent to it.
+type testNetwork struct {
+	sent *[]*wire.MsgTx
+}
+
+func (n testNetwork) Start() error {
+	return nil
+}
+
+func (n testNetwork) Stop() {}
+
+func (n testNetwork) RegisterTxListener(network.Listener) {}
+
+func (n testNetwork) RegisterBlockListener(network.Listener) {}
+
+func (n testNetwork) GetTX(context.Context,
+	*chainhash.Hash) (*wire.MsgTx, error) {
+
+	return nil, nil
+}
+
+func (n testNetwork) SendTX(ctx context.Context,
+	tx *wire.MsgTx) (*chainhash.Hash, error) {
+
+	*n.sent = append(*n.sent, tx)
+
+	hash := tx.TxHash()
+	return &hash, nil
+}
+
+func (n testNetwork) ListTransactions(context.Context,
+	btcutil.Address) ([]btcjson.ListTransactionsResult, error) {
+
+	return nil, nil
+}
+
+// newResponse returns a wallet for a contract, and a response from it
+// paying the receiver with the change to the change address. The change
+// address is the contract address when nil.
+func newResponse(t *testing.T,
+	changeAddress btcutil.Address) (*wallet.Wallet, btcutil.Address, *txbuilder.Tx, txbuilder.UTXOs) {
+
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wif, err := btcutil.NewWIF(key, &chaincfg.MainNetParams, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := wallet.NewWallet(wif.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address, err := btcutil.DecodeAddress(w.PublicAddress,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := chainhash.NewHashFromStr("2c2786fe332e94ea61f2a0aef6037cd08bf6495f800a4c829c0f1c07e6104ab8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	utxos := txbuilder.UTXOs{
+		txbuilder.UTXO{
+			Hash:     *hash,
+			Index:    0,
+			PkScript: pkScript,
+			Value:    100000,
+		},
+	}
+
+	receiver, err := btcutil.DecodeAddress("18H59cUZMAPRhp74xoeE6LXingw3Wxr3VG",
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changeAddress == nil {
+		changeAddress = address
+	}
+
+	builder := txbuilder.NewTxBuilder(key)
+
+	tx, err := builder.BuildTx(utxos,
+		[]txbuilder.PayAddress{txbuilder.NewPayAddress(receiver, 546)},
+		changeAddress,
+		[]byte{0x6a, 0x02, 0x00, 0x20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return w, address, tx, utxos
+}
+
+func TestFeeBumpService_Bump(t *testing.T) {
+	ctx := context.Background()
+
+	w, address, tx, utxos := newResponse(t, nil)
+
+	if tx.ChangeIndex != 1 {
+		t.Fatalf("got change index %v, want 1", tx.ChangeIndex)
+	}
+
+	sent := []*wire.MsgTx{}
+
+	fb := config.FeeBump{
+		After:    time.Nanosecond,
+		Increase: 500,
+	}
+
+	s := NewFeeBumpService(fb, memoryStorage{}, testNetwork{&sent}, *w)
+
+	if err := s.Track(ctx, "request", address, tx.MsgTx, utxos,
+		tx.ChangeIndex); err != nil {
+		t.Fatal(err)
+	}
+
+	// each bump is a child of the one before it
+	parent := tx.MsgTx
+	index := uint32(tx.ChangeIndex)
+
+	for i := 0; i < 2; i++ {
+		time.Sleep(time.Millisecond)
+
+		if err := s.Bump(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(sent) != i+1 {
+			t.Fatalf("got %v children, want %v", len(sent), i+1)
+		}
+
+		child := sent[i]
+
+		want := wire.OutPoint{Hash: parent.TxHash(), Index: index}
+		if len(child.TxIn) != 1 || child.TxIn[0].PreviousOutPoint != want {
+			t.Fatalf("got inputs %v, want %v", child.TxIn, want)
+		}
+
+		wantValue := parent.TxOut[index].Value - 500
+		if len(child.TxOut) != 1 || child.TxOut[0].Value != wantValue {
+			t.Fatalf("got outputs %v, want value %v", child.TxOut, wantValue)
+		}
+
+		if string(child.TxOut[0].PkScript) != string(utxos[0].PkScript) {
+			t.Fatalf("child does not pay the contract")
+		}
+
+		parent = child
+		index = 0
+	}
+
+	// confirming the response stops tracking the children
+	if err := s.Confirmed(ctx, tx.MsgTx.TxHash().String()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.tracked) != 0 {
+		t.Errorf("got %v tracked, want 0", len(s.tracked))
+	}
+}
+
+func TestFeeBumpService_Track(t *testing.T) {
+	ctx := context.Background()
+
+	requester, err := btcutil.DecodeAddress("1HQ2ULuD7T5ykaucZ3KmTo4i29925Qa6ic",
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		changeAddress btcutil.Address
+		noChange      bool
+		want          int
+	}{
+		{
+			name: "change to the contract",
+			want: 1,
+		},
+		{
+			name:          "change to the requester",
+			changeAddress: requester,
+			want:          0,
+		},
+		{
+			name:     "no change",
+			noChange: true,
+			want:     0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, address, tx, utxos := newResponse(t, tt.changeAddress)
+
+			if tt.noChange {
+				tx.ChangeIndex = -1
+			}
+
+			s := NewFeeBumpService(config.FeeBump{After: time.Minute},
+				memoryStorage{}, testNetwork{&[]*wire.MsgTx{}}, *w)
+
+			if err := s.Track(ctx, "request", address, tx.MsgTx, utxos,
+				tx.ChangeIndex); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(s.tracked) != tt.want {
+				t.Fatalf("got %v tracked, want %v", len(s.tracked), tt.want)
+			}
+		})
+	}
+}
+
+func TestFeeBumpService_Load(t *testing.T) {
+	ctx := context.Background()
+
+	w, address, tx, utxos := newResponse(t, nil)
+
+	store := memoryStorage{}
+	sent := []*wire.MsgTx{}
+
+	fb := config.FeeBump{
+		After:    time.Nanosecond,
+		Increase: 500,
+	}
+
+	s := NewFeeBumpService(fb, store, testNetwork{&sent}, *w)
+
+	if err := s.Track(ctx, "request", address, tx.MsgTx, utxos,
+		tx.ChangeIndex); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Millisecond)
+
+	if err := s.Bump(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// a confirmed response is not reloaded
+	_, confirmedAddress, confirmed, confirmedUTXOs := newResponse(t, nil)
+
+	if err := s.Track(ctx, "confirmed", confirmedAddress, confirmed.MsgTx,
+		confirmedUTXOs, confirmed.ChangeIndex); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Confirmed(ctx, confirmed.MsgTx.TxHash().String()); err != nil {
+		t.Fatal(err)
+	}
+
+	// a restart resumes bumping from the latest child
+	restarted := NewFeeBumpService(fb, store, testNetwork{&sent}, *w)
+
+	if err := restarted.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(restarted.tracked) != 2 {
+		t.Fatalf("got %v tracked, want 2", len(restarted.tracked))
+	}
+
+	time.Sleep(time.Millisecond)
+
+	if err := restarted.Bump(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sent) != 2 {
+		t.Fatalf("got %v children, want 2", len(sent))
+	}
+
+	want := wire.OutPoint{Hash: sent[0].TxHash(), Index: 0}
+	if sent[1].TxIn[0].PreviousOutPoint != want {
+		t.Fatalf("got input %v, want %v", sent[1].TxIn[0].PreviousOutPoint, want)
+	}
+}
@@ -0,0 +1,97 @@
+package feebump
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+// Broadcast is a TX broadcast for a response, either the response itself or
+// a child of it paying a higher fee.
+type Broadcast struct {
+	TxID        string `json:"txid"`
+	Tx          string `json:"tx"`
+	Fee         uint64 `json:"fee"`
+	BroadcastAt int64  `json:"broadcast_at"`
+}
+
+// TrackedTX is a response that has been broadcast but not confirmed.
+type TrackedTX struct {
+	RequestTxID     string `json:"request_txid"`
+	ContractAddress string `json:"contract_address"`
+
+	// ChangeIndex is the index of the output of the response paying the
+	// change to the contract.
+	ChangeIndex int `json:"change_index"`
+
+	// Broadcasts is the chain of TX's, starting with the response. Each
+	// child spends the change of the response, or the output of the child
+	// before it.
+	Broadcasts    []Broadcast `json:"broadcasts"`
+	ConfirmedTxID string      `json:"confirmed_txid,omitempty"`
+}
+
+// Latest returns the most recent broadcast for the response.
+func (t TrackedTX) Latest() Broadcast {
+	return t.Broadcasts[len(t.Broadcasts)-1]
+}
+
+// spendable returns the output of the latest broadcast that the next child
+// spends.
+func (t TrackedTX) spendable() (txbuilder.UTXO, error) {
+	tx, err := t.Latest().msgTx()
+	if err != nil {
+		return txbuilder.UTXO{}, err
+	}
+
+	index := uint32(0)
+	if len(t.Broadcasts) == 1 {
+		index = uint32(t.ChangeIndex)
+	}
+
+	return txbuilder.NewUTXOFromTX(*tx, index), nil
+}
+
+// newBroadcast returns a Broadcast for the TX spending the inputs.
+func newBroadcast(tx *wire.MsgTx, inputs txbuilder.UTXOs, now int64) (Broadcast, error) {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return Broadcast{}, err
+	}
+
+	in := uint64(0)
+	for _, utxo := range inputs {
+		in += utxo.Value
+	}
+
+	out := uint64(0)
+	for _, txOut := range tx.TxOut {
+		out += uint64(txOut.Value)
+	}
+
+	b := Broadcast{
+		TxID:        tx.TxHash().String(),
+		Tx:          hex.EncodeToString(buf.Bytes()),
+		Fee:         in - out,
+		BroadcastAt: now,
+	}
+
+	return b, nil
+}
+
+// msgTx returns the decoded TX of the Broadcast.
+func (b Broadcast) msgTx() (*wire.MsgTx, error) {
+	raw, err := hex.DecodeString(b.Tx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := wire.MsgTx{}
+	if err := tx.Deserialize(bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+
+	return &tx, nil
+}
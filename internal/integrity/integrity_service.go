@@ -0,0 +1,188 @@
+package integrity
+
+/**
+ * Integrity Service
+ *
+ * What is my purpose?
+ * - You record the checksums of critical records on a clean shutdown
+ * - You tell me on startup if a record has been corrupted
+ * - You tell me on startup if the last shutdown was not clean
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+const (
+	// ManifestPrefix is the storage path that Manifest's are written to.
+	ManifestPrefix = "integrity"
+)
+
+var (
+	ErrUncleanShutdown = errors.New("Previous shutdown was not clean, so records written since the last start may be incomplete")
+	ErrCorruptRecord   = errors.New("Record does not match manifest")
+	ErrManifestVersion = errors.New("Unsupported manifest version")
+)
+
+// KeysFunc returns the keys of records that are only known when the
+// records are sealed, such as the record another record points to.
+type KeysFunc func(context.Context, storage.Reader) ([]string, error)
+
+// IntegrityService verifies a set of records held in a Storage.
+type IntegrityService struct {
+	Storage storage.ReadWriter
+	Name    string
+	Keys    []string
+
+	// KeysFunc, if set, returns the keys of more records to seal along
+	// with the Keys.
+	KeysFunc KeysFunc
+}
+
+// NewIntegrityService returns a new IntegrityService for the records with
+// the keys. The name identifies the Manifest of the records, which is
+// written to the same Storage.
+func NewIntegrityService(store storage.ReadWriter,
+	name string,
+	keys ...string) IntegrityService {
+
+	return IntegrityService{
+		Storage: store,
+		Name:    name,
+		Keys:    keys,
+	}
+}
+
+// Verify checks the records against the Manifest written by the last clean
+// shutdown.
+//
+// There is nothing to verify on the first start, when there is no Manifest.
+// A record that is missing, or no longer matches its checksum, is corrupt.
+func (s IntegrityService) Verify(ctx context.Context) error {
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	m, err := s.read(ctx)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil
+		}
+
+		return err
+	}
+
+	if m.Version != ManifestVersion {
+		return ErrManifestVersion
+	}
+
+	if !m.Clean {
+		return ErrUncleanShutdown
+	}
+
+	corrupt := false
+
+	for key, sum := range m.Checksums {
+		b, err := s.Storage.Read(ctx, key)
+		if err != nil && err != storage.ErrNotFound {
+			return err
+		}
+
+		if err == storage.ErrNotFound {
+			log.Errorf("Record missing : %v", key)
+			corrupt = true
+			continue
+		}
+
+		if checksum(b) != sum {
+			log.Errorf("Record checksum mismatch : %v", key)
+			corrupt = true
+		}
+	}
+
+	if corrupt {
+		return ErrCorruptRecord
+	}
+
+	return nil
+}
+
+// Open marks the Manifest as in use, so that a run that does not shut down
+// cleanly is detected on the next startup.
+func (s IntegrityService) Open(ctx context.Context) error {
+	m := Manifest{
+		Version:   ManifestVersion,
+		Checksums: map[string]string{},
+	}
+
+	return s.write(ctx, m)
+}
+
+// Seal writes the checksums of the records to the Manifest, and marks it
+// clean. It must only be called once no more records will be written.
+func (s IntegrityService) Seal(ctx context.Context) error {
+	m := Manifest{
+		Version:   ManifestVersion,
+		Clean:     true,
+		SealedAt:  time.Now().UnixNano(),
+		Checksums: map[string]string{},
+	}
+
+	keys := s.Keys
+	if s.KeysFunc != nil {
+		more, err := s.KeysFunc(ctx, s.Storage)
+		if err != nil {
+			return err
+		}
+
+		keys = append(append([]string{}, s.Keys...), more...)
+	}
+
+	for _, key := range keys {
+		b, err := s.Storage.Read(ctx, key)
+		if err != nil {
+			if err == storage.ErrNotFound {
+				// the record has not been written yet
+				continue
+			}
+
+			return err
+		}
+
+		m.Checksums[key] = checksum(b)
+	}
+
+	return s.write(ctx, m)
+}
+
+func (s IntegrityService) read(ctx context.Context) (*Manifest, error) {
+	b, err := s.Storage.Read(ctx, s.buildPath())
+	if err != nil {
+		return nil, err
+	}
+
+	m := Manifest{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+func (s IntegrityService) write(ctx context.Context, m Manifest) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return s.Storage.Write(ctx, s.buildPath(), b, nil)
+}
+
+func (s IntegrityService) buildPath() string {
+	return fmt.Sprintf("%v/%v", ManifestPrefix, s.Name)
+}
@@ -0,0 +1,102 @@
+package integrity
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+type memoryStorage map[string][]byte
+
+func (m memoryStorage) Read(ctx context.Context, key string) ([]byte, error) {
+	b, ok := m[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	return b, nil
+}
+
+func (m memoryStorage) Write(ctx context.Context,
+	key string,
+	body []byte,
+	opts *storage.Options) error {
+
+	m[key] = body
+	return nil
+}
+
+func TestIntegrityService_Verify(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		before func(memoryStorage, IntegrityService)
+		err    error
+	}{
+		{
+			name:   "first start",
+			before: func(store memoryStorage, s IntegrityService) {},
+		},
+		{
+			name: "clean shutdown",
+			before: func(store memoryStorage, s IntegrityService) {
+				s.Open(ctx)
+				s.Seal(ctx)
+			},
+		},
+		{
+			name: "unclean shutdown",
+			before: func(store memoryStorage, s IntegrityService) {
+				s.Open(ctx)
+			},
+			err: ErrUncleanShutdown,
+		},
+		{
+			name: "modified record",
+			before: func(store memoryStorage, s IntegrityService) {
+				s.Seal(ctx)
+				store["contracts/1"] = []byte(`{"revision":2}`)
+			},
+			err: ErrCorruptRecord,
+		},
+		{
+			name: "modified record found when sealed",
+			before: func(store memoryStorage, s IntegrityService) {
+				s.Seal(ctx)
+				store["blocks/tip"] = []byte(`{"height":2}`)
+			},
+			err: ErrCorruptRecord,
+		},
+		{
+			name: "missing record",
+			before: func(store memoryStorage, s IntegrityService) {
+				s.Seal(ctx)
+				delete(store, "state.json")
+			},
+			err: ErrCorruptRecord,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memoryStorage{
+				"contracts/1": []byte(`{"revision":1}`),
+				"state.json":  []byte(`{"last_seen":{}}`),
+				"blocks/tip":  []byte(`{"height":1}`),
+			}
+
+			s := NewIntegrityService(store, "test", "contracts/1", "state.json")
+			s.KeysFunc = func(context.Context, storage.Reader) ([]string, error) {
+				return []string{"blocks/tip"}, nil
+			}
+
+			tt.before(store, s)
+
+			if err := s.Verify(ctx); err != tt.err {
+				t.Errorf("got error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
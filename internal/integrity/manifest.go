@@ -0,0 +1,29 @@
+package integrity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// ManifestVersion is the version of the Manifest format.
+const ManifestVersion = 1
+
+// Manifest records the checksums of critical records when they were last
+// known to be good.
+//
+// A Manifest is sealed on a clean shutdown, and opened again on startup. A
+// Manifest that is still open at startup means the previous run did not shut
+// down cleanly.
+type Manifest struct {
+	Version   int               `json:"version"`
+	Clean     bool              `json:"clean"`
+	SealedAt  int64             `json:"sealed_at,omitempty"`
+	Checksums map[string]string `json:"checksums"`
+}
+
+// checksum returns the hex encoded SHA256 of a record.
+func checksum(b []byte) string {
+	h := sha256.Sum256(b)
+
+	return hex.EncodeToString(h[:])
+}
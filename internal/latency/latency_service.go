@@ -0,0 +1,252 @@
+package latency
+
+/**
+ * Latency Service
+ *
+ * What is my purpose?
+ * - You measure the time taken to respond to requests
+ * - You measure the time taken for responses to be confirmed
+ * - You tell me about percentiles for each action
+ * - You tell me when an SLA has been exceeded
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+const (
+	// LatencyPrefix is the storage path that Record's are written to.
+	LatencyPrefix = "latency"
+
+	// maxSamples is the number of recent latencies kept for each action and
+	// stage when calculating percentiles.
+	maxSamples = 1000
+)
+
+// Alerter is notified when the latency of a request exceeds the SLA.
+type Alerter interface {
+	Alert(context.Context, Record, Stage, time.Duration)
+}
+
+// LogAlerter writes alerts to the Logger.
+type LogAlerter struct{}
+
+// Alert implements the Alerter interface.
+func (a LogAlerter) Alert(ctx context.Context,
+	r Record,
+	stage Stage,
+	latency time.Duration) {
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+	log.Warnf("SLA exceeded for %v %v : %v took %v",
+		r.Action, r.RequestTxID, stage, latency)
+}
+
+// Percentiles holds latency percentiles for an action and stage.
+type Percentiles struct {
+	Action string
+	Stage  Stage
+	Count  int
+	P50    time.Duration
+	P90    time.Duration
+	P99    time.Duration
+}
+
+type LatencyService struct {
+	Storage storage.ReadWriter
+	SLA     config.SLA
+	Alerter Alerter
+
+	mu *sync.Mutex
+
+	// records that have not been confirmed, keyed by request TX ID
+	pending map[string]*Record
+
+	// request TX ID's, keyed by the TX ID of the response
+	responses map[string]string
+
+	// recent latencies, keyed by action and stage
+	samples map[string]map[Stage][]time.Duration
+}
+
+func NewLatencyService(store storage.ReadWriter,
+	sla config.SLA) LatencyService {
+
+	return LatencyService{
+		Storage:   store,
+		SLA:       sla,
+		Alerter:   LogAlerter{},
+		mu:        &sync.Mutex{},
+		pending:   map[string]*Record{},
+		responses: map[string]string{},
+		samples:   map[string]map[Stage][]time.Duration{},
+	}
+}
+
+// Observed starts measuring the latency of a request.
+//
+// A request that has already been observed keeps its original time.
+func (s LatencyService) Observed(ctx context.Context,
+	txID string,
+	action string) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pending[txID]; ok {
+		return nil
+	}
+
+	r := &Record{
+		RequestTxID: txID,
+		Action:      action,
+		ObservedAt:  time.Now().UnixNano(),
+	}
+
+	s.pending[txID] = r
+
+	return s.write(ctx, *r)
+}
+
+// Broadcast records the response to a request being broadcast.
+func (s LatencyService) Broadcast(ctx context.Context,
+	txID string,
+	responseTxID string) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.pending[txID]
+	if !ok {
+		return nil
+	}
+
+	r.ResponseTxID = responseTxID
+	r.BroadcastAt = time.Now().UnixNano()
+	s.responses[responseTxID] = txID
+
+	s.measure(ctx, *r, StageBroadcast, s.SLA.Broadcast)
+
+	return s.write(ctx, *r)
+}
+
+// Confirmed records a TX being confirmed in a block. TX's that are not the
+// response to a pending request are ignored.
+func (s LatencyService) Confirmed(ctx context.Context, txID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requestTxID, ok := s.responses[txID]
+	if !ok {
+		return nil
+	}
+
+	r := s.pending[requestTxID]
+	r.ConfirmedAt = time.Now().UnixNano()
+
+	delete(s.responses, txID)
+	delete(s.pending, requestTxID)
+
+	s.measure(ctx, *r, StageConfirmation, s.SLA.Confirmation)
+
+	return s.write(ctx, *r)
+}
+
+// Percentiles returns the latency percentiles of each action for the
+// stage.
+func (s LatencyService) Percentiles(stage Stage) []Percentiles {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []Percentiles{}
+
+	for action, stages := range s.samples {
+		samples := stages[stage]
+		if len(samples) == 0 {
+			continue
+		}
+
+		sorted := make([]time.Duration, len(samples))
+		copy(sorted, samples)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i] < sorted[j]
+		})
+
+		result = append(result, Percentiles{
+			Action: action,
+			Stage:  stage,
+			Count:  len(sorted),
+			P50:    percentile(sorted, 50),
+			P90:    percentile(sorted, 90),
+			P99:    percentile(sorted, 99),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Action < result[j].Action
+	})
+
+	return result
+}
+
+// measure keeps the latency of the stage as a sample, alerting if it
+// exceeds the SLA.
+func (s LatencyService) measure(ctx context.Context,
+	r Record,
+	stage Stage,
+	sla time.Duration) {
+
+	latency, ok := r.Latency(stage)
+	if !ok {
+		return
+	}
+
+	stages, ok := s.samples[r.Action]
+	if !ok {
+		stages = map[Stage][]time.Duration{}
+		s.samples[r.Action] = stages
+	}
+
+	samples := append(stages[stage], latency)
+	if len(samples) > maxSamples {
+		samples = samples[len(samples)-maxSamples:]
+	}
+
+	stages[stage] = samples
+
+	if sla > 0 && latency > sla && s.Alerter != nil {
+		s.Alerter.Alert(ctx, r, stage, latency)
+	}
+}
+
+func (s LatencyService) write(ctx context.Context, r Record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	return s.Storage.Write(ctx, s.buildPath(r.RequestTxID), b, nil)
+}
+
+func (s LatencyService) buildPath(txID string) string {
+	return fmt.Sprintf("%v/%v", LatencyPrefix, txID)
+}
+
+// percentile returns the nearest rank percentile p of the sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	idx := (len(sorted)*p+99)/100 - 1
+	if idx < 0 {
+		idx = 0
+	}
+
+	return sorted[idx]
+}
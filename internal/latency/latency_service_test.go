@@ -0,0 +1,140 @@
+package latency
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+type memoryStorage map[string][]byte
+
+func (m memoryStorage) Read(ctx context.Context, key string) ([]byte, error) {
+	b, ok := m[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	return b, nil
+}
+
+func (m memoryStorage) Write(ctx context.Context,
+	key string,
+	body []byte,
+	opts *storage.Options) error {
+
+	m[key] = body
+	return nil
+}
+
+type testAlerter struct {
+	stages *[]Stage
+}
+
+func (a testAlerter) Alert(ctx context.Context,
+	r Record,
+	stage Stage,
+	latency time.Duration) {
+
+	*a.stages = append(*a.stages, stage)
+}
+
+func TestLatencyService(t *testing.T) {
+	ctx := context.Background()
+	store := memoryStorage{}
+
+	// every confirmation takes longer than a nanosecond
+	sla := config.SLA{
+		Broadcast:    time.Hour,
+		Confirmation: time.Nanosecond,
+	}
+
+	stages := []Stage{}
+
+	s := NewLatencyService(store, sla)
+	s.Alerter = testAlerter{&stages}
+
+	if err := s.Observed(ctx, "request", "T1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Broadcast(ctx, "request", "response"); err != nil {
+		t.Fatal(err)
+	}
+
+	// unrelated TX's are ignored
+	if err := s.Confirmed(ctx, "other"); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Millisecond)
+
+	if err := s.Confirmed(ctx, "response"); err != nil {
+		t.Fatal(err)
+	}
+
+	r := Record{}
+	if err := json.Unmarshal(store["latency/request"], &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.ResponseTxID != "response" || r.Action != "T1" {
+		t.Errorf("got record %+v", r)
+	}
+
+	if _, ok := r.Latency(StageConfirmation); !ok {
+		t.Errorf("got unconfirmed record, want confirmed")
+	}
+
+	wantStages := []Stage{StageConfirmation}
+	if len(stages) != 1 || stages[0] != StageConfirmation {
+		t.Errorf("got alerts %v, want %v", stages, wantStages)
+	}
+
+	percentiles := s.Percentiles(StageBroadcast)
+	if len(percentiles) != 1 || percentiles[0].Count != 1 {
+		t.Errorf("got percentiles %+v, want 1 sample", percentiles)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{}
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+
+	tests := []struct {
+		name string
+		p    int
+		want time.Duration
+	}{
+		{
+			name: "median",
+			p:    50,
+			want: 50,
+		},
+		{
+			name: "90th",
+			p:    90,
+			want: 90,
+		},
+		{
+			name: "99th",
+			p:    99,
+			want: 99,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := percentile(sorted, tt.p)
+
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package latency
+
+import (
+	"time"
+)
+
+// Stage is a point in the processing of a request that latency is measured
+// to.
+type Stage string
+
+const (
+	// StageBroadcast is reached when the response to a request has been
+	// broadcast.
+	StageBroadcast Stage = "broadcast"
+
+	// StageConfirmation is reached when the response to a request has been
+	// confirmed in a block.
+	StageConfirmation Stage = "confirmation"
+)
+
+// Record holds the timings of a single request.
+type Record struct {
+	RequestTxID  string `json:"request_txid"`
+	ResponseTxID string `json:"response_txid,omitempty"`
+	Action       string `json:"action"`
+	ObservedAt   int64  `json:"observed_at"`
+	BroadcastAt  int64  `json:"broadcast_at,omitempty"`
+	ConfirmedAt  int64  `json:"confirmed_at,omitempty"`
+}
+
+// Latency returns the time from the request being observed to the stage
+// being reached, and false if the stage has not been reached.
+func (r Record) Latency(stage Stage) (time.Duration, bool) {
+	at := int64(0)
+
+	switch stage {
+	case StageBroadcast:
+		at = r.BroadcastAt
+	case StageConfirmation:
+		at = r.ConfirmedAt
+	}
+
+	if at == 0 {
+		return 0, false
+	}
+
+	return time.Duration(at - r.ObservedAt), true
+}
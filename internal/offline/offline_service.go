@@ -0,0 +1,261 @@
+package offline
+
+/**
+ * Offline Signing Service
+ *
+ * What is my purpose?
+ * - You export unsigned responses to be signed elsewhere
+ * - You accept signatures produced elsewhere
+ * - You finalize and broadcast signed responses
+ */
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/internal/app/network"
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/storage"
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+const (
+	// UnsignedPrefix is the storage path that UnsignedTX's are written to.
+	UnsignedPrefix = "unsigned"
+)
+
+var (
+	ErrUnsignedTXNotFound = errs.New(errs.NotFound, "Unsigned TX not found")
+	ErrSignatureCount     = errs.New(errs.Invalid, "Signature count does not match inputs")
+	ErrNotSigner          = errs.New(errs.Invalid, "Public key is not in the redeem script")
+	ErrBadSignature       = errs.New(errs.Invalid, "Signature does not verify")
+	ErrNotSigned          = errs.New(errs.Conflict, "Not enough signatures to finalize")
+)
+
+type OfflineService struct {
+	Storage storage.Storage
+	Network network.NetworkInterface
+}
+
+func NewOfflineService(store storage.Storage,
+	network network.NetworkInterface) OfflineService {
+
+	return OfflineService{
+		Storage: store,
+		Network: network,
+	}
+}
+
+// Export writes an unsigned response TX, with the UTXO's it spends, to be
+// signed offline.
+func (s OfflineService) Export(ctx context.Context,
+	requestTxID string,
+	tx *wire.MsgTx,
+	utxos txbuilder.UTXOs,
+	redeemScript []byte) (*UnsignedTX, error) {
+
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+
+	inputs := txbuilder.UTXOs{}
+
+	for i, txIn := range tx.TxIn {
+		utxo, ok := utxos.ForOutPoint(txIn.PreviousOutPoint)
+		if !ok {
+			return nil, fmt.Errorf("No UTXO for input %v", i)
+		}
+
+		inputs = append(inputs, utxo)
+	}
+
+	u := UnsignedTX{
+		ID:           tx.TxHash().String(),
+		RequestTxID:  requestTxID,
+		Tx:           hex.EncodeToString(buf.Bytes()),
+		Inputs:       inputs,
+		RedeemScript: redeemScript,
+		Signatures:   map[string][][]byte{},
+		CreatedAt:    time.Now().UnixNano(),
+	}
+
+	if err := s.write(ctx, u); err != nil {
+		return nil, err
+	}
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+	log.Infof("Exported unsigned TX %v for request %v", u.ID, requestTxID)
+
+	return &u, nil
+}
+
+// Find returns the UnsignedTX with the ID.
+func (s OfflineService) Find(ctx context.Context,
+	id string) (*UnsignedTX, error) {
+
+	b, err := s.Storage.Read(ctx, s.buildPath(id))
+	if err != nil {
+		if err == storage.ErrNotFound {
+			err = ErrUnsignedTXNotFound
+		}
+
+		return nil, err
+	}
+
+	u := UnsignedTX{}
+	if err := json.Unmarshal(b, &u); err != nil {
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+// Pending returns the UnsignedTX's that have not been broadcast.
+func (s OfflineService) Pending(ctx context.Context) ([]UnsignedTX, error) {
+	query := map[string]string{
+		"path": UnsignedPrefix,
+	}
+
+	objects, err := s.Storage.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []UnsignedTX{}
+
+	for _, b := range objects {
+		u := UnsignedTX{}
+		if err := json.Unmarshal(b, &u); err != nil {
+			return nil, err
+		}
+
+		pending = append(pending, u)
+	}
+
+	return pending, nil
+}
+
+// AddSignatures adds the signatures made offline by the public key, one
+// for each input of the TX, in order.
+//
+// The public key must be one of the keys of the redeem script, and each
+// signature must verify against its input.
+func (s OfflineService) AddSignatures(ctx context.Context,
+	id string,
+	pubKey []byte,
+	sigs [][]byte) error {
+
+	u, err := s.Find(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if len(sigs) != len(u.Inputs) {
+		return ErrSignatureCount
+	}
+
+	tx, err := u.MsgTx()
+	if err != nil {
+		return err
+	}
+
+	for i, sig := range sigs {
+		err := txbuilder.VerifyMultiSigInput(tx, i, u.RedeemScript, pubKey, sig,
+			u.Inputs[i])
+
+		switch err {
+		case nil:
+		case txbuilder.ErrNotMultiSigKey:
+			return ErrNotSigner
+		case txbuilder.ErrInvalidSignature:
+			return ErrBadSignature
+		default:
+			return err
+		}
+	}
+
+	u.Signatures[hex.EncodeToString(pubKey)] = sigs
+
+	return s.write(ctx, *u)
+}
+
+// Finalize returns the TX with unlocking scripts built from the signatures
+// that have been added.
+func (s OfflineService) Finalize(ctx context.Context,
+	id string) (*wire.MsgTx, error) {
+
+	u, err := s.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := u.MsgTx()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, txIn := range tx.TxIn {
+		// signatures for this input, keyed by public key
+		sigs := map[string][]byte{}
+		for pubKey, inputSigs := range u.Signatures {
+			sigs[pubKey] = inputSigs[i]
+		}
+
+		script, err := txbuilder.MultiSigUnlockingScript(u.RedeemScript, sigs)
+		if err != nil {
+			if err == txbuilder.ErrNotEnoughSignatures {
+				err = ErrNotSigned
+			}
+
+			return nil, err
+		}
+
+		txIn.SignatureScript = script
+	}
+
+	return tx, nil
+}
+
+// Broadcast finalizes and broadcasts the TX, removing it from the pending
+// TX's.
+func (s OfflineService) Broadcast(ctx context.Context,
+	id string) (*chainhash.Hash, error) {
+
+	tx, err := s.Finalize(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := s.Network.SendTX(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Storage.Remove(ctx, s.buildPath(id)); err != nil {
+		return nil, err
+	}
+
+	return hash, nil
+}
+
+func (s OfflineService) write(ctx context.Context, u UnsignedTX) error {
+	b, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+
+	return s.Storage.Write(ctx, s.buildPath(u.ID), b, nil)
+}
+
+func (s OfflineService) buildPath(id string) string {
+	return fmt.Sprintf("%v/%v", UnsignedPrefix, id)
+}
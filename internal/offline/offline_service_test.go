@@ -0,0 +1,159 @@
+package offline
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/tokenized/smart-contract/pkg/storage"
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+	"github.com/tokenized/smart-contract/pkg/txscript"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+)
+
+func TestOfflineService(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := storage.NewFilesystemStorage(storage.Config{
+		Root:   dir,
+		Bucket: "test",
+	})
+
+	keys := []*btcec.PrivateKey{}
+	pubKeys := []*btcec.PublicKey{}
+
+	for i := 0; i < 3; i++ {
+		key, err := btcec.NewPrivateKey(btcec.S256())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		keys = append(keys, key)
+		pubKeys = append(pubKeys, key.PubKey())
+	}
+
+	// 2 of 3 multisig
+	redeemScript, address, err := txbuilder.NewMultiSigScript(pubKeys, 2,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := chainhash.NewHashFromStr("2c2786fe332e94ea61f2a0aef6037cd08bf6495f800a4c829c0f1c07e6104ab8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	utxos := txbuilder.UTXOs{
+		txbuilder.UTXO{
+			Hash:     *hash,
+			Index:    0,
+			PkScript: pkScript,
+			Value:    10000,
+		},
+	}
+
+	receiver, err := btcutil.DecodeAddress("18H59cUZMAPRhp74xoeE6LXingw3Wxr3VG",
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outs := []txbuilder.PayAddress{
+		txbuilder.NewPayAddress(receiver, 546),
+	}
+
+	builder := txbuilder.NewMultiSigTxBuilder(redeemScript)
+
+	tx, err := builder.Build(utxos, outs, address, []byte{0x6a, 0x02, 0x00, 0x20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewOfflineService(store, nil)
+
+	u, err := s.Export(ctx, "request", tx, utxos, redeemScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := s.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pending) != 1 || pending[0].ID != u.ID {
+		t.Fatalf("got pending %+v, want %v", pending, u.ID)
+	}
+
+	foreign, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := txbuilder.SignMultiSigInput(tx, 0, redeemScript, foreign, utxos[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.AddSignatures(ctx, u.ID, foreign.PubKey().SerializeCompressed(), [][]byte{sig})
+	if err != ErrNotSigner {
+		t.Errorf("got error %v, want %v", err, ErrNotSigner)
+	}
+
+	// a signature of another key of the redeem script
+	err = s.AddSignatures(ctx, u.ID, keys[2].PubKey().SerializeCompressed(), [][]byte{sig})
+	if err != ErrBadSignature {
+		t.Errorf("got error %v, want %v", err, ErrBadSignature)
+	}
+
+	// sign offline with 2 of the keys
+	for _, key := range keys[:2] {
+		sig, err := txbuilder.SignMultiSigInput(tx, 0, redeemScript, key,
+			utxos[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pubKey := key.PubKey().SerializeCompressed()
+
+		if err := s.AddSignatures(ctx, u.ID, pubKey, [][]byte{sig, sig}); err != ErrSignatureCount {
+			t.Errorf("got error %v, want %v", err, ErrSignatureCount)
+		}
+
+		if err := s.AddSignatures(ctx, u.ID, pubKey, [][]byte{sig}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	signed, err := s.Finalize(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pushes, err := txscript.PushedData(signed.TxIn[0].SignatureScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// OP_FALSE, 2 signatures and the redeem script
+	if len(pushes) != 4 {
+		t.Errorf("got %v pushes, want 4", len(pushes))
+	}
+}
@@ -0,0 +1,38 @@
+package offline
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+// UnsignedTX is a response TX exported to be signed offline.
+//
+// The UTXO's spent by each input are included, as they are needed to sign
+// the inputs.
+type UnsignedTX struct {
+	ID           string              `json:"id"`
+	RequestTxID  string              `json:"request_txid"`
+	Tx           string              `json:"tx"`
+	Inputs       txbuilder.UTXOs     `json:"inputs"`
+	RedeemScript []byte              `json:"redeem_script"`
+	Signatures   map[string][][]byte `json:"signatures"`
+	CreatedAt    int64               `json:"created_at"`
+}
+
+// MsgTx returns the decoded TX.
+func (u UnsignedTX) MsgTx() (*wire.MsgTx, error) {
+	b, err := hex.DecodeString(u.Tx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := wire.MsgTx{}
+	if err := tx.Deserialize(bytes.NewReader(b)); err != nil {
+		return nil, err
+	}
+
+	return &tx, nil
+}
@@ -0,0 +1,133 @@
+package operation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// webhookTimeout is how long a webhook has to respond to an Event.
+	webhookTimeout = 10 * time.Second
+)
+
+const (
+	// KindRescan is a download of past blocks from untrusted peers.
+	KindRescan = "rescan"
+
+	// KindSync is a catch up of the contract state from another node.
+	KindSync = "sync"
+)
+
+// State is where an Operation is in its life.
+type State string
+
+const (
+	StateRunning   State = "running"
+	StatePaused    State = "paused"
+	StateFinished  State = "finished"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// Operation is the progress of a long running job, as of UpdatedAt.
+type Operation struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+
+	State State `json:"state"`
+
+	// Total is the number of items to process, or 0 if it is not known.
+	Total int64 `json:"total"`
+
+	// Done is the number of items processed.
+	Done int64 `json:"done"`
+
+	// LastItem identifies the last item processed, such as a block hash.
+	LastItem string `json:"last_item"`
+
+	// Percent is Done as a percentage of Total, or 0 if Total is not known.
+	Percent float64 `json:"percent"`
+
+	// ETA is the estimated time until the operation finishes, in
+	// nanoseconds, or 0 if it can't be estimated. Time spent paused is not
+	// counted.
+	ETA int64 `json:"eta"`
+
+	StartedAt int64  `json:"started_at"`
+	UpdatedAt int64  `json:"updated_at"`
+	Error     string `json:"error,omitempty"`
+}
+
+// EventType identifies what happened to an Operation.
+type EventType string
+
+const (
+	EventStarted   EventType = "OperationStarted"
+	EventProgress  EventType = "OperationProgress"
+	EventPaused    EventType = "OperationPaused"
+	EventResumed   EventType = "OperationResumed"
+	EventFinished  EventType = "OperationFinished"
+	EventFailed    EventType = "OperationFailed"
+	EventCancelled EventType = "OperationCancelled"
+)
+
+// Event is a change to an Operation that Notifier's are told about.
+//
+// Progress events are only emitted when a whole percent more is done.
+type Event struct {
+	Type      EventType `json:"type"`
+	Operation Operation `json:"operation"`
+}
+
+// Notifier is told about each Event, such as to pass it on to an external
+// service.
+type Notifier interface {
+	Notify(context.Context, Event) error
+}
+
+// WebhookNotifier posts each Event as JSON to a URL.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookNotifier returns a new WebhookNotifier posting to the URL.
+func NewWebhookNotifier(url string) WebhookNotifier {
+	return WebhookNotifier{
+		URL:    url,
+		Client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Notify implements the Notifier interface.
+//
+// A response other than 2xx is returned as an error.
+func (n WebhookNotifier) Notify(ctx context.Context, e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := n.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("Webhook %v returned %v", n.URL, res.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,439 @@
+package operation
+
+/**
+ * Operation Service
+ *
+ * What is my purpose?
+ * - You keep track of the jobs that take hours, like rescans
+ * - You tell me how far along each one is, and when it should be done
+ * - You let an operator pause, resume or cancel them
+ */
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/pkg/errs"
+)
+
+const (
+	// maxEnded is the number of ended operations that are kept, so recent
+	// results can still be read.
+	maxEnded = 100
+)
+
+var (
+	// ErrNotFound is returned for an operation that is not known.
+	ErrNotFound = errs.New(errs.NotFound, "Operation not found")
+
+	// ErrNotRunning is returned when controlling an operation that has
+	// ended.
+	ErrNotRunning = errs.New(errs.Conflict, "Operation is not running")
+
+	// ErrCancelled is returned to the job of an operation that an operator
+	// cancelled.
+	ErrCancelled = errs.New(errs.Conflict, "Operation cancelled")
+)
+
+// OperationService tracks the progress of long running jobs.
+//
+// A job is given a Tracker when it starts, and reports each item it
+// processes with Step. Pausing and cancelling take effect at the next
+// Step.
+type OperationService struct {
+	Notifiers []Notifier
+
+	mu       *sync.Mutex
+	trackers map[string]*Tracker
+}
+
+// NewOperationService returns a new OperationService, telling the notifiers
+// about each Event.
+func NewOperationService(notifiers ...Notifier) OperationService {
+	return OperationService{
+		Notifiers: notifiers,
+		mu:        &sync.Mutex{},
+		trackers:  map[string]*Tracker{},
+	}
+}
+
+// Start starts tracking an operation of the kind, with the number of items
+// to process, or 0 if it is not known.
+func (s OperationService) Start(ctx context.Context,
+	kind string,
+	total int64) *Tracker {
+
+	now := time.Now().UnixNano()
+
+	t := &Tracker{
+		service: s,
+		mu:      &sync.Mutex{},
+		op: Operation{
+			ID:        newID(),
+			Kind:      kind,
+			State:     StateRunning,
+			Total:     total,
+			StartedAt: now,
+			UpdatedAt: now,
+		},
+	}
+
+	s.mu.Lock()
+	s.trackers[t.op.ID] = t
+	s.prune()
+	s.mu.Unlock()
+
+	s.notify(ctx, EventStarted, t.Operation())
+
+	return t
+}
+
+// All returns all operations, most recently started first.
+func (s OperationService) All() []Operation {
+	s.mu.Lock()
+	trackers := []*Tracker{}
+	for _, t := range s.trackers {
+		trackers = append(trackers, t)
+	}
+	s.mu.Unlock()
+
+	ops := []Operation{}
+	for _, t := range trackers {
+		ops = append(ops, t.Operation())
+	}
+
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].StartedAt > ops[j].StartedAt
+	})
+
+	return ops
+}
+
+// Get returns the operation with the ID.
+func (s OperationService) Get(id string) (Operation, error) {
+	t, err := s.tracker(id)
+	if err != nil {
+		return Operation{}, err
+	}
+
+	return t.Operation(), nil
+}
+
+// Pause pauses the operation, at its next Step.
+func (s OperationService) Pause(ctx context.Context, id string) (Operation, error) {
+	t, err := s.tracker(id)
+	if err != nil {
+		return Operation{}, err
+	}
+
+	return t.pause(ctx)
+}
+
+// Resume resumes a paused operation.
+func (s OperationService) Resume(ctx context.Context, id string) (Operation, error) {
+	t, err := s.tracker(id)
+	if err != nil {
+		return Operation{}, err
+	}
+
+	return t.resume(ctx)
+}
+
+// Cancel cancels the operation, at its next Step.
+func (s OperationService) Cancel(ctx context.Context, id string) (Operation, error) {
+	t, err := s.tracker(id)
+	if err != nil {
+		return Operation{}, err
+	}
+
+	return t.cancel()
+}
+
+// tracker returns the Tracker of the operation with the ID.
+func (s OperationService) tracker(id string) (*Tracker, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.trackers[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return t, nil
+}
+
+// prune drops the oldest ended operations beyond maxEnded. The lock must be
+// held.
+func (s OperationService) prune() {
+	ended := []Operation{}
+
+	for _, t := range s.trackers {
+		if op := t.Operation(); op.ended() {
+			ended = append(ended, op)
+		}
+	}
+
+	if len(ended) <= maxEnded {
+		return
+	}
+
+	sort.Slice(ended, func(i, j int) bool {
+		return ended[i].UpdatedAt > ended[j].UpdatedAt
+	})
+
+	for _, op := range ended[maxEnded:] {
+		delete(s.trackers, op.ID)
+	}
+}
+
+// notify tells each Notifier about the Event. Failures are logged, so they
+// don't hold up the operation.
+func (s OperationService) notify(ctx context.Context,
+	t EventType,
+	op Operation) {
+
+	e := Event{
+		Type:      t,
+		Operation: op,
+	}
+
+	for _, n := range s.Notifiers {
+		if err := n.Notify(ctx, e); err != nil {
+			log := logger.NewLoggerFromContext(ctx).Sugar()
+			log.Errorf("Failed to notify %v of operation %v : %v", t, op.ID, err)
+		}
+	}
+}
+
+// Tracker is the handle a job reports the progress of its operation with.
+type Tracker struct {
+	service OperationService
+
+	mu        *sync.Mutex
+	op        Operation
+	paused    chan struct{}
+	pausedAt  int64
+	pausedFor int64
+	cancelled bool
+}
+
+// Operation returns the progress of the operation.
+func (t *Tracker) Operation() Operation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.snapshot()
+}
+
+// SetTotal sets the number of items to process, once it is known.
+func (t *Tracker) SetTotal(total int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.op.Total = total
+	t.op.UpdatedAt = time.Now().UnixNano()
+}
+
+// Step records that the item was processed.
+//
+// If the operation is paused, Step blocks until it is resumed. If it is
+// cancelled, ErrCancelled is returned and the job should stop.
+func (t *Tracker) Step(ctx context.Context, item string) error {
+	t.mu.Lock()
+
+	for t.paused != nil && !t.cancelled {
+		paused := t.paused
+		t.mu.Unlock()
+
+		select {
+		case <-paused:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		t.mu.Lock()
+	}
+
+	if t.cancelled {
+		t.mu.Unlock()
+		return ErrCancelled
+	}
+
+	before := t.snapshot().Percent
+
+	t.op.Done++
+	t.op.LastItem = item
+	t.op.UpdatedAt = time.Now().UnixNano()
+
+	op := t.snapshot()
+	t.mu.Unlock()
+
+	if int(op.Percent) > int(before) {
+		t.service.notify(ctx, EventProgress, op)
+	}
+
+	return nil
+}
+
+// Finish ends the operation, with the error the job returned, if any.
+func (t *Tracker) Finish(ctx context.Context, err error) {
+	t.mu.Lock()
+
+	if t.op.ended() {
+		t.mu.Unlock()
+		return
+	}
+
+	e := EventFinished
+	t.op.State = StateFinished
+
+	switch {
+	case err == ErrCancelled:
+		e = EventCancelled
+		t.op.State = StateCancelled
+
+	case err != nil:
+		e = EventFailed
+		t.op.State = StateFailed
+		t.op.Error = err.Error()
+	}
+
+	t.op.UpdatedAt = time.Now().UnixNano()
+
+	if t.paused != nil {
+		close(t.paused)
+		t.paused = nil
+	}
+
+	op := t.snapshot()
+	t.mu.Unlock()
+
+	t.service.notify(ctx, e, op)
+}
+
+func (t *Tracker) pause(ctx context.Context) (Operation, error) {
+	t.mu.Lock()
+
+	if t.op.ended() || t.cancelled {
+		t.mu.Unlock()
+		return Operation{}, ErrNotRunning
+	}
+
+	if t.paused != nil {
+		defer t.mu.Unlock()
+		return t.snapshot(), nil
+	}
+
+	t.paused = make(chan struct{})
+	t.pausedAt = time.Now().UnixNano()
+	t.op.State = StatePaused
+	t.op.UpdatedAt = t.pausedAt
+
+	op := t.snapshot()
+	t.mu.Unlock()
+
+	t.service.notify(ctx, EventPaused, op)
+
+	return op, nil
+}
+
+func (t *Tracker) resume(ctx context.Context) (Operation, error) {
+	t.mu.Lock()
+
+	if t.op.ended() || t.cancelled {
+		t.mu.Unlock()
+		return Operation{}, ErrNotRunning
+	}
+
+	if t.paused == nil {
+		defer t.mu.Unlock()
+		return t.snapshot(), nil
+	}
+
+	now := time.Now().UnixNano()
+
+	close(t.paused)
+	t.paused = nil
+	t.pausedFor += now - t.pausedAt
+	t.op.State = StateRunning
+	t.op.UpdatedAt = now
+
+	op := t.snapshot()
+	t.mu.Unlock()
+
+	t.service.notify(ctx, EventResumed, op)
+
+	return op, nil
+}
+
+// cancel marks the operation cancelled. It ends when the job returns from
+// its next Step, and calls Finish.
+func (t *Tracker) cancel() (Operation, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.op.ended() {
+		return Operation{}, ErrNotRunning
+	}
+
+	t.cancelled = true
+
+	if t.paused != nil {
+		// wake the job, so it sees the cancel
+		close(t.paused)
+		t.paused = nil
+	}
+
+	return t.snapshot(), nil
+}
+
+// snapshot returns the operation with the Percent and ETA as of now. The
+// lock must be held.
+func (t *Tracker) snapshot() Operation {
+	op := t.op
+
+	if op.Total <= 0 {
+		return op
+	}
+
+	op.Percent = 100 * float64(op.Done) / float64(op.Total)
+
+	if op.Done == 0 || op.ended() {
+		return op
+	}
+
+	now := time.Now().UnixNano()
+
+	active := now - op.StartedAt - t.pausedFor
+	if t.paused != nil {
+		active -= now - t.pausedAt
+	}
+
+	remaining := op.Total - op.Done
+	if remaining > 0 {
+		op.ETA = int64(float64(active) / float64(op.Done) * float64(remaining))
+	}
+
+	return op
+}
+
+// ended returns true if the operation has finished, failed or been
+// cancelled.
+func (op Operation) ended() bool {
+	return op.State == StateFinished ||
+		op.State == StateFailed ||
+		op.State == StateCancelled
+}
+
+// newID returns a random ID for an operation.
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,157 @@
+package operation
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testNotifier struct {
+	mu     *sync.Mutex
+	events *[]EventType
+}
+
+func (n testNotifier) Notify(ctx context.Context, e Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	*n.events = append(*n.events, e.Type)
+	return nil
+}
+
+func TestOperationService(t *testing.T) {
+	ctx := context.Background()
+
+	events := []EventType{}
+	notifier := testNotifier{
+		mu:     &sync.Mutex{},
+		events: &events,
+	}
+
+	s := NewOperationService(notifier)
+
+	tracker := s.Start(ctx, KindRescan, 4)
+	id := tracker.Operation().ID
+
+	if err := tracker.Step(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	op, err := s.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if op.Done != 1 || op.Percent != 25 || op.LastItem != "a" || op.ETA <= 0 {
+		t.Errorf("got\n%#+v\nwant 1 of 4 done", op)
+	}
+
+	if _, err := s.Pause(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	// a paused step blocks until resumed
+	stepped := make(chan error)
+	go func() {
+		stepped <- tracker.Step(ctx, "b")
+	}()
+
+	select {
+	case <-stepped:
+		t.Fatal("step returned while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if _, err := s.Resume(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-stepped; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Cancel(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	err = tracker.Step(ctx, "c")
+	if err != ErrCancelled {
+		t.Fatalf("got error %v, want %v", err, ErrCancelled)
+	}
+
+	tracker.Finish(ctx, err)
+
+	if op, _ := s.Get(id); op.State != StateCancelled || op.Done != 2 {
+		t.Errorf("got\n%#+v\nwant cancelled after 2", op)
+	}
+
+	if _, err := s.Resume(ctx, id); err != ErrNotRunning {
+		t.Errorf("got error %v, want %v", err, ErrNotRunning)
+	}
+
+	if _, err := s.Get("missing"); err != ErrNotFound {
+		t.Errorf("got error %v, want %v", err, ErrNotFound)
+	}
+
+	want := []EventType{
+		EventStarted,
+		EventProgress,
+		EventPaused,
+		EventResumed,
+		EventProgress,
+		EventCancelled,
+	}
+
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("got\n%#+v\nwant\n%#+v", events, want)
+	}
+}
+
+func TestOperationService_Finish(t *testing.T) {
+	ctx := context.Background()
+
+	s := NewOperationService()
+
+	tests := []struct {
+		name string
+		err  error
+		want State
+	}{
+		{
+			name: "finished",
+			want: StateFinished,
+		},
+		{
+			name: "failed",
+			err:  ErrNotFound,
+			want: StateFailed,
+		},
+		{
+			name: "cancelled",
+			err:  ErrCancelled,
+			want: StateCancelled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := s.Start(ctx, KindSync, 0)
+			tracker.Finish(ctx, tt.err)
+
+			op := tracker.Operation()
+			if op.State != tt.want {
+				t.Errorf("got %v, want %v", op.State, tt.want)
+			}
+
+			if op.Percent != 0 || op.ETA != 0 {
+				t.Errorf("got progress of an unknown total\n%#+v", op)
+			}
+		})
+	}
+
+	if got := len(s.All()); got != len(tests) {
+		t.Errorf("got %v operations, want %v", got, len(tests))
+	}
+}
@@ -0,0 +1,331 @@
+package query
+
+/**
+ * Query Kit
+ *
+ * What is my purpose?
+ * - You answer questions about the state of contracts
+ * - You let me browse the votes of a contract, down to each ballot
+ * - You let me search the completed votes of a contract
+ * - You let me read the documents attached to an asset
+ * - You let me fetch the receipt issued for a ballot
+ */
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/internal/app/state"
+	"github.com/tokenized/smart-contract/internal/archive"
+	"github.com/tokenized/smart-contract/internal/document"
+	"github.com/tokenized/smart-contract/internal/receipt"
+	"github.com/tokenized/smart-contract/internal/vote"
+	"github.com/tokenized/smart-contract/pkg/errs"
+)
+
+// QueryService serves read only queries over HTTP. Each request must carry
+// the token in the header
+//
+//	Authorization: Bearer {token}
+//
+// The votes of a contract are browsed with the endpoints
+//
+//	GET /contracts/{contract}/votes?status={open|closed|resulted}
+//	GET /contracts/{contract}/votes/{vote}/totals
+//	GET /contracts/{contract}/votes/{vote}/ballots?anonymize=true
+//
+// Anonymized voters are hashed with the secret, so they can't be matched to
+// the addresses of holders by anyone without it.
+//
+// The completed votes of a contract are searched with the endpoint
+//
+//	GET /contracts/{contract}/archive?asset={asset}&from={ns}&to={ns}&outcome={passed|failed}
+//
+// The documents attached to an asset are listed, and the content of one
+// read, with the endpoints
+//
+//	GET /contracts/{contract}/assets/{asset}/documents
+//	GET /contracts/{contract}/assets/{asset}/documents/{name}
+//
+// The signed receipt issued to a voter for the ballot in a TX is fetched
+// with the endpoint
+//
+//	GET /contracts/{contract}/receipts/{voter}/{txid}
+type QueryService struct {
+	Token     string
+	Secret    []byte
+	State     state.StateInterface
+	Votes     vote.VoteService
+	Archive   archive.ArchiveService
+	Documents document.DocumentService
+	Receipts  receipt.ReceiptService
+}
+
+// ErrAssetNotFound is returned when the asset of a request is not one of
+// the contract.
+var ErrAssetNotFound = errs.New(errs.NotFound, "Asset not found")
+
+// NewQueryService returns a new QueryService, serving requests that carry
+// the token, and anonymizing voters with the secret.
+func NewQueryService(token string,
+	secret []byte,
+	state state.StateInterface,
+	votes vote.VoteService,
+	archive archive.ArchiveService,
+	documents document.DocumentService,
+	receipts receipt.ReceiptService) QueryService {
+
+	return QueryService{
+		Token:     token,
+		Secret:    secret,
+		State:     state,
+		Votes:     votes,
+		Archive:   archive,
+		Documents: documents,
+		Receipts:  receipts,
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s QueryService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	if len(parts) == 3 && parts[0] == "contracts" && parts[2] == "archive" {
+		s.serveArchive(w, r, parts[1])
+		return
+	}
+
+	if len(parts) >= 5 && len(parts) <= 6 && parts[0] == "contracts" &&
+		parts[2] == "assets" && parts[4] == "documents" {
+
+		s.serveDocuments(w, r, parts[1], parts[3], parts[5:])
+		return
+	}
+
+	if len(parts) == 5 && parts[0] == "contracts" && parts[2] == "receipts" {
+		s.serveReceipt(w, r, parts[1], parts[3], parts[4])
+		return
+	}
+
+	if len(parts) < 3 || parts[0] != "contracts" || parts[2] != "votes" {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx := r.Context()
+
+	c, err := s.State.Read(ctx, parts[1])
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	var body interface{}
+
+	switch {
+	case len(parts) == 3:
+		body = s.Votes.List(*c, r.URL.Query().Get("status"))
+
+	case len(parts) == 5 && parts[4] == "totals":
+		body, err = s.Votes.Totals(*c, parts[3])
+
+	case len(parts) == 5 && parts[4] == "ballots":
+		var key []byte
+		if r.URL.Query().Get("anonymize") == "true" {
+			if len(s.Secret) == 0 {
+				http.Error(w, "Anonymizing not configured", http.StatusNotImplemented)
+				return
+			}
+
+			key = s.Secret
+		}
+
+		body, err = s.Votes.BallotDetails(*c, parts[3], key)
+
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeBody(w, r, body)
+}
+
+// serveArchive writes the archived votes of a contract selected by the
+// query parameters.
+func (s QueryService) serveArchive(w http.ResponseWriter,
+	r *http.Request,
+	contractID string) {
+
+	q, err := newArchiveQuery(contractID, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	records, err := s.Archive.Find(r.Context(), q)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeBody(w, r, records)
+}
+
+// serveDocuments writes the documents attached to an asset, or the content
+// of the document of the name, if there is one.
+func (s QueryService) serveDocuments(w http.ResponseWriter,
+	r *http.Request,
+	contractID string,
+	assetID string,
+	name []string) {
+
+	ctx := r.Context()
+
+	c, err := s.State.Read(ctx, contractID)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	a, ok := c.Assets[assetID]
+	if !ok {
+		s.writeError(w, r, ErrAssetNotFound)
+		return
+	}
+
+	if len(name) == 0 {
+		s.writeBody(w, r, a.Documents)
+		return
+	}
+
+	content, err := s.Documents.Retrieve(ctx, a, name[0])
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+
+	if _, err := w.Write(content); err != nil {
+		log := logger.NewLoggerFromContext(ctx).Sugar()
+		log.Errorf("Failed to write response : %v", err)
+	}
+}
+
+// serveReceipt writes the receipt issued by the contract to the voter for
+// the ballot in the TX with the ID.
+func (s QueryService) serveReceipt(w http.ResponseWriter,
+	r *http.Request,
+	contractID string,
+	address string,
+	txID string) {
+
+	rc, err := s.Receipts.Find(r.Context(), address, txID)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	// receipts are stored by voter, so one issued by another contract
+	// sharing the storage is not found for this one
+	if rc.ContractAddress != contractID {
+		s.writeError(w, r, receipt.ErrReceiptNotFound)
+		return
+	}
+
+	s.writeBody(w, r, rc)
+}
+
+// newArchiveQuery returns the archive.Query for the query parameters.
+func newArchiveQuery(contractID string,
+	values url.Values) (archive.Query, error) {
+
+	q := archive.Query{
+		ContractID: contractID,
+		AssetID:    values.Get("asset"),
+		Outcome:    values.Get("outcome"),
+	}
+
+	var err error
+
+	if from := values.Get("from"); from != "" {
+		if q.From, err = strconv.ParseInt(from, 10, 64); err != nil {
+			return q, err
+		}
+	}
+
+	if to := values.Get("to"); to != "" {
+		if q.To, err = strconv.ParseInt(to, 10, 64); err != nil {
+			return q, err
+		}
+	}
+
+	return q, nil
+}
+
+// authorized returns true if the request carries the query token.
+//
+// No request is authorized if the token is not set.
+func (s QueryService) authorized(r *http.Request) bool {
+	if s.Token == "" {
+		return false
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+
+	got := strings.TrimPrefix(auth, "Bearer ")
+
+	return subtle.ConstantTimeCompare([]byte(got), []byte(s.Token)) == 1
+}
+
+// writeBody writes the body of a response as JSON.
+func (s QueryService) writeBody(w http.ResponseWriter,
+	r *http.Request,
+	body interface{}) {
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log := logger.NewLoggerFromContext(r.Context()).Sugar()
+		log.Errorf("Failed to write response : %v", err)
+	}
+}
+
+// writeError writes the HTTP status for an error.
+func (s QueryService) writeError(w http.ResponseWriter,
+	r *http.Request,
+	err error) {
+
+	if status := errs.StatusCode(err); status != http.StatusInternalServerError {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	log := logger.NewLoggerFromContext(r.Context()).Sugar()
+	log.Errorf("Failed to query %v : %v", r.URL.Path, err)
+
+	http.Error(w, "Internal error", http.StatusInternalServerError)
+}
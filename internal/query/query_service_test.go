@@ -0,0 +1,302 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/state"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/internal/archive"
+	"github.com/tokenized/smart-contract/internal/document"
+	"github.com/tokenized/smart-contract/internal/receipt"
+	"github.com/tokenized/smart-contract/internal/vote"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+type memoryState map[string]contract.Contract
+
+func (m memoryState) Write(ctx context.Context, c contract.Contract) error {
+	m[c.ID] = c
+	return nil
+}
+
+func (m memoryState) Read(ctx context.Context,
+	id string) (*contract.Contract, error) {
+
+	c, ok := m[id]
+	if !ok {
+		return nil, state.ErrContractNotFound
+	}
+
+	return &c, nil
+}
+
+func TestQueryService_ServeHTTP(t *testing.T) {
+	contractID := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+
+	st := memoryState{
+		contractID: contract.Contract{
+			ID: contractID,
+			Votes: map[string]contract.Vote{
+				"vote": contract.Vote{
+					VoteOptions: []byte{65, 66},
+					Result: &contract.BallotResult{
+						66: 10,
+					},
+				},
+			},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := storage.NewFilesystemStorage(storage.Config{
+		Root:   dir,
+		Bucket: "test",
+	})
+
+	a := archive.NewArchiveService(store, vote.NewVoteService())
+	if err := a.Index(context.Background(), state.Event{
+		Contract: st[contractID],
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the terms of the asset, and a document altered in storage since it
+	// was attached
+	documents := document.NewDocumentService(store)
+
+	asset, err := documents.Attach(context.Background(),
+		contract.Asset{ID: "asset"}, "terms", []byte("the terms"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	asset, err = documents.Attach(context.Background(), asset, "altered",
+		[]byte("the original"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	altered := fmt.Sprintf("%v/%v/%x", document.DocumentPrefix, asset.ID,
+		asset.Documents["altered"].Hash)
+	if err := store.Write(context.Background(), altered, []byte("altered"),
+		nil); err != nil {
+		t.Fatal(err)
+	}
+
+	c := st[contractID]
+	c.Assets = map[string]contract.Asset{"asset": asset}
+	st[contractID] = c
+
+	// the receipt of a ballot, and of one cast with another contract
+	voterAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+
+	for txID, issuer := range map[string]string{
+		"ballot": contractID,
+		"other":  "1HQ2ULuD7T5ykaucZ3KmTo4i29925Qa6ic",
+	} {
+		b, err := json.Marshal(receipt.Receipt{
+			ContractAddress: issuer,
+			VoterAddress:    voterAddr,
+			TxID:            txID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		path := fmt.Sprintf("%v/%v/%v", receipt.ReceiptPrefix, voterAddr, txID)
+		if err := store.Write(context.Background(), path, b, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewQueryService("secret", []byte("key"), st, vote.NewVoteService(),
+		a, documents, receipt.NewReceiptService(store, nil))
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		status int
+	}{
+		{
+			name:   "no token",
+			path:   "/contracts/" + contractID + "/votes",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "wrong token",
+			path:   "/contracts/" + contractID + "/votes",
+			token:  "guess",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "list votes",
+			path:   "/contracts/" + contractID + "/votes?status=resulted",
+			token:  "secret",
+			status: http.StatusOK,
+		},
+		{
+			name:   "totals",
+			path:   "/contracts/" + contractID + "/votes/vote/totals",
+			token:  "secret",
+			status: http.StatusOK,
+		},
+		{
+			name:   "ballots",
+			path:   "/contracts/" + contractID + "/votes/vote/ballots?anonymize=true",
+			token:  "secret",
+			status: http.StatusOK,
+		},
+		{
+			name:   "unknown contract",
+			path:   "/contracts/missing/votes",
+			token:  "secret",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "unknown vote",
+			path:   "/contracts/" + contractID + "/votes/missing/totals",
+			token:  "secret",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "archive",
+			path:   "/contracts/" + contractID + "/archive?outcome=passed&from=0",
+			token:  "secret",
+			status: http.StatusOK,
+		},
+		{
+			name:   "archive with invalid date",
+			path:   "/contracts/" + contractID + "/archive?from=yesterday",
+			token:  "secret",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "documents",
+			path:   "/contracts/" + contractID + "/assets/asset/documents",
+			token:  "secret",
+			status: http.StatusOK,
+		},
+		{
+			name:   "document",
+			path:   "/contracts/" + contractID + "/assets/asset/documents/terms",
+			token:  "secret",
+			status: http.StatusOK,
+		},
+		{
+			name:   "unknown document",
+			path:   "/contracts/" + contractID + "/assets/asset/documents/missing",
+			token:  "secret",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "documents of unknown asset",
+			path:   "/contracts/" + contractID + "/assets/missing/documents",
+			token:  "secret",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "altered document",
+			path:   "/contracts/" + contractID + "/assets/asset/documents/altered",
+			token:  "secret",
+			status: http.StatusInternalServerError,
+		},
+		{
+			name:   "receipt",
+			path:   "/contracts/" + contractID + "/receipts/" + voterAddr + "/ballot",
+			token:  "secret",
+			status: http.StatusOK,
+		},
+		{
+			name:   "unknown receipt",
+			path:   "/contracts/" + contractID + "/receipts/" + voterAddr + "/missing",
+			token:  "secret",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "receipt of other contract",
+			path:   "/contracts/" + contractID + "/receipts/" + voterAddr + "/other",
+			token:  "secret",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "unknown endpoint",
+			path:   "/contracts/" + contractID + "/assets",
+			token:  "secret",
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			w := httptest.NewRecorder()
+
+			s.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("got status %v, want %v", w.Code, tt.status)
+			}
+		})
+	}
+
+	// the totals are reported in the order of the vote options
+	r := httptest.NewRequest(http.MethodGet,
+		"/contracts/"+contractID+"/votes/vote/totals", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+
+	s.ServeHTTP(w, r)
+
+	totals := []vote.OptionTotal{}
+	if err := json.NewDecoder(w.Body).Decode(&totals); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(totals) != 2 || totals[1].Option != 66 || totals[1].Tally != 10 {
+		t.Errorf("got totals %#+v", totals)
+	}
+
+	// the content of a document is written as is
+	r = httptest.NewRequest(http.MethodGet,
+		"/contracts/"+contractID+"/assets/asset/documents/terms", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+
+	s.ServeHTTP(w, r)
+
+	if got := w.Body.String(); got != "the terms" {
+		t.Errorf("got document %q, want %q", got, "the terms")
+	}
+
+	// voters aren't anonymized without a secret to hash them with
+	s.Secret = nil
+
+	r = httptest.NewRequest(http.MethodGet,
+		"/contracts/"+contractID+"/votes/vote/ballots?anonymize=true", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+
+	s.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("got status %v, want %v", w.Code, http.StatusNotImplemented)
+	}
+}
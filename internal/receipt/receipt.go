@@ -0,0 +1,117 @@
+package receipt
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+)
+
+var (
+	ErrInvalidSignature = errors.New("Invalid receipt signature")
+	ErrWrongSigner      = errors.New("Receipt not signed by contract")
+)
+
+// Receipt is issued to a voter when their ballot is accepted, signed by the
+// key of the contract.
+//
+// A voter can later present the Receipt to prove their ballot was accepted.
+type Receipt struct {
+	ContractAddress string `json:"contract_address"`
+	VoteTxnID       string `json:"vote_txn_id"`
+	VoterAddress    string `json:"voter_address"`
+	Vote            []byte `json:"vote"`
+	Weight          uint64 `json:"weight"`
+	TxID            string `json:"txid"`
+	IssuedAt        int64  `json:"issued_at"`
+	Signature       []byte `json:"signature,omitempty"`
+}
+
+// NewReceipt returns an unsigned Receipt for a ballot accepted for a Vote
+// of the contract, in the TX with the ID.
+func NewReceipt(c contract.Contract,
+	ballot contract.Ballot,
+	txID string) Receipt {
+
+	vo := c.Votes[ballot.VoteTxnID]
+
+	return Receipt{
+		ContractAddress: c.ID,
+		VoteTxnID:       ballot.VoteTxnID,
+		VoterAddress:    ballot.Address,
+		Vote:            ballot.Vote,
+		Weight:          c.HoldingsOf(ballot.Address, vo.Scope()),
+		TxID:            txID,
+		IssuedAt:        time.Now().UnixNano(),
+	}
+}
+
+// Sign signs the Receipt with the key.
+func (r *Receipt) Sign(key *btcec.PrivateKey) error {
+	hash, err := r.hash()
+	if err != nil {
+		return err
+	}
+
+	sig, err := key.Sign(hash)
+	if err != nil {
+		return err
+	}
+
+	r.Signature = sig.Serialize()
+
+	return nil
+}
+
+// Verify returns nil if the Receipt was signed by the key of the contract
+// it was issued for, the address of the contract being encoded for the
+// network of the params.
+func Verify(r Receipt,
+	pub *btcec.PublicKey,
+	params *chaincfg.Params) error {
+
+	address, err := btcutil.NewAddressPubKeyHash(
+		btcutil.Hash160(pub.SerializeCompressed()), params)
+	if err != nil {
+		return err
+	}
+
+	if address.EncodeAddress() != r.ContractAddress {
+		return ErrWrongSigner
+	}
+
+	sig, err := btcec.ParseDERSignature(r.Signature, btcec.S256())
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	hash, err := r.hash()
+	if err != nil {
+		return err
+	}
+
+	if !sig.Verify(hash, pub) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// hash returns the hash of the Receipt that is signed, excluding the
+// signature itself.
+func (r Receipt) hash() ([]byte, error) {
+	r.Signature = nil
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return chainhash.DoubleHashB(b), nil
+}
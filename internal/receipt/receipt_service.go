@@ -0,0 +1,102 @@
+package receipt
+
+/**
+ * Receipt Service
+ *
+ * What is my purpose?
+ * - You issue a signed receipt when a ballot is accepted
+ * - You store receipts for each voter
+ * - You find the receipts of a voter
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/internal/app/wallet"
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+const (
+	// ReceiptPrefix is the storage path that Receipt's are written to.
+	ReceiptPrefix = "receipts"
+)
+
+var ErrReceiptNotFound = errs.New(errs.NotFound, "Receipt not found")
+
+type ReceiptService struct {
+	Storage storage.ReadWriter
+	Wallet  wallet.WalletInterface
+}
+
+func NewReceiptService(store storage.ReadWriter,
+	wallet wallet.WalletInterface) ReceiptService {
+
+	return ReceiptService{
+		Storage: store,
+		Wallet:  wallet,
+	}
+}
+
+// Issue signs and stores a Receipt for a ballot accepted in the TX with
+// the ID.
+func (s ReceiptService) Issue(ctx context.Context,
+	c contract.Contract,
+	ballot contract.Ballot,
+	txID string) (*Receipt, error) {
+
+	key, err := s.Wallet.Get(c.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewReceipt(c, ballot, txID)
+	if err := r.Sign(key); err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Storage.Write(ctx, s.buildPath(r.VoterAddress, r.TxID), b, nil); err != nil {
+		return nil, err
+	}
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+	log.Infof("Issued receipt to %v for ballot %v", r.VoterAddress, r.TxID)
+
+	return &r, nil
+}
+
+// Find returns the Receipt issued to the voter for the ballot in the TX
+// with the ID.
+func (s ReceiptService) Find(ctx context.Context,
+	address string,
+	txID string) (*Receipt, error) {
+
+	b, err := s.Storage.Read(ctx, s.buildPath(address, txID))
+	if err != nil {
+		if err == storage.ErrNotFound {
+			err = ErrReceiptNotFound
+		}
+
+		return nil, err
+	}
+
+	r := Receipt{}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+func (s ReceiptService) buildPath(address, txID string) string {
+	return fmt.Sprintf("%v/%v/%v", ReceiptPrefix, address, txID)
+}
@@ -0,0 +1,174 @@
+package receipt
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/internal/app/wallet"
+	"github.com/tokenized/smart-contract/pkg/storage"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+)
+
+type memoryStorage map[string][]byte
+
+func (m memoryStorage) Read(ctx context.Context, key string) ([]byte, error) {
+	b, ok := m[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	return b, nil
+}
+
+func (m memoryStorage) Write(ctx context.Context,
+	key string,
+	body []byte,
+	opts *storage.Options) error {
+
+	m[key] = body
+	return nil
+}
+
+func TestReceiptService(t *testing.T) {
+	ctx := context.Background()
+
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	voteTxnID := "d5d1b2ff2a4ff5b9ef1cd6c4fbcaf3b7f00e5ad6e4e8cf3b0e5e95d3c30a8a4f"
+	txID := "5a4a3f2c1f1e0d9b8c7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c"
+
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wif, err := btcutil.NewWIF(key, &chaincfg.MainNetParams, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := wallet.NewWallet(wif.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := contract.Contract{
+		ID: w.PublicAddress,
+		Assets: map[string]contract.Asset{
+			assetID: contract.Asset{
+				Holdings: map[string]contract.Holding{
+					userAddr: contract.Holding{
+						Address: userAddr,
+						Balance: 42,
+					},
+				},
+			},
+		},
+		Votes: map[string]contract.Vote{
+			voteTxnID: contract.Vote{
+				AssetID: assetID,
+			},
+		},
+	}
+
+	ballot := contract.Ballot{
+		Address:   userAddr,
+		AssetID:   assetID,
+		VoteTxnID: voteTxnID,
+		Vote:      []byte{65},
+	}
+
+	store := memoryStorage{}
+	s := NewReceiptService(store, *w)
+
+	issued, err := s.Issue(ctx, c, ballot, txID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if issued.Weight != 42 {
+		t.Errorf("got weight %v, want %v", issued.Weight, 42)
+	}
+
+	found, err := s.Find(ctx, userAddr, txID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(found, issued) {
+		t.Fatalf("got\n%#+v\nwant\n%#+v", found, issued)
+	}
+
+	if _, err := s.Find(ctx, userAddr, voteTxnID); err != ErrReceiptNotFound {
+		t.Errorf("got error %v, want %v", err, ErrReceiptNotFound)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Receipt)
+		pub    *btcec.PublicKey
+		params *chaincfg.Params
+		err    error
+	}{
+		{
+			name:   "valid",
+			modify: func(r *Receipt) {},
+			pub:    key.PubKey(),
+		},
+		{
+			name: "changed weight",
+			modify: func(r *Receipt) {
+				r.Weight = 1000
+			},
+			pub: key.PubKey(),
+			err: ErrInvalidSignature,
+		},
+		{
+			name: "changed vote",
+			modify: func(r *Receipt) {
+				r.Vote = []byte{66}
+			},
+			pub: key.PubKey(),
+			err: ErrInvalidSignature,
+		},
+		{
+			name:   "other key",
+			modify: func(r *Receipt) {},
+			pub:    other.PubKey(),
+			err:    ErrWrongSigner,
+		},
+		{
+			name:   "other network",
+			modify: func(r *Receipt) {},
+			pub:    key.PubKey(),
+			params: &chaincfg.TestNet3Params,
+			err:    ErrWrongSigner,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := *found
+			tt.modify(&r)
+
+			params := tt.params
+			if params == nil {
+				params = &chaincfg.MainNetParams
+			}
+
+			if err := Verify(r, tt.pub, params); err != tt.err {
+				t.Errorf("got error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -2,9 +2,9 @@ package request
 
 import (
 	"context"
-	"errors"
 
 	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/protocol"
 	"github.com/tokenized/smart-contract/pkg/txbuilder"
 )
@@ -26,7 +26,7 @@ func (h assetDefinitionHandler) handle(ctx context.Context,
 
 	ad, ok := r.m.(*protocol.AssetDefinition)
 	if !ok {
-		return nil, errors.New("Not *protocol.AssetDefinition")
+		return nil, errs.New(errs.Invalid, "Not *protocol.AssetDefinition")
 	}
 
 	// Contract
@@ -76,11 +76,11 @@ func (h assetDefinitionHandler) buildOutputs(r contractRequest) ([]txbuilder.TxO
 	outs := []txbuilder.TxOutput{
 		txbuilder.TxOutput{
 			Address: contractAddress,
-			Value:   dustLimit,
+			Value:   r.contract.DustPolicy.Minimum(),
 		},
 		txbuilder.TxOutput{
 			Address: r.senders[0],
-			Value:   dustLimit, // any change will be added to this output value
+			Value:   r.contract.DustPolicy.Minimum(), // any change will be added to this output value
 		},
 	}
 
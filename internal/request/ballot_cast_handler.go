@@ -2,9 +2,9 @@ package request
 
 import (
 	"context"
-	"errors"
+	"time"
 
-	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/protocol"
 )
 
@@ -19,7 +19,7 @@ func (h ballotCastHandler) handle(ctx context.Context,
 
 	ballotCast, ok := r.m.(*protocol.BallotCast)
 	if !ok {
-		return nil, errors.New("Not *protocol.BallotCast")
+		return nil, errs.New(errs.Invalid, "Not *protocol.BallotCast")
 	}
 
 	// Contract
@@ -29,10 +29,10 @@ func (h ballotCastHandler) handle(ctx context.Context,
 	key := string(ballotCast.VoteTxnID)
 	vote, ok := c.Votes[key]
 	if !ok {
-		return nil, errors.New("Vote not found")
+		return nil, errs.New(errs.NotFound, "Vote not found")
 	}
 
-	ballot := contract.NewBallotFromBallotCast(r.senders[0], ballotCast)
+	ballot := vote.NewBallot(r.senders[0], ballotCast, time.Now())
 
 	// there is no response from the ballot cast, until the vote cut off
 	// time has been reached.
@@ -0,0 +1,68 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+)
+
+// BenchmarkSendHandler_handle performs a benchmark on how long it takes to
+// build the settlement of a send.
+func BenchmarkSendHandler_handle(b *testing.B) {
+	ctx := newSilentContext()
+
+	hash := newHash("82b1576993052733ca685419ca4be32cde1e6f7c772e839cd76cd931537222b8")
+
+	issuerAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	receiverAddr := "123h2RL1DT4AuYyJUseGxcXSAe5imPSeLV"
+
+	asset := contract.Asset{
+		ID:  "foo",
+		Qty: 20,
+		Holdings: map[string]contract.Holding{
+			issuerAddr: contract.Holding{
+				Address: issuerAddr,
+				Balance: 20,
+			},
+		},
+	}
+
+	issue := protocol.NewSend()
+	issue.AssetID = []byte(asset.ID)
+	issue.AssetType = []byte("RRE")
+	issue.TokenQty = 1
+
+	req := contractRequest{
+		hash: hash,
+		contract: contract.Contract{
+			ID:            "1DNTgNSWtTestKs7j1DwaoxmSc4q9sEUsb",
+			IssuerAddress: issuerAddr,
+			Assets: map[string]contract.Asset{
+				asset.ID: asset,
+			},
+		},
+		senders: []btcutil.Address{
+			decodeAddress(issuerAddr),
+		},
+		receivers: []txbuilder.TxOutput{
+			txbuilder.TxOutput{},
+			txbuilder.TxOutput{
+				Address: decodeAddress(receiverAddr),
+			},
+		},
+		m: &issue,
+	}
+
+	h := newSendHandler(newTestConfig().Fee)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := h.handle(ctx, req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
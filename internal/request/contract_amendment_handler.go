@@ -2,9 +2,9 @@ package request
 
 import (
 	"context"
-	"errors"
 
 	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/protocol"
 	"github.com/tokenized/smart-contract/pkg/txbuilder"
 )
@@ -26,7 +26,7 @@ func (h contractAmendmentHandler) handle(ctx context.Context,
 
 	ca, ok := r.m.(*protocol.ContractAmendment)
 	if !ok {
-		return nil, errors.New("Not *protocol.ContractAmendment")
+		return nil, errs.New(errs.Invalid, "Not *protocol.ContractAmendment")
 	}
 
 	// Contract
@@ -63,7 +63,7 @@ func (h contractAmendmentHandler) handle(ctx context.Context,
 	// outs := []txbuilder.TxOutput{
 	// 	txbuilder.TxOutput{
 	// 		Address: r.sender,
-	// 		Value:   dustLimit,
+	// 		Value:   r.contract.DustPolicy.Minimum(),
 	// 	},
 	// }
 
@@ -85,11 +85,11 @@ func (h contractAmendmentHandler) buildOutputs(r contractRequest) ([]txbuilder.T
 	outs := []txbuilder.TxOutput{
 		txbuilder.TxOutput{
 			Address: contractAddress,
-			Value:   dustLimit,
+			Value:   r.contract.DustPolicy.Minimum(),
 		},
 		txbuilder.TxOutput{
 			Address: r.senders[0],
-			Value:   dustLimit, // any change will be added to this output value
+			Value:   r.contract.DustPolicy.Minimum(), // any change will be added to this output value
 		},
 	}
 
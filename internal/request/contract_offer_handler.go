@@ -2,9 +2,9 @@ package request
 
 import (
 	"context"
-	"errors"
 
 	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/protocol"
 	"github.com/tokenized/smart-contract/pkg/txbuilder"
 )
@@ -24,7 +24,7 @@ func (h contractOfferHandler) handle(ctx context.Context,
 
 	co, ok := r.m.(*protocol.ContractOffer)
 	if !ok {
-		return nil, errors.New("Not *protocol.ContractOffer")
+		return nil, errs.New(errs.Invalid, "Not *protocol.ContractOffer")
 	}
 
 	// Contract Formation <- Contract Offer
@@ -70,11 +70,11 @@ func (h contractOfferHandler) buildOutputs(r contractRequest) ([]txbuilder.TxOut
 	outs := []txbuilder.TxOutput{
 		txbuilder.TxOutput{
 			Address: contractAddress,
-			Value:   dustLimit,
+			Value:   r.contract.DustPolicy.Minimum(),
 		},
 		txbuilder.TxOutput{
 			Address: r.senders[0],
-			Value:   dustLimit, // any change will be added to this output value
+			Value:   r.contract.DustPolicy.Minimum(), // any change will be added to this output value
 		},
 	}
 
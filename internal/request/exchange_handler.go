@@ -2,7 +2,6 @@ package request
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"time"
 
@@ -10,6 +9,7 @@ import (
 	"github.com/btcsuite/btcutil"
 	"github.com/tokenized/smart-contract/internal/app/config"
 	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/protocol"
 	"github.com/tokenized/smart-contract/pkg/txbuilder"
 )
@@ -29,7 +29,7 @@ func (h exchangeHandler) handle(ctx context.Context,
 
 	exchange, ok := r.m.(*protocol.Exchange)
 	if !ok {
-		return nil, errors.New("Not *protocol.Exchange")
+		return nil, errs.New(errs.Invalid, "Not *protocol.Exchange")
 	}
 
 	// Contract
@@ -96,8 +96,8 @@ func (h exchangeHandler) handle(ctx context.Context,
 	// 2200 if the fee for Exchange
 	party1OutValue := r.receivers[0].Value - 2200
 
-	if party1OutValue < 546 {
-		party1OutValue = 546
+	if minimum := c.DustPolicy.Minimum(); party1OutValue < minimum {
+		party1OutValue = minimum
 	}
 
 	// Outputs
@@ -106,10 +106,20 @@ func (h exchangeHandler) handle(ctx context.Context,
 		return nil, err
 	}
 
+	// Transfer fee of the asset, split between its payees
+	feeOutputs, fee, err := newTransferFee(asset)
+	if err != nil {
+		return nil, err
+	}
+
 	resp := contractResponse{
 		Contract: c,
 		Message:  &settlement,
-		outs:     outputs,
+		outs:     append(outputs, feeOutputs...),
+	}
+
+	if fee != nil {
+		resp.fees = append(resp.fees, *fee)
 	}
 
 	return &resp, nil
@@ -126,22 +136,22 @@ func (h exchangeHandler) buildOutputs(r contractRequest) ([]txbuilder.TxOutput,
 
 	exchange, ok := r.m.(*protocol.Exchange)
 	if !ok {
-		return nil, errors.New("Not *protocol.Exchange")
+		return nil, errs.New(errs.Invalid, "Not *protocol.Exchange")
 	}
 
 	// the TX needs to pay to the Receiver as well, so add that here.
 	outs := []txbuilder.TxOutput{
 		txbuilder.TxOutput{
 			Address: party1Addr,
-			Value:   dustLimit, // any change will be added to this output value
+			Value:   r.contract.DustPolicy.Minimum(), // any change will be added to this output value
 		},
 		txbuilder.TxOutput{
 			Address: party2Addr,
-			Value:   dustLimit,
+			Value:   r.contract.DustPolicy.Minimum(),
 		},
 		txbuilder.TxOutput{
 			Address: contractAddress,
-			Value:   dustLimit,
+			Value:   r.contract.DustPolicy.Minimum(),
 		},
 	}
 
@@ -2,12 +2,12 @@ package request
 
 import (
 	"context"
-	"errors"
 	"time"
 
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcutil"
 	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/protocol"
 	"github.com/tokenized/smart-contract/pkg/txbuilder"
 )
@@ -23,7 +23,7 @@ func (h initiativeHandler) handle(ctx context.Context,
 
 	initiative, ok := r.m.(*protocol.Initiative)
 	if !ok {
-		return nil, errors.New("Not *protocol.Initiative")
+		return nil, errs.New(errs.Invalid, "Not *protocol.Initiative")
 	}
 
 	// Contract
@@ -54,7 +54,7 @@ func (h initiativeHandler) handle(ctx context.Context,
 	c.Votes[v.RefTxnIDHash] = v
 
 	// calculate the fee to pay the issuer.
-	issuerFee := uint64(546)
+	issuerFee := c.DustPolicy.Minimum()
 
 	// how much to return to the sender?
 	// 0 : Contract's Public Address
@@ -0,0 +1,145 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+)
+
+type messageHandler struct {
+	Fee config.Fee
+}
+
+func newMessageHandler(fee config.Fee) messageHandler {
+	return messageHandler{
+		Fee: fee,
+	}
+}
+
+// handle accepts a transfer awaiting the acceptance of the receiver,
+// settling it.
+//
+// No other types of Message are handled.
+func (h messageHandler) handle(ctx context.Context,
+	r contractRequest) (*contractResponse, error) {
+
+	m, ok := r.m.(*protocol.Message)
+	if !ok {
+		return nil, errs.New(errs.Invalid, "Not *protocol.Message")
+	}
+
+	if string(m.MessageType) != contract.MessageTypeTransferAccept {
+		return nil, fmt.Errorf("Unsupported message type %s", m.MessageType)
+	}
+
+	// Contract
+	c := r.contract
+
+	id := string(m.Message)
+	pending, ok := c.PendingTransfers[id]
+	if !ok {
+		return nil, fmt.Errorf("message : Transfer not found : contract=%s id=%s", c.ID, id)
+	}
+
+	asset, ok := c.Assets[pending.AssetID]
+	if !ok {
+		return nil, fmt.Errorf("message : Asset ID not found : contract=%s assetID=%s", c.ID, pending.AssetID)
+	}
+
+	// The tokens of the sender are already held in escrow, so only the
+	// receiver balance changes.
+	party1Balance := asset.Holdings[pending.Sender].Balance
+	party2Balance := asset.Holdings[pending.Receiver].Balance + pending.TokenQty
+
+	// Settlement <- Message
+	settlement := protocol.NewSettlement()
+	settlement.AssetType = []byte(pending.AssetType)
+	settlement.AssetID = []byte(pending.AssetID)
+	settlement.Party1TokenQty = party1Balance
+	settlement.Party2TokenQty = party2Balance
+	settlement.Timestamp = uint64(time.Now().Unix())
+
+	// Outputs
+	outputs, err := h.buildOutputs(r, pending)
+	if err != nil {
+		return nil, err
+	}
+
+	// Transfer fee of the asset, split between its payees
+	feeOutputs, fee, err := newTransferFee(asset)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := contractResponse{
+		Contract: c,
+		Message:  &settlement,
+		outs:     append(outputs, feeOutputs...),
+		release:  pending.ID,
+	}
+
+	if fee != nil {
+		resp.fees = append(resp.fees, *fee)
+	}
+
+	return &resp, nil
+}
+
+// buildOutputs returns the outputs of a Settlement, paying the sender and
+// receiver of the transfer in the order the Settlement expects.
+func (h messageHandler) buildOutputs(r contractRequest,
+	pending contract.PendingTransfer) ([]txbuilder.TxOutput, error) {
+
+	party1Addr, err := btcutil.DecodeAddress(pending.Sender,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		return nil, err
+	}
+
+	party2Addr, err := btcutil.DecodeAddress(pending.Receiver,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		return nil, err
+	}
+
+	contractAddress, err := r.contract.Address()
+	if err != nil {
+		return nil, err
+	}
+
+	outs := []txbuilder.TxOutput{
+		txbuilder.TxOutput{
+			Address: party1Addr,
+			Value:   r.contract.DustPolicy.Minimum(),
+		},
+		txbuilder.TxOutput{
+			Address: party2Addr,
+			Value:   r.contract.DustPolicy.Minimum(), // any change will be added to this output value
+		},
+		txbuilder.TxOutput{
+			Address: contractAddress,
+			Value:   r.contract.DustPolicy.Minimum(),
+		},
+	}
+
+	// optional contract fee
+	if h.Fee.Value > 0 {
+		feeOutput := txbuilder.TxOutput{
+			Address: h.Fee.Address,
+			Value:   h.Fee.Value,
+		}
+
+		outs = append(outs, feeOutput)
+	}
+
+	return outs, nil
+}
@@ -0,0 +1,99 @@
+package request
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+func TestMessageHandler_handle(t *testing.T) {
+	ctx := newSilentContext()
+
+	hash := newHash("82b1576993052733ca685419ca4be32cde1e6f7c772e839cd76cd931537222b8")
+
+	contractAddr := "1DNTgNSWtTestKs7j1DwaoxmSc4q9sEUsb"
+
+	issuerAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	receiverAddr := "123h2RL1DT4AuYyJUseGxcXSAe5imPSeLV"
+
+	asset := contract.Asset{
+		ID:               "foo",
+		Type:             "RRE",
+		Qty:              20,
+		ReceiverApproval: true,
+		Holdings: map[string]contract.Holding{
+			issuerAddr: contract.Holding{
+				Address: issuerAddr,
+				Balance: 20,
+			},
+		},
+	}
+
+	c := contract.Contract{
+		ID:            contractAddr,
+		IssuerAddress: issuerAddr,
+		Assets: map[string]contract.Asset{
+			asset.ID: asset,
+		},
+	}
+
+	pending := contract.NewPendingTransfer("1", asset, issuerAddr,
+		receiverAddr, 1)
+	c.Escrow(pending)
+
+	accept := protocol.NewMessage()
+	accept.MessageType = []byte(contract.MessageTypeTransferAccept)
+	accept.Message = []byte(pending.ID)
+
+	req := contractRequest{
+		hash:     hash,
+		contract: c,
+		senders: []btcutil.Address{
+			decodeAddress(receiverAddr),
+		},
+		m: &accept,
+	}
+
+	config := newTestConfig()
+
+	h := newMessageHandler(config.Fee)
+	resp, err := h.handle(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settlement, ok := resp.Message.(*protocol.Settlement)
+	if !ok {
+		t.Fatalf("Could not assert as *Settlement : %#+v\n", resp.Message)
+	}
+
+	// timestamp should be close
+	ts := time.Now().Unix()
+	if !isCloseTo(int64(settlement.Timestamp), ts, 1) {
+		t.Errorf("Expected %v to be close to %v", settlement.Timestamp, ts)
+	}
+
+	wantSettlement := protocol.NewSettlement()
+	wantSettlement.AssetType = []byte(asset.Type)
+	wantSettlement.AssetID = []byte(asset.ID)
+	wantSettlement.Party1TokenQty = 19
+	wantSettlement.Party2TokenQty = 1
+	wantSettlement.Timestamp = settlement.Timestamp
+
+	if !reflect.DeepEqual(settlement, &wantSettlement) {
+		t.Fatalf("got\n%+v\nwant\n%+v", settlement, &wantSettlement)
+	}
+
+	if resp.release != pending.ID {
+		t.Errorf("got release %v, want %v", resp.release, pending.ID)
+	}
+
+	// the settlement pays the sender then the receiver
+	if got := resp.outs[1].Address.EncodeAddress(); got != receiverAddr {
+		t.Errorf("got receiver %v, want %v", got, receiverAddr)
+	}
+}
@@ -2,7 +2,6 @@ package request
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"time"
 
@@ -10,6 +9,7 @@ import (
 	"github.com/btcsuite/btcutil"
 	"github.com/tokenized/smart-contract/internal/app/config"
 	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/protocol"
 	"github.com/tokenized/smart-contract/pkg/txbuilder"
 )
@@ -29,7 +29,7 @@ func (h orderHandler) handle(ctx context.Context,
 
 	order, ok := r.m.(*protocol.Order)
 	if !ok {
-		return nil, errors.New("Not *protocol.Order")
+		return nil, errs.New(errs.Invalid, "Not *protocol.Order")
 	}
 
 	// Contract
@@ -218,11 +218,11 @@ func (h orderHandler) buildFreezeThawOutputs(contract contract.Contract,
 	outs := []txbuilder.TxOutput{
 		txbuilder.TxOutput{
 			Address: targetAddr,
-			Value:   546,
+			Value:   contract.DustPolicy.Minimum(),
 		},
 		txbuilder.TxOutput{
 			Address: contractAddr,
-			Value:   546, // address will receive change, if any
+			Value:   contract.DustPolicy.Minimum(), // address will receive change, if any
 		},
 	}
 
@@ -263,15 +263,15 @@ func (h orderHandler) buildConfiscateOutputs(contract contract.Contract,
 	outs := []txbuilder.TxOutput{
 		txbuilder.TxOutput{
 			Address: targetAddr,
-			Value:   546,
+			Value:   contract.DustPolicy.Minimum(),
 		},
 		txbuilder.TxOutput{
 			Address: depositAddr,
-			Value:   546,
+			Value:   contract.DustPolicy.Minimum(),
 		},
 		txbuilder.TxOutput{
 			Address: contractAddr,
-			Value:   546, // address will receive change, if any
+			Value:   contract.DustPolicy.Minimum(), // address will receive change, if any
 		},
 	}
 
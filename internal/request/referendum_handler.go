@@ -2,10 +2,10 @@ package request
 
 import (
 	"context"
-	"errors"
 	"time"
 
 	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/protocol"
 	"github.com/tokenized/smart-contract/pkg/txbuilder"
 )
@@ -21,7 +21,7 @@ func (h referendumHandler) handle(ctx context.Context,
 
 	referendum, ok := r.m.(*protocol.Referendum)
 	if !ok {
-		return nil, errors.New("Not *protocol.Referendum")
+		return nil, errs.New(errs.Invalid, "Not *protocol.Referendum")
 	}
 
 	// Contract
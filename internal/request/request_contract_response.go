@@ -13,4 +13,14 @@ type contractResponse struct {
 	outs          []txbuilder.TxOutput
 	Responses     []contractResponse
 	changeAddress btcutil.Address
+
+	// escrow is a transfer held until the receiver accepts it.
+	escrow *contract.PendingTransfer
+
+	// release is the ID of a pending transfer settled by the response.
+	release string
+
+	// fees are the transfer fees paid by the response, recorded in the
+	// audit log.
+	fees []transferFee
 }
@@ -13,6 +13,7 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
+	"time"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/tokenized/smart-contract/internal/app/config"
@@ -20,6 +21,7 @@ import (
 	"github.com/tokenized/smart-contract/internal/app/state"
 	"github.com/tokenized/smart-contract/internal/app/state/contract"
 	"github.com/tokenized/smart-contract/internal/app/wallet"
+	"github.com/tokenized/smart-contract/internal/audit"
 	"github.com/tokenized/smart-contract/pkg/protocol"
 )
 
@@ -35,13 +37,10 @@ var (
 		protocol.CodeReferendum:        true,
 		protocol.CodeBallotCast:        true,
 		protocol.CodeOrder:             true,
+		protocol.CodeMessage:           true,
 	}
 )
 
-const (
-	dustLimit = 546
-)
-
 func newRequestHandlers(state state.StateInterface,
 	config config.Config) map[string]requestHandlerInterface {
 
@@ -53,6 +52,7 @@ func newRequestHandlers(state state.StateInterface,
 		protocol.CodeSend:              newSendHandler(config.Fee),
 		protocol.CodeExchange:          newExchangeHandler(config.Fee),
 		protocol.CodeOrder:             newOrderHandler(config.Fee),
+		protocol.CodeMessage:           newMessageHandler(config.Fee),
 		// protocol.CodeInitiative:        newInitiativeHandler(),
 		// protocol.CodeReferendum:        newReferendumHandler(),
 		// protocol.CodeBallotCast:        newBallotCastHandler(),
@@ -64,19 +64,24 @@ type RequestService struct {
 	State     state.StateInterface
 	Wallet    wallet.WalletInterface
 	Inspector inspector.InspectorService
+	Recorders []audit.Recorder
 	handlers  map[string]requestHandlerInterface
 }
 
+// NewRequestService returns a new RequestService. The transfer fees paid by
+// each response are recorded with the recorders.
 func NewRequestService(config config.Config,
 	wallet wallet.WalletInterface,
 	state state.StateInterface,
-	inspector inspector.InspectorService) RequestService {
+	inspector inspector.InspectorService,
+	recorders ...audit.Recorder) RequestService {
 
 	return RequestService{
 		Config:    config,
 		State:     state,
 		Wallet:    wallet,
 		Inspector: inspector,
+		Recorders: recorders,
 		handlers:  newRequestHandlers(state, config),
 	}
 }
@@ -101,9 +106,11 @@ func (s RequestService) PreFilter(ctx context.Context,
 	}
 
 	contractAddress := itx.Outputs[0].Address.String()
-	_, err := s.Wallet.Get(contractAddress)
-	if err != nil {
-		return nil, err
+	if _, err := s.Wallet.Get(contractAddress); err != nil {
+		// a multisig contract address has a signer quorum instead of a key
+		if _, qerr := s.Wallet.GetQuorum(contractAddress); qerr != nil {
+			return nil, err
+		}
 	}
 
 	return itx, nil
@@ -142,6 +149,12 @@ func (s RequestService) Process(ctx context.Context,
 
 	res.Contract.Hashes = append(res.Contract.Hashes, hash.String())
 
+	// Apply the dust policy of the contract to the outputs
+	outs, err := res.Contract.DustPolicy.Apply(res.outs)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get spendable UTXO's received for the contract address
 	contractAddress := itx.Outputs[0].Address
 	utxos, err := itx.UTXOs.ForAddress(contractAddress)
@@ -156,24 +169,64 @@ func (s RequestService) Process(ctx context.Context,
 		changeAddress = res.changeAddress
 	}
 
-	// Contract private key
-	key, err := s.Wallet.Get(contractAddress.String())
+	// Create usable transaction to pass back, signed by the contract key or
+	// signer quorum
+	newTx, err := s.Wallet.BuildContractTX(ctx, contractAddress, utxos,
+		outs, changeAddress, res.Message)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create usable transaction to pass back
-	newTx, err := s.Wallet.BuildTX(key, utxos, res.outs, changeAddress, res.Message)
-	if err != nil {
+	if err := s.recordFees(ctx, res, hash, newTx.MsgTx.TxHash()); err != nil {
 		return nil, err
 	}
 
-	newItx := s.Inspector.CreateTransaction(utxos, res.outs, res.Message)
-	newItx.MsgTx = newTx
+	newItx := s.Inspector.CreateTransaction(utxos, outs, res.Message)
+	newItx.MsgTx = newTx.MsgTx
+	newItx.ChangeIndex = newTx.ChangeIndex
+
+	// transfers awaiting acceptance by the receiver are held and released
+	// when the response is processed
+	newItx.Escrow = res.escrow
+	newItx.Release = res.release
 
 	return newItx, nil
 }
 
+// recordFees records the transfer fees paid by the response to the request
+// in the audit log, as paid by the outputs once the dust policy of the
+// contract is applied.
+func (s RequestService) recordFees(ctx context.Context,
+	res *contractResponse,
+	requestHash chainhash.Hash,
+	responseHash chainhash.Hash) error {
+
+	for _, fee := range res.fees {
+		fee, err := fee.paid(res.Contract.DustPolicy)
+		if err != nil {
+			return err
+		}
+
+		e := audit.Entry{
+			Kind:         audit.KindTransferFee,
+			ContractID:   res.Contract.ID,
+			AssetID:      fee.AssetID,
+			RequestTxID:  requestHash.String(),
+			ResponseTxID: responseHash.String(),
+			Splits:       fee.Splits,
+			Timestamp:    time.Now().UnixNano(),
+		}
+
+		for _, r := range s.Recorders {
+			if err := r.Record(ctx, e); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 // isIncomingMessageType returns true is the message type is one that we
 // want to process, false otherwise.
 func (s RequestService) isIncomingMessageType(msg protocol.OpReturnMessage) bool {
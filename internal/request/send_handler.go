@@ -2,11 +2,12 @@ package request
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"time"
 
 	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/protocol"
 	"github.com/tokenized/smart-contract/pkg/txbuilder"
 )
@@ -26,7 +27,7 @@ func (h sendHandler) handle(ctx context.Context,
 
 	issue, ok := r.m.(*protocol.Send)
 	if !ok {
-		return nil, errors.New("Not *protocol.Issue")
+		return nil, errs.New(errs.Invalid, "Not *protocol.Issue")
 	}
 
 	// Contract
@@ -69,6 +70,11 @@ func (h sendHandler) handle(ctx context.Context,
 		party2Balance = party2Holding.Balance
 	}
 
+	// Hold the transfer until the receiver accepts it
+	if asset.ReceiverApproval {
+		return h.escrow(r, asset, party1Addr, party2Addr, issue.TokenQty)
+	}
+
 	// Modify balances
 	party1Balance -= issue.TokenQty
 	party2Balance += issue.TokenQty
@@ -87,10 +93,52 @@ func (h sendHandler) handle(ctx context.Context,
 		return nil, err
 	}
 
+	// Transfer fee of the asset, split between its payees
+	feeOutputs, fee, err := newTransferFee(asset)
+	if err != nil {
+		return nil, err
+	}
+
 	resp := contractResponse{
 		Contract: c,
 		Message:  &settlement,
+		outs:     append(outputs, feeOutputs...),
+	}
+
+	if fee != nil {
+		resp.fees = append(resp.fees, *fee)
+	}
+
+	return &resp, nil
+}
+
+// escrow returns a response holding the transfer in escrow, notifying the
+// receiver that the transfer is awaiting their acceptance.
+func (h sendHandler) escrow(r contractRequest,
+	asset contract.Asset,
+	party1Addr string,
+	party2Addr string,
+	qty uint64) (*contractResponse, error) {
+
+	pending := contract.NewPendingTransfer(r.hash.String(), asset,
+		party1Addr, party2Addr, qty)
+
+	// Message <- Send
+	message := protocol.NewMessage()
+	message.Timestamp = uint64(time.Now().Unix())
+	message.MessageType = []byte(contract.MessageTypeTransferPending)
+	message.Message = []byte(pending.ID)
+
+	outputs, err := h.buildOutputs(r)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := contractResponse{
+		Contract: r.contract,
+		Message:  &message,
 		outs:     outputs,
+		escrow:   &pending,
 	}
 
 	return &resp, nil
@@ -109,15 +157,15 @@ func (h sendHandler) buildOutputs(r contractRequest) ([]txbuilder.TxOutput, erro
 	outs := []txbuilder.TxOutput{
 		txbuilder.TxOutput{
 			Address: party1Addr,
-			Value:   dustLimit, // any change will be added to this output value
+			Value:   r.contract.DustPolicy.Minimum(), // any change will be added to this output value
 		},
 		txbuilder.TxOutput{
 			Address: party2Addr,
-			Value:   dustLimit,
+			Value:   r.contract.DustPolicy.Minimum(),
 		},
 		txbuilder.TxOutput{
 			Address: contractAddress,
-			Value:   dustLimit,
+			Value:   r.contract.DustPolicy.Minimum(),
 		},
 	}
 
@@ -98,3 +98,184 @@ func TestSendHandler_handle(t *testing.T) {
 		t.Fatalf("got\n%+v\nwant\n%+v", settlement, &wantSettlement)
 	}
 }
+
+func TestSendHandler_handle_receiverApproval(t *testing.T) {
+	ctx := newSilentContext()
+
+	hash := newHash("82b1576993052733ca685419ca4be32cde1e6f7c772e839cd76cd931537222b8")
+
+	contractAddr := "1DNTgNSWtTestKs7j1DwaoxmSc4q9sEUsb"
+
+	issuerAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	receiverAddr := "123h2RL1DT4AuYyJUseGxcXSAe5imPSeLV"
+
+	asset := contract.Asset{
+		ID:               "foo",
+		Qty:              20,
+		ReceiverApproval: true,
+		Holdings: map[string]contract.Holding{
+			issuerAddr: contract.Holding{
+				Address: issuerAddr,
+				Balance: 20,
+			},
+		},
+	}
+
+	c := contract.Contract{
+		ID:            contractAddr,
+		IssuerAddress: issuerAddr,
+		Assets: map[string]contract.Asset{
+			asset.ID: asset,
+		},
+	}
+
+	issue := protocol.NewSend()
+	issue.AssetID = []byte(asset.ID)
+	issue.AssetType = []byte("RRE")
+	issue.TokenQty = 1
+
+	req := contractRequest{
+		hash:     hash,
+		contract: c,
+		senders: []btcutil.Address{
+			decodeAddress(issuerAddr),
+		},
+		receivers: []txbuilder.TxOutput{
+			txbuilder.TxOutput{},
+			txbuilder.TxOutput{
+				Address: decodeAddress(receiverAddr),
+			},
+		},
+		m: &issue,
+	}
+
+	config := newTestConfig()
+
+	h := newSendHandler(config.Fee)
+	resp, err := h.handle(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the receiver is notified instead of the transfer settling
+	message, ok := resp.Message.(*protocol.Message)
+	if !ok {
+		t.Fatalf("Could not assert as *Message : %#+v\n", resp.Message)
+	}
+
+	if got, want := string(message.MessageType), contract.MessageTypeTransferPending; got != want {
+		t.Errorf("got message type %v, want %v", got, want)
+	}
+
+	if resp.escrow == nil {
+		t.Fatal("Transfer not held in escrow")
+	}
+
+	want := contract.PendingTransfer{
+		ID:        hash.String(),
+		AssetID:   asset.ID,
+		Sender:    issuerAddr,
+		Receiver:  receiverAddr,
+		TokenQty:  1,
+		CreatedAt: resp.escrow.CreatedAt,
+		ExpiresAt: resp.escrow.ExpiresAt,
+	}
+
+	if !reflect.DeepEqual(*resp.escrow, want) {
+		t.Fatalf("got\n%+v\nwant\n%+v", *resp.escrow, want)
+	}
+
+	if string(message.Message) != want.ID {
+		t.Errorf("got message %s, want %v", message.Message, want.ID)
+	}
+}
+
+func TestSendHandler_handle_transferFee(t *testing.T) {
+	ctx := newSilentContext()
+
+	hash := newHash("82b1576993052733ca685419ca4be32cde1e6f7c772e839cd76cd931537222b8")
+
+	contractAddr := "1DNTgNSWtTestKs7j1DwaoxmSc4q9sEUsb"
+
+	issuerAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	receiverAddr := "123h2RL1DT4AuYyJUseGxcXSAe5imPSeLV"
+	platformAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+
+	asset := contract.Asset{
+		ID:  "foo",
+		Qty: 20,
+		TransferFee: &contract.TransferFee{
+			Value: 2000,
+			Payees: []contract.FeePayee{
+				{Role: contract.FeeRoleIssuer, Address: issuerAddr, Percent: 75},
+				{Role: contract.FeeRolePlatform, Address: platformAddr, Percent: 25},
+			},
+		},
+		Holdings: map[string]contract.Holding{
+			issuerAddr: contract.Holding{
+				Address: issuerAddr,
+				Balance: 20,
+			},
+		},
+	}
+
+	c := contract.Contract{
+		ID:            contractAddr,
+		IssuerAddress: issuerAddr,
+		Assets: map[string]contract.Asset{
+			asset.ID: asset,
+		},
+	}
+
+	issue := protocol.NewSend()
+	issue.AssetID = []byte(asset.ID)
+	issue.AssetType = []byte("RRE")
+	issue.TokenQty = 1
+
+	req := contractRequest{
+		hash:     hash,
+		contract: c,
+		senders: []btcutil.Address{
+			decodeAddress(issuerAddr),
+		},
+		receivers: []txbuilder.TxOutput{
+			txbuilder.TxOutput{},
+			txbuilder.TxOutput{
+				Address: decodeAddress(receiverAddr),
+			},
+		},
+		m: &issue,
+	}
+
+	h := newSendHandler(newTestConfig().Fee)
+	resp, err := h.handle(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the fee outputs follow the outputs of the settlement
+	outs := resp.outs[len(resp.outs)-2:]
+
+	wantOuts := []txbuilder.TxOutput{
+		{Address: decodeAddress(issuerAddr), Value: 1500},
+		{Address: decodeAddress(platformAddr), Value: 500},
+	}
+
+	if !reflect.DeepEqual(outs, wantOuts) {
+		t.Errorf("got\n%#+v\nwant\n%#+v", outs, wantOuts)
+	}
+
+	wantFees := []transferFee{
+		{
+			AssetID: asset.ID,
+			Splits: []contract.FeeSplit{
+				{Role: contract.FeeRoleIssuer, Address: issuerAddr, Value: 1500},
+				{Role: contract.FeeRolePlatform, Address: platformAddr, Value: 500},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(resp.fees, wantFees) {
+		t.Errorf("got\n%#+v\nwant\n%#+v", resp.fees, wantFees)
+	}
+}
@@ -0,0 +1,78 @@
+package request
+
+import (
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+)
+
+// transferFee is the transfer fee of an asset paid by a response, as split
+// between the payees of the asset.
+type transferFee struct {
+	AssetID string
+	Splits  []contract.FeeSplit
+}
+
+// newTransferFee returns the outputs paying the transfer fee of the asset
+// to each of its payees, and the fee paid.
+//
+// Returns nil's if the asset has no transfer fee.
+func newTransferFee(asset contract.Asset) ([]txbuilder.TxOutput, *transferFee, error) {
+	if asset.TransferFee == nil || asset.TransferFee.Value == 0 {
+		return nil, nil, nil
+	}
+
+	splits, err := asset.TransferFee.Split()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outs := []txbuilder.TxOutput{}
+
+	for _, split := range splits {
+		address, err := btcutil.DecodeAddress(split.Address, &chaincfg.MainNetParams)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		outs = append(outs, txbuilder.TxOutput{
+			Address: address,
+			Value:   split.Value,
+		})
+	}
+
+	fee := transferFee{
+		AssetID: asset.ID,
+		Splits:  splits,
+	}
+
+	return outs, &fee, nil
+}
+
+// paid returns the fee as paid once the dust policy is applied to the
+// outputs of the splits. A split below the minimum output value is paid
+// the rounded up value, or dropped if the policy absorbs it.
+func (f transferFee) paid(p contract.DustPolicy) (transferFee, error) {
+	paid := transferFee{
+		AssetID: f.AssetID,
+		Splits:  []contract.FeeSplit{},
+	}
+
+	for _, split := range f.Splits {
+		value, err := p.ApplyValue(split.Value)
+		if err != nil {
+			return transferFee{}, err
+		}
+
+		if value == 0 {
+			continue
+		}
+
+		split.Value = value
+		paid.Splits = append(paid.Splits, split)
+	}
+
+	return paid, nil
+}
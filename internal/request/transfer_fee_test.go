@@ -0,0 +1,80 @@
+package request
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+)
+
+func TestTransferFee_paid(t *testing.T) {
+	issuerAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	platformAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+
+	fee := transferFee{
+		AssetID: "foo",
+		Splits: []contract.FeeSplit{
+			{Role: contract.FeeRoleIssuer, Address: issuerAddr, Value: 1400},
+			{Role: contract.FeeRolePlatform, Address: platformAddr, Value: 600},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		policy contract.DustPolicy
+		want   []uint64
+		err    error
+	}{
+		{
+			name:   "above minimum",
+			policy: contract.DustPolicy{MinimumOutput: 600},
+			want:   []uint64{1400, 600},
+		},
+		{
+			name: "round up",
+			policy: contract.DustPolicy{
+				MinimumOutput: 800,
+				Handling:      contract.DustRoundUp,
+			},
+			want: []uint64{1400, 800},
+		},
+		{
+			name: "absorb",
+			policy: contract.DustPolicy{
+				MinimumOutput: 800,
+				Handling:      contract.DustAbsorb,
+			},
+			want: []uint64{1400},
+		},
+		{
+			name: "reject",
+			policy: contract.DustPolicy{
+				MinimumOutput: 800,
+				Handling:      contract.DustReject,
+			},
+			err: contract.ErrDustOutput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paid, err := fee.paid(tt.policy)
+			if err != tt.err {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			got := []uint64{}
+			for _, split := range paid.Splits {
+				got = append(got, split.Value)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%#+v\nwant\n%#+v", got, tt.want)
+			}
+		})
+	}
+}
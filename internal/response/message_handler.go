@@ -0,0 +1,36 @@
+package response
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tokenized/smart-contract/internal/app/inspector"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+type messageHandler struct{}
+
+func newMessageHandler() messageHandler {
+	return messageHandler{}
+}
+
+// process holds a transfer in escrow when the Message notifies the receiver
+// that it awaits their acceptance. Other Messages have nothing to apply.
+func (h messageHandler) process(ctx context.Context,
+	itx *inspector.Transaction, c *contract.Contract) error {
+
+	m := itx.MsgProto.(*protocol.Message)
+
+	if string(m.MessageType) != contract.MessageTypeTransferPending {
+		return nil
+	}
+
+	if itx.Escrow == nil || itx.Escrow.ID != string(m.Message) {
+		return fmt.Errorf("message : No transfer to hold : contract=%s id=%s", c.ID, m.Message)
+	}
+
+	c.Escrow(*itx.Escrow)
+
+	return nil
+}
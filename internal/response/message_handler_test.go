@@ -0,0 +1,155 @@
+package response
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/inspector"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+)
+
+const (
+	issuerAddr   = "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	receiverAddr = "123h2RL1DT4AuYyJUseGxcXSAe5imPSeLV"
+)
+
+// newApprovalContract returns a contract with an asset requiring receiver
+// approval, held by the issuer.
+func newApprovalContract() (*contract.Contract, contract.Asset) {
+	asset := contract.Asset{
+		ID:               "foo",
+		Type:             "RRE",
+		Qty:              20,
+		ReceiverApproval: true,
+		Holdings: map[string]contract.Holding{
+			issuerAddr: contract.NewHolding(issuerAddr, 20),
+		},
+	}
+
+	c := &contract.Contract{
+		ID: "1DNTgNSWtTestKs7j1DwaoxmSc4q9sEUsb",
+		Assets: map[string]contract.Asset{
+			asset.ID: asset,
+		},
+	}
+
+	return c, asset
+}
+
+func TestMessageHandler_process(t *testing.T) {
+	ctx := context.Background()
+
+	_, asset := newApprovalContract()
+
+	pending := contract.NewPendingTransfer("1", asset, issuerAddr,
+		receiverAddr, 5)
+
+	tests := []struct {
+		name        string
+		messageType string
+		message     string
+		escrow      *contract.PendingTransfer
+		wantErr     bool
+		wantBalance uint64
+	}{
+		{
+			name:        "other message",
+			messageType: "XX",
+			message:     "hello",
+			wantBalance: 20,
+		},
+		{
+			name:        "pending without a transfer",
+			messageType: contract.MessageTypeTransferPending,
+			message:     pending.ID,
+			wantErr:     true,
+			wantBalance: 20,
+		},
+		{
+			name:        "pending",
+			messageType: contract.MessageTypeTransferPending,
+			message:     pending.ID,
+			escrow:      &pending,
+			wantBalance: 15,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newApprovalContract()
+
+			m := protocol.NewMessage()
+			m.MessageType = []byte(tt.messageType)
+			m.Message = []byte(tt.message)
+
+			itx := &inspector.Transaction{
+				MsgProto: &m,
+				Escrow:   tt.escrow,
+			}
+
+			err := newMessageHandler().process(ctx, itx, c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+
+			if got := c.Assets[asset.ID].Holdings[issuerAddr].Balance; got != tt.wantBalance {
+				t.Fatalf("got sender balance %v, want %v", got, tt.wantBalance)
+			}
+
+			if _, held := c.PendingTransfers[pending.ID]; held != (tt.escrow != nil) {
+				t.Fatalf("got held %v, want %v", held, tt.escrow != nil)
+			}
+		})
+	}
+}
+
+func TestSettlementHandler_process_release(t *testing.T) {
+	ctx := context.Background()
+
+	c, asset := newApprovalContract()
+
+	pending := contract.NewPendingTransfer("1", asset, issuerAddr,
+		receiverAddr, 5)
+	c.Escrow(pending)
+
+	settlement := protocol.NewSettlement()
+	settlement.AssetType = []byte(asset.Type)
+	settlement.AssetID = []byte(asset.ID)
+	settlement.Party1TokenQty = 15
+	settlement.Party2TokenQty = 5
+
+	itx := &inspector.Transaction{
+		Outputs: []txbuilder.TxOutput{
+			txbuilder.TxOutput{Address: decodeAddress(t, issuerAddr)},
+			txbuilder.TxOutput{Address: decodeAddress(t, receiverAddr)},
+		},
+		MsgProto: &settlement,
+		Release:  pending.ID,
+	}
+
+	if err := newSettlementHandler().process(ctx, itx, c); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := c.PendingTransfers[pending.ID]; ok {
+		t.Fatal("transfer still held after settlement")
+	}
+
+	if got := c.Assets[asset.ID].Holdings[receiverAddr].Balance; got != 5 {
+		t.Fatalf("got receiver balance %v, want 5", got)
+	}
+}
+
+func decodeAddress(t *testing.T, address string) btcutil.Address {
+	a, err := btcutil.DecodeAddress(address, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a
+}
@@ -6,14 +6,17 @@ package response
  * What is my purpose?
  * - You accept a Response action
  * - You save it to the Contract state
+ * - You tell indexers about the new state
  */
 
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/tokenized/smart-contract/internal/app/config"
 	"github.com/tokenized/smart-contract/internal/app/inspector"
+	"github.com/tokenized/smart-contract/internal/app/logger"
 	"github.com/tokenized/smart-contract/internal/app/state"
 	"github.com/tokenized/smart-contract/internal/app/state/contract"
 	"github.com/tokenized/smart-contract/pkg/protocol"
@@ -32,6 +35,7 @@ var (
 		protocol.CodeConfiscation:      true,
 		protocol.CodeReconciliation:    true,
 		protocol.CodeRejection:         true,
+		protocol.CodeMessage:           true,
 	}
 )
 
@@ -47,6 +51,7 @@ func newResponseHandlers(state state.StateInterface,
 		protocol.CodeConfiscation:      newConfiscationHandler(),
 		protocol.CodeReconciliation:    newReconciliationHandler(),
 		protocol.CodeRejection:         newRejectionHandler(),
+		protocol.CodeMessage:           newMessageHandler(),
 		// protocol.CodeVote:              newVoteHandler(),
 		// protocol.CodeBallotCounted:     newBallotCountedHandler(),
 		// protocol.CodeResult:            newResultHandler(),
@@ -56,14 +61,17 @@ func newResponseHandlers(state state.StateInterface,
 type ResponseService struct {
 	Config   config.Config
 	State    state.StateInterface
+	Indexers []state.Indexer
 	handlers map[string]responseHandlerInterface
 }
 
 func NewResponseService(config config.Config,
-	state state.StateInterface) ResponseService {
+	state state.StateInterface,
+	indexers ...state.Indexer) ResponseService {
 	return ResponseService{
 		State:    state,
 		Config:   config,
+		Indexers: indexers,
 		handlers: newResponseHandlers(state, config),
 	}
 }
@@ -89,5 +97,64 @@ func (s ResponseService) Process(ctx context.Context,
 		return err
 	}
 
+	s.index(ctx, state.Event{
+		TxID:       itx.MsgTx.TxHash().String(),
+		Action:     itx.MsgProto.Type(),
+		ContractID: contract.ID,
+		Message:    itx.MsgProto,
+		Contract:   *contract,
+	})
+
 	return nil
 }
+
+// Update implements the state.Updater interface.
+//
+// It does not serialize the change with requests, so the caller must hold
+// the lock of the contract that requests are processed under.
+func (s ResponseService) Update(ctx context.Context,
+	contractID string,
+	action string,
+	f func(*contract.Contract) error) (*contract.Contract, error) {
+
+	c, err := s.State.Read(ctx, contractID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f(c); err != nil {
+		return nil, err
+	}
+
+	if err := s.State.Write(ctx, *c); err != nil {
+		return nil, err
+	}
+
+	s.index(ctx, state.Event{
+		Action:     action,
+		ContractID: c.ID,
+		Contract:   *c,
+	})
+
+	return c, nil
+}
+
+// index passes the written state to each of the Indexers.
+//
+// The state has already been written, so a failing Indexer is logged rather
+// than failing the response.
+func (s ResponseService) index(ctx context.Context, e state.Event) {
+	if len(s.Indexers) == 0 {
+		return
+	}
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	e.Timestamp = time.Now().UnixNano()
+
+	for _, indexer := range s.Indexers {
+		if err := indexer.Index(ctx, e); err != nil {
+			log.Errorf("Failed to index %v of %v : %v", e.Action, e.ContractID, err)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/internal/app/inspector"
+	"github.com/tokenized/smart-contract/internal/app/state"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+var errWrite = errors.New("write failed")
+
+// memoryState holds the Contract's written, failing writes if failWrite is
+// set.
+type memoryState struct {
+	contracts map[string]contract.Contract
+	failWrite bool
+}
+
+func (m *memoryState) Write(ctx context.Context, c contract.Contract) error {
+	if m.failWrite {
+		return errWrite
+	}
+
+	m.contracts[c.ID] = c
+	return nil
+}
+
+func (m *memoryState) Read(ctx context.Context,
+	id string) (*contract.Contract, error) {
+
+	c, ok := m.contracts[id]
+	if !ok {
+		return nil, state.ErrContractNotFound
+	}
+
+	return &c, nil
+}
+
+// recordingIndexer records the Event's indexed, failing each if err is set.
+type recordingIndexer struct {
+	events []state.Event
+	err    error
+}
+
+func (i *recordingIndexer) Index(ctx context.Context, e state.Event) error {
+	i.events = append(i.events, e)
+	return i.err
+}
+
+func TestResponseService_Process_index(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		messageType string
+		failWrite   bool
+		wantErr     bool
+	}{
+		{
+			name:        "committed",
+			messageType: "XX",
+		},
+		{
+			name:        "handler failed",
+			messageType: contract.MessageTypeTransferPending,
+			wantErr:     true,
+		},
+		{
+			name:        "write failed",
+			messageType: "XX",
+			failWrite:   true,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &memoryState{
+				contracts: map[string]contract.Contract{},
+				failWrite: tt.failWrite,
+			}
+
+			// the first indexer failing doesn't stop the second
+			failing := &recordingIndexer{err: errors.New("index failed")}
+			indexer := &recordingIndexer{}
+
+			s := NewResponseService(config.Config{}, st, failing, indexer)
+
+			c, _ := newApprovalContract()
+
+			m := protocol.NewMessage()
+			m.MessageType = []byte(tt.messageType)
+
+			tx := wire.NewMsgTx(1)
+
+			itx := &inspector.Transaction{
+				MsgTx:    tx,
+				MsgProto: &m,
+			}
+
+			err := s.Process(ctx, itx, c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if len(failing.events) != 0 || len(indexer.events) != 0 {
+					t.Fatalf("got %v and %v indexed, want none",
+						len(failing.events), len(indexer.events))
+				}
+
+				return
+			}
+
+			if len(failing.events) != 1 || len(indexer.events) != 1 {
+				t.Fatalf("got %v and %v indexed, want 1", len(failing.events),
+					len(indexer.events))
+			}
+
+			e := indexer.events[0]
+			if e.TxID != tx.TxHash().String() || e.Action != protocol.CodeMessage ||
+				e.ContractID != c.ID || e.Contract.ID != c.ID || e.Timestamp == 0 {
+
+				t.Fatalf("got event %#+v", e)
+			}
+
+			if _, ok := st.contracts[c.ID]; !ok {
+				t.Fatal("contract not written")
+			}
+		})
+	}
+}
+
+func TestResponseService_Update_index(t *testing.T) {
+	ctx := context.Background()
+
+	c, _ := newApprovalContract()
+
+	errUpdate := errors.New("update failed")
+
+	tests := []struct {
+		name      string
+		update    error
+		failWrite bool
+		wantErr   error
+		indexed   int
+	}{
+		{
+			name:    "committed",
+			indexed: 1,
+		},
+		{
+			name:    "update failed",
+			update:  errUpdate,
+			wantErr: errUpdate,
+		},
+		{
+			name:      "write failed",
+			failWrite: true,
+			wantErr:   errWrite,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &memoryState{
+				contracts: map[string]contract.Contract{c.ID: *c},
+				failWrite: tt.failWrite,
+			}
+
+			indexer := &recordingIndexer{}
+
+			s := NewResponseService(config.Config{}, st, indexer)
+
+			_, err := s.Update(ctx, c.ID, "admin/test",
+				func(c *contract.Contract) error {
+					c.Revision++
+					return tt.update
+				})
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if len(indexer.events) != tt.indexed {
+				t.Fatalf("got %v indexed, want %v", len(indexer.events), tt.indexed)
+			}
+
+			if tt.indexed == 0 {
+				return
+			}
+
+			e := indexer.events[0]
+			if e.Action != "admin/test" || e.TxID != "" || e.Contract.Revision != 1 {
+				t.Fatalf("got event %#+v", e)
+			}
+		})
+	}
+}
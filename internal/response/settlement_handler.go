@@ -58,5 +58,10 @@ func (h settlementHandler) process(ctx context.Context,
 	// Put the asset back  on the contract
 	c.Assets[assetKey] = asset
 
+	// Settle a transfer accepted by its receiver
+	if itx.Release != "" {
+		c.Release(itx.Release)
+	}
+
 	return nil
 }
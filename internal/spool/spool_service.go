@@ -0,0 +1,249 @@
+package spool
+
+/**
+ * Spool Service
+ *
+ * What is my purpose?
+ * - You notice when the contract storage is unavailable
+ * - You keep requests on local disk until storage recovers
+ * - You replay the kept requests, in order, when it does
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/internal/app/network"
+	"github.com/tokenized/smart-contract/pkg/storage"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+const (
+	// SpoolPrefix is the storage path that SpooledTX's are written to.
+	SpoolPrefix = "spool"
+
+	// probeKey is written to the contract storage to check it is available.
+	probeKey = "spool/probe"
+)
+
+var (
+	// ErrSpoolFull is returned when a request cannot be spooled as the
+	// spool holds as many requests as its limit.
+	ErrSpoolFull = errors.New("Spool is full")
+
+	// ErrSpoolDisabled is returned when a request cannot be spooled as no
+	// spool has been configured.
+	ErrSpoolDisabled = errors.New("Spool is disabled")
+)
+
+type SpoolService struct {
+	Config  config.Spool
+	Backend storage.ReadWriter
+	Storage storage.Storage
+
+	mu       *sync.Mutex
+	degraded *bool
+}
+
+// NewSpoolService returns a new SpoolService watching the Backend, and
+// spooling requests to the Storage while it is unavailable.
+func NewSpoolService(config config.Spool,
+	backend storage.ReadWriter,
+	store storage.Storage) SpoolService {
+
+	degraded := false
+
+	return SpoolService{
+		Config:   config,
+		Backend:  backend,
+		Storage:  store,
+		mu:       &sync.Mutex{},
+		degraded: &degraded,
+	}
+}
+
+// Enabled returns true if a spool has been configured.
+func (s SpoolService) Enabled() bool {
+	return s.Storage != nil && s.Config.Limit > 0
+}
+
+// Load enters degraded mode if requests were left in the spool, such as by
+// a restart before storage recovered, so they are replayed.
+func (s SpoolService) Load(ctx context.Context) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	spooled, err := s.all(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(spooled) > 0 {
+		s.setDegraded(true)
+	}
+
+	return nil
+}
+
+// Degraded returns true if the contract storage is unavailable.
+func (s SpoolService) Degraded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return *s.degraded
+}
+
+// Probe checks the contract storage is available by writing to it and
+// reading it back. A failure enters degraded mode.
+func (s SpoolService) Probe(ctx context.Context) error {
+	v := []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+
+	err := s.Backend.Write(ctx, probeKey, v, nil)
+	if err == nil {
+		_, err = s.Backend.Read(ctx, probeKey)
+	}
+
+	if err != nil && s.Enabled() {
+		if !s.Degraded() {
+			log := logger.NewLoggerFromContext(ctx).Sugar()
+			log.Warnf("Storage unavailable, spooling requests : %v", err)
+		}
+
+		s.setDegraded(true)
+	}
+
+	return err
+}
+
+// Spool writes a request to the spool to be replayed when storage
+// recovers.
+func (s SpoolService) Spool(ctx context.Context, tx *wire.MsgTx) error {
+	if !s.Enabled() {
+		return ErrSpoolDisabled
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	spooled, err := s.all(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(spooled) >= s.Config.Limit {
+		return ErrSpoolFull
+	}
+
+	st, err := newSpooledTX(tx, time.Now().UnixNano())
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	return s.Storage.Write(ctx, s.buildPath(st), b, nil)
+}
+
+// Recover checks whether storage has recovered while in degraded mode. If
+// it has, degraded mode ends and the spooled requests are handed to the
+// Listener in the order they were received.
+//
+// A request that fails again is spooled again by the Listener.
+func (s SpoolService) Recover(ctx context.Context,
+	l network.Listener) error {
+
+	if !s.Degraded() {
+		return nil
+	}
+
+	if err := s.Probe(ctx); err != nil {
+		return nil
+	}
+
+	// only one caller replays the spool
+	s.mu.Lock()
+	replay := *s.degraded
+	*s.degraded = false
+	s.mu.Unlock()
+
+	if !replay {
+		return nil
+	}
+
+	spooled, err := s.all(ctx)
+	if err != nil {
+		return err
+	}
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+	log.Infof("Storage recovered, replaying %v requests", len(spooled))
+
+	for _, st := range spooled {
+		tx, err := st.MsgTx()
+		if err != nil {
+			return err
+		}
+
+		if err := s.Storage.Remove(ctx, s.buildPath(st)); err != nil {
+			return err
+		}
+
+		if err := l.Handle(ctx, tx); err != nil {
+			log.Errorf("Failed to replay %v : %v", st.TxID, err)
+		}
+	}
+
+	return nil
+}
+
+// all returns the spooled requests in the order they were received.
+func (s SpoolService) all(ctx context.Context) ([]SpooledTX, error) {
+	query := map[string]string{
+		"path": SpoolPrefix,
+	}
+
+	objects, err := s.Storage.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	spooled := []SpooledTX{}
+
+	for _, b := range objects {
+		st := SpooledTX{}
+		if err := json.Unmarshal(b, &st); err != nil {
+			return nil, err
+		}
+
+		spooled = append(spooled, st)
+	}
+
+	sort.Slice(spooled, func(i, j int) bool {
+		return spooled[i].SpooledAt < spooled[j].SpooledAt
+	})
+
+	return spooled, nil
+}
+
+func (s SpoolService) setDegraded(degraded bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	*s.degraded = degraded
+}
+
+func (s SpoolService) buildPath(st SpooledTX) string {
+	return fmt.Sprintf("%v/%020d-%v", SpoolPrefix, st.SpooledAt, st.TxID)
+}
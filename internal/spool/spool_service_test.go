@@ -0,0 +1,194 @@
+package spool
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/pkg/spvnode"
+	"github.com/tokenized/smart-contract/pkg/storage"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+var errOutage = errors.New("Outage")
+
+// backendStorage is contract storage that can be made unavailable.
+type backendStorage struct {
+	down *bool
+	data map[string][]byte
+}
+
+func (b backendStorage) Read(ctx context.Context, key string) ([]byte, error) {
+	if *b.down {
+		return nil, errOutage
+	}
+
+	v, ok := b.data[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	return v, nil
+}
+
+func (b backendStorage) Write(ctx context.Context,
+	key string,
+	body []byte,
+	opts *storage.Options) error {
+
+	if *b.down {
+		return errOutage
+	}
+
+	b.data[key] = body
+	return nil
+}
+
+type testListener struct {
+	handled *[]string
+}
+
+func (l testListener) Handle(ctx context.Context, m wire.Message) error {
+	tx := m.(*wire.MsgTx)
+	*l.handled = append(*l.handled, tx.TxHash().String())
+	return nil
+}
+
+func (l testListener) HandleReorg(ctx context.Context, r spvnode.Reorg) error {
+	return nil
+}
+
+func (l testListener) HandleExpired(ctx context.Context,
+	hashes []chainhash.Hash) error {
+
+	return nil
+}
+
+func (l testListener) HandleDoubleSpend(ctx context.Context,
+	d spvnode.DoubleSpend) error {
+
+	return nil
+}
+
+func newTX(lockTime uint32) *wire.MsgTx {
+	tx := wire.NewMsgTx(1)
+	tx.LockTime = lockTime
+	return tx
+}
+
+func TestSpoolService(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := storage.NewFilesystemStorage(storage.Config{
+		Root:   dir,
+		Bucket: "test",
+	})
+
+	down := false
+	backend := backendStorage{
+		down: &down,
+		data: map[string][]byte{},
+	}
+
+	cfg := config.Spool{
+		Root:  dir,
+		Limit: 2,
+	}
+
+	s := NewSpoolService(cfg, backend, store)
+
+	if err := s.Probe(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Degraded() {
+		t.Fatal("degraded while storage is available")
+	}
+
+	// storage goes down
+	down = true
+
+	if err := s.Probe(ctx); err != errOutage {
+		t.Fatalf("got error %v, want %v", err, errOutage)
+	}
+
+	if !s.Degraded() {
+		t.Fatal("not degraded while storage is unavailable")
+	}
+
+	txs := []*wire.MsgTx{newTX(1), newTX(2), newTX(3)}
+
+	for _, tx := range txs[:2] {
+		if err := s.Spool(ctx, tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.Spool(ctx, txs[2]); err != ErrSpoolFull {
+		t.Fatalf("got error %v, want %v", err, ErrSpoolFull)
+	}
+
+	handled := []string{}
+	l := testListener{handled: &handled}
+
+	// still down, nothing is replayed
+	if err := s.Recover(ctx, l); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(handled) != 0 {
+		t.Fatalf("got %v replayed, want 0", len(handled))
+	}
+
+	// a restart keeps the spool, and degraded mode
+	s = NewSpoolService(cfg, backend, store)
+
+	if err := s.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if !s.Degraded() {
+		t.Fatal("not degraded with a spool to replay")
+	}
+
+	// storage recovers
+	down = false
+
+	if err := s.Recover(ctx, l); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		txs[0].TxHash().String(),
+		txs[1].TxHash().String(),
+	}
+
+	if !reflect.DeepEqual(handled, want) {
+		t.Errorf("got\n%#+v\nwant\n%#+v", handled, want)
+	}
+
+	if s.Degraded() {
+		t.Error("degraded after storage recovered")
+	}
+
+	spooled, err := s.all(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(spooled) != 0 {
+		t.Errorf("got %v spooled after replay, want 0", len(spooled))
+	}
+}
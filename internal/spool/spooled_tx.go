@@ -0,0 +1,45 @@
+package spool
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+// SpooledTX is a request received while storage was unavailable, held to
+// be processed when storage recovers.
+type SpooledTX struct {
+	TxID      string `json:"txid"`
+	Tx        string `json:"tx"`
+	SpooledAt int64  `json:"spooled_at"`
+}
+
+// newSpooledTX returns a SpooledTX holding the TX.
+func newSpooledTX(tx *wire.MsgTx, now int64) (SpooledTX, error) {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return SpooledTX{}, err
+	}
+
+	return SpooledTX{
+		TxID:      tx.TxHash().String(),
+		Tx:        hex.EncodeToString(buf.Bytes()),
+		SpooledAt: now,
+	}, nil
+}
+
+// MsgTx returns the spooled TX.
+func (s SpooledTX) MsgTx() (*wire.MsgTx, error) {
+	b, err := hex.DecodeString(s.Tx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := wire.MsgTx{}
+	if err := tx.Deserialize(bytes.NewReader(b)); err != nil {
+		return nil, err
+	}
+
+	return &tx, nil
+}
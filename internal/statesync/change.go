@@ -0,0 +1,173 @@
+package statesync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+var (
+	ErrBadSignature = errors.New("Page signature is not valid")
+	ErrWrongSigner  = errors.New("Page is not signed by the contract")
+	ErrWrongRequest = errors.New("Page is not for the changes requested")
+	ErrPageOrder    = errors.New("Page changes are not after its cursor")
+)
+
+// Change is a mutation of Contract state, as recorded by the node that made
+// it.
+type Change struct {
+	ContractID string `json:"contract_id"`
+
+	// Sequence is the position of the change among the changes to the
+	// contract, counting from 1.
+	Sequence uint64 `json:"sequence"`
+
+	// Height is the height the chain had reached when the change was made,
+	// or that of the change before it if that is higher, so heights never
+	// go back as the Sequence grows.
+	Height int32 `json:"height"`
+
+	TxID      string `json:"txid"`
+	Action    string `json:"action"`
+	Timestamp int64  `json:"timestamp"`
+
+	// Patch is the JSON merge patch (RFC 7386) of the Contract state made
+	// by the change, from the state after the change before it.
+	Patch json.RawMessage `json:"patch"`
+}
+
+// cursor returns the position of the Change in the order changes are
+// served, oldest first.
+func (c Change) cursor() string {
+	return formatCursor(c.Sequence)
+}
+
+// formatCursor returns the cursor of the Change with the sequence, padded
+// so cursors are ordered as strings.
+func formatCursor(sequence uint64) string {
+	return fmt.Sprintf("%020d", sequence)
+}
+
+// Page is a page of the changes to a Contract since a block height.
+//
+// The page is signed by the contract, so a node can trust changes relayed
+// by another. The signature covers the height and cursor the page was
+// requested with, so a page can't be replayed in answer to another request.
+type Page struct {
+	ContractID string `json:"contract_id"`
+	Since      int32  `json:"since"`
+
+	// Cursor is the cursor the page was requested with. The changes of the
+	// page all come after it.
+	Cursor string `json:"cursor,omitempty"`
+
+	Changes []Change `json:"changes"`
+
+	// Next is the cursor of the next page, or empty if this is the last.
+	Next string `json:"next,omitempty"`
+
+	// PublicKey is the hex encoded key the page is signed with.
+	PublicKey string `json:"public_key"`
+
+	// Signature is the hex encoded DER signature of the hash of the page.
+	Signature string `json:"signature"`
+}
+
+// Sign signs the page with the key.
+func (p *Page) Sign(key *btcec.PrivateKey) error {
+	p.PublicKey = hex.EncodeToString(key.PubKey().SerializeCompressed())
+
+	hash, err := p.hash()
+	if err != nil {
+		return err
+	}
+
+	sig, err := key.Sign(hash)
+	if err != nil {
+		return err
+	}
+
+	p.Signature = hex.EncodeToString(sig.Serialize())
+
+	return nil
+}
+
+// Verify returns nil if the page is signed with its PublicKey, and the
+// signing key is returned.
+func (p Page) Verify() (*btcec.PublicKey, error) {
+	b, err := hex.DecodeString(p.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, err := btcec.ParsePubKey(b, btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+
+	b, err = hex.DecodeString(p.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := btcec.ParseDERSignature(b, btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := p.hash()
+	if err != nil {
+		return nil, err
+	}
+
+	if !sig.Verify(hash, pub) {
+		return nil, ErrBadSignature
+	}
+
+	return pub, nil
+}
+
+// Check returns nil if the page answers a request for the changes to the
+// contract at or after the height, starting after the cursor.
+//
+// Each change must come after the one before it, and the Next cursor must
+// be that of the last change, so following it always moves forward.
+func (p Page) Check(contractID string, since int32, cursor string) error {
+	if p.ContractID != contractID || p.Since != since || p.Cursor != cursor {
+		return ErrWrongRequest
+	}
+
+	last := cursor
+	for _, c := range p.Changes {
+		if c.ContractID != contractID || c.Height < since || c.cursor() <= last {
+			return ErrPageOrder
+		}
+
+		last = c.cursor()
+	}
+
+	if p.Next != "" && (len(p.Changes) == 0 || p.Next != last) {
+		return ErrPageOrder
+	}
+
+	return nil
+}
+
+// hash returns the hash of the page that is signed, which covers every
+// field other than the Signature.
+func (p Page) hash() ([]byte, error) {
+	p.Signature = ""
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(b)
+
+	return hash[:], nil
+}
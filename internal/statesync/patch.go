@@ -0,0 +1,121 @@
+package statesync
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// newMergePatch returns the JSON merge patch (RFC 7386) that changes the
+// document from into the document to. An empty from is an empty document.
+func newMergePatch(from, to []byte) ([]byte, error) {
+	var a interface{}
+	if len(from) != 0 {
+		var err error
+		if a, err = decodeJSON(from); err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := decodeJSON(to)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(diff(a, b))
+}
+
+// applyMergePatch returns the document with the JSON merge patch applied.
+// An empty doc is an empty document.
+func applyMergePatch(doc, patch []byte) ([]byte, error) {
+	var d interface{}
+	if len(doc) != 0 {
+		var err error
+		if d, err = decodeJSON(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	p, err := decodeJSON(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(merge(d, p))
+}
+
+// diff returns the patch that changes the value from into the value to.
+// Objects are patched member by member, and any other value is replaced.
+func diff(from, to interface{}) interface{} {
+	fromObj, ok := from.(map[string]interface{})
+	if !ok {
+		return to
+	}
+
+	toObj, ok := to.(map[string]interface{})
+	if !ok {
+		return to
+	}
+
+	patch := map[string]interface{}{}
+
+	for k, v := range toObj {
+		old, ok := fromObj[k]
+		if !ok {
+			patch[k] = v
+			continue
+		}
+
+		if !reflect.DeepEqual(old, v) {
+			patch[k] = diff(old, v)
+		}
+	}
+
+	// a null member removes the member
+	for k := range fromObj {
+		if _, ok := toObj[k]; !ok {
+			patch[k] = nil
+		}
+	}
+
+	return patch
+}
+
+// merge returns the target with the patch applied, as described by
+// RFC 7386.
+func merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+
+		t[k] = merge(t[k], v)
+	}
+
+	return t
+}
+
+// decodeJSON decodes the JSON, keeping numbers as they were written so
+// balances and timestamps too large for a float64 are not rounded.
+func decodeJSON(b []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
@@ -0,0 +1,205 @@
+package statesync
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/state"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/internal/operation"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+)
+
+// pageTimeout is how long the SyncService has to serve a page.
+const pageTimeout = 30 * time.Second
+
+// SyncClient fetches the changes to a Contract from the SyncService of
+// another node, verifying the signature of each page.
+type SyncClient struct {
+	// URL is the base URL the SyncService is served at.
+	URL string
+
+	// PublicKey is the key pages must be signed with. If it is nil, pages
+	// must be signed with the key of the contract address.
+	PublicKey *btcec.PublicKey
+
+	Client *http.Client
+
+	// Progress, if set, is told about each page fetched, by the TxID of its
+	// last change.
+	Progress *operation.Tracker
+}
+
+// NewSyncClient returns a new SyncClient for the SyncService at the URL.
+func NewSyncClient(url string) SyncClient {
+	return SyncClient{
+		URL:    url,
+		Client: &http.Client{Timeout: pageTimeout},
+	}
+}
+
+// Changes returns the changes to the contract at or after the height,
+// oldest first.
+func (c SyncClient) Changes(ctx context.Context,
+	contractID string,
+	since int32) ([]Change, error) {
+
+	changes := []Change{}
+	cursor := ""
+
+	for {
+		p, err := c.page(ctx, contractID, since, cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, p.Changes...)
+
+		if c.Progress != nil && len(p.Changes) > 0 {
+			last := p.Changes[len(p.Changes)-1]
+			if err := c.Progress.Step(ctx, last.TxID); err != nil {
+				return nil, err
+			}
+		}
+
+		if p.Next == "" {
+			return changes, nil
+		}
+
+		cursor = p.Next
+	}
+}
+
+// CatchUp applies the changes to the contract at or after the height to
+// its state, and writes the result. It returns the number of changes
+// fetched.
+func (c SyncClient) CatchUp(ctx context.Context,
+	s state.StateInterface,
+	contractID string,
+	since int32) (int, error) {
+
+	changes, err := c.Changes(ctx, contractID, since)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(changes) == 0 {
+		return 0, nil
+	}
+
+	// each change patches the state before it, so the changes are applied
+	// to the state this node has. A patch sets each member it changes, so
+	// the changes at the height this node stopped at can be applied again.
+	var b []byte
+
+	current, err := s.Read(ctx, contractID)
+	if err != nil {
+		if err != state.ErrContractNotFound {
+			return 0, err
+		}
+	} else if b, err = json.Marshal(current); err != nil {
+		return 0, err
+	}
+
+	for _, change := range changes {
+		if b, err = applyMergePatch(b, change.Patch); err != nil {
+			return 0, err
+		}
+	}
+
+	updated := contract.Contract{}
+	if err := json.Unmarshal(b, &updated); err != nil {
+		return 0, err
+	}
+
+	if err := s.Write(ctx, updated); err != nil {
+		return 0, err
+	}
+
+	return len(changes), nil
+}
+
+// page fetches and verifies a page of changes, rejecting a page that is
+// not the answer to the request.
+func (c SyncClient) page(ctx context.Context,
+	contractID string,
+	since int32,
+	cursor string) (*Page, error) {
+
+	values := url.Values{}
+	values.Set("since", fmt.Sprintf("%v", since))
+
+	if cursor != "" {
+		values.Set("cursor", cursor)
+	}
+
+	u := fmt.Sprintf("%v/contracts/%v/changes?%v",
+		c.URL, contractID, values.Encode())
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Fetching changes failed : %v", res.Status)
+	}
+
+	p := Page{}
+	if err := json.NewDecoder(res.Body).Decode(&p); err != nil {
+		return nil, err
+	}
+
+	if err := c.verify(p); err != nil {
+		return nil, err
+	}
+
+	if err := p.Check(contractID, since, cursor); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// verify returns nil if the page is signed by the expected key.
+func (c SyncClient) verify(p Page) error {
+	pub, err := p.Verify()
+	if err != nil {
+		return err
+	}
+
+	if c.PublicKey != nil {
+		if !c.PublicKey.IsEqual(pub) {
+			return ErrWrongSigner
+		}
+
+		return nil
+	}
+
+	h := hex.EncodeToString(pub.SerializeCompressed())
+
+	address, err := btcutil.DecodeAddress(h, &chaincfg.MainNetParams)
+	if err != nil {
+		return err
+	}
+
+	if address.EncodeAddress() != p.ContractID {
+		return ErrWrongSigner
+	}
+
+	return nil
+}
@@ -0,0 +1,343 @@
+package statesync
+
+/**
+ * State Sync Service
+ *
+ * What is my purpose?
+ * - You record each change to the state of a contract
+ * - You let another node fetch the changes since a block height
+ * - You sign what you serve, so it can be trusted
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tokenized/smart-contract/internal/activation"
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/internal/app/state"
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/storage"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+const (
+	// SyncPrefix is the storage path that Change's are written to.
+	SyncPrefix = "changes"
+
+	// headKey is the name of the head of the changes to a contract.
+	headKey = "head"
+
+	// defaultLimit is the number of changes in a page if no limit is given.
+	defaultLimit = 100
+
+	// maxLimit is the largest number of changes in a page.
+	maxLimit = 1000
+)
+
+var ErrInvalidCursor = errs.New(errs.Invalid, "Invalid cursor")
+
+// head is the last Change recorded for a contract, with the Contract state
+// after it that the next Change is a patch of.
+type head struct {
+	Sequence uint64          `json:"sequence"`
+	Height   int32           `json:"height"`
+	Contract json.RawMessage `json:"contract,omitempty"`
+}
+
+// SyncService records the changes to Contract state, and serves them to
+// other nodes over HTTP. It is registered as a state.Indexer.
+//
+// Each change is stored under its sequence number as a patch of the state
+// before it, so a page is read by key without reading the changes before
+// it.
+//
+// The changes to a contract are fetched, a page at a time, with the
+// endpoint
+//
+//	GET /contracts/{contract}/changes?since={height}&cursor={cursor}&limit={n}
+type SyncService struct {
+	Storage    storage.ReadWriter
+	Activation activation.ActivationService
+	Key        *btcec.PrivateKey
+
+	mu *sync.Mutex
+}
+
+// NewSyncService returns a new SyncService, signing pages with the key.
+//
+// The height of each change is read from the chain point of the
+// ActivationService, which must be the one the block handler connects
+// blocks to.
+func NewSyncService(store storage.ReadWriter,
+	activation activation.ActivationService,
+	key *btcec.PrivateKey) SyncService {
+
+	return SyncService{
+		Storage:    store,
+		Activation: activation,
+		Key:        key,
+		mu:         &sync.Mutex{},
+	}
+}
+
+// Index implements the state.Indexer interface, recording the Event as a
+// Change.
+func (s SyncService) Index(ctx context.Context, e state.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, err := s.readHead(ctx, e.ContractID)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(e.Contract)
+	if err != nil {
+		return err
+	}
+
+	patch, err := newMergePatch(h.Contract, b)
+	if err != nil {
+		return err
+	}
+
+	c := Change{
+		ContractID: e.ContractID,
+		Sequence:   h.Sequence + 1,
+		Height:     s.Activation.ChainPoint().Height,
+		TxID:       e.TxID,
+		Action:     e.Action,
+		Timestamp:  e.Timestamp,
+		Patch:      patch,
+	}
+
+	// the chain point goes back in a reorg, but the changes must stay in
+	// height order to be searched by height
+	if c.Height < h.Height {
+		c.Height = h.Height
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Storage.Write(ctx, s.buildPath(c.ContractID, c.cursor()), data, nil); err != nil {
+		return err
+	}
+
+	h = head{
+		Sequence: c.Sequence,
+		Height:   c.Height,
+		Contract: b,
+	}
+
+	data, err = json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	return s.Storage.Write(ctx, s.buildPath(c.ContractID, headKey), data, nil)
+}
+
+// Changes returns a signed Page of the changes to the contract at or after
+// the height, starting after the cursor.
+func (s SyncService) Changes(ctx context.Context,
+	contractID string,
+	since int32,
+	cursor string,
+	limit int) (Page, error) {
+
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	first := uint64(1)
+	if cursor != "" {
+		sequence, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return Page{}, ErrInvalidCursor
+		}
+
+		first = sequence + 1
+	}
+
+	h, err := s.readHead(ctx, contractID)
+	if err != nil {
+		return Page{}, err
+	}
+
+	start, err := s.search(ctx, contractID, first, h.Sequence+1, since)
+	if err != nil {
+		return Page{}, err
+	}
+
+	changes := []Change{}
+
+	for sequence := start; sequence <= h.Sequence && len(changes) < limit; sequence++ {
+		c, err := s.read(ctx, contractID, sequence)
+		if err != nil {
+			return Page{}, err
+		}
+
+		changes = append(changes, *c)
+	}
+
+	p := Page{
+		ContractID: contractID,
+		Since:      since,
+		Cursor:     cursor,
+		Changes:    changes,
+	}
+
+	if len(changes) > 0 && changes[len(changes)-1].Sequence < h.Sequence {
+		p.Next = changes[len(changes)-1].cursor()
+	}
+
+	if err := p.Sign(s.Key); err != nil {
+		return Page{}, err
+	}
+
+	return p, nil
+}
+
+// search returns the first sequence, from first up to but not including
+// end, of a change at or after the height, or end if there is none.
+//
+// Heights never go back as the sequence grows, so the changes are searched
+// by halves.
+func (s SyncService) search(ctx context.Context,
+	contractID string,
+	first uint64,
+	end uint64,
+	height int32) (uint64, error) {
+
+	for first < end {
+		mid := first + (end-first)/2
+
+		c, err := s.read(ctx, contractID, mid)
+		if err != nil {
+			return 0, err
+		}
+
+		if c.Height >= height {
+			end = mid
+		} else {
+			first = mid + 1
+		}
+	}
+
+	return first, nil
+}
+
+// read returns the change to the contract with the sequence.
+func (s SyncService) read(ctx context.Context,
+	contractID string,
+	sequence uint64) (*Change, error) {
+
+	b, err := s.Storage.Read(ctx, s.buildPath(contractID, formatCursor(sequence)))
+	if err != nil {
+		return nil, err
+	}
+
+	c := Change{}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// readHead returns the head of the changes to the contract, which is empty
+// if none have been recorded.
+func (s SyncService) readHead(ctx context.Context,
+	contractID string) (head, error) {
+
+	h := head{}
+
+	b, err := s.Storage.Read(ctx, s.buildPath(contractID, headKey))
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return h, nil
+		}
+
+		return h, err
+	}
+
+	if err := json.Unmarshal(b, &h); err != nil {
+		return h, err
+	}
+
+	return h, nil
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s SyncService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	if len(parts) != 3 || parts[0] != "contracts" || parts[2] != "changes" {
+		http.NotFound(w, r)
+		return
+	}
+
+	values := r.URL.Query()
+
+	since, err := strconv.ParseInt(values.Get("since"), 10, 32)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := 0
+	if l := values.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	p, err := s.Changes(r.Context(),
+		parts[1],
+		int32(since),
+		values.Get("cursor"),
+		limit)
+
+	log := logger.NewLoggerFromContext(r.Context()).Sugar()
+
+	if err != nil {
+		if status := errs.StatusCode(err); status != http.StatusInternalServerError {
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		log.Errorf("Failed to read changes %v : %v", r.URL.Path, err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		log.Errorf("Failed to write response : %v", err)
+	}
+}
+
+func (s SyncService) buildPath(contractID, key string) string {
+	return fmt.Sprintf("%v/%v/%v", SyncPrefix, contractID, key)
+}
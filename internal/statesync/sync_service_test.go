@@ -0,0 +1,494 @@
+package statesync
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/activation"
+	"github.com/tokenized/smart-contract/internal/app/state"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/storage"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+)
+
+// newKey returns a new key, and the contract address of the key.
+func newKey(t *testing.T) (*btcec.PrivateKey, string) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := hex.EncodeToString(key.PubKey().SerializeCompressed())
+
+	address, err := btcutil.DecodeAddress(h, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, address.EncodeAddress()
+}
+
+func TestSyncService(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "statesync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := storage.NewFilesystemStorage(storage.Config{
+		Root:   dir,
+		Bucket: "test",
+	})
+
+	key, contractID := newKey(t)
+
+	chain := activation.NewActivationService(nil, store)
+	s := NewSyncService(store, chain, key)
+
+	// event returns the i'th change to the contract
+	event := func(i int) state.Event {
+		return state.Event{
+			TxID:       fmt.Sprintf("tx%v", i),
+			Action:     "T2",
+			ContractID: contractID,
+			Contract: contract.Contract{
+				ID:       contractID,
+				Revision: uint16(i),
+				Votes: map[string]contract.Vote{
+					"vote": contract.Vote{
+						VoteOptions: []byte{65, 66},
+						Ballots: []contract.Ballot{
+							contract.Ballot{
+								Address: "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg",
+								Vote:    []byte{65},
+							},
+						},
+					},
+				},
+			},
+			Timestamp: int64(1000 + i),
+		}
+	}
+
+	// index records the change as the chain reaches the height
+	index := func(i int, height int32) {
+		point := fmt.Sprintf(`{"height":%v}`, height)
+		if err := store.Write(ctx, "activation/chain_point", []byte(point), nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// as the block handler moves the chain point
+		if err := chain.Load(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.Index(ctx, event(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// changes made as the chain reaches each height
+	heights := []int32{100, 100, 101, 102}
+
+	for i, height := range heights {
+		index(i, height)
+	}
+
+	// txIDs returns the TxID's of the changes
+	txIDs := func(changes []Change) []string {
+		got := []string{}
+		for _, c := range changes {
+			got = append(got, c.TxID)
+		}
+
+		return got
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		got := []string{}
+		cursor := ""
+
+		for {
+			p, err := s.Changes(ctx, contractID, 100, cursor, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := p.Verify(); err != nil {
+				t.Fatal(err)
+			}
+
+			got = append(got, txIDs(p.Changes)...)
+
+			if p.Next == "" {
+				break
+			}
+
+			cursor = p.Next
+		}
+
+		want := []string{"tx0", "tx1", "tx2", "tx3"}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got\n%#+v\nwant\n%#+v", got, want)
+		}
+	})
+
+	t.Run("since", func(t *testing.T) {
+		for since, want := range map[int32][]string{
+			0:   []string{"tx0", "tx1", "tx2", "tx3"},
+			101: []string{"tx2", "tx3"},
+			102: []string{"tx3"},
+			103: []string{},
+		} {
+			p, err := s.Changes(ctx, contractID, since, "", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := txIDs(p.Changes); !reflect.DeepEqual(got, want) {
+				t.Errorf("since %v : got %v, want %v", since, got, want)
+			}
+		}
+	})
+
+	t.Run("patches", func(t *testing.T) {
+		p, err := s.Changes(ctx, contractID, 0, "", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// only the revision changed after the first change
+		if got := string(p.Changes[1].Patch); got != `{"revision":1}` {
+			t.Errorf("got patch %v, want the revision", got)
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		if _, err := s.Changes(ctx, contractID, 0, "tx1", 0); err != ErrInvalidCursor {
+			t.Errorf("got %v, want %v", err, ErrInvalidCursor)
+		}
+	})
+
+	t.Run("catch up", func(t *testing.T) {
+		server := httptest.NewServer(s)
+		defer server.Close()
+
+		client := NewSyncClient(server.URL)
+
+		// the state after the second change, at height 100
+		stopped := event(1).Contract
+
+		tests := []struct {
+			name  string
+			since int32
+			state *contract.Contract
+			want  int
+		}{
+			{
+				name:  "no state",
+				since: 0,
+				want:  4,
+			},
+			{
+				name:  "stopped",
+				since: 100,
+				state: &stopped,
+				want:  4,
+			},
+			{
+				name:  "stopped after height",
+				since: 101,
+				state: &stopped,
+				want:  2,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				standby := state.NewStateService(storage.NewFilesystemStorage(storage.Config{
+					Root:   dir,
+					Bucket: "standby-" + tt.name,
+				}))
+
+				if tt.state != nil {
+					if err := standby.Write(ctx, *tt.state); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				n, err := client.CatchUp(ctx, standby, contractID, tt.since)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if n != tt.want {
+					t.Errorf("got %v changes, want %v", n, tt.want)
+				}
+
+				c, err := standby.Read(ctx, contractID)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !reflect.DeepEqual(*c, event(3).Contract) {
+					t.Errorf("got\n%#+v\nwant\n%#+v", *c, event(3).Contract)
+				}
+			})
+		}
+	})
+
+	t.Run("replayed page", func(t *testing.T) {
+		first, err := s.Changes(ctx, contractID, 100, "", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// answers every request with the signed first page
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+			r *http.Request) {
+
+			json.NewEncoder(w).Encode(first)
+		}))
+		defer server.Close()
+
+		client := NewSyncClient(server.URL)
+
+		if _, err := client.Changes(ctx, contractID, 100); err != ErrWrongRequest {
+			t.Errorf("got %v, want %v", err, ErrWrongRequest)
+		}
+
+		if _, err := client.Changes(ctx, contractID, 101); err != ErrWrongRequest {
+			t.Errorf("got %v, want %v", err, ErrWrongRequest)
+		}
+	})
+
+	t.Run("wrong signer", func(t *testing.T) {
+		server := httptest.NewServer(s)
+		defer server.Close()
+
+		other, _ := newKey(t)
+
+		client := NewSyncClient(server.URL)
+		client.PublicKey = other.PubKey()
+
+		if _, err := client.Changes(ctx, contractID, 0); err != ErrWrongSigner {
+			t.Errorf("got %v, want %v", err, ErrWrongSigner)
+		}
+	})
+
+	t.Run("reorg", func(t *testing.T) {
+		// the chain point goes back, but the change is kept in height order
+		index(4, 101)
+
+		p, err := s.Changes(ctx, contractID, 102, "", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := txIDs(p.Changes); !reflect.DeepEqual(got, []string{"tx3", "tx4"}) {
+			t.Errorf("got %v, want tx3 and tx4", got)
+		}
+	})
+}
+
+func TestPage_Verify(t *testing.T) {
+	key, contractID := newKey(t)
+
+	p := Page{
+		ContractID: contractID,
+		Changes: []Change{
+			Change{
+				ContractID: contractID,
+				Height:     100,
+				TxID:       "tx0",
+			},
+		},
+	}
+
+	if err := p.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	p.Changes[0].Height = 99
+
+	if _, err := p.Verify(); err != ErrBadSignature {
+		t.Errorf("got %v, want %v", err, ErrBadSignature)
+	}
+}
+
+func TestPage_Check(t *testing.T) {
+	_, contractID := newKey(t)
+
+	changes := []Change{
+		Change{ContractID: contractID, Sequence: 1, Height: 100, TxID: "tx0"},
+		Change{ContractID: contractID, Sequence: 2, Height: 101, TxID: "tx1"},
+	}
+
+	tests := []struct {
+		name   string
+		page   Page
+		since  int32
+		cursor string
+		err    error
+	}{
+		{
+			name: "first page",
+			page: Page{
+				ContractID: contractID,
+				Since:      100,
+				Changes:    changes,
+				Next:       changes[1].cursor(),
+			},
+			since: 100,
+		},
+		{
+			name: "next page",
+			page: Page{
+				ContractID: contractID,
+				Since:      100,
+				Cursor:     changes[0].cursor(),
+				Changes:    changes[1:],
+			},
+			since:  100,
+			cursor: changes[0].cursor(),
+		},
+		{
+			name: "other height",
+			page: Page{
+				ContractID: contractID,
+				Since:      99,
+				Changes:    changes,
+			},
+			since: 100,
+			err:   ErrWrongRequest,
+		},
+		{
+			name: "other cursor",
+			page: Page{
+				ContractID: contractID,
+				Since:      100,
+				Changes:    changes,
+				Next:       changes[1].cursor(),
+			},
+			since:  100,
+			cursor: changes[1].cursor(),
+			err:    ErrWrongRequest,
+		},
+		{
+			name: "change before cursor",
+			page: Page{
+				ContractID: contractID,
+				Since:      100,
+				Cursor:     changes[1].cursor(),
+				Changes:    changes[1:],
+			},
+			since:  100,
+			cursor: changes[1].cursor(),
+			err:    ErrPageOrder,
+		},
+		{
+			name: "next behind last change",
+			page: Page{
+				ContractID: contractID,
+				Since:      100,
+				Changes:    changes,
+				Next:       changes[0].cursor(),
+			},
+			since: 100,
+			err:   ErrPageOrder,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.page.Check(contractID, tt.since, tt.cursor); err != tt.err {
+				t.Errorf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		from  string
+		to    string
+		patch string
+	}{
+		{
+			name:  "empty",
+			to:    `{"a":1}`,
+			patch: `{"a":1}`,
+		},
+		{
+			name:  "unchanged",
+			from:  `{"a":1,"b":[1,2]}`,
+			to:    `{"a":1,"b":[1,2]}`,
+			patch: `{}`,
+		},
+		{
+			name:  "nested",
+			from:  `{"a":{"b":1,"c":2},"d":3}`,
+			to:    `{"a":{"b":1,"c":4},"d":3}`,
+			patch: `{"a":{"c":4}}`,
+		},
+		{
+			name:  "removed",
+			from:  `{"a":{"b":1,"c":2}}`,
+			to:    `{"a":{"b":1}}`,
+			patch: `{"a":{"c":null}}`,
+		},
+		{
+			name:  "array replaced",
+			from:  `{"a":[1,2]}`,
+			to:    `{"a":[1,2,3]}`,
+			patch: `{"a":[1,2,3]}`,
+		},
+		{
+			name:  "large number",
+			from:  `{"a":1}`,
+			to:    `{"a":1600000000000000001}`,
+			patch: `{"a":1600000000000000001}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := newMergePatch([]byte(tt.from), []byte(tt.to))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(patch) != tt.patch {
+				t.Fatalf("got patch %s, want %s", patch, tt.patch)
+			}
+
+			got, err := applyMergePatch([]byte(tt.from), patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tt.to {
+				t.Fatalf("got %s, want %s", got, tt.to)
+			}
+		})
+	}
+}
@@ -2,9 +2,9 @@ package validator
 
 import (
 	"context"
+	"time"
 
 	"github.com/tokenized/smart-contract/internal/app/inspector"
-	"github.com/tokenized/smart-contract/internal/app/state/contract"
 	"github.com/tokenized/smart-contract/pkg/protocol"
 )
 
@@ -36,7 +36,7 @@ func (h ballotCastValidator) validate(ctx context.Context,
 
 	// Can this person vote
 	sender := itx.InputAddrs[0]
-	ballot := contract.NewBallotFromBallotCast(sender, m)
+	ballot := vote.NewBallot(sender, m, time.Now())
 
 	if code := c.CanVote(vote, ballot); code != protocol.RejectionCodeOK {
 		return code
@@ -79,6 +79,13 @@ func (h exchangeValidator) validate(ctx context.Context,
 		return protocol.RejectionCodeReceiverUnspecified
 	}
 
+	// Receiver output below the minimum output of the contract
+	//
+	if c.DustPolicy.Rejects(itx.Outputs[2].Value) {
+		log.Errorf("exchange : Receiver output below minimum")
+		return protocol.RejectionCodeInsufficientValue
+	}
+
 	// Party 2: Skip if no holding
 	//
 	party2Address := itx.InputAddrs[1]
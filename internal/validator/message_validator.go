@@ -0,0 +1,60 @@
+package validator
+
+import (
+	"context"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/inspector"
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+type messageValidator struct{}
+
+func newMessageValidator() messageValidator {
+	return messageValidator{}
+}
+
+// can returns a code indicating if the message can be applied to the
+// contract.
+//
+// A return value of 0 (protocol.RejectionCodeOK) indicates that the message
+// can be applied to the Contract. Any non-zero value should be interpreted
+// as the rejection code.
+func (h messageValidator) validate(ctx context.Context,
+	itx *inspector.Transaction, vd validatorData) uint8 {
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	// Contract and Message
+	c := vd.contract
+	m := vd.m.(*protocol.Message)
+
+	if string(m.MessageType) != contract.MessageTypeTransferAccept {
+		// not a message for the contract to act on
+		return protocol.RejectionCodeOK
+	}
+
+	id := string(m.Message)
+	pending, ok := c.PendingTransfers[id]
+	if !ok {
+		log.Errorf("message : Transfer not found contract=%s id=%s", c.ID, id)
+		return protocol.RejectionCodeTransferNotFound
+	}
+
+	// Only the receiver can accept the transfer
+	//
+	sender := itx.InputAddrs[0].EncodeAddress()
+	if sender != pending.Receiver {
+		log.Errorf("message : Transfer not accepted by receiver contract=%s id=%s sender=%s", c.ID, id, sender)
+		return protocol.RejectionCodeUnknownAddress
+	}
+
+	if pending.Expired(time.Now()) {
+		log.Errorf("message : Transfer expired contract=%s id=%s", c.ID, id)
+		return protocol.RejectionCodeTransferExpired
+	}
+
+	return protocol.RejectionCodeOK
+}
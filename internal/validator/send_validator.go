@@ -87,6 +87,13 @@ func (h sendValidator) validate(ctx context.Context,
 	// Party 2 (Receiver)
 	party2Addr := itx.Outputs[1].Address.EncodeAddress()
 
+	// Receiver output below the minimum output of the contract
+	//
+	if c.DustPolicy.Rejects(itx.Outputs[1].Value) {
+		log.Errorf("send : Receiver output below minimum contract=%s assetID=%s party2=%s", c.ID, m.AssetID, party2Addr)
+		return protocol.RejectionCodeInsufficientValue
+	}
+
 	// Cannot transfer to self
 	//
 	if party1Addr == party2Addr {
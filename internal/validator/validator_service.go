@@ -46,6 +46,7 @@ func newRequestValidators(state state.StateInterface,
 		protocol.CodeSend:              newSendValidator(config.Fee),
 		protocol.CodeExchange:          newExchangeValidator(config.Fee),
 		protocol.CodeOrder:             newOrderValidator(config.Fee),
+		protocol.CodeMessage:           newMessageValidator(),
 		// protocol.CodeInitiative:        newInitiativeValidator(),
 		// protocol.CodeReferendum:        newReferendumValidator(),
 		// protocol.CodeBallotCast:        newBallotCastValidator(),
@@ -85,11 +86,6 @@ func (s ValidatorService) CheckAndFetch(ctx context.Context,
 		return nil, nil, fmt.Errorf("Missing type mapping type : %v", m.Type())
 	}
 
-	minimum, ok := s.Fees[m.Type()]
-	if !ok {
-		return nil, nil, fmt.Errorf("No minimum for type : %v", m.Type())
-	}
-
 	contractAddress := itx.Outputs[0].Address
 
 	// This is the message we need to create a contract for. All other
@@ -108,18 +104,13 @@ func (s ValidatorService) CheckAndFetch(ctx context.Context,
 		return nil, nil, err
 	}
 
-	// Get spendable UTXO's received for the contract address
-	utxos, err := itx.UTXOs.ForAddress(contractAddress)
-	if err != nil {
-		return nil, nil, err
-	}
-
 	// Check that we have received enough to perform the action.
 	//
 	// The txn fee (if any) will be paid by the responding transaction, so
 	// the amount paid to the contract address needs to be the minimum, plus
 	// the txn fee value.
-	if uint64(utxos.Value()) < minimum {
+	_, err = inspector.FeePaid(itx, contractAddress, s.Fees)
+	if err == inspector.ErrInsufficientFee {
 		// There is insufficient value to fund this transaction.
 		code := protocol.RejectionCodeInsufficientValue
 		newTx, err := s.reject(ctx, itx, code)
@@ -130,6 +121,10 @@ func (s ValidatorService) CheckAndFetch(ctx context.Context,
 		return newTx, nil, nil
 	}
 
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// State: I have seen this already
 	if contract.KnownTX(ctx, itx.MsgTx) {
 		return nil, nil, nil
@@ -267,6 +262,15 @@ func (s ValidatorService) isPermitted(itx *inspector.Transaction,
 // A Rejection message will be sent to the network, if there are enough
 // funds issue the message.
 //
+// Reject returns a TX rejecting the request with the code, such as when the
+// request cannot be processed at all.
+func (s ValidatorService) Reject(ctx context.Context,
+	itx *inspector.Transaction,
+	code uint8) (*wire.MsgTx, error) {
+
+	return s.reject(ctx, itx, code)
+}
+
 func (s ValidatorService) reject(ctx context.Context,
 	itx *inspector.Transaction,
 	code uint8) (*wire.MsgTx, error) {
@@ -303,18 +307,13 @@ func (s ValidatorService) reject(ctx context.Context,
 	// The UTXOs to spend are in the TX we received.
 	changeAddress := sender
 
-	// Contract address
-	key, err := s.Wallet.Get(receiver.Address.String())
-	if err != nil {
-		return nil, err
-	}
-
-	newTx, err := s.Wallet.BuildTX(key, utxos, outs, changeAddress, &rejection)
+	newTx, err := s.Wallet.BuildContractTX(ctx, receiver.Address, utxos, outs,
+		changeAddress, &rejection)
 	if err != nil {
 		return nil, err
 	}
 
-	return newTx, nil
+	return newTx.MsgTx, nil
 }
 
 // Build a rejection protocol message
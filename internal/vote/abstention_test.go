@@ -0,0 +1,132 @@
+package vote
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+func TestVoteService_abstentions(t *testing.T) {
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+	issuerAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	otherUserAddr := "1DnoezsMcKZeQrXVW7eqU5v8HRKmnPSYd2"
+
+	// "Y" = 89, "N" = 78, "A" = 65 to abstain
+	vo := contract.Vote{
+		AssetID:       assetID,
+		VoteOptions:   []byte{89, 78},
+		AbstainOption: 65,
+		VoteLogic:     '0',
+		VoteMax:       2,
+		Ballots: []contract.Ballot{
+			contract.Ballot{
+				Address: issuerAddr,
+				AssetID: assetID,
+				Vote:    []byte{89},
+			},
+			contract.Ballot{
+				Address: userAddr,
+				AssetID: assetID,
+				Vote:    []byte{78},
+			},
+			contract.Ballot{
+				Address: otherUserAddr,
+				AssetID: assetID,
+				Vote:    []byte{65},
+			},
+		},
+	}
+
+	tests := []struct {
+		name                string
+		abstentionsInQuorum bool
+		wantWinners         []uint8
+		wantParticipation   float64
+	}{
+		{
+			// 15 of 20 votes cast
+			name:                "abstentions not in quorum",
+			abstentionsInQuorum: false,
+			wantWinners:         []uint8{89},
+			wantParticipation:   50,
+		},
+		{
+			// 15 of 40 votes cast, including abstentions
+			name:                "abstentions in quorum",
+			abstentionsInQuorum: true,
+			wantWinners:         []uint8{},
+			wantParticipation:   100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := contract.Contract{
+				AbstentionsInQuorum: tt.abstentionsInQuorum,
+				Assets: map[string]contract.Asset{
+					assetID: contract.Asset{
+						VotingSystem: protocol.VotingSystemRelativeMajority,
+						Holdings: map[string]contract.Holding{
+							issuerAddr: contract.Holding{
+								Address: issuerAddr,
+								Balance: 15,
+							},
+							userAddr: contract.Holding{
+								Address: userAddr,
+								Balance: 5,
+							},
+							otherUserAddr: contract.Holding{
+								Address: otherUserAddr,
+								Balance: 20,
+							},
+						},
+					},
+				},
+			}
+
+			s := NewVoteService()
+
+			result := s.generateResult(c, vo)
+
+			wantResult := contract.BallotResult{
+				89: 15,
+				78: 5,
+				65: 20,
+			}
+
+			if !reflect.DeepEqual(result, wantResult) {
+				t.Fatalf("got\n%#+v\nwant\n%#+v", result, wantResult)
+			}
+
+			resulted := vo
+			resulted.Result = &result
+
+			outcome, err := s.resolveOutcome(c, resulted)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(outcome.Winners, tt.wantWinners) {
+				t.Errorf("got winners %v, want %v", outcome.Winners, tt.wantWinners)
+			}
+
+			stats, err := s.Stats(c, resulted)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stats.Abstainers != 1 || stats.AbstainedTokens != 20 {
+				t.Errorf("got %v abstainers with %v tokens, want 1 with 20",
+					stats.Abstainers, stats.AbstainedTokens)
+			}
+
+			if stats.Participation != tt.wantParticipation {
+				t.Errorf("got participation %v, want %v", stats.Participation,
+					tt.wantParticipation)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package vote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+var (
+	// ErrNoProposal is returned when generating the amendment of a Vote
+	// that has no Proposal.
+	ErrNoProposal = errors.New("Vote has no proposal")
+
+	// ErrUnknownField is returned when a Proposal amends a field that cannot
+	// be amended by a vote.
+	ErrUnknownField = errors.New("Field cannot be amended")
+)
+
+// Amender is given the amendment action generated when a Vote with a
+// Proposal passes, such as to broadcast it.
+type Amender interface {
+	Amend(context.Context, contract.Contract, contract.Vote, protocol.OpReturnMessage) error
+}
+
+// contractFields sets the fields of a ContractFormation that a Proposal can
+// amend.
+var contractFields = map[string]func(*protocol.ContractFormation, string) error{
+	"ContractName": func(m *protocol.ContractFormation, v string) error {
+		m.ContractName = []byte(v)
+		return nil
+	},
+	"ContractFileHash": func(m *protocol.ContractFormation, v string) error {
+		m.ContractFileHash = []byte(v)
+		return nil
+	},
+	"GoverningLaw": func(m *protocol.ContractFormation, v string) error {
+		m.GoverningLaw = []byte(v)
+		return nil
+	},
+	"Jurisdiction": func(m *protocol.ContractFormation, v string) error {
+		m.Jurisdiction = []byte(v)
+		return nil
+	},
+	"ContractExpiration": func(m *protocol.ContractFormation, v string) error {
+		n, err := strconv.ParseUint(v, 10, 64)
+		m.ContractExpiration = n
+		return err
+	},
+	"URI": func(m *protocol.ContractFormation, v string) error {
+		m.URI = []byte(v)
+		return nil
+	},
+	"IssuerID": func(m *protocol.ContractFormation, v string) error {
+		m.IssuerID = []byte(v)
+		return nil
+	},
+	"ContractOperatorID": func(m *protocol.ContractFormation, v string) error {
+		m.ContractOperatorID = []byte(v)
+		return nil
+	},
+	"VotingSystem": func(m *protocol.ContractFormation, v string) error {
+		b, err := parseByte(v)
+		m.VotingSystem = b
+		return err
+	},
+	"RestrictedQty": func(m *protocol.ContractFormation, v string) error {
+		n, err := strconv.ParseUint(v, 10, 64)
+		m.RestrictedQty = n
+		return err
+	},
+}
+
+// assetFields sets the fields of an AssetCreation that a Proposal can
+// amend.
+var assetFields = map[string]func(*protocol.AssetCreation, string) error{
+	"VotingSystem": func(m *protocol.AssetCreation, v string) error {
+		b, err := parseByte(v)
+		m.VotingSystem = b
+		return err
+	},
+	"VoteMultiplier": func(m *protocol.AssetCreation, v string) error {
+		n, err := strconv.ParseUint(v, 10, 8)
+		m.VoteMultiplier = uint8(n)
+		return err
+	},
+	"Qty": func(m *protocol.AssetCreation, v string) error {
+		n, err := strconv.ParseUint(v, 10, 64)
+		m.Qty = n
+		return err
+	},
+}
+
+// NewAmendmentMessage returns the action that makes the amendment proposed
+// by a Vote.
+//
+// A contract amendment is made by a ContractFormation, and an asset
+// amendment by an AssetCreation. Fields not amended keep their current
+// values, and the revision is bumped.
+func NewAmendmentMessage(c contract.Contract,
+	vo contract.Vote) (protocol.OpReturnMessage, error) {
+
+	p := vo.Proposal
+	if p == nil {
+		return nil, ErrNoProposal
+	}
+
+	if p.AssetID == "" {
+		return newContractAmendment(c, *p)
+	}
+
+	a, ok := c.Assets[p.AssetID]
+	if !ok {
+		return nil, fmt.Errorf("Asset not found : %v", p.AssetID)
+	}
+
+	return newAssetAmendment(a, *p)
+}
+
+// newContractAmendment returns the ContractFormation amending the contract.
+func newContractAmendment(c contract.Contract,
+	p contract.Proposal) (*protocol.ContractFormation, error) {
+
+	m := protocol.NewContractFormation()
+	m.ContractName = []byte(c.ContractName)
+	m.ContractFileHash = []byte(c.ContractFileHash)
+	m.GoverningLaw = []byte(c.GoverningLaw)
+	m.Jurisdiction = []byte(c.Jurisdiction)
+	m.ContractExpiration = c.ContractExpiration
+	m.URI = []byte(c.URI)
+	m.ContractRevision = c.Revision + 1
+	m.IssuerID = []byte(c.IssuerID)
+	m.ContractOperatorID = []byte(c.ContractOperatorID)
+	m.AuthorizationFlags = c.AuthorizationFlags
+	m.InitiativeThreshold = c.InitiativeThreshold
+	m.InitiativeThresholdCurrency = []byte(c.InitiativeThresholdCurrency)
+	m.RestrictedQty = c.Qty
+
+	if len(c.IssuerType) > 0 {
+		m.IssuerType = c.IssuerType[0]
+	}
+
+	if len(c.VotingSystem) > 0 {
+		m.VotingSystem = c.VotingSystem[0]
+	}
+
+	for _, amendment := range p.Amendments {
+		set, ok := contractFields[amendment.Field]
+		if !ok {
+			return nil, ErrUnknownField
+		}
+
+		if err := set(&m, amendment.Value); err != nil {
+			return nil, fmt.Errorf("Invalid %v : %v", amendment.Field, err)
+		}
+	}
+
+	return &m, nil
+}
+
+// newAssetAmendment returns the AssetCreation amending the asset.
+func newAssetAmendment(a contract.Asset,
+	p contract.Proposal) (*protocol.AssetCreation, error) {
+
+	m := protocol.NewAssetCreation()
+	m.AssetType = []byte(a.Type)
+	m.AssetID = []byte(a.ID)
+	m.AssetRevision = a.Revision + 1
+	m.AuthorizationFlags = a.AuthorizationFlags
+	m.VotingSystem = a.VotingSystem
+	m.VoteMultiplier = a.VoteMultiplier
+	m.Qty = a.Qty
+
+	for _, amendment := range p.Amendments {
+		set, ok := assetFields[amendment.Field]
+		if !ok {
+			return nil, ErrUnknownField
+		}
+
+		if err := set(&m, amendment.Value); err != nil {
+			return nil, fmt.Errorf("Invalid %v : %v", amendment.Field, err)
+		}
+	}
+
+	return &m, nil
+}
+
+// parseByte returns the single byte code held by a value, such as a voting
+// system.
+func parseByte(v string) (byte, error) {
+	if len(v) != 1 {
+		return 0, fmt.Errorf("Expected a single byte : %q", v)
+	}
+
+	return v[0], nil
+}
+
+// amend generates the amendment proposed by a resulted Vote, and passes it
+// to the Amender's, if the proposed option won.
+func (v VoteService) amend(ctx context.Context,
+	c contract.Contract,
+	vo contract.Vote) error {
+
+	if vo.Proposal == nil || len(v.amenders) == 0 {
+		return nil
+	}
+
+	if len(vo.Winners) != 1 || vo.Winners[0] != vo.Proposal.Option {
+		return nil
+	}
+
+	m, err := NewAmendmentMessage(c, vo)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range v.amenders {
+		if err := a.Amend(ctx, c, vo, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
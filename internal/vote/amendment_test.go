@@ -0,0 +1,228 @@
+package vote
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+type testAmender struct {
+	messages *[]protocol.OpReturnMessage
+}
+
+func (a testAmender) Amend(ctx context.Context,
+	c contract.Contract,
+	vo contract.Vote,
+	m protocol.OpReturnMessage) error {
+
+	*a.messages = append(*a.messages, m)
+	return nil
+}
+
+func TestNewAmendmentMessage(t *testing.T) {
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+
+	c := contract.Contract{
+		ContractName: "Before",
+		Revision:     2,
+		VotingSystem: "M",
+		Qty:          10,
+		Assets: map[string]contract.Asset{
+			assetID: contract.Asset{
+				ID:       assetID,
+				Type:     "SHC",
+				Revision: 1,
+				Qty:      100,
+			},
+		},
+	}
+
+	wantContract := protocol.NewContractFormation()
+	wantContract.ContractName = []byte("After")
+	wantContract.ContractFileHash = []byte{}
+	wantContract.GoverningLaw = []byte{}
+	wantContract.Jurisdiction = []byte{}
+	wantContract.URI = []byte{}
+	wantContract.ContractRevision = 3
+	wantContract.IssuerID = []byte{}
+	wantContract.ContractOperatorID = []byte{}
+	wantContract.VotingSystem = 'M'
+	wantContract.InitiativeThresholdCurrency = []byte{}
+	wantContract.RestrictedQty = 20
+
+	wantAsset := protocol.NewAssetCreation()
+	wantAsset.AssetType = []byte("SHC")
+	wantAsset.AssetID = []byte(assetID)
+	wantAsset.AssetRevision = 2
+	wantAsset.Qty = 200
+
+	tests := []struct {
+		name     string
+		proposal *contract.Proposal
+		want     protocol.OpReturnMessage
+		err      error
+	}{
+		{
+			name: "no proposal",
+			err:  ErrNoProposal,
+		},
+		{
+			name: "contract",
+			proposal: &contract.Proposal{
+				Option: 65,
+				Amendments: []contract.Amendment{
+					contract.Amendment{Field: "ContractName", Value: "After"},
+					contract.Amendment{Field: "RestrictedQty", Value: "20"},
+				},
+			},
+			want: &wantContract,
+		},
+		{
+			name: "asset",
+			proposal: &contract.Proposal{
+				Option:  65,
+				AssetID: assetID,
+				Amendments: []contract.Amendment{
+					contract.Amendment{Field: "Qty", Value: "200"},
+				},
+			},
+			want: &wantAsset,
+		},
+		{
+			name: "unknown field",
+			proposal: &contract.Proposal{
+				Option:  65,
+				AssetID: assetID,
+				Amendments: []contract.Amendment{
+					contract.Amendment{Field: "ContractName", Value: "After"},
+				},
+			},
+			err: ErrUnknownField,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vo := contract.Vote{
+				Proposal: tt.proposal,
+			}
+
+			got, err := NewAmendmentMessage(c, vo)
+			if err != tt.err {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+
+			if tt.err != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%#+v\nwant\n%#+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVoteService_handle_amend(t *testing.T) {
+	ctx := context.Background()
+
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+	issuerAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+
+	now := time.Now().UnixNano()
+
+	description := []byte(`{"option":65,"amendments":[{"field":"ContractName","value":"After"}]}`)
+
+	newVote := func(choice uint8) contract.Vote {
+		return contract.Vote{
+			AssetID:             assetID,
+			VoteOptions:         []byte{65, 66},
+			VoteLogic:           '0',
+			VoteMax:             1,
+			VoteCutOffTimestamp: now - 1,
+			CreatedAt:           now - int64(time.Hour),
+			Proposal:            contract.ParseProposal(description),
+			Ballots: []contract.Ballot{
+				contract.Ballot{
+					Address: issuerAddr,
+					AssetID: assetID,
+					Vote:    []byte{choice},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		vote  contract.Vote
+		count int
+	}{
+		{
+			name:  "passed",
+			vote:  newVote(65),
+			count: 1,
+		},
+		{
+			name:  "rejected",
+			vote:  newVote(66),
+			count: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := contract.Contract{
+				IssuerAddress: issuerAddr,
+				ContractName:  "Before",
+				Assets: map[string]contract.Asset{
+					assetID: contract.Asset{
+						Holdings: map[string]contract.Holding{
+							issuerAddr: contract.Holding{
+								Address: issuerAddr,
+								Balance: 10,
+							},
+							userAddr: contract.Holding{
+								Address: userAddr,
+								Balance: 5,
+							},
+						},
+					},
+				},
+				Votes: map[string]contract.Vote{
+					"vote": tt.vote,
+				},
+			}
+
+			messages := []protocol.OpReturnMessage{}
+
+			s := NewVoteService()
+			s.RegisterAmender(testAmender{messages: &messages})
+
+			if _, err := s.handle(ctx, c); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(messages) != tt.count {
+				t.Fatalf("got %v amendments, want %v", len(messages), tt.count)
+			}
+
+			if tt.count == 0 {
+				return
+			}
+
+			cf, ok := messages[0].(*protocol.ContractFormation)
+			if !ok {
+				t.Fatalf("got %T, want *protocol.ContractFormation", messages[0])
+			}
+
+			if string(cf.ContractName) != "After" {
+				t.Errorf("got name %q, want %q", cf.ContractName, "After")
+			}
+		})
+	}
+}
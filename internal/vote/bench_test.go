@@ -0,0 +1,83 @@
+package vote
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+)
+
+// benchVoters is the number of holders that cast a ballot in the
+// benchmarks.
+const benchVoters = 1000
+
+// newBenchVote returns a contract with benchVoters holders of an asset, and
+// a vote with a ballot from each of them.
+func newBenchVote() (contract.Contract, contract.Vote) {
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+
+	asset := contract.Asset{
+		ID:       assetID,
+		Holdings: map[string]contract.Holding{},
+	}
+
+	vo := contract.Vote{
+		AssetID:     assetID,
+		VoteOptions: []byte{65, 66, 67},
+		VoteLogic:   '1',
+		VoteMax:     2,
+	}
+
+	for i := 0; i < benchVoters; i++ {
+		address := fmt.Sprintf("voter%v", i)
+
+		asset.Holdings[address] = contract.Holding{
+			Address: address,
+			Balance: uint64(i + 1),
+		}
+
+		vo.Ballots = append(vo.Ballots, contract.Ballot{
+			Address: address,
+			AssetID: assetID,
+			Vote:    []byte{vo.VoteOptions[i%3], vo.VoteOptions[(i+1)%3]},
+		})
+	}
+
+	c := contract.Contract{
+		Assets: map[string]contract.Asset{
+			assetID: asset,
+		},
+	}
+
+	return c, vo
+}
+
+// BenchmarkGenerateResult performs a benchmark on how long it takes to
+// tally the ballots of a vote from scratch.
+func BenchmarkGenerateResult(b *testing.B) {
+	c, vo := newBenchVote()
+	v := NewVoteService()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		v.generateResult(c, vo)
+	}
+}
+
+// BenchmarkTallyAccumulator_Add performs a benchmark on how long it takes to
+// count a ballot as it arrives, replacing the voter's earlier ballot.
+func BenchmarkTallyAccumulator_Add(b *testing.B) {
+	c, vo := newBenchVote()
+	acc := NewTallyAccumulator(c, vo)
+
+	for _, ballot := range vo.Ballots {
+		acc.Add(ballot)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		acc.Add(vo.Ballots[i%len(vo.Ballots)])
+	}
+}
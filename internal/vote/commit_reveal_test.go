@@ -0,0 +1,201 @@
+package vote
+
+import (
+	"context"
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+)
+
+func TestVoteService_generateResult_commitReveal(t *testing.T) {
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+	issuerAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	otherUserAddr := "1DnoezsMcKZeQrXVW7eqU5v8HRKmnPSYd2"
+
+	c := contract.Contract{
+		Assets: map[string]contract.Asset{
+			assetID: contract.Asset{
+				Holdings: map[string]contract.Holding{
+					issuerAddr: contract.Holding{
+						Address: issuerAddr,
+						Balance: 10,
+					},
+					userAddr: contract.Holding{
+						Address: userAddr,
+						Balance: 5,
+					},
+					otherUserAddr: contract.Holding{
+						Address: otherUserAddr,
+						Balance: 20,
+					},
+				},
+			},
+		},
+	}
+
+	salt := []byte("01234567")
+
+	commit := func(address string, choice uint8) contract.Ballot {
+		return contract.Ballot{
+			Address:    address,
+			AssetID:    assetID,
+			Vote:       []byte{},
+			Commitment: contract.NewCommitment(address, []byte{byte(choice)}, salt),
+		}
+	}
+
+	reveal := func(address string, choice uint8) contract.Ballot {
+		return contract.Ballot{
+			Address: address,
+			AssetID: assetID,
+			Vote:    []byte{choice},
+			Salt:    hex.EncodeToString(salt),
+		}
+	}
+
+	vo := contract.Vote{
+		AssetID:      assetID,
+		VoteOptions:  []byte{65, 66},
+		VoteMax:      1,
+		CommitReveal: &contract.CommitReveal{RevealPeriod: 3600},
+		Ballots: []contract.Ballot{
+			commit(issuerAddr, 65),
+			commit(userAddr, 66),
+			commit(otherUserAddr, 66),
+			reveal(issuerAddr, 65),
+			// revealing a choice other than the commitment is not counted
+			reveal(userAddr, 65),
+			// the other user never revealed
+		},
+	}
+
+	got := NewVoteService().generateResult(c, vo)
+	want := contract.BallotResult{65: 10}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%#+v\nwant\n%#+v", got, want)
+	}
+}
+
+func TestVoteService_handle_commitRevealRevote(t *testing.T) {
+	ctx := context.Background()
+
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+	issuerAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+
+	c := contract.Contract{
+		IssuerAddress: issuerAddr,
+		TieBreak:      TieBreakRevote,
+		Assets: map[string]contract.Asset{
+			assetID: contract.Asset{
+				Holdings: map[string]contract.Holding{
+					issuerAddr: contract.Holding{
+						Address: issuerAddr,
+						Balance: 5,
+					},
+					userAddr: contract.Holding{
+						Address: userAddr,
+						Balance: 5,
+					},
+				},
+			},
+		},
+	}
+
+	salt := []byte("01234567")
+
+	ballots := func(address string, choice uint8) []contract.Ballot {
+		return []contract.Ballot{
+			contract.Ballot{
+				Address:    address,
+				AssetID:    assetID,
+				Vote:       []byte{},
+				Commitment: contract.NewCommitment(address, []byte{byte(choice)}, salt),
+			},
+			contract.Ballot{
+				Address: address,
+				AssetID: assetID,
+				Vote:    []byte{choice},
+				Salt:    hex.EncodeToString(salt),
+			},
+		}
+	}
+
+	proposal := &contract.Proposal{
+		Option: 65,
+		Amendments: []contract.Amendment{
+			contract.Amendment{Field: "ContractName", Value: "Renamed"},
+		},
+	}
+
+	now := time.Now().UnixNano()
+
+	c.Votes = map[string]contract.Vote{
+		"vote": contract.Vote{
+			AssetID:             assetID,
+			VoteOptions:         []byte{65, 66, 67},
+			VoteMax:             1,
+			AbstainOption:       67,
+			Proposal:            proposal,
+			CommitReveal:        &contract.CommitReveal{RevealPeriod: 1},
+			VoteCutOffTimestamp: now - 2*int64(time.Second),
+			CreatedAt:           now - int64(time.Hour),
+			Ballots: append(ballots(issuerAddr, 65),
+				ballots(userAddr, 66)...),
+		},
+	}
+
+	votes, err := NewVoteService().handle(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(votes) != 2 {
+		t.Fatalf("got %v votes, want 2", len(votes))
+	}
+
+	revote := votes[1]
+
+	if !reflect.DeepEqual(revote.CommitReveal, &contract.CommitReveal{RevealPeriod: 1}) {
+		t.Errorf("got commit reveal %v, want reveal period 1", revote.CommitReveal)
+	}
+
+	if !reflect.DeepEqual(revote.Proposal, proposal) {
+		t.Errorf("got proposal %v, want %v", revote.Proposal, proposal)
+	}
+
+	wantOptions := []byte{65, 66, 67}
+	if !reflect.DeepEqual(revote.VoteOptions, wantOptions) {
+		t.Errorf("got options %v, want %v", revote.VoteOptions, wantOptions)
+	}
+
+	if revote.AbstainOption != 67 {
+		t.Errorf("got abstain option %v, want 67", revote.AbstainOption)
+	}
+
+	// ballots cast while the revote is open are hidden until revealed
+	address, err := btcutil.DecodeAddress(userAddr, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := protocol.BallotCast{
+		AssetID:   []byte(assetID),
+		VoteTxnID: []byte(revote.RefTxnIDHash),
+		Vote:      []byte{66},
+	}
+
+	b := revote.NewBallot(address, &m, time.Now())
+	if b.Commitment == "" || len(b.Vote) != 0 {
+		t.Errorf("got public ballot %v, want a commitment", b)
+	}
+}
@@ -0,0 +1,171 @@
+package vote
+
+import (
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+)
+
+// condorcetSystem is won by the option preferred to every other option head
+// to head, weighted by the tokens of each voter.
+//
+// If no option beats every other, such as when the preferences are cyclic,
+// the Schulze method is used to decide the winners.
+type condorcetSystem struct{}
+
+// Winners returns the outcome with the Condorcet winner, or the Schulze
+// winners if there is no Condorcet winner. The quorum is met if any tokens
+// took part in the vote.
+func (s condorcetSystem) Winners(c contract.Contract,
+	vo contract.Vote) VoteOutcome {
+
+	p := newParticipation(c, vo)
+	options := rankedOptions(vo)
+	d := pairwisePreferences(options, rankedBallots(c, vo))
+
+	winners := []uint8{}
+
+	if w, ok := condorcetWinner(options, d); ok {
+		winners = append(winners, w)
+	} else {
+		winners = schulzeWinners(options, d)
+	}
+
+	return newVoteOutcome(c, vo, p.quorumMet(s.QuorumThreshold()), winners)
+}
+
+// QuorumThreshold implements the VotingSystem interface.
+func (s condorcetSystem) QuorumThreshold() float64 {
+	return 0
+}
+
+// preferences holds the tokens preferring one option to another, such that
+// d[a][b] is the tokens ranking a above b.
+type preferences map[uint8]map[uint8]uint64
+
+// pairwisePreferences returns the head to head preferences between each
+// pair of options.
+func pairwisePreferences(options []uint8,
+	ballots []rankedBallot) preferences {
+
+	d := preferences{}
+
+	for _, a := range options {
+		d[a] = map[uint8]uint64{}
+	}
+
+	for _, b := range ballots {
+		for _, x := range options {
+			for _, y := range options {
+				if x != y && b.rank(x) < b.rank(y) {
+					d[x][y] += b.tokens
+				}
+			}
+		}
+	}
+
+	return d
+}
+
+// condorcetWinner returns the option that beats every other option head to
+// head, and false if there is none.
+func condorcetWinner(options []uint8,
+	d preferences) (uint8, bool) {
+
+	for _, a := range options {
+		wins := true
+
+		for _, b := range options {
+			if a != b && d[a][b] <= d[b][a] {
+				wins = false
+				break
+			}
+		}
+
+		if wins {
+			return a, true
+		}
+	}
+
+	return 0, false
+}
+
+// schulzeWinners returns the winners under the Schulze method, which
+// compares the strongest paths of head to head wins between the options.
+//
+// An option wins if no other option has a stronger path to it than it has
+// to that option. There is more than one winner if the paths are tied, and
+// none if no ballots ranked the options.
+func schulzeWinners(options []uint8,
+	d preferences) []uint8 {
+
+	// the strength of the strongest path from a to b
+	p := preferences{}
+
+	for _, a := range options {
+		p[a] = map[uint8]uint64{}
+
+		for _, b := range options {
+			if a != b && d[a][b] > d[b][a] {
+				p[a][b] = d[a][b]
+			}
+		}
+	}
+
+	for _, i := range options {
+		for _, j := range options {
+			if i == j {
+				continue
+			}
+
+			for _, k := range options {
+				if i == k || j == k {
+					continue
+				}
+
+				if via := minTokens(p[j][i], p[i][k]); via > p[j][k] {
+					p[j][k] = via
+				}
+			}
+		}
+	}
+
+	winners := []uint8{}
+	beaten := false
+
+	for _, a := range options {
+		wins := true
+
+		for _, b := range options {
+			if a == b {
+				continue
+			}
+
+			if p[b][a] > p[a][b] {
+				wins = false
+			}
+
+			if p[a][b] > 0 {
+				beaten = true
+			}
+		}
+
+		if wins {
+			winners = append(winners, a)
+		}
+	}
+
+	if !beaten {
+		// no option was preferred to any other
+		return []uint8{}
+	}
+
+	return winners
+}
+
+// minTokens returns the smaller of two values.
+func minTokens(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,145 @@
+package vote
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+func TestCondorcetSystem_Winners(t *testing.T) {
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+	addrA := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	addrB := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	addrC := "1DnoezsMcKZeQrXVW7eqU5v8HRKmnPSYd2"
+
+	c := contract.Contract{
+		Assets: map[string]contract.Asset{
+			assetID: contract.Asset{
+				VotingSystem: protocol.VotingSystemCondorcet,
+				Holdings: map[string]contract.Holding{
+					addrA: contract.Holding{
+						Address: addrA,
+						Balance: 40,
+					},
+					addrB: contract.Holding{
+						Address: addrB,
+						Balance: 35,
+					},
+					addrC: contract.Holding{
+						Address: addrC,
+						Balance: 25,
+					},
+				},
+			},
+		},
+	}
+
+	ballot := func(address string, ranking ...uint8) contract.Ballot {
+		return contract.Ballot{
+			Address: address,
+			AssetID: assetID,
+			Vote:    []byte(ranking),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		ballots []contract.Ballot
+		want    VoteOutcome
+	}{
+		{
+			name: "no ballots",
+			want: VoteOutcome{
+				Winners: []uint8{},
+				Reason:  ReasonNoQuorum,
+			},
+		},
+		{
+			// B is preferred to A and C head to head, though A has the
+			// most first choices
+			name: "condorcet winner",
+			ballots: []contract.Ballot{
+				ballot(addrA, 65, 66, 67),
+				ballot(addrB, 66, 67, 65),
+				ballot(addrC, 67, 66, 65),
+			},
+			want: VoteOutcome{
+				Winners:   []uint8{66},
+				Passed:    true,
+				QuorumMet: true,
+			},
+		},
+		{
+			// A beats B, B beats C and C beats A, so the Schulze method
+			// decides the winner
+			name: "cycle",
+			ballots: []contract.Ballot{
+				ballot(addrA, 65, 66, 67),
+				ballot(addrB, 66, 67, 65),
+				ballot(addrC, 67, 65, 66),
+			},
+			want: VoteOutcome{
+				Winners:   []uint8{65},
+				Passed:    true,
+				QuorumMet: true,
+			},
+		},
+		{
+			// unranked options are preferred equally, below ranked options
+			name: "partial rankings",
+			ballots: []contract.Ballot{
+				ballot(addrB, 66),
+				ballot(addrC, 67),
+			},
+			want: VoteOutcome{
+				Winners:   []uint8{66},
+				Passed:    true,
+				QuorumMet: true,
+			},
+		},
+	}
+
+	s := NewVoteService()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vo := contract.Vote{
+				AssetID:     assetID,
+				VoteOptions: []byte{65, 66, 67},
+				VoteLogic:   protocol.VoteLogicStandard,
+				VoteMax:     3,
+				Ballots:     tt.ballots,
+			}
+
+			result := s.generateResult(c, vo)
+			vo.Result = &result
+
+			got, err := s.Outcome(c, vo)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%#+v\nwant\n%#+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchulzeWinners_draw(t *testing.T) {
+	options := []uint8{65, 66}
+
+	d := pairwisePreferences(options, []rankedBallot{
+		rankedBallot{ranking: []uint8{65, 66}, tokens: 10},
+		rankedBallot{ranking: []uint8{66, 65}, tokens: 10},
+	})
+
+	got := schulzeWinners(options, d)
+	want := []uint8{}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%#+v\nwant\n%#+v", got, want)
+	}
+}
@@ -0,0 +1,146 @@
+package vote
+
+import (
+	"errors"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+)
+
+var (
+	ErrHoldingTooSmall  = errors.New("Holding below minimum")
+	ErrHoldingTooRecent = errors.New("Holding acquired too recently")
+	ErrJurisdiction     = errors.New("Jurisdiction not allowed")
+)
+
+// EligibilityRule defines an interface for deciding if the voter of a
+// ballot is eligible to vote.
+//
+// Eligible is given the tokens the ballot counts for, and returns the reason
+// the voter is not eligible, or nil if they are.
+type EligibilityRule interface {
+	Eligible(contract.Contract, contract.Vote, contract.Ballot, uint64) error
+}
+
+// newEligibilityRules returns the EligibilityRule's configured on the
+// contract.
+func newEligibilityRules(c contract.Contract) []EligibilityRule {
+	e := c.Eligibility
+	rules := []EligibilityRule{}
+
+	if e.MinimumHolding > 0 {
+		rules = append(rules, minimumHoldingRule{minimum: e.MinimumHolding})
+	}
+
+	if e.MinimumHoldingAge > 0 {
+		rules = append(rules, holdingAgeRule{age: e.MinimumHoldingAge})
+	}
+
+	if len(e.Jurisdictions) > 0 {
+		rules = append(rules, jurisdictionRule{jurisdictions: e.Jurisdictions})
+	}
+
+	return rules
+}
+
+// checkEligibility returns the reason the voter of a ballot is not eligible
+// under the rules of the contract, or nil if they are.
+func checkEligibility(c contract.Contract,
+	vo contract.Vote,
+	ballot contract.Ballot,
+	tokens uint64) error {
+
+	for _, rule := range newEligibilityRules(c) {
+		if err := rule.Eligible(c, vo, ballot, tokens); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ineligibleVoters returns the reason each voter whose ballot was not
+// counted for being ineligible, keyed by address.
+func ineligibleVoters(c contract.Contract, vo contract.Vote) map[string]string {
+	ineligible := map[string]string{}
+
+	for _, ballot := range vo.Ballots {
+		if !vo.Countable(ballot) {
+			continue
+		}
+
+		tokens, ok := ballotTokens(c, vo, ballot)
+		if !ok {
+			continue
+		}
+
+		if err := checkEligibility(c, vo, ballot, tokens); err != nil {
+			ineligible[ballot.Address] = err.Error()
+		}
+	}
+
+	return ineligible
+}
+
+// minimumHoldingRule requires voters to hold a minimum number of tokens.
+type minimumHoldingRule struct {
+	minimum uint64
+}
+
+// Eligible implements the EligibilityRule interface.
+func (r minimumHoldingRule) Eligible(c contract.Contract,
+	vo contract.Vote,
+	ballot contract.Ballot,
+	tokens uint64) error {
+
+	if tokens < r.minimum {
+		return ErrHoldingTooSmall
+	}
+
+	return nil
+}
+
+// holdingAgeRule requires voters to have held the asset they vote with for
+// a minimum time before the vote was created, so tokens cannot be acquired
+// just to vote.
+type holdingAgeRule struct {
+	age int64
+}
+
+// Eligible implements the EligibilityRule interface.
+func (r holdingAgeRule) Eligible(c contract.Contract,
+	vo contract.Vote,
+	ballot contract.Ballot,
+	tokens uint64) error {
+
+	h, ok := c.Assets[ballot.AssetID].Holdings[ballot.Address]
+	if !ok || vo.CreatedAt-h.CreatedAt < r.age {
+		return ErrHoldingTooRecent
+	}
+
+	return nil
+}
+
+// jurisdictionRule requires voters to be in one of a set of jurisdictions.
+type jurisdictionRule struct {
+	jurisdictions []string
+}
+
+// Eligible implements the EligibilityRule interface.
+//
+// The jurisdiction of a voter is the one recorded for them as a holder of
+// the contract, on any of its assets.
+func (r jurisdictionRule) Eligible(c contract.Contract,
+	vo contract.Vote,
+	ballot contract.Ballot,
+	tokens uint64) error {
+
+	jurisdiction := c.HolderJurisdiction(ballot.Address)
+
+	for _, j := range r.jurisdictions {
+		if jurisdiction == j {
+			return nil
+		}
+	}
+
+	return ErrJurisdiction
+}
@@ -0,0 +1,124 @@
+package vote
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+func TestEligibility(t *testing.T) {
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+	issuerAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+
+	now := time.Now().UnixNano()
+	day := int64(24 * time.Hour)
+
+	holdings := map[string]contract.Holding{
+		issuerAddr: contract.Holding{
+			Address:      issuerAddr,
+			Balance:      100,
+			Jurisdiction: "AUS",
+			CreatedAt:    now - 30*day,
+		},
+		userAddr: contract.Holding{
+			Address:      userAddr,
+			Balance:      5,
+			Jurisdiction: "USA",
+			CreatedAt:    now - day,
+		},
+	}
+
+	ballots := []contract.Ballot{
+		contract.Ballot{
+			Address: issuerAddr,
+			AssetID: assetID,
+			Vote:    []byte{65},
+		},
+		contract.Ballot{
+			Address: userAddr,
+			AssetID: assetID,
+			Vote:    []byte{66},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		eligibility    contract.Eligibility
+		wantResult     contract.BallotResult
+		wantIneligible map[string]string
+	}{
+		{
+			name:           "no rules",
+			wantResult:     contract.BallotResult{65: 100, 66: 5},
+			wantIneligible: map[string]string{},
+		},
+		{
+			name: "minimum holding",
+			eligibility: contract.Eligibility{
+				MinimumHolding: 10,
+			},
+			wantResult: contract.BallotResult{65: 100},
+			wantIneligible: map[string]string{
+				userAddr: ErrHoldingTooSmall.Error(),
+			},
+		},
+		{
+			name: "holding age",
+			eligibility: contract.Eligibility{
+				MinimumHoldingAge: 7 * day,
+			},
+			wantResult: contract.BallotResult{65: 100},
+			wantIneligible: map[string]string{
+				userAddr: ErrHoldingTooRecent.Error(),
+			},
+		},
+		{
+			name: "jurisdiction",
+			eligibility: contract.Eligibility{
+				Jurisdictions: []string{"USA", "GBR"},
+			},
+			wantResult: contract.BallotResult{66: 5},
+			wantIneligible: map[string]string{
+				issuerAddr: ErrJurisdiction.Error(),
+			},
+		},
+	}
+
+	s := NewVoteService()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := contract.Contract{
+				Eligibility: tt.eligibility,
+				Assets: map[string]contract.Asset{
+					assetID: contract.Asset{
+						Holdings: holdings,
+					},
+				},
+			}
+
+			vo := contract.Vote{
+				AssetID:     assetID,
+				VoteOptions: []byte{65, 66},
+				VoteLogic:   protocol.VoteLogicStandard,
+				VoteMax:     1,
+				Ballots:     ballots,
+				CreatedAt:   now,
+			}
+
+			result := s.generateResult(c, vo)
+			if !reflect.DeepEqual(result, tt.wantResult) {
+				t.Errorf("got\n%#+v\nwant\n%#+v", result, tt.wantResult)
+			}
+
+			ineligible := ineligibleVoters(c, vo)
+			if !reflect.DeepEqual(ineligible, tt.wantIneligible) {
+				t.Errorf("got\n%#+v\nwant\n%#+v", ineligible, tt.wantIneligible)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package vote
+
+/**
+ * Notification Service
+ *
+ * What is my purpose?
+ * - You watch votes change as the contract state is written
+ * - You tell the Notifier's when votes open, close and are resulted
+ * - You tell the Notifier's when ballots are accepted or rejected
+ * - You keep quiet about contracts that have webhooks turned off
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/internal/app/state"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/internal/feature"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+const (
+	// NotificationPrefix is the storage path the progress of each Vote is
+	// written to.
+	NotificationPrefix = "notifications"
+)
+
+// ErrNotHolder is the reason given for rejecting a ballot from a voter
+// who does not hold the assets of the Vote.
+var ErrNotHolder = errors.New("Voter does not hold the assets of the vote")
+
+// notified is how far through a Vote the Notifier's have been told about.
+type notified struct {
+	Ballots  int  `json:"ballots"`
+	Closed   bool `json:"closed"`
+	Resulted bool `json:"resulted"`
+}
+
+// NotificationService emits an Event for each change to the votes of a
+// contract. It is registered as a state.Indexer.
+//
+// The progress of each Vote is stored, so no Event is emitted twice across
+// restarts. Progress is still stored while the webhooks feature of a
+// contract is disabled, so enabling it doesn't replay old events.
+//
+// Progress is also kept in memory, so storage is only read the first time
+// a Vote is seen, rather than on every write of the contract.
+//
+// Notifiers are told about Events while the contract is locked, so slow
+// ones, such as webhooks, should be wrapped in a QueuedNotifier.
+type NotificationService struct {
+	Storage   storage.ReadWriter
+	Votes     VoteService
+	Features  feature.FeatureService
+	Notifiers []Notifier
+
+	mu       *sync.Mutex
+	progress map[string]notified
+}
+
+func NewNotificationService(store storage.ReadWriter,
+	votes VoteService,
+	features feature.FeatureService,
+	notifiers ...Notifier) NotificationService {
+
+	return NotificationService{
+		Storage:   store,
+		Votes:     votes,
+		Features:  features,
+		Notifiers: notifiers,
+		mu:        &sync.Mutex{},
+		progress:  map[string]notified{},
+	}
+}
+
+// Index implements the state.Indexer interface.
+func (s NotificationService) Index(ctx context.Context, e state.Event) error {
+	if len(s.Notifiers) == 0 {
+		return nil
+	}
+
+	c := e.Contract
+	now := time.Unix(0, e.Timestamp)
+
+	deliver := s.Features.Enabled(ctx, c.ID, feature.FlagWebhooks)
+
+	for id, vo := range c.Votes {
+		path := s.buildPath(c.ID, id)
+
+		n, opened, err := s.read(ctx, path)
+		if err != nil {
+			return err
+		}
+
+		events := []Event{}
+
+		newEvent := func(t EventType) Event {
+			return Event{
+				Type:       t,
+				ContractID: c.ID,
+				VoteID:     id,
+				TxID:       e.TxID,
+				Timestamp:  e.Timestamp,
+			}
+		}
+
+		if !opened {
+			events = append(events, newEvent(EventVoteOpened))
+		}
+
+		for i := n.Ballots; i < len(vo.Ballots); i++ {
+			ballot := vo.Ballots[i]
+
+			ev := newEvent(EventBallotAccepted)
+			ev.Ballot = &ballot
+
+			if err := ballotRejection(c, vo, ballot); err != nil {
+				ev.Type = EventBallotRejected
+				ev.Reason = err.Error()
+			}
+
+			events = append(events, ev)
+		}
+
+		n.Ballots = len(vo.Ballots)
+
+		if !n.Closed && !vo.IsOpen(now) {
+			events = append(events, newEvent(EventVoteClosed))
+			n.Closed = true
+		}
+
+		if !n.Resulted && vo.Result != nil {
+			ev := newEvent(EventResultPublished)
+
+			if o, err := s.Votes.Outcome(c, vo); err == nil {
+				ev.Outcome = &o
+			}
+
+			events = append(events, ev)
+			n.Resulted = true
+		}
+
+		if len(events) == 0 {
+			continue
+		}
+
+		if deliver {
+			s.notify(ctx, events)
+		}
+
+		b, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+
+		if err := s.Storage.Write(ctx, path, b, nil); err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		s.progress[path] = n
+		s.mu.Unlock()
+	}
+
+	return nil
+}
+
+// notify passes the events to each of the Notifier's.
+//
+// A failing Notifier is logged, so it does not stop the others being told.
+func (s NotificationService) notify(ctx context.Context, events []Event) {
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	for _, e := range events {
+		for _, n := range s.Notifiers {
+			if err := n.Notify(ctx, e); err != nil {
+				log.Errorf("Failed to notify %v of vote %v : %v",
+					e.Type, e.VoteID, err)
+			}
+		}
+	}
+}
+
+// read returns the progress stored for a Vote, and false if nothing has
+// been stored.
+func (s NotificationService) read(ctx context.Context,
+	path string) (notified, bool, error) {
+
+	s.mu.Lock()
+	n, ok := s.progress[path]
+	s.mu.Unlock()
+
+	if ok {
+		return n, true, nil
+	}
+
+	b, err := s.Storage.Read(ctx, path)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return n, false, nil
+		}
+
+		return n, false, err
+	}
+
+	if err := json.Unmarshal(b, &n); err != nil {
+		return n, false, err
+	}
+
+	s.mu.Lock()
+	s.progress[path] = n
+	s.mu.Unlock()
+
+	return n, true, nil
+}
+
+func (s NotificationService) buildPath(contractID, voteID string) string {
+	return fmt.Sprintf("%v/%v/%v", NotificationPrefix, contractID, voteID)
+}
+
+// ballotRejection returns the reason a ballot cannot be counted for the
+// vote, or nil if it can.
+func ballotRejection(c contract.Contract,
+	vo contract.Vote,
+	ballot contract.Ballot) error {
+
+	tokens, ok := ballotTokens(c, vo, ballot)
+	if !ok {
+		return ErrNotHolder
+	}
+
+	return checkEligibility(c, vo, ballot, tokens)
+}
@@ -0,0 +1,341 @@
+package vote
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/state"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/internal/feature"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+type memoryStorage map[string][]byte
+
+func (m memoryStorage) Read(ctx context.Context, key string) ([]byte, error) {
+	b, ok := m[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	return b, nil
+}
+
+func (m memoryStorage) Write(ctx context.Context,
+	key string,
+	body []byte,
+	opts *storage.Options) error {
+
+	m[key] = body
+	return nil
+}
+
+// countingStorage counts the reads of a memoryStorage.
+type countingStorage struct {
+	memoryStorage
+	reads *int
+}
+
+func (c countingStorage) Read(ctx context.Context, key string) ([]byte, error) {
+	*c.reads++
+	return c.memoryStorage.Read(ctx, key)
+}
+
+type testNotifier struct {
+	events *[]EventType
+}
+
+func (n testNotifier) Notify(ctx context.Context, e Event) error {
+	*n.events = append(*n.events, e.Type)
+	return nil
+}
+
+func TestNotificationService(t *testing.T) {
+	ctx := context.Background()
+
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+	issuerAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+
+	now := time.Now().UnixNano()
+
+	vo := contract.Vote{
+		AssetID:             assetID,
+		VoteOptions:         []byte{65, 66},
+		VoteLogic:           '0',
+		VoteMax:             1,
+		VoteCutOffTimestamp: now + int64(time.Hour),
+		Ballots:             []contract.Ballot{},
+	}
+
+	c := contract.Contract{
+		ID: "1JJVSnXVHRDmPbPgBLMFUgJ27zfUHbRPnq",
+		Assets: map[string]contract.Asset{
+			assetID: contract.Asset{
+				Holdings: map[string]contract.Holding{
+					issuerAddr: contract.Holding{
+						Address: issuerAddr,
+						Balance: 10,
+					},
+				},
+			},
+		},
+		Votes: map[string]contract.Vote{
+			"vote": vo,
+		},
+	}
+
+	events := []EventType{}
+
+	store := memoryStorage{}
+
+	s := NewNotificationService(store,
+		NewVoteService(),
+		feature.NewFeatureService(store),
+		testNotifier{events: &events})
+
+	accepted := contract.Ballot{
+		Address: issuerAddr,
+		AssetID: assetID,
+		Vote:    []byte{65},
+	}
+
+	rejected := contract.Ballot{
+		Address: userAddr,
+		AssetID: assetID,
+		Vote:    []byte{66},
+	}
+
+	steps := []struct {
+		name   string
+		update func(*contract.Vote)
+		at     int64
+		want   []EventType
+	}{
+		{
+			name:   "opened",
+			update: func(vo *contract.Vote) {},
+			at:     now,
+			want:   []EventType{EventVoteOpened},
+		},
+		{
+			name: "ballots",
+			update: func(vo *contract.Vote) {
+				vo.Ballots = append(vo.Ballots, accepted, rejected)
+			},
+			at:   now,
+			want: []EventType{EventBallotAccepted, EventBallotRejected},
+		},
+		{
+			name:   "no change",
+			update: func(vo *contract.Vote) {},
+			at:     now,
+			want:   []EventType{},
+		},
+		{
+			name: "webhooks disabled",
+			update: func(vo *contract.Vote) {
+				s.Features.Set(ctx, c.ID, feature.FlagWebhooks, false)
+				vo.Ballots = append(vo.Ballots, accepted)
+			},
+			at:   now,
+			want: []EventType{},
+		},
+		{
+			name: "webhooks enabled",
+			update: func(vo *contract.Vote) {
+				s.Features.Set(ctx, c.ID, feature.FlagWebhooks, true)
+			},
+			at:   now,
+			want: []EventType{},
+		},
+		{
+			name: "closed and resulted",
+			update: func(vo *contract.Vote) {
+				result := contract.BallotResult{65: 10}
+				vo.Result = &result
+			},
+			at:   now + 2*int64(time.Hour),
+			want: []EventType{EventVoteClosed, EventResultPublished},
+		},
+	}
+
+	for _, step := range steps {
+		events = events[:0]
+
+		vo := c.Votes["vote"]
+		step.update(&vo)
+		c.Votes["vote"] = vo
+
+		e := state.Event{
+			ContractID: c.ID,
+			Contract:   c,
+			Timestamp:  step.at,
+		}
+
+		if err := s.Index(ctx, e); err != nil {
+			t.Fatalf("%v : %v", step.name, err)
+		}
+
+		if !reflect.DeepEqual(events, step.want) {
+			t.Errorf("%v : got\n%#+v\nwant\n%#+v", step.name, events, step.want)
+		}
+	}
+}
+
+func TestNotificationService_progress(t *testing.T) {
+	ctx := context.Background()
+
+	c := contract.Contract{
+		ID: "1JJVSnXVHRDmPbPgBLMFUgJ27zfUHbRPnq",
+		Votes: map[string]contract.Vote{
+			"vote": contract.Vote{
+				VoteCutOffTimestamp: time.Now().Add(time.Hour).UnixNano(),
+			},
+		},
+	}
+
+	e := state.Event{
+		ContractID: c.ID,
+		Contract:   c,
+		Timestamp:  time.Now().UnixNano(),
+	}
+
+	reads := 0
+	store := countingStorage{memoryStorage: memoryStorage{}, reads: &reads}
+	events := []EventType{}
+
+	newService := func() NotificationService {
+		return NewNotificationService(store,
+			NewVoteService(),
+			feature.NewFeatureService(memoryStorage{}),
+			testNotifier{events: &events})
+	}
+
+	s := newService()
+
+	for i := 0; i < 3; i++ {
+		if err := s.Index(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if reads != 1 {
+		t.Errorf("got %v reads of progress, want 1", reads)
+	}
+
+	// progress survives a restart, so nothing is emitted twice
+	events = events[:0]
+
+	if err := newService().Index(ctx, e); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 0 {
+		t.Errorf("got events %v after restart, want none", events)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	received := make(chan Event, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+
+		e := Event{}
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		received <- e
+	}))
+	defer server.Close()
+
+	want := Event{
+		Type:       EventVoteOpened,
+		ContractID: "1JJVSnXVHRDmPbPgBLMFUgJ27zfUHbRPnq",
+		VoteID:     "vote",
+		Timestamp:  1,
+	}
+
+	n := NewWebhookNotifier(server.URL)
+
+	if err := n.Notify(context.Background(), want); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := <-received; !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%#+v\nwant\n%#+v", got, want)
+	}
+}
+
+// blockingNotifier passes each Event on to a channel.
+type blockingNotifier struct {
+	events chan Event
+}
+
+func (n blockingNotifier) Notify(ctx context.Context, e Event) error {
+	select {
+	case n.events <- e:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestQueuedNotifier(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	delivered := make(chan Event)
+	q := NewQueuedNotifier(blockingNotifier{events: delivered}, 2)
+
+	// the queue takes events while nothing delivers them
+	for _, id := range []string{"a", "b"} {
+		if err := q.Notify(ctx, Event{VoteID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := q.Notify(ctx, Event{VoteID: "c"}); err != ErrQueueFull {
+		t.Fatalf("got %v, want %v", err, ErrQueueFull)
+	}
+
+	go q.Run(ctx)
+
+	for _, want := range []string{"a", "b"} {
+		select {
+		case e := <-delivered:
+			if e.VoteID != want {
+				t.Errorf("got %v, want %v", e.VoteID, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%v not delivered", want)
+		}
+	}
+}
+
+func TestWebhookNotifier_timeout(t *testing.T) {
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	n := NewWebhookNotifier(server.URL)
+	n.Client.Timeout = 50 * time.Millisecond
+
+	if err := n.Notify(context.Background(), Event{}); err == nil {
+		t.Fatal("got no error, want a timeout")
+	}
+}
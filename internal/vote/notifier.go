@@ -0,0 +1,154 @@
+package vote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/logger"
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+)
+
+const (
+	// webhookTimeout is how long a webhook has to respond to an Event.
+	webhookTimeout = 10 * time.Second
+)
+
+// ErrQueueFull is returned when an Event can't be queued, because the
+// Notifier has fallen too far behind.
+var ErrQueueFull = errors.New("Notification queue is full")
+
+// EventType identifies what happened to a Vote.
+type EventType string
+
+const (
+	// EventVoteOpened is emitted when a Vote is created.
+	EventVoteOpened EventType = "VoteOpened"
+
+	// EventBallotAccepted is emitted when a ballot is counted for a Vote.
+	EventBallotAccepted EventType = "BallotAccepted"
+
+	// EventBallotRejected is emitted when a ballot cannot be counted for a
+	// Vote, with the reason.
+	EventBallotRejected EventType = "BallotRejected"
+
+	// EventVoteClosed is emitted when the cut off time of a Vote has passed.
+	EventVoteClosed EventType = "VoteClosed"
+
+	// EventResultPublished is emitted when a Vote is resulted, with the
+	// outcome.
+	EventResultPublished EventType = "ResultPublished"
+)
+
+// Event is a change to a Vote that Notifier's are told about.
+type Event struct {
+	Type       EventType        `json:"type"`
+	ContractID string           `json:"contract_id"`
+	VoteID     string           `json:"vote_id"`
+	TxID       string           `json:"txid"`
+	Ballot     *contract.Ballot `json:"ballot,omitempty"`
+	Reason     string           `json:"reason,omitempty"`
+	Outcome    *VoteOutcome     `json:"outcome,omitempty"`
+	Timestamp  int64            `json:"timestamp"`
+}
+
+// Notifier is told about each Event, such as to pass it on to an external
+// service.
+type Notifier interface {
+	Notify(context.Context, Event) error
+}
+
+// WebhookNotifier posts each Event as JSON to a URL.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookNotifier returns a new WebhookNotifier posting to the URL.
+func NewWebhookNotifier(url string) WebhookNotifier {
+	return WebhookNotifier{
+		URL:    url,
+		Client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Notify implements the Notifier interface.
+//
+// A response other than 2xx is returned as an error.
+func (n WebhookNotifier) Notify(ctx context.Context, e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := n.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("Webhook %v returned %v", n.URL, res.Status)
+	}
+
+	return nil
+}
+
+// QueuedNotifier passes each Event on to a Notifier from a queue, so the
+// caller is not held up by a slow or unreachable Notifier. The Events are
+// delivered, in order, while Run is running.
+type QueuedNotifier struct {
+	Notifier Notifier
+	queue    chan Event
+}
+
+// NewQueuedNotifier returns a new QueuedNotifier, queueing up to size
+// Events for the Notifier.
+func NewQueuedNotifier(n Notifier, size int) QueuedNotifier {
+	return QueuedNotifier{
+		Notifier: n,
+		queue:    make(chan Event, size),
+	}
+}
+
+// Notify implements the Notifier interface, queueing the Event.
+//
+// The Event is dropped, and ErrQueueFull returned, if the queue is full.
+func (q QueuedNotifier) Notify(ctx context.Context, e Event) error {
+	select {
+	case q.queue <- e:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run delivers the queued Events until the Context is done. A failed
+// delivery is logged, and not retried.
+func (q QueuedNotifier) Run(ctx context.Context) {
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case e := <-q.queue:
+			if err := q.Notifier.Notify(ctx, e); err != nil {
+				log.Errorf("Failed to deliver %v of vote %v : %v",
+					e.Type, e.VoteID, err)
+			}
+		}
+	}
+}
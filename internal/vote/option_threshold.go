@@ -0,0 +1,69 @@
+package vote
+
+import (
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+)
+
+// support is the backing an option received in a vote.
+type support struct {
+	ballots uint64
+	tokens  uint64
+}
+
+// optionSupport returns the number of ballots choosing each option, and the
+// tokens held by those voters.
+//
+// Unlike the result of a vote, the tokens are not weighted by the position
+// of the choice on the ballot.
+func optionSupport(c contract.Contract,
+	vo contract.Vote) map[uint8]support {
+
+	supports := map[uint8]support{}
+
+	for _, cb := range countBallots(c, vo) {
+		if vo.IsAbstention(cb.ballot) {
+			continue
+		}
+
+		values := cb.ballot.Vote
+		if len(values) > int(vo.VoteMax) {
+			values = values[:vo.VoteMax]
+		}
+
+		for _, val := range values {
+			s := supports[val]
+			s.ballots++
+			s.tokens += cb.tokens
+			supports[val] = s
+		}
+	}
+
+	return supports
+}
+
+// meetsThresholds returns the winners that attracted the minimum support
+// required by the thresholds of the vote.
+func meetsThresholds(c contract.Contract,
+	vo contract.Vote,
+	winners []uint8) []uint8 {
+
+	if len(vo.Thresholds) == 0 || len(winners) == 0 {
+		return winners
+	}
+
+	supports := optionSupport(c, vo)
+	met := []uint8{}
+
+	for _, option := range winners {
+		threshold := vo.Thresholds[option]
+		s := supports[option]
+
+		if s.ballots < threshold.Ballots || s.tokens < threshold.Tokens {
+			continue
+		}
+
+		met = append(met, option)
+	}
+
+	return met
+}
@@ -0,0 +1,125 @@
+package vote
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+func TestVotingSystem_Winners_thresholds(t *testing.T) {
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+	issuerAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	otherUserAddr := "1DnoezsMcKZeQrXVW7eqU5v8HRKmnPSYd2"
+
+	c := contract.Contract{
+		Assets: map[string]contract.Asset{
+			assetID: contract.Asset{
+				Holdings: map[string]contract.Holding{
+					issuerAddr: contract.Holding{
+						Address: issuerAddr,
+						Balance: 700,
+					},
+					userAddr: contract.Holding{
+						Address: userAddr,
+						Balance: 200,
+					},
+					otherUserAddr: contract.Holding{
+						Address: otherUserAddr,
+						Balance: 100,
+					},
+				},
+			},
+		},
+	}
+
+	// A wins with 700 tokens from a single ballot, B has 300 tokens from two
+	ballots := []contract.Ballot{
+		contract.Ballot{
+			Address: issuerAddr,
+			AssetID: assetID,
+			Vote:    []byte{65},
+		},
+		contract.Ballot{
+			Address: userAddr,
+			AssetID: assetID,
+			Vote:    []byte{66},
+		},
+		contract.Ballot{
+			Address: otherUserAddr,
+			AssetID: assetID,
+			Vote:    []byte{66},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		system     byte
+		thresholds map[uint8]contract.OptionThreshold
+		want       []uint8
+	}{
+		{
+			name:   "no thresholds",
+			system: protocol.VotingSystemPlurality,
+			want:   []uint8{65},
+		},
+		{
+			name:   "token threshold met",
+			system: protocol.VotingSystemPlurality,
+			thresholds: map[uint8]contract.OptionThreshold{
+				65: contract.OptionThreshold{Tokens: 700},
+			},
+			want: []uint8{65},
+		},
+		{
+			name:   "token threshold not met",
+			system: protocol.VotingSystemPlurality,
+			thresholds: map[uint8]contract.OptionThreshold{
+				65: contract.OptionThreshold{Tokens: 1000},
+			},
+			want: []uint8{},
+		},
+		{
+			name:   "ballot threshold not met",
+			system: protocol.VotingSystemRelativeMajority,
+			thresholds: map[uint8]contract.OptionThreshold{
+				65: contract.OptionThreshold{Ballots: 2},
+			},
+			want: []uint8{},
+		},
+		{
+			name:   "threshold on another option",
+			system: protocol.VotingSystemAbsoluteMajority,
+			thresholds: map[uint8]contract.OptionThreshold{
+				66: contract.OptionThreshold{Ballots: 3},
+			},
+			want: []uint8{65},
+		},
+	}
+
+	s := NewVoteService()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vo := contract.Vote{
+				AssetID:     assetID,
+				VoteOptions: []byte{65, 66},
+				VoteLogic:   protocol.VoteLogicStandard,
+				VoteMax:     1,
+				Thresholds:  tt.thresholds,
+				Ballots:     ballots,
+			}
+
+			result := s.generateResult(c, vo)
+			vo.Result = &result
+
+			got := s.votingSystems[tt.system].Winners(c, vo).Winners
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%#+v\nwant\n%#+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package vote
+
+import (
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+)
+
+// rankedBallot is a counted ballot read as a ranking of the options of a
+// vote, from most to least preferred.
+type rankedBallot struct {
+	ranking []uint8
+	tokens  uint64
+}
+
+// rank returns the position of an option in the ranking, or the length of
+// the ranking if the option was not ranked. Unranked options are preferred
+// equally, below all ranked options.
+func (b rankedBallot) rank(option uint8) int {
+	for i, o := range b.ranking {
+		if o == option {
+			return i
+		}
+	}
+
+	return len(b.ranking)
+}
+
+// rankedBallots returns the counted ballots of the vote as rankings.
+//
+// The choices of a ballot are its ranking, in order, up to the VoteMax of
+// the vote. Choices that are not options of the vote, or that repeat an
+// earlier choice, are discarded.
+// Abstentions rank no options and are not returned.
+func rankedBallots(c contract.Contract, vo contract.Vote) []rankedBallot {
+	ranked := []rankedBallot{}
+
+	for _, cb := range countBallots(c, vo) {
+		if vo.IsAbstention(cb.ballot) {
+			continue
+		}
+
+		rb := rankedBallot{
+			ranking: []uint8{},
+			tokens:  cb.tokens,
+		}
+
+		choices := cb.ballot.Vote
+		if vo.VoteMax > 0 && len(choices) > int(vo.VoteMax) {
+			choices = choices[:vo.VoteMax]
+		}
+
+		seen := map[uint8]bool{}
+
+		for _, choice := range choices {
+			if seen[choice] || !containsOption(vo.VoteOptions, choice) {
+				continue
+			}
+
+			seen[choice] = true
+			rb.ranking = append(rb.ranking, choice)
+		}
+
+		if len(rb.ranking) == 0 {
+			continue
+		}
+
+		ranked = append(ranked, rb)
+	}
+
+	return ranked
+}
+
+// rankedOptions returns the options of the vote that can be ranked, which
+// excludes the abstain option.
+func rankedOptions(vo contract.Vote) []uint8 {
+	options := []uint8{}
+
+	for _, option := range vo.VoteOptions {
+		if vo.AbstainOption != 0 && option == vo.AbstainOption {
+			continue
+		}
+
+		options = append(options, option)
+	}
+
+	return options
+}
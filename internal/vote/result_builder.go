@@ -0,0 +1,122 @@
+package vote
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/internal/app/wallet"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+const (
+	// maxResultTallies is the number of option tallies a Result can hold.
+	maxResultTallies = 15
+
+	// resultWinnersLen is the fixed length of the winners in a Result.
+	resultWinnersLen = 16
+)
+
+var (
+	// ErrNoResult is returned when building a Result for a Vote that has
+	// not been counted.
+	ErrNoResult = errors.New("Vote has no result")
+
+	// ErrNoVoteUTXO is returned when there are no UTXO's to fund a Result.
+	ErrNoVoteUTXO = errors.New("No UTXO to fund the result")
+)
+
+// NewResultMessage returns the Result action for a counted Vote.
+//
+// The tallies are given in the order of the VoteOptions, and the winners are
+// padded to the fixed length of the Result.
+func NewResultMessage(vo contract.Vote) protocol.Result {
+	m := protocol.NewResult()
+	m.AssetType = []byte(vo.AssetType)
+	m.AssetID = []byte(vo.AssetID)
+	m.VoteType = vo.VoteType
+	m.VoteTxnID = []byte(vo.RefTxnIDHash)
+	m.Timestamp = uint64(time.Now().Unix())
+
+	if vo.Result != nil {
+		tallies := []*uint64{
+			&m.Option1Tally, &m.Option2Tally, &m.Option3Tally,
+			&m.Option4Tally, &m.Option5Tally, &m.Option6Tally,
+			&m.Option7Tally, &m.Option8Tally, &m.Option9Tally,
+			&m.Option10Tally, &m.Option11Tally, &m.Option12Tally,
+			&m.Option13Tally, &m.Option14Tally, &m.Option15Tally,
+		}
+
+		result := *vo.Result
+
+		for i, option := range vo.VoteOptions {
+			if i == maxResultTallies {
+				break
+			}
+
+			*tallies[i] = result[option]
+		}
+	}
+
+	winners := vo.Winners
+	if winners == nil {
+		winners = Winners(vo)
+	}
+
+	m.Result = make([]byte, resultWinnersLen, resultWinnersLen)
+	copy(m.Result, winners)
+
+	return m
+}
+
+// ResultBuilder builds the TX publishing the Result of a Vote.
+type ResultBuilder struct {
+	Wallet wallet.WalletInterface
+}
+
+// NewResultBuilder returns a new ResultBuilder.
+func NewResultBuilder(w wallet.WalletInterface) ResultBuilder {
+	return ResultBuilder{
+		Wallet: w,
+	}
+}
+
+// Build returns a funded TX from the contract holding the Result of the
+// Vote, ready to be broadcast.
+//
+// The TX is funded by the UTXO's given, or the UTXO held by the Vote if
+// there are none. Any change is returned to the contract.
+func (b ResultBuilder) Build(ctx context.Context,
+	c contract.Contract,
+	vo contract.Vote,
+	utxos txbuilder.UTXOs) (*wire.MsgTx, error) {
+
+	if vo.Result == nil {
+		return nil, ErrNoResult
+	}
+
+	if len(utxos) == 0 {
+		if vo.UTXO.Value == 0 {
+			return nil, ErrNoVoteUTXO
+		}
+
+		utxos = txbuilder.UTXOs{vo.UTXO}
+	}
+
+	address, err := c.Address()
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewResultMessage(vo)
+
+	tx, err := b.Wallet.BuildContractTX(ctx, address, utxos, nil, address, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.MsgTx, nil
+}
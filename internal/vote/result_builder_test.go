@@ -0,0 +1,142 @@
+package vote
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/internal/app/wallet"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+	"github.com/tokenized/smart-contract/pkg/txbuilder"
+	"github.com/tokenized/smart-contract/pkg/txscript"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+)
+
+func TestNewResultMessage(t *testing.T) {
+	vo := contract.Vote{
+		AssetType:    "SHC",
+		AssetID:      "w840mxhrhupngqthd9quwtgsocaonv2f",
+		VoteType:     'C',
+		VoteOptions:  []byte{65, 66, 67},
+		RefTxnIDHash: "a9a1ed8b7ffb2a6c4f7e2d3c6a0c2f2b",
+		Result: &contract.BallotResult{
+			65: 5,
+			66: 15,
+			67: 10,
+		},
+	}
+
+	m := NewResultMessage(vo)
+
+	tallies := []uint64{m.Option1Tally, m.Option2Tally, m.Option3Tally,
+		m.Option4Tally}
+	wantTallies := []uint64{5, 15, 10, 0}
+
+	if !reflect.DeepEqual(tallies, wantTallies) {
+		t.Errorf("got tallies %v, want %v", tallies, wantTallies)
+	}
+
+	wantResult := make([]byte, 16, 16)
+	wantResult[0] = 66
+
+	if !bytes.Equal(m.Result, wantResult) {
+		t.Errorf("got result %v, want %v", m.Result, wantResult)
+	}
+
+	if string(m.VoteTxnID) != vo.RefTxnIDHash {
+		t.Errorf("got vote txn id %s, want %s", m.VoteTxnID, vo.RefTxnIDHash)
+	}
+}
+
+func TestResultBuilder_Build(t *testing.T) {
+	ctx := context.Background()
+
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wif, err := btcutil.NewWIF(key, &chaincfg.MainNetParams, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := wallet.NewWallet(wif.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address, err := btcutil.DecodeAddress(w.PublicAddress,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := chainhash.NewHashFromStr("2c2786fe332e94ea61f2a0aef6037cd08bf6495f800a4c829c0f1c07e6104ab8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := contract.Contract{
+		ID: w.PublicAddress,
+	}
+
+	vo := contract.Vote{
+		VoteOptions: []byte{65, 66},
+		Result: &contract.BallotResult{
+			65: 5,
+		},
+		UTXO: txbuilder.UTXO{
+			Hash:     *hash,
+			Index:    0,
+			PkScript: pkScript,
+			Value:    10000,
+		},
+	}
+
+	b := NewResultBuilder(w)
+
+	tx, err := b.Build(ctx, c, vo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tx.TxIn) != 1 {
+		t.Fatalf("got %v inputs, want 1", len(tx.TxIn))
+	}
+
+	if tx.TxIn[0].PreviousOutPoint.Hash != *hash {
+		t.Errorf("got input %v, want %v", tx.TxIn[0].PreviousOutPoint.Hash, hash)
+	}
+
+	// change back to the contract, and the OP_RETURN
+	if len(tx.TxOut) != 2 {
+		t.Fatalf("got %v outputs, want 2", len(tx.TxOut))
+	}
+
+	m, err := protocol.New(tx.TxOut[1].PkScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := m.(*protocol.Result); !ok {
+		t.Errorf("got message %T, want *protocol.Result", m)
+	}
+
+	// a vote that has not been counted has no result to publish
+	vo.Result = nil
+	if _, err := b.Build(ctx, c, vo, nil); err != ErrNoResult {
+		t.Errorf("got error %v, want %v", err, ErrNoResult)
+	}
+}
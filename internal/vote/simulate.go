@@ -0,0 +1,61 @@
+package vote
+
+import (
+	"sort"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+)
+
+// Outcome is the outcome of a simulated Vote under a voting system.
+type Outcome struct {
+	VotingSystem byte
+	Result       contract.BallotResult
+	VoteOutcome
+
+	// Revote is true if the tie break policy of the contract would require
+	// the vote to be held again.
+	Revote bool
+}
+
+// Simulate returns the outcome of a Vote with the hypothetical ballots,
+// under each of the registered voting systems, ordered by voting system
+// code.
+//
+// Any ballots already cast on the Vote are replaced by the hypothetical
+// ballots. Neither the Contract nor the Vote are modified.
+func (v VoteService) Simulate(c contract.Contract,
+	vo contract.Vote,
+	ballots []contract.Ballot) ([]Outcome, error) {
+
+	vo.Ballots = ballots
+
+	result := v.generateResult(c, vo)
+	vo.Result = &result
+
+	codes := []byte{}
+	for code := range v.votingSystems {
+		codes = append(codes, code)
+	}
+
+	sort.Slice(codes, func(i, j int) bool {
+		return codes[i] < codes[j]
+	})
+
+	outcomes := []Outcome{}
+
+	for _, code := range codes {
+		o, err := v.breakTie(c, vo, v.votingSystems[code].Winners(c, vo))
+		if err != nil && err != ErrRevote {
+			return nil, err
+		}
+
+		outcomes = append(outcomes, Outcome{
+			VotingSystem: code,
+			Result:       result,
+			VoteOutcome:  o,
+			Revote:       err == ErrRevote,
+		})
+	}
+
+	return outcomes, nil
+}
@@ -0,0 +1,133 @@
+package vote
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+func TestVoteService_Simulate(t *testing.T) {
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+	issuerAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	otherUserAddr := "1DnoezsMcKZeQrXVW7eqU5v8HRKmnPSYd2"
+
+	c := contract.Contract{
+		Assets: map[string]contract.Asset{
+			assetID: contract.Asset{
+				Holdings: map[string]contract.Holding{
+					issuerAddr: contract.Holding{
+						Address: issuerAddr,
+						Balance: 15,
+					},
+					userAddr: contract.Holding{
+						Address: userAddr,
+						Balance: 5,
+					},
+					otherUserAddr: contract.Holding{
+						Address: otherUserAddr,
+						Balance: 20,
+					},
+				},
+			},
+		},
+	}
+
+	vo := contract.Vote{
+		AssetID:     assetID,
+		VoteOptions: []byte{89, 78},
+		VoteLogic:   '0',
+		VoteMax:     1,
+	}
+
+	// 75% of the votes cast, 37.5% of all tokens
+	ballots := []contract.Ballot{
+		contract.Ballot{
+			Address: issuerAddr,
+			AssetID: assetID,
+			Vote:    []byte{89},
+		},
+		contract.Ballot{
+			Address: userAddr,
+			AssetID: assetID,
+			Vote:    []byte{78},
+		},
+	}
+
+	result := contract.BallotResult{
+		89: 15,
+		78: 5,
+	}
+
+	want := []Outcome{
+		{
+			VotingSystem: protocol.VotingSystemAbsoluteMajority,
+			Result:       result,
+			VoteOutcome: VoteOutcome{
+				Winners: []uint8{},
+				Reason:  ReasonNoQuorum,
+			},
+		},
+		{
+			VotingSystem: protocol.VotingSystemCondorcet,
+			Result:       result,
+			VoteOutcome: VoteOutcome{
+				Winners:   []uint8{89},
+				Passed:    true,
+				QuorumMet: true,
+			},
+		},
+		{
+			VotingSystem: protocol.VotingSystemPlurality,
+			Result:       result,
+			VoteOutcome: VoteOutcome{
+				Winners:   []uint8{89},
+				Passed:    true,
+				QuorumMet: true,
+			},
+		},
+		{
+			VotingSystem: protocol.VotingSystemRelativeMajority,
+			Result:       result,
+			VoteOutcome: VoteOutcome{
+				Winners:   []uint8{89},
+				Passed:    true,
+				QuorumMet: true,
+			},
+		},
+		{
+			VotingSystem: protocol.VotingSystemRelativeSuperMajority,
+			Result:       result,
+			VoteOutcome: VoteOutcome{
+				Winners:   []uint8{89},
+				Passed:    true,
+				QuorumMet: true,
+			},
+		},
+		{
+			VotingSystem: protocol.VotingSystemAbsoluteSuperMajority,
+			Result:       result,
+			VoteOutcome: VoteOutcome{
+				Winners: []uint8{},
+				Reason:  ReasonNoQuorum,
+			},
+		},
+	}
+
+	s := NewVoteService()
+
+	got, err := s.Simulate(c, vo, ballots)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%#+v\nwant\n%#+v", got, want)
+	}
+
+	if len(vo.Ballots) != 0 {
+		t.Errorf("got %v ballots on the vote, want 0", len(vo.Ballots))
+	}
+}
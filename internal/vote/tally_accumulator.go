@@ -0,0 +1,156 @@
+package vote
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+// TallyAccumulator tallies the ballots of a Vote as they arrive, so the
+// result does not need to be counted from scratch when the Vote closes.
+//
+// Only the latest ballot of each voter is counted, or of each voter on each
+// asset unless the Vote counts the latest ballot only. A TallyAccumulator is
+// safe for concurrent use.
+type TallyAccumulator struct {
+	contract contract.Contract
+	vote     contract.Vote
+
+	mu      sync.Mutex
+	counted map[string]countedBallot
+	result  contract.BallotResult
+}
+
+// NewTallyAccumulator returns a new TallyAccumulator for the Vote, with no
+// ballots counted.
+//
+// The holdings of the Contract determine the tokens each ballot counts for.
+func NewTallyAccumulator(c contract.Contract,
+	vo contract.Vote) *TallyAccumulator {
+
+	return &TallyAccumulator{
+		contract: c,
+		vote:     vo,
+		counted:  map[string]countedBallot{},
+		result:   contract.NewBallotResult(),
+	}
+}
+
+// Add counts a ballot, replacing any ballot previously counted for the
+// voter. It returns false if the ballot cannot be counted, in which case any
+// earlier ballot of the voter is still counted.
+func (t *TallyAccumulator) Add(ballot contract.Ballot) bool {
+	tokens, ok := countBallot(t.contract, t.vote, ballot)
+	if !ok {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := voterKey(t.vote, ballot)
+
+	if previous, ok := t.counted[key]; ok {
+		t.subtract(previous)
+	}
+
+	cb := countedBallot{
+		ballot: ballot,
+		tokens: tokens,
+	}
+
+	for option, value := range t.values(cb) {
+		t.result[option] += value
+	}
+
+	t.counted[key] = cb
+
+	return true
+}
+
+// Remove stops counting a ballot, such as one that was never confirmed. It
+// returns false if the ballot is not the one counted for the voter.
+func (t *TallyAccumulator) Remove(ballot contract.Ballot) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := voterKey(t.vote, ballot)
+
+	cb, ok := t.counted[key]
+	if !ok || !reflect.DeepEqual(cb.ballot, ballot) {
+		return false
+	}
+
+	t.subtract(cb)
+	delete(t.counted, key)
+
+	return true
+}
+
+// Result returns a copy of the current tally.
+func (t *TallyAccumulator) Result() contract.BallotResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := contract.NewBallotResult()
+	for option, value := range t.result {
+		result[option] = value
+	}
+
+	return result
+}
+
+// subtract removes the values of a counted ballot from the tally. The
+// caller must hold the lock.
+func (t *TallyAccumulator) subtract(cb countedBallot) {
+	for option, value := range t.values(cb) {
+		t.result[option] -= value
+
+		if t.result[option] == 0 {
+			delete(t.result, option)
+		}
+	}
+}
+
+// values returns the value a counted ballot adds to each option.
+//
+// Choices that are not options of the vote are discarded.
+func (t *TallyAccumulator) values(cb countedBallot) contract.BallotResult {
+	vo := t.vote
+	values := contract.NewBallotResult()
+
+	if vo.IsAbstention(cb.ballot) {
+		// an abstention is recorded against the abstain option only
+		values[vo.AbstainOption] = cb.tokens
+		return values
+	}
+
+	// get the vote values the user sent
+	choices := cb.ballot.Vote
+	if len(choices) > int(vo.VoteMax) {
+		choices = choices[:vo.VoteMax]
+	}
+
+	for i, choice := range choices {
+		if !containsOption(vo.VoteOptions, choice) {
+			// the option that was voted for wasn't found
+			continue
+		}
+
+		// 0 - Standard Scoring (+1 * # of tokens owned),
+		// 1 - Weighted Scoring (1st choice * Vote Max * # of tokens held,
+		//     2nd choice * Vote Max-1 * # of tokens held,..etc.)
+		value := cb.tokens
+
+		if vo.VoteLogic == protocol.VoteLogicWeighted {
+			max := uint64(int(vo.VoteMax) - i)
+			value = max * cb.tokens
+		}
+
+		values[choice] += value
+	}
+
+	return values
+}
@@ -0,0 +1,186 @@
+package vote
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+)
+
+func TestTallyAccumulator(t *testing.T) {
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+	issuerAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+
+	c := contract.Contract{
+		Assets: map[string]contract.Asset{
+			assetID: contract.Asset{
+				Holdings: map[string]contract.Holding{
+					issuerAddr: contract.Holding{
+						Address: issuerAddr,
+						Balance: 15,
+					},
+					userAddr: contract.Holding{
+						Address: userAddr,
+						Balance: 5,
+					},
+				},
+			},
+		},
+	}
+
+	vo := contract.Vote{
+		AssetID:     assetID,
+		VoteOptions: []byte{65, 66},
+		VoteLogic:   '0',
+		VoteMax:     1,
+	}
+
+	issuerBallot := contract.Ballot{
+		Address: issuerAddr,
+		AssetID: assetID,
+		Vote:    []byte{65},
+	}
+
+	userBallot := contract.Ballot{
+		Address: userAddr,
+		AssetID: assetID,
+		Vote:    []byte{65},
+	}
+
+	changedBallot := contract.Ballot{
+		Address: userAddr,
+		AssetID: assetID,
+		Vote:    []byte{66},
+	}
+
+	wrongAssetBallot := contract.Ballot{
+		Address: userAddr,
+		AssetID: "FOO",
+		Vote:    []byte{65},
+	}
+
+	steps := []struct {
+		name   string
+		add    *contract.Ballot
+		remove *contract.Ballot
+		ok     bool
+		want   contract.BallotResult
+	}{
+		{
+			name: "add",
+			add:  &issuerBallot,
+			ok:   true,
+			want: contract.BallotResult{65: 15},
+		},
+		{
+			name: "add another voter",
+			add:  &userBallot,
+			ok:   true,
+			want: contract.BallotResult{65: 20},
+		},
+		{
+			name: "replace ballot",
+			add:  &changedBallot,
+			ok:   true,
+			want: contract.BallotResult{65: 15, 66: 5},
+		},
+		{
+			name: "ballot that cannot be counted",
+			add:  &wrongAssetBallot,
+			ok:   false,
+			want: contract.BallotResult{65: 15, 66: 5},
+		},
+		{
+			name:   "remove replaced ballot",
+			remove: &userBallot,
+			ok:     false,
+			want:   contract.BallotResult{65: 15, 66: 5},
+		},
+		{
+			name:   "remove",
+			remove: &changedBallot,
+			ok:     true,
+			want:   contract.BallotResult{65: 15},
+		},
+	}
+
+	acc := NewTallyAccumulator(c, vo)
+
+	for _, step := range steps {
+		var ok bool
+		if step.add != nil {
+			ok = acc.Add(*step.add)
+		} else {
+			ok = acc.Remove(*step.remove)
+		}
+
+		if ok != step.ok {
+			t.Errorf("%v : got %v, want %v", step.name, ok, step.ok)
+		}
+
+		if got := acc.Result(); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%v : got\n%#+v\nwant\n%#+v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestTallyAccumulator_concurrent(t *testing.T) {
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+
+	holdings := map[string]contract.Holding{}
+	ballots := []contract.Ballot{}
+
+	for i := 0; i < 100; i++ {
+		address := fmt.Sprintf("voter%v", i)
+
+		holdings[address] = contract.Holding{
+			Address: address,
+			Balance: 1,
+		}
+
+		ballots = append(ballots, contract.Ballot{
+			Address: address,
+			AssetID: assetID,
+			Vote:    []byte{uint8(65 + i%2)},
+		})
+	}
+
+	c := contract.Contract{
+		Assets: map[string]contract.Asset{
+			assetID: contract.Asset{
+				Holdings: holdings,
+			},
+		},
+	}
+
+	vo := contract.Vote{
+		AssetID:     assetID,
+		VoteOptions: []byte{65, 66},
+		VoteLogic:   '0',
+		VoteMax:     1,
+	}
+
+	acc := NewTallyAccumulator(c, vo)
+
+	var wg sync.WaitGroup
+
+	for _, ballot := range ballots {
+		wg.Add(1)
+
+		go func(ballot contract.Ballot) {
+			defer wg.Done()
+			acc.Add(ballot)
+		}(ballot)
+	}
+
+	wg.Wait()
+
+	want := contract.BallotResult{65: 50, 66: 50}
+
+	if got := acc.Result(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%#+v\nwant\n%#+v", got, want)
+	}
+}
@@ -0,0 +1,173 @@
+package vote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+)
+
+const (
+	// TieBreakNone leaves a draw unresolved, reporting all tied options as
+	// winners.
+	TieBreakNone = ""
+
+	// TieBreakIssuer resolves a draw using the issuer's ballot as a casting
+	// vote.
+	TieBreakIssuer = "issuer"
+
+	// TieBreakEarliest resolves a draw in favour of the tied option that
+	// appears first in the vote options.
+	TieBreakEarliest = "earliest"
+
+	// TieBreakRevote resolves a draw by holding a new vote between the tied
+	// options.
+	TieBreakRevote = "revote"
+)
+
+// ErrRevote is returned by a TieBreak when the draw can only be resolved by
+// holding the vote again.
+var ErrRevote = errors.New("Vote must be held again")
+
+// TieBreak defines an interface for resolving a draw between options that
+// received the same tally.
+type TieBreak interface {
+	Break(contract.Contract, contract.Vote, []uint8) ([]uint8, error)
+}
+
+// newTieBreaks returns a mapping of tie break policies and TieBreak's.
+func newTieBreaks() map[string]TieBreak {
+	return map[string]TieBreak{
+		TieBreakIssuer:   issuerTieBreak{},
+		TieBreakEarliest: earliestTieBreak{},
+		TieBreakRevote:   revoteTieBreak{},
+	}
+}
+
+// issuerTieBreak uses the issuer's ballot as the casting vote.
+type issuerTieBreak struct{}
+
+// Break returns the first of the issuers choices that is one of the tied
+// options.
+//
+// If the issuer did not vote for any of the tied options, the draw is left
+// unresolved.
+func (t issuerTieBreak) Break(c contract.Contract,
+	vo contract.Vote,
+	tied []uint8) ([]uint8, error) {
+
+	for _, ballot := range vo.Ballots {
+		if !c.IsIssuer(ballot.Address) {
+			continue
+		}
+
+		for _, choice := range ballot.Vote {
+			if containsOption(tied, choice) {
+				return []uint8{choice}, nil
+			}
+		}
+	}
+
+	return tied, nil
+}
+
+// earliestTieBreak selects the tied option listed first in the vote.
+type earliestTieBreak struct{}
+
+// Break returns the tied option that appears first in the VoteOptions.
+func (t earliestTieBreak) Break(c contract.Contract,
+	vo contract.Vote,
+	tied []uint8) ([]uint8, error) {
+
+	for _, option := range vo.VoteOptions {
+		if containsOption(tied, option) {
+			return []uint8{option}, nil
+		}
+	}
+
+	return tied, nil
+}
+
+// revoteTieBreak requires the vote to be held again.
+type revoteTieBreak struct{}
+
+// Break always returns ErrRevote, as a new vote must be held between the
+// tied options.
+func (t revoteTieBreak) Break(c contract.Contract,
+	vo contract.Vote,
+	tied []uint8) ([]uint8, error) {
+
+	return nil, ErrRevote
+}
+
+// RevoteRef returns the reference of the Vote held again after the Vote
+// with the reference drew.
+//
+// It is derived from the reference of the drawn Vote, so holders can cast
+// their ballots on the revote, and each revote of a Vote has its own.
+func RevoteRef(ref string) string {
+	h := sha256.Sum256([]byte(TieBreakRevote + ":" + ref))
+	return hex.EncodeToString(h[:])
+}
+
+// newRevote returns a new Vote between the tied options of the drawn Vote
+// with the reference.
+//
+// The new Vote is open for the same length of time as the original Vote,
+// and is referenced by the RevoteRef of the original. It keeps the config
+// of the original, such as its abstain option, thresholds, Proposal and
+// commit-reveal ballots.
+func newRevote(ref string,
+	vo contract.Vote,
+	tied []uint8) contract.Vote {
+
+	options := append([]byte{}, tied...)
+	if vo.AbstainOption != 0 && !containsOption(options, vo.AbstainOption) {
+		options = append(options, vo.AbstainOption)
+	}
+
+	v := contract.NewVote()
+	v.RefTxnIDHash = RevoteRef(ref)
+	v.Address = vo.Address
+	v.AssetType = vo.AssetType
+	v.AssetID = vo.AssetID
+	v.AssetIDs = vo.AssetIDs
+	v.LatestBallotOnly = vo.LatestBallotOnly
+	v.VoteType = vo.VoteType
+	v.VoteOptions = options
+	v.VoteMax = 1
+	v.VoteLogic = vo.VoteLogic
+	v.AbstainOption = vo.AbstainOption
+	v.Thresholds = vo.Thresholds
+	v.ProposalDescription = vo.ProposalDescription
+	v.ProposalDocumentHash = vo.ProposalDocumentHash
+
+	if vo.Proposal != nil {
+		proposal := *vo.Proposal
+		v.Proposal = &proposal
+	}
+
+	if vo.CommitReveal != nil {
+		commitReveal := *vo.CommitReveal
+		v.CommitReveal = &commitReveal
+	}
+
+	period := vo.VoteCutOffTimestamp - vo.CreatedAt
+	v.VoteCutOffTimestamp = time.Now().UnixNano() + period
+
+	return v
+}
+
+// containsOption returns true if the option is in the list of options,
+// false otherwise.
+func containsOption(options []uint8, option uint8) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,255 @@
+package vote
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+)
+
+func TestWinners(t *testing.T) {
+	options := []byte{65, 66, 67, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+
+	tests := []struct {
+		name   string
+		result *contract.BallotResult
+		want   []uint8
+	}{
+		{
+			name:   "no result",
+			result: nil,
+			want:   []uint8{},
+		},
+		{
+			name:   "no votes",
+			result: &contract.BallotResult{},
+			want:   []uint8{},
+		},
+		{
+			name: "single winner",
+			result: &contract.BallotResult{
+				65: 5,
+				66: 15,
+			},
+			want: []uint8{66},
+		},
+		{
+			name: "draw",
+			result: &contract.BallotResult{
+				65: 15,
+				66: 5,
+				67: 15,
+			},
+			want: []uint8{65, 67},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vo := contract.Vote{
+				VoteOptions: options,
+				Result:      tt.result,
+			}
+
+			got := Winners(vo)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%#+v\nwant\n%#+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTieBreak_Break(t *testing.T) {
+	issuerAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+
+	c := contract.Contract{
+		IssuerAddress: issuerAddr,
+	}
+
+	vo := contract.Vote{
+		VoteOptions: []byte{65, 66, 67},
+		VoteMax:     2,
+		Ballots: []contract.Ballot{
+			contract.Ballot{
+				Address: userAddr,
+				Vote:    []byte{65, 67},
+			},
+			contract.Ballot{
+				Address: issuerAddr,
+				Vote:    []byte{66, 67},
+			},
+		},
+	}
+
+	tied := []uint8{65, 67}
+
+	tests := []struct {
+		name     string
+		tieBreak TieBreak
+		want     []uint8
+		err      error
+	}{
+		{
+			name:     "issuer casting vote",
+			tieBreak: issuerTieBreak{},
+			want:     []uint8{67},
+		},
+		{
+			name:     "earliest option",
+			tieBreak: earliestTieBreak{},
+			want:     []uint8{65},
+		},
+		{
+			name:     "revote",
+			tieBreak: revoteTieBreak{},
+			err:      ErrRevote,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.tieBreak.Break(c, vo, tied)
+			if err != tt.err {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%#+v\nwant\n%#+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVoteService_handle_revote(t *testing.T) {
+	ctx := context.Background()
+
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+	issuerAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+
+	now := time.Now().UnixNano()
+
+	c := contract.Contract{
+		IssuerAddress: issuerAddr,
+		TieBreak:      TieBreakRevote,
+		Assets: map[string]contract.Asset{
+			assetID: contract.Asset{
+				Holdings: map[string]contract.Holding{
+					issuerAddr: contract.Holding{
+						Address: issuerAddr,
+						Balance: 5,
+					},
+					userAddr: contract.Holding{
+						Address: userAddr,
+						Balance: 5,
+					},
+				},
+			},
+		},
+		Votes: map[string]contract.Vote{
+			"vote": contract.Vote{
+				AssetID:             assetID,
+				VoteOptions:         []byte{65, 66, 67},
+				VoteLogic:           '0',
+				VoteMax:             1,
+				VoteCutOffTimestamp: now - 1,
+				CreatedAt:           now - int64(time.Hour),
+				Ballots: []contract.Ballot{
+					contract.Ballot{
+						Address: issuerAddr,
+						AssetID: assetID,
+						Vote:    []byte{65},
+					},
+					contract.Ballot{
+						Address: userAddr,
+						AssetID: assetID,
+						Vote:    []byte{67},
+					},
+				},
+			},
+		},
+	}
+
+	s := NewVoteService()
+
+	votes, err := s.handle(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(votes) != 2 {
+		t.Fatalf("got %v votes, want 2", len(votes))
+	}
+
+	revote := votes[1]
+
+	if revote.Result != nil {
+		t.Errorf("got result %v, want nil", *revote.Result)
+	}
+
+	wantOptions := []byte{65, 67}
+	if !reflect.DeepEqual(revote.VoteOptions, wantOptions) {
+		t.Errorf("got options %v, want %v", revote.VoteOptions, wantOptions)
+	}
+
+	if !revote.IsOpen(time.Now()) {
+		t.Errorf("got closed revote, want open")
+	}
+
+	if revote.RefTxnIDHash != RevoteRef("vote") {
+		t.Fatalf("got revote ref %v, want %v", revote.RefTxnIDHash, RevoteRef("vote"))
+	}
+
+	// store the votes, as the result of the drawn vote is sent
+	c.Votes["vote"] = votes[0]
+	c.Votes[revote.RefTxnIDHash] = revote
+
+	if len(c.Votes) != 2 {
+		t.Fatalf("got %v votes stored, want 2", len(c.Votes))
+	}
+
+	// cast ballots on the revote, as the ballot cast handler does
+	for _, addr := range []string{issuerAddr, userAddr} {
+		address, err := btcutil.DecodeAddress(addr, &chaincfg.MainNetParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m := protocol.BallotCast{
+			AssetID:   []byte(assetID),
+			VoteTxnID: []byte(RevoteRef("vote")),
+			Vote:      []byte{67},
+		}
+
+		vo, ok := c.Votes[string(m.VoteTxnID)]
+		if !ok {
+			t.Fatalf("revote %v not found", string(m.VoteTxnID))
+		}
+
+		vo.Ballots = append(vo.Ballots, vo.NewBallot(address, &m, time.Now()))
+		vo.VoteCutOffTimestamp = time.Now().UnixNano() - 1
+		c.Votes[string(m.VoteTxnID)] = vo
+	}
+
+	votes, err = s.handle(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(votes) != 1 {
+		t.Fatalf("got %v votes, want 1", len(votes))
+	}
+
+	wantWinners := []byte{67}
+	if !reflect.DeepEqual(votes[0].Winners, wantWinners) {
+		t.Errorf("got winners %v, want %v", votes[0].Winners, wantWinners)
+	}
+}
@@ -0,0 +1,212 @@
+package vote
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/errs"
+)
+
+const (
+	// StatusOpen identifies a vote that is accepting ballots.
+	StatusOpen = "open"
+
+	// StatusClosed identifies a vote that has passed its cut off, but has
+	// not been resulted.
+	StatusClosed = "closed"
+
+	// StatusRevealing identifies a commit-reveal vote that has passed its
+	// cut off, and is accepting ballots that reveal a commitment.
+	StatusRevealing = "revealing"
+
+	// StatusResulted identifies a vote that has been resulted.
+	StatusResulted = "resulted"
+)
+
+// ErrVoteNotFound is returned when a contract has no vote with an ID.
+var ErrVoteNotFound = errs.New(errs.NotFound, "Vote not found")
+
+// VoteSummary describes a vote of a contract.
+type VoteSummary struct {
+	ID                  string   `json:"id"`
+	Status              string   `json:"status"`
+	AssetIDs            []string `json:"asset_ids,omitempty"`
+	VoteType            byte     `json:"vote_type"`
+	VoteOptions         []byte   `json:"vote_options"`
+	Ballots             int      `json:"ballots"`
+	VoteCutOffTimestamp int64    `json:"vote_cut_off_timestamp"`
+	Winners             []uint8  `json:"winners,omitempty"`
+}
+
+// OptionTotal is the running total of an option of a vote.
+type OptionTotal struct {
+	Option uint8  `json:"option"`
+	Tally  uint64 `json:"tally"`
+}
+
+// BallotDetail describes a ballot cast in a vote, and the weight it carries.
+//
+// An anonymized Voter is a keyed hash of the address, which is only the
+// same for ballots from the same voter in the same vote. Without the key,
+// it can't be matched to an address by hashing the known holders.
+type BallotDetail struct {
+	Voter     string `json:"voter"`
+	AssetID   string `json:"asset_id"`
+	Vote      []byte `json:"vote"`
+	Tokens    uint64 `json:"tokens"`
+	Counted   bool   `json:"counted"`
+	CreatedAt int64  `json:"created_at"`
+
+	// Commitment is the commitment of a ballot on a commit-reveal vote,
+	// which hides the choices until they are revealed.
+	Commitment string `json:"commitment,omitempty"`
+}
+
+// VoteStatus returns the status of a vote at the time.
+func VoteStatus(vo contract.Vote, ts time.Time) string {
+	if vo.Result != nil {
+		return StatusResulted
+	}
+
+	if vo.IsOpen(ts) {
+		return StatusOpen
+	}
+
+	if vo.IsRevealing(ts) {
+		return StatusRevealing
+	}
+
+	return StatusClosed
+}
+
+// List returns a summary of the votes of the contract, ordered by ID.
+//
+// If a status is given, only the votes with that status are returned.
+func (v VoteService) List(c contract.Contract, status string) []VoteSummary {
+	now := time.Now()
+	summaries := []VoteSummary{}
+
+	for id, vo := range c.Votes {
+		s := VoteStatus(vo, now)
+		if status != "" && s != status {
+			continue
+		}
+
+		summaries = append(summaries, VoteSummary{
+			ID:                  id,
+			Status:              s,
+			AssetIDs:            vo.Scope(),
+			VoteType:            vo.VoteType,
+			VoteOptions:         vo.VoteOptions,
+			Ballots:             len(vo.Ballots),
+			VoteCutOffTimestamp: vo.VoteCutOffTimestamp,
+			Winners:             vo.Winners,
+		})
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].ID < summaries[j].ID
+	})
+
+	return summaries
+}
+
+// Totals returns the tally of each option of a vote, in the order of the
+// vote options.
+//
+// A vote that has not been resulted is tallied from the ballots cast so
+// far.
+func (v VoteService) Totals(c contract.Contract,
+	id string) ([]OptionTotal, error) {
+
+	vo, ok := c.Votes[id]
+	if !ok {
+		return nil, ErrVoteNotFound
+	}
+
+	result := v.tally(c, vo)
+
+	totals := []OptionTotal{}
+
+	for _, option := range vo.VoteOptions {
+		totals = append(totals, OptionTotal{
+			Option: option,
+			Tally:  result[option],
+		})
+	}
+
+	return totals, nil
+}
+
+// BallotDetails returns the ballots cast in a vote, in the order they were
+// cast.
+//
+// A ballot is counted if it is the latest ballot of the voter on an asset
+// of the vote. If the key is set, the addresses of the voters are replaced
+// with a hash keyed with it.
+func (v VoteService) BallotDetails(c contract.Contract,
+	id string,
+	key []byte) ([]BallotDetail, error) {
+
+	vo, ok := c.Votes[id]
+	if !ok {
+		return nil, ErrVoteNotFound
+	}
+
+	counted := map[int]countedBallot{}
+	for _, cb := range countBallots(c, vo) {
+		counted[cb.index] = cb
+	}
+
+	details := []BallotDetail{}
+
+	for i, ballot := range vo.Ballots {
+		cb, isCounted := counted[i]
+
+		voter := ballot.Address
+		if len(key) > 0 {
+			voter = anonymizeVoter(key, id, ballot.Address)
+		}
+
+		d := BallotDetail{
+			Voter:      voter,
+			AssetID:    ballot.AssetID,
+			Vote:       ballot.Vote,
+			Counted:    isCounted,
+			CreatedAt:  ballot.CreatedAt,
+			Commitment: ballot.Commitment,
+		}
+
+		if isCounted {
+			d.Tokens = cb.tokens
+		}
+
+		details = append(details, d)
+	}
+
+	return details, nil
+}
+
+// tally returns the result of a vote, or the running result of a vote that
+// has not been resulted.
+func (v VoteService) tally(c contract.Contract,
+	vo contract.Vote) contract.BallotResult {
+
+	if vo.Result != nil {
+		return *vo.Result
+	}
+
+	return v.generateResult(c, vo)
+}
+
+// anonymizeVoter returns an HMAC identifying the voter within a vote.
+func anonymizeVoter(key []byte, id string, address string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "/" + address))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
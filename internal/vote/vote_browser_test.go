@@ -0,0 +1,144 @@
+package vote
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+)
+
+func TestVoteService_browse(t *testing.T) {
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+	issuerAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+
+	now := time.Now().UnixNano()
+
+	c := contract.Contract{
+		Assets: map[string]contract.Asset{
+			assetID: contract.Asset{
+				Holdings: map[string]contract.Holding{
+					issuerAddr: contract.Holding{
+						Address: issuerAddr,
+						Balance: 15,
+					},
+					userAddr: contract.Holding{
+						Address: userAddr,
+						Balance: 5,
+					},
+				},
+			},
+		},
+		Votes: map[string]contract.Vote{
+			"open": contract.Vote{
+				AssetID:             assetID,
+				VoteOptions:         []byte{65, 66},
+				VoteLogic:           '0',
+				VoteMax:             1,
+				VoteCutOffTimestamp: now + int64(time.Hour),
+				Ballots: []contract.Ballot{
+					contract.Ballot{
+						Address: userAddr,
+						AssetID: assetID,
+						Vote:    []byte{65},
+					},
+					contract.Ballot{
+						Address: issuerAddr,
+						AssetID: assetID,
+						Vote:    []byte{66},
+					},
+					// replaces the earlier ballot of the user
+					contract.Ballot{
+						Address: userAddr,
+						AssetID: assetID,
+						Vote:    []byte{66},
+					},
+				},
+			},
+			"resulted": contract.Vote{
+				AssetID:             assetID,
+				VoteOptions:         []byte{65, 66},
+				VoteCutOffTimestamp: now - int64(time.Hour),
+				Result: &contract.BallotResult{
+					65: 20,
+				},
+			},
+		},
+	}
+
+	s := NewVoteService()
+
+	summaries := s.List(c, StatusOpen)
+	if len(summaries) != 1 || summaries[0].ID != "open" {
+		t.Fatalf("got open votes %#+v, want [open]", summaries)
+	}
+
+	if got := len(s.List(c, "")); got != 2 {
+		t.Errorf("got %v votes, want 2", got)
+	}
+
+	totals, err := s.Totals(c, "open")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantTotals := []OptionTotal{
+		OptionTotal{Option: 65, Tally: 0},
+		OptionTotal{Option: 66, Tally: 20},
+	}
+
+	if !reflect.DeepEqual(totals, wantTotals) {
+		t.Errorf("got\n%#+v\nwant\n%#+v", totals, wantTotals)
+	}
+
+	details, err := s.BallotDetails(c, "open", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotCounted := []bool{}
+	for _, d := range details {
+		gotCounted = append(gotCounted, d.Counted)
+
+		if d.Voter == userAddr || d.Voter == issuerAddr {
+			t.Errorf("got voter %v, want anonymized", d.Voter)
+		}
+	}
+
+	wantCounted := []bool{false, true, true}
+	if !reflect.DeepEqual(gotCounted, wantCounted) {
+		t.Errorf("got counted %v, want %v", gotCounted, wantCounted)
+	}
+
+	if details[0].Voter != details[2].Voter {
+		t.Errorf("got different voters for ballots of the same address")
+	}
+
+	if details[2].Tokens != 5 {
+		t.Errorf("got %v tokens, want 5", details[2].Tokens)
+	}
+
+	// the voter can't be found without the key
+	other, err := s.BallotDetails(c, "open", []byte("guess"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if other[0].Voter == details[0].Voter {
+		t.Errorf("got the same voter with another key")
+	}
+
+	plain, err := s.BallotDetails(c, "open", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plain[0].Voter != userAddr {
+		t.Errorf("got voter %v, want %v", plain[0].Voter, userAddr)
+	}
+
+	if _, err := s.Totals(c, "missing"); err != ErrVoteNotFound {
+		t.Errorf("got error %v, want %v", err, ErrVoteNotFound)
+	}
+}
@@ -0,0 +1,80 @@
+package vote
+
+import (
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+)
+
+const (
+	// ReasonNoQuorum is given when too few tokens took part in the vote.
+	ReasonNoQuorum = "no quorum"
+
+	// ReasonThresholdNotReached is given when no option received enough of
+	// the vote to win under the voting system.
+	ReasonThresholdNotReached = "no option reached threshold"
+
+	// ReasonOptionThreshold is given when the winning options did not
+	// attract the minimum support required by the thresholds of the vote.
+	ReasonOptionThreshold = "option threshold not met"
+
+	// ReasonDraw is given when more than one option won.
+	ReasonDraw = "draw"
+)
+
+// VoteOutcome is the outcome of a resulted Vote under a voting system.
+type VoteOutcome struct {
+	// Winners are the winning options. There is more than one winner if the
+	// vote was a draw.
+	Winners []uint8
+
+	// Passed is true if the vote was decided with a single winner.
+	Passed bool
+
+	// QuorumMet is true if enough tokens took part in the vote for it to be
+	// decided.
+	QuorumMet bool
+
+	// Reason is the reason the vote did not pass, or empty if it passed.
+	Reason string
+}
+
+// newVoteOutcome returns the outcome of a vote, given whether the quorum
+// was met and the winners under the voting system.
+//
+// The winners must also meet the thresholds of the vote.
+func newVoteOutcome(c contract.Contract,
+	vo contract.Vote,
+	quorumMet bool,
+	winners []uint8) VoteOutcome {
+
+	o := VoteOutcome{
+		Winners:   []uint8{},
+		QuorumMet: quorumMet,
+	}
+
+	if !quorumMet {
+		o.Reason = ReasonNoQuorum
+		return o
+	}
+
+	if len(winners) == 0 {
+		o.Reason = ReasonThresholdNotReached
+		return o
+	}
+
+	met := meetsThresholds(c, vo, winners)
+	if len(met) == 0 {
+		o.Reason = ReasonOptionThreshold
+		return o
+	}
+
+	o.Winners = met
+
+	if len(met) > 1 {
+		o.Reason = ReasonDraw
+		return o
+	}
+
+	o.Passed = true
+
+	return o
+}
@@ -0,0 +1,165 @@
+package vote
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+func TestVotingSystem_Winners_outcome(t *testing.T) {
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+	issuerAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	otherUserAddr := "1DnoezsMcKZeQrXVW7eqU5v8HRKmnPSYd2"
+
+	c := contract.Contract{
+		Assets: map[string]contract.Asset{
+			assetID: contract.Asset{
+				Holdings: map[string]contract.Holding{
+					issuerAddr: contract.Holding{
+						Address: issuerAddr,
+						Balance: 10,
+					},
+					userAddr: contract.Holding{
+						Address: userAddr,
+						Balance: 10,
+					},
+					otherUserAddr: contract.Holding{
+						Address: otherUserAddr,
+						Balance: 20,
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		system     byte
+		ballots    []contract.Ballot
+		thresholds map[uint8]contract.OptionThreshold
+		want       VoteOutcome
+	}{
+		{
+			name:   "no ballots",
+			system: protocol.VotingSystemPlurality,
+			want: VoteOutcome{
+				Winners: []uint8{},
+				Reason:  ReasonNoQuorum,
+			},
+		},
+		{
+			name:   "draw",
+			system: protocol.VotingSystemPlurality,
+			ballots: []contract.Ballot{
+				contract.Ballot{
+					Address: issuerAddr,
+					AssetID: assetID,
+					Vote:    []byte{65},
+				},
+				contract.Ballot{
+					Address: userAddr,
+					AssetID: assetID,
+					Vote:    []byte{66},
+				},
+			},
+			want: VoteOutcome{
+				Winners:   []uint8{65, 66},
+				QuorumMet: true,
+				Reason:    ReasonDraw,
+			},
+		},
+		{
+			name:   "no majority",
+			system: protocol.VotingSystemRelativeSuperMajority,
+			ballots: []contract.Ballot{
+				contract.Ballot{
+					Address: issuerAddr,
+					AssetID: assetID,
+					Vote:    []byte{65},
+				},
+				contract.Ballot{
+					Address: otherUserAddr,
+					AssetID: assetID,
+					Vote:    []byte{66},
+				},
+			},
+			want: VoteOutcome{
+				Winners:   []uint8{},
+				QuorumMet: true,
+				Reason:    ReasonThresholdNotReached,
+			},
+		},
+		{
+			name:   "option threshold",
+			system: protocol.VotingSystemAbsoluteMajority,
+			ballots: []contract.Ballot{
+				contract.Ballot{
+					Address: otherUserAddr,
+					AssetID: assetID,
+					Vote:    []byte{66},
+				},
+				contract.Ballot{
+					Address: userAddr,
+					AssetID: assetID,
+					Vote:    []byte{66},
+				},
+			},
+			thresholds: map[uint8]contract.OptionThreshold{
+				66: contract.OptionThreshold{Ballots: 3},
+			},
+			want: VoteOutcome{
+				Winners:   []uint8{},
+				QuorumMet: true,
+				Reason:    ReasonOptionThreshold,
+			},
+		},
+		{
+			name:   "passed",
+			system: protocol.VotingSystemAbsoluteMajority,
+			ballots: []contract.Ballot{
+				contract.Ballot{
+					Address: otherUserAddr,
+					AssetID: assetID,
+					Vote:    []byte{66},
+				},
+				contract.Ballot{
+					Address: userAddr,
+					AssetID: assetID,
+					Vote:    []byte{66},
+				},
+			},
+			want: VoteOutcome{
+				Winners:   []uint8{66},
+				Passed:    true,
+				QuorumMet: true,
+			},
+		},
+	}
+
+	s := NewVoteService()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vo := contract.Vote{
+				AssetID:     assetID,
+				VoteOptions: []byte{65, 66},
+				VoteLogic:   protocol.VoteLogicStandard,
+				VoteMax:     1,
+				Thresholds:  tt.thresholds,
+				Ballots:     tt.ballots,
+			}
+
+			result := s.generateResult(c, vo)
+			vo.Result = &result
+
+			got := s.votingSystems[tt.system].Winners(c, vo)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%#+v\nwant\n%#+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,25 +5,63 @@ import (
 	"time"
 
 	"github.com/tokenized/smart-contract/internal/app/state/contract"
-	"github.com/tokenized/smart-contract/pkg/protocol"
 )
 
-type VoteService struct{}
+type VoteService struct {
+	votingSystems map[byte]VotingSystem
+	tieBreaks     map[string]TieBreak
+	amenders      []Amender
+}
 
 func NewVoteService() VoteService {
-	return VoteService{}
+	return VoteService{
+		votingSystems: newVotingSystems(),
+		tieBreaks:     newTieBreaks(),
+	}
+}
+
+// RegisterAmender adds an Amender to be given the amendment of each vote
+// that passes with a Proposal.
+func (v *VoteService) RegisterAmender(a Amender) {
+	v.amenders = append(v.amenders, a)
 }
 
+// handle returns the votes that can be resulted.
+//
+// If a drawn vote is resolved by holding the vote again, the new vote is
+// also returned. It can be identified by having no Result, and is
+// referenced by the RevoteRef of the key of the drawn vote.
 func (v VoteService) handle(ctx context.Context, c contract.Contract) ([]contract.Vote, error) {
 
 	votes := []contract.Vote{}
 
-	for _, vote := range c.Votes {
-		if vote.Result == nil && !vote.IsOpen(time.Now()) {
+	for key, vote := range c.Votes {
+		if vote.Result == nil && time.Now().UnixNano() >= vote.RevealCutOff() {
 			// we can result this vote
 			result := v.generateResult(c, vote)
 
 			vote.Result = &result
+
+			if ineligible := ineligibleVoters(c, vote); len(ineligible) > 0 {
+				vote.Ineligible = ineligible
+			}
+
+			outcome, err := v.resolveOutcome(c, vote)
+			if err != nil && err != ErrRevote {
+				return nil, err
+			}
+
+			if err == ErrRevote {
+				votes = append(votes, vote, newRevote(key, vote, outcome.Winners))
+				continue
+			}
+
+			vote.Winners = outcome.Winners
+
+			if err := v.amend(ctx, c, vote); err != nil {
+				return nil, err
+			}
+
 			votes = append(votes, vote)
 		}
 	}
@@ -31,87 +69,196 @@ func (v VoteService) handle(ctx context.Context, c contract.Contract) ([]contrac
 	return votes, nil
 }
 
+// Outcome returns the outcome of a resulted vote under the voting system of
+// the vote, applying the tie break policy of the contract if the vote was a
+// draw.
+//
+// A draw that can only be resolved by holding the vote again is returned
+// unresolved.
+func (v VoteService) Outcome(c contract.Contract,
+	vo contract.Vote) (VoteOutcome, error) {
+
+	o, err := v.resolveOutcome(c, vo)
+	if err == ErrRevote {
+		return o, nil
+	}
+
+	return o, err
+}
+
+// resolveOutcome returns the outcome of a resulted vote under the voting
+// system of the vote, applying the tie break policy of the contract if the
+// vote was a draw.
+func (v VoteService) resolveOutcome(c contract.Contract,
+	vo contract.Vote) (VoteOutcome, error) {
+
+	code, err := GetVotingSystemCode(c, vo.Scope())
+	if err != nil {
+		return VoteOutcome{}, err
+	}
+
+	vs, ok := v.votingSystems[code]
+	if !ok {
+		return VoteOutcome{}, ErrUnknownVotingSystem
+	}
+
+	return v.breakTie(c, vo, vs.Winners(c, vo))
+}
+
+// breakTie applies the tie break policy of the contract to the outcome of a
+// vote, if the vote was a draw.
+//
+// If the draw can only be resolved by holding the vote again, the outcome
+// is returned unchanged with ErrRevote.
+func (v VoteService) breakTie(c contract.Contract,
+	vo contract.Vote,
+	o VoteOutcome) (VoteOutcome, error) {
+
+	if len(o.Winners) < 2 {
+		return o, nil
+	}
+
+	tb, ok := v.tieBreaks[c.TieBreak]
+	if !ok {
+		// no tie break policy, the vote is a draw
+		return o, nil
+	}
+
+	winners, err := tb.Break(c, vo, o.Winners)
+	if err != nil {
+		return o, err
+	}
+
+	o.Winners = winners
+
+	if len(winners) == 1 {
+		o.Passed = true
+		o.Reason = ""
+	}
+
+	return o, nil
+}
+
 func (v VoteService) generateResult(c contract.Contract, vo contract.Vote) contract.BallotResult {
 	// before this method can be called, Vote.VoteLogic must be verified as
 	// a valid value (0, or 1).
-	result := contract.NewBallotResult()
-
-	// get all token owners
-	// holdings := contract.getHoldings()
+	t := NewTallyAccumulator(c, vo)
 
 	for _, ballot := range vo.Ballots {
-		// if the contract is a contract level vote, then any holder can vote.
-		// but if the vote is on a specific asset id, the user must
-		// have that asset id.
-		if vo.AssetID != "" && ballot.AssetID != vo.AssetID {
-			// this ballot cannot be accepted for this vote, wrong asset id
-			continue
-		}
+		t.Add(ballot)
+	}
 
-		tokens := uint64(0)
+	return t.Result()
+}
 
-		asset, ok := c.Assets[ballot.AssetID]
-		if !ok {
-			// skipping
-			continue
-		}
+// countedBallot is a ballot accepted for a vote, with its position in the
+// ballots of the vote and the tokens it counts for.
+type countedBallot struct {
+	ballot contract.Ballot
+	index  int
+	tokens uint64
+}
 
-		holding, ok := asset.Holdings[ballot.Address]
-		if !ok {
-			// skipping
+// countBallots returns the ballots of the vote that can be counted.
+//
+// A ballot must be cast on one of the assets the vote is held on. If the
+// vote counts the latest ballot only, the latest ballot of each voter counts
+// for their holdings aggregated across all of those assets. Otherwise the
+// latest ballot of a voter on each asset counts for their holding of that
+// asset.
+func countBallots(c contract.Contract, vo contract.Vote) []countedBallot {
+	counted := []countedBallot{}
+	seen := map[string]bool{}
+
+	for i := len(vo.Ballots) - 1; i >= 0; i-- {
+		ballot := vo.Ballots[i]
+		key := voterKey(vo, ballot)
+
+		if seen[key] {
+			// a later ballot from this voter has been counted
 			continue
 		}
 
-		if holding.Balance == 0 {
-			// skipping
+		tokens, ok := countBallot(c, vo, ballot)
+		if !ok {
 			continue
 		}
 
-		tokens = uint64(holding.Balance)
+		seen[key] = true
 
-		// if this is an asset level vote, the voter must have that asset
+		counted = append(counted, countedBallot{
+			ballot: ballot,
+			index:  i,
+			tokens: tokens,
+		})
+	}
 
-		// get the vote values the user sent
-		values := ballot.Vote[:vo.VoteMax]
+	return counted
+}
 
-		for i, val := range values {
-			// 0 - Standard Scoring (+1 * # of tokens owned),
-			// 1 - Weighted Scoring (1st choice * Vote Max * # of tokens held,
-			//     2nd choice * Vote Max-1 * # of tokens held,..etc.)
+// voterKey returns the key a ballot replaces the earlier ballots of the
+// voter with: the voter, or if each ballot counts for the holding of the
+// asset it is cast on, the voter and the asset.
+func voterKey(vo contract.Vote, ballot contract.Ballot) string {
+	if vo.LatestBallotOnly {
+		return ballot.Address
+	}
 
-			// assuming VoteLogic == "0", as a valid VoteLogic has already
-			// been verified.
-			voteValue := tokens
+	return ballot.Address + "/" + ballot.AssetID
+}
 
-			if vo.VoteLogic == protocol.VoteLogicWeighted {
-				max := uint64(int(vo.VoteMax) - i)
-				voteValue = max * tokens
-			}
+// countBallot returns the tokens a ballot counts for, and false if the
+// ballot cannot be counted.
+//
+// A ballot from a voter that is not eligible under the rules of the
+// contract is not counted, nor is a ballot on a commit-reveal vote that does
+// not reveal a commitment.
+func countBallot(c contract.Contract,
+	vo contract.Vote,
+	ballot contract.Ballot) (uint64, bool) {
+
+	if !vo.Countable(ballot) {
+		return 0, false
+	}
 
-			result[val] += voteValue
-		}
+	tokens, ok := ballotTokens(c, vo, ballot)
+	if !ok {
+		return 0, false
+	}
 
+	if err := checkEligibility(c, vo, ballot, tokens); err != nil {
+		return 0, false
 	}
 
-	// discard any incorrect selections
-	for k := range result {
-		// delete any key that is not in the vote options
-		found := false
+	return tokens, true
+}
 
-		for _, o := range vo.VoteOptions {
-			if o == k {
-				found = true
-				break
-			}
-		}
+// ballotTokens returns the tokens held by the voter of a ballot, and false
+// if the ballot is not cast by a holder of the assets of the vote.
+func ballotTokens(c contract.Contract,
+	vo contract.Vote,
+	ballot contract.Ballot) (uint64, bool) {
+
+	// if the contract is a contract level vote, then any holder can vote.
+	// but if the vote is on specific assets, the ballot must be cast on one
+	// of them.
+	if !vo.InScope(ballot.AssetID) {
+		// this ballot cannot be accepted for this vote, wrong asset id
+		return 0, false
+	}
 
-		if !found {
-			// the option that was voted for wasn't found, remove it.
-			delete(result, k)
-		}
+	if _, ok := c.Assets[ballot.AssetID]; !ok {
+		// skipping
+		return 0, false
 	}
 
-	return result
+	tokens := c.HoldingsOf(ballot.Address, vo.BallotHoldings(ballot))
+	if tokens == 0 {
+		// skipping
+		return 0, false
+	}
+
+	return tokens, true
 }
 
 // TBA
@@ -156,7 +303,7 @@ func (s VoteService) FinaliseVotes(ctx context.Context,
 			Value:    vote.UTXO.Value,
 		}
 
-		result := buildResultFromVoteResult(vote)
+		result := NewResultMessage(vote)
 		contract.Votes[vote.Address] = vote
 
 		cr := ContractResponse{
@@ -174,48 +321,4 @@ func (s VoteService) FinaliseVotes(ctx context.Context,
 
 	return nil
 }
-
-func buildResultFromVoteResult(vote contract.Vote) protocol.Result {
-	result := protocol.NewResult()
-	result.AssetType = []byte(vote.AssetType)
-	result.AssetID = []byte(vote.AssetID)
-	result.VoteType = vote.VoteType
-	result.VoteTxnID = []byte(vote.RefTxnIDHash)
-
-	// get the result from the vote, which is a map of counts
-	voteResult := *vote.Result
-
-	// maximum seen vote count for an option
-	max := uint64(0)
-
-	for _, option := range vote.VoteOptions {
-		count := voteResult[option]
-
-		if count < max {
-			continue
-		}
-
-		max = count
-	}
-
-	// we know the largest value, find any values with that count. there
-	// can be more than one as it is possible for a vote to draw.
-	winners := make([]byte, 16, 16)
-	winnerIdx := 0
-
-	for _, option := range vote.VoteOptions {
-		count := voteResult[option]
-
-		if count != max {
-			continue
-		}
-
-		winners[winnerIdx] = option
-		winnerIdx++
-	}
-
-	result.Result = winners
-
-	return result
-}
 */
@@ -0,0 +1,168 @@
+package vote
+
+import (
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+)
+
+// participation holds the counts of who was able to vote, and who did.
+//
+// Voters that explicitly abstained are counted separately.
+type participation struct {
+	eligibleVoters  int
+	voters          int
+	abstainers      int
+	eligibleTokens  uint64
+	votedTokens     uint64
+	abstainedTokens uint64
+
+	// abstentionsInQuorum is true if abstentions count toward the quorum.
+	abstentionsInQuorum bool
+}
+
+// newParticipation returns the participation in a Vote.
+func newParticipation(c contract.Contract, vo contract.Vote) participation {
+	p := participation{
+		eligibleVoters:      len(c.HoldersOf(vo.Scope())),
+		eligibleTokens:      c.TokensHeld(vo.Scope()),
+		abstentionsInQuorum: c.AbstentionsInQuorum,
+	}
+
+	// a voter may have a ballot counted on each asset
+	voters := map[string]bool{}
+	abstainers := map[string]bool{}
+
+	for _, cb := range countBallots(c, vo) {
+		if vo.IsAbstention(cb.ballot) {
+			abstainers[cb.ballot.Address] = true
+			p.abstainedTokens += cb.tokens
+			continue
+		}
+
+		voters[cb.ballot.Address] = true
+		p.votedTokens += cb.tokens
+	}
+
+	p.voters = len(voters)
+	p.abstainers = len(abstainers)
+
+	return p
+}
+
+// quorumTokens returns the tokens that count toward the quorum of the
+// Vote.
+func (p participation) quorumTokens() uint64 {
+	if p.abstentionsInQuorum {
+		return p.votedTokens + p.abstainedTokens
+	}
+
+	return p.votedTokens
+}
+
+// quorumMet returns true if more than the threshold of the eligible tokens
+// count toward the quorum. A zero threshold is met by any tokens.
+func (p participation) quorumMet(threshold float64) bool {
+	tokens := p.quorumTokens()
+	if tokens == 0 {
+		return false
+	}
+
+	return float64(tokens) > threshold*float64(p.eligibleTokens)
+}
+
+// VoteStats holds statistics about the participation in a Vote.
+type VoteStats struct {
+	// EligibleVoters is the number of holders of the assets of the vote.
+	EligibleVoters int
+
+	// Voters is the number of holders whose ballot was counted, excluding
+	// abstentions.
+	Voters int
+
+	// Abstainers is the number of holders that explicitly abstained.
+	Abstainers int
+
+	// Turnout is the percentage of eligible voters that voted or
+	// abstained.
+	Turnout float64
+
+	// EligibleTokens is the number of tokens held across the assets of the
+	// vote.
+	EligibleTokens uint64
+
+	// VotedTokens is the number of tokens held by the voters.
+	VotedTokens uint64
+
+	// AbstainedTokens is the number of tokens held by the abstainers.
+	AbstainedTokens uint64
+
+	// Participation is the percentage of eligible tokens that count toward
+	// the quorum.
+	Participation float64
+
+	// Distribution is the percentage of the tally received by each option.
+	Distribution map[uint8]float64
+
+	// QuorumMet is true if the participation exceeds the quorum threshold
+	// of the voting system of the vote.
+	QuorumMet bool
+}
+
+// Stats returns statistics about the participation in a Vote, resulting it
+// first if needed.
+func (v VoteService) Stats(c contract.Contract,
+	vo contract.Vote) (VoteStats, error) {
+
+	if vo.Result == nil {
+		result := v.generateResult(c, vo)
+		vo.Result = &result
+	}
+
+	code, err := GetVotingSystemCode(c, vo.Scope())
+	if err != nil {
+		return VoteStats{}, err
+	}
+
+	vs, ok := v.votingSystems[code]
+	if !ok {
+		return VoteStats{}, ErrUnknownVotingSystem
+	}
+
+	p := newParticipation(c, vo)
+
+	turnout := uint64(p.voters + p.abstainers)
+
+	stats := VoteStats{
+		EligibleVoters:  p.eligibleVoters,
+		Voters:          p.voters,
+		Abstainers:      p.abstainers,
+		Turnout:         percentage(turnout, uint64(p.eligibleVoters)),
+		EligibleTokens:  p.eligibleTokens,
+		VotedTokens:     p.votedTokens,
+		AbstainedTokens: p.abstainedTokens,
+		Participation:   percentage(p.quorumTokens(), p.eligibleTokens),
+		Distribution:    map[uint8]float64{},
+		QuorumMet:       p.quorumMet(vs.QuorumThreshold()),
+	}
+
+	result := *vo.Result
+
+	total := uint64(0)
+	for _, option := range vo.VoteOptions {
+		total += result[option]
+	}
+
+	for _, option := range vo.VoteOptions {
+		stats.Distribution[option] = percentage(result[option], total)
+	}
+
+	return stats, nil
+}
+
+// percentage returns n as a percentage of total, or 0 if total is 0.
+func percentage(n, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(n) / float64(total) * 100
+}
@@ -0,0 +1,246 @@
+package vote
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+func TestVoteService_Stats(t *testing.T) {
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+	issuerAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	otherUserAddr := "1DnoezsMcKZeQrXVW7eqU5v8HRKmnPSYd2"
+
+	c := contract.Contract{
+		Assets: map[string]contract.Asset{
+			assetID: contract.Asset{
+				Holdings: map[string]contract.Holding{
+					issuerAddr: contract.Holding{
+						Address: issuerAddr,
+						Balance: 15,
+					},
+					userAddr: contract.Holding{
+						Address: userAddr,
+						Balance: 5,
+					},
+					otherUserAddr: contract.Holding{
+						Address: otherUserAddr,
+						Balance: 20,
+					},
+				},
+			},
+		},
+	}
+
+	vo := contract.Vote{
+		AssetID:     assetID,
+		VoteOptions: []byte{89, 78},
+		VoteLogic:   '0',
+		VoteMax:     1,
+		Ballots: []contract.Ballot{
+			contract.Ballot{
+				Address: issuerAddr,
+				AssetID: assetID,
+				Vote:    []byte{89},
+			},
+			contract.Ballot{
+				Address: userAddr,
+				AssetID: assetID,
+				Vote:    []byte{78},
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		votingSystem byte
+		want         VoteStats
+	}{
+		{
+			name:         "relative majority",
+			votingSystem: protocol.VotingSystemRelativeMajority,
+			want: VoteStats{
+				EligibleVoters: 3,
+				Voters:         2,
+				Turnout:        float64(2) / 3 * 100,
+				EligibleTokens: 40,
+				VotedTokens:    20,
+				Participation:  50,
+				Distribution: map[uint8]float64{
+					89: 75,
+					78: 25,
+				},
+				QuorumMet: true,
+			},
+		},
+		{
+			name:         "absolute majority",
+			votingSystem: protocol.VotingSystemAbsoluteMajority,
+			want: VoteStats{
+				EligibleVoters: 3,
+				Voters:         2,
+				Turnout:        float64(2) / 3 * 100,
+				EligibleTokens: 40,
+				VotedTokens:    20,
+				Participation:  50,
+				Distribution: map[uint8]float64{
+					89: 75,
+					78: 25,
+				},
+				QuorumMet: false,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asset := c.Assets[assetID]
+			asset.VotingSystem = tt.votingSystem
+			c.Assets[assetID] = asset
+
+			s := NewVoteService()
+
+			got, err := s.Stats(c, vo)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%#+v\nwant\n%#+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestVoteService_Stats_quorum tests that the quorum is met when the
+// participation exceeds the quorum threshold of the voting system, as it is
+// when the vote is decided.
+func TestVoteService_Stats_quorum(t *testing.T) {
+	assetID := "w840mxhrhupngqthd9quwtgsocaonv2f"
+	issuerAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	otherUserAddr := "1DnoezsMcKZeQrXVW7eqU5v8HRKmnPSYd2"
+
+	holdings := map[string]contract.Holding{
+		issuerAddr: contract.Holding{
+			Address: issuerAddr,
+			Balance: 15,
+		},
+		userAddr: contract.Holding{
+			Address: userAddr,
+			Balance: 5,
+		},
+		otherUserAddr: contract.Holding{
+			Address: otherUserAddr,
+			Balance: 20,
+		},
+	}
+
+	// the quorum thresholds are 0 for the relative systems, and 1/2 and
+	// 2/3 of the 40 tokens held for the absolute systems
+	tests := []struct {
+		name   string
+		voters []string
+		want   map[byte]bool
+	}{
+		{
+			name: "no ballots",
+			want: map[byte]bool{},
+		},
+		{
+			name:   "37.5%",
+			voters: []string{issuerAddr},
+			want: map[byte]bool{
+				protocol.VotingSystemPlurality:             true,
+				protocol.VotingSystemRelativeMajority:      true,
+				protocol.VotingSystemRelativeSuperMajority: true,
+				protocol.VotingSystemCondorcet:             true,
+			},
+		},
+		{
+			name:   "50%",
+			voters: []string{issuerAddr, userAddr},
+			want: map[byte]bool{
+				protocol.VotingSystemPlurality:             true,
+				protocol.VotingSystemRelativeMajority:      true,
+				protocol.VotingSystemRelativeSuperMajority: true,
+				protocol.VotingSystemCondorcet:             true,
+			},
+		},
+		{
+			name:   "62.5%",
+			voters: []string{userAddr, otherUserAddr},
+			want: map[byte]bool{
+				protocol.VotingSystemPlurality:             true,
+				protocol.VotingSystemRelativeMajority:      true,
+				protocol.VotingSystemRelativeSuperMajority: true,
+				protocol.VotingSystemAbsoluteMajority:      true,
+				protocol.VotingSystemCondorcet:             true,
+			},
+		},
+		{
+			name:   "87.5%",
+			voters: []string{issuerAddr, otherUserAddr},
+			want: map[byte]bool{
+				protocol.VotingSystemPlurality:             true,
+				protocol.VotingSystemRelativeMajority:      true,
+				protocol.VotingSystemRelativeSuperMajority: true,
+				protocol.VotingSystemAbsoluteMajority:      true,
+				protocol.VotingSystemAbsoluteSuperMajority: true,
+				protocol.VotingSystemCondorcet:             true,
+			},
+		},
+	}
+
+	s := NewVoteService()
+
+	for _, tt := range tests {
+		for code, vs := range s.votingSystems {
+			c := contract.Contract{
+				Assets: map[string]contract.Asset{
+					assetID: contract.Asset{
+						VotingSystem: code,
+						Holdings:     holdings,
+					},
+				},
+			}
+
+			vo := contract.Vote{
+				AssetID:     assetID,
+				VoteOptions: []byte{89, 78},
+				VoteLogic:   '0',
+				VoteMax:     1,
+				Ballots:     []contract.Ballot{},
+			}
+
+			for _, voter := range tt.voters {
+				vo.Ballots = append(vo.Ballots, contract.Ballot{
+					Address: voter,
+					AssetID: assetID,
+					Vote:    []byte{89},
+				})
+			}
+
+			got, err := s.Stats(c, vo)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.QuorumMet != tt.want[code] {
+				t.Fatalf("%v : got quorum met %v under %c, want %v", tt.name,
+					got.QuorumMet, code, tt.want[code])
+			}
+
+			result := s.generateResult(c, vo)
+			vo.Result = &result
+
+			if o := vs.Winners(c, vo); o.QuorumMet != got.QuorumMet {
+				t.Fatalf("%v : got quorum met %v deciding the vote under %c, %v in the stats",
+					tt.name, o.QuorumMet, code, got.QuorumMet)
+			}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package vote
+
+import (
+	"errors"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+var (
+	// ErrMixedVotingSystems is returned when the assets a vote is held on do
+	// not share a voting system.
+	ErrMixedVotingSystems = errors.New("Assets have different voting systems")
+
+	// ErrUnknownVotingSystem is returned when there is no VotingSystem for
+	// the code of a vote.
+	ErrUnknownVotingSystem = errors.New("Unknown voting system")
+)
+
+// VotingSystem defines an interface for deciding the outcome of a resulted
+// Vote.
+//
+// QuorumThreshold returns the fraction of the eligible tokens that must take
+// part in a Vote for its quorum to be met. The quorum of a zero threshold is
+// met if any tokens take part.
+type VotingSystem interface {
+	Winners(contract.Contract, contract.Vote) VoteOutcome
+	QuorumThreshold() float64
+}
+
+// newVotingSystems returns a mapping of voting system codes and
+// VotingSystem's.
+func newVotingSystems() map[byte]VotingSystem {
+	return map[byte]VotingSystem{
+		protocol.VotingSystemPlurality:             pluralitySystem{},
+		protocol.VotingSystemRelativeMajority:      relativeSystem{threshold: 1.0 / 2},
+		protocol.VotingSystemRelativeSuperMajority: relativeSystem{threshold: 2.0 / 3},
+		protocol.VotingSystemAbsoluteMajority:      absoluteSystem{threshold: 1.0 / 2},
+		protocol.VotingSystemAbsoluteSuperMajority: absoluteSystem{threshold: 2.0 / 3},
+		protocol.VotingSystemCondorcet:             condorcetSystem{},
+	}
+}
+
+// GetVotingSystemCode returns the code of the voting system used for a vote
+// held on the assets. A nil list of assets is a contract level vote, which
+// uses the voting system of the contract.
+//
+// All assets of a vote must share a voting system. If no voting system has
+// been set, VotingSystemPlurality is used.
+func GetVotingSystemCode(c contract.Contract, assetIDs []string) (byte, error) {
+	code := byte(0)
+
+	if assetIDs == nil {
+		if len(c.VotingSystem) > 0 {
+			code = c.VotingSystem[0]
+		}
+	}
+
+	for i, id := range assetIDs {
+		asset := c.Assets[id]
+
+		if i > 0 && asset.VotingSystem != code {
+			return 0, ErrMixedVotingSystems
+		}
+
+		code = asset.VotingSystem
+	}
+
+	if code == 0 {
+		code = protocol.VotingSystemPlurality
+	}
+
+	return code, nil
+}
+
+// pluralitySystem is won by the options with the most votes.
+type pluralitySystem struct{}
+
+// Winners returns the outcome with the options with the highest tally as
+// the winners. The quorum is met if any tokens took part in the vote.
+func (s pluralitySystem) Winners(c contract.Contract,
+	vo contract.Vote) VoteOutcome {
+
+	p := newParticipation(c, vo)
+
+	return newVoteOutcome(c, vo, p.quorumMet(s.QuorumThreshold()), Winners(vo))
+}
+
+// QuorumThreshold implements the VotingSystem interface.
+func (s pluralitySystem) QuorumThreshold() float64 {
+	return 0
+}
+
+// relativeSystem is won by an option receiving more than the threshold of
+// the votes cast. Abstentions are counted as votes cast if they count
+// toward the quorum.
+type relativeSystem struct {
+	threshold float64
+}
+
+// Winners returns the outcome with the options with the highest tally as
+// the winners, if that tally exceeds the threshold of the tokens that voted.
+// The quorum is met if any tokens took part in the vote.
+func (s relativeSystem) Winners(c contract.Contract,
+	vo contract.Vote) VoteOutcome {
+
+	p := newParticipation(c, vo)
+
+	return newVoteOutcome(c, vo, p.quorumMet(s.QuorumThreshold()),
+		thresholdWinners(vo, p.quorumTokens(), s.threshold))
+}
+
+// QuorumThreshold implements the VotingSystem interface.
+func (s relativeSystem) QuorumThreshold() float64 {
+	return 0
+}
+
+// absoluteSystem is won by an option receiving votes from more than the
+// threshold of all tokens held, whether they voted or not.
+type absoluteSystem struct {
+	threshold float64
+}
+
+// Winners returns the outcome with the options with the highest tally as
+// the winners, if that tally exceeds the threshold of the tokens held across
+// the assets of the vote.
+//
+// The quorum is only met if more than the threshold of the tokens held took
+// part in the vote, as no option can win otherwise.
+func (s absoluteSystem) Winners(c contract.Contract,
+	vo contract.Vote) VoteOutcome {
+
+	p := newParticipation(c, vo)
+
+	return newVoteOutcome(c, vo, p.quorumMet(s.QuorumThreshold()),
+		thresholdWinners(vo, p.eligibleTokens, s.threshold))
+}
+
+// QuorumThreshold implements the VotingSystem interface.
+func (s absoluteSystem) QuorumThreshold() float64 {
+	return s.threshold
+}
+
+// thresholdWinners returns the options with the highest tally, if that
+// tally exceeds the threshold of the tokens.
+//
+// With weighted vote logic a choice can count for up to VoteMax times the
+// tokens of the voter, so the tokens are scaled to match.
+func thresholdWinners(vo contract.Vote,
+	tokens uint64,
+	threshold float64) []uint8 {
+
+	if vo.VoteLogic == protocol.VoteLogicWeighted {
+		tokens *= uint64(vo.VoteMax)
+	}
+
+	winners := []uint8{}
+
+	candidates := Winners(vo)
+	if tokens == 0 || len(candidates) == 0 {
+		return winners
+	}
+
+	result := *vo.Result
+
+	for _, option := range candidates {
+		if float64(result[option]) <= threshold*float64(tokens) {
+			continue
+		}
+
+		winners = append(winners, option)
+	}
+
+	return winners
+}
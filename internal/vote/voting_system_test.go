@@ -0,0 +1,307 @@
+package vote
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+	"github.com/tokenized/smart-contract/pkg/protocol"
+)
+
+func TestGetVotingSystemCode(t *testing.T) {
+	c := contract.Contract{
+		VotingSystem: string(protocol.VotingSystemRelativeMajority),
+		Assets: map[string]contract.Asset{
+			"a": contract.Asset{
+				VotingSystem: protocol.VotingSystemAbsoluteMajority,
+			},
+			"b": contract.Asset{
+				VotingSystem: protocol.VotingSystemAbsoluteMajority,
+			},
+			"c": contract.Asset{
+				VotingSystem: protocol.VotingSystemPlurality,
+			},
+			"d": contract.Asset{},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		assetIDs []string
+		want     byte
+		err      error
+	}{
+		{
+			name:     "contract vote",
+			assetIDs: nil,
+			want:     protocol.VotingSystemRelativeMajority,
+		},
+		{
+			name:     "single asset",
+			assetIDs: []string{"c"},
+			want:     protocol.VotingSystemPlurality,
+		},
+		{
+			name:     "single asset, no voting system",
+			assetIDs: []string{"d"},
+			want:     protocol.VotingSystemPlurality,
+		},
+		{
+			name:     "multiple assets",
+			assetIDs: []string{"a", "b"},
+			want:     protocol.VotingSystemAbsoluteMajority,
+		},
+		{
+			name:     "multiple assets, mixed voting systems",
+			assetIDs: []string{"a", "c"},
+			err:      ErrMixedVotingSystems,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetVotingSystemCode(c, tt.assetIDs)
+			if err != tt.err {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVotingSystem_Winners_multiAsset(t *testing.T) {
+	issuerAddr := "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv"
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	otherUserAddr := "1DnoezsMcKZeQrXVW7eqU5v8HRKmnPSYd2"
+
+	c := contract.Contract{
+		Assets: map[string]contract.Asset{
+			"a": contract.Asset{
+				Holdings: map[string]contract.Holding{
+					userAddr: contract.Holding{
+						Address: userAddr,
+						Balance: 10,
+					},
+					otherUserAddr: contract.Holding{
+						Address: otherUserAddr,
+						Balance: 15,
+					},
+				},
+			},
+			"b": contract.Asset{
+				Holdings: map[string]contract.Holding{
+					userAddr: contract.Holding{
+						Address: userAddr,
+						Balance: 10,
+					},
+					issuerAddr: contract.Holding{
+						Address: issuerAddr,
+						Balance: 5,
+					},
+				},
+			},
+			"c": contract.Asset{
+				Holdings: map[string]contract.Holding{
+					issuerAddr: contract.Holding{
+						Address: issuerAddr,
+						Balance: 100,
+					},
+				},
+			},
+		},
+	}
+
+	// a vote on assets a and b, 40 tokens held in total. the user votes
+	// with holdings across both assets, but only their latest ballot
+	// counts. the issuer can only vote with their holding of asset b.
+	vo := contract.Vote{
+		AssetIDs:         []string{"a", "b"},
+		LatestBallotOnly: true,
+		VoteOptions:      []byte{65, 66},
+		VoteLogic:        '0',
+		VoteMax:          1,
+		Ballots: []contract.Ballot{
+			contract.Ballot{
+				Address: userAddr,
+				AssetID: "a",
+				Vote:    []byte{66},
+			},
+			contract.Ballot{
+				Address: userAddr,
+				AssetID: "b",
+				Vote:    []byte{65},
+			},
+			contract.Ballot{
+				Address: issuerAddr,
+				AssetID: "b",
+				Vote:    []byte{65},
+			},
+			contract.Ballot{
+				Address: issuerAddr,
+				AssetID: "c",
+				Vote:    []byte{66},
+			},
+		},
+	}
+
+	s := NewVoteService()
+
+	result := s.generateResult(c, vo)
+
+	wantResult := contract.BallotResult{
+		65: 25,
+	}
+
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Fatalf("got\n%#+v\nwant\n%#+v", result, wantResult)
+	}
+
+	vo.Result = &result
+
+	tests := []struct {
+		name string
+		code byte
+		want []uint8
+	}{
+		{
+			name: "plurality",
+			code: protocol.VotingSystemPlurality,
+			want: []uint8{65},
+		},
+		{
+			name: "relative super majority",
+			code: protocol.VotingSystemRelativeSuperMajority,
+			want: []uint8{65},
+		},
+		{
+			name: "absolute majority",
+			code: protocol.VotingSystemAbsoluteMajority,
+			want: []uint8{65},
+		},
+		{
+			name: "absolute super majority",
+			code: protocol.VotingSystemAbsoluteSuperMajority,
+			want: []uint8{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.votingSystems[tt.code].Winners(c, vo).Winners
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%#+v\nwant\n%#+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCountBallots tests the ballots counted with and without
+// LatestBallotOnly set on the vote.
+func TestCountBallots(t *testing.T) {
+	userAddr := "13FzCGiNWaUHCWGvuLobWM7iaNyP3TJAJg"
+	otherUserAddr := "1DnoezsMcKZeQrXVW7eqU5v8HRKmnPSYd2"
+
+	c := contract.Contract{
+		Assets: map[string]contract.Asset{
+			"a": contract.Asset{
+				Holdings: map[string]contract.Holding{
+					userAddr: contract.Holding{
+						Address: userAddr,
+						Balance: 10,
+					},
+					otherUserAddr: contract.Holding{
+						Address: otherUserAddr,
+						Balance: 15,
+					},
+				},
+			},
+			"b": contract.Asset{
+				Holdings: map[string]contract.Holding{
+					userAddr: contract.Holding{
+						Address: userAddr,
+						Balance: 20,
+					},
+				},
+			},
+		},
+	}
+
+	// the user changes their vote on asset a, then votes differently on
+	// asset b
+	ballots := []contract.Ballot{
+		contract.Ballot{
+			Address: userAddr,
+			AssetID: "a",
+			Vote:    []byte{66},
+		},
+		contract.Ballot{
+			Address: otherUserAddr,
+			AssetID: "a",
+			Vote:    []byte{66},
+		},
+		contract.Ballot{
+			Address: userAddr,
+			AssetID: "a",
+			Vote:    []byte{65},
+		},
+		contract.Ballot{
+			Address: userAddr,
+			AssetID: "b",
+			Vote:    []byte{66},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		latestBallotOnly bool
+		want             contract.BallotResult
+		wantVoters       int
+	}{
+		{
+			// the latest ballot on each asset counts for the holding of
+			// that asset
+			name: "every asset",
+			want: contract.BallotResult{
+				65: 10,
+				66: 35,
+			},
+			wantVoters: 2,
+		},
+		{
+			// the latest ballot counts for the holdings across both
+			// assets
+			name:             "latest ballot only",
+			latestBallotOnly: true,
+			want: contract.BallotResult{
+				66: 45,
+			},
+			wantVoters: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vo := contract.Vote{
+				AssetIDs:         []string{"a", "b"},
+				LatestBallotOnly: tt.latestBallotOnly,
+				VoteOptions:      []byte{65, 66},
+				VoteLogic:        '0',
+				VoteMax:          1,
+				Ballots:          ballots,
+			}
+
+			result := NewVoteService().generateResult(c, vo)
+			if !reflect.DeepEqual(result, tt.want) {
+				t.Fatalf("got\n%#+v\nwant\n%#+v", result, tt.want)
+			}
+
+			if p := newParticipation(c, vo); p.voters != tt.wantVoters {
+				t.Fatalf("got %v voters, want %v", p.voters, tt.wantVoters)
+			}
+		})
+	}
+}
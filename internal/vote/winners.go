@@ -0,0 +1,46 @@
+package vote
+
+import (
+	"github.com/tokenized/smart-contract/internal/app/state/contract"
+)
+
+// Winners returns the options with the highest tally in the result of the
+// Vote.
+//
+// More than one option is returned when the vote is a draw. No options are
+// returned if the vote has no result, or no valid ballots were counted.
+func Winners(vo contract.Vote) []uint8 {
+	winners := []uint8{}
+
+	if vo.Result == nil {
+		return winners
+	}
+
+	result := *vo.Result
+
+	// maximum seen vote count for an option
+	max := uint64(0)
+
+	for _, option := range vo.VoteOptions {
+		if count := result[option]; count > max {
+			max = count
+		}
+	}
+
+	if max == 0 {
+		// nobody voted for a valid option
+		return winners
+	}
+
+	// we know the largest value, find any options with that count. there
+	// can be more than one as it is possible for a vote to draw.
+	for _, option := range vo.VoteOptions {
+		if result[option] != max {
+			continue
+		}
+
+		winners = append(winners, option)
+	}
+
+	return winners
+}
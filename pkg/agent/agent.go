@@ -0,0 +1,221 @@
+// Package agent embeds a smart contract agent in another service.
+//
+// An Agent processes the requests sent to a single contract, and broadcasts
+// the responses. It hides the wiring of the node, inspector, handlers and
+// state behind a small lifecycle.
+//
+//	a, err := agent.New(cfg, store, key, source)
+//	if err != nil {
+//		return err
+//	}
+//
+//	if err := a.Start(ctx); err != nil {
+//		return err
+//	}
+//
+//	<-ctx.Done()
+//
+//	return a.Stop(context.Background())
+//
+// Wait returns early if the Agent stops on its own, such as when its peer
+// can't be reached. Stop must still be called to release it.
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tokenized/smart-contract/cmd/smartcontractd/node"
+	"github.com/tokenized/smart-contract/internal/app/config"
+	"github.com/tokenized/smart-contract/internal/app/network"
+	"github.com/tokenized/smart-contract/internal/app/rpcnode"
+	"github.com/tokenized/smart-contract/internal/app/state"
+	"github.com/tokenized/smart-contract/internal/app/wallet"
+	"github.com/tokenized/smart-contract/internal/integrity"
+	"github.com/tokenized/smart-contract/pkg/spvnode"
+	"github.com/tokenized/smart-contract/pkg/storage"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil"
+)
+
+var (
+	ErrNotStarted     = errors.New("Agent not started")
+	ErrAlreadyStarted = errors.New("Agent already started")
+)
+
+// Config holds the settings of an Agent.
+type Config struct {
+	// OperatorName identifies the operator of the contract.
+	OperatorName string
+
+	// Version is reported in the responses of the contract.
+	Version string
+
+	// FeeAddress is paid the operator fee of each response.
+	FeeAddress string
+
+	// FeeValue is the operator fee in satoshis, which must not be 0.
+	FeeValue uint64
+}
+
+// NodeSource is where an Agent receives TX's and blocks from, and sends its
+// responses to.
+type NodeSource struct {
+	// Peer is the trusted peer that TX's and blocks are received from.
+	Peer spvnode.Config
+
+	// Storage holds the block headers seen from the Peer.
+	//
+	// The network of the Peer also sets the chain parameters that
+	// addresses and keys are encoded for.
+	Storage storage.Storage
+
+	// RPCHost, RPCUsername and RPCPassword are used to fetch and send TX's.
+	RPCHost     string
+	RPCUsername string
+	RPCPassword string
+}
+
+// Agent is a smart contract agent for a single contract.
+type Agent struct {
+	node      node.Node
+	address   string
+	manifests []integrity.IntegrityService
+
+	// done is closed when the node stops, and err is what stopped it.
+	done chan struct{}
+	err  error
+}
+
+// New returns a new Agent for the contract with the private key, holding the
+// contract state in the Storage.
+func New(cfg Config,
+	store storage.Storage,
+	key *btcec.PrivateKey,
+	source NodeSource) (*Agent, error) {
+
+	params := spvnode.ChainParams(source.Peer.Network)
+
+	feeAddress, err := btcutil.DecodeAddress(cfg.FeeAddress, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.FeeValue == 0 {
+		return nil, errors.New("Fee is set to 0 sats")
+	}
+
+	c := config.Config{
+		ContractProviderID: cfg.OperatorName,
+		Version:            cfg.Version,
+		Fee: config.Fee{
+			Address: feeAddress,
+			Value:   cfg.FeeValue,
+		},
+	}
+
+	wif, err := btcutil.NewWIF(key, params, true)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := wallet.NewWallet(wif.String())
+	if err != nil {
+		return nil, err
+	}
+
+	peer := spvnode.NewNode(source.Peer, source.Storage)
+
+	rpcConfig := rpcnode.NewConfig(source.RPCHost,
+		source.RPCUsername,
+		source.RPCPassword)
+
+	n, err := network.NewNetwork(rpcConfig, peer)
+	if err != nil {
+		return nil, err
+	}
+
+	a := Agent{
+		node:    node.NewNode(c, n, *w, store),
+		address: w.PublicAddress,
+		manifests: []integrity.IntegrityService{
+			integrity.NewIntegrityService(store, "contract",
+				fmt.Sprintf("%v/%v", state.ContractPrefix, w.PublicAddress)),
+			integrity.NewIntegrityService(source.Storage, "spvnode",
+				spvnode.StateKey),
+		},
+	}
+
+	return &a, nil
+}
+
+// Address returns the address of the contract.
+func (a *Agent) Address() string {
+	return a.address
+}
+
+// Start verifies the integrity of the stored state, then starts processing
+// requests in the background.
+func (a *Agent) Start(ctx context.Context) error {
+	if a.done != nil {
+		return ErrAlreadyStarted
+	}
+
+	for _, m := range a.manifests {
+		if err := m.Verify(ctx); err != nil {
+			return fmt.Errorf("Integrity check of %v failed : %v", m.Name, err)
+		}
+
+		if err := m.Open(ctx); err != nil {
+			return err
+		}
+	}
+
+	a.done = make(chan struct{})
+
+	go func() {
+		defer close(a.done)
+		a.err = a.node.Start()
+	}()
+
+	return nil
+}
+
+// Wait blocks until the Agent stops processing requests, returning the
+// error that stopped it.
+func (a *Agent) Wait() error {
+	if a.done == nil {
+		return ErrNotStarted
+	}
+
+	<-a.done
+
+	return a.err
+}
+
+// Stop stops processing requests, waits for the request being processed to
+// finish, then records the state as cleanly shut down, so it is verified on
+// the next Start. No requests are processed after Stop returns.
+func (a *Agent) Stop(ctx context.Context) error {
+	if a.done == nil {
+		return ErrNotStarted
+	}
+
+	a.node.Stop()
+
+	select {
+	case <-a.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	for _, m := range a.manifests {
+		if err := m.Seal(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
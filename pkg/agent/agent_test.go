@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/spvnode"
+	"github.com/tokenized/smart-contract/pkg/storage"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+)
+
+func TestNew(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := storage.NewFilesystemStorage(storage.NewConfig("", "", "", "", dir))
+
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address, err := btcutil.NewAddressPubKeyHash(
+		btcutil.Hash160(key.PubKey().SerializeCompressed()),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := NodeSource{
+		Peer:    spvnode.NewConfig("127.0.0.1:8333", "agent-test"),
+		Storage: store,
+		RPCHost: "127.0.0.1:8332",
+	}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			cfg: Config{
+				OperatorName: "Operator",
+				FeeAddress:   "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv",
+				FeeValue:     2000,
+			},
+		},
+		{
+			name: "invalid fee address",
+			cfg: Config{
+				FeeAddress: "FOO",
+				FeeValue:   2000,
+			},
+			wantErr: true,
+		},
+		{
+			name: "fee address of another network",
+			cfg: Config{
+				FeeAddress: "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn",
+				FeeValue:   2000,
+			},
+			wantErr: true,
+		},
+		{
+			name: "no fee",
+			cfg: Config{
+				FeeAddress: "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := New(tt.cfg, store, key, source)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if got, want := a.Address(), address.String(); got != want {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestAgent_notStarted(t *testing.T) {
+	a := Agent{}
+
+	if err := a.Wait(); err != ErrNotStarted {
+		t.Errorf("got %v, want %v", err, ErrNotStarted)
+	}
+
+	if err := a.Stop(context.Background()); err != ErrNotStarted {
+		t.Errorf("got %v, want %v", err, ErrNotStarted)
+	}
+}
+
+func TestAgent_Stop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := storage.NewFilesystemStorage(storage.NewConfig("", "", "", "", dir))
+
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing listens on the peer, so the Agent keeps trying to connect
+	source := NodeSource{
+		Peer:    spvnode.NewConfig("127.0.0.1:1", "agent-test"),
+		Storage: store,
+		RPCHost: "127.0.0.1:1",
+	}
+
+	cfg := Config{
+		OperatorName: "Operator",
+		FeeAddress:   "1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv",
+		FeeValue:     2000,
+	}
+
+	a, err := New(cfg, store, key, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := a.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	stopCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if err := a.Stop(stopCtx); err != nil {
+		t.Fatalf("got %v, want the Agent stopped", err)
+	}
+
+	waited := make(chan error, 1)
+	go func() {
+		waited <- a.Wait()
+	}()
+
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("Wait blocked after Stop")
+	}
+
+	// the state was sealed, so the Agent starts again
+	a, err = New(cfg, store, key, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Start(ctx); err != nil {
+		t.Fatalf("got %v, want the Agent restarted", err)
+	}
+
+	if err := a.Stop(stopCtx); err != nil {
+		t.Fatal(err)
+	}
+}
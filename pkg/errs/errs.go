@@ -0,0 +1,127 @@
+// Package errs classifies errors, so callers can branch on the kind of an
+// error, such as NotFound, without knowing each error that could be
+// returned, and APIs can map errors to consistent status codes.
+package errs
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Class is a category of error.
+type Class struct {
+	name   string
+	status int
+}
+
+var (
+	// NotFound is the Class of errors for items that do not exist.
+	NotFound = &Class{"not_found", http.StatusNotFound}
+
+	// Invalid is the Class of errors for input that is malformed or breaks
+	// the rules, and will fail again if retried.
+	Invalid = &Class{"invalid", http.StatusBadRequest}
+
+	// Temporary is the Class of errors that may succeed if retried, such as
+	// an unavailable service.
+	Temporary = &Class{"temporary", http.StatusServiceUnavailable}
+
+	// Unauthorized is the Class of errors for missing or rejected
+	// credentials.
+	Unauthorized = &Class{"unauthorized", http.StatusUnauthorized}
+
+	// Conflict is the Class of errors for requests that are not allowed in
+	// the current state, such as pausing an operation that has ended.
+	Conflict = &Class{"conflict", http.StatusConflict}
+)
+
+// String returns the name of the Class.
+func (c *Class) String() string {
+	return c.name
+}
+
+// StatusCode returns the HTTP status of errors of the Class.
+func (c *Class) StatusCode() int {
+	return c.status
+}
+
+// Error is an error of a Class.
+type Error struct {
+	Class *Class
+	Err   error
+}
+
+// Error implements the error interface. The message is that of the
+// wrapped error, so classifying an error doesn't change what is logged.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Cause returns the wrapped error.
+func (e *Error) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the wrapped error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns a new error of the Class with the message.
+//
+// It is intended for declaring sentinel errors, which can still be
+// compared with ==.
+func New(class *Class, message string) error {
+	return &Error{
+		Class: class,
+		Err:   errors.New(message),
+	}
+}
+
+// Wrap returns the error as an error of the Class, or nil if err is nil.
+func Wrap(class *Class, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{
+		Class: class,
+		Err:   err,
+	}
+}
+
+// ClassOf returns the Class of the error, or nil if it has none.
+//
+// Errors that wrap another, with a Cause or Unwrap method, are followed
+// until a classified error is found.
+func ClassOf(err error) *Class {
+	for err != nil {
+		switch e := err.(type) {
+		case *Error:
+			return e.Class
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		case interface{ Cause() error }:
+			err = e.Cause()
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Is returns true if the error is of the Class.
+func Is(err error, class *Class) bool {
+	return class != nil && ClassOf(err) == class
+}
+
+// StatusCode returns the HTTP status for the error, which is 500 for an
+// error without a Class.
+func StatusCode(err error) int {
+	if c := ClassOf(err); c != nil {
+		return c.StatusCode()
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,92 @@
+package errs
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// causer wraps an error the way github.com/pkg/errors does.
+type causer struct {
+	err error
+}
+
+func (c causer) Error() string {
+	return "context : " + c.err.Error()
+}
+
+func (c causer) Cause() error {
+	return c.err
+}
+
+func TestClassOf(t *testing.T) {
+	sentinel := New(NotFound, "Thing not found")
+
+	tests := []struct {
+		name   string
+		err    error
+		class  *Class
+		status int
+	}{
+		{
+			name:   "nil",
+			status: http.StatusInternalServerError,
+		},
+		{
+			name:   "unclassified",
+			err:    errors.New("Boom"),
+			status: http.StatusInternalServerError,
+		},
+		{
+			name:   "sentinel",
+			err:    sentinel,
+			class:  NotFound,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "wrapped",
+			err:    Wrap(Temporary, errors.New("Timeout")),
+			class:  Temporary,
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name:   "cause",
+			err:    causer{err: New(Conflict, "Not running")},
+			class:  Conflict,
+			status: http.StatusConflict,
+		},
+		{
+			name:   "outermost class",
+			err:    Wrap(Invalid, causer{err: sentinel}),
+			class:  Invalid,
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassOf(tt.err); got != tt.class {
+				t.Errorf("got class %v, want %v", got, tt.class)
+			}
+
+			if tt.class != nil && !Is(tt.err, tt.class) {
+				t.Errorf("got Is false, want true")
+			}
+
+			if got := StatusCode(tt.err); got != tt.status {
+				t.Errorf("got status %v, want %v", got, tt.status)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	if err := Wrap(Invalid, nil); err != nil {
+		t.Errorf("got %v, want nil", err)
+	}
+
+	err := errors.New("Boom")
+	if got := Wrap(Invalid, err).Error(); got != err.Error() {
+		t.Errorf("got %v, want %v", got, err.Error())
+	}
+}
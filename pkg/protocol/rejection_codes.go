@@ -23,5 +23,10 @@ var (
 		19: []byte("Frozen"),
 		20: []byte("Contract Revision incorrect"),
 		21: []byte("Asset Revision incorrect"),
+		22: []byte("Transfer Not Found"),
+		23: []byte("Transfer Expired"),
+		24: []byte("Contract Unavailable"),
+		25: []byte("Ballot Commitment Invalid"),
+		26: []byte("Message Malformed"),
 	}
 )
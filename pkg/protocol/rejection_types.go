@@ -87,4 +87,26 @@ const (
 	// RejectionCodeAssetRevision is returned when the incorrect asset
 	// revision is sent.
 	RejectionCodeAssetRevision
+
+	// RejectionCodeTransferNotFound is returned when a transfer awaiting
+	// acceptance could not be found.
+	RejectionCodeTransferNotFound
+
+	// RejectionCodeTransferExpired is returned when a transfer is accepted
+	// after it has expired.
+	RejectionCodeTransferExpired
+
+	// RejectionCodeUnavailable is returned when the contract cannot accept
+	// the request while its state is unavailable.
+	RejectionCodeUnavailable
+
+	// RejectionCodeCommitment is returned when a ballot on a commit-reveal
+	// vote is not a commitment while the vote is open, or does not reveal
+	// the commitment of the voter after it closes.
+	RejectionCodeCommitment
+
+	// RejectionCodeMalformed is returned in strict protocol mode when the
+	// OP_RETURN payload of a request is not exactly the encoding of the
+	// message it decodes to.
+	RejectionCodeMalformed
 )
@@ -0,0 +1,30 @@
+package protocol
+
+const (
+	// VotingSystemPlurality identifies a voting system where the option with
+	// the most votes wins.
+	VotingSystemPlurality = 'P'
+
+	// VotingSystemRelativeMajority identifies a voting system where the
+	// winning option must receive more than half of the votes cast.
+	VotingSystemRelativeMajority = 'R'
+
+	// VotingSystemRelativeSuperMajority identifies a voting system where the
+	// winning option must receive more than two thirds of the votes cast.
+	VotingSystemRelativeSuperMajority = 'S'
+
+	// VotingSystemAbsoluteMajority identifies a voting system where the
+	// winning option must receive votes from more than half of all tokens
+	// held.
+	VotingSystemAbsoluteMajority = 'A'
+
+	// VotingSystemAbsoluteSuperMajority identifies a voting system where the
+	// winning option must receive votes from more than two thirds of all
+	// tokens held.
+	VotingSystemAbsoluteSuperMajority = 'T'
+
+	// VotingSystemCondorcet identifies a voting system where ballots rank
+	// the options, and the option preferred to every other option head to
+	// head wins.
+	VotingSystemCondorcet = 'C'
+)
@@ -0,0 +1,162 @@
+package spvnode
+
+import (
+	"context"
+	"encoding/base32"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// SourceAddr is the Source of peers relayed by the trusted node in addr and
+// addrv2 messages.
+const SourceAddr = "addr"
+
+// AddrHandler exists to handle the Addr and AddrV2 commands.
+type AddrHandler struct {
+	Peers PeerRepository
+}
+
+// NewAddrHandler returns a new AddrHandler that stores the peers in the
+// PeerRepository.
+func NewAddrHandler(peers PeerRepository) AddrHandler {
+	return AddrHandler{
+		Peers: peers,
+	}
+}
+
+// Handle implments the Handler interface.
+//
+// This function handles type conversion and delegates the the contrete
+// handler.
+func (h AddrHandler) Handle(ctx context.Context,
+	m wire.Message) ([]wire.Message, error) {
+
+	switch msg := m.(type) {
+	case *wire.MsgAddr:
+		return h.handle(ctx, addrPeers(msg))
+
+	case *wire.MsgAddrV2:
+		return h.handle(ctx, addrV2Peers(msg))
+	}
+
+	return nil, errs.New(errs.Invalid, "Could not assert as *wire.MsgAddr or *wire.MsgAddrV2")
+}
+
+// handle stores the relayed peers.
+//
+// There is no response for this handler. A peer that is already known is
+// only updated if it was seen more recently, and keeps its Source.
+func (h AddrHandler) handle(ctx context.Context,
+	peers []Peer) ([]wire.Message, error) {
+
+	now := time.Now().UnixNano()
+
+	for _, p := range peers {
+		// a peer can't have been seen in the future
+		if p.LastSeen > now {
+			p.LastSeen = now
+		}
+
+		known, err := h.Peers.Read(ctx, p.Address)
+		if err != nil && err != ErrPeerNotFound {
+			return nil, err
+		}
+
+		if known != nil {
+			if known.LastSeen >= p.LastSeen {
+				continue
+			}
+
+			p.Source = known.Source
+		}
+
+		if err := h.Peers.Write(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// addrPeers returns the peers of the addresses in the addr message.
+func addrPeers(msg *wire.MsgAddr) []Peer {
+	peers := []Peer{}
+
+	for _, na := range msg.AddrList {
+		if na.Port == 0 || na.IP == nil || na.IP.IsUnspecified() {
+			continue
+		}
+
+		peers = append(peers, Peer{
+			Address:  net.JoinHostPort(na.IP.String(), strconv.Itoa(int(na.Port))),
+			Source:   SourceAddr,
+			LastSeen: na.Timestamp.UnixNano(),
+		})
+	}
+
+	return peers
+}
+
+// addrV2Peers returns the peers of the addresses in the addrv2 message.
+//
+// Only IPv4, IPv6 and Tor v3 addresses are kept, as the other networks
+// can't be connected to.
+func addrV2Peers(msg *wire.MsgAddrV2) []Peer {
+	peers := []Peer{}
+
+	for _, na := range msg.AddrList {
+		if na.Port == 0 {
+			continue
+		}
+
+		var host string
+
+		switch na.NetworkID {
+		case wire.NetIDIPv4, wire.NetIDIPv6:
+			ip := na.IP()
+			if ip == nil || ip.IsUnspecified() {
+				continue
+			}
+
+			host = ip.String()
+
+		case wire.NetIDTorV3:
+			host = onionV3Host(na.Addr)
+
+		default:
+			continue
+		}
+
+		peers = append(peers, Peer{
+			Address:  net.JoinHostPort(host, strconv.Itoa(int(na.Port))),
+			Source:   SourceAddr,
+			LastSeen: na.Timestamp.UnixNano(),
+		})
+	}
+
+	return peers
+}
+
+// onionV3Host returns the .onion host name of the public key of a Tor v3
+// hidden service, which is the key, its checksum and the version, in base32.
+func onionV3Host(pubKey []byte) string {
+	const version = 0x03
+
+	h := sha3.New256()
+	h.Write([]byte(".onion checksum"))
+	h.Write(pubKey)
+	h.Write([]byte{version})
+
+	b := append([]byte{}, pubKey...)
+	b = append(b, h.Sum(nil)[:2]...)
+	b = append(b, version)
+
+	return strings.ToLower(base32.StdEncoding.EncodeToString(b)) + ".onion"
+}
@@ -0,0 +1,110 @@
+package spvnode
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// backoffMin is the delay before reconnecting to an address after its
+	// first failure.
+	backoffMin = 5 * time.Second
+
+	// backoffMax is the longest delay before reconnecting to an address.
+	backoffMax = 10 * time.Minute
+)
+
+// Backoff tracks when each address may be connected to again, after
+// connections to it have failed. The delay doubles with each failure, up to
+// the Max, and is reset once a connection succeeds.
+//
+// The delay is jittered, between half and all of it, so many nodes that
+// lost the same peer don't all reconnect at once. It is safe for concurrent
+// use.
+type Backoff struct {
+	Min time.Duration
+	Max time.Duration
+
+	mu       *sync.Mutex
+	failures map[string]uint
+	retryAt  map[string]time.Time
+}
+
+// NewBackoff returns a new Backoff with delays from min to max.
+func NewBackoff(min, max time.Duration) Backoff {
+	return Backoff{
+		Min:      min,
+		Max:      max,
+		mu:       &sync.Mutex{},
+		failures: map[string]uint{},
+		retryAt:  map[string]time.Time{},
+	}
+}
+
+// Failed records a failed connection to the address, and returns the delay
+// before it may be connected to again.
+func (b Backoff) Failed(address string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failures := b.failures[address]
+
+	delay := b.Max
+	if failures < 32 && b.Min<<failures < b.Max {
+		delay = b.Min << failures
+	}
+
+	// half the delay, plus up to the other half
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+
+	b.failures[address] = failures + 1
+	b.retryAt[address] = time.Now().Add(delay)
+
+	return delay
+}
+
+// Succeeded records a successful connection to the address, resetting its
+// delay.
+func (b Backoff) Succeeded(address string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.failures, address)
+	delete(b.retryAt, address)
+}
+
+// Ready returns true if the address may be connected to now.
+func (b Backoff) Ready(address string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return !time.Now().Before(b.retryAt[address])
+}
+
+// Wait returns how long until the first of the addresses may be connected
+// to, which is 0 if one may be now.
+func (b Backoff) Wait(addresses []string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	wait := time.Duration(-1)
+
+	for _, address := range addresses {
+		d := b.retryAt[address].Sub(now)
+		if d <= 0 {
+			return 0
+		}
+
+		if wait < 0 || d < wait {
+			wait = d
+		}
+	}
+
+	if wait < 0 {
+		return 0
+	}
+
+	return wait
+}
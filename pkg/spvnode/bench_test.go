@@ -0,0 +1,60 @@
+package spvnode
+
+import (
+	"testing"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+// newBenchTxs returns n distinct TX's.
+func newBenchTxs(n int) []*wire.MsgTx {
+	txs := make([]*wire.MsgTx, n)
+
+	for i := range txs {
+		tx := wire.NewMsgTx(2)
+		tx.LockTime = uint32(i)
+		tx.AddTxOut(wire.NewTxOut(1000, []byte{0x6a}))
+
+		txs[i] = tx
+	}
+
+	return txs
+}
+
+// BenchmarkMempool_Add performs a benchmark on how long it takes to add a
+// new TX to a full Mempool, dropping the oldest.
+func BenchmarkMempool_Add(b *testing.B) {
+	m := NewMempool(Config{})
+	m.SetLimits(1000, 0)
+
+	txs := newBenchTxs(2 * m.limits.maxTxs)
+
+	for _, tx := range txs[:m.limits.maxTxs] {
+		m.Add(tx)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		m.Add(txs[(i+m.limits.maxTxs)%len(txs)])
+	}
+}
+
+// BenchmarkMempool_AddConflict performs a benchmark on how long it takes to
+// reject a TX that is already in the Mempool.
+func BenchmarkMempool_AddConflict(b *testing.B) {
+	m := NewMempool(Config{})
+	m.SetLimits(1000, 0)
+
+	txs := newBenchTxs(m.limits.maxTxs)
+
+	for _, tx := range txs {
+		m.Add(tx)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		m.Add(txs[i%len(txs)])
+	}
+}
@@ -0,0 +1,360 @@
+package spvnode
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/spvnode/logger"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+const (
+	// downloadRange is the number of blocks requested from a peer at once.
+	downloadRange = 16
+)
+
+var (
+	ErrBlockHash  = errs.New(errs.Invalid, "Block does not match the trusted header")
+	ErrMerkleRoot = errs.New(errs.Invalid, "Block transactions do not match the merkle root")
+)
+
+// BlockFetcher is a peer that blocks can be downloaded from, such as a
+// BlockPeer.
+type BlockFetcher interface {
+	Address() string
+	GetBlocks([]chainhash.Hash) ([]*wire.MsgBlock, error)
+	LoadFilter(*wire.MsgFilterLoad) error
+	AddToFilter([]byte) error
+	GetFilteredBlocks([]chainhash.Hash) ([]FilteredBlock, error)
+
+	// Latency returns the last round trip measured to the peer.
+	Latency() time.Duration
+}
+
+// BlockDownloader downloads blocks from several untrusted peers in
+// parallel.
+//
+// The blocks to download are split into ranges, and each peer is given the
+// next range as soon as it has served the last. A peer that fails, or sends
+// a block that does not match the trusted headers, is dropped and its range
+// is given to another peer.
+//
+// If a Filter is set it is loaded on each peer, and only the TX's matching
+// it are downloaded with each block.
+//
+// The Filter may be made from the first Loaded of the Filters, after they
+// were Replaced that many times. The filters added to them during the
+// download are added to the filter of each peer before its next range, and
+// the filter is reloaded if they are replaced.
+type BlockDownloader struct {
+	Peers       []BlockFetcher
+	Conformance Conformance
+	RangeSize   int
+	Filter      *wire.MsgFilterLoad
+	Filters     TxFilterList
+	Loaded      int
+	Replaced    int
+}
+
+// NewBlockDownloader returns a new BlockDownloader for the peers.
+func NewBlockDownloader(peers []BlockFetcher,
+	conformance Conformance) BlockDownloader {
+
+	return BlockDownloader{
+		Peers:       peers,
+		Conformance: conformance,
+		RangeSize:   downloadRange,
+	}
+}
+
+// blockRange is the result of downloading a range of blocks.
+type blockRange struct {
+	index  int
+	blocks []*wire.MsgBlock
+}
+
+// Download downloads the blocks with the trusted hashes, calling the func
+// with each block in the order of the hashes.
+//
+// It returns ErrNoPeers if every peer was dropped before all blocks were
+// downloaded.
+func (d BlockDownloader) Download(ctx context.Context,
+	hashes []chainhash.Hash,
+	handle func(*wire.MsgBlock) error) error {
+
+	if len(d.Peers) == 0 {
+		return ErrNoPeers
+	}
+
+	ranges := [][]chainhash.Hash{}
+	for start := 0; start < len(hashes); start += d.RangeSize {
+		end := start + d.RangeSize
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+
+		ranges = append(ranges, hashes[start:end])
+	}
+
+	// each range is queued or in flight with a single peer at a time, so
+	// the buffers never fill
+	jobs := make(chan int, len(ranges))
+	results := make(chan blockRange, len(ranges))
+	dropped := make(chan string, len(d.Peers))
+	done := make(chan struct{})
+	defer close(done)
+
+	for i := range ranges {
+		jobs <- i
+	}
+
+	for _, peer := range d.Peers {
+		go d.work(ctx, peer, ranges, jobs, results, dropped, done)
+	}
+
+	pending := map[int][]*wire.MsgBlock{}
+	alive := len(d.Peers)
+	next := 0
+
+	for next < len(ranges) {
+		select {
+		case r := <-results:
+			pending[r.index] = r.blocks
+
+		case <-dropped:
+			alive--
+			if alive == 0 {
+				return ErrNoPeers
+			}
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		// assemble the ranges that are complete, in order
+		for {
+			blocks, ok := pending[next]
+			if !ok {
+				break
+			}
+
+			for _, b := range blocks {
+				if err := handle(b); err != nil {
+					return err
+				}
+			}
+
+			delete(pending, next)
+			next++
+		}
+	}
+
+	return nil
+}
+
+// work downloads ranges from the peer, until there are none left or the
+// peer fails.
+func (d BlockDownloader) work(ctx context.Context,
+	peer BlockFetcher,
+	ranges [][]chainhash.Hash,
+	jobs chan int,
+	results chan<- blockRange,
+	dropped chan<- string,
+	done <-chan struct{}) {
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	if d.Filter != nil {
+		if err := peer.LoadFilter(d.Filter); err != nil {
+			log.Warnf("Dropping peer %v : %v", peer.Address(), err)
+
+			d.Conformance.Record(peer.Address(), AnomalyUnexpected, err)
+
+			dropped <- peer.Address()
+			return
+		}
+	}
+
+	loaded, replaced := d.Loaded, d.Replaced
+
+	for {
+		var i int
+
+		select {
+		case i = <-jobs:
+		case <-done:
+			return
+		}
+
+		var blocks []*wire.MsgBlock
+		var err error
+
+		if d.Filter != nil {
+			loaded, replaced, err = d.refilter(peer, loaded, replaced)
+		}
+
+		if err == nil {
+			blocks, err = d.fetch(peer, ranges[i])
+		}
+
+		if err != nil {
+			log.Warnf("Dropping peer %v : %v", peer.Address(), err)
+
+			anomaly := AnomalyUnexpected
+			if err == ErrBlockHash || err == ErrMerkleRoot {
+				anomaly = AnomalyMalformed
+			} else if err == ErrMessageLimit {
+				anomaly = AnomalyOversized
+			}
+
+			d.Conformance.Record(peer.Address(), anomaly, err)
+
+			jobs <- i
+			dropped <- peer.Address()
+			return
+		}
+
+		d.Conformance.Received(peer.Address())
+		d.Conformance.RecordLatency(peer.Address(), peer.Latency())
+
+		results <- blockRange{
+			index:  i,
+			blocks: blocks,
+		}
+	}
+}
+
+// refilter adds the Filters after the first loaded to the filter of the
+// peer, and returns the number now loaded, and the times the Filters were
+// replaced. A filter without elements matches every TX, so the filter is
+// reloaded to match every TX, as it is if the Filters were replaced since
+// it was loaded.
+func (d BlockDownloader) refilter(peer BlockFetcher, loaded,
+	replaced int) (int, int, error) {
+
+	filters, current := d.Filters.snapshot()
+	if current != replaced {
+		// an empty list matches every TX
+		list := NewTxFilterList(filters...)
+		return len(filters), current,
+			peer.LoadFilter(NewFilterLoad([]TxFilter{list}))
+	}
+
+	if len(filters) <= loaded {
+		return loaded, replaced, nil
+	}
+
+	for _, f := range filters[loaded:] {
+		elements := f.Elements()
+		if elements == nil {
+			return len(filters), replaced, peer.LoadFilter(NewFilterLoad(filters))
+		}
+
+		for _, e := range elements {
+			if err := peer.AddToFilter(e); err != nil {
+				return loaded, replaced, err
+			}
+		}
+	}
+
+	return len(filters), replaced, nil
+}
+
+// fetch downloads and verifies a range of blocks from the peer. With a
+// Filter, the blocks only hold the matched TX's.
+func (d BlockDownloader) fetch(peer BlockFetcher,
+	hashes []chainhash.Hash) ([]*wire.MsgBlock, error) {
+
+	if d.Filter == nil {
+		blocks, err := peer.GetBlocks(hashes)
+		if err != nil {
+			return nil, err
+		}
+
+		return blocks, verifyBlocks(hashes, blocks)
+	}
+
+	filtered, err := peer.GetFilteredBlocks(hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(filtered) != len(hashes) {
+		return nil, fmt.Errorf("Got %v blocks, want %v", len(filtered), len(hashes))
+	}
+
+	blocks := []*wire.MsgBlock{}
+
+	for i, f := range filtered {
+		if f.MerkleBlock.Header.BlockHash() != hashes[i] {
+			return nil, ErrBlockHash
+		}
+
+		b, err := f.Block()
+		if err == ErrPartialMerkleTree {
+			err = ErrMerkleRoot
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		blocks = append(blocks, b)
+	}
+
+	return blocks, nil
+}
+
+// verifyBlocks returns nil if the blocks match the trusted hashes, and the
+// transactions of each block match its merkle root.
+func verifyBlocks(hashes []chainhash.Hash, blocks []*wire.MsgBlock) error {
+	if len(blocks) != len(hashes) {
+		return fmt.Errorf("Got %v blocks, want %v", len(blocks), len(hashes))
+	}
+
+	for i, b := range blocks {
+		if b.BlockHash() != hashes[i] {
+			return ErrBlockHash
+		}
+
+		if merkleRoot(b.Transactions) != b.Header.MerkleRoot {
+			return ErrMerkleRoot
+		}
+	}
+
+	return nil
+}
+
+// merkleRoot returns the merkle root of the transactions.
+func merkleRoot(txs []*wire.MsgTx) chainhash.Hash {
+	if len(txs) == 0 {
+		return chainhash.Hash{}
+	}
+
+	level := []chainhash.Hash{}
+	for _, tx := range txs {
+		level = append(level, tx.TxHash())
+	}
+
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			// an odd level pairs the last hash with itself
+			level = append(level, level[len(level)-1])
+		}
+
+		parents := []chainhash.Hash{}
+
+		for i := 0; i < len(level); i += 2 {
+			b := append(level[i][:], level[i+1][:]...)
+			parents = append(parents, chainhash.DoubleHashH(b))
+		}
+
+		level = parents
+	}
+
+	return level[0]
+}
@@ -2,8 +2,11 @@ package spvnode
 
 import (
 	"context"
-	"errors"
+	"time"
 
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/spvnode/logger"
 	"github.com/tokenized/smart-contract/pkg/wire"
 )
 
@@ -11,16 +14,40 @@ import (
 type BlockHandler struct {
 	Config       Config
 	BlockService *BlockService
+	Mempool      Mempool
 	Listener     Listener
+	Tracker      BroadcastTracker
+	Rebroadcast  Rebroadcaster
+
+	// TXHandler is told of each block passed on, so the orphans waiting
+	// for its TX's, and the double spends it resolves, are passed on after
+	// the block.
+	TXHandler TXHandler
+
+	// Filters select the TX's of each block that are recorded, so they can
+	// be reported if the block is disconnected.
+	Filters []TxFilter
 }
 
 // NewBlockHandler returns a new BlockHandler with the given Config.
-func NewBlockHandler(config Config, blockService *BlockService, listener Listener) BlockHandler {
+func NewBlockHandler(config Config,
+	blockService *BlockService,
+	mempool Mempool,
+	listener Listener,
+	tracker BroadcastTracker,
+	rebroadcast Rebroadcaster,
+	txHandler TXHandler,
+	filters []TxFilter) BlockHandler {
 
 	return BlockHandler{
 		Config:       config,
 		BlockService: blockService,
+		Mempool:      mempool,
 		Listener:     listener,
+		Tracker:      tracker,
+		Rebroadcast:  rebroadcast,
+		TXHandler:    txHandler,
+		Filters:      filters,
 	}
 }
 
@@ -33,7 +60,7 @@ func (h BlockHandler) Handle(ctx context.Context,
 
 	msg, ok := m.(*wire.MsgBlock)
 	if !ok {
-		return nil, errors.New("Could not assert as *wire.MsgBlock")
+		return nil, errs.New(errs.Invalid, "Could not assert as *wire.MsgBlock")
 	}
 
 	return h.handle(ctx, msg)
@@ -45,8 +72,35 @@ func (h BlockHandler) Handle(ctx context.Context,
 func (h BlockHandler) handle(ctx context.Context,
 	b *wire.MsgBlock) ([]wire.Message, error) {
 
+	// the TX's of the block no longer help reconstruct compact blocks
+	h.Mempool.Confirmed(b)
+
+	txHashes := relevantTxHashes(h.Filters, b)
+
 	// if we already have this block, we don't need to ask for more
 	if h.BlockService.HasBlock(ctx, b.BlockHash()) {
+		// the body of a known block is only passed on when it was
+		// requested after a headers first sync, or a reorg
+		if h.BlockService.Unwant(b.BlockHash()) {
+			known, err := h.BlockService.Read(ctx, b.BlockHash())
+			if err != nil {
+				return nil, err
+			}
+
+			h.Tracker.Connected(b, known.Height)
+			h.Rebroadcast.Confirmed(b)
+
+			if err := h.storeKnown(ctx, *known, b, txHashes); err != nil {
+				return nil, err
+			}
+
+			if h.Listener != nil {
+				h.Listener.Handle(ctx, b)
+			}
+
+			h.TXHandler.Connected(ctx, b)
+		}
+
 		return nil, nil
 	}
 
@@ -57,30 +111,142 @@ func (h BlockHandler) handle(ctx context.Context,
 		return nil, err
 	}
 
+	if err := h.BlockService.checkHeader(ctx, &b.Header, *prevBlock); err != nil {
+		return nil, err
+	}
+
 	block := Block{
 		Hash:      b.BlockHash().String(),
 		PrevBlock: prevBlock.Hash,
 		Height:    prevBlock.Height + 1,
+		Bits:      b.Header.Bits,
+		Timestamp: b.Header.Timestamp.Unix(),
+		TxHashes:  txHashes,
+
+		MerkleRoot: b.Header.MerkleRoot.String(),
 	}
 
-	// we haven't seen this block, store it
-	if err = h.BlockService.Write(ctx, block); err != nil {
+	// we haven't seen this block, store its body, which is referenced by
+	// its header once that is committed
+	if err := h.BlockService.storeBody(ctx, &block, b); err != nil {
 		return nil, err
 	}
 
+	notify := h.shouldNotify(block) && h.Listener != nil
+
+	var reorg *Reorg
+	if notify {
+		if reorg, err = h.BlockService.findReorg(ctx, block); err != nil {
+			return nil, err
+		}
+	}
+
+	// store the header, and potentially update the "last seen" block.
+	tip, err := h.BlockService.Commit(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.BlockService.compactBodies(ctx, tip.Height); err != nil {
+		return nil, err
+	}
+
+	if reorg != nil {
+		// the connected blocks are confirmed as their bodies arrive
+		h.Tracker.Disconnected(reorg.Disconnected)
+
+		return h.reorg(ctx, *reorg)
+	}
+
+	if tip.Hash == block.Hash {
+		h.Tracker.Connected(b, block.Height)
+		h.Rebroadcast.Confirmed(b)
+	}
+
 	// do we need to send the block to the notifier?
-	if h.shouldNotify(block) && h.Listener != nil {
+	if notify {
 		h.Listener.Handle(ctx, b)
 	}
 
-	// potenitally update te "last seen" block.
-	if _, err := h.BlockService.LastSeen(ctx, block); err != nil {
-		return nil, err
+	if tip.Hash == block.Hash {
+		h.TXHandler.Connected(ctx, b)
 	}
 
 	return nil, nil
 }
 
+// reorg passes the Reorg to the Listener, and requests the bodies of the
+// connected blocks so they are passed on in order.
+//
+// The bodies of blocks that were not the tip when they arrived were never
+// passed on, so they are requested again, along with the new tip.
+func (h BlockHandler) reorg(ctx context.Context,
+	r Reorg) ([]wire.Message, error) {
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+	log.Warnf("Reorg from height %v : %v blocks disconnected, %v connected, %v TX's unconfirmed",
+		r.AncestorHeight, len(r.Disconnected), len(r.Connected), len(r.Unconfirmed))
+
+	if err := h.Listener.HandleReorg(ctx, r); err != nil {
+		log.Errorf("Failed to handle reorg : %v", err)
+	}
+
+	// the TX's are still seen, but are confirmed again by the connected
+	// blocks
+	h.TXHandler.SeenTxs.Unconfirmed(r.Unconfirmed, time.Now())
+
+	h.BlockService.Want(r.Connected)
+
+	getdata := wire.NewMsgGetData()
+	for i := range r.Connected {
+		getdata.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, &r.Connected[i]))
+	}
+
+	return []wire.Message{getdata}, nil
+}
+
+// storeKnown stores the body, or relevant TX's, of a known block, and sets
+// its TX hashes if
+// they weren't known when its header was stored, then commits the header
+// if it changed.
+func (h BlockHandler) storeKnown(ctx context.Context,
+	block Block,
+	b *wire.MsgBlock,
+	txHashes []string) error {
+
+	changed := false
+
+	if h.Listener != nil && len(block.TxHashes) == 0 && len(txHashes) > 0 {
+		block.TxHashes = txHashes
+		changed = true
+	}
+
+	if !block.Body && !block.Txs {
+		if err := h.BlockService.storeBody(ctx, &block, b); err != nil {
+			return err
+		}
+
+		changed = block.Body || block.Txs || changed
+	}
+
+	tip := block.Height
+
+	if changed {
+		last, err := h.BlockService.Commit(ctx, block)
+		if err != nil {
+			return err
+		}
+
+		tip = last.Height
+	} else if h.BlockService.State != nil &&
+		h.BlockService.State.LastSeen.Height > tip {
+
+		tip = h.BlockService.State.LastSeen.Height
+	}
+
+	return h.BlockService.compactBodies(ctx, tip)
+}
+
 func (h BlockHandler) shouldNotify(block Block) bool {
 	if !h.BlockService.synced || h.BlockService.State == nil {
 		return false
@@ -92,3 +258,13 @@ func (h BlockHandler) shouldNotify(block Block) bool {
 
 	return false
 }
+
+// blockTxHashes returns the hashes of the TX's of the block.
+func blockTxHashes(b *wire.MsgBlock) []chainhash.Hash {
+	hashes := make([]chainhash.Hash, 0, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		hashes = append(hashes, tx.TxHash())
+	}
+
+	return hashes
+}
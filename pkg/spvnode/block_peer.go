@@ -0,0 +1,474 @@
+package spvnode
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"net"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+const (
+	// peerTimeout is how long an untrusted peer has to send a range of
+	// blocks.
+	peerTimeout = 2 * time.Minute
+
+	// txTimeout is how long an untrusted peer has to send a TX, or say it
+	// doesn't have it.
+	txTimeout = 30 * time.Second
+)
+
+var (
+	ErrBlockNotServed = errs.New(errs.Temporary, "Peer did not serve the block")
+	ErrNoPeers        = errs.New(errs.Temporary, "No peers to download from")
+	ErrTxNotServed    = errs.New(errs.NotFound, "Peer did not serve the TX")
+)
+
+// BlockPeer is a connection to an untrusted peer that blocks are
+// downloaded from.
+//
+// Unlike the connection to the trusted node, a BlockPeer is used
+// synchronously, one request at a time.
+type BlockPeer struct {
+	address string
+	net     wire.BitcoinNet
+	limits  MessageLimits
+	conn    net.Conn
+
+	// latency is the last round trip measured to the peer.
+	latency *time.Duration
+
+	// feeFilter is the fee rate from the last feefilter message of the
+	// peer.
+	feeFilter *int64
+
+	// traffic counts the messages sent to and received from the peer.
+	traffic Traffic
+}
+
+// DialBlockPeer connects to the peer at the address on the network, and
+// completes the version Handshake. The connection is made with the Dialer,
+// so it goes through the proxy if one is configured. The messages of the
+// peer are checked against the limits, and counted by the Traffic.
+func DialBlockPeer(dialer Dialer,
+	network wire.BitcoinNet,
+	limits MessageLimits,
+	handshake Handshake,
+	traffic Traffic,
+	address string) (*BlockPeer, error) {
+
+	conn, err := dialer.Dial(address)
+	if err != nil {
+		return nil, err
+	}
+
+	p := BlockPeer{
+		address:   address,
+		net:       network,
+		limits:    limits,
+		conn:      conn,
+		latency:   new(time.Duration),
+		feeFilter: new(int64),
+		traffic:   traffic,
+	}
+
+	traffic.Connected(address, PeerOutbound)
+
+	if err := p.handshake(handshake); err != nil {
+		p.Close()
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Address returns the address of the peer.
+func (p BlockPeer) Address() string {
+	return p.address
+}
+
+// Latency returns the last round trip measured to the peer, from a request
+// to its first response, or 0 if none has been. The handshake is the first.
+func (p BlockPeer) Latency() time.Duration {
+	return *p.latency
+}
+
+// GetBlocks requests the blocks with the hashes, and returns them in the
+// same order.
+//
+// The blocks are returned as sent, and must be verified by the caller.
+func (p BlockPeer) GetBlocks(hashes []chainhash.Hash) ([]*wire.MsgBlock, error) {
+	if err := p.conn.SetDeadline(time.Now().Add(peerTimeout)); err != nil {
+		return nil, err
+	}
+
+	getdata := wire.NewMsgGetData()
+	for i := range hashes {
+		getdata.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, &hashes[i]))
+	}
+
+	if err := p.send(getdata); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	received := map[chainhash.Hash]*wire.MsgBlock{}
+
+	for len(received) < len(hashes) {
+		m, err := p.read()
+		if err != nil {
+			return nil, err
+		}
+
+		switch msg := m.(type) {
+		case *wire.MsgBlock:
+			if len(received) == 0 {
+				p.measured(start)
+			}
+
+			received[msg.BlockHash()] = msg
+
+		case *wire.MsgNotFound:
+			return nil, ErrBlockNotServed
+
+		case *wire.MsgPing:
+			if err := p.send(wire.NewMsgPong(msg.Nonce)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	blocks := []*wire.MsgBlock{}
+
+	for _, hash := range hashes {
+		b, ok := received[hash]
+		if !ok {
+			return nil, ErrBlockNotServed
+		}
+
+		blocks = append(blocks, b)
+	}
+
+	return blocks, nil
+}
+
+// GetTX requests the TX with the hash, which a peer only has if it is
+// unconfirmed, and waits up to the timeout for it.
+//
+// A ping is sent after the request, so a peer that ignores the request is
+// known once the pong arrives.
+func (p BlockPeer) GetTX(hash chainhash.Hash,
+	timeout time.Duration) (*wire.MsgTx, error) {
+
+	if err := p.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	getdata := wire.NewMsgGetData()
+	getdata.AddInvVect(wire.NewInvVect(wire.InvTypeTx, &hash))
+
+	if err := p.send(getdata); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	nonce := binary.LittleEndian.Uint64(buf)
+
+	if err := p.send(wire.NewMsgPing(nonce)); err != nil {
+		return nil, err
+	}
+
+	for {
+		m, err := p.read()
+		if err != nil {
+			return nil, err
+		}
+
+		switch msg := m.(type) {
+		case *wire.MsgTx:
+			// other TX's may be relayed meanwhile
+			if msg.TxHash() == hash {
+				return msg, nil
+			}
+
+		case *wire.MsgNotFound:
+			return nil, ErrTxNotServed
+
+		case *wire.MsgPing:
+			if err := p.send(wire.NewMsgPong(msg.Nonce)); err != nil {
+				return nil, err
+			}
+
+		case *wire.MsgPong:
+			if msg.Nonce == nonce {
+				return nil, ErrTxNotServed
+			}
+		}
+	}
+}
+
+// LoadFilter loads a bloom filter on the peer, so it only relays the TX's
+// that match it, and sends merkleblocks in place of blocks.
+func (p BlockPeer) LoadFilter(filter *wire.MsgFilterLoad) error {
+	if err := p.conn.SetDeadline(time.Now().Add(peerTimeout)); err != nil {
+		return err
+	}
+
+	return p.send(filter)
+}
+
+// AddToFilter adds data to the bloom filter loaded on the peer.
+func (p BlockPeer) AddToFilter(data []byte) error {
+	if err := p.conn.SetDeadline(time.Now().Add(peerTimeout)); err != nil {
+		return err
+	}
+
+	return p.send(wire.NewMsgFilterAdd(data))
+}
+
+// ClearFilter removes the bloom filter loaded on the peer.
+func (p BlockPeer) ClearFilter() error {
+	if err := p.conn.SetDeadline(time.Now().Add(peerTimeout)); err != nil {
+		return err
+	}
+
+	return p.send(wire.NewMsgFilterClear())
+}
+
+// GetFilteredBlocks requests the merkleblocks with the hashes, and returns
+// them in the same order, with the TX's that matched the loaded filter.
+//
+// A peer sends the matched TX's of a merkleblock right after it, but
+// doesn't mark the end of them, so a ping is sent after the request. The
+// pong is only sent once every merkleblock and TX has been sent.
+//
+// The blocks are returned as sent, and must be verified by the caller.
+func (p BlockPeer) GetFilteredBlocks(hashes []chainhash.Hash) ([]FilteredBlock, error) {
+	if err := p.conn.SetDeadline(time.Now().Add(peerTimeout)); err != nil {
+		return nil, err
+	}
+
+	getdata := wire.NewMsgGetData()
+	for i := range hashes {
+		getdata.AddInvVect(wire.NewInvVect(wire.InvTypeFilteredBlock, &hashes[i]))
+	}
+
+	if err := p.send(getdata); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	nonce := binary.LittleEndian.Uint64(buf)
+
+	if err := p.send(wire.NewMsgPing(nonce)); err != nil {
+		return nil, err
+	}
+
+	received := map[chainhash.Hash]*FilteredBlock{}
+	var current *FilteredBlock
+
+	for {
+		m, err := p.read()
+		if err != nil {
+			return nil, err
+		}
+
+		switch msg := m.(type) {
+		case *wire.MsgMerkleBlock:
+			if len(received) == 0 {
+				p.measured(start)
+			}
+
+			current = &FilteredBlock{
+				MerkleBlock: msg,
+			}
+
+			received[msg.Header.BlockHash()] = current
+
+		case *wire.MsgTx:
+			// TX's relayed outside of a merkleblock are ignored
+			if current != nil {
+				current.Transactions = append(current.Transactions, msg)
+			}
+
+		case *wire.MsgNotFound:
+			return nil, ErrBlockNotServed
+
+		case *wire.MsgPing:
+			if err := p.send(wire.NewMsgPong(msg.Nonce)); err != nil {
+				return nil, err
+			}
+
+		case *wire.MsgPong:
+			if msg.Nonce != nonce {
+				continue
+			}
+
+			blocks := []FilteredBlock{}
+
+			for _, hash := range hashes {
+				b, ok := received[hash]
+				if !ok {
+					return nil, ErrBlockNotServed
+				}
+
+				blocks = append(blocks, *b)
+			}
+
+			return blocks, nil
+		}
+	}
+}
+
+// Ping checks that the peer is still connected and responding, answering
+// any pings it sent while the connection was idle.
+func (p BlockPeer) Ping() error {
+	if err := p.conn.SetDeadline(time.Now().Add(peerTimeout)); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	nonce := binary.LittleEndian.Uint64(buf)
+
+	if err := p.send(wire.NewMsgPing(nonce)); err != nil {
+		return err
+	}
+
+	start := time.Now()
+
+	for {
+		m, err := p.read()
+		if err != nil {
+			return err
+		}
+
+		switch msg := m.(type) {
+		case *wire.MsgPing:
+			if err := p.send(wire.NewMsgPong(msg.Nonce)); err != nil {
+				return err
+			}
+
+		case *wire.MsgPong:
+			if msg.Nonce == nonce {
+				p.measured(start)
+				return nil
+			}
+		}
+	}
+}
+
+// FeeFilter returns the lowest fee rate of the TX's the peer wants to be
+// sent, in satoshis per 1000 bytes, from its last feefilter message. It is
+// 0 if the peer hasn't sent one.
+func (p BlockPeer) FeeFilter() int64 {
+	return *p.feeFilter
+}
+
+// Close closes the connection to the peer.
+func (p BlockPeer) Close() error {
+	p.traffic.Disconnected(p.address)
+	return p.conn.Close()
+}
+
+// handshake exchanges version messages with the peer, and checks its
+// version against the Handshake.
+func (p BlockPeer) handshake(h Handshake) error {
+	if err := p.conn.SetDeadline(time.Now().Add(h.Timeout)); err != nil {
+		return err
+	}
+
+	local := wire.NewNetAddressIPPort(net.IPv4(127, 0, 0, 1), 9333, 0)
+	remote := wire.NewNetAddressIPPort(net.IPv4(127, 0, 0, 1), 8333, 0)
+
+	buf := make([]byte, 8)
+	rand.Read(buf)
+
+	msg := wire.NewMsgVersion(remote, local, binary.LittleEndian.Uint64(buf), 0)
+	msg.UserAgent = h.UserAgent
+	msg.Services = h.Services
+	msg.DisableRelayTx = h.DisableRelay
+
+	if err := p.send(msg); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	version, verack := false, false
+	protocol := uint32(0)
+
+	for !version || !verack {
+		m, err := p.read()
+		if err != nil {
+			return handshakeError(err)
+		}
+
+		switch msg := m.(type) {
+		case *wire.MsgVersion:
+			if err := h.Check(msg); err != nil {
+				return err
+			}
+
+			version = true
+			protocol = uint32(msg.ProtocolVersion)
+			p.measured(start)
+
+			if err := p.send(wire.NewMsgVerAck()); err != nil {
+				return err
+			}
+
+		case *wire.MsgVerAck:
+			verack = true
+		}
+	}
+
+	if h.FeeFilter > 0 && protocol >= wire.FeeFilterVersion {
+		return p.send(wire.NewMsgFeeFilter(h.FeeFilter))
+	}
+
+	return nil
+}
+
+// read reads the next message from the peer, and records its feefilter
+// messages.
+func (p BlockPeer) read() (wire.Message, error) {
+	n, m, err := p.limits.ReadN(p.conn, p.net)
+	p.traffic.Received(p.address, command(m), n)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg, ok := m.(*wire.MsgFeeFilter); ok {
+		*p.feeFilter = msg.MinFee
+	}
+
+	return m, nil
+}
+
+// measured sets the Latency to the time since the request was sent.
+func (p BlockPeer) measured(start time.Time) {
+	*p.latency = time.Since(start)
+}
+
+// send writes a message to the peer.
+func (p BlockPeer) send(m wire.Message) error {
+	var buf bytes.Buffer
+
+	if err := wire.WriteMessage(&buf, m, wire.ProtocolVersion, p.net); err != nil {
+		return err
+	}
+
+	n, err := p.conn.Write(buf.Bytes())
+	p.traffic.Sent(p.address, m.Command(), n)
+
+	return err
+}
@@ -1,22 +1,61 @@
 package spvnode
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"os"
 
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/storage"
+	"github.com/tokenized/smart-contract/pkg/wire"
 )
 
 // ErrBlockNotFound is returns when a requested item is not found.
-var ErrBlockNotFound = errors.New("Block not found")
+var ErrBlockNotFound = errs.New(errs.NotFound, "Block not found")
+
+// ErrBlockBodyNotFound is returned when the full block is not stored.
+var ErrBlockBodyNotFound = errs.New(errs.NotFound, "Block body not found")
+
+// ErrBlockTxsNotFound is returned when the relevant TX's of a block are not
+// stored.
+var ErrBlockTxsNotFound = errs.New(errs.NotFound, "Block TX's not found")
 
 // Block represents a block on the blockchain.
 type Block struct {
 	Hash      string `json:"hash"`
 	PrevBlock string `json:"prev_block"`
 	Height    int32  `json:"height"`
+
+	// Bits and Timestamp are of the header of the block, so the difficulty
+	// of the blocks after it can be checked.
+	Bits      uint32 `json:"bits,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+
+	// MerkleRoot is of the header of the block, so merkle proofs of its
+	// TX's can be verified.
+	MerkleRoot string `json:"merkle_root,omitempty"`
+
+	// Body is true if the full block is stored, along with the header.
+	Body bool `json:"body,omitempty"`
+
+	// Txs is true if the relevant TX's of the block are stored, with their
+	// merkle proofs, in place of the full block.
+	Txs bool `json:"txs,omitempty"`
+
+	// TxHashes are the hashes of the relevant TX's of the block, so they
+	// can be reported as unconfirmed if the block is disconnected. They are
+	// only known for blocks whose bodies were received.
+	TxHashes []string `json:"tx_hashes,omitempty"`
+}
+
+// BlockTx is a relevant TX of a block, as it is stored in place of the
+// full block, with the proof that it is in the block.
+type BlockTx struct {
+	// Tx is the serialized TX.
+	Tx    []byte      `json:"tx"`
+	Proof MerkleProof `json:"proof"`
 }
 
 // BlockRepository is used for managing Block data.
@@ -98,6 +137,91 @@ func (r BlockRepository) Remove(ctx context.Context, b Block) error {
 	return r.Storage.Remove(ctx, r.buildPath(b.Hash))
 }
 
+// WriteBody stores the full block.
+func (r BlockRepository) WriteBody(ctx context.Context, b *wire.MsgBlock) error {
+	var buf bytes.Buffer
+	if err := b.Serialize(&buf); err != nil {
+		return err
+	}
+
+	key := r.buildBodyPath(b.BlockHash().String())
+
+	return r.Storage.Write(ctx, key, buf.Bytes(), nil)
+}
+
+// ReadBody reads the full block.
+func (r BlockRepository) ReadBody(ctx context.Context, id string) (*wire.MsgBlock, error) {
+	data, err := r.Storage.Read(ctx, r.buildBodyPath(id))
+	if err != nil {
+		if err == storage.ErrNotFound {
+			err = ErrBlockBodyNotFound
+		}
+
+		return nil, err
+	}
+
+	b := wire.MsgBlock{}
+	if err := b.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// RemoveBody removes the full block from storage, keeping the header. It
+// is not an error if the body is already removed.
+func (r BlockRepository) RemoveBody(ctx context.Context, id string) error {
+	err := r.Storage.Remove(ctx, r.buildBodyPath(id))
+	if err == storage.ErrNotFound || os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// WriteTxs stores the relevant TX's of the block, in place of the full
+// block.
+func (r BlockRepository) WriteTxs(ctx context.Context, id string, txs []BlockTx) error {
+	b, err := json.Marshal(txs)
+	if err != nil {
+		return err
+	}
+
+	return r.Storage.Write(ctx, r.buildTxsPath(id), b, nil)
+}
+
+// ReadTxs reads the relevant TX's of the block.
+func (r BlockRepository) ReadTxs(ctx context.Context, id string) ([]BlockTx, error) {
+	data, err := r.Storage.Read(ctx, r.buildTxsPath(id))
+	if err != nil {
+		if err == storage.ErrNotFound {
+			err = ErrBlockTxsNotFound
+		}
+
+		return nil, err
+	}
+
+	txs := []BlockTx{}
+	if err := json.Unmarshal(data, &txs); err != nil {
+		return nil, err
+	}
+
+	return txs, nil
+}
+
 func (r BlockRepository) buildPath(id string) string {
+	return blockPath(id)
+}
+
+// blockPath returns the storage key of the Block with the hash.
+func blockPath(id string) string {
 	return fmt.Sprintf("blocks/%v", id)
 }
+
+func (r BlockRepository) buildBodyPath(id string) string {
+	return fmt.Sprintf("block_bodies/%v", id)
+}
+
+func (r BlockRepository) buildTxsPath(id string) string {
+	return fmt.Sprintf("block_txs/%v", id)
+}
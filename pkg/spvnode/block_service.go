@@ -1,10 +1,14 @@
 package spvnode
 
 import (
+	"bytes"
 	"context"
+	"sort"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/spvnode/logger"
+	"github.com/tokenized/smart-contract/pkg/wire"
 )
 
 const (
@@ -13,21 +17,76 @@ const (
 	// At approximately 1 block every 10 minutes, 10000 blocks is roughly
 	// a week worth of blocks.
 	maxBlocks = 10000
+
+	// locatorDense is the number of most recent blocks of a locator that are
+	// one apart, before the step between them doubles.
+	locatorDense = 10
+)
+
+// ErrNotInChain is returned for a known block that is not on the chain of
+// the last seen block.
+var ErrNotInChain = errs.New(errs.NotFound, "Block not in chain")
+
+// Direction is the order that WalkHeaders visits the headers in.
+type Direction int
+
+const (
+	// Ascending visits the headers from the height up to the last seen
+	// block.
+	Ascending Direction = iota
+
+	// Descending visits the headers from the height, or the last seen
+	// block if it is lower, down to the oldest stored header.
+	Descending
 )
 
+// HeaderFunc is called with each header of a walk of the chain. The walk
+// stops if it returns false.
+type HeaderFunc func(Block) bool
+
 type BlockService struct {
 	BlockRepostory  BlockRepository
 	StateRepository StateRepository
+	Journal         Journal
 	Blocks          map[chainhash.Hash]Block
 	State           *State
 	synced          bool
+
+	// keepFrom is the height that blocks are kept from when pruning, so
+	// the bodies from that height can be requested. 0 keeps no extra blocks.
+	keepFrom int32
+
+	// keepBodies is the number of recent blocks whose bodies are stored.
+	// Older bodies are removed, unless they have relevant TX's. No bodies
+	// are stored if it is 0.
+	keepBodies int32
+
+	// keepTxs is true if only the relevant TX's of blocks are stored, with
+	// their merkle proofs, in place of the bodies.
+	keepTxs bool
+
+	// wanted are the known blocks whose bodies have been requested.
+	wanted map[chainhash.Hash]bool
+
+	// rules are the proof of work rules headers are checked against.
+	rules HeaderRules
+
+	// checkpoints are the known hashes of blocks, lowest first.
+	checkpoints []Checkpoint
 }
 
-func NewBlockService(br BlockRepository, sr StateRepository) BlockService {
+func NewBlockService(br BlockRepository,
+	sr StateRepository,
+	journal Journal) BlockService {
+
 	return BlockService{
 		BlockRepostory:  br,
 		StateRepository: sr,
+		Journal:         journal,
 		Blocks:          map[chainhash.Hash]Block{},
+		wanted:          map[chainhash.Hash]bool{},
+		rules:           NetworkHeaderRules[MainNetBch],
+		checkpoints:     NetworkCheckpoints[MainNetBch],
 	}
 }
 
@@ -84,7 +143,297 @@ func (b *BlockService) Write(ctx context.Context, block Block) error {
 	return nil
 }
 
-func (b BlockService) LastSeen(ctx context.Context,
+// Chain returns the hashes of the blocks from the height to the last seen
+// block, lowest first, following the trusted headers back from the last
+// seen block.
+func (b BlockService) Chain(ctx context.Context,
+	from int32) ([]chainhash.Hash, error) {
+
+	hashes := []chainhash.Hash{}
+
+	if b.State == nil {
+		return hashes, nil
+	}
+
+	block := b.State.LastSeen
+
+	for block.Hash != "" && block.Height >= from {
+		h, err := chainhash.NewHashFromStr(block.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		hashes = append(hashes, *h)
+
+		if block.Height == from {
+			break
+		}
+
+		prev, err := chainhash.NewHashFromStr(block.PrevBlock)
+		if err != nil {
+			return nil, err
+		}
+
+		p, err := b.Read(ctx, *prev)
+		if err != nil {
+			return nil, err
+		}
+
+		block = *p
+	}
+
+	// reverse, so the lowest block is first
+	for i, j := 0, len(hashes)-1; i < j; i, j = i+1, j-1 {
+		hashes[i], hashes[j] = hashes[j], hashes[i]
+	}
+
+	return hashes, nil
+}
+
+// Ancestor returns the block at the height on the chain of the last seen
+// block, following the headers back from it. ErrBlockNotFound is returned if
+// the height is above the last seen block, or below the stored headers.
+func (b BlockService) Ancestor(ctx context.Context,
+	height int32) (*Block, error) {
+
+	if b.State == nil || height < 0 || height > b.State.LastSeen.Height {
+		return nil, ErrBlockNotFound
+	}
+
+	block := b.State.LastSeen
+
+	for block.Height > height {
+		prev, err := chainhash.NewHashFromStr(block.PrevBlock)
+		if err != nil {
+			return nil, err
+		}
+
+		p, err := b.Read(ctx, *prev)
+		if err != nil {
+			return nil, err
+		}
+
+		block = *p
+	}
+
+	return &block, nil
+}
+
+// Height returns the height of the block, if it is on the chain of the last
+// seen block. ErrNotInChain is returned for a block of another chain.
+func (b BlockService) Height(ctx context.Context,
+	hash chainhash.Hash) (int32, error) {
+
+	block, err := b.Read(ctx, hash)
+	if err != nil {
+		return 0, err
+	}
+
+	ancestor, err := b.Ancestor(ctx, block.Height)
+	if err == ErrBlockNotFound {
+		return 0, ErrNotInChain
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if ancestor.Hash != block.Hash {
+		return 0, ErrNotInChain
+	}
+
+	return block.Height, nil
+}
+
+// Locator returns the hashes of a block locator of the chain of the last
+// seen block, most recent first. The most recent blocks are one apart, then
+// the step between them doubles, back to the oldest stored header, which
+// ends it.
+func (b BlockService) Locator(ctx context.Context) ([]chainhash.Hash, error) {
+	hashes := []chainhash.Hash{}
+
+	if b.State == nil || b.State.LastSeen.Hash == "" {
+		return hashes, nil
+	}
+
+	block := b.State.LastSeen
+	step := int32(1)
+
+	for {
+		h, err := chainhash.NewHashFromStr(block.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		hashes = append(hashes, *h)
+
+		if len(hashes) > locatorDense {
+			step *= 2
+		}
+
+		height := block.Height - step
+		if height < 0 {
+			return hashes, nil
+		}
+
+		for block.Height > height {
+			prev, err := chainhash.NewHashFromStr(block.PrevBlock)
+			if err != nil {
+				return nil, err
+			}
+
+			p, err := b.Read(ctx, *prev)
+			if err == ErrBlockNotFound {
+				// the older headers are not stored, so end with the oldest
+				// that is
+				if h, err := chainhash.NewHashFromStr(block.Hash); err == nil &&
+					*h != hashes[len(hashes)-1] {
+					hashes = append(hashes, *h)
+				}
+
+				return hashes, nil
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			block = *p
+		}
+	}
+}
+
+// WalkHeaders calls the func with each header of the chain of the last seen
+// block, from the height in the direction, until it returns false or the
+// chain ends.
+//
+// Headers are read one at a time. An Ascending walk first follows the
+// chain back to the height, so it holds the hashes of the headers it will
+// visit, but not the headers. It starts from the oldest stored header if
+// the height is below it.
+func (b BlockService) WalkHeaders(ctx context.Context, from int32,
+	direction Direction, fn HeaderFunc) error {
+
+	if b.State == nil || b.State.LastSeen.Hash == "" {
+		return nil
+	}
+
+	if direction == Descending {
+		if from > b.State.LastSeen.Height {
+			from = b.State.LastSeen.Height
+		}
+
+		block, err := b.Ancestor(ctx, from)
+		if err == ErrBlockNotFound {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		for fn(*block) && block.Height > 0 {
+			prev, err := chainhash.NewHashFromStr(block.PrevBlock)
+			if err != nil {
+				return err
+			}
+
+			block, err = b.Read(ctx, *prev)
+			if err == ErrBlockNotFound {
+				// the older headers are not stored
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	hashes := []chainhash.Hash{}
+	block := b.State.LastSeen
+
+	for block.Height >= from {
+		h, err := chainhash.NewHashFromStr(block.Hash)
+		if err != nil {
+			return err
+		}
+
+		hashes = append(hashes, *h)
+
+		if block.Height == from || block.Height == 0 {
+			break
+		}
+
+		prev, err := chainhash.NewHashFromStr(block.PrevBlock)
+		if err != nil {
+			return err
+		}
+
+		p, err := b.Read(ctx, *prev)
+		if err == ErrBlockNotFound {
+			// start from the oldest stored header
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		block = *p
+	}
+
+	for i := len(hashes) - 1; i >= 0; i-- {
+		block, err := b.Read(ctx, hashes[i])
+		if err != nil {
+			return err
+		}
+
+		if !fn(*block) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Want records that the bodies of the known blocks have been requested.
+func (b BlockService) Want(hashes []chainhash.Hash) {
+	for _, hash := range hashes {
+		b.wanted[hash] = true
+	}
+}
+
+// Wanted returns the known blocks whose bodies have been requested, but not
+// yet received, lowest first.
+func (b BlockService) Wanted() []chainhash.Hash {
+	hashes := []chainhash.Hash{}
+	for hash := range b.wanted {
+		hashes = append(hashes, hash)
+	}
+
+	sort.Slice(hashes, func(i, j int) bool {
+		return b.Blocks[hashes[i]].Height < b.Blocks[hashes[j]].Height
+	})
+
+	return hashes
+}
+
+// Unwant returns true if the body of the known block was requested, and
+// stops wanting it.
+func (b BlockService) Unwant(hash chainhash.Hash) bool {
+	if !b.wanted[hash] {
+		return false
+	}
+
+	delete(b.wanted, hash)
+
+	return true
+}
+
+func (b *BlockService) LastSeen(ctx context.Context,
 	block Block) (*Block, error) {
 
 	if b.State != nil && block.Height <= b.State.LastSeen.Height {
@@ -111,6 +460,106 @@ func (b BlockService) LastSeen(ctx context.Context,
 	return &block, nil
 }
 
+// Commit writes the header of the block, and makes it the last seen block
+// if it is higher, as one commit journaled by the Journal. Its body, or
+// relevant TX's, must already be stored. It returns the last seen block.
+func (b *BlockService) Commit(ctx context.Context, block Block) (*Block, error) {
+	e := JournalEntry{
+		Blocks: []Block{block},
+	}
+
+	if b.State == nil || block.Height > b.State.LastSeen.Height {
+		e.State = &State{
+			LastSeen: block,
+		}
+	}
+
+	if err := b.Journal.Write(ctx, e); err != nil {
+		return nil, err
+	}
+
+	if err := b.apply(ctx, e); err != nil {
+		return nil, err
+	}
+
+	if err := b.Journal.Remove(ctx); err != nil {
+		return nil, err
+	}
+
+	return &b.State.LastSeen, nil
+}
+
+// Recover completes the commit that was in progress when the Node last
+// stopped, if any, and returns true if there was one. The headers of the
+// commit whose bodies, or relevant TX's, are missing are written without
+// them.
+//
+// It must be called before the State and blocks are loaded.
+func (b *BlockService) Recover(ctx context.Context) (bool, error) {
+	e, err := b.Journal.Read(ctx)
+	if err == ErrJournalNotFound {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	for i, block := range e.Blocks {
+		if block.Body {
+			_, err := b.BlockRepostory.ReadBody(ctx, block.Hash)
+			if err == ErrBlockBodyNotFound {
+				block.Body = false
+			} else if err != nil {
+				return false, err
+			}
+		}
+
+		if block.Txs {
+			_, err := b.BlockRepostory.ReadTxs(ctx, block.Hash)
+			if err == ErrBlockTxsNotFound {
+				block.Txs = false
+			} else if err != nil {
+				return false, err
+			}
+		}
+
+		e.Blocks[i] = block
+
+		if e.State != nil && e.State.LastSeen.Hash == block.Hash {
+			e.State.LastSeen = block
+		}
+	}
+
+	if err := b.apply(ctx, *e); err != nil {
+		return false, err
+	}
+
+	if err := b.Journal.Remove(ctx); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// apply makes the writes of the journaled commit. They may be made again,
+// if they were interrupted.
+func (b *BlockService) apply(ctx context.Context, e JournalEntry) error {
+	for _, block := range e.Blocks {
+		if err := b.Write(ctx, block); err != nil {
+			return err
+		}
+	}
+
+	if e.State == nil {
+		return nil
+	}
+
+	_, err := b.LastSeen(ctx, e.State.LastSeen)
+
+	return err
+}
+
 func (b BlockService) Remove(ctx context.Context, block Block) error {
 	if err := b.BlockRepostory.Remove(ctx, block); err != nil {
 		return err
@@ -129,15 +578,29 @@ func (b BlockService) prune(ctx context.Context, max int32) error {
 
 	// delete any blocks with a height less than this
 	minHeight := max - maxBlocks
+	if b.keepFrom > 0 && b.keepFrom < minHeight {
+		minHeight = b.keepFrom
+	}
 
 	for k, block := range b.Blocks {
 		if block.Height < minHeight {
+			if (block.Body || block.Txs) && len(block.TxHashes) > 0 {
+				// the blocks with relevant TX's are kept
+				continue
+			}
 
-			// delete from the store.
+			// delete from the store, before the body, so a failure part
+			// way leaves an unreferenced body.
 			if err := b.BlockRepostory.Remove(ctx, block); err != nil {
 				return err
 			}
 
+			if block.Body {
+				if err := b.BlockRepostory.RemoveBody(ctx, block.Hash); err != nil {
+					return err
+				}
+			}
+
 			// maybe link the store and the hash.
 			delete(b.Blocks, k)
 		}
@@ -146,6 +609,310 @@ func (b BlockService) prune(ctx context.Context, max int32) error {
 	return nil
 }
 
+// ReadBody returns the full block, if it is stored.
+func (b BlockService) ReadBody(ctx context.Context,
+	hash chainhash.Hash) (*wire.MsgBlock, error) {
+
+	return b.BlockRepostory.ReadBody(ctx, hash.String())
+}
+
+// ReadTx returns the relevant TX from the stored body, or relevant TX's, of
+// the block it was confirmed in, or ErrTxNotFound if none has it.
+func (b BlockService) ReadTx(ctx context.Context,
+	hash chainhash.Hash) (*wire.MsgTx, error) {
+
+	id := hash.String()
+
+	for blockHash, block := range b.Blocks {
+		if !hasTxHash(block, id) {
+			continue
+		}
+
+		if block.Txs {
+			tx, err := b.readBlockTx(ctx, blockHash, hash)
+			if err == ErrTxNotFound || err == ErrBlockTxsNotFound {
+				continue
+			}
+
+			return tx, err
+		}
+
+		if !block.Body {
+			continue
+		}
+
+		body, err := b.ReadBody(ctx, blockHash)
+		if err == ErrBlockBodyNotFound {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tx := range body.Transactions {
+			if tx.TxHash() == hash {
+				return tx, nil
+			}
+		}
+	}
+
+	return nil, ErrTxNotFound
+}
+
+// ReindexStats are the results of a Reindex.
+type ReindexStats struct {
+	// Blocks is the number of stored bodies that were scanned, and Changed
+	// is the number of those whose relevant TX's changed.
+	Blocks  int
+	Changed int
+
+	// Txs is the number of relevant TX's found.
+	Txs int
+}
+
+// Reindex scans the stored block bodies against the filters, and records
+// their relevant TX's again, so TX's that match filters added since the
+// blocks were received are found by ReadTx, and their bodies are kept.
+//
+// Only the blocks whose bodies are still stored can be scanned.
+func (b *BlockService) Reindex(ctx context.Context,
+	filters []TxFilter) (ReindexStats, error) {
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	stats := ReindexStats{}
+
+	blocks, err := b.BlockRepostory.All(ctx)
+	if err != nil {
+		return stats, err
+	}
+
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Height < blocks[j].Height
+	})
+
+	for _, block := range blocks {
+		if !block.Body {
+			continue
+		}
+
+		hash, err := chainhash.NewHashFromStr(block.Hash)
+		if err != nil {
+			return stats, err
+		}
+
+		body, err := b.ReadBody(ctx, *hash)
+		if err == ErrBlockBodyNotFound {
+			log.Warnf("Body of block %v height %v is missing", block.Hash,
+				block.Height)
+			continue
+		}
+
+		if err != nil {
+			return stats, err
+		}
+
+		txHashes := relevantTxHashes(filters, body)
+
+		stats.Blocks++
+		stats.Txs += len(txHashes)
+
+		if sameStrings(block.TxHashes, txHashes) {
+			continue
+		}
+
+		block.TxHashes = txHashes
+
+		if err := b.Write(ctx, block); err != nil {
+			return stats, err
+		}
+
+		stats.Changed++
+	}
+
+	return stats, nil
+}
+
+// sameStrings returns true if the slices hold the same strings, in order.
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hasTxHash returns true if the TX is one of the relevant TX's of the block.
+func hasTxHash(block Block, hash string) bool {
+	for _, h := range block.TxHashes {
+		if h == hash {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReadProof returns the stored merkle proof of the relevant TX of the
+// block, or ErrTxNotFound if it is not stored.
+func (b BlockService) ReadProof(ctx context.Context,
+	blockHash chainhash.Hash,
+	txHash chainhash.Hash) (*MerkleProof, error) {
+
+	txs, err := b.BlockRepostory.ReadTxs(ctx, blockHash.String())
+	if err == ErrBlockTxsNotFound {
+		return nil, ErrTxNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tx := range txs {
+		if tx.Proof.TxHash == txHash.String() {
+			proof := tx.Proof
+			return &proof, nil
+		}
+	}
+
+	return nil, ErrTxNotFound
+}
+
+// readBlockTx returns the relevant TX from the stored TX's of the block,
+// or ErrTxNotFound if it isn't one of them.
+func (b BlockService) readBlockTx(ctx context.Context,
+	blockHash chainhash.Hash,
+	txHash chainhash.Hash) (*wire.MsgTx, error) {
+
+	txs, err := b.BlockRepostory.ReadTxs(ctx, blockHash.String())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range txs {
+		if t.Proof.TxHash != txHash.String() {
+			continue
+		}
+
+		tx := wire.MsgTx{}
+		if err := tx.Deserialize(bytes.NewReader(t.Tx)); err != nil {
+			return nil, err
+		}
+
+		return &tx, nil
+	}
+
+	return nil, ErrTxNotFound
+}
+
+// storeBody stores the full block of the header, if bodies are kept and it
+// isn't already, and sets Body. The header must then be committed, so the
+// body is stored before any header that references it.
+//
+// If only relevant TX's are kept, those of the TxHashes are stored in its
+// place, and Txs is set.
+func (b *BlockService) storeBody(ctx context.Context,
+	block *Block,
+	body *wire.MsgBlock) error {
+
+	if b.keepTxs {
+		return b.storeTxs(ctx, block, body)
+	}
+
+	if b.keepBodies <= 0 || block.Body {
+		return nil
+	}
+
+	if err := b.BlockRepostory.WriteBody(ctx, body); err != nil {
+		return err
+	}
+
+	block.Body = true
+
+	return nil
+}
+
+// storeTxs stores the relevant TX's of the block, with their merkle
+// proofs, and sets Txs. Nothing is stored for a block without relevant
+// TX's.
+func (b *BlockService) storeTxs(ctx context.Context,
+	block *Block,
+	body *wire.MsgBlock) error {
+
+	if block.Txs || len(block.TxHashes) == 0 {
+		return nil
+	}
+
+	txs := []BlockTx{}
+
+	for _, tx := range body.Transactions {
+		hash := tx.TxHash()
+		if !hasTxHash(*block, hash.String()) {
+			continue
+		}
+
+		proof, err := NewMerkleProof(body, hash)
+		if err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		if err := tx.Serialize(&buf); err != nil {
+			return err
+		}
+
+		txs = append(txs, BlockTx{
+			Tx:    buf.Bytes(),
+			Proof: *proof,
+		})
+	}
+
+	if err := b.BlockRepostory.WriteTxs(ctx, block.Hash, txs); err != nil {
+		return err
+	}
+
+	block.Txs = true
+
+	return nil
+}
+
+// compactBodies removes the stored bodies of blocks more than keepBodies
+// below the tip, except those with relevant TX's. The header is updated
+// before the body is removed, so a failure part way leaves an unreferenced
+// body rather than a header whose body is missing.
+func (b *BlockService) compactBodies(ctx context.Context, tip int32) error {
+	if b.keepBodies <= 0 {
+		return nil
+	}
+
+	minHeight := tip - b.keepBodies + 1
+
+	for _, block := range b.Blocks {
+		if !block.Body || block.Height >= minHeight || len(block.TxHashes) > 0 {
+			continue
+		}
+
+		block.Body = false
+
+		if err := b.Write(ctx, block); err != nil {
+			return err
+		}
+
+		if err := b.BlockRepostory.RemoveBody(ctx, block.Hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (b *BlockService) LoadState(ctx context.Context) (*State, error) {
 	state, err := b.StateRepository.Read(ctx, "")
 
@@ -0,0 +1,331 @@
+package spvnode
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tokenized/smart-contract/pkg/storage"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+var errCrash = errors.New("crash")
+
+type memoryStorage map[string][]byte
+
+func (m memoryStorage) Read(ctx context.Context, key string) ([]byte, error) {
+	b, ok := m[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	return b, nil
+}
+
+func (m memoryStorage) Write(ctx context.Context,
+	key string,
+	body []byte,
+	opts *storage.Options) error {
+
+	m[key] = body
+	return nil
+}
+
+func (m memoryStorage) Remove(ctx context.Context, key string) error {
+	if _, ok := m[key]; !ok {
+		return storage.ErrNotFound
+	}
+
+	delete(m, key)
+	return nil
+}
+
+func (m memoryStorage) Search(ctx context.Context,
+	query map[string]string) ([][]byte, error) {
+
+	prefix := query["path"] + "/"
+
+	objects := [][]byte{}
+	for key, b := range m {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, b)
+		}
+	}
+
+	return objects, nil
+}
+
+// crashingStorage is a memoryStorage that stops making writes and removes
+// from the one numbered crashAt, starting at 1, as if the process crashed.
+type crashingStorage struct {
+	memoryStorage
+	crashAt int
+	count   *int
+}
+
+func (c crashingStorage) crashed() bool {
+	*c.count++
+	return *c.count >= c.crashAt
+}
+
+func (c crashingStorage) Write(ctx context.Context,
+	key string,
+	body []byte,
+	opts *storage.Options) error {
+
+	if c.crashed() {
+		return errCrash
+	}
+
+	return c.memoryStorage.Write(ctx, key, body, opts)
+}
+
+func (c crashingStorage) Remove(ctx context.Context, key string) error {
+	if c.crashed() {
+		return errCrash
+	}
+
+	return c.memoryStorage.Remove(ctx, key)
+}
+
+func newTestBlockService(store storage.Storage) BlockService {
+	return NewBlockService(NewBlockRepository(store), NewStateRepository(store),
+		NewJournal(store))
+}
+
+// restart returns a BlockService started on the store, as the Node does.
+func restart(t *testing.T, ctx context.Context, store storage.Storage) (BlockService, bool) {
+	b := newTestBlockService(store)
+
+	recovered, err := b.Recover(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.LoadState(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.LoadBlocks(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	return b, recovered
+}
+
+func newBlock(height int32) Block {
+	hash := chainhash.DoubleHashH([]byte{byte(height)})
+	prev := chainhash.DoubleHashH([]byte{byte(height - 1)})
+
+	return Block{
+		Hash:      hash.String(),
+		PrevBlock: prev.String(),
+		Height:    height,
+	}
+}
+
+// TestBlockService_Recover tests that a commit interrupted at each of its
+// writes is either not made at all, or completed by Recover.
+func TestBlockService_Recover(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+
+		// crashAt is the write of the commit that is interrupted: the
+		// journal, the header, the state, then removing the journal.
+		crashAt       int
+		wantRecovered bool
+		wantHeight    int32
+	}{
+		{
+			name:       "journal",
+			crashAt:    1,
+			wantHeight: 1,
+		},
+		{
+			name:          "header",
+			crashAt:       2,
+			wantRecovered: true,
+			wantHeight:    2,
+		},
+		{
+			name:          "state",
+			crashAt:       3,
+			wantRecovered: true,
+			wantHeight:    2,
+		},
+		{
+			name:          "journal removal",
+			crashAt:       4,
+			wantRecovered: true,
+			wantHeight:    2,
+		},
+		{
+			name:       "not interrupted",
+			crashAt:    5,
+			wantHeight: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memoryStorage{}
+
+			b, _ := restart(t, ctx, store)
+			if _, err := b.Commit(ctx, newBlock(1)); err != nil {
+				t.Fatal(err)
+			}
+
+			count := 0
+			b.BlockRepostory.Storage = crashingStorage{store, tt.crashAt, &count}
+			b.StateRepository.Storage = b.BlockRepostory.Storage
+			b.Journal.Storage = b.BlockRepostory.Storage
+
+			_, err := b.Commit(ctx, newBlock(2))
+			if (err == errCrash) != (tt.crashAt <= 4) {
+				t.Fatalf("got error %v, crashed at %v", err, tt.crashAt)
+			}
+
+			b, recovered := restart(t, ctx, store)
+			if recovered != tt.wantRecovered {
+				t.Fatalf("got recovered %v, want %v", recovered, tt.wantRecovered)
+			}
+
+			if _, ok := store[JournalKey]; ok {
+				t.Fatal("journal not removed")
+			}
+
+			if b.State.LastSeen.Height != tt.wantHeight {
+				t.Fatalf("got last seen height %v, want %v",
+					b.State.LastSeen.Height, tt.wantHeight)
+			}
+
+			// the last seen block has a header
+			h, _ := chainhash.NewHashFromStr(b.State.LastSeen.Hash)
+			if _, err := b.Read(ctx, *h); err != nil {
+				t.Fatalf("last seen block : %v", err)
+			}
+
+			// recovering again has no effect
+			if _, recovered := restart(t, ctx, store); recovered {
+				t.Fatal("recovered twice")
+			}
+		})
+	}
+}
+
+// TestBlockService_Recover_missingBody tests that a journaled header whose
+// body, or TX's, were not stored is written without them.
+func TestBlockService_Recover_missingBody(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		body     bool
+		txs      bool
+		wantBody bool
+		wantTxs  bool
+	}{
+		{
+			name: "body",
+			body: true,
+		},
+		{
+			name: "txs",
+			txs:  true,
+		},
+		{
+			name:     "stored body",
+			body:     true,
+			wantBody: true,
+		},
+		{
+			name:    "stored txs",
+			txs:     true,
+			wantTxs: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memoryStorage{}
+			b := newTestBlockService(store)
+
+			body, _ := newCompactBlock(newTxs(1))
+
+			block := newBlock(1)
+			block.Hash = body.BlockHash().String()
+			block.Body = tt.body
+			block.Txs = tt.txs
+
+			if tt.wantBody {
+				if err := b.BlockRepostory.WriteBody(ctx, body); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if tt.wantTxs {
+				if err := b.BlockRepostory.WriteTxs(ctx, block.Hash, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			e := JournalEntry{
+				Blocks: []Block{block},
+				State:  &State{LastSeen: block},
+			}
+
+			if err := b.Journal.Write(ctx, e); err != nil {
+				t.Fatal(err)
+			}
+
+			b, recovered := restart(t, ctx, store)
+			if !recovered {
+				t.Fatal("not recovered")
+			}
+
+			got, err := b.BlockRepostory.Read(ctx, block.Hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Body != tt.wantBody || got.Txs != tt.wantTxs {
+				t.Fatalf("got body %v txs %v, want %v %v", got.Body, got.Txs,
+					tt.wantBody, tt.wantTxs)
+			}
+
+			if b.State.LastSeen.Body != tt.wantBody || b.State.LastSeen.Txs != tt.wantTxs {
+				t.Fatalf("got last seen body %v txs %v, want %v %v",
+					b.State.LastSeen.Body, b.State.LastSeen.Txs, tt.wantBody,
+					tt.wantTxs)
+			}
+		})
+	}
+}
+
+func TestTipKeys(t *testing.T) {
+	ctx := context.Background()
+	store := memoryStorage{}
+
+	// nothing before the State is written
+	if keys, err := TipKeys(ctx, store); err != nil || len(keys) != 0 {
+		t.Fatalf("got keys %v with error %v, want none", keys, err)
+	}
+
+	s := State{LastSeen: Block{Hash: "tip", Height: 10}}
+	if err := NewStateRepository(store).Write(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := TipKeys(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 1 || keys[0] != NewBlockRepository(store).buildPath("tip") {
+		t.Fatalf("got keys %v, want the tip block", keys)
+	}
+}
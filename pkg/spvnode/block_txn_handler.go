@@ -0,0 +1,61 @@
+package spvnode
+
+import (
+	"context"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+// BlockTxnHandler exists to handle the BlockTxn command.
+type BlockTxnHandler struct {
+	Config        Config
+	CompactBlocks CompactBlocks
+	Blocks        BlockHandler
+}
+
+// NewBlockTxnHandler returns a new BlockTxnHandler with the given Config.
+// Completed blocks are handled by the BlockHandler.
+func NewBlockTxnHandler(config Config,
+	compactBlocks CompactBlocks,
+	blocks BlockHandler) BlockTxnHandler {
+
+	return BlockTxnHandler{
+		Config:        config,
+		CompactBlocks: compactBlocks,
+		Blocks:        blocks,
+	}
+}
+
+// Handle implments the Handler interface.
+//
+// This function handles type conversion and delegates the the contrete
+// handler.
+func (h BlockTxnHandler) Handle(ctx context.Context,
+	m wire.Message) ([]wire.Message, error) {
+
+	msg, ok := m.(*wire.MsgBlockTxn)
+	if !ok {
+		return nil, errs.New(errs.Invalid, "Could not assert as *wire.MsgBlockTxn")
+	}
+
+	return h.handle(ctx, msg)
+}
+
+// handle processes the MsgBlockTxn.
+//
+// The TX's complete a compact block that was waiting for them.
+func (h BlockTxnHandler) handle(ctx context.Context,
+	m *wire.MsgBlockTxn) ([]wire.Message, error) {
+
+	b, err := h.CompactBlocks.Fill(m)
+	if err == ErrMerkleRoot {
+		return requestFullBlock(m.BlockHash), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return h.Blocks.handle(ctx, b)
+}
@@ -0,0 +1,75 @@
+package spvnode
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/bloom"
+)
+
+const (
+	// bloomFalsePositiveRate is the rate of irrelevant TX's matched by the
+	// bloom filters loaded on untrusted peers. A higher rate hides which
+	// TX's are relevant better, at the cost of bandwidth.
+	bloomFalsePositiveRate = 0.0001
+)
+
+// NewFilterLoad returns a BIP37 filterload message with the elements of the
+// filters, to load a bloom filter on an untrusted peer.
+//
+// The filter is updated with the outpoints of matched outputs, so TX's
+// spending them are also matched. If any filter has no elements, the bloom
+// filter matches every TX.
+func NewFilterLoad(filters []TxFilter) *wire.MsgFilterLoad {
+	elements := [][]byte{}
+	for _, f := range filters {
+		e := f.Elements()
+		if e == nil {
+			return wire.NewMsgFilterLoad([]byte{0xff}, 1, 0, wire.BloomUpdateNone)
+		}
+
+		elements = append(elements, e...)
+	}
+
+	buf := make([]byte, 4)
+	rand.Read(buf)
+
+	f := bloom.NewFilter(uint32(len(elements)),
+		binary.LittleEndian.Uint32(buf),
+		bloomFalsePositiveRate,
+		btcwire.BloomUpdateAll)
+
+	for _, e := range elements {
+		f.Add(e)
+	}
+
+	m := f.MsgFilterLoad()
+
+	return wire.NewMsgFilterLoad(m.Filter, m.HashFuncs, m.Tweak,
+		wire.BloomUpdateType(m.Flags))
+}
+
+// newTxHashFilterLoad returns a BIP37 filterload message that matches the
+// TX's with the hashes, so their merkle proofs can be requested.
+func newTxHashFilterLoad(hashes []chainhash.Hash) *wire.MsgFilterLoad {
+	buf := make([]byte, 4)
+	rand.Read(buf)
+
+	f := bloom.NewFilter(uint32(len(hashes)),
+		binary.LittleEndian.Uint32(buf),
+		bloomFalsePositiveRate,
+		btcwire.BloomUpdateNone)
+
+	for i := range hashes {
+		f.Add(hashes[i][:])
+	}
+
+	m := f.MsgFilterLoad()
+
+	return wire.NewMsgFilterLoad(m.Filter, m.HashFuncs, m.Tweak,
+		wire.BloomUpdateType(m.Flags))
+}
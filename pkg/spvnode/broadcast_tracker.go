@@ -0,0 +1,452 @@
+package spvnode
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// BroadcastStatus is how the tracking of a broadcast TX ended.
+type BroadcastStatus string
+
+const (
+	// BroadcastConfirmed is a TX that reached the confirmations waited for.
+	BroadcastConfirmed BroadcastStatus = "confirmed"
+
+	// BroadcastDoubleSpent is a TX with an input spent by another TX.
+	BroadcastDoubleSpent BroadcastStatus = "double_spent"
+)
+
+// BroadcastResult is passed on the channel of a tracked TX when it is
+// confirmed, or double spent.
+type BroadcastResult struct {
+	Hash   chainhash.Hash
+	Status BroadcastStatus
+
+	// Block and Height are of the block the TX is confirmed in. When the TX
+	// is double spent they are of the block the conflicting TX is confirmed
+	// in, and are zero if it is unconfirmed.
+	Block  chainhash.Hash
+	Height int32
+
+	Confirmations int32
+
+	// Conflict is the TX that spent an input of the TX, when it is double
+	// spent.
+	Conflict chainhash.Hash
+}
+
+// broadcastEventBuffer is the number of BroadcastEvents buffered for a
+// watched TX. Events are dropped once it is full.
+const broadcastEventBuffer = 32
+
+// BroadcastEventType is what happened to a watched TX.
+type BroadcastEventType string
+
+const (
+	// BroadcastSent is a TX sent to a peer, or that failed to be if there
+	// is an Err.
+	BroadcastSent BroadcastEventType = "sent"
+
+	// BroadcastRejected is a TX that a peer sent a reject message for.
+	BroadcastRejected BroadcastEventType = "rejected"
+
+	// BroadcastAccepted is a TX relayed back to the Node, or confirmed, so
+	// it was accepted into the mempool of a node of the network.
+	BroadcastAccepted BroadcastEventType = "accepted"
+)
+
+// BroadcastEvent is passed on the channel of a watched TX as it is sent to
+// peers, and accepted or rejected by them.
+type BroadcastEvent struct {
+	Hash chainhash.Hash
+	Type BroadcastEventType
+
+	// Peer is the address of the peer the TX was sent to, or that rejected
+	// it. It is empty if the TX couldn't be sent to any peer, or was
+	// accepted.
+	Peer string
+
+	// Err is why the TX couldn't be sent.
+	Err error
+
+	// Code and Reason are from the reject message of the peer.
+	Code   wire.RejectCode
+	Reason string
+}
+
+// BroadcastTracker follows broadcast TX's through the TX's and blocks the
+// Node receives, until they are confirmed or double spent.
+//
+// Watched TX's are followed until they are accepted, reporting what
+// happens to them on the way.
+type BroadcastTracker struct {
+	mu *sync.Mutex
+
+	txs map[chainhash.Hash]*trackedTx
+
+	// spends are the tracked TX's by the outputs they spend.
+	spends map[wire.OutPoint]chainhash.Hash
+
+	watches map[chainhash.Hash]*broadcastWatch
+}
+
+// trackedTx is a TX being tracked, and the block it is confirmed in so far.
+type trackedTx struct {
+	tx            *wire.MsgTx
+	confirmations int32
+	block         chainhash.Hash
+	height        int32
+	result        chan BroadcastResult
+	done          chan struct{}
+}
+
+// broadcastWatch is a watched TX, and the channel its events are passed on.
+type broadcastWatch struct {
+	events chan BroadcastEvent
+	done   chan struct{}
+}
+
+// NewBroadcastTracker returns a new BroadcastTracker.
+func NewBroadcastTracker() BroadcastTracker {
+	return BroadcastTracker{
+		mu:      &sync.Mutex{},
+		txs:     map[chainhash.Hash]*trackedTx{},
+		spends:  map[wire.OutPoint]chainhash.Hash{},
+		watches: map[chainhash.Hash]*broadcastWatch{},
+	}
+}
+
+// Track tracks the TX until it has the confirmations, or is double spent.
+//
+// The returned channel receives the result, then is closed. It is closed
+// without a result if the Context is done first.
+func (t BroadcastTracker) Track(ctx context.Context,
+	tx *wire.MsgTx,
+	confirmations int) <-chan BroadcastResult {
+
+	if confirmations < 1 {
+		confirmations = 1
+	}
+
+	hash := tx.TxHash()
+
+	tracked := &trackedTx{
+		tx:            tx,
+		confirmations: int32(confirmations),
+		result:        make(chan BroadcastResult, 1),
+		done:          make(chan struct{}),
+	}
+
+	t.mu.Lock()
+
+	if existing, ok := t.txs[hash]; ok {
+		// only one caller waits on a TX
+		t.finish(hash, existing, nil)
+	}
+
+	t.txs[hash] = tracked
+
+	for _, in := range tx.TxIn {
+		t.spends[in.PreviousOutPoint] = hash
+	}
+
+	t.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			t.Untrack(hash)
+		case <-tracked.done:
+		}
+	}()
+
+	return tracked.result
+}
+
+// Watch passes on the BroadcastEvents of the TX until it is accepted, or
+// the Context is done.
+//
+// The returned channel is closed after the BroadcastAccepted event, or
+// when the Context is done. Events are dropped if it isn't read and its
+// buffer fills.
+func (t BroadcastTracker) Watch(ctx context.Context,
+	hash chainhash.Hash) <-chan BroadcastEvent {
+
+	watch := &broadcastWatch{
+		events: make(chan BroadcastEvent, broadcastEventBuffer),
+		done:   make(chan struct{}),
+	}
+
+	t.mu.Lock()
+
+	if existing, ok := t.watches[hash]; ok {
+		// only one caller watches a TX
+		t.unwatch(hash, existing)
+	}
+
+	t.watches[hash] = watch
+
+	t.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			t.mu.Lock()
+			if t.watches[hash] == watch {
+				t.unwatch(hash, watch)
+			}
+			t.mu.Unlock()
+
+		case <-watch.done:
+		}
+	}()
+
+	return watch.events
+}
+
+// Sent passes on that the watched TX was sent to the peer, or failed to be
+// if the error is not nil.
+func (t BroadcastTracker) Sent(hash chainhash.Hash, peer string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.event(BroadcastEvent{
+		Hash: hash,
+		Type: BroadcastSent,
+		Peer: peer,
+		Err:  err,
+	})
+}
+
+// Unsent passes on that the watched TX couldn't be sent to any peer, and
+// stops watching it.
+func (t BroadcastTracker) Unsent(hash chainhash.Hash, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.event(BroadcastEvent{
+		Hash: hash,
+		Type: BroadcastSent,
+		Err:  err,
+	})
+
+	if watch, ok := t.watches[hash]; ok {
+		t.unwatch(hash, watch)
+	}
+}
+
+// Rejected passes on the reject message of the peer, if it is of a watched
+// TX.
+func (t BroadcastTracker) Rejected(peer string, msg *wire.MsgReject) {
+	if msg.Cmd != wire.CmdTx {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.event(BroadcastEvent{
+		Hash:   msg.Hash,
+		Type:   BroadcastRejected,
+		Peer:   peer,
+		Code:   msg.Code,
+		Reason: msg.Reason,
+	})
+}
+
+// Untrack stops tracking the TX, closing its channel without a result.
+func (t BroadcastTracker) Untrack(hash chainhash.Hash) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if tracked, ok := t.txs[hash]; ok {
+		t.finish(hash, tracked, nil)
+	}
+}
+
+// Tracked returns the hashes of the TX's being tracked.
+func (t BroadcastTracker) Tracked() []chainhash.Hash {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	hashes := make([]chainhash.Hash, 0, len(t.txs))
+	for hash := range t.txs {
+		hashes = append(hashes, hash)
+	}
+
+	return hashes
+}
+
+// Seen checks an unconfirmed TX for spends of the inputs of the tracked
+// TX's, and accepts it if it is watched.
+func (t BroadcastTracker) Seen(tx *wire.MsgTx) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.event(BroadcastEvent{
+		Hash: tx.TxHash(),
+		Type: BroadcastAccepted,
+	})
+
+	t.checkConflicts(tx, nil, 0)
+}
+
+// Connected confirms the tracked TX's in the block, which is at the height
+// of the tip of the best chain, and passes on the results of those that
+// have all of their confirmations, or are double spent by a TX of the
+// block. The watched TX's of the block are accepted.
+func (t BroadcastTracker) Connected(b *wire.MsgBlock, height int32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	blockHash := b.BlockHash()
+
+	for _, tx := range b.Transactions {
+		hash := tx.TxHash()
+
+		t.event(BroadcastEvent{
+			Hash: hash,
+			Type: BroadcastAccepted,
+		})
+
+		if tracked, ok := t.txs[hash]; ok {
+			tracked.block = blockHash
+			tracked.height = height
+			continue
+		}
+
+		t.checkConflicts(tx, &blockHash, height)
+	}
+
+	for hash, tracked := range t.txs {
+		if tracked.height == 0 {
+			continue
+		}
+
+		confirmations := height - tracked.height + 1
+		if confirmations < tracked.confirmations {
+			continue
+		}
+
+		t.finish(hash, tracked, &BroadcastResult{
+			Hash:          hash,
+			Status:        BroadcastConfirmed,
+			Block:         tracked.block,
+			Height:        tracked.height,
+			Confirmations: confirmations,
+		})
+	}
+}
+
+// Disconnected unconfirms the tracked TX's of the blocks, after they are
+// disconnected from the best chain by a Reorg.
+func (t BroadcastTracker) Disconnected(blocks []chainhash.Hash) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	disconnected := map[chainhash.Hash]bool{}
+	for _, hash := range blocks {
+		disconnected[hash] = true
+	}
+
+	for _, tracked := range t.txs {
+		if tracked.height > 0 && disconnected[tracked.block] {
+			tracked.block = chainhash.Hash{}
+			tracked.height = 0
+		}
+	}
+}
+
+// checkConflicts passes on the result of each unconfirmed tracked TX that
+// spends an input of the TX. The block is nil if the TX is unconfirmed.
+//
+// The caller must hold the lock.
+func (t BroadcastTracker) checkConflicts(tx *wire.MsgTx,
+	block *chainhash.Hash,
+	height int32) {
+
+	hash := tx.TxHash()
+
+	for _, in := range tx.TxIn {
+		spender, ok := t.spends[in.PreviousOutPoint]
+		if !ok || spender == hash {
+			continue
+		}
+
+		tracked, ok := t.txs[spender]
+		if !ok || tracked.height > 0 {
+			continue
+		}
+
+		result := &BroadcastResult{
+			Hash:     spender,
+			Status:   BroadcastDoubleSpent,
+			Conflict: hash,
+		}
+
+		if block != nil {
+			result.Block = *block
+			result.Height = height
+		}
+
+		t.finish(spender, tracked, result)
+	}
+}
+
+// finish stops tracking the TX, passing on the result if there is one.
+//
+// The caller must hold the lock.
+func (t BroadcastTracker) finish(hash chainhash.Hash,
+	tracked *trackedTx,
+	result *BroadcastResult) {
+
+	delete(t.txs, hash)
+
+	for _, in := range tracked.tx.TxIn {
+		if t.spends[in.PreviousOutPoint] == hash {
+			delete(t.spends, in.PreviousOutPoint)
+		}
+	}
+
+	if result != nil {
+		tracked.result <- *result
+	}
+
+	close(tracked.result)
+	close(tracked.done)
+}
+
+// event passes the event on to the watch of its TX, if there is one, and
+// stops watching the TX once it is accepted.
+//
+// The caller must hold the lock.
+func (t BroadcastTracker) event(e BroadcastEvent) {
+	watch, ok := t.watches[e.Hash]
+	if !ok {
+		return
+	}
+
+	select {
+	case watch.events <- e:
+	default:
+		// the buffer is full
+	}
+
+	if e.Type == BroadcastAccepted {
+		t.unwatch(e.Hash, watch)
+	}
+}
+
+// unwatch stops watching the TX, closing its channel.
+//
+// The caller must hold the lock.
+func (t BroadcastTracker) unwatch(hash chainhash.Hash, watch *broadcastWatch) {
+	delete(t.watches, hash)
+
+	close(watch.events)
+	close(watch.done)
+}
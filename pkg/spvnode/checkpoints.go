@@ -0,0 +1,132 @@
+package spvnode
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+var (
+	// ErrCheckpoint is returned when a header is at the height of a
+	// checkpoint, but does not have its hash.
+	ErrCheckpoint = errs.New(errs.Invalid, "Header contradicts a checkpoint")
+)
+
+// Checkpoint is the known hash of the block at a height.
+type Checkpoint struct {
+	Height int32
+	Hash   chainhash.Hash
+}
+
+// String returns the Checkpoint as height:hash.
+func (c Checkpoint) String() string {
+	return fmt.Sprintf("%v:%v", c.Height, c.Hash)
+}
+
+// NetworkCheckpoints are the compiled in Checkpoints of each network,
+// lowest first.
+var NetworkCheckpoints = map[wire.BitcoinNet][]Checkpoint{
+	MainNetBch: []Checkpoint{
+		newCheckpoint(11111, "0000000069e244f73d78e8fd29ba2fd2ed618bd6fa2ee92559f542fdb26e7c1d"),
+		newCheckpoint(33333, "000000002dd5588a74784eaa7ab0507a18ad16a236e7b1ce69f00d7ddfb5d0a6"),
+		newCheckpoint(74000, "0000000000573993a3c9e41ce34471c079dcf5f52a0e824a81e7f953b8661a20"),
+		newCheckpoint(105000, "00000000000291ce28027faea320c8d2b054b2e0fe44a773f3eefb151d6bdc97"),
+		newCheckpoint(134444, "00000000000005b12ffd4cd315cd34ffd4a594f430ac814c91184a0d42d2b0fe"),
+		newCheckpoint(168000, "000000000000099e61ea72015e79632f216fe6cb33d7899acb35b75c8303b763"),
+		newCheckpoint(193000, "000000000000059f452a5f7340de6682a977387c17010ff6e6c3bd83ca8b1317"),
+		newCheckpoint(210000, "000000000000048b95347e83192f69cf0366076336c639f9b7228e9ba171342e"),
+		newCheckpoint(216116, "00000000000001b4f4b433e81ee46494af945cf96014816a4e2370f11b23df4e"),
+		newCheckpoint(225430, "00000000000001c108384350f74090433e7fcf79a606b8e797f065b130575932"),
+		newCheckpoint(250000, "000000000000003887df1f29024b06fc2200b55f8af8f35453d7be294df2d214"),
+		newCheckpoint(279000, "0000000000000001ae8c72a0b0c301f67e3afca10e819efa9041e458e9bd7e40"),
+		newCheckpoint(295000, "00000000000000004d9b4ef50f0f9d686fd69db2e03af35a100370c64632a983"),
+		newCheckpoint(478559, "000000000000000000651ef99cb9fcbe0dadde1d424bd9f15ff20136191a5eec"),
+		newCheckpoint(504031, "0000000000000000011ebf65b60d0a3de80b8175be709d653b4c1a1beeb6ab9c"),
+	},
+	TestNetBch: []Checkpoint{
+		newCheckpoint(546, "000000002a936ca763904c3c35fce2f3556c559c0214345d31b1bcebf76acb70"),
+	},
+}
+
+// newCheckpoint returns a Checkpoint of a compiled in hash, and panics if
+// the hash is malformed.
+func newCheckpoint(height int32, hash string) Checkpoint {
+	h, err := chainhash.NewHashFromStr(hash)
+	if err != nil {
+		panic(err)
+	}
+
+	return Checkpoint{
+		Height: height,
+		Hash:   *h,
+	}
+}
+
+// ParseCheckpoints returns the Checkpoints of a comma separated list of
+// height:hash pairs, lowest first.
+func ParseCheckpoints(s string) ([]Checkpoint, error) {
+	checkpoints := []Checkpoint{}
+
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		parts := strings.Split(pair, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Checkpoint is not height:hash %v", pair)
+		}
+
+		height, err := strconv.ParseInt(parts[0], 10, 32)
+		if err != nil {
+			return nil, err
+		}
+
+		hash, err := chainhash.NewHashFromStr(parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		checkpoints = append(checkpoints, Checkpoint{
+			Height: int32(height),
+			Hash:   *hash,
+		})
+	}
+
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Height < checkpoints[j].Height
+	})
+
+	return checkpoints, nil
+}
+
+// checkCheckpoint returns nil unless there is a checkpoint at the height
+// with a different hash.
+func (b BlockService) checkCheckpoint(height int32, hash chainhash.Hash) error {
+	for _, c := range b.checkpoints {
+		if c.Height == height && c.Hash != hash {
+			return ErrCheckpoint
+		}
+	}
+
+	return nil
+}
+
+// lastCheckpoint returns the height of the highest checkpoint, or 0 if
+// there are none.
+//
+// The chain up to it is fixed by the checkpoint hashes, so the difficulty
+// of the headers below it is not checked.
+func (b BlockService) lastCheckpoint() int32 {
+	if len(b.checkpoints) == 0 {
+		return 0
+	}
+
+	return b.checkpoints[len(b.checkpoints)-1].Height
+}
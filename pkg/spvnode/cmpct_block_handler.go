@@ -0,0 +1,85 @@
+package spvnode
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+// CmpctBlockHandler exists to handle the CmpctBlock command.
+type CmpctBlockHandler struct {
+	Config        Config
+	BlockService  *BlockService
+	CompactBlocks CompactBlocks
+	Blocks        BlockHandler
+}
+
+// NewCmpctBlockHandler returns a new CmpctBlockHandler with the given
+// Config. Reconstructed blocks are handled by the BlockHandler.
+func NewCmpctBlockHandler(config Config,
+	blockService *BlockService,
+	compactBlocks CompactBlocks,
+	blocks BlockHandler) CmpctBlockHandler {
+
+	return CmpctBlockHandler{
+		Config:        config,
+		BlockService:  blockService,
+		CompactBlocks: compactBlocks,
+		Blocks:        blocks,
+	}
+}
+
+// Handle implments the Handler interface.
+//
+// This function handles type conversion and delegates the the contrete
+// handler.
+func (h CmpctBlockHandler) Handle(ctx context.Context,
+	m wire.Message) ([]wire.Message, error) {
+
+	msg, ok := m.(*wire.MsgCmpctBlock)
+	if !ok {
+		return nil, errs.New(errs.Invalid, "Could not assert as *wire.MsgCmpctBlock")
+	}
+
+	return h.handle(ctx, msg)
+}
+
+// handle processes the MsgCmpctBlock.
+//
+// The block is reconstructed from the mempool if possible, otherwise the
+// missing TX's are requested.
+func (h CmpctBlockHandler) handle(ctx context.Context,
+	m *wire.MsgCmpctBlock) ([]wire.Message, error) {
+
+	hash := m.BlockHash()
+
+	if h.BlockService.HasBlock(ctx, hash) {
+		return nil, nil
+	}
+
+	b, getBlockTxn, err := h.CompactBlocks.Reconstruct(m)
+	if err == ErrMerkleRoot {
+		return requestFullBlock(hash), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if getBlockTxn != nil {
+		return []wire.Message{getBlockTxn}, nil
+	}
+
+	return h.Blocks.handle(ctx, b)
+}
+
+// requestFullBlock returns the message requesting a block that couldn't be
+// reconstructed from its compact block.
+func requestFullBlock(hash chainhash.Hash) []wire.Message {
+	getdata := wire.NewMsgGetData()
+	getdata.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, &hash))
+
+	return []wire.Message{getdata}
+}
@@ -0,0 +1,191 @@
+package spvnode
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+const (
+	// compactBlockVersion is the version of compact blocks requested from
+	// the peer.
+	compactBlockVersion = 1
+
+	// maxPendingBlocks is the number of compact blocks kept while waiting
+	// for their missing TX's.
+	maxPendingBlocks = 16
+)
+
+var (
+	// ErrPrefilledIndex is returned when a prefilled TX of a compact block
+	// is outside the block, or shares an index with another.
+	ErrPrefilledIndex = errs.New(errs.Invalid, "Prefilled transaction index out of range")
+
+	// ErrNotPending is returned when TX's are received for a compact block
+	// that isn't waiting for any.
+	ErrNotPending = errs.New(errs.NotFound, "Compact block is not pending")
+)
+
+// pendingBlock is a compact block waiting for its missing TX's.
+type pendingBlock struct {
+	header  wire.BlockHeader
+	txs     []*wire.MsgTx
+	missing []uint32
+}
+
+// CompactBlocks reconstructs the blocks relayed as compact blocks from the
+// TX's in the Mempool, as described in BIP152.
+//
+// Blocks with TX's that aren't in the Mempool are kept pending until the
+// missing TX's are received.
+type CompactBlocks struct {
+	Mempool Mempool
+	mu      *sync.Mutex
+	pending map[chainhash.Hash]*pendingBlock
+}
+
+// NewCompactBlocks returns a new CompactBlocks, reconstructing blocks from
+// the Mempool.
+func NewCompactBlocks(mempool Mempool) CompactBlocks {
+	return CompactBlocks{
+		Mempool: mempool,
+		mu:      &sync.Mutex{},
+		pending: map[chainhash.Hash]*pendingBlock{},
+	}
+}
+
+// Reconstruct returns the block of a compact block.
+//
+// If any TX's are not in the Mempool, the block is kept pending and the
+// request for the missing TX's is returned instead.
+func (c CompactBlocks) Reconstruct(m *wire.MsgCmpctBlock) (*wire.MsgBlock,
+	*wire.MsgGetBlockTxn, error) {
+
+	txs := make([]*wire.MsgTx, m.TxCount())
+
+	for _, p := range m.PrefilledTxs {
+		if int(p.Index) >= len(txs) || txs[p.Index] != nil {
+			return nil, nil, ErrPrefilledIndex
+		}
+
+		txs[p.Index] = p.Tx
+	}
+
+	// index the mempool by short id. Colliding TX's can't be told apart,
+	// so they are treated as missing.
+	k0, k1 := m.ShortIDKeys()
+
+	known := map[uint64]*wire.MsgTx{}
+	for _, tx := range c.Mempool.All() {
+		id := wire.ShortID(k0, k1, tx.TxHash())
+
+		if _, ok := known[id]; ok {
+			known[id] = nil
+			continue
+		}
+
+		known[id] = tx
+	}
+
+	missing := []uint32{}
+	next := 0
+
+	for i := range txs {
+		if txs[i] != nil {
+			continue
+		}
+
+		if tx := known[m.ShortIDs[next]]; tx != nil {
+			txs[i] = tx
+		} else {
+			missing = append(missing, uint32(i))
+		}
+
+		next++
+	}
+
+	hash := m.BlockHash()
+
+	if len(missing) > 0 {
+		c.keep(hash, &pendingBlock{
+			header:  m.Header,
+			txs:     txs,
+			missing: missing,
+		})
+
+		return nil, wire.NewMsgGetBlockTxn(&hash, missing), nil
+	}
+
+	b, err := buildBlock(m.Header, txs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return b, nil, nil
+}
+
+// Fill returns the block of a pending compact block, completed with the
+// missing TX's.
+func (c CompactBlocks) Fill(m *wire.MsgBlockTxn) (*wire.MsgBlock, error) {
+	c.mu.Lock()
+	p, ok := c.pending[m.BlockHash]
+	delete(c.pending, m.BlockHash)
+	c.mu.Unlock()
+
+	if !ok {
+		return nil, ErrNotPending
+	}
+
+	if len(m.Transactions) != len(p.missing) {
+		return nil, fmt.Errorf("Got %v transactions, want %v",
+			len(m.Transactions), len(p.missing))
+	}
+
+	for i, index := range p.missing {
+		p.txs[index] = m.Transactions[i]
+	}
+
+	return buildBlock(p.header, p.txs)
+}
+
+// keep holds a compact block until its missing TX's are received, dropping
+// an arbitrary pending block if there are too many.
+func (c CompactBlocks) keep(hash chainhash.Hash, p *pendingBlock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for h := range c.pending {
+		if len(c.pending) < maxPendingBlocks {
+			break
+		}
+
+		delete(c.pending, h)
+	}
+
+	c.pending[hash] = p
+}
+
+// buildBlock returns the block with the header and TX's, verifying the TX's
+// against the merkle root of the header.
+//
+// A mismatch means a short id matched the wrong TX, so the full block must
+// be requested instead.
+func buildBlock(header wire.BlockHeader,
+	txs []*wire.MsgTx) (*wire.MsgBlock, error) {
+
+	if merkleRoot(txs) != header.MerkleRoot {
+		return nil, ErrMerkleRoot
+	}
+
+	b := wire.NewMsgBlock(&header)
+	for _, tx := range txs {
+		if err := b.AddTransaction(tx); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
@@ -0,0 +1,311 @@
+package spvnode
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+// newTxs returns n distinct TX's, the first of them a coinbase.
+func newTxs(n int) []*wire.MsgTx {
+	txs := make([]*wire.MsgTx, n)
+
+	for i := range txs {
+		tx := wire.NewMsgTx(1)
+
+		prev := wire.OutPoint{
+			Hash:  chainhash.DoubleHashH([]byte{byte(i)}),
+			Index: uint32(i),
+		}
+		if i == 0 {
+			prev = wire.OutPoint{Index: 0xffffffff}
+		}
+
+		tx.AddTxIn(wire.NewTxIn(&prev, []byte{byte(i)}))
+		tx.AddTxOut(wire.NewTxOut(int64(1000+i), []byte{0x51}))
+
+		txs[i] = tx
+	}
+
+	return txs
+}
+
+// newCompactBlock returns a block of the TX's, and a compact block of it
+// with the coinbase prefilled.
+func newCompactBlock(txs []*wire.MsgTx) (*wire.MsgBlock, *wire.MsgCmpctBlock) {
+	header := wire.BlockHeader{
+		Version:    1,
+		MerkleRoot: merkleRoot(txs),
+		Timestamp:  time.Unix(1600000000, 0),
+		Bits:       0x207fffff,
+	}
+
+	b := wire.NewMsgBlock(&header)
+	for _, tx := range txs {
+		b.AddTransaction(tx)
+	}
+
+	m := wire.NewMsgCmpctBlock(&header, 42)
+	k0, k1 := m.ShortIDKeys()
+
+	m.PrefilledTxs = append(m.PrefilledTxs, wire.PrefilledTx{
+		Index: 0,
+		Tx:    txs[0],
+	})
+
+	for _, tx := range txs[1:] {
+		m.ShortIDs = append(m.ShortIDs, wire.ShortID(k0, k1, tx.TxHash()))
+	}
+
+	return b, m
+}
+
+func TestCompactBlocks_Reconstruct(t *testing.T) {
+	txs := newTxs(5)
+	want, m := newCompactBlock(txs)
+
+	tests := []struct {
+		name        string
+		mempool     []*wire.MsgTx
+		wantMissing []uint32
+	}{
+		{
+			name:    "all in mempool",
+			mempool: txs[1:],
+		},
+		{
+			name:        "missing",
+			mempool:     []*wire.MsgTx{txs[1], txs[3]},
+			wantMissing: []uint32{2, 4},
+		},
+		{
+			name:        "empty mempool",
+			wantMissing: []uint32{1, 2, 3, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mempool := NewMempool(Config{})
+			for _, tx := range tt.mempool {
+				mempool.Add(tx)
+			}
+
+			// unrelated TX's don't get in the way
+			mempool.Add(newTxs(8)[7])
+
+			c := NewCompactBlocks(mempool)
+
+			b, getBlockTxn, err := c.Reconstruct(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantMissing == nil {
+				if getBlockTxn != nil {
+					t.Fatalf("got request for %v, want none", getBlockTxn.Indexes)
+				}
+
+				if b.BlockHash() != want.BlockHash() || len(b.Transactions) != len(txs) {
+					t.Fatalf("got block %v with %v txs, want %v with %v",
+						b.BlockHash(), len(b.Transactions), want.BlockHash(), len(txs))
+				}
+
+				return
+			}
+
+			if b != nil {
+				t.Fatalf("got block, want request for missing TX's")
+			}
+
+			if getBlockTxn.BlockHash != want.BlockHash() {
+				t.Fatalf("got request for block %v, want %v", getBlockTxn.BlockHash,
+					want.BlockHash())
+			}
+
+			if len(getBlockTxn.Indexes) != len(tt.wantMissing) {
+				t.Fatalf("got missing %v, want %v", getBlockTxn.Indexes, tt.wantMissing)
+			}
+
+			for i := range tt.wantMissing {
+				if getBlockTxn.Indexes[i] != tt.wantMissing[i] {
+					t.Fatalf("got missing %v, want %v", getBlockTxn.Indexes, tt.wantMissing)
+				}
+			}
+		})
+	}
+}
+
+// TestCompactBlocks_Fill tests the round trip of a compact block with
+// missing TX's: the getblocktxn request is encoded and decoded as the peer
+// would receive it, answered with a blocktxn holding the requested TX's,
+// which completes the block.
+func TestCompactBlocks_Fill(t *testing.T) {
+	pver := wire.ProtocolVersion
+
+	txs := newTxs(6)
+	want, m := newCompactBlock(txs)
+
+	mempool := NewMempool(Config{})
+	mempool.Add(txs[2])
+	mempool.Add(txs[5])
+
+	c := NewCompactBlocks(mempool)
+
+	_, getBlockTxn, err := c.Reconstruct(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := getBlockTxn.BtcEncode(&buf, pver); err != nil {
+		t.Fatal(err)
+	}
+
+	request := wire.MsgGetBlockTxn{}
+	if err := request.BtcDecode(&buf, pver); err != nil {
+		t.Fatal(err)
+	}
+
+	// the peer answers with the TX's at the requested indexes
+	blockTxn := wire.NewMsgBlockTxn(&request.BlockHash)
+	for _, index := range request.Indexes {
+		blockTxn.Transactions = append(blockTxn.Transactions, txs[index])
+	}
+
+	buf.Reset()
+	if err := blockTxn.BtcEncode(&buf, pver); err != nil {
+		t.Fatal(err)
+	}
+
+	response := wire.MsgBlockTxn{}
+	if err := response.BtcDecode(&buf, pver); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := c.Fill(&response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b.BlockHash() != want.BlockHash() {
+		t.Fatalf("got block %v, want %v", b.BlockHash(), want.BlockHash())
+	}
+
+	for i, tx := range b.Transactions {
+		if tx.TxHash() != txs[i].TxHash() {
+			t.Fatalf("got tx %v at %v, want %v", tx.TxHash(), i, txs[i].TxHash())
+		}
+	}
+
+	// the block is no longer pending once filled
+	if _, err := c.Fill(&response); err != ErrNotPending {
+		t.Fatalf("got error %v, want %v", err, ErrNotPending)
+	}
+}
+
+func TestCompactBlocks_Fill_invalid(t *testing.T) {
+	txs := newTxs(4)
+	_, m := newCompactBlock(txs)
+	hash := m.BlockHash()
+
+	tests := []struct {
+		name string
+		txs  []*wire.MsgTx
+		want error
+	}{
+		{
+			name: "wrong TX",
+			txs:  newTxs(10)[7:],
+			want: ErrMerkleRoot,
+		},
+		{
+			name: "correct TX's",
+			txs:  txs[1:],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCompactBlocks(NewMempool(Config{}))
+
+			if _, _, err := c.Reconstruct(m); err != nil {
+				t.Fatal(err)
+			}
+
+			blockTxn := wire.NewMsgBlockTxn(&hash)
+			blockTxn.Transactions = tt.txs
+
+			if _, err := c.Fill(blockTxn); err != tt.want {
+				t.Fatalf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// too few TX's for the missing indexes
+	c := NewCompactBlocks(NewMempool(Config{}))
+	if _, _, err := c.Reconstruct(m); err != nil {
+		t.Fatal(err)
+	}
+
+	blockTxn := wire.NewMsgBlockTxn(&hash)
+	blockTxn.Transactions = txs[1:2]
+
+	if _, err := c.Fill(blockTxn); err == nil {
+		t.Fatal("got no error for too few TX's")
+	}
+}
+
+func TestCompactBlocks_Reconstruct_invalid(t *testing.T) {
+	txs := newTxs(3)
+
+	tests := []struct {
+		name   string
+		modify func(m *wire.MsgCmpctBlock)
+		want   error
+	}{
+		{
+			name: "prefilled out of range",
+			modify: func(m *wire.MsgCmpctBlock) {
+				m.PrefilledTxs[0].Index = 3
+			},
+			want: ErrPrefilledIndex,
+		},
+		{
+			name: "prefilled twice",
+			modify: func(m *wire.MsgCmpctBlock) {
+				m.PrefilledTxs = append(m.PrefilledTxs, m.PrefilledTxs[0])
+				m.ShortIDs = m.ShortIDs[1:]
+			},
+			want: ErrPrefilledIndex,
+		},
+		{
+			name: "short id of the wrong TX",
+			modify: func(m *wire.MsgCmpctBlock) {
+				m.ShortIDs[0], m.ShortIDs[1] = m.ShortIDs[1], m.ShortIDs[0]
+			},
+			want: ErrMerkleRoot,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, m := newCompactBlock(txs)
+			tt.modify(m)
+
+			mempool := NewMempool(Config{})
+			for _, tx := range txs[1:] {
+				mempool.Add(tx)
+			}
+
+			c := NewCompactBlocks(mempool)
+
+			if _, _, err := c.Reconstruct(m); err != tt.want {
+				t.Fatalf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
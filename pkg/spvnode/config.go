@@ -3,12 +3,189 @@ package spvnode
 import (
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
 )
 
 // Config holds all configuration for the running service.
 type Config struct {
 	NodeAddress string
-	UserAgent   string
+
+	// UserAgent and Services are advertised in the version message sent to
+	// each peer, so operators can identify their deployments. Services are
+	// SFNodeNetwork by default.
+	//
+	// DisableRelay asks peers not to relay new TX's, so only the blocks are
+	// followed, unless a bloom filter is loaded. It is implied by
+	// HeadersOnly.
+	UserAgent    string
+	Services     wire.ServiceFlag
+	DisableRelay bool
+
+	// FeeFilter is the lowest fee rate, in satoshis per 1000 bytes, of the
+	// TX's that peers are asked to announce with a BIP133 feefilter
+	// message, so low fee TX's aren't requested. No feefilter is sent if it
+	// is 0.
+	FeeFilter int64
+
+	// Network is the network the Node runs on, which selects the magic
+	// bytes of its messages, its default port and DNS seeds, its genesis
+	// block and header rules. It is MainNetBch if it is 0.
+	Network wire.BitcoinNet
+
+	// FailoverAddresses are the trusted nodes, in order of priority, that
+	// are failed over to when the NodeAddress disconnects or stalls.
+	FailoverAddresses []string
+
+	// Seeds are the DNS seeds peers are discovered from. The DefaultSeeds
+	// of the network are used if there are none.
+	Seeds []string
+
+	// HeadersFirst syncs the whole header chain before requesting any
+	// block bodies. Once the headers are synced, the bodies
+	// from StartHeight are requested and passed to the block Listener.
+	HeadersFirst bool
+	StartHeight  int32
+
+	// HeadersOnly only tracks the header chain. No block bodies or TX's are
+	// requested, and there is no Mempool. Each new tip of the best chain is
+	// passed to the block Listener as a block with only its header, after
+	// any Reorg it causes. It overrides CompactBlocks and PersistMempool.
+	HeadersOnly bool
+
+	// CompactBlocks requests new blocks as BIP152 compact blocks, which are
+	// reconstructed from the TX's already relayed by the peer. The peer
+	// must support protocol version 70014.
+	CompactBlocks bool
+
+	// MempoolMaxTxs and MempoolMaxBytes limit the number and total size of
+	// the unconfirmed TX's kept to reconstruct compact blocks from. The
+	// size is of the serialized TX's, which approximates their memory.
+	// 100000 TX's are kept if MempoolMaxTxs is 0, and the size is not
+	// limited if MempoolMaxBytes is 0.
+	//
+	// MempoolEviction is the policy of which TX's are evicted once a limit
+	// is reached.
+	MempoolMaxTxs   int
+	MempoolMaxBytes int
+	MempoolEviction Eviction
+
+	// MempoolExpiry is how long an unconfirmed TX is kept in the Mempool.
+	// Expired TX's are passed to the TX Listener. TX's don't expire if it
+	// is 0.
+	MempoolExpiry time.Duration
+
+	// ListenerWorkers is the number of workers that pass the TX's to the
+	// Listeners, so a slow Listener doesn't hold up the handling of the
+	// messages of the peers. TX's are passed on as they are handled if it
+	// is 0.
+	ListenerWorkers int
+
+	// PersistMempool saves the relevant TX's of the Mempool to storage
+	// when the Node stops, and loads them when it starts, so they are
+	// still known after a restart. Relevant TX's are kept in the Mempool
+	// even without CompactBlocks.
+	PersistMempool bool
+
+	// RebroadcastInterval is how long a TX the Node broadcast may stay
+	// unconfirmed before it is broadcast again. The delay doubles after
+	// each rebroadcast, and the TX is given up on after
+	// RebroadcastMaxAttempts, or 10 if it is 0. TX's aren't rebroadcast if
+	// the interval is 0.
+	RebroadcastInterval    time.Duration
+	RebroadcastMaxAttempts int
+
+	// MaxTxsInFlight is the most announced TX's requested from the trusted
+	// node and not yet received. The requests of further TX's wait until
+	// earlier ones are answered. 1000 are requested at once if it is 0.
+	MaxTxsInFlight int
+
+	// SendMessagesPerSecond and SendBytesPerSecond limit the messages sent
+	// to the trusted node, so a burst of them doesn't overwhelm a slow
+	// connection. Up to a second of them are sent at once. Sends aren't
+	// limited if they are 0.
+	SendMessagesPerSecond int
+	SendBytesPerSecond    int
+
+	// MaxMessageSize, MaxInvPerMsg, MaxHeadersPerMsg and MaxAddrsPerMsg cap
+	// the payload size, inventory vectors, headers and addresses of the
+	// messages received from peers. A peer that exceeds one is penalized
+	// and disconnected. The limits of the protocol apply to those that are
+	// 0.
+	MaxMessageSize   int
+	MaxInvPerMsg     int
+	MaxHeadersPerMsg int
+	MaxAddrsPerMsg   int
+
+	// HandshakeTimeout is how long an untrusted peer has to complete the
+	// version handshake, and is 30 seconds if it is 0. MinPeerVersion is
+	// the lowest protocol version, and RequiredServices are the service
+	// bits, that an untrusted peer must advertise. A peer that fails them is
+	// penalized and disconnected.
+	HandshakeTimeout time.Duration
+	MinPeerVersion   uint32
+	RequiredServices wire.ServiceFlag
+
+	// KeepBlocks is the number of recent full blocks stored along with the
+	// headers. Older full blocks are removed as new ones arrive, except
+	// those with relevant TX's. No full blocks are stored if it is 0.
+	KeepBlocks int
+
+	// KeepRelevantTxs stores only the relevant TX's of each block, with
+	// their merkle proofs, in place of the full blocks, so far less is
+	// stored. KeepBlocks is ignored when it is set.
+	KeepRelevantTxs bool
+
+	// Peers is the number of untrusted peers kept connected, so blocks can
+	// be downloaded from them at once. If it is 0, peers are only connected
+	// to for each download.
+	//
+	// MaxPeersPerGroup is the most of those peers in one network group, the
+	// same /16 for IPv4, /32 for IPv6, or Tor. It is 1 if it is 0.
+	Peers            int
+	MaxPeersPerGroup int
+
+	// PeerRotationInterval is how often the lowest ranked of the Peers are
+	// disconnected, and replaced by fresh candidates, so better peers keep
+	// being found. PeerRotationCount is how many are rotated out each time,
+	// or 1 if it is 0. Peers are not rotated if the interval is 0.
+	PeerRotationInterval time.Duration
+	PeerRotationCount    int
+
+	// PinnedPeers are the addresses of untrusted peers, such as the
+	// operator's own nodes, that are always kept connected along with the
+	// Peers. They are never dropped for misbehaving.
+	PinnedPeers []string
+
+	// Listen is the host:port that inbound peers may connect to, such as
+	// the other nodes of a regtest network. No connections are accepted if
+	// it is empty.
+	//
+	// MaxInbound is the most inbound peers connected at once, and is 8 if
+	// it is 0. MaxInboundMessageSize caps the payloads of their messages in
+	// place of the MaxMessageSize, if it is not 0.
+	Listen                string
+	MaxInbound            int
+	MaxInboundMessageSize int
+
+	// Checkpoints replace the compiled in NetworkCheckpoints. Headers at
+	// the height of a checkpoint must have its hash.
+	Checkpoints []Checkpoint
+
+	// TxFilters select the TX's that are relevant to the Listeners, as if
+	// each was added with AddTxFilter.
+	TxFilters []TxFilter
+
+	// Proxy is the host:port of a SOCKS5 proxy, such as Tor, that all
+	// outbound peer connections are made through. Peers at .onion addresses
+	// can only be reached through Tor.
+	//
+	// DNS seeds are not looked up through the proxy, so they are skipped
+	// when a Proxy is set, unless Seeds are given.
+	Proxy         string
+	ProxyUsername string
+	ProxyPassword string
 }
 
 // NewConfig returns a new Config populated from environment variables.
@@ -16,6 +193,8 @@ func NewConfig(host, useragent string) Config {
 	c := Config{
 		NodeAddress: host,
 		UserAgent:   useragent,
+		Services:    wire.SFNodeNetwork,
+		Network:     MainNetBch,
 	}
 
 	return c
@@ -26,8 +205,43 @@ func NewConfig(host, useragent string) Config {
 // This is important so we don't log sensitive config values.
 func (c Config) String() string {
 	pairs := map[string]string{
-		"NodeAddress": c.NodeAddress,
-		"UserAgent":   c.UserAgent,
+		"NodeAddress":   c.NodeAddress,
+		"Network":       NetworkNames[c.Network],
+		"Failover":      strings.Join(c.FailoverAddresses, ","),
+		"UserAgent":     c.UserAgent,
+		"Services":      fmt.Sprintf("%v", c.Services),
+		"DisableRelay":  fmt.Sprintf("%v", c.DisableRelay),
+		"FeeFilter":     fmt.Sprintf("%v", c.FeeFilter),
+		"Seeds":         strings.Join(c.Seeds, ","),
+		"HeadersFirst":  fmt.Sprintf("%v", c.HeadersFirst),
+		"HeadersOnly":   fmt.Sprintf("%v", c.HeadersOnly),
+		"StartHeight":   fmt.Sprintf("%v", c.StartHeight),
+		"CompactBlocks": fmt.Sprintf("%v", c.CompactBlocks),
+		"Peers":         fmt.Sprintf("%v per group %v", c.Peers, c.MaxPeersPerGroup),
+		"PinnedPeers":   strings.Join(c.PinnedPeers, ","),
+		"KeepBlocks":    fmt.Sprintf("%v relevant only %v", c.KeepBlocks, c.KeepRelevantTxs),
+		"TxsInFlight":   fmt.Sprintf("%v", c.MaxTxsInFlight),
+		"TxFilters":     fmt.Sprintf("%v", len(c.TxFilters)),
+		"Handshake": fmt.Sprintf("%v version %v services %v",
+			c.HandshakeTimeout, c.MinPeerVersion, c.RequiredServices),
+		"Listen": fmt.Sprintf("%v max %v message size %v", c.Listen,
+			c.MaxInbound, c.MaxInboundMessageSize),
+		"SendLimit": fmt.Sprintf("%v msgs/s %v bytes/s",
+			c.SendMessagesPerSecond, c.SendBytesPerSecond),
+		"ReceiveLimit": fmt.Sprintf("%v bytes %v inv %v headers %v addrs",
+			c.MaxMessageSize, c.MaxInvPerMsg, c.MaxHeadersPerMsg,
+			c.MaxAddrsPerMsg),
+		"Mempool": fmt.Sprintf("%v txs %v bytes %v expiry %v persist %v",
+			c.MempoolMaxTxs, c.MempoolMaxBytes, c.MempoolEviction,
+			c.MempoolExpiry, c.PersistMempool),
+		"ListenerWorkers": fmt.Sprintf("%v", c.ListenerWorkers),
+		"PeerRotation": fmt.Sprintf("%v every %v", c.PeerRotationCount,
+			c.PeerRotationInterval),
+		"Rebroadcast": fmt.Sprintf("%v attempts %v",
+			c.RebroadcastInterval, c.RebroadcastMaxAttempts),
+		"Checkpoints":   fmt.Sprintf("%v", c.Checkpoints),
+		"Proxy":         c.Proxy,
+		"ProxyUsername": c.ProxyUsername,
 	}
 
 	parts := []string{}
@@ -38,3 +252,17 @@ func (c Config) String() string {
 
 	return fmt.Sprintf("{%v}", strings.Join(parts, " "))
 }
+
+// TrustedNodes returns the addresses of the trusted nodes, in order of
+// priority.
+func (c Config) TrustedNodes() []string {
+	nodes := []string{}
+
+	for _, address := range append([]string{c.NodeAddress}, c.FailoverAddresses...) {
+		if address != "" {
+			nodes = append(nodes, address)
+		}
+	}
+
+	return nodes
+}
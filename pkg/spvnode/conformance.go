@@ -0,0 +1,263 @@
+package spvnode
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Anomaly is a way in which a peer did not conform to the protocol.
+type Anomaly string
+
+const (
+	// AnomalyMalformed is recorded when a message from the peer could not
+	// be decoded.
+	AnomalyMalformed Anomaly = "malformed"
+
+	// AnomalyUnexpected is recorded when a message from the peer could not
+	// be handled, such as a response that does not match the command.
+	AnomalyUnexpected Anomaly = "unexpected"
+
+	// AnomalyStaleChain is recorded when the peer sends headers that do
+	// not extend the chain.
+	AnomalyStaleChain Anomaly = "stale_chain"
+
+	// AnomalyInvalidHeader is recorded when the peer sends a header
+	// without valid proof of work, with the wrong difficulty, or that
+	// contradicts a checkpoint.
+	AnomalyInvalidHeader Anomaly = "invalid_header"
+
+	// AnomalyOversized is recorded when the peer sends a message over the
+	// MessageLimits. The peer is disconnected.
+	AnomalyOversized Anomaly = "oversized"
+
+	// AnomalyHandshake is recorded when the peer does not complete the
+	// Handshake in time, or advertises an old version or too few services.
+	// The peer is disconnected.
+	AnomalyHandshake Anomaly = "handshake"
+)
+
+const (
+	// latencyWeight is the weight of each new round trip in the average
+	// Latency of a peer, so it follows changes without jumping on one slow
+	// response.
+	latencyWeight = 0.2
+
+	// latencyScale is the Latency at which the Rank of a peer is half of
+	// its Score.
+	latencyScale = 250 * time.Millisecond
+
+	// conformanceHalfLife is how long it takes the counts of the messages
+	// and anomalies of a peer to halve, so its Score returns to the
+	// neutralScore as its history ages.
+	conformanceHalfLife = 24 * time.Hour
+
+	// neutralScore is the Score of a peer without recent history. It is
+	// between minPeerScore and a perfect score, so peers that have proven
+	// reliable are preferred to unknown ones, and peers that misbehaved
+	// long ago are given another chance.
+	neutralScore = 0.75
+
+	// neutralWeight is the weight of the neutralScore in the Score of a
+	// peer, as a count of messages.
+	neutralWeight = 2.0
+
+	// reportMaxAge is how long since a peer was last seen before its report
+	// is forgotten.
+	reportMaxAge = 7 * 24 * time.Hour
+)
+
+// ConformanceReport holds the protocol anomalies seen from a peer.
+//
+// The counts of Messages and Anomalies decay by half every
+// conformanceHalfLife, so recent behaviour outweighs old.
+type ConformanceReport struct {
+	Peer          string
+	Messages      float64
+	Anomalies     map[Anomaly]float64
+	LastAnomaly   string
+	LastAnomalyAt int64
+
+	// LastSeen is when the last message or round trip of the peer was
+	// recorded, in nanoseconds since the epoch.
+	LastSeen int64
+
+	// Latency is the moving average of the round trips to the peer, from
+	// pings and requests, over LatencySamples of them. It is 0 until one is
+	// measured.
+	Latency        time.Duration
+	LatencySamples uint64
+
+	// decayedAt is when the counts were last decayed, in nanoseconds since
+	// the epoch.
+	decayedAt int64
+}
+
+// Score returns the fraction of messages from the peer that conformed to
+// the protocol, from 0 to 1, weighted toward the neutralScore. A peer
+// without recent messages scores the neutralScore.
+//
+// A slow peer that conforms scores close to 1, so the score tells a buggy
+// or malicious peer apart from one that is merely slow.
+func (r ConformanceReport) Score() float64 {
+	total := 0.0
+	for _, count := range r.Anomalies {
+		total += count
+	}
+
+	// anomalies may be recorded for messages that were not received whole
+	messages := math.Max(r.Messages, total)
+
+	score := (neutralScore*neutralWeight + r.Messages - total) /
+		(neutralWeight + messages)
+	if score < 0 {
+		return 0
+	}
+
+	return score
+}
+
+// Rank returns the Score of the peer weighted by its Latency, from 0 to 1,
+// so peers that are both reliable and fast are preferred. A peer whose
+// Latency has not been measured ranks at its Score.
+func (r ConformanceReport) Rank() float64 {
+	return r.Score() * float64(latencyScale) / float64(latencyScale+r.Latency)
+}
+
+// Conformance tracks the protocol anomalies of each peer. It is safe for
+// concurrent use.
+type Conformance struct {
+	mu    *sync.Mutex
+	peers map[string]*ConformanceReport
+}
+
+func NewConformance() Conformance {
+	return Conformance{
+		mu:    &sync.Mutex{},
+		peers: map[string]*ConformanceReport{},
+	}
+}
+
+// Received records a message received from the peer.
+func (c Conformance) Received(peer string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r := c.get(peer)
+	r.Messages++
+	r.LastSeen = time.Now().UnixNano()
+}
+
+// Record records an anomaly seen from the peer, with the error describing
+// it.
+func (c Conformance) Record(peer string, a Anomaly, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r := c.get(peer)
+	r.Anomalies[a]++
+	r.LastAnomaly = err.Error()
+	r.LastAnomalyAt = time.Now().UnixNano()
+}
+
+// RecordLatency records a round trip to the peer, such as from a ping to
+// its pong, or from a request to the first response.
+func (c Conformance) RecordLatency(peer string, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r := c.get(peer)
+	r.LastSeen = time.Now().UnixNano()
+
+	if r.LatencySamples == 0 {
+		r.Latency = d
+	} else {
+		r.Latency += time.Duration(latencyWeight * float64(d-r.Latency))
+	}
+
+	r.LatencySamples++
+}
+
+// Report returns the ConformanceReport of each peer, ordered by peer.
+func (c Conformance) Report() []ConformanceReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	reports := []ConformanceReport{}
+	now := time.Now().UnixNano()
+
+	for _, r := range c.peers {
+		r.decay(now)
+
+		report := *r
+		report.Anomalies = map[Anomaly]float64{}
+
+		for a, count := range r.Anomalies {
+			report.Anomalies[a] = count
+		}
+
+		reports = append(reports, report)
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Peer < reports[j].Peer
+	})
+
+	return reports
+}
+
+// Forget removes the reports of the peers that have not been seen, or
+// misbehaved, since the time, and returns how many were removed.
+func (c Conformance) Forget(before time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+
+	for peer, r := range c.peers {
+		if r.LastSeen < before.UnixNano() && r.LastAnomalyAt < before.UnixNano() {
+			delete(c.peers, peer)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// get returns the report for the peer, creating it if needed, with its
+// counts decayed to now. The caller must hold the lock.
+func (c Conformance) get(peer string) *ConformanceReport {
+	r, ok := c.peers[peer]
+	if !ok {
+		r = &ConformanceReport{
+			Peer:      peer,
+			Anomalies: map[Anomaly]float64{},
+		}
+
+		c.peers[peer] = r
+	}
+
+	r.decay(time.Now().UnixNano())
+
+	return r
+}
+
+// decay halves the counts of the report for each conformanceHalfLife since
+// they were last decayed.
+func (r *ConformanceReport) decay(now int64) {
+	if r.decayedAt > 0 && now > r.decayedAt {
+		f := math.Pow(0.5, float64(now-r.decayedAt)/float64(conformanceHalfLife))
+
+		r.Messages *= f
+		for a := range r.Anomalies {
+			r.Anomalies[a] *= f
+		}
+	}
+
+	r.decayedAt = now
+}
@@ -0,0 +1,247 @@
+package spvnode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+)
+
+const (
+	socks5Version = 0x05
+
+	socks5AuthNone     = 0x00
+	socks5AuthPassword = 0x02
+	socks5AuthNoMatch  = 0xff
+
+	// socks5PasswordVersion is the version of the username/password
+	// subnegotiation of RFC 1929.
+	socks5PasswordVersion = 0x01
+
+	socks5CmdConnect = 0x01
+
+	socks5AddrIPv4   = 0x01
+	socks5AddrDomain = 0x03
+	socks5AddrIPv6   = 0x04
+)
+
+var (
+	ErrProxyAuth = errs.New(errs.Unauthorized, "SOCKS5 proxy rejected the credentials")
+)
+
+// socks5Replies are the errors of the reply codes of a SOCKS5 proxy.
+var socks5Replies = map[byte]string{
+	0x01: "general failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// Dialer opens the outbound connections to peers, trusted and untrusted,
+// either directly or through a SOCKS5 proxy such as Tor.
+//
+// Through a proxy, hostnames are resolved by the proxy, so .onion addresses
+// can be dialed.
+type Dialer struct {
+	// Proxy is the host:port of the SOCKS5 proxy. Connections are direct if
+	// it is empty.
+	Proxy string
+
+	// Username and Password authenticate with the proxy, if set.
+	Username string
+	Password string
+
+	Timeout time.Duration
+}
+
+// NewDialer returns a new Dialer for the proxy settings of the Config.
+func NewDialer(config Config) Dialer {
+	return Dialer{
+		Proxy:    config.Proxy,
+		Username: config.ProxyUsername,
+		Password: config.ProxyPassword,
+		Timeout:  peerTimeout,
+	}
+}
+
+// CanReach returns true if the address, a host:port, can be dialed. Tor
+// addresses can only be dialed through a proxy.
+func (d Dialer) CanReach(address string) bool {
+	return d.Proxy != "" || netGroup(address) != "onion"
+}
+
+// Dial connects to the address, a host:port.
+func (d Dialer) Dial(address string) (net.Conn, error) {
+	if d.Proxy == "" {
+		return net.DialTimeout("tcp", address, d.Timeout)
+	}
+
+	conn, err := net.DialTimeout("tcp", d.Proxy, d.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(d.Timeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := d.connect(conn, address); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Proxy %v failed to connect to %v : %v",
+			d.Proxy, address, err)
+	}
+
+	// the deadline of the handshake doesn't apply to the connection
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// connect asks the proxy to connect to the address, as in RFC 1928.
+func (d Dialer) connect(conn net.Conn, address string) error {
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return err
+	}
+
+	if err := d.authenticate(conn); err != nil {
+		return err
+	}
+
+	req := []byte{socks5Version, socks5CmdConnect, 0x00}
+
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return fmt.Errorf("Hostname too long %v", host)
+		}
+
+		req = append(req, socks5AddrDomain, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, socks5AddrIPv4)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, socks5AddrIPv6)
+		req = append(req, ip.To16()...)
+	}
+
+	req = append(req, byte(port>>8), byte(port))
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	// VER REP RSV ATYP, then the bound address and port
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+
+	if reply[0] != socks5Version {
+		return fmt.Errorf("Unexpected SOCKS version %v", reply[0])
+	}
+
+	if reply[1] != 0x00 {
+		reason, ok := socks5Replies[reply[1]]
+		if !ok {
+			reason = fmt.Sprintf("reply %v", reply[1])
+		}
+
+		return errors.New(reason)
+	}
+
+	var size int
+
+	switch reply[3] {
+	case socks5AddrIPv4:
+		size = net.IPv4len
+	case socks5AddrIPv6:
+		size = net.IPv6len
+	case socks5AddrDomain:
+		b := make([]byte, 1)
+		if _, err := io.ReadFull(conn, b); err != nil {
+			return err
+		}
+
+		size = int(b[0])
+	default:
+		return fmt.Errorf("Unexpected address type %v", reply[3])
+	}
+
+	bound := make([]byte, size+2)
+	if _, err := io.ReadFull(conn, bound); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// authenticate negotiates the authentication method with the proxy, with
+// the username and password if they are set.
+func (d Dialer) authenticate(conn net.Conn) error {
+	method := byte(socks5AuthNone)
+	if d.Username != "" {
+		method = socks5AuthPassword
+	}
+
+	if _, err := conn.Write([]byte{socks5Version, 1, method}); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+
+	if reply[0] != socks5Version {
+		return fmt.Errorf("Unexpected SOCKS version %v", reply[0])
+	}
+
+	if reply[1] == socks5AuthNoMatch || reply[1] != method {
+		return ErrProxyAuth
+	}
+
+	if method == socks5AuthNone {
+		return nil
+	}
+
+	if len(d.Username) > 255 || len(d.Password) > 255 {
+		return ErrProxyAuth
+	}
+
+	req := []byte{socks5PasswordVersion, byte(len(d.Username))}
+	req = append(req, d.Username...)
+	req = append(req, byte(len(d.Password)))
+	req = append(req, d.Password...)
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+
+	if reply[1] != 0x00 {
+		return ErrProxyAuth
+	}
+
+	return nil
+}
@@ -0,0 +1,261 @@
+package spvnode
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubProxy is a SOCKS5 proxy that answers one connection, and writes
+// "hello" on it once the connection to the target is made.
+type stubProxy struct {
+	// username and password are required if set.
+	username string
+	password string
+
+	// reply is the reply code to the connect request.
+	reply byte
+
+	// boundDomain replies with a domain as the bound address.
+	boundDomain bool
+
+	// requested receives the address the proxy was asked to connect to.
+	requested chan string
+}
+
+func (s stubProxy) serve(ln net.Listener) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	greeting := make([]byte, 3)
+	if _, err := io.ReadFull(conn, greeting); err != nil {
+		return
+	}
+
+	method := byte(socks5AuthNone)
+	if s.username != "" {
+		method = socks5AuthPassword
+	}
+
+	if greeting[2] != method {
+		conn.Write([]byte{socks5Version, socks5AuthNoMatch})
+		return
+	}
+
+	conn.Write([]byte{socks5Version, method})
+
+	if method == socks5AuthPassword {
+		username := readString(conn, 2)
+		password := readString(conn, 1)
+
+		if username != s.username || password != s.password {
+			conn.Write([]byte{socks5PasswordVersion, 0x01})
+			return
+		}
+
+		conn.Write([]byte{socks5PasswordVersion, 0x00})
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return
+	}
+
+	var host string
+	switch request[3] {
+	case socks5AddrIPv4:
+		host = net.IP(readBytes(conn, net.IPv4len)).String()
+	case socks5AddrIPv6:
+		host = net.IP(readBytes(conn, net.IPv6len)).String()
+	case socks5AddrDomain:
+		host = readString(conn, 1)
+	}
+
+	port := readBytes(conn, 2)
+	s.requested <- net.JoinHostPort(host, strconv.Itoa(int(port[0])<<8|int(port[1])))
+
+	reply := []byte{socks5Version, s.reply, 0x00}
+	if s.boundDomain {
+		reply = append(reply, socks5AddrDomain, 4)
+		reply = append(reply, "host"...)
+	} else {
+		reply = append(reply, socks5AddrIPv4, 127, 0, 0, 1)
+	}
+
+	reply = append(reply, 0x20, 0x8d)
+	conn.Write(reply)
+
+	if s.reply == 0x00 {
+		conn.Write([]byte("hello"))
+	}
+}
+
+// readBytes reads n bytes, or returns those read before an error.
+func readBytes(r io.Reader, n int) []byte {
+	b := make([]byte, n)
+	io.ReadFull(r, b)
+
+	return b
+}
+
+// readString reads a string prefixed with its length, after skipping the
+// bytes before the length.
+func readString(r io.Reader, skip int) string {
+	prefix := readBytes(r, skip)
+
+	return string(readBytes(r, int(prefix[skip-1])))
+}
+
+func TestDialer_Dial_proxy(t *testing.T) {
+	tests := []struct {
+		name     string
+		proxy    stubProxy
+		username string
+		password string
+		address  string
+		wantErr  string
+	}{
+		{
+			name:    "ipv4",
+			address: "10.0.0.1:8333",
+		},
+		{
+			name:    "ipv6",
+			address: "[2001:db8::1]:8333",
+		},
+		{
+			name:    "onion",
+			address: "expyuzz4wqqyqhjn.onion:8333",
+		},
+		{
+			name:    "bound domain",
+			proxy:   stubProxy{boundDomain: true},
+			address: "10.0.0.1:8333",
+		},
+		{
+			name:     "password",
+			proxy:    stubProxy{username: "user", password: "secret"},
+			username: "user",
+			password: "secret",
+			address:  "10.0.0.1:8333",
+		},
+		{
+			name:     "wrong password",
+			proxy:    stubProxy{username: "user", password: "secret"},
+			username: "user",
+			password: "guess",
+			address:  "10.0.0.1:8333",
+			wantErr:  ErrProxyAuth.Error(),
+		},
+		{
+			name:    "password required",
+			proxy:   stubProxy{username: "user", password: "secret"},
+			address: "10.0.0.1:8333",
+			wantErr: ErrProxyAuth.Error(),
+		},
+		{
+			name:     "password not supported",
+			username: "user",
+			password: "secret",
+			address:  "10.0.0.1:8333",
+			wantErr:  ErrProxyAuth.Error(),
+		},
+		{
+			name:    "connection refused",
+			proxy:   stubProxy{reply: 0x05},
+			address: "10.0.0.1:8333",
+			wantErr: "connection refused",
+		},
+		{
+			name:    "unknown reply",
+			proxy:   stubProxy{reply: 0x2a},
+			address: "10.0.0.1:8333",
+			wantErr: "reply 42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln := listen(t)
+			defer ln.Close()
+
+			proxy := tt.proxy
+			proxy.requested = make(chan string, 1)
+			go proxy.serve(ln)
+
+			d := Dialer{
+				Proxy:    ln.Addr().String(),
+				Username: tt.username,
+				Password: tt.password,
+				Timeout:  5 * time.Second,
+			}
+
+			conn, err := d.Dial(tt.address)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			if got := <-proxy.requested; got != tt.address {
+				t.Fatalf("got request for %v, want %v", got, tt.address)
+			}
+
+			// the connection is passed on to the target, without the
+			// deadline of the handshake
+			b := make([]byte, 5)
+			if _, err := io.ReadFull(conn, b); err != nil {
+				t.Fatal(err)
+			}
+
+			if string(b) != "hello" {
+				t.Fatalf("got %q, want %q", b, "hello")
+			}
+		})
+	}
+}
+
+func TestDialer_Dial_proxyDown(t *testing.T) {
+	ln := listen(t)
+	address := ln.Addr().String()
+	ln.Close()
+
+	d := Dialer{
+		Proxy:   address,
+		Timeout: 5 * time.Second,
+	}
+
+	if _, err := d.Dial("10.0.0.1:8333"); err == nil {
+		t.Fatal("got no error with the proxy down")
+	}
+}
+
+func TestDialer_CanReach(t *testing.T) {
+	direct := Dialer{}
+	proxied := Dialer{Proxy: "127.0.0.1:9050"}
+
+	onion := "expyuzz4wqqyqhjn.onion:8333"
+
+	if direct.CanReach(onion) {
+		t.Fatal("onion address reachable without a proxy")
+	}
+
+	if !proxied.CanReach(onion) || !direct.CanReach("10.0.0.1:8333") {
+		t.Fatal("address not reachable")
+	}
+}
@@ -0,0 +1,206 @@
+package spvnode
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+const (
+	// doubleSpendWindow is how long the spends of an unconfirmed relevant
+	// TX are watched for conflicts.
+	doubleSpendWindow = 24 * time.Hour
+
+	// maxWatchedSpends is the most outputs watched for conflicts. The spends
+	// of further relevant TX's aren't watched.
+	maxWatchedSpends = 100000
+
+	// DoubleSpendConflict is a TX spending an output already spent by a TX
+	// it doesn't replace. The TX seen first is kept in the Mempool.
+	DoubleSpendConflict DoubleSpendKind = "conflict"
+
+	// DoubleSpendReplacement is a TX that replaces the TX seen before it,
+	// which signaled it may be replaced, by paying a higher fee. The
+	// replacement is kept in the Mempool.
+	DoubleSpendReplacement DoubleSpendKind = "replacement"
+)
+
+// DoubleSpendKind is the classification of a DoubleSpend.
+type DoubleSpendKind string
+
+// DoubleSpend is a conflict between TX's that spend the same output, at
+// least one of which is relevant.
+//
+// It is passed to the TX Listener when the conflict is first seen, and
+// again when one of the TX's is confirmed, with Confirmed and Block set.
+type DoubleSpend struct {
+	OutPoint wire.OutPoint
+
+	// Txs are the conflicting TX's, in the order they were seen.
+	Txs []chainhash.Hash
+
+	// Kind is whether the last of Txs replaced the TX before it, or is in
+	// conflict with it.
+	Kind DoubleSpendKind
+
+	// Confirmed is the TX of Txs that was confirmed, in the Block. They are
+	// nil while none is.
+	Confirmed *chainhash.Hash
+	Block     *chainhash.Hash
+}
+
+// DoubleSpends watches the outputs spent by unconfirmed relevant TX's, to
+// detect other TX's spending them.
+//
+// It is safe for concurrent use.
+type DoubleSpends struct {
+	mu     *sync.Mutex
+	spends map[wire.OutPoint]*watchedSpend
+}
+
+// watchedSpend are the TX's seen spending an output, and the Kind of the
+// last DoubleSpend of them.
+type watchedSpend struct {
+	txs   []chainhash.Hash
+	kind  DoubleSpendKind
+	added time.Time
+}
+
+// NewDoubleSpends returns a new DoubleSpends watching no outputs.
+func NewDoubleSpends() DoubleSpends {
+	return DoubleSpends{
+		mu:     &sync.Mutex{},
+		spends: map[wire.OutPoint]*watchedSpend{},
+	}
+}
+
+// Seen returns the DoubleSpends of the outputs the TX spends, and watches
+// them if the TX is relevant. They are DoubleSpendConflicts until they are
+// Classified.
+func (d DoubleSpends) Seen(tx *wire.MsgTx, relevant bool) []DoubleSpend {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	hash := tx.TxHash()
+	found := []DoubleSpend{}
+
+	for _, txIn := range tx.TxIn {
+		s, ok := d.spends[txIn.PreviousOutPoint]
+		if !ok {
+			if relevant && len(d.spends) < maxWatchedSpends {
+				d.spends[txIn.PreviousOutPoint] = &watchedSpend{
+					txs:   []chainhash.Hash{hash},
+					added: time.Now(),
+				}
+			}
+
+			continue
+		}
+
+		if s.has(hash) {
+			continue
+		}
+
+		s.txs = append(s.txs, hash)
+		s.kind = DoubleSpendConflict
+
+		found = append(found, DoubleSpend{
+			OutPoint: txIn.PreviousOutPoint,
+			Txs:      append([]chainhash.Hash{}, s.txs...),
+			Kind:     DoubleSpendConflict,
+		})
+	}
+
+	return found
+}
+
+// Confirmed stops watching the outputs spent by the TX's of the block, and
+// returns the DoubleSpends they resolve.
+func (d DoubleSpends) Confirmed(b *wire.MsgBlock) []DoubleSpend {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	blockHash := b.BlockHash()
+	found := []DoubleSpend{}
+
+	for _, tx := range b.Transactions {
+		hash := tx.TxHash()
+
+		for _, txIn := range tx.TxIn {
+			s, ok := d.spends[txIn.PreviousOutPoint]
+			if !ok {
+				continue
+			}
+
+			delete(d.spends, txIn.PreviousOutPoint)
+
+			if !s.has(hash) {
+				// the confirmed TX was never relayed
+				s.txs = append(s.txs, hash)
+				s.kind = DoubleSpendConflict
+			}
+
+			if len(s.txs) < 2 {
+				continue
+			}
+
+			confirmed := hash
+
+			found = append(found, DoubleSpend{
+				OutPoint:  txIn.PreviousOutPoint,
+				Txs:       s.txs,
+				Kind:      s.kind,
+				Confirmed: &confirmed,
+				Block:     &blockHash,
+			})
+		}
+	}
+
+	return found
+}
+
+// Classify records the Kind of the DoubleSpend, so it is passed on again
+// with it when one of the TX's is confirmed.
+func (d DoubleSpends) Classify(ds DoubleSpend) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if s, ok := d.spends[ds.OutPoint]; ok {
+		s.kind = ds.Kind
+	}
+}
+
+// Expire stops watching the outputs whose first spend was seen before the
+// time.
+func (d DoubleSpends) Expire(before time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for outpoint, s := range d.spends {
+		if s.added.Before(before) {
+			delete(d.spends, outpoint)
+		}
+	}
+}
+
+// Len returns the number of outputs watched.
+func (d DoubleSpends) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.spends)
+}
+
+// has returns true if the TX was seen spending the output.
+func (s watchedSpend) has(hash chainhash.Hash) bool {
+	for _, h := range s.txs {
+		if h == hash {
+			return true
+		}
+	}
+
+	return false
+}
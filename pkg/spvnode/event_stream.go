@@ -0,0 +1,283 @@
+package spvnode
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/tokenized/smart-contract/pkg/spvnode/logger"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+const (
+	EventTx           = "tx"
+	EventConfirmation = "confirmation"
+	EventBlock        = "block"
+	EventReorg        = "reorg"
+	EventExpired      = "expired"
+	EventDoubleSpend  = "double_spend"
+
+	// eventBuffer is the number of events queued for a subscriber. A
+	// subscriber that falls further behind is disconnected.
+	eventBuffer = 256
+
+	// maxSubscribers is the most WebSocket clients of an EventStream.
+	maxSubscribers = 100
+)
+
+// Event is sent, as JSON, to the subscribers of an EventStream.
+type Event struct {
+	Type string `json:"type"`
+
+	// Hash is of the TX of a tx or confirmation event, the block of a block
+	// event, or the confirmed TX of a double_spend event.
+	Hash string `json:"hash,omitempty"`
+
+	// Tx is the serialized TX of a tx event, in hex.
+	Tx string `json:"tx,omitempty"`
+
+	// Block and Height are of the block of a confirmation or block event,
+	// or of the last block in common of a reorg event. Block is of the
+	// confirmed TX of a double_spend event.
+	Block  string `json:"block,omitempty"`
+	Height int32  `json:"height,omitempty"`
+
+	// TxHashes are the relevant TX's of a block event, the unconfirmed TX's
+	// of a reorg event, the expired TX's of an expired event, or the
+	// conflicting TX's of a double_spend event.
+	TxHashes []string `json:"tx_hashes,omitempty"`
+
+	// OutPoint is the output spent by the TX's of a double_spend event, and
+	// Kind is whether the last of them is a replacement of, or in conflict
+	// with, the TX before it.
+	OutPoint string `json:"outpoint,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+
+	// Disconnected are the blocks of the old chain of a reorg event.
+	Disconnected []string `json:"disconnected,omitempty"`
+}
+
+// EventStream is a Listener that streams the relevant TX's, their
+// confirmations, and new blocks to WebSocket clients, as JSON Events. It
+// is an http.Handler that upgrades each request to a WebSocket.
+//
+// Clients only receive events, and anything they send is ignored.
+type EventStream struct {
+	Filters      []TxFilter
+	BlockService *BlockService
+
+	mu          *sync.Mutex
+	subscribers map[*wsConn]chan []byte
+}
+
+// NewEventStream returns a new EventStream of the TX's relevant to the
+// filters. The BlockService gives the heights of blocks.
+func NewEventStream(filters []TxFilter, blockService *BlockService) EventStream {
+	return EventStream{
+		Filters:      filters,
+		BlockService: blockService,
+		mu:           &sync.Mutex{},
+		subscribers:  map[*wsConn]chan []byte{},
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+//
+// It blocks, sending events to the client, until the client disconnects.
+func (s EventStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Len() >= maxSubscribers {
+		http.Error(w, "Too many subscribers", http.StatusServiceUnavailable)
+		return
+	}
+
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer conn.Close()
+
+	events := make(chan []byte, eventBuffer)
+
+	s.mu.Lock()
+	s.subscribers[conn] = events
+	s.mu.Unlock()
+
+	go func() {
+		// the client is gone once it can't be read from
+		conn.readLoop()
+		s.unsubscribe(conn)
+	}()
+
+	for e := range events {
+		if err := conn.WriteText(e); err != nil {
+			s.unsubscribe(conn)
+			return
+		}
+	}
+}
+
+// Len returns the number of subscribers.
+func (s EventStream) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.subscribers)
+}
+
+// Close disconnects all subscribers.
+func (s EventStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn, events := range s.subscribers {
+		delete(s.subscribers, conn)
+		close(events)
+		conn.Close()
+	}
+}
+
+// Handle implements the Listener interface.
+func (s EventStream) Handle(ctx context.Context, m wire.Message) error {
+	switch msg := m.(type) {
+	case *wire.MsgTx:
+		if !isRelevant(s.Filters, msg) {
+			return nil
+		}
+
+		var buf bytes.Buffer
+		if err := msg.Serialize(&buf); err != nil {
+			return err
+		}
+
+		s.publish(ctx, Event{
+			Type: EventTx,
+			Hash: msg.TxHash().String(),
+			Tx:   hex.EncodeToString(buf.Bytes()),
+		})
+
+	case *wire.MsgBlock:
+		hash := msg.BlockHash()
+
+		height := int32(0)
+		if block, err := s.BlockService.Read(ctx, hash); err == nil {
+			height = block.Height
+		}
+
+		txHashes := relevantTxHashes(s.Filters, msg)
+
+		for _, txHash := range txHashes {
+			s.publish(ctx, Event{
+				Type:   EventConfirmation,
+				Hash:   txHash,
+				Block:  hash.String(),
+				Height: height,
+			})
+		}
+
+		s.publish(ctx, Event{
+			Type:     EventBlock,
+			Hash:     hash.String(),
+			Height:   height,
+			TxHashes: txHashes,
+		})
+	}
+
+	return nil
+}
+
+// HandleReorg implements the Listener interface.
+func (s EventStream) HandleReorg(ctx context.Context, r Reorg) error {
+	s.publish(ctx, Event{
+		Type:         EventReorg,
+		Block:        r.AncestorHash.String(),
+		Height:       r.AncestorHeight,
+		TxHashes:     hashStrings(r.Unconfirmed),
+		Disconnected: hashStrings(r.Disconnected),
+	})
+
+	return nil
+}
+
+// HandleExpired implements the Listener interface.
+func (s EventStream) HandleExpired(ctx context.Context,
+	hashes []chainhash.Hash) error {
+
+	s.publish(ctx, Event{
+		Type:     EventExpired,
+		TxHashes: hashStrings(hashes),
+	})
+
+	return nil
+}
+
+// HandleDoubleSpend implements the Listener interface.
+func (s EventStream) HandleDoubleSpend(ctx context.Context, d DoubleSpend) error {
+	e := Event{
+		Type:     EventDoubleSpend,
+		TxHashes: hashStrings(d.Txs),
+		OutPoint: d.OutPoint.String(),
+		Kind:     string(d.Kind),
+	}
+
+	if d.Confirmed != nil {
+		e.Hash = d.Confirmed.String()
+		e.Block = d.Block.String()
+	}
+
+	s.publish(ctx, e)
+
+	return nil
+}
+
+// publish queues the event for each subscriber. Subscribers whose queue is
+// full are disconnected, rather than holding up the Node.
+func (s EventStream) publish(ctx context.Context, e Event) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		log := logger.NewLoggerFromContext(ctx).Sugar()
+		log.Errorf("Failed to marshal %v event : %v", e.Type, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn, events := range s.subscribers {
+		select {
+		case events <- b:
+		default:
+			delete(s.subscribers, conn)
+			close(events)
+			conn.Close()
+		}
+	}
+}
+
+// unsubscribe removes the subscriber, if it hasn't been already.
+func (s EventStream) unsubscribe(conn *wsConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if events, ok := s.subscribers[conn]; ok {
+		delete(s.subscribers, conn)
+		close(events)
+	}
+
+	conn.Close()
+}
+
+// hashStrings returns the hashes as strings.
+func hashStrings(hashes []chainhash.Hash) []string {
+	s := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		s = append(s, hash.String())
+	}
+
+	return s
+}
@@ -0,0 +1,160 @@
+package spvnode
+
+import (
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+// AllOfFilter is a TxFilter matching the TX's relevant to all of its
+// filters. With no filters, every TX is relevant.
+type AllOfFilter struct {
+	Filters []TxFilter
+}
+
+// AllOf returns a TxFilter matching the TX's relevant to all of the
+// filters.
+func AllOf(filters ...TxFilter) AllOfFilter {
+	return AllOfFilter{
+		Filters: filters,
+	}
+}
+
+// IsRelevant implements the TxFilter interface.
+func (f AllOfFilter) IsRelevant(tx *wire.MsgTx) bool {
+	for _, filter := range f.Filters {
+		if !filter.IsRelevant(tx) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Elements implements the TxFilter interface.
+//
+// The elements of any one filter are enough, as every TX relevant to all
+// of them is relevant to each.
+func (f AllOfFilter) Elements() [][]byte {
+	for _, filter := range f.Filters {
+		if e := filter.Elements(); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// AnyOfFilter is a TxFilter matching the TX's relevant to any of its
+// filters. With no filters, no TX is relevant.
+type AnyOfFilter struct {
+	Filters []TxFilter
+}
+
+// AnyOf returns a TxFilter matching the TX's relevant to any of the
+// filters.
+func AnyOf(filters ...TxFilter) AnyOfFilter {
+	return AnyOfFilter{
+		Filters: filters,
+	}
+}
+
+// IsRelevant implements the TxFilter interface.
+func (f AnyOfFilter) IsRelevant(tx *wire.MsgTx) bool {
+	for _, filter := range f.Filters {
+		if filter.IsRelevant(tx) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Elements implements the TxFilter interface.
+//
+// The elements of every filter are needed, so if any filter can't be
+// matched by its data, neither can this.
+func (f AnyOfFilter) Elements() [][]byte {
+	elements := [][]byte{}
+
+	for _, filter := range f.Filters {
+		e := filter.Elements()
+		if e == nil {
+			return nil
+		}
+
+		elements = append(elements, e...)
+	}
+
+	return elements
+}
+
+// NotFilter is a TxFilter matching the TX's not relevant to its filter.
+type NotFilter struct {
+	Filter TxFilter
+}
+
+// Not returns a TxFilter matching the TX's not relevant to the filter.
+func Not(filter TxFilter) NotFilter {
+	return NotFilter{
+		Filter: filter,
+	}
+}
+
+// IsRelevant implements the TxFilter interface.
+func (f NotFilter) IsRelevant(tx *wire.MsgTx) bool {
+	return !f.Filter.IsRelevant(tx)
+}
+
+// Elements implements the TxFilter interface.
+//
+// The TX's that don't push some data can't be matched by a bloom filter.
+func (f NotFilter) Elements() [][]byte {
+	return nil
+}
+
+// FilterSet is a TxFilter matching the TX's relevant to any of the
+// included filters, and none of the excluded ones, such as the TX's of a
+// protocol, or paying to some addresses, but not from a blacklist.
+//
+// Include and Exclude return a new FilterSet, so a FilterSet can be built
+// up declaratively.
+type FilterSet struct {
+	Included []TxFilter
+	Excluded []TxFilter
+}
+
+// NewFilterSet returns a new FilterSet including the filters.
+func NewFilterSet(included ...TxFilter) FilterSet {
+	return FilterSet{
+		Included: included,
+	}
+}
+
+// Include returns a copy of the FilterSet that also includes the filters.
+func (s FilterSet) Include(filters ...TxFilter) FilterSet {
+	return FilterSet{
+		Included: append(append([]TxFilter{}, s.Included...), filters...),
+		Excluded: s.Excluded,
+	}
+}
+
+// Exclude returns a copy of the FilterSet that also excludes the filters.
+func (s FilterSet) Exclude(filters ...TxFilter) FilterSet {
+	return FilterSet{
+		Included: s.Included,
+		Excluded: append(append([]TxFilter{}, s.Excluded...), filters...),
+	}
+}
+
+// IsRelevant implements the TxFilter interface.
+func (s FilterSet) IsRelevant(tx *wire.MsgTx) bool {
+	return AnyOf(s.Included...).IsRelevant(tx) &&
+		!AnyOf(s.Excluded...).IsRelevant(tx)
+}
+
+// Elements implements the TxFilter interface.
+//
+// Only the included filters narrow the TX's that are sent, as a bloom
+// filter can't leave TX's out.
+func (s FilterSet) Elements() [][]byte {
+	return AnyOf(s.Included...).Elements()
+}
@@ -0,0 +1,150 @@
+package spvnode
+
+import (
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// ErrPartialMerkleTree is returned when the partial merkle tree of a
+// merkleblock is malformed.
+var ErrPartialMerkleTree = errs.New(errs.Invalid, "Malformed partial merkle tree")
+
+// FilteredBlock is a BIP37 merkleblock, with the TX's that matched the
+// bloom filter of the peer.
+type FilteredBlock struct {
+	MerkleBlock  *wire.MsgMerkleBlock
+	Transactions []*wire.MsgTx
+}
+
+// Block returns a block with the header, and only the matched TX's.
+//
+// The TX's are verified against the merkle root of the header, through the
+// partial merkle tree of the merkleblock.
+func (b FilteredBlock) Block() (*wire.MsgBlock, error) {
+	m := b.MerkleBlock
+
+	root, matched, err := partialMerkleRoot(m.Transactions, m.Hashes, m.Flags)
+	if err != nil {
+		return nil, err
+	}
+
+	if root != m.Header.MerkleRoot {
+		return nil, ErrMerkleRoot
+	}
+
+	txs := map[chainhash.Hash]*wire.MsgTx{}
+	for _, tx := range b.Transactions {
+		txs[tx.TxHash()] = tx
+	}
+
+	block := wire.NewMsgBlock(&m.Header)
+
+	for _, hash := range matched {
+		tx, ok := txs[hash]
+		if !ok {
+			return nil, ErrMerkleRoot
+		}
+
+		block.AddTransaction(tx)
+	}
+
+	return block, nil
+}
+
+// partialMerkleRoot returns the merkle root of a partial merkle tree, and
+// the hashes of the matched TX's in the order of the block.
+func partialMerkleRoot(total uint32,
+	hashes []*chainhash.Hash,
+	flags []byte) (chainhash.Hash, []chainhash.Hash, error) {
+
+	if total == 0 || len(hashes) == 0 || len(hashes) > int(total) {
+		return chainhash.Hash{}, nil, ErrPartialMerkleTree
+	}
+
+	t := partialMerkleTree{
+		total:  total,
+		hashes: hashes,
+		flags:  flags,
+	}
+
+	height := uint(0)
+	for t.width(height) > 1 {
+		height++
+	}
+
+	root, err := t.traverse(height, 0)
+	if err != nil {
+		return chainhash.Hash{}, nil, err
+	}
+
+	// all hashes and flag bytes must be used
+	if t.hashesUsed != len(hashes) || (t.bitsUsed+7)/8 != len(flags) {
+		return chainhash.Hash{}, nil, ErrPartialMerkleTree
+	}
+
+	return root, t.matched, nil
+}
+
+// partialMerkleTree holds the state of traversing a partial merkle tree.
+type partialMerkleTree struct {
+	total      uint32
+	hashes     []*chainhash.Hash
+	flags      []byte
+	hashesUsed int
+	bitsUsed   int
+	matched    []chainhash.Hash
+}
+
+// width returns the number of nodes at the height of the tree.
+func (t *partialMerkleTree) width(height uint) uint32 {
+	return (t.total + (1 << height) - 1) >> height
+}
+
+// traverse returns the hash of the node at the height and position, in
+// depth first order.
+func (t *partialMerkleTree) traverse(height uint,
+	pos uint32) (chainhash.Hash, error) {
+
+	if t.bitsUsed >= len(t.flags)*8 {
+		return chainhash.Hash{}, ErrPartialMerkleTree
+	}
+
+	parent := t.flags[t.bitsUsed/8]&(1<<uint(t.bitsUsed%8)) != 0
+	t.bitsUsed++
+
+	if height == 0 || !parent {
+		if t.hashesUsed >= len(t.hashes) {
+			return chainhash.Hash{}, ErrPartialMerkleTree
+		}
+
+		hash := *t.hashes[t.hashesUsed]
+		t.hashesUsed++
+
+		if height == 0 && parent {
+			t.matched = append(t.matched, hash)
+		}
+
+		return hash, nil
+	}
+
+	left, err := t.traverse(height-1, pos*2)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+
+	right := left
+	if pos*2+1 < t.width(height-1) {
+		if right, err = t.traverse(height-1, pos*2+1); err != nil {
+			return chainhash.Hash{}, err
+		}
+
+		// identical children can forge a tree with duplicated TX's
+		if right == left {
+			return chainhash.Hash{}, ErrPartialMerkleTree
+		}
+	}
+
+	return chainhash.DoubleHashH(append(left[:], right[:]...)), nil
+}
@@ -2,8 +2,8 @@ package spvnode
 
 import (
 	"context"
-	"errors"
 
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/spvnode/logger"
 	"github.com/tokenized/smart-contract/pkg/wire"
 
@@ -33,7 +33,7 @@ func (h GetHeadersHandler) Handle(ctx context.Context,
 
 	in, ok := m.(*wire.MsgGetHeaders)
 	if !ok {
-		return nil, errors.New("Could not assert as *wire.Msginv")
+		return nil, errs.New(errs.Invalid, "Could not assert as *wire.Msginv")
 	}
 
 	return h.handle(ctx, in)
@@ -47,7 +47,7 @@ func (h GetHeadersHandler) handle(ctx context.Context,
 
 	// loop over the hashes, which are ordered most recent to oldest. For
 	// the first one that is found, send a getheaders message. If none are
-	// found, send the last hash (genesis block).
+	// found, send the genesis block of the network.
 	var mostRecent *chainhash.Hash
 
 	for _, hash := range m.BlockLocatorHashes {
@@ -58,9 +58,13 @@ func (h GetHeadersHandler) handle(ctx context.Context,
 	}
 
 	if mostRecent == nil {
-		// we have no blocks with matching hashes, so use the oldest, which
-		// for an empty set will be the genesis block.
-		mostRecent = m.BlockLocatorHashes[len(m.BlockLocatorHashes)-1]
+		// we have no blocks with matching hashes, so start from the genesis
+		// block, which is also the oldest hash of a locator.
+		if genesis, ok := NetworkGenesis[h.Config.Network]; ok {
+			mostRecent = &genesis
+		} else {
+			mostRecent = m.BlockLocatorHashes[len(m.BlockLocatorHashes)-1]
+		}
 	}
 
 	block, ok := h.BlockService.Blocks[*mostRecent]
@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 )
 
 // CommandHandler defines an interface for handing commands received from
@@ -12,22 +14,59 @@ type CommandHandler interface {
 	Handle(context.Context, wire.Message) ([]wire.Message, error)
 }
 
+// Listener is passed the TX's or blocks received by the Node.
 type Listener interface {
 	Handle(context.Context, wire.Message) error
+
+	// HandleReorg is passed each change of the best chain, before the
+	// blocks of the new chain. It is only called on the block Listener.
+	HandleReorg(context.Context, Reorg) error
+
+	// HandleExpired is passed the hashes of the TX's that expired from the
+	// Mempool without being confirmed. It is only called on the TX
+	// Listener.
+	HandleExpired(context.Context, []chainhash.Hash) error
+
+	// HandleDoubleSpend is passed each conflict between TX's spending the
+	// same output, at least one of which is relevant, when it is seen and
+	// when it is resolved by a block. It is only called on the TX Listener.
+	HandleDoubleSpend(context.Context, DoubleSpend) error
 }
 
 // newCommandHandlers returns a mapping of commands and Handler's.
 func newCommandHandlers(config Config,
 	blockService *BlockService,
-	listeners map[string]Listener) map[string]CommandHandler {
+	mempool Mempool,
+	tracker BroadcastTracker,
+	rebroadcast Rebroadcaster,
+	txRequests TxRequests,
+	orphans OrphanPool,
+	doubleSpends DoubleSpends,
+	seen SeenTxs,
+	peers PeerRepository,
+	txListener Listener,
+	blockListener Listener,
+	filters []TxFilter) map[string]CommandHandler {
+
+	addrs := NewAddrHandler(peers)
+	compactBlocks := NewCompactBlocks(mempool)
+	txs := NewTXHandler(config, blockService, mempool, txListener,
+		tracker, txRequests, orphans, doubleSpends, seen, filters)
+	blocks := NewBlockHandler(config, blockService, mempool,
+		blockListener, tracker, rebroadcast, txs, filters)
 
 	return map[string]CommandHandler{
 		wire.CmdPing:       NewPingHandler(config),
 		wire.CmdVersion:    NewVersionHandler(config),
-		wire.CmdInv:        NewInvHandler(config),
-		wire.CmdTx:         NewTXHandler(config, blockService, listeners[ListenerTX]),
-		wire.CmdBlock:      NewBlockHandler(config, blockService, listeners[ListenerBlock]),
+		wire.CmdAddr:       addrs,
+		wire.CmdAddrV2:     addrs,
+		wire.CmdInv:        NewInvHandler(config, blockService, txRequests),
+		wire.CmdTx:         txs,
+		wire.CmdNotFound:   NewNotFoundHandler(txRequests, txs),
+		wire.CmdBlock:      blocks,
+		wire.CmdCmpctBlock: NewCmpctBlockHandler(config, blockService, compactBlocks, blocks),
+		wire.CmdBlockTxn:   NewBlockTxnHandler(config, compactBlocks, blocks),
 		wire.CmdGetHeaders: NewGetHeadersHandler(config, blockService),
-		wire.CmdHeaders:    NewHeadersHandler(config, blockService),
+		wire.CmdHeaders:    NewHeadersHandler(config, blockService, blockListener),
 	}
 }
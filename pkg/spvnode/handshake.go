@@ -0,0 +1,92 @@
+package spvnode
+
+import (
+	"net"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+const (
+	// defaultHandshakeTimeout is how long an untrusted peer has to
+	// complete the version handshake, if the Config doesn't set it.
+	defaultHandshakeTimeout = 30 * time.Second
+)
+
+var (
+	ErrHandshakeTimeout = errs.New(errs.Temporary, "Peer did not complete the handshake in time")
+	ErrPeerVersion      = errs.New(errs.Invalid, "Peer protocol version is too old")
+	ErrPeerServices     = errs.New(errs.Invalid, "Peer does not offer the required services")
+)
+
+// Handshake is how the version handshake with an untrusted peer is made,
+// and what the peer must advertise in it to stay connected.
+type Handshake struct {
+	// UserAgent, Services and DisableRelay are advertised in the version
+	// message sent to the peer.
+	UserAgent    string
+	Services     wire.ServiceFlag
+	DisableRelay bool
+
+	// FeeFilter is sent to the peer after the handshake, if it is not 0.
+	FeeFilter int64
+
+	// Timeout is how long the peer has to send its version and verack.
+	Timeout time.Duration
+
+	// MinVersion is the lowest protocol version accepted, and
+	// RequiredServices are the service bits the peer must offer.
+	MinVersion       uint32
+	RequiredServices wire.ServiceFlag
+}
+
+// NewHandshake returns the Handshake of the Config.
+func NewHandshake(config Config) Handshake {
+	h := Handshake{
+		UserAgent:        config.UserAgent,
+		Services:         config.Services,
+		DisableRelay:     config.DisableRelay || config.HeadersOnly,
+		FeeFilter:        config.FeeFilter,
+		Timeout:          config.HandshakeTimeout,
+		MinVersion:       config.MinPeerVersion,
+		RequiredServices: config.RequiredServices,
+	}
+
+	if h.Timeout == 0 {
+		h.Timeout = defaultHandshakeTimeout
+	}
+
+	return h
+}
+
+// Check returns an error if the version message of the peer is too old, or
+// doesn't offer the required services.
+func (h Handshake) Check(msg *wire.MsgVersion) error {
+	if uint32(msg.ProtocolVersion) < h.MinVersion {
+		return ErrPeerVersion
+	}
+
+	if msg.Services&h.RequiredServices != h.RequiredServices {
+		return ErrPeerServices
+	}
+
+	return nil
+}
+
+// handshakeError returns ErrHandshakeTimeout in place of the error of a
+// read that timed out during the handshake.
+func handshakeError(err error) error {
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		return ErrHandshakeTimeout
+	}
+
+	return err
+}
+
+// isHandshakeError returns true if the error is from a peer that failed the
+// Handshake.
+func isHandshakeError(err error) bool {
+	return err == ErrHandshakeTimeout || err == ErrPeerVersion ||
+		err == ErrPeerServices
+}
@@ -0,0 +1,434 @@
+package spvnode
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+const (
+	// targetSpacing is the time between blocks the difficulty targets, in
+	// seconds.
+	targetSpacing = 600
+
+	// daaWindow is the number of blocks the work and time is measured over
+	// by the difficulty adjustment algorithm.
+	daaWindow = 144
+)
+
+var (
+	// ErrProofOfWork is returned when the hash of a header is above the
+	// target set by its difficulty bits.
+	ErrProofOfWork = errs.New(errs.Invalid, "Header does not meet its proof of work target")
+
+	// ErrTargetLimit is returned when the target of a header is easier than
+	// the network allows.
+	ErrTargetLimit = errs.New(errs.Invalid, "Header target is above the proof of work limit")
+
+	// ErrDifficulty is returned when the difficulty bits of a header are
+	// not those required by the retarget rules of the network.
+	ErrDifficulty = errs.New(errs.Invalid, "Header difficulty does not follow the retarget rules")
+)
+
+// isHeaderError returns true if the error is from the validation of a
+// header.
+func isHeaderError(err error) bool {
+	return err == ErrProofOfWork || err == ErrTargetLimit ||
+		err == ErrDifficulty || err == ErrCheckpoint
+}
+
+// HeaderRules are the proof of work rules of a network.
+type HeaderRules struct {
+	// PowLimit is the highest, and easiest, target allowed.
+	PowLimit *big.Int
+
+	// DAAHeight is the height of the last block before the difficulty
+	// adjustment algorithm of November 2017 activated. Difficulty
+	// transitions before it are not checked.
+	DAAHeight int32
+
+	// MinDifficultyBlocks allows a block at the PowLimit when it is more
+	// than 20 minutes after the previous block, as on the test network.
+	MinDifficultyBlocks bool
+
+	// NoRetargeting requires each block to have the difficulty of the
+	// previous block, as on the regression test network.
+	NoRetargeting bool
+
+	// ASERT is the anchor of the aserti3-2d difficulty adjustment algorithm
+	// of November 2020, which replaced the algorithm of November 2017 for
+	// the blocks after it. Nil if the network does not use it.
+	ASERT *ASERTAnchor
+}
+
+// ASERTAnchor is the block the aserti3-2d difficulty adjustment algorithm
+// computes the target of each later block from.
+type ASERTAnchor struct {
+	// Height is the height of the anchor block, the last block with a
+	// target set by the algorithm of November 2017.
+	Height int32
+
+	// Bits are the difficulty bits of the anchor block.
+	Bits uint32
+
+	// PrevTimestamp is the timestamp of the block before the anchor block.
+	PrevTimestamp int64
+
+	// HalfLife is the time, in seconds, the chain must fall behind, or get
+	// ahead of, the ideal schedule for the target to double, or halve.
+	HalfLife int64
+}
+
+// NetworkHeaderRules are the HeaderRules of each network.
+var NetworkHeaderRules = map[wire.BitcoinNet]HeaderRules{
+	MainNetBch: HeaderRules{
+		PowLimit:  powLimit(224),
+		DAAHeight: 504031,
+		ASERT: &ASERTAnchor{
+			Height:        661647,
+			Bits:          0x1804dafe,
+			PrevTimestamp: 1605447844,
+			HalfLife:      2 * 24 * 60 * 60,
+		},
+	},
+	TestNetBch: HeaderRules{
+		PowLimit:            powLimit(224),
+		DAAHeight:           1188697,
+		MinDifficultyBlocks: true,
+		ASERT: &ASERTAnchor{
+			Height:        1421481,
+			Bits:          0x1d00ffff,
+			PrevTimestamp: 1605445400,
+			HalfLife:      60 * 60,
+		},
+	},
+	RegTestBch: HeaderRules{
+		PowLimit:      powLimit(255),
+		NoRetargeting: true,
+	},
+	StnBch: HeaderRules{
+		PowLimit:            powLimit(224),
+		DAAHeight:           2200,
+		MinDifficultyBlocks: true,
+	},
+}
+
+// powLimit returns the target 2^bits - 1.
+func powLimit(bits uint) *big.Int {
+	limit := new(big.Int).Lsh(big.NewInt(1), bits)
+	return limit.Sub(limit, big.NewInt(1))
+}
+
+// checkProofOfWork returns nil if the hash of the header meets the target
+// encoded in its bits, and the target is within the limit of the network.
+func checkProofOfWork(rules HeaderRules, header *wire.BlockHeader) error {
+	target := compactToBig(header.Bits)
+	if target.Sign() <= 0 {
+		return ErrProofOfWork
+	}
+
+	if target.Cmp(rules.PowLimit) > 0 {
+		return ErrTargetLimit
+	}
+
+	hash := header.BlockHash()
+	if hashToBig(&hash).Cmp(target) > 0 {
+		return ErrProofOfWork
+	}
+
+	return nil
+}
+
+// checkHeader returns nil if the header has valid proof of work, the
+// difficulty required by the retarget rules after the previous block, and
+// does not contradict a checkpoint.
+//
+// The difficulty of headers up to the last checkpoint is not checked, as
+// the checkpoints already fix that part of the chain.
+//
+// The difficulty can only be checked once the bits and timestamps of the
+// blocks of the last retarget window are known, so it is not checked
+// just after the chain was started from a block.
+func (b BlockService) checkHeader(ctx context.Context,
+	header *wire.BlockHeader,
+	prev Block) error {
+
+	rules := b.rules
+
+	if err := b.checkCheckpoint(prev.Height+1, header.BlockHash()); err != nil {
+		return err
+	}
+
+	if err := checkProofOfWork(rules, header); err != nil {
+		return err
+	}
+
+	if prev.Height < b.lastCheckpoint() {
+		return nil
+	}
+
+	if prev.Height < rules.DAAHeight || prev.Bits == 0 {
+		return nil
+	}
+
+	if rules.NoRetargeting {
+		if header.Bits != prev.Bits {
+			return ErrDifficulty
+		}
+
+		return nil
+	}
+
+	if rules.MinDifficultyBlocks &&
+		header.Timestamp.Unix() > prev.Timestamp+2*targetSpacing {
+
+		// a block that took too long is mined at the lowest difficulty
+		if header.Bits != bigToCompact(rules.PowLimit) {
+			return ErrDifficulty
+		}
+
+		return nil
+	}
+
+	if rules.ASERT != nil && prev.Height >= rules.ASERT.Height {
+		if header.Bits != nextASERTBits(rules, prev) {
+			return ErrDifficulty
+		}
+
+		return nil
+	}
+
+	window, ok := b.retargetWindow(ctx, prev)
+	if !ok {
+		return nil
+	}
+
+	if header.Bits != nextBits(rules, window) {
+		return ErrDifficulty
+	}
+
+	return nil
+}
+
+// retargetWindow returns the previous block and its ancestors, lowest
+// first, as far back as the difficulty adjustment algorithm looks. It
+// returns false if any of them, or their bits and timestamps, are unknown.
+func (b BlockService) retargetWindow(ctx context.Context,
+	prev Block) ([]Block, bool) {
+
+	window := make([]Block, daaWindow+3)
+
+	block := prev
+	for i := len(window) - 1; i >= 0; i-- {
+		if block.Bits == 0 {
+			return nil, false
+		}
+
+		window[i] = block
+
+		if i == 0 {
+			break
+		}
+
+		p, err := b.readPrev(ctx, block)
+		if err != nil {
+			return nil, false
+		}
+
+		block = *p
+	}
+
+	return window, true
+}
+
+// nextBits returns the difficulty bits required of the block after the
+// last block of the window, as in the difficulty adjustment algorithm of
+// November 2017.
+//
+// The target is set by the work done over the last 144 blocks, and the time
+// it took. The median timestamp of three blocks is used at each end, so a
+// single block with a skewed timestamp has little effect.
+func nextBits(rules HeaderRules, window []Block) uint32 {
+	last := len(window) - 1
+	first := suitableBlock(window, last-daaWindow)
+	last = suitableBlock(window, last)
+
+	// the work done by the blocks after the first, up to the last
+	work := new(big.Int)
+	for _, block := range window[first+1 : last+1] {
+		work.Add(work, blockWork(block.Bits))
+	}
+
+	timespan := window[last].Timestamp - window[first].Timestamp
+	if timespan < daaWindow/2*targetSpacing {
+		timespan = daaWindow / 2 * targetSpacing
+	}
+
+	if timespan > daaWindow*2*targetSpacing {
+		timespan = daaWindow * 2 * targetSpacing
+	}
+
+	work.Mul(work, big.NewInt(targetSpacing))
+	work.Div(work, big.NewInt(timespan))
+
+	// the target that takes that much work, on average, per block
+	target := new(big.Int).Lsh(big.NewInt(1), 256)
+	target.Sub(target, work)
+	target.Div(target, work)
+
+	if target.Cmp(rules.PowLimit) > 0 {
+		return bigToCompact(rules.PowLimit)
+	}
+
+	return bigToCompact(target)
+}
+
+// nextASERTBits returns the difficulty bits required of the block after
+// prev, as in the aserti3-2d difficulty adjustment algorithm of November
+// 2020.
+//
+// The target of the anchor block is doubled for every half life the chain
+// is behind the ideal schedule since the anchor, and halved for every half
+// life it is ahead. The fractional part of the exponent is approximated by
+// a cubic polynomial, in the fixed point arithmetic of the specification,
+// so every node computes the same target.
+func nextASERTBits(rules HeaderRules, prev Block) uint32 {
+	anchor := rules.ASERT
+
+	timeDelta := prev.Timestamp - anchor.PrevTimestamp
+	heightDelta := int64(prev.Height - anchor.Height)
+
+	// the exponent in 16.16 fixed point, truncated toward zero
+	exponent := (timeDelta - targetSpacing*(heightDelta+1)) * 65536 /
+		anchor.HalfLife
+
+	// an arithmetic shift rounds down, so frac is always positive
+	shifts := exponent >> 16
+	frac := uint64(uint16(exponent))
+
+	factor := 65536 + ((195766423245049*frac +
+		971821376*frac*frac +
+		5127*frac*frac*frac +
+		(1 << 47)) >> 48)
+
+	target := compactToBig(anchor.Bits)
+	target.Mul(target, new(big.Int).SetUint64(factor))
+
+	shifts -= 16
+	if shifts <= 0 {
+		target.Rsh(target, uint(-shifts))
+	} else if shifts > 256 {
+		return bigToCompact(rules.PowLimit)
+	} else {
+		target.Lsh(target, uint(shifts))
+	}
+
+	if target.Sign() == 0 {
+		return bigToCompact(big.NewInt(1))
+	}
+
+	if target.Cmp(rules.PowLimit) > 0 {
+		return bigToCompact(rules.PowLimit)
+	}
+
+	return bigToCompact(target)
+}
+
+// suitableBlock returns the index of the block with the median timestamp
+// of the block at i and the two before it.
+func suitableBlock(window []Block, i int) int {
+	blocks := []int{i - 2, i - 1, i}
+
+	if window[blocks[0]].Timestamp > window[blocks[2]].Timestamp {
+		blocks[0], blocks[2] = blocks[2], blocks[0]
+	}
+
+	if window[blocks[0]].Timestamp > window[blocks[1]].Timestamp {
+		blocks[0], blocks[1] = blocks[1], blocks[0]
+	}
+
+	if window[blocks[1]].Timestamp > window[blocks[2]].Timestamp {
+		blocks[1], blocks[2] = blocks[2], blocks[1]
+	}
+
+	return blocks[1]
+}
+
+// blockWork returns the expected number of hashes to find a block with the
+// difficulty bits, which is 2^256 / (target + 1).
+func blockWork(bits uint32) *big.Int {
+	target := compactToBig(bits)
+	if target.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+
+	work := new(big.Int).Lsh(big.NewInt(1), 256)
+
+	return work.Div(work, target.Add(target, big.NewInt(1)))
+}
+
+// hashToBig returns the hash as a big integer. Hashes are little endian.
+func hashToBig(hash *chainhash.Hash) *big.Int {
+	b := make([]byte, chainhash.HashSize)
+	for i := range hash {
+		b[chainhash.HashSize-1-i] = hash[i]
+	}
+
+	return new(big.Int).SetBytes(b)
+}
+
+// compactToBig returns the target encoded by the compact form of the
+// difficulty bits.
+//
+// The high byte is the size of the target in bytes, and the low 23 bits
+// are its most significant bits. Bit 24 is the sign.
+func compactToBig(compact uint32) *big.Int {
+	mantissa := compact & 0x007fffff
+	negative := compact&0x00800000 != 0
+	exponent := uint(compact >> 24)
+
+	var n *big.Int
+	if exponent <= 3 {
+		mantissa >>= 8 * (3 - exponent)
+		n = big.NewInt(int64(mantissa))
+	} else {
+		n = big.NewInt(int64(mantissa))
+		n.Lsh(n, 8*(exponent-3))
+	}
+
+	if negative {
+		n = n.Neg(n)
+	}
+
+	return n
+}
+
+// bigToCompact returns the compact form of a positive target, the inverse
+// of compactToBig. Precision below the 3 most significant bytes is lost.
+func bigToCompact(n *big.Int) uint32 {
+	if n.Sign() == 0 {
+		return 0
+	}
+
+	var mantissa uint32
+	exponent := uint(len(n.Bytes()))
+
+	if exponent <= 3 {
+		mantissa = uint32(n.Bits()[0])
+		mantissa <<= 8 * (3 - exponent)
+	} else {
+		mantissa = uint32(new(big.Int).Rsh(n, 8*(exponent-3)).Bits()[0])
+	}
+
+	// the mantissa would be read as negative, so use a larger exponent
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		exponent++
+	}
+
+	return uint32(exponent<<24) | mantissa
+}
@@ -0,0 +1,262 @@
+package spvnode
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+func TestNextASERTBits(t *testing.T) {
+	rules := NetworkHeaderRules[MainNetBch]
+	anchor := rules.ASERT
+
+	tests := []struct {
+		name   string
+		height int32
+		time   int64
+		want   uint32
+	}{
+		{
+			name:   "anchor on schedule",
+			height: anchor.Height,
+			time:   anchor.PrevTimestamp + targetSpacing,
+			want:   anchor.Bits,
+		},
+		{
+			name:   "later block on schedule",
+			height: anchor.Height + 1000,
+			time:   anchor.PrevTimestamp + 1001*targetSpacing,
+			want:   anchor.Bits,
+		},
+		{
+			name:   "one half life behind",
+			height: anchor.Height,
+			time:   anchor.PrevTimestamp + targetSpacing + anchor.HalfLife,
+			want:   0x1809b5fc,
+		},
+		{
+			name:   "one half life ahead",
+			height: anchor.Height + int32(anchor.HalfLife/targetSpacing),
+			time:   anchor.PrevTimestamp + targetSpacing,
+			want:   0x18026d7f,
+		},
+		{
+			name:   "far behind",
+			height: anchor.Height,
+			time:   anchor.PrevTimestamp + 100*anchor.HalfLife,
+			want:   bigToCompact(rules.PowLimit),
+		},
+		{
+			name:   "far ahead",
+			height: anchor.Height + 60000,
+			time:   anchor.PrevTimestamp,
+			want:   0x01010000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := Block{
+				Height:    tt.height,
+				Timestamp: tt.time,
+			}
+
+			if got := nextASERTBits(rules, prev); got != tt.want {
+				t.Fatalf("got %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextASERTBits_fraction(t *testing.T) {
+	rules := NetworkHeaderRules[MainNetBch]
+	anchor := rules.ASERT
+
+	// half a half life behind multiplies the target by the square root of 2
+	prev := Block{
+		Height:    anchor.Height,
+		Timestamp: anchor.PrevTimestamp + targetSpacing + anchor.HalfLife/2,
+	}
+
+	got := new(big.Float).SetInt(compactToBig(nextASERTBits(rules, prev)))
+	ratio, _ := got.Quo(got, new(big.Float).SetInt(compactToBig(anchor.Bits))).Float64()
+
+	if ratio < 1.4140 || ratio > 1.4144 {
+		t.Fatalf("got ratio %v, want %v", ratio, 1.4142)
+	}
+}
+
+func TestNextBits(t *testing.T) {
+	rules := NetworkHeaderRules[MainNetBch]
+
+	tests := []struct {
+		name    string
+		spacing int64
+		want    uint32
+	}{
+		{
+			name:    "on schedule",
+			spacing: targetSpacing,
+			want:    0x1804dafe,
+		},
+		{
+			name:    "twice as slow",
+			spacing: 2 * targetSpacing,
+			want:    0x1809b5fc,
+		},
+		{
+			name:    "clamped slow",
+			spacing: 10 * targetSpacing,
+			want:    0x1809b5fc,
+		},
+		{
+			name:    "clamped fast",
+			spacing: 1,
+			want:    0x18026d7f,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window := newWindow(504000, 0x1804dafe, 1510000000, tt.spacing)
+
+			if got := nextBits(rules, window); got != tt.want {
+				t.Fatalf("got %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockService_checkHeader(t *testing.T) {
+	ctx := context.Background()
+
+	rules := HeaderRules{
+		PowLimit: powLimit(255),
+		ASERT: &ASERTAnchor{
+			Height:        1200,
+			Bits:          0x2000ffff,
+			PrevTimestamp: 1600000000,
+			HalfLife:      2 * 24 * 60 * 60,
+		},
+	}
+
+	tests := []struct {
+		name   string
+		height int32
+		time   int64
+		bits   func([]Block) uint32
+		want   error
+	}{
+		{
+			name:   "2017 algorithm before the anchor",
+			height: 1000,
+			time:   1600000000,
+			bits: func(window []Block) uint32 {
+				return nextBits(rules, window)
+			},
+		},
+		{
+			name:   "ASERT bits before the anchor",
+			height: 1000,
+			time:   1600000000,
+			bits: func(window []Block) uint32 {
+				return nextASERTBits(rules, window[len(window)-1])
+			},
+			want: ErrDifficulty,
+		},
+		{
+			name:   "ASERT after the anchor",
+			height: 1300,
+			time:   1600000000,
+			bits: func(window []Block) uint32 {
+				return nextASERTBits(rules, window[len(window)-1])
+			},
+		},
+		{
+			name:   "2017 algorithm after the anchor",
+			height: 1300,
+			time:   1600000000,
+			bits: func(window []Block) uint32 {
+				return nextBits(rules, window)
+			},
+			want: ErrDifficulty,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// blocks an hour apart, so the algorithms disagree
+			window := newWindow(tt.height-daaWindow-2, 0x2000ffff, tt.time,
+				6*targetSpacing)
+
+			b := BlockService{
+				Blocks: map[chainhash.Hash]Block{},
+				rules:  rules,
+			}
+
+			for _, block := range window {
+				h, _ := chainhash.NewHashFromStr(block.Hash)
+				b.Blocks[*h] = block
+			}
+
+			prev := window[len(window)-1]
+			header := mineHeader(t, prev, tt.bits(window))
+
+			if err := b.checkHeader(ctx, header, prev); err != tt.want {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// newWindow returns a retarget window of blocks with the bits, starting at
+// the height and time, the spacing apart.
+func newWindow(height int32, bits uint32, start, spacing int64) []Block {
+	window := make([]Block, daaWindow+3)
+
+	prev := chainhash.Hash{}
+	for i := range window {
+		hash := chainhash.DoubleHashH(prev[:])
+
+		window[i] = Block{
+			Hash:      hash.String(),
+			PrevBlock: prev.String(),
+			Height:    height + int32(i),
+			Bits:      bits,
+			Timestamp: start + int64(i)*spacing,
+		}
+
+		prev = hash
+	}
+
+	return window
+}
+
+// mineHeader returns a header after prev with the bits, and a nonce that
+// meets the target.
+func mineHeader(t *testing.T, prev Block, bits uint32) *wire.BlockHeader {
+	prevHash, _ := chainhash.NewHashFromStr(prev.Hash)
+
+	header := &wire.BlockHeader{
+		PrevBlock: *prevHash,
+		Timestamp: time.Unix(prev.Timestamp+targetSpacing, 0),
+		Bits:      bits,
+	}
+
+	target := compactToBig(bits)
+	for ; header.Nonce < 1<<20; header.Nonce++ {
+		hash := header.BlockHash()
+		if hashToBig(&hash).Cmp(target) <= 0 {
+			return header
+		}
+	}
+
+	t.Fatalf("no nonce meets the target %x", bits)
+
+	return nil
+}
@@ -2,8 +2,8 @@ package spvnode
 
 import (
 	"context"
-	"errors"
 
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/spvnode/logger"
 	"github.com/tokenized/smart-contract/pkg/wire"
 
@@ -14,15 +14,21 @@ import (
 type HeadersHandler struct {
 	Config       Config
 	BlockService *BlockService
+
+	// Listener is passed each new tip of the best chain in HeadersOnly
+	// mode.
+	Listener Listener
 }
 
 // NewHeadersHandler returns a new HeadersHandler with the given Config.
 func NewHeadersHandler(config Config,
-	blockService *BlockService) HeadersHandler {
+	blockService *BlockService,
+	listener Listener) HeadersHandler {
 
 	return HeadersHandler{
 		Config:       config,
 		BlockService: blockService,
+		Listener:     listener,
 	}
 }
 
@@ -35,7 +41,7 @@ func (h HeadersHandler) Handle(ctx context.Context,
 
 	in, ok := m.(*wire.MsgHeaders)
 	if !ok {
-		return nil, errors.New("Could not assert as *wire.Msginv")
+		return nil, errs.New(errs.Invalid, "Could not assert as *wire.Msginv")
 	}
 
 	return h.handle(ctx, in)
@@ -75,20 +81,36 @@ func (h HeadersHandler) handle(ctx context.Context,
 			continue
 		}
 
+		if err := h.BlockService.checkHeader(ctx, header, *previous); err != nil {
+			return nil, err
+		}
+
 		b := Block{
 			Hash:      hash.String(),
 			PrevBlock: header.PrevBlock.String(),
 			Height:    previous.Height + 1,
+			Bits:      header.Bits,
+			Timestamp: header.Timestamp.Unix(),
+
+			MerkleRoot: header.MerkleRoot.String(),
 		}
 
 		if getdata := h.buildGetDataForBlock(ctx, hash); getdata != nil {
 			outs = append(outs, getdata)
 		}
 
+		notify := h.isNewTip(b)
+
 		if err := h.BlockService.Write(ctx, b); err != nil {
 			return nil, err
 		}
 
+		if notify {
+			if err := h.notifyTip(ctx, header, b); err != nil {
+				return nil, err
+			}
+		}
+
 		max = b
 	}
 
@@ -125,10 +147,53 @@ func (h HeadersHandler) handle(ctx context.Context,
 	return outs, nil
 }
 
+// isNewTip returns true if the block is a new tip of the best chain that
+// is passed to the Listener, in HeadersOnly mode once synced.
+func (h HeadersHandler) isNewTip(b Block) bool {
+	if !h.Config.HeadersOnly || h.Listener == nil || !h.BlockService.synced {
+		return false
+	}
+
+	state := h.BlockService.State
+
+	return state != nil && b.Height > state.LastSeen.Height
+}
+
+// notifyTip passes the new tip to the Listener, as a block with only the
+// header, after the Reorg it causes, if any.
+func (h HeadersHandler) notifyTip(ctx context.Context,
+	header *wire.BlockHeader,
+	b Block) error {
+
+	reorg, err := h.BlockService.findReorg(ctx, b)
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.BlockService.LastSeen(ctx, b); err != nil {
+		return err
+	}
+
+	if reorg != nil {
+		log := logger.NewLoggerFromContext(ctx).Sugar()
+		log.Warnf("Reorg from height %v : %v blocks disconnected, %v connected",
+			reorg.AncestorHeight, len(reorg.Disconnected), len(reorg.Connected))
+
+		if err := h.Listener.HandleReorg(ctx, *reorg); err != nil {
+			log.Errorf("Failed to handle reorg : %v", err)
+		}
+	}
+
+	h.Listener.Handle(ctx, wire.NewMsgBlock(header))
+
+	return nil
+}
+
 func (h HeadersHandler) buildGetDataForBlock(ctx context.Context,
 	blockHash chainhash.Hash) *wire.MsgGetData {
 
-	if !h.BlockService.synced || h.BlockService.HasBlock(ctx, blockHash) {
+	if h.Config.HeadersOnly || !h.BlockService.synced ||
+		h.BlockService.HasBlock(ctx, blockHash) {
 		return nil
 	}
 
@@ -2,21 +2,30 @@ package spvnode
 
 import (
 	"context"
-	"errors"
 	"fmt"
 
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 )
 
 // InvHandler exists to handle the Ping command.
 type InvHandler struct {
-	Config Config
+	Config       Config
+	BlockService *BlockService
+	TxRequests   TxRequests
 }
 
 // NewInvHandler returns a new InvHandler with the given Config.
-func NewInvHandler(config Config) InvHandler {
+func NewInvHandler(config Config,
+	blockService *BlockService,
+	txRequests TxRequests) InvHandler {
+
 	return InvHandler{
-		Config: config,
+		Config:       config,
+		BlockService: blockService,
+		TxRequests:   txRequests,
 	}
 }
 
@@ -29,7 +38,7 @@ func (h InvHandler) Handle(ctx context.Context,
 
 	in, ok := m.(*wire.MsgInv)
 	if !ok {
-		return nil, errors.New("Could not assert as *wire.Msginv")
+		return nil, errs.New(errs.Invalid, "Could not assert as *wire.Msginv")
 	}
 
 	return h.handle(ctx, in)
@@ -38,28 +47,72 @@ func (h InvHandler) Handle(ctx context.Context,
 // handle processes the MsgInv.
 //
 // There are no responses for this, but new messages to send may be queued.
+//
+// The announced blocks are requested in a single getdata message, and the
+// TX's through the TxRequests, so only as many are in flight as allowed.
 func (h InvHandler) handle(ctx context.Context,
 	m *wire.MsgInv) ([]wire.Message, error) {
 
+	if h.Config.HeadersOnly {
+		return h.requestHeaders(m), nil
+	}
+
 	messages := []wire.Message{}
+	blocks := wire.NewMsgGetData()
+	txs := []chainhash.Hash{}
 
 	for _, v := range m.InvList {
 		switch v.Type {
 		case wire.InvTypeTx:
-			out := wire.NewMsgGetData()
-			out.AddInvVect(v)
-			messages = append(messages, out)
+			txs = append(txs, v.Hash)
 
 		case wire.InvTypeBlock:
-			out := wire.NewMsgGetData()
-			out.AddInvVect(v)
-
-			messages = append(messages, out)
+			if h.Config.CompactBlocks {
+				// the peer reconstructs new blocks from the mempool
+				blocks.AddInvVect(wire.NewInvVect(wire.InvTypeCmpctBlock, &v.Hash))
+			} else {
+				blocks.AddInvVect(v)
+			}
 
 		default:
 			fmt.Printf("unhandled inv vector type = %v\n", v.Type)
 		}
 	}
 
+	if len(blocks.InvList) > 0 {
+		messages = append(messages, blocks)
+	}
+
+	if len(txs) > 0 {
+		messages = append(messages, h.TxRequests.Announced(txs)...)
+	}
+
 	return messages, nil
 }
+
+// requestHeaders returns a request for the headers after the last seen
+// block, if the MsgInv announces any blocks. Announced TX's are ignored.
+func (h InvHandler) requestHeaders(m *wire.MsgInv) []wire.Message {
+	for _, v := range m.InvList {
+		if v.Type != wire.InvTypeBlock {
+			continue
+		}
+
+		state := h.BlockService.State
+		if state == nil || state.LastSeen.Hash == "" {
+			return nil
+		}
+
+		last, err := chainhash.NewHashFromStr(state.LastSeen.Hash)
+		if err != nil {
+			return nil
+		}
+
+		out := wire.NewMsgGetHeaders()
+		out.BlockLocatorHashes = []*chainhash.Hash{last}
+
+		return []wire.Message{out}
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package spvnode
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+// JournalKey is the storage key the Journal is written to.
+const JournalKey = "journal.json"
+
+var ErrJournalNotFound = errs.New(errs.NotFound, "Journal not found")
+
+// JournalEntry is the writes of a block commit, made once the body of the
+// block is stored.
+type JournalEntry struct {
+	// Blocks are the headers written.
+	Blocks []Block `json:"blocks"`
+
+	// State is written after the headers, if it is not nil.
+	State *State `json:"state,omitempty"`
+}
+
+// Journal is a write-ahead log of the block commit in progress. The writes
+// of a commit are journaled before they are made, and the journal removed
+// once they all are, so a commit interrupted by a crash is completed when
+// the Node restarts.
+type Journal struct {
+	Storage storage.Storage
+}
+
+// NewJournal returns a new Journal.
+func NewJournal(store storage.Storage) Journal {
+	return Journal{
+		Storage: store,
+	}
+}
+
+// Write journals the writes of a commit, before they are made.
+func (j Journal) Write(ctx context.Context, e JournalEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return j.Storage.Write(ctx, JournalKey, b, nil)
+}
+
+// Read returns the writes of the commit in progress, or ErrJournalNotFound
+// if there is none.
+func (j Journal) Read(ctx context.Context) (*JournalEntry, error) {
+	b, err := j.Storage.Read(ctx, JournalKey)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			err = ErrJournalNotFound
+		}
+
+		return nil, err
+	}
+
+	e := JournalEntry{}
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// Remove removes the journal, once the writes of the commit are made.
+func (j Journal) Remove(ctx context.Context) error {
+	return j.Storage.Remove(ctx, JournalKey)
+}
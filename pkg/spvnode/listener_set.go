@@ -0,0 +1,150 @@
+package spvnode
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"go.uber.org/multierr"
+)
+
+var ErrUnknownListener = errs.New(errs.NotFound, "Unknown listener")
+
+// ListenerID identifies a Listener added to a ListenerSet, so it can be
+// removed.
+type ListenerID uint64
+
+// ListenerSet is a Listener that passes everything on to each of its
+// Listeners, in the order they were added.
+//
+// Listeners may be added and removed while the Node is running. A Listener
+// removed while a message is being passed on may still receive that
+// message. It is safe for concurrent use.
+type ListenerSet struct {
+	mu        *sync.RWMutex
+	next      *ListenerID
+	listeners map[ListenerID]Listener
+}
+
+// NewListenerSet returns a new, empty, ListenerSet.
+func NewListenerSet() ListenerSet {
+	next := ListenerID(0)
+
+	return ListenerSet{
+		mu:        &sync.RWMutex{},
+		next:      &next,
+		listeners: map[ListenerID]Listener{},
+	}
+}
+
+// Add adds the Listener, and returns the ID to remove it with.
+func (s ListenerSet) Add(l Listener) ListenerID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	*s.next++
+	s.listeners[*s.next] = l
+
+	return *s.next
+}
+
+// Remove removes the Listener with the ID, and returns false if there was
+// none.
+func (s ListenerSet) Remove(id ListenerID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.listeners[id]
+	delete(s.listeners, id)
+
+	return ok
+}
+
+// Len returns the number of Listeners.
+func (s ListenerSet) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.listeners)
+}
+
+// Handle implements the Listener interface.
+func (s ListenerSet) Handle(ctx context.Context, m wire.Message) error {
+	errors := []error{}
+
+	for _, l := range s.snapshot() {
+		if err := l.Handle(ctx, m); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	return multierr.Combine(errors...)
+}
+
+// HandleReorg implements the Listener interface.
+func (s ListenerSet) HandleReorg(ctx context.Context, r Reorg) error {
+	errors := []error{}
+
+	for _, l := range s.snapshot() {
+		if err := l.HandleReorg(ctx, r); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	return multierr.Combine(errors...)
+}
+
+// HandleExpired implements the Listener interface.
+func (s ListenerSet) HandleExpired(ctx context.Context,
+	hashes []chainhash.Hash) error {
+
+	errors := []error{}
+
+	for _, l := range s.snapshot() {
+		if err := l.HandleExpired(ctx, hashes); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	return multierr.Combine(errors...)
+}
+
+// HandleDoubleSpend implements the Listener interface.
+func (s ListenerSet) HandleDoubleSpend(ctx context.Context, d DoubleSpend) error {
+	errors := []error{}
+
+	for _, l := range s.snapshot() {
+		if err := l.HandleDoubleSpend(ctx, d); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	return multierr.Combine(errors...)
+}
+
+// snapshot returns the Listeners in the order they were added. They are
+// called without the lock held, so a Listener may add or remove Listeners.
+func (s ListenerSet) snapshot() []Listener {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]ListenerID, 0, len(s.listeners))
+	for id := range s.listeners {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	listeners := make([]Listener, 0, len(ids))
+	for _, id := range ids {
+		listeners = append(listeners, s.listeners[id])
+	}
+
+	return listeners
+}
@@ -0,0 +1,503 @@
+package spvnode
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/tokenized/smart-contract/pkg/protocol"
+	"github.com/tokenized/smart-contract/pkg/txscript"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+)
+
+// Matcher is a TxFilter compiled from a rule, so the TX's that are
+// relevant can be set in config.
+//
+// A rule is made of terms, each matching the TX's with an output or input
+// of a kind.
+//
+//	p2pkh(address, ...)   an output paying to any of the P2PKH addresses
+//	p2sh(address, ...)    an output paying to any of the P2SH addresses
+//	spends(address, ...)  an input spending from any of the addresses
+//	op_return(code, ...)  a Tokenized OP_RETURN with any of the action
+//	                      codes, or any action code if none are given
+//
+// Terms are combined with "not", "and" and "or", in that order of
+// precedence, and grouped with parentheses. For example
+//
+//	p2pkh(1CmQLd5vRdcvqXFaCeeLTcXZVHXzSzgscv) and op_return(T1, T2)
+type Matcher struct {
+	Rule string
+	root matchNode
+}
+
+// CompileMatcher returns the Matcher for a rule.
+func CompileMatcher(rule string) (Matcher, error) {
+	p := matchParser{
+		tokens: tokenizeRule(rule),
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return Matcher{}, fmt.Errorf("Invalid rule %q : %v", rule, err)
+	}
+
+	if p.pos < len(p.tokens) {
+		return Matcher{}, fmt.Errorf("Invalid rule %q : unexpected %q",
+			rule, p.tokens[p.pos])
+	}
+
+	m := Matcher{
+		Rule: rule,
+		root: root,
+	}
+
+	return m, nil
+}
+
+// IsRelevant implements the TxFilter interface.
+func (m Matcher) IsRelevant(tx *wire.MsgTx) bool {
+	return m.root.match(tx)
+}
+
+// Elements implements the TxFilter interface.
+//
+// A rule that matches TX's by their action code alone, or with "not",
+// can't be matched by a bloom filter, and has no elements.
+func (m Matcher) Elements() [][]byte {
+	return m.root.elements()
+}
+
+// String returns the rule of the Matcher.
+func (m Matcher) String() string {
+	return m.Rule
+}
+
+// matchNode is a compiled part of a rule.
+type matchNode interface {
+	match(tx *wire.MsgTx) bool
+
+	// elements returns nil if the node can't be matched by a bloom filter.
+	elements() [][]byte
+}
+
+// scriptNode matches TX's with an output script in a set. The scripts are
+// built when the rule is compiled, so each output is a single lookup.
+type scriptNode struct {
+	scripts map[string]bool
+	hashes  [][]byte
+}
+
+func (n scriptNode) match(tx *wire.MsgTx) bool {
+	for _, txOut := range tx.TxOut {
+		if n.scripts[string(txOut.PkScript)] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (n scriptNode) elements() [][]byte {
+	return n.hashes
+}
+
+// spendsNode matches TX's with an input pushing a key that hashes to one in
+// a set.
+type spendsNode struct {
+	keyHashes map[string]bool
+	hashes    [][]byte
+}
+
+func (n spendsNode) match(tx *wire.MsgTx) bool {
+	for _, txIn := range tx.TxIn {
+		pushes, err := txscript.PushedData(txIn.SignatureScript)
+		if err != nil {
+			continue
+		}
+
+		for _, data := range pushes {
+			if n.keyHashes[string(btcutil.Hash160(data))] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (n spendsNode) elements() [][]byte {
+	return n.hashes
+}
+
+// opReturnNode matches TX's with a Tokenized OP_RETURN output with an
+// action code in a set, or any action code if the set is empty.
+type opReturnNode struct {
+	codes map[string]bool
+}
+
+func (n opReturnNode) match(tx *wire.MsgTx) bool {
+	for _, txOut := range tx.TxOut {
+		if !isTokenizedOpReturn(txOut.PkScript) {
+			continue
+		}
+
+		code, err := protocol.Code(txOut.PkScript)
+		if err != nil {
+			continue
+		}
+
+		if len(n.codes) == 0 || n.codes[code] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (n opReturnNode) elements() [][]byte {
+	return nil
+}
+
+// andNode matches TX's matched by both nodes.
+type andNode struct {
+	left  matchNode
+	right matchNode
+}
+
+func (n andNode) match(tx *wire.MsgTx) bool {
+	return n.left.match(tx) && n.right.match(tx)
+}
+
+// elements of either node are enough, as every TX matched by both is
+// matched by each.
+func (n andNode) elements() [][]byte {
+	if e := n.left.elements(); e != nil {
+		return e
+	}
+
+	return n.right.elements()
+}
+
+// orNode matches TX's matched by either node.
+type orNode struct {
+	left  matchNode
+	right matchNode
+}
+
+func (n orNode) match(tx *wire.MsgTx) bool {
+	return n.left.match(tx) || n.right.match(tx)
+}
+
+func (n orNode) elements() [][]byte {
+	left, right := n.left.elements(), n.right.elements()
+	if left == nil || right == nil {
+		return nil
+	}
+
+	return append(append([][]byte{}, left...), right...)
+}
+
+// notNode matches TX's not matched by the node.
+type notNode struct {
+	node matchNode
+}
+
+func (n notNode) match(tx *wire.MsgTx) bool {
+	return !n.node.match(tx)
+}
+
+func (n notNode) elements() [][]byte {
+	return nil
+}
+
+// tokenizedVersion is the protocol version of a Tokenized OP_RETURN.
+var tokenizedVersion = []byte{0x0, 0x0, 0x0, 0x20}
+
+// isTokenizedOpReturn returns true if the script is a Tokenized OP_RETURN.
+func isTokenizedOpReturn(script []byte) bool {
+	if len(script) < 20 || script[0] != txscript.OP_RETURN {
+		return false
+	}
+
+	offset := 3
+	if script[1] < txscript.OP_PUSHDATA1 {
+		offset = 2
+	}
+
+	return bytes.Equal(script[offset:offset+4], tokenizedVersion)
+}
+
+// tokenizeRule splits a rule into words, parentheses and commas.
+func tokenizeRule(rule string) []string {
+	tokens := []string{}
+	word := ""
+
+	for _, r := range rule {
+		switch {
+		case r == '(' || r == ')' || r == ',':
+			if word != "" {
+				tokens = append(tokens, word)
+				word = ""
+			}
+
+			tokens = append(tokens, string(r))
+
+		case unicode.IsSpace(r):
+			if word != "" {
+				tokens = append(tokens, word)
+				word = ""
+			}
+
+		default:
+			word += string(r)
+		}
+	}
+
+	if word != "" {
+		tokens = append(tokens, word)
+	}
+
+	return tokens
+}
+
+// matchParser parses the tokens of a rule into matchNodes, by recursive
+// descent.
+type matchParser struct {
+	tokens []string
+	pos    int
+}
+
+// peek returns the next token, or "" at the end of the rule.
+func (p *matchParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+
+	return p.tokens[p.pos]
+}
+
+// next consumes and returns the next token, or "" at the end of the rule.
+func (p *matchParser) next() string {
+	t := p.peek()
+	if t != "" {
+		p.pos++
+	}
+
+	return t
+}
+
+// expect consumes the next token, which must be t.
+func (p *matchParser) expect(t string) error {
+	if got := p.next(); got != t {
+		return fmt.Errorf("expected %q, got %q", t, got)
+	}
+
+	return nil
+}
+
+func (p *matchParser) parseOr() (matchNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for strings.ToLower(p.peek()) == "or" {
+		p.next()
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = orNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *matchParser) parseAnd() (matchNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for strings.ToLower(p.peek()) == "and" {
+		p.next()
+
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		left = andNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *matchParser) parseNot() (matchNode, error) {
+	if strings.ToLower(p.peek()) != "not" {
+		return p.parseTerm()
+	}
+
+	p.next()
+
+	node, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	return notNode{node: node}, nil
+}
+
+func (p *matchParser) parseTerm() (matchNode, error) {
+	name := strings.ToLower(p.next())
+
+	if name == "" {
+		return nil, fmt.Errorf("unexpected end of rule")
+	}
+
+	if name == "(" {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		return node, p.expect(")")
+	}
+
+	args, err := p.parseArgs()
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "p2pkh", "p2sh":
+		return newScriptNode(name, args)
+
+	case "spends":
+		return newSpendsNode(args)
+
+	case "op_return":
+		n := opReturnNode{
+			codes: map[string]bool{},
+		}
+
+		for _, code := range args {
+			n.codes[code] = true
+		}
+
+		return n, nil
+
+	default:
+		return nil, fmt.Errorf("unknown term %q", name)
+	}
+}
+
+// parseArgs parses a parenthesized, comma separated list of arguments.
+func (p *matchParser) parseArgs() ([]string, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	args := []string{}
+
+	if p.peek() == ")" {
+		p.next()
+		return args, nil
+	}
+
+	for {
+		arg := p.next()
+		if arg == "" || arg == "(" || arg == ")" || arg == "," {
+			return nil, fmt.Errorf("expected an argument, got %q", arg)
+		}
+
+		args = append(args, arg)
+
+		switch t := p.next(); t {
+		case ")":
+			return args, nil
+		case ",":
+		default:
+			return nil, fmt.Errorf("expected \",\" or \")\", got %q", t)
+		}
+	}
+}
+
+// newScriptNode returns a scriptNode for the P2PKH or P2SH scripts of the
+// addresses.
+func newScriptNode(kind string, args []string) (matchNode, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%v needs an address", kind)
+	}
+
+	n := scriptNode{
+		scripts: map[string]bool{},
+	}
+
+	for _, arg := range args {
+		a, err := btcutil.DecodeAddress(arg, &chaincfg.MainNetParams)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q : %v", arg, err)
+		}
+
+		var script []byte
+
+		switch a := a.(type) {
+		case *btcutil.AddressPubKeyHash:
+			if kind != "p2pkh" {
+				return nil, fmt.Errorf("%v is not a P2SH address", arg)
+			}
+
+			script = []byte{txscript.OP_DUP, txscript.OP_HASH160, txscript.OP_DATA_20}
+			script = append(script, a.ScriptAddress()...)
+			script = append(script, txscript.OP_EQUALVERIFY, txscript.OP_CHECKSIG)
+
+		case *btcutil.AddressScriptHash:
+			if kind != "p2sh" {
+				return nil, fmt.Errorf("%v is not a P2PKH address", arg)
+			}
+
+			script = []byte{txscript.OP_HASH160, txscript.OP_DATA_20}
+			script = append(script, a.ScriptAddress()...)
+			script = append(script, txscript.OP_EQUAL)
+
+		default:
+			return nil, fmt.Errorf("unsupported address %q", arg)
+		}
+
+		n.scripts[string(script)] = true
+		n.hashes = append(n.hashes, a.ScriptAddress())
+	}
+
+	return n, nil
+}
+
+// newSpendsNode returns a spendsNode for the addresses.
+func newSpendsNode(args []string) (matchNode, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("spends needs an address")
+	}
+
+	n := spendsNode{
+		keyHashes: map[string]bool{},
+	}
+
+	for _, arg := range args {
+		a, err := btcutil.DecodeAddress(arg, &chaincfg.MainNetParams)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q : %v", arg, err)
+		}
+
+		n.keyHashes[string(a.ScriptAddress())] = true
+		n.hashes = append(n.hashes, a.ScriptAddress())
+	}
+
+	return n, nil
+}
@@ -0,0 +1,641 @@
+package spvnode
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+const (
+	// maxMempoolTxs is the default number of unconfirmed TX's kept to
+	// reconstruct compact blocks from.
+	maxMempoolTxs = 100000
+
+	// EvictOldest evicts the TX's that were added first.
+	EvictOldest Eviction = "oldest"
+
+	// EvictLowestFeeRate evicts the TX's that pay the lowest fee per byte.
+	EvictLowestFeeRate Eviction = "feerate"
+)
+
+var (
+	// ErrUnknownEviction is returned for an Eviction policy that is not
+	// supported.
+	ErrUnknownEviction = errs.New(errs.Invalid, "Unknown mempool eviction policy")
+)
+
+// Eviction is the policy of which TX's are dropped from a full Mempool.
+type Eviction string
+
+// ParseEviction returns the Eviction policy of the name, which is
+// EvictOldest if the name is empty.
+func ParseEviction(name string) (Eviction, error) {
+	switch Eviction(name) {
+	case "":
+		return EvictOldest, nil
+	case EvictOldest, EvictLowestFeeRate:
+		return Eviction(name), nil
+	}
+
+	return "", ErrUnknownEviction
+}
+
+// InputValuer returns the value of an output spent by a TX, so the fee of
+// TX's that spend outputs that aren't in the Mempool can be known, such as
+// from an RPC node.
+type InputValuer interface {
+	OutputValue(context.Context, wire.OutPoint) (int64, error)
+}
+
+// MempoolEntry is a TX of the Mempool, with its size and fee.
+type MempoolEntry struct {
+	Hash chainhash.Hash
+	Size int
+
+	// Fee is in satoshis, and is -1 if unknown. FeeRate is in satoshis per
+	// byte.
+	Fee     int64
+	FeeRate float64
+}
+
+// MempoolStats are the size of a Mempool, and the TX's evicted to keep it
+// within its limits.
+type MempoolStats struct {
+	Txs          int
+	Bytes        int
+	Evicted      uint64
+	EvictedBytes uint64
+	Expired      uint64
+}
+
+// Mempool holds the unconfirmed TX's relayed by the peer, so compact blocks
+// can be reconstructed without downloading the TX's again.
+//
+// The number of TX's, and their total size, are limited. When either is
+// exceeded TX's are evicted by the Eviction policy.
+//
+// The fee of a TX is only known when the TX's it spends are in the Mempool
+// too, or the values of the outputs it spends are fetched by the Valuer.
+// With EvictLowestFeeRate, TX's whose fee is unknown are evicted before
+// those whose fee is known, oldest first.
+//
+// A Mempool is safe for concurrent use.
+type Mempool struct {
+	// Valuer fetches the values of spent outputs that aren't in the
+	// Mempool, if set. It must be set before the Node is started.
+	Valuer InputValuer
+
+	mu     *sync.Mutex
+	txs    map[chainhash.Hash]*poolEntry
+	order  *[]chainhash.Hash
+	evict  *mempoolHeap
+	seq    *uint64
+	stats  *MempoolStats
+	limits *mempoolLimits
+}
+
+// mempoolLimits are the most TX's of a Mempool, and their most total size,
+// which is not limited if it is 0.
+type mempoolLimits struct {
+	maxTxs   int
+	maxBytes int
+}
+
+// poolEntry is a TX in the Mempool.
+type poolEntry struct {
+	tx    *wire.MsgTx
+	size  int
+	added time.Time
+
+	// fee is in satoshis, and is -1 if unknown.
+	fee int64
+
+	// seq is the order the TX was added in, and index is its position in
+	// the eviction heap.
+	seq   uint64
+	index int
+}
+
+// NewMempool returns a new, empty Mempool with the limits and eviction
+// policy of the Config.
+func NewMempool(config Config) Mempool {
+	m := Mempool{
+		mu:    &sync.Mutex{},
+		txs:   map[chainhash.Hash]*poolEntry{},
+		order: &[]chainhash.Hash{},
+		evict: &mempoolHeap{
+			lowestFeeRate: config.MempoolEviction == EvictLowestFeeRate,
+		},
+		seq:    new(uint64),
+		stats:  &MempoolStats{},
+		limits: &mempoolLimits{},
+	}
+
+	m.setLimits(config.MempoolMaxTxs, config.MempoolMaxBytes)
+
+	return m
+}
+
+// SetLimits changes the most TX's of the Mempool, and their most total
+// size, evicting TX's if it is now over them. 100000 TX's are kept if
+// maxTxs is 0, and the size is not limited if maxBytes is 0.
+func (m Mempool) SetLimits(maxTxs, maxBytes int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.setLimits(maxTxs, maxBytes)
+	m.evictOver()
+}
+
+// setLimits sets the limits. The lock must be held, if the Mempool is in
+// use.
+func (m Mempool) setLimits(maxTxs, maxBytes int) {
+	if maxTxs <= 0 {
+		maxTxs = maxMempoolTxs
+	}
+
+	m.limits.maxTxs = maxTxs
+	m.limits.maxBytes = maxBytes
+}
+
+// Add adds a TX, evicting TX's if the Mempool is full.
+func (m Mempool) Add(tx *wire.MsgTx) {
+	m.add(tx, time.Now())
+}
+
+// add adds a TX that was first seen at the time.
+func (m Mempool) add(tx *wire.MsgTx, added time.Time) {
+	hash := tx.TxHash()
+
+	if m.has(hash) {
+		return
+	}
+
+	// the values of the inputs may be fetched, so without the lock
+	fee := m.fee(tx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.txs[hash]; ok {
+		return
+	}
+
+	*m.seq++
+
+	e := &poolEntry{
+		tx:    tx,
+		size:  tx.SerializeSize(),
+		added: added,
+		fee:   fee,
+		seq:   *m.seq,
+	}
+
+	m.txs[hash] = e
+	*m.order = append(*m.order, hash)
+	heap.Push(m.evict, e)
+	m.stats.Bytes += e.size
+
+	m.evictOver()
+}
+
+// evictOver evicts TX's until the Mempool is within its limits. The lock
+// must be held.
+func (m Mempool) evictOver() {
+	for len(m.txs) > m.limits.maxTxs ||
+		(m.limits.maxBytes > 0 && m.stats.Bytes > m.limits.maxBytes) {
+
+		evicted := heap.Pop(m.evict).(*poolEntry)
+
+		delete(m.txs, evicted.tx.TxHash())
+		m.stats.Bytes -= evicted.size
+		m.stats.Evicted++
+		m.stats.EvictedBytes += uint64(evicted.size)
+	}
+
+	// drop the hashes of any evicted, or confirmed since
+	if len(*m.order) > 2*m.limits.maxTxs {
+		m.compact()
+	}
+}
+
+// Confirmed removes the TX's of a block.
+func (m Mempool) Confirmed(b *wire.MsgBlock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, tx := range b.Transactions {
+		hash := tx.TxHash()
+
+		e, ok := m.txs[hash]
+		if !ok {
+			continue
+		}
+
+		heap.Remove(m.evict, e.index)
+		delete(m.txs, hash)
+		m.stats.Bytes -= e.size
+	}
+
+	if len(*m.order) > 2*len(m.txs)+m.limits.maxTxs/10 {
+		m.compact()
+	}
+}
+
+// Remove removes the TX, such as one replaced by another, and returns false
+// if it is not in the Mempool.
+func (m Mempool) Remove(hash chainhash.Hash) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.txs[hash]
+	if !ok {
+		return false
+	}
+
+	heap.Remove(m.evict, e.index)
+	delete(m.txs, hash)
+	m.stats.Bytes -= e.size
+
+	return true
+}
+
+// Replaces returns true if the TX replaces the earlier TX it conflicts
+// with, by replace-by-fee. The earlier TX must be in the Mempool, and
+// signal that it may be replaced, and the TX must pay a higher fee, at a
+// higher fee rate. Neither fee may be unknown.
+func (m Mempool) Replaces(tx *wire.MsgTx, earlier chainhash.Hash) bool {
+	m.mu.Lock()
+	e, ok := m.txs[earlier]
+	if ok {
+		ok = signalsReplacement(e.tx) && e.fee >= 0
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	// the values of the inputs may be fetched, so without the lock
+	fee := m.fee(tx)
+	if fee < 0 {
+		return false
+	}
+
+	// compare fee/size to e.fee/e.size without dividing
+	return fee > e.fee &&
+		fee*int64(e.size) > e.fee*int64(tx.SerializeSize())
+}
+
+// Expire removes the TX's added before the time, and returns their hashes,
+// oldest first.
+func (m Mempool) Expire(before time.Time) []chainhash.Hash {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expired := []chainhash.Hash{}
+
+	// the order is the order TX's were added, so the expired are first
+	for _, hash := range *m.order {
+		e, ok := m.txs[hash]
+		if !ok {
+			continue
+		}
+
+		if !e.added.Before(before) {
+			break
+		}
+
+		heap.Remove(m.evict, e.index)
+		delete(m.txs, hash)
+		m.stats.Bytes -= e.size
+		m.stats.Expired++
+
+		expired = append(expired, hash)
+	}
+
+	if len(expired) > 0 {
+		m.compact()
+	}
+
+	return expired
+}
+
+// Retain removes the TX's that aren't relevant to the filters, such as
+// after they are replaced, and returns their hashes.
+func (m Mempool) Retain(filters []TxFilter) []chainhash.Hash {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := []chainhash.Hash{}
+
+	for hash, e := range m.txs {
+		if isRelevant(filters, e.tx) {
+			continue
+		}
+
+		heap.Remove(m.evict, e.index)
+		delete(m.txs, hash)
+		m.stats.Bytes -= e.size
+
+		removed = append(removed, hash)
+	}
+
+	if len(removed) > 0 {
+		m.compact()
+	}
+
+	return removed
+}
+
+// Export returns the TX's in the Mempool that are relevant to the filters,
+// in the order they were added, to be saved.
+func (m Mempool) Export(filters []TxFilter) ([]MempoolTx, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	txs := []MempoolTx{}
+
+	for _, hash := range *m.order {
+		e, ok := m.txs[hash]
+		if !ok || !isRelevant(filters, e.tx) {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := e.tx.Serialize(&buf); err != nil {
+			return nil, err
+		}
+
+		txs = append(txs, MempoolTx{
+			Tx:    buf.Bytes(),
+			Added: e.added.UnixNano(),
+		})
+	}
+
+	return txs, nil
+}
+
+// Import adds saved TX's, keeping when they were first added so they
+// expire as if the Mempool had not been saved.
+func (m Mempool) Import(txs []MempoolTx) error {
+	for _, saved := range txs {
+		tx := wire.MsgTx{}
+		if err := tx.Deserialize(bytes.NewReader(saved.Tx)); err != nil {
+			return err
+		}
+
+		m.add(&tx, time.Unix(0, saved.Added))
+	}
+
+	return nil
+}
+
+// All returns the TX's in the Mempool.
+func (m Mempool) All() []*wire.MsgTx {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	txs := make([]*wire.MsgTx, 0, len(m.txs))
+	for _, hash := range *m.order {
+		if e, ok := m.txs[hash]; ok {
+			txs = append(txs, e.tx)
+		}
+	}
+
+	return txs
+}
+
+// Len returns the number of TX's in the Mempool.
+func (m Mempool) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.txs)
+}
+
+// Stats returns the MempoolStats.
+func (m Mempool) Stats() MempoolStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := *m.stats
+	stats.Txs = len(m.txs)
+
+	return stats
+}
+
+// Get returns the TX, or nil if it is not in the Mempool.
+func (m Mempool) Get(hash chainhash.Hash) *wire.MsgTx {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.txs[hash]
+	if !ok {
+		return nil
+	}
+
+	return e.tx
+}
+
+// has returns true if the TX is in the Mempool.
+func (m Mempool) has(hash chainhash.Hash) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.txs[hash]
+
+	return ok
+}
+
+// MedianFeeRate returns the median fee rate of the TX's in the Mempool
+// whose fee is known, in satoshis per byte. It returns 0 if none are
+// known.
+func (m Mempool) MedianFeeRate() float64 {
+	entries := m.EntriesAboveRate(0)
+	if len(entries) == 0 {
+		return 0
+	}
+
+	// the entries are highest first
+	mid := len(entries) / 2
+	if len(entries)%2 == 1 {
+		return entries[mid].FeeRate
+	}
+
+	return (entries[mid-1].FeeRate + entries[mid].FeeRate) / 2
+}
+
+// EntriesAboveRate returns the TX's whose fee is known, and whose fee rate
+// is at least the rate in satoshis per byte, highest fee rate first.
+func (m Mempool) EntriesAboveRate(rate float64) []MempoolEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := []MempoolEntry{}
+
+	for hash, e := range m.txs {
+		if e.fee < 0 || e.feeRate() < rate {
+			continue
+		}
+
+		entries = append(entries, MempoolEntry{
+			Hash:    hash,
+			Size:    e.size,
+			Fee:     e.fee,
+			FeeRate: e.feeRate(),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].FeeRate > entries[j].FeeRate
+	})
+
+	return entries
+}
+
+// fee returns the fee of the TX, or -1 if the value of any of the outputs
+// it spends is unknown.
+//
+// The values of outputs of TX's in the Mempool are known, and the others
+// are fetched by the Valuer, if there is one.
+func (m Mempool) fee(tx *wire.MsgTx) int64 {
+	fee := int64(0)
+
+	for _, in := range tx.TxIn {
+		value, ok := m.outputValue(in.PreviousOutPoint)
+		if !ok {
+			if m.Valuer == nil {
+				return -1
+			}
+
+			v, err := m.Valuer.OutputValue(context.Background(),
+				in.PreviousOutPoint)
+			if err != nil {
+				return -1
+			}
+
+			value = v
+		}
+
+		fee += value
+	}
+
+	for _, out := range tx.TxOut {
+		fee -= out.Value
+	}
+
+	if fee < 0 {
+		return -1
+	}
+
+	return fee
+}
+
+// outputValue returns the value of the output, if it is of a TX in the
+// Mempool.
+func (m Mempool) outputValue(out wire.OutPoint) (int64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	parent, ok := m.txs[out.Hash]
+	if !ok || int(out.Index) >= len(parent.tx.TxOut) {
+		return 0, false
+	}
+
+	return parent.tx.TxOut[out.Index].Value, true
+}
+
+// signalsReplacement returns true if the TX signals that it may be replaced
+// by a TX paying a higher fee, by an input sequence below
+// MaxTxInSequenceNum-1, as in BIP125.
+func signalsReplacement(tx *wire.MsgTx) bool {
+	for _, in := range tx.TxIn {
+		if in.Sequence < wire.MaxTxInSequenceNum-1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// feeRate returns the fee of the entry in satoshis per byte, if it is
+// known.
+func (e poolEntry) feeRate() float64 {
+	if e.size == 0 {
+		return 0
+	}
+
+	return float64(e.fee) / float64(e.size)
+}
+
+// compact drops the hashes of removed TX's from the order. The caller must
+// hold the lock.
+func (m Mempool) compact() {
+	order := make([]chainhash.Hash, 0, len(m.txs))
+	for _, hash := range *m.order {
+		if _, ok := m.txs[hash]; ok {
+			order = append(order, hash)
+		}
+	}
+
+	*m.order = order
+}
+
+// mempoolHeap orders the entries of a Mempool by which is evicted first, as
+// a container/heap.
+type mempoolHeap struct {
+	entries       []*poolEntry
+	lowestFeeRate bool
+}
+
+func (h mempoolHeap) Len() int {
+	return len(h.entries)
+}
+
+func (h mempoolHeap) Less(i, j int) bool {
+	a, b := h.entries[i], h.entries[j]
+
+	if h.lowestFeeRate && (a.fee >= 0 || b.fee >= 0) {
+		if a.fee < 0 || b.fee < 0 {
+			// an unknown fee is evicted first
+			return a.fee < 0
+		}
+
+		// compare a.fee/a.size to b.fee/b.size without dividing
+		ra, rb := a.fee*int64(b.size), b.fee*int64(a.size)
+		if ra != rb {
+			return ra < rb
+		}
+	}
+
+	return a.seq < b.seq
+}
+
+func (h mempoolHeap) Swap(i, j int) {
+	h.entries[i], h.entries[j] = h.entries[j], h.entries[i]
+	h.entries[i].index = i
+	h.entries[j].index = j
+}
+
+func (h *mempoolHeap) Push(x interface{}) {
+	e := x.(*poolEntry)
+	e.index = len(h.entries)
+	h.entries = append(h.entries, e)
+}
+
+func (h *mempoolHeap) Pop() interface{} {
+	last := len(h.entries) - 1
+	e := h.entries[last]
+	h.entries[last] = nil
+	h.entries = h.entries[:last]
+
+	return e
+}
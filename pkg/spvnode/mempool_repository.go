@@ -0,0 +1,65 @@
+package spvnode
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+// MempoolKey is the storage key the Mempool is written to.
+const MempoolKey = "mempool.json"
+
+var ErrMempoolNotFound = errs.New(errs.NotFound, "Mempool not found")
+
+// MempoolTx is a TX of the Mempool, as it is written to storage.
+type MempoolTx struct {
+	// Tx is the serialized TX.
+	Tx []byte `json:"tx"`
+
+	// Added is when the TX was added to the Mempool, in nanoseconds since
+	// the epoch.
+	Added int64 `json:"added"`
+}
+
+// MempoolRepository is used for managing the saved Mempool.
+type MempoolRepository struct {
+	Storage storage.Storage
+}
+
+// NewMempoolRepository returns a new MempoolRepository.
+func NewMempoolRepository(store storage.Storage) MempoolRepository {
+	return MempoolRepository{
+		Storage: store,
+	}
+}
+
+// Write replaces the saved Mempool with the TX's.
+func (r MempoolRepository) Write(ctx context.Context, txs []MempoolTx) error {
+	b, err := json.Marshal(txs)
+	if err != nil {
+		return err
+	}
+
+	return r.Storage.Write(ctx, MempoolKey, b, nil)
+}
+
+// Read returns the TX's of the saved Mempool, in the order they were added.
+func (r MempoolRepository) Read(ctx context.Context) ([]MempoolTx, error) {
+	b, err := r.Storage.Read(ctx, MempoolKey)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			err = ErrMempoolNotFound
+		}
+
+		return nil, err
+	}
+
+	txs := []MempoolTx{}
+	if err := json.Unmarshal(b, &txs); err != nil {
+		return nil, err
+	}
+
+	return txs, nil
+}
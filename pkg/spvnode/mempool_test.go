@@ -0,0 +1,287 @@
+package spvnode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// fixedValuer values every spent output at 1000.
+type fixedValuer struct{}
+
+func (fixedValuer) OutputValue(context.Context, wire.OutPoint) (int64, error) {
+	return 1000, nil
+}
+
+// feeTx returns a TX spending the output at the index, worth 1000 to the
+// fixedValuer, paying the fee.
+func feeTx(index uint32, fee int64) *wire.MsgTx {
+	prev := chainhash.DoubleHashH([]byte("prev"))
+
+	tx := wire.NewMsgTx(1)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prev, index), nil))
+	tx.AddTxOut(wire.NewTxOut(1000-fee, []byte{0x51}))
+
+	return tx
+}
+
+func TestMempool_eviction(t *testing.T) {
+	size := feeTx(0, 0).SerializeSize()
+
+	tests := []struct {
+		name   string
+		config Config
+		fees   []int64
+		want   []int
+	}{
+		{
+			name:   "oldest by count",
+			config: Config{MempoolMaxTxs: 2},
+			fees:   []int64{10, 20, 30},
+			want:   []int{1, 2},
+		},
+		{
+			name:   "oldest by size",
+			config: Config{MempoolMaxBytes: 2 * size},
+			fees:   []int64{10, 20, 30},
+			want:   []int{1, 2},
+		},
+		{
+			name: "lowest fee rate",
+			config: Config{
+				MempoolMaxTxs:   2,
+				MempoolEviction: EvictLowestFeeRate,
+			},
+			fees: []int64{50, 10, 300, 20},
+			want: []int{0, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMempool(tt.config)
+			m.Valuer = fixedValuer{}
+
+			txs := []*wire.MsgTx{}
+			for i, fee := range tt.fees {
+				tx := feeTx(uint32(i), fee)
+				txs = append(txs, tx)
+				m.Add(tx)
+			}
+
+			if m.Len() != len(tt.want) {
+				t.Fatalf("got %v TX's, want %v", m.Len(), len(tt.want))
+			}
+
+			for _, i := range tt.want {
+				if m.Get(txs[i].TxHash()) == nil {
+					t.Fatalf("tx %v evicted", i)
+				}
+			}
+
+			stats := m.Stats()
+			evicted := len(tt.fees) - len(tt.want)
+			if stats.Evicted != uint64(evicted) ||
+				stats.EvictedBytes != uint64(evicted*size) ||
+				stats.Bytes != len(tt.want)*size {
+
+				t.Fatalf("got stats %+v", stats)
+			}
+		})
+	}
+}
+
+func TestMempool_Expire(t *testing.T) {
+	m := NewMempool(Config{})
+
+	now := time.Now()
+	txs := newTxs(4)
+
+	for i, tx := range txs {
+		// added a minute apart, the first the longest ago
+		m.add(tx, now.Add(time.Duration(i-4)*time.Minute))
+	}
+
+	expired := m.Expire(now.Add(-150 * time.Second))
+
+	if len(expired) != 2 || expired[0] != txs[0].TxHash() ||
+		expired[1] != txs[1].TxHash() {
+		t.Fatalf("got %v expired, want the first 2 in order", expired)
+	}
+
+	if m.Len() != 2 || m.Stats().Expired != 2 {
+		t.Fatalf("got %v TX's with %v expired, want 2 and 2", m.Len(),
+			m.Stats().Expired)
+	}
+
+	if got := m.Expire(now.Add(-150 * time.Second)); len(got) != 0 {
+		t.Fatalf("got %v expired again", len(got))
+	}
+}
+
+// TestMempool_persist tests that the relevant TX's of the Mempool are saved
+// and loaded in the order they were added, and expire as if they had not
+// been saved.
+func TestMempool_persist(t *testing.T) {
+	ctx := context.Background()
+
+	store := memoryStorage{}
+	config := Config{
+		PersistMempool: true,
+		TxFilters:      []TxFilter{NewValueFilter(1000, 1002)},
+	}
+
+	now := time.Now()
+
+	// the values of the outputs of the TX's are 1000 to 1004
+	txs := newTxs(5)
+
+	n := NewNode(config, store)
+	for i, tx := range txs {
+		n.Mempool.add(tx, now.Add(time.Duration(i-5)*time.Minute))
+	}
+
+	n.saveMempool(ctx)
+
+	restarted := NewNode(config, store)
+	if err := restarted.loadMempool(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	all := restarted.Mempool.All()
+	if len(all) != 3 {
+		t.Fatalf("got %v TX's, want the 3 relevant", len(all))
+	}
+
+	for i, tx := range all {
+		if tx.TxHash() != txs[i].TxHash() {
+			t.Fatalf("got %v at %v, want %v", tx.TxHash(), i, txs[i].TxHash())
+		}
+	}
+
+	expired := restarted.Mempool.Expire(now.Add(-210 * time.Second))
+	if len(expired) != 2 {
+		t.Fatalf("got %v expired, want 2", len(expired))
+	}
+
+	// nothing saved is not an error
+	empty := NewNode(config, memoryStorage{})
+	if err := empty.loadMempool(ctx); err != nil || empty.Mempool.Len() != 0 {
+		t.Fatalf("got %v TX's with error %v, want none", empty.Mempool.Len(), err)
+	}
+}
+
+// TestTXHandler_doubleSpends tests that TX's spending an output already
+// spent by a relevant TX are passed on as double spends, classified by
+// whether they replace it, and again when one of them is confirmed.
+func TestTXHandler_doubleSpends(t *testing.T) {
+	ctx := context.Background()
+
+	// the parent pays 1001, so the fees of the TX's spending it are known
+	parent := newTxs(2)[1]
+	parentHash := parent.TxHash()
+
+	spend := func(value int64, sequence uint32) *wire.MsgTx {
+		tx := wire.NewMsgTx(1)
+		txIn := wire.NewTxIn(wire.NewOutPoint(&parentHash, 0), nil)
+		txIn.Sequence = sequence
+		tx.AddTxIn(txIn)
+		tx.AddTxOut(wire.NewTxOut(value, []byte{0x51}))
+
+		return tx
+	}
+
+	tests := []struct {
+		name     string
+		first    *wire.MsgTx
+		second   *wire.MsgTx
+		wantKind DoubleSpendKind
+		kept     int
+	}{
+		{
+			name:     "replacement",
+			first:    spend(901, 0),
+			second:   spend(701, 0),
+			wantKind: DoubleSpendReplacement,
+			kept:     1,
+		},
+		{
+			name:     "not signaled",
+			first:    spend(901, wire.MaxTxInSequenceNum),
+			second:   spend(701, wire.MaxTxInSequenceNum),
+			wantKind: DoubleSpendConflict,
+		},
+		{
+			name:     "lower fee",
+			first:    spend(701, 0),
+			second:   spend(901, 0),
+			wantKind: DoubleSpendConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRecordingListener()
+			blockService := newTestBlockService(memoryStorage{})
+
+			h := NewTXHandler(Config{CompactBlocks: true}, &blockService,
+				NewMempool(Config{}), l, NewBroadcastTracker(), NewTxRequests(10),
+				NewOrphanPool(), NewDoubleSpends(), NewSeenTxs(), nil)
+
+			for _, tx := range []*wire.MsgTx{parent, tt.first, tt.second} {
+				if _, err := h.Handle(ctx, tx); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if len(*l.doubleSpends) != 1 {
+				t.Fatalf("got %v double spends, want 1", len(*l.doubleSpends))
+			}
+
+			d := (*l.doubleSpends)[0]
+			if d.Kind != tt.wantKind || len(d.Txs) != 2 ||
+				d.Txs[0] != tt.first.TxHash() || d.Txs[1] != tt.second.TxHash() {
+				t.Fatalf("got %v double spend of %v, want %v", d.Kind, d.Txs,
+					tt.wantKind)
+			}
+
+			// the TX kept in the Mempool
+			txs := []*wire.MsgTx{tt.first, tt.second}
+			for i, tx := range txs {
+				if (h.Mempool.Get(tx.TxHash()) != nil) != (i == tt.kept) {
+					t.Fatalf("got tx %v kept %v, want tx %v kept", i,
+						h.Mempool.Get(tx.TxHash()) != nil, tt.kept)
+				}
+			}
+
+			// the confirmation is passed on with the Kind
+			block, _ := newCompactBlock([]*wire.MsgTx{newTxs(1)[0], tt.second})
+			h.Connected(ctx, block)
+
+			if len(*l.doubleSpends) != 2 {
+				t.Fatalf("got %v double spends, want 2", len(*l.doubleSpends))
+			}
+
+			d = (*l.doubleSpends)[1]
+			if d.Kind != tt.wantKind || d.Confirmed == nil ||
+				*d.Confirmed != tt.second.TxHash() || *d.Block != block.BlockHash() {
+				t.Fatalf("got confirmed double spend %+v", d)
+			}
+
+			// only the output spent by the parent is still watched
+			if h.DoubleSpends.Len() != 1 {
+				t.Fatalf("got %v outputs watched, want 1", h.DoubleSpends.Len())
+			}
+
+			h.DoubleSpends.Expire(time.Now().Add(time.Minute))
+			if h.DoubleSpends.Len() != 0 {
+				t.Fatalf("got %v outputs watched after expiry, want none",
+					h.DoubleSpends.Len())
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package spvnode
+
+import (
+	"context"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+var (
+	ErrTxNotInBlock = errs.New(errs.NotFound, "TX is not in the block")
+	ErrMerkleProof  = errs.New(errs.Invalid, "Merkle proof does not match the block header")
+	ErrNoMerkleRoot = errs.New(errs.NotFound, "Merkle root of the block is not stored")
+)
+
+// MerkleProof is evidence that a TX is confirmed in a block. It is the
+// partial merkle tree of a BIP37 merkleblock that matches the TX, which
+// hashes to the merkle root of the block header.
+type MerkleProof struct {
+	TxHash    string `json:"tx_hash"`
+	BlockHash string `json:"block_hash"`
+
+	// Total is the number of TX's in the block. Hashes and Flags are the
+	// partial merkle tree, in the order of a merkleblock.
+	Total  uint32   `json:"total"`
+	Hashes []string `json:"hashes"`
+	Flags  []byte   `json:"flags"`
+}
+
+// NewMerkleProof returns the proof that the TX is in the block.
+func NewMerkleProof(b *wire.MsgBlock, txHash chainhash.Hash) (*MerkleProof, error) {
+	hashes := make([]chainhash.Hash, 0, len(b.Transactions))
+	matches := make([]bool, 0, len(b.Transactions))
+	found := false
+
+	for _, tx := range b.Transactions {
+		hash := tx.TxHash()
+		hashes = append(hashes, hash)
+		matches = append(matches, hash == txHash)
+
+		if hash == txHash {
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, ErrTxNotInBlock
+	}
+
+	t := partialMerkleBuilder{
+		txHashes: hashes,
+		matches:  matches,
+	}
+
+	height := uint(0)
+	for t.width(height) > 1 {
+		height++
+	}
+
+	t.build(height, 0)
+
+	p := MerkleProof{
+		TxHash:    txHash.String(),
+		BlockHash: b.BlockHash().String(),
+		Total:     uint32(len(hashes)),
+		Flags:     t.flags,
+	}
+
+	for _, hash := range t.hashes {
+		p.Hashes = append(p.Hashes, hash.String())
+	}
+
+	return &p, nil
+}
+
+// NewMerkleProofFromMerkleBlock returns the proof that the TX is in the
+// block of the merkleblock, which must have matched the TX.
+func NewMerkleProofFromMerkleBlock(m *wire.MsgMerkleBlock,
+	txHash chainhash.Hash) (*MerkleProof, error) {
+
+	p := MerkleProof{
+		TxHash:    txHash.String(),
+		BlockHash: m.Header.BlockHash().String(),
+		Total:     m.Transactions,
+		Flags:     m.Flags,
+	}
+
+	for _, hash := range m.Hashes {
+		p.Hashes = append(p.Hashes, hash.String())
+	}
+
+	root, err := p.Root()
+	if err != nil {
+		return nil, err
+	}
+
+	if root != m.Header.MerkleRoot {
+		return nil, ErrMerkleProof
+	}
+
+	return &p, nil
+}
+
+// Root returns the merkle root the proof hashes to. It returns an error if
+// the partial merkle tree is malformed, or doesn't match the TX.
+func (p MerkleProof) Root() (chainhash.Hash, error) {
+	txHash, err := chainhash.NewHashFromStr(p.TxHash)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+
+	hashes := make([]*chainhash.Hash, 0, len(p.Hashes))
+	for _, s := range p.Hashes {
+		hash, err := chainhash.NewHashFromStr(s)
+		if err != nil {
+			return chainhash.Hash{}, err
+		}
+
+		hashes = append(hashes, hash)
+	}
+
+	root, matched, err := partialMerkleRoot(p.Total, hashes, p.Flags)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+
+	for _, hash := range matched {
+		if hash == *txHash {
+			return root, nil
+		}
+	}
+
+	return chainhash.Hash{}, ErrTxNotInBlock
+}
+
+// VerifyMerkleProof checks the proof against the stored header of its
+// block, and returns the block.
+func (b BlockService) VerifyMerkleProof(ctx context.Context,
+	p MerkleProof) (*Block, error) {
+
+	hash, err := chainhash.NewHashFromStr(p.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := b.Read(ctx, *hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.MerkleRoot == "" {
+		return nil, ErrNoMerkleRoot
+	}
+
+	root, err := p.Root()
+	if err != nil {
+		return nil, err
+	}
+
+	if root.String() != block.MerkleRoot {
+		return nil, ErrMerkleProof
+	}
+
+	return block, nil
+}
+
+// partialMerkleBuilder holds the state of building a partial merkle tree,
+// as described by BIP37.
+type partialMerkleBuilder struct {
+	txHashes []chainhash.Hash
+	matches  []bool
+	hashes   []chainhash.Hash
+	flags    []byte
+	bits     int
+}
+
+// width returns the number of nodes at the height of the tree.
+func (t *partialMerkleBuilder) width(height uint) int {
+	return (len(t.txHashes) + (1 << height) - 1) >> height
+}
+
+// hash returns the hash of the node at the height and position.
+func (t *partialMerkleBuilder) hash(height uint, pos int) chainhash.Hash {
+	if height == 0 {
+		return t.txHashes[pos]
+	}
+
+	left := t.hash(height-1, pos*2)
+
+	right := left
+	if pos*2+1 < t.width(height-1) {
+		right = t.hash(height-1, pos*2+1)
+	}
+
+	return chainhash.DoubleHashH(append(left[:], right[:]...))
+}
+
+// build adds the node at the height and position to the tree, in depth
+// first order. Only the nodes above a matched TX are descended into.
+func (t *partialMerkleBuilder) build(height uint, pos int) {
+	parent := false
+	for i := pos << height; i < (pos+1)<<height && i < len(t.txHashes); i++ {
+		if t.matches[i] {
+			parent = true
+			break
+		}
+	}
+
+	if t.bits%8 == 0 {
+		t.flags = append(t.flags, 0)
+	}
+
+	if parent {
+		t.flags[t.bits/8] |= 1 << uint(t.bits%8)
+	}
+
+	t.bits++
+
+	if height == 0 || !parent {
+		t.hashes = append(t.hashes, t.hash(height, pos))
+		return
+	}
+
+	t.build(height-1, pos*2)
+
+	if pos*2+1 < t.width(height-1) {
+		t.build(height-1, pos*2+1)
+	}
+}
@@ -0,0 +1,403 @@
+package spvnode
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+// mustHash returns the hash of the string, in the byte reversed order that
+// hashes are displayed in.
+func mustHash(t *testing.T, s string) *chainhash.Hash {
+	hash, err := chainhash.NewHashFromStr(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return hash
+}
+
+// mainnetMerkleBlock returns the merkleblock of a mainnet block, matching
+// one of its TX's, as it is received from a peer.
+func mainnetMerkleBlock(t *testing.T,
+	header wire.BlockHeader,
+	total uint32,
+	hashes []string,
+	flags []byte) *wire.MsgMerkleBlock {
+
+	m := wire.NewMsgMerkleBlock(&header)
+	m.Transactions = total
+	m.Flags = flags
+
+	for _, s := range hashes {
+		if err := m.AddTxHash(mustHash(t, s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := m.BtcEncode(&buf, wire.ProtocolVersion); err != nil {
+		t.Fatal(err)
+	}
+
+	received := &wire.MsgMerkleBlock{}
+	if err := received.BtcDecode(&buf, wire.ProtocolVersion); err != nil {
+		t.Fatal(err)
+	}
+
+	return received
+}
+
+// block170 is the header of mainnet block 170, which holds the first TX
+// between two people.
+func block170(t *testing.T) wire.BlockHeader {
+	return wire.BlockHeader{
+		Version:    1,
+		PrevBlock:  *mustHash(t, "000000002a22cfee1f2c846adbd12b3e183d4f97683f85dad08a79780a84bd55"),
+		MerkleRoot: *mustHash(t, "7dac2c5666815c17a3b36427de37bb9d2e2c5ccec3f8633eb91a4205cb4c10ff"),
+		Timestamp:  time.Unix(1231731025, 0),
+		Bits:       0x1d00ffff,
+		Nonce:      1889418792,
+	}
+}
+
+// block100000 is the header of mainnet block 100000, which holds four TX's.
+func block100000(t *testing.T) wire.BlockHeader {
+	return wire.BlockHeader{
+		Version:    1,
+		PrevBlock:  *mustHash(t, "000000000002d01c1fccc21636b607dfd930d31d01c3a62104612a1719011250"),
+		MerkleRoot: *mustHash(t, "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766"),
+		Timestamp:  time.Unix(1293623863, 0),
+		Bits:       0x1b04864c,
+		Nonce:      274148111,
+	}
+}
+
+func TestNewMerkleProofFromMerkleBlock(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    wire.BlockHeader
+		blockHash string
+		total     uint32
+		hashes    []string
+		flags     []byte
+		txHash    string
+		want      error
+	}{
+		{
+			name:      "block 170",
+			header:    block170(t),
+			blockHash: "00000000d1145790a8694403d4063f323d499e655c83426834d4ce2f8dd4a2ee",
+			total:     2,
+			hashes: []string{
+				"b1fea52486ce0c62bb442b530a3f0132b826c74e473d1f2c220bfa78111c5082",
+				"f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			},
+			flags:  []byte{0x05},
+			txHash: "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+		},
+		{
+			name:      "block 100000",
+			header:    block100000(t),
+			blockHash: "000000000003ba27aa200b1cecaad478d2b00432346c3f1f3986da1afd33e506",
+			total:     4,
+			hashes: []string{
+				"ccdafb73d8dcd0173d5d5c3c9a0770d0b3953db889dab99ef05b1907518cb815",
+				"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+				"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+			},
+			flags:  []byte{0x0d},
+			txHash: "6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+		},
+		{
+			name:   "unmatched TX",
+			header: block170(t),
+			total:  2,
+			hashes: []string{
+				"b1fea52486ce0c62bb442b530a3f0132b826c74e473d1f2c220bfa78111c5082",
+				"f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			},
+			flags:  []byte{0x05},
+			txHash: "b1fea52486ce0c62bb442b530a3f0132b826c74e473d1f2c220bfa78111c5082",
+			want:   ErrTxNotInBlock,
+		},
+		{
+			name:   "header of another block",
+			header: block100000(t),
+			total:  2,
+			hashes: []string{
+				"b1fea52486ce0c62bb442b530a3f0132b826c74e473d1f2c220bfa78111c5082",
+				"f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			},
+			flags:  []byte{0x05},
+			txHash: "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			want:   ErrMerkleProof,
+		},
+		{
+			name:   "unused hash",
+			header: block170(t),
+			total:  2,
+			hashes: []string{
+				"b1fea52486ce0c62bb442b530a3f0132b826c74e473d1f2c220bfa78111c5082",
+				"f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+				"f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			},
+			flags:  []byte{0x05},
+			txHash: "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			want:   ErrPartialMerkleTree,
+		},
+		{
+			name:   "unused flag byte",
+			header: block170(t),
+			total:  2,
+			hashes: []string{
+				"b1fea52486ce0c62bb442b530a3f0132b826c74e473d1f2c220bfa78111c5082",
+				"f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			},
+			flags:  []byte{0x05, 0x00},
+			txHash: "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			want:   ErrPartialMerkleTree,
+		},
+		{
+			name:   "missing hash",
+			header: block170(t),
+			total:  2,
+			hashes: []string{
+				"b1fea52486ce0c62bb442b530a3f0132b826c74e473d1f2c220bfa78111c5082",
+			},
+			flags:  []byte{0x05},
+			txHash: "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			want:   ErrPartialMerkleTree,
+		},
+		{
+			name:   "no flags",
+			header: block170(t),
+			total:  2,
+			hashes: []string{
+				"b1fea52486ce0c62bb442b530a3f0132b826c74e473d1f2c220bfa78111c5082",
+				"f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			},
+			txHash: "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			want:   ErrPartialMerkleTree,
+		},
+		{
+			name:   "more hashes than TX's",
+			header: block170(t),
+			total:  1,
+			hashes: []string{
+				"b1fea52486ce0c62bb442b530a3f0132b826c74e473d1f2c220bfa78111c5082",
+				"f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			},
+			flags:  []byte{0x05},
+			txHash: "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			want:   ErrPartialMerkleTree,
+		},
+		{
+			name:   "no TX's",
+			header: block170(t),
+			flags:  []byte{0x05},
+			txHash: "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			want:   ErrPartialMerkleTree,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mainnetMerkleBlock(t, tt.header, tt.total, tt.hashes, tt.flags)
+
+			p, err := NewMerkleProofFromMerkleBlock(m, *mustHash(t, tt.txHash))
+			if err != tt.want {
+				t.Fatalf("got error %v, want %v", err, tt.want)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if p.BlockHash != tt.blockHash {
+				t.Fatalf("got block hash %v, want %v", p.BlockHash, tt.blockHash)
+			}
+		})
+	}
+}
+
+// TestPartialMerkleRoot_duplicate tests that a tree with identical sibling
+// hashes is rejected (CVE-2012-2459), using the TX's of block 100000.
+func TestPartialMerkleRoot_duplicate(t *testing.T) {
+	header := block100000(t)
+
+	// the TX's of block 100000
+	txHashes := []*chainhash.Hash{
+		mustHash(t, "8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87"),
+		mustHash(t, "fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4"),
+		mustHash(t, "6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4"),
+		mustHash(t, "e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d"),
+	}
+
+	// all TX's matched, which sends every hash
+	root, matched, err := partialMerkleRoot(4, txHashes, []byte{0x7f})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if root != header.MerkleRoot || len(matched) != 4 {
+		t.Fatalf("got root %v matching %v, want %v matching 4", root,
+			len(matched), header.MerkleRoot)
+	}
+
+	// the root over [a, b, c] is that over [a, b, c, c], so the duplicated
+	// TX must not be accepted as a fourth TX
+	three := []*chainhash.Hash{txHashes[0], txHashes[1], txHashes[2]}
+	if _, _, err := partialMerkleRoot(3, three, []byte{0x7f}); err != nil {
+		t.Fatal(err)
+	}
+
+	duplicated := []*chainhash.Hash{txHashes[0], txHashes[1], txHashes[2],
+		txHashes[2]}
+
+	if _, _, err := partialMerkleRoot(4, duplicated, []byte{0x7f}); err != ErrPartialMerkleTree {
+		t.Fatalf("got error %v, want %v", err, ErrPartialMerkleTree)
+	}
+}
+
+// TestNewMerkleProof tests that the proof of each TX of blocks of each size
+// up to 9 TX's hashes to the merkle root of the block, and only proves that
+// TX.
+func TestNewMerkleProof(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		txs := newTxs(n)
+		b, _ := newCompactBlock(txs)
+
+		for i, tx := range txs {
+			p, err := NewMerkleProof(b, tx.TxHash())
+			if err != nil {
+				t.Fatalf("%v txs, tx %v : %v", n, i, err)
+			}
+
+			root, err := p.Root()
+			if err != nil {
+				t.Fatalf("%v txs, tx %v : %v", n, i, err)
+			}
+
+			if root != b.Header.MerkleRoot {
+				t.Fatalf("%v txs, tx %v : got root %v, want %v", n, i, root,
+					b.Header.MerkleRoot)
+			}
+
+			// the proof doesn't prove another TX of the block
+			if n > 1 {
+				p.TxHash = txs[(i+1)%n].TxHash().String()
+				if _, err := p.Root(); err != ErrTxNotInBlock {
+					t.Fatalf("%v txs, tx %v : got error %v, want %v", n, i,
+						err, ErrTxNotInBlock)
+				}
+			}
+		}
+	}
+
+	b, _ := newCompactBlock(newTxs(3))
+	if _, err := NewMerkleProof(b, newTxs(4)[3].TxHash()); err != ErrTxNotInBlock {
+		t.Fatalf("got error %v, want %v", err, ErrTxNotInBlock)
+	}
+}
+
+func TestFilteredBlock_Block(t *testing.T) {
+	txs := newTxs(5)
+	b, _ := newCompactBlock(txs)
+
+	// a merkleblock matching the TX's at 1 and 3
+	proof1, err := NewMerkleProof(b, txs[1].TxHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builder := partialMerkleBuilder{}
+	for _, tx := range txs {
+		builder.txHashes = append(builder.txHashes, tx.TxHash())
+	}
+
+	builder.matches = []bool{false, true, false, true, false}
+	builder.build(3, 0)
+
+	m := wire.NewMsgMerkleBlock(&b.Header)
+	m.Transactions = uint32(len(txs))
+	m.Flags = builder.flags
+
+	for i := range builder.hashes {
+		m.AddTxHash(&builder.hashes[i])
+	}
+
+	tests := []struct {
+		name    string
+		txs     []*wire.MsgTx
+		want    error
+		wantTxs int
+	}{
+		{
+			name:    "matched",
+			txs:     []*wire.MsgTx{txs[3], txs[1]},
+			wantTxs: 2,
+		},
+		{
+			name: "missing matched TX",
+			txs:  []*wire.MsgTx{txs[1]},
+			want: ErrMerkleRoot,
+		},
+		{
+			name: "unmatched TX only",
+			txs:  []*wire.MsgTx{txs[1], txs[2]},
+			want: ErrMerkleRoot,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := FilteredBlock{
+				MerkleBlock:  m,
+				Transactions: tt.txs,
+			}
+
+			block, err := fb.Block()
+			if err != tt.want {
+				t.Fatalf("got error %v, want %v", err, tt.want)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if len(block.Transactions) != tt.wantTxs {
+				t.Fatalf("got %v txs, want %v", len(block.Transactions), tt.wantTxs)
+			}
+
+			// in the order of the block
+			if block.Transactions[0].TxHash() != txs[1].TxHash() {
+				t.Fatalf("got first tx %v, want %v", block.Transactions[0].TxHash(),
+					txs[1].TxHash())
+			}
+		})
+	}
+
+	// the merkleblock of the proof of one TX
+	single := wire.NewMsgMerkleBlock(&b.Header)
+	single.Transactions = proof1.Total
+	single.Flags = proof1.Flags
+	for _, s := range proof1.Hashes {
+		single.AddTxHash(mustHash(t, s))
+	}
+
+	wrongHeader := *single
+	wrongHeader.Header.MerkleRoot = chainhash.Hash{}
+
+	fb := FilteredBlock{
+		MerkleBlock:  &wrongHeader,
+		Transactions: []*wire.MsgTx{txs[1]},
+	}
+
+	if _, err := fb.Block(); err != ErrMerkleRoot {
+		t.Fatalf("got error %v, want %v", err, ErrMerkleRoot)
+	}
+}
@@ -0,0 +1,114 @@
+package spvnode
+
+import (
+	"io"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+// ErrMessageLimit is returned for a message from a peer that exceeds the
+// MessageLimits. The peer is disconnected.
+var ErrMessageLimit = errs.New(errs.Invalid, "Message exceeds limit")
+
+// MessageLimits are the caps on the messages received from peers, lower
+// than the protocol allows, so a malicious peer can't make the Node read or
+// hold large messages.
+type MessageLimits struct {
+	// MaxPayload is the most bytes of a message payload.
+	MaxPayload uint32
+
+	// MaxInv is the most inventory vectors of an inv, getdata or notfound
+	// message.
+	MaxInv int
+
+	// MaxHeaders is the most headers of a headers message.
+	MaxHeaders int
+
+	// MaxAddrs is the most addresses of an addr or addrv2 message.
+	MaxAddrs int
+}
+
+// NewMessageLimits returns the MessageLimits of the Config. The limits of
+// the protocol are used for those that are 0.
+func NewMessageLimits(config Config) MessageLimits {
+	l := MessageLimits{
+		MaxPayload: wire.MaxMessagePayload,
+		MaxInv:     wire.MaxInvPerMsg,
+		MaxHeaders: wire.MaxBlockHeadersPerMsg,
+		MaxAddrs:   wire.MaxAddrPerMsg,
+	}
+
+	if config.MaxMessageSize > 0 {
+		l.MaxPayload = uint32(config.MaxMessageSize)
+	}
+
+	if config.MaxInvPerMsg > 0 {
+		l.MaxInv = config.MaxInvPerMsg
+	}
+
+	if config.MaxHeadersPerMsg > 0 {
+		l.MaxHeaders = config.MaxHeadersPerMsg
+	}
+
+	if config.MaxAddrsPerMsg > 0 {
+		l.MaxAddrs = config.MaxAddrsPerMsg
+	}
+
+	return l
+}
+
+// Read reads the next message from the peer, and checks it against the
+// limits. ErrMessageLimit is returned for a message over the limits, after
+// which the connection must be closed.
+func (l MessageLimits) Read(r io.Reader, network wire.BitcoinNet) (wire.Message, error) {
+	_, m, err := l.ReadN(r, network)
+	return m, err
+}
+
+// ReadN is Read, that also returns the number of bytes read, even if there
+// is an error.
+func (l MessageLimits) ReadN(r io.Reader, network wire.BitcoinNet) (int, wire.Message, error) {
+	n, m, _, err := wire.ReadMessageMaxN(r, wire.ProtocolVersion, network,
+		l.MaxPayload)
+	if err == wire.ErrPayloadTooLarge {
+		return n, nil, ErrMessageLimit
+	}
+
+	if err != nil {
+		return n, nil, err
+	}
+
+	if err := l.Check(m); err != nil {
+		return n, nil, err
+	}
+
+	return n, m, nil
+}
+
+// Check returns ErrMessageLimit if the message has more entries than the
+// limits allow.
+func (l MessageLimits) Check(m wire.Message) error {
+	count, max := 0, 0
+
+	switch msg := m.(type) {
+	case *wire.MsgInv:
+		count, max = len(msg.InvList), l.MaxInv
+	case *wire.MsgGetData:
+		count, max = len(msg.InvList), l.MaxInv
+	case *wire.MsgNotFound:
+		count, max = len(msg.InvList), l.MaxInv
+	case *wire.MsgHeaders:
+		count, max = len(msg.Headers), l.MaxHeaders
+	case *wire.MsgAddr:
+		count, max = len(msg.AddrList), l.MaxAddrs
+	case *wire.MsgAddrV2:
+		count, max = len(msg.AddrList), l.MaxAddrs
+	}
+
+	if count > max {
+		return ErrMessageLimit
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package spvnode
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// encode returns the message as it is sent on the network.
+func encode(t *testing.T, m wire.Message) []byte {
+	var buf bytes.Buffer
+	if err := wire.WriteMessage(&buf, m, wire.ProtocolVersion, MainNetBch); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func newInv(n int) *wire.MsgInv {
+	m := wire.NewMsgInv()
+	for i := 0; i < n; i++ {
+		hash := chainhash.DoubleHashH([]byte{byte(i), byte(i >> 8)})
+		m.AddInvVect(wire.NewInvVect(wire.InvTypeTx, &hash))
+	}
+
+	return m
+}
+
+func newHeaders(n int) *wire.MsgHeaders {
+	m := wire.NewMsgHeaders()
+	for i := 0; i < n; i++ {
+		m.AddBlockHeader(&wire.BlockHeader{Nonce: uint32(i)})
+	}
+
+	return m
+}
+
+func newAddrs(n int) *wire.MsgAddr {
+	m := wire.NewMsgAddr()
+	for i := 0; i < n; i++ {
+		m.AddAddress(wire.NewNetAddressIPPort(net.IPv4(10, 0, 0, byte(i)), 8333, 0))
+	}
+
+	return m
+}
+
+func TestMessageLimits_Read(t *testing.T) {
+	limits := NewMessageLimits(Config{
+		MaxMessageSize:   1000,
+		MaxInvPerMsg:     3,
+		MaxHeadersPerMsg: 2,
+		MaxAddrsPerMsg:   2,
+	})
+
+	ping := encode(t, wire.NewMsgPing(1))
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+
+		// wantRead is the number of bytes read, if not all of them, as the
+		// payload of an oversized message is left unread
+		wantRead int
+	}{
+		{
+			name: "ping",
+			data: ping,
+		},
+		{
+			name:     "oversized payload",
+			data:     encode(t, newInv(40)),
+			want:     ErrMessageLimit,
+			wantRead: wire.MessageHeaderSize,
+		},
+		{
+			name: "inv at the limit",
+			data: encode(t, newInv(3)),
+		},
+		{
+			name: "inv over the limit",
+			data: encode(t, newInv(4)),
+			want: ErrMessageLimit,
+		},
+		{
+			name: "headers over the limit",
+			data: encode(t, newHeaders(3)),
+			want: ErrMessageLimit,
+		},
+		{
+			name: "addrs over the limit",
+			data: encode(t, newAddrs(3)),
+			want: ErrMessageLimit,
+		},
+		{
+			name:     "truncated header",
+			data:     ping[:wire.MessageHeaderSize-1],
+			want:     io.ErrUnexpectedEOF,
+			wantRead: wire.MessageHeaderSize - 1,
+		},
+		{
+			name:     "truncated payload",
+			data:     ping[:len(ping)-1],
+			want:     io.ErrUnexpectedEOF,
+			wantRead: len(ping) - 1,
+		},
+		{
+			name: "empty",
+			want: io.EOF,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, m, err := limits.ReadN(bytes.NewReader(tt.data), MainNetBch)
+			if err != tt.want {
+				t.Fatalf("got error %v, want %v", err, tt.want)
+			}
+
+			wantRead := tt.wantRead
+			if wantRead == 0 {
+				wantRead = len(tt.data)
+			}
+
+			if n != wantRead {
+				t.Fatalf("got %v bytes read, want %v", n, wantRead)
+			}
+
+			if (m == nil) != (err != nil) {
+				t.Fatalf("got message %v with error %v", m, err)
+			}
+		})
+	}
+}
+
+func TestNewMessageLimits(t *testing.T) {
+	got := NewMessageLimits(Config{})
+
+	want := MessageLimits{
+		MaxPayload: wire.MaxMessagePayload,
+		MaxInv:     wire.MaxInvPerMsg,
+		MaxHeaders: wire.MaxBlockHeadersPerMsg,
+		MaxAddrs:   wire.MaxAddrPerMsg,
+	}
+
+	if got != want {
+		t.Fatalf("got %+v, want the protocol limits %+v", got, want)
+	}
+}
+
+// TestNodeLimits_inbound tests that the messages of inbound peers are held
+// to the lower payload cap, and those of other peers aren't.
+func TestNodeLimits_inbound(t *testing.T) {
+	l := newNodeLimits(Config{
+		MaxInboundMessageSize: 100,
+	})
+
+	_, limits, inbound := l.current()
+
+	data := encode(t, newInv(10))
+
+	if _, err := limits.Read(bytes.NewReader(data), MainNetBch); err != nil {
+		t.Fatalf("got error %v from an outbound peer, want none", err)
+	}
+
+	if _, err := inbound.Read(bytes.NewReader(data), MainNetBch); err != ErrMessageLimit {
+		t.Fatalf("got error %v from an inbound peer, want %v", err,
+			ErrMessageLimit)
+	}
+}
@@ -0,0 +1,34 @@
+package spvnode
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// netGroup returns the network group of the peer address, so peers can be
+// spread across networks that aren't run by one operator.
+//
+// IPv4 addresses are grouped by their /16, IPv6 addresses by their /32, and
+// all Tor addresses share one group. A host name is its own group.
+func netGroup(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+
+	if strings.HasSuffix(host, ".onion") {
+		return "onion"
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return host
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d", ip4[0], ip4[1])
+	}
+
+	return fmt.Sprintf("%x", []byte(ip[:4]))
+}
@@ -0,0 +1,58 @@
+package spvnode
+
+import (
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// ErrUnknownNetwork is returned for a network name that is not supported.
+var ErrUnknownNetwork = errs.New(errs.Invalid, "Unknown network")
+
+// NetworkNames are the names of the networks the Node can run on, as they
+// are configured.
+var NetworkNames = map[wire.BitcoinNet]string{
+	MainNetBch: "mainnet",
+	TestNetBch: "testnet",
+	RegTestBch: "regtest",
+	StnBch:     "stn",
+}
+
+// NetworkGenesis are the hashes of the genesis block of each network.
+var NetworkGenesis = map[wire.BitcoinNet]chainhash.Hash{
+	MainNetBch: *chaincfg.MainNetParams.GenesisHash,
+	TestNetBch: *chaincfg.TestNet3Params.GenesisHash,
+	RegTestBch: *chaincfg.RegressionNetParams.GenesisHash,
+	StnBch:     *chaincfg.TestNet3Params.GenesisHash,
+}
+
+// ParseNetwork returns the network of the name, which is MainNetBch if the
+// name is empty.
+func ParseNetwork(name string) (wire.BitcoinNet, error) {
+	if name == "" {
+		return MainNetBch, nil
+	}
+
+	for net, n := range NetworkNames {
+		if n == name {
+			return net, nil
+		}
+	}
+
+	return 0, ErrUnknownNetwork
+}
+
+// ChainParams returns the chain parameters of the network, such as the
+// prefixes of its addresses. The test networks share those of testnet.
+func ChainParams(net wire.BitcoinNet) *chaincfg.Params {
+	switch net {
+	case TestNetBch, StnBch:
+		return &chaincfg.TestNet3Params
+	case RegTestBch:
+		return &chaincfg.RegressionNetParams
+	}
+
+	return &chaincfg.MainNetParams
+}
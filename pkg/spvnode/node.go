@@ -5,15 +5,17 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/binary"
-	"fmt"
+	"errors"
 	"net"
 	"sync"
 	"time"
 
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/spvnode/logger"
 	"github.com/tokenized/smart-contract/pkg/storage"
 	"github.com/tokenized/smart-contract/pkg/wire"
 
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"go.uber.org/multierr"
 )
 
@@ -21,34 +23,218 @@ const (
 	MainNetBch wire.BitcoinNet = 0xe8f3e1e3
 	TestNetBch wire.BitcoinNet = 0xf4f3e5f4
 	RegTestBch wire.BitcoinNet = 0xfabfb5da
+	StnBch     wire.BitcoinNet = 0xf9c4cefb
 
 	ListenerTX    = "TX"
 	ListenerBlock = "block"
 
 	firstBCHBlock = 478559
+
+	// downloadPeers is the number of untrusted peers blocks are downloaded
+	// from in parallel.
+	downloadPeers = 4
+
+	// maxBodiesPerRequest is the number of block bodies requested in each
+	// getdata message of a headers first sync.
+	maxBodiesPerRequest = 500
+
+	// mempoolSweepInterval is how often expired TX's are removed from the
+	// Mempool.
+	mempoolSweepInterval = time.Minute
+
+	// stallTimeout is how long the trusted node can send nothing before it
+	// is failed over. Nodes ping every 2 minutes, so a live node is never
+	// silent this long.
+	stallTimeout = 5 * time.Minute
+
+	// defaultMaxInbound is the most inbound peers connected at once, if the
+	// Config doesn't set it.
+	defaultMaxInbound = 8
+)
+
+var (
+	// ErrNotConnected is returned when a message is sent while there is no
+	// connection to a trusted node, such as during a failover.
+	ErrNotConnected = errs.New(errs.Temporary, "Not connected to a trusted node")
+
+	// ErrNoVersion is returned when an inbound peer sends a message before
+	// its version message.
+	ErrNoVersion = errs.New(errs.Invalid, "Message before version")
+
+	// ErrTxNotFound is returned when a TX is not stored, in the Mempool, or
+	// served by any peer.
+	ErrTxNotFound = errs.New(errs.NotFound, "TX not found")
+
+	// ErrBelowFeeFilter is passed on when a broadcast TX isn't sent to a
+	// peer, as its fee rate is below the feefilter of the peer.
+	ErrBelowFeeFilter = errs.New(errs.Invalid, "TX fee rate is below the peer fee filter")
 )
 
 type Node struct {
+	// Config is the Config the Node was created with. The limits Reload
+	// changes are read from the limits instead.
 	Config       Config
 	Handlers     map[string]CommandHandler
 	conn         net.Conn
+	connLock     *sync.Mutex
 	messages     chan wire.Message
 	BlockService *BlockService
-	Listeners    map[string]Listener
+	Listeners    map[string]ListenerSet
+	Conformance  Conformance
+	Traffic      Traffic
+	Backoff      Backoff
+	Seeder       Seeder
+	Pool         PeerPool
+	Mempool      Mempool
+	Tracker      BroadcastTracker
+	TxFilters    TxFilterList
+
+	// Rebroadcaster keeps the TX's the Node broadcast, to send them again
+	// if they aren't confirmed.
+	Rebroadcaster Rebroadcaster
+
+	// TxRequests limits the announced TX's requested from the trusted node
+	// at once.
+	TxRequests TxRequests
+
+	// Orphans holds the relevant TX's that arrive before their parents.
+	Orphans OrphanPool
+
+	// DoubleSpends watches the outputs spent by relevant TX's for
+	// conflicts.
+	DoubleSpends DoubleSpends
+
+	// SeenTxs remembers the TX's passed to the TX Listener, so the copies
+	// sent by other peers aren't passed on again.
+	SeenTxs SeenTxs
+
+	// Workers pass the TX's to the Listeners, if the Config sets any, so a
+	// slow Listener doesn't hold up the handling of the messages.
+	Workers WorkerPool
+
+	// Limiter limits the messages sent to the trusted node. It is reset for
+	// each connection.
+	Limiter RateLimiter
+
+	// limits cap the messages received from peers, and the inbound peers
+	// accepted. They are read for each message, and replaced by Reload.
+	limits *nodeLimits
+
+	// inbound are the connections of the inbound peers, so they are closed
+	// when the Node is stopped, with the RateLimiter of each.
+	inbound     map[net.Conn]RateLimiter
+	inboundLock *sync.Mutex
+
+	// handleLock serializes the handling of the messages of the trusted
+	// node and the inbound peers, as the handlers are not safe for
+	// concurrent use.
+	handleLock *sync.Mutex
+
+	// state keeps the height announced by the trusted node, and its last
+	// error, for the Status.
+	state *nodeState
+
+	// trusted are the addresses of the trusted nodes, in order of priority,
+	// and active is the index of the one connected to.
+	trusted []string
+	active  int
+
+	// resume is set after a failover, so the sync is resumed once the new
+	// node completes the handshake.
+	resume bool
+
+	// mempoolRepo saves the Mempool while the Node is stopped.
+	mempoolRepo MempoolRepository
+
+	// ctx is cancelled by Stop, which ends the goroutines of Start.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 func NewNode(config Config, store storage.Storage) Node {
+	if config.Network == 0 {
+		config.Network = MainNetBch
+	}
+
 	stateRepo := NewStateRepository(store)
 	blockRepo := NewBlockRepository(store)
-	blockService := NewBlockService(blockRepo, stateRepo)
+	blockService := NewBlockService(blockRepo, stateRepo, NewJournal(store))
+	peerRepo := NewPeerRepository(store)
+
+	blockService.rules = NetworkHeaderRules[config.Network]
+	blockService.checkpoints = NetworkCheckpoints[config.Network]
+
+	if config.HeadersFirst {
+		blockService.keepFrom = config.StartHeight
+	}
+
+	blockService.keepBodies = int32(config.KeepBlocks)
+	blockService.keepTxs = config.KeepRelevantTxs
+
+	if len(config.Checkpoints) > 0 {
+		blockService.checkpoints = config.Checkpoints
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	listeners := map[string]ListenerSet{
+		ListenerTX:    NewListenerSet(),
+		ListenerBlock: NewListenerSet(),
+	}
+
+	conformance := NewConformance()
+	backoff := NewBackoff(backoffMin, backoffMax)
+	limits := NewMessageLimits(config)
+	traffic := NewTraffic()
+	pool := NewPeerPool(config.Peers, config.MaxPeersPerGroup,
+		config.PinnedPeers, config.Network, limits, NewDialer(config),
+		NewHandshake(config), traffic, peerRepo, conformance, backoff)
+	pool.SetRotation(config.PeerRotationInterval, config.PeerRotationCount)
 
 	n := Node{
 		Config:       config,
+		connLock:     &sync.Mutex{},
 		messages:     make(chan wire.Message),
 		BlockService: &blockService,
-		Listeners:    map[string]Listener{},
+		Listeners:    listeners,
+		Conformance:  conformance,
+		Traffic:      traffic,
+		Backoff:      backoff,
+		Seeder:       NewSeeder(config.Network, config.Seeds, peerRepo),
+		Pool:         pool,
+		Mempool:      NewMempool(config),
+		Tracker:      NewBroadcastTracker(),
+		TxFilters:    NewTxFilterList(config.TxFilters...),
+		mempoolRepo:  NewMempoolRepository(store),
+		trusted:      config.TrustedNodes(),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+
+	n.Rebroadcaster = NewRebroadcaster(config.RebroadcastInterval,
+		config.RebroadcastMaxAttempts)
+	n.TxRequests = NewTxRequests(config.MaxTxsInFlight)
+	n.Orphans = NewOrphanPool()
+	n.DoubleSpends = NewDoubleSpends()
+	n.SeenTxs = NewSeenTxs()
+	n.Workers = NewWorkerPool(config.ListenerWorkers)
+	n.Limiter = NewRateLimiter(config.SendMessagesPerSecond,
+		config.SendBytesPerSecond)
+
+	nodeLimits := newNodeLimits(config)
+	n.limits = &nodeLimits
+
+	if n.Config.MaxInbound == 0 {
+		n.Config.MaxInbound = defaultMaxInbound
 	}
 
+	n.inbound = map[net.Conn]RateLimiter{}
+	n.inboundLock = &sync.Mutex{}
+	n.handleLock = &sync.Mutex{}
+
+	state := newNodeState()
+	n.state = &state
+
 	return n
 }
 
@@ -56,7 +242,20 @@ func (n *Node) Start() error {
 	ctx := logger.NewContext()
 	log := logger.NewLoggerFromContext(ctx).Sugar()
 
-	n.Handlers = newCommandHandlers(n.Config, n.BlockService, n.Listeners)
+	n.Handlers = newCommandHandlers(n.Config, n.BlockService, n.Mempool,
+		n.Tracker, n.Rebroadcaster, n.TxRequests, n.Orphans, n.DoubleSpends,
+		n.SeenTxs, n.Seeder.Peers, n.Workers.Listener(n.Listeners[ListenerTX]),
+		n.Workers.Listener(n.Listeners[ListenerBlock]), []TxFilter{n.TxFilters})
+
+	// complete a block commit interrupted when the Node last stopped
+	recovered, err := n.BlockService.Recover(ctx)
+	if err != nil {
+		return err
+	}
+
+	if recovered {
+		log.Warnf("Recovered an interrupted block commit")
+	}
 
 	state, err := n.BlockService.LoadState(ctx)
 	if err != nil {
@@ -80,15 +279,96 @@ func (n *Node) Start() error {
 
 	log.Infof("Loaded %v blocks", len(n.BlockService.Blocks))
 
-	if err := n.connect(); err != nil {
+	if n.Config.PersistMempool && !n.Config.HeadersOnly {
+		if err := n.loadMempool(ctx); err != nil {
+			return err
+		}
+
+		// saved once the goroutines below have finished
+		defer n.saveMempool(ctx)
+	}
+
+	n.seed(ctx)
+
+	if err := n.connect(n.connectOrder(-1)); err != nil {
 		return err
 	}
 
-	// we will probably never really exit.
 	defer n.close()
 
+	// listen before any goroutine starts, so a failure leaves none running
+	var ln net.Listener
+	if n.Config.Listen != "" {
+		ln, err = net.Listen("tcp", n.Config.Listen)
+		if err != nil {
+			return err
+		}
+
+		log.Infof("Listening for inbound peers on %v", ln.Addr())
+	}
+
 	wg := sync.WaitGroup{}
-	wg.Add(2)
+	wg.Add(3)
+
+	go func() {
+		defer wg.Done()
+
+		// closing the connection unblocks the read of the peer
+		<-n.ctx.Done()
+		n.close()
+	}()
+
+	if n.Config.MempoolExpiry > 0 && !n.Config.HeadersOnly {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			n.sweepMempool()
+		}()
+	}
+
+	if n.Config.RebroadcastInterval > 0 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			n.rebroadcast()
+		}()
+	}
+
+	if n.Config.ListenerWorkers > 0 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			// pass the TX's to the listeners
+			n.Workers.Run(n.ctx)
+		}()
+	}
+
+	if n.Config.Peers > 0 || len(n.Config.PinnedPeers) > 0 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			// keep untrusted peers connected to download blocks from
+			n.Pool.Run(n.ctx)
+		}()
+	}
+
+	if ln != nil {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			n.acceptPeers(ln)
+		}()
+	}
 
 	go func() {
 		defer wg.Done()
@@ -107,29 +387,277 @@ func (n *Node) Start() error {
 	// kick off the connection handshaking process by sending a version
 	// message.
 	if err := n.handshake(); err != nil {
-		return nil
+		// stop the goroutines and the listener before returning
+		n.cancel()
+		if ln != nil {
+			ln.Close()
+		}
+
+		wg.Wait()
+
+		return err
 	}
 
-	// block until goroutines finish, which is currently never
+	// block until the goroutines finish, once the Node is stopped
 	wg.Wait()
 
+	log.Info("Stopped")
+
 	return nil
 }
 
-func (n *Node) connect() error {
+// Stop stops the Node, and returns once the goroutines of Start have
+// been told to finish. Start returns once they have.
+func (n *Node) Stop() {
+	n.cancel()
+}
+
+// stopped returns true if the Node has been stopped.
+func (n *Node) stopped() bool {
+	return n.ctx.Err() != nil
+}
+
+// connect connects to the first of the trusted nodes, by their index in
+// order, that accepts the connection. Nodes that are backing off after
+// failed connections are skipped.
+func (n *Node) connect(order []int) error {
 	n.close()
 
-	conn, err := net.Dial("tcp", n.Config.NodeAddress)
-	if err != nil {
-		return err
+	if len(n.trusted) == 0 {
+		// no trusted node is configured, so use a discovered peer
+		address, err := n.discoveredPeer()
+		if err != nil {
+			return err
+		}
+
+		n.Config.NodeAddress = address
+
+		conn, err := NewDialer(n.Config).Dial(address)
+		if err != nil {
+			n.Backoff.Failed(address)
+			return err
+		}
+
+		n.Backoff.Succeeded(address)
+		n.setConn(conn)
+
+		return nil
+	}
+
+	ctx := logger.NewContext()
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	err := ErrNotConnected
+
+	for _, i := range order {
+		if !n.Backoff.Ready(n.trusted[i]) {
+			continue
+		}
+
+		conn, dialErr := NewDialer(n.Config).Dial(n.trusted[i])
+		if dialErr != nil {
+			delay := n.Backoff.Failed(n.trusted[i])
+			log.Warnf("Failed to connect to trusted node %v, retrying in %v : %v",
+				n.trusted[i], delay, dialErr)
+			err = dialErr
+			continue
+		}
+
+		n.Backoff.Succeeded(n.trusted[i])
+
+		log.Infof("Connected to trusted node %v", n.trusted[i])
+
+		n.active = i
+		n.Config.NodeAddress = n.trusted[i]
+		n.setConn(conn)
+
+		return nil
+	}
+
+	return err
+}
+
+// connectOrder returns the indexes of the trusted nodes in order of
+// priority, except that the failed node, if not -1, is tried last.
+func (n Node) connectOrder(failed int) []int {
+	order := []int{}
+
+	for i := range n.trusted {
+		if i != failed {
+			order = append(order, i)
+		}
+	}
+
+	if failed >= 0 && failed < len(n.trusted) {
+		order = append(order, failed)
+	}
+
+	return order
+}
+
+// failover replaces the connection to the trusted node, which has
+// disconnected or stalled, with one to the trusted node of highest priority
+// that can be connected to. It blocks until one can, or the Node is
+// stopped, backing off from each node that fails.
+//
+// The handshake is started with the new node, and the sync is resumed once
+// it completes.
+func (n *Node) failover(ctx context.Context) {
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	failed := -1
+	if len(n.trusted) > 0 {
+		failed = n.active
+	}
+
+	log.Warnf("Lost trusted node %v, failing over", n.Config.NodeAddress)
+
+	// the lost node is not reconnected to at once
+	n.Backoff.Failed(n.Config.NodeAddress)
+
+	addresses := n.trusted
+	if len(addresses) == 0 {
+		addresses = []string{n.Config.NodeAddress}
+	}
+
+	for {
+		err := n.connect(n.connectOrder(failed))
+		if n.stopped() {
+			// stopped while connecting
+			n.close()
+			return
+		}
+
+		if err == nil {
+			break
+		}
+
+		wait := n.Backoff.Wait(addresses)
+		if wait == 0 {
+			wait = n.Backoff.Min
+		}
+		log.Errorf("Failed to connect to a trusted node, retrying in %v : %v",
+			wait, err)
+
+		// wait until a node may be reconnected to
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+
+	n.resume = true
+
+	if err := n.handshake(); err != nil {
+		log.Errorf("Failed to start handshake : %v", err)
 	}
+}
+
+// resync resumes the sync with a trusted node that was failed over to.
+//
+// Headers are requested from the last seen block, so any blocks missed, or
+// a different chain tip of the new node, are reconciled by the headers and
+// block handlers. Block bodies that were requested from the failed node,
+// and not received, are requested again. The TX's requested from it are
+// forgotten, as the new node announces its own.
+func (n Node) resync(ctx context.Context) []wire.Message {
+	out := []wire.Message{}
+
+	n.TxRequests.Reset()
+
+	if last := n.BlockService.State.LastSeen; last.Hash != "" {
+		hash, err := chainhash.NewHashFromStr(last.Hash)
+		if err == nil {
+			getheaders := wire.NewMsgGetHeaders()
+			getheaders.BlockLocatorHashes = []*chainhash.Hash{hash}
+			out = append(out, getheaders)
+		}
+	}
+
+	return append(out, buildGetDataBlocks(n.BlockService.Wanted())...)
+}
+
+// peerConn returns the connection to the trusted node.
+func (n *Node) peerConn() net.Conn {
+	n.connLock.Lock()
+	defer n.connLock.Unlock()
+
+	return n.conn
+}
+
+// setConn replaces the connection to the trusted node.
+func (n *Node) setConn(conn net.Conn) {
+	n.connLock.Lock()
+	defer n.connLock.Unlock()
 
 	n.conn = conn
+	n.Limiter.Reset()
+	n.Traffic.Connected(n.Config.NodeAddress, PeerTrusted)
+}
 
-	return nil
+// seed removes the peers not seen for the peerPruneAge, then bootstraps the
+// known peers from the DNS seeds, if there are none or they are stale.
+// Failures are logged, as the trusted node may be enough.
+func (n Node) seed(ctx context.Context) {
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	pruned, err := n.Seeder.Peers.Prune(ctx, time.Now().Add(-peerPruneAge))
+	if err != nil {
+		log.Errorf("Failed to prune peers : %v", err)
+	} else if pruned > 0 {
+		log.Infof("Removed %v peers not seen for %v", pruned, peerPruneAge)
+	}
+
+	if n.Config.Proxy != "" && len(n.Config.Seeds) == 0 {
+		// the DNS lookups would bypass the proxy
+		log.Infof("Not looking up the default DNS seeds through proxy %v",
+			n.Config.Proxy)
+		return
+	}
+
+	stale, err := n.Seeder.NeedsSeeding(ctx)
+	if err != nil {
+		log.Errorf("Failed to read peers : %v", err)
+		return
+	}
+
+	if !stale {
+		return
+	}
+
+	if _, err := n.Seeder.Bootstrap(ctx); err != nil {
+		log.Warnf("Failed to bootstrap peers : %v", err)
+	}
+}
+
+// discoveredPeer returns the address of the most recently seen peer.
+func (n Node) discoveredPeer() (string, error) {
+	ctx := logger.NewContext()
+
+	peers, err := n.Seeder.Peers.All(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if len(peers) == 0 {
+		return "", ErrPeerNotFound
+	}
+
+	best := peers[0]
+	for _, p := range peers[1:] {
+		if p.LastSeen > best.LastSeen {
+			best = p
+		}
+	}
+
+	return best.Address, nil
 }
 
 func (n *Node) close() {
+	n.connLock.Lock()
+	defer n.connLock.Unlock()
+
 	if n.conn == nil {
 		return
 	}
@@ -138,57 +666,107 @@ func (n *Node) close() {
 	_ = n.conn.Close()
 
 	n.conn = nil
+	n.Traffic.Disconnected(n.Config.NodeAddress)
 }
 
 // readPeer reads new messages from the Peer.
 //
-// This is a blocking function that will run forever, so it should be run
-// in a goroutine.
-func (n Node) readPeer() {
-	for {
+// This is a blocking function that runs until the Node is stopped, so it
+// should be run in a goroutine.
+//
+// If the trusted node disconnects, or sends nothing for the stallTimeout,
+// it is failed over to the next trusted node.
+func (n *Node) readPeer() {
+	for !n.stopped() {
 		ctx := logger.NewContext()
 
+		conn := n.peerConn()
+		if conn == nil {
+			n.failover(ctx)
+			continue
+		}
+
 		// read new messages, blocking
-		m, _, err := wire.ReadMessage(n.conn, wire.ProtocolVersion, MainNetBch)
+		var m wire.Message
+		err := conn.SetReadDeadline(time.Now().Add(stallTimeout))
+		if err == nil {
+			_, limits, _ := n.limits.current()
+
+			var size int
+			size, m, err = limits.ReadN(conn, n.Config.Network)
+			n.Traffic.Received(n.Config.NodeAddress, command(m), size)
+		}
+
 		if err != nil {
+			if n.stopped() {
+				// the connection was closed by Stop
+				return
+			}
+
 			log := logger.NewLoggerFromContext(ctx)
 			log.Error(err.Error())
 
-			// wait before reconnecting
-			time.Sleep(time.Second * 30)
+			n.state.failed(err)
+
+			if _, ok := err.(*wire.MessageError); ok {
+				// the rest of the message was read, so the connection is
+				// still usable
+				n.Conformance.Record(n.Config.NodeAddress, AnomalyMalformed, err)
+				continue
+			}
+
+			if err == ErrMessageLimit {
+				// the rest of the message may not have been read, so the
+				// connection can't be used
+				n.Conformance.Record(n.Config.NodeAddress, AnomalyOversized, err)
+			}
+
+			n.failover(ctx)
 			continue
 		}
 
+		n.Conformance.Received(n.Config.NodeAddress)
+
+		if version, ok := m.(*wire.MsgVersion); ok {
+			n.state.announced(version.LastBlock)
+		}
+
+		if _, ok := m.(*wire.MsgVerAck); ok && n.resume {
+			n.resume = false
+
+			for _, out := range n.resync(ctx) {
+				if err := n.Queue(ctx, out); err != nil {
+					log := logger.NewLoggerFromContext(ctx).Sugar()
+					log.Error(err)
+				}
+			}
+		}
+
 		if err := n.handle(ctx, m); err != nil {
+			anomaly := AnomalyUnexpected
+			if isHeaderError(err) {
+				anomaly = AnomalyInvalidHeader
+			}
+
+			n.Conformance.Record(n.Config.NodeAddress, anomaly, err)
+			n.state.failed(err)
+
 			log := logger.NewLoggerFromContext(ctx).Sugar()
 			log.Errorf("msg = %+v : %v", m, err.Error())
 		}
 	}
 }
 
-// handle processes an inbound message.
+// handle processes a message of the trusted node, and queues the replies
+// to it.
 func (n Node) handle(ctx context.Context,
 	m wire.Message) error {
 
-	h, ok := n.Handlers[m.Command()]
-	if !ok {
-		// no handler for this command
-		return nil
-	}
-
-	out, err := h.Handle(ctx, m)
+	out, err := n.dispatch(ctx, n.Config.NodeAddress, m)
 	if err != nil {
 		return err
 	}
 
-	if out == nil {
-		if _, ok := m.(*wire.MsgHeaders); ok {
-			n.BlockService.synced = true
-		}
-
-		return nil
-	}
-
 	errors := []error{}
 
 	for _, m := range out {
@@ -202,61 +780,1340 @@ func (n Node) handle(ctx context.Context,
 	return multierr.Combine(errors...)
 }
 
-func (n *Node) RegisterListener(name string, listener Listener) {
-	n.Listeners[name] = listener
-}
+// dispatch passes a message of the peer at the address to its handler, and
+// returns the replies to the peer.
+func (n Node) dispatch(ctx context.Context, address string,
+	m wire.Message) ([]wire.Message, error) {
 
-// handshake starts the handshake process.
-//
-// Sending a version message to the peer will fire off is enough as the
-// process is event driven, and this node responds appropriately to the
-// responses.
-//
-// The version handshake look like this.
-//
-// L -> R: Send version message with the local peer's version
-// R -> L: Send version message back
-// R -> L: Send verack message
-// R:      Sets version to the minimum of the 2 versions
-// L -> R: Send verack message after receiving version message from R
-// L:      Sets version to the minimum of the 2 versions
-func (n Node) handshake() error {
-	ctx := logger.NewContext()
+	if reject, ok := m.(*wire.MsgReject); ok {
+		// a broadcast TX may have been rejected
+		n.Tracker.Rejected(address, reject)
+	}
 
-	// my local. This doesn't matter, we don't accept inboound connections.
-	local := wire.NewNetAddressIPPort(net.IPv4(127, 0, 0, 1), 9333, 0)
+	h, ok := n.Handlers[m.Command()]
+	if !ok {
+		// no handler for this command
+		return nil, nil
+	}
 
-	// build the address of the remote
-	remote := wire.NewNetAddressIPPort(net.IPv4(127, 0, 0, 1), 8333, 0)
+	n.handleLock.Lock()
+	defer n.handleLock.Unlock()
 
-	lastSeen := n.BlockService.State.LastSeen
-	msg := wire.NewMsgVersion(remote, local, n.nonce(), lastSeen.Height)
-	msg.UserAgent = n.buildUserAgent()
-	msg.Services = 0x01
+	if headers, ok := m.(*wire.MsgHeaders); ok && n.isStale(ctx, headers) {
+		n.Conformance.Record(address, AnomalyStaleChain,
+			errors.New("Headers do not extend the chain"))
+	}
 
-	return n.Queue(ctx, msg)
-}
+	out, err := h.Handle(ctx, m)
+	if err != nil {
+		return nil, err
+	}
 
-// Queue puts the message on a queue for async delivery.
-func (n Node) Queue(ctx context.Context, msg wire.Message) error {
-	go func() {
-		n.messages <- msg
-	}()
+	if out == nil {
+		if _, ok := m.(*wire.MsgHeaders); ok {
+			if !n.BlockService.synced && n.Config.HeadersFirst &&
+				!n.Config.HeadersOnly {
+				// the header chain is complete, so fetch the bodies
+				out = n.requestBodies(ctx)
+			}
 
-	return nil
+			n.BlockService.synced = true
+		}
+	}
+
+	return out, nil
+}
+
+// acceptPeers accepts the connections of inbound peers on the listener,
+// up to the MaxInbound at once, and reads their messages.
+//
+// This is a blocking function that runs until the Node is stopped, so it
+// should be run in a goroutine.
+func (n *Node) acceptPeers(ln net.Listener) {
+	ctx := logger.NewContext()
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+
+	go func() {
+		// closing the listener and connections unblocks their reads
+		<-n.ctx.Done()
+		ln.Close()
+		n.closeInbound()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if n.stopped() {
+				return
+			}
+
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				log.Warnf("Failed to accept inbound peer : %v", err)
+				time.Sleep(time.Second)
+				continue
+			}
+
+			log.Errorf("Stopped listening for inbound peers : %v", err)
+			return
+		}
+
+		limiter, ok := n.addInbound(conn)
+		if !ok {
+			log.Infof("Rejected inbound peer %v, the most are connected",
+				conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer n.removeInbound(conn)
+
+			n.readInbound(conn, limiter)
+		}()
+	}
+}
+
+// addInbound adds the connection of an inbound peer, and returns the
+// RateLimiter of the messages sent to it, unless the MaxInbound are
+// connected, or the Node is stopped, in which case it returns false.
+func (n Node) addInbound(conn net.Conn) (RateLimiter, bool) {
+	n.inboundLock.Lock()
+	defer n.inboundLock.Unlock()
+
+	config, _, _ := n.limits.current()
+
+	if n.stopped() || len(n.inbound) >= config.MaxInbound {
+		return RateLimiter{}, false
+	}
+
+	limiter := NewRateLimiter(config.SendMessagesPerSecond,
+		config.SendBytesPerSecond)
+	n.inbound[conn] = limiter
+
+	return limiter, true
+}
+
+// setInboundRates changes the rates messages are sent to the inbound peers
+// at.
+func (n Node) setInboundRates(messagesPerSecond, bytesPerSecond int) {
+	n.inboundLock.Lock()
+	defer n.inboundLock.Unlock()
+
+	for _, limiter := range n.inbound {
+		limiter.SetRates(messagesPerSecond, bytesPerSecond)
+	}
+}
+
+// removeInbound closes the connection of an inbound peer, and removes it.
+func (n Node) removeInbound(conn net.Conn) {
+	n.inboundLock.Lock()
+	defer n.inboundLock.Unlock()
+
+	conn.Close()
+	delete(n.inbound, conn)
+}
+
+// closeInbound closes the connections of all the inbound peers.
+func (n Node) closeInbound() {
+	n.inboundLock.Lock()
+	defer n.inboundLock.Unlock()
+
+	for conn := range n.inbound {
+		// close the connection, ignoring any errors
+		_ = conn.Close()
+	}
+}
+
+// readInbound reads the messages of an inbound peer, and writes the replies
+// of their handlers back to it, until it disconnects.
+//
+// The peer must start the handshake with its version message, which is
+// answered with the version of the Node, and complete it within the
+// Handshake timeout. A peer whose version fails the Handshake is penalized
+// and disconnected. The messages are held to the InboundLimits, and a peer
+// that exceeds them, or sends nothing for the stallTimeout, is
+// disconnected. The replies are sent as fast as the RateLimiter allows.
+func (n Node) readInbound(conn net.Conn, limiter RateLimiter) {
+	ctx := logger.NewContext()
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	address := conn.RemoteAddr().String()
+	config, _, _ := n.limits.current()
+	handshake := NewHandshake(config)
+	handshakeDeadline := time.Now().Add(handshake.Timeout)
+	versioned, verack := false, false
+
+	log.Infof("Accepted inbound peer %v", address)
+
+	n.Traffic.Connected(address, PeerInbound)
+	defer n.Traffic.Disconnected(address)
+
+	for !n.stopped() {
+		deadline := time.Now().Add(stallTimeout)
+		if !verack {
+			deadline = handshakeDeadline
+		}
+
+		var m wire.Message
+		err := conn.SetReadDeadline(deadline)
+		if err == nil {
+			_, _, limits := n.limits.current()
+
+			var size int
+			size, m, err = limits.ReadN(conn, n.Config.Network)
+			n.Traffic.Received(address, command(m), size)
+		}
+
+		if err != nil {
+			if n.stopped() {
+				return
+			}
+
+			if !verack {
+				err = handshakeError(err)
+			}
+
+			if err == ErrHandshakeTimeout {
+				n.Conformance.Record(address, AnomalyHandshake, err)
+			}
+
+			if _, ok := err.(*wire.MessageError); ok {
+				n.Conformance.Record(address, AnomalyMalformed, err)
+				continue
+			}
+
+			if err == ErrMessageLimit {
+				n.Conformance.Record(address, AnomalyOversized, err)
+			}
+
+			log.Infof("Disconnected inbound peer %v : %v", address, err)
+			return
+		}
+
+		out := []wire.Message{}
+
+		if !versioned {
+			version, ok := m.(*wire.MsgVersion)
+			if !ok {
+				n.Conformance.Record(address, AnomalyUnexpected, ErrNoVersion)
+				log.Infof("Disconnected inbound peer %v : %v", address,
+					ErrNoVersion)
+				return
+			}
+
+			if err := handshake.Check(version); err != nil {
+				n.Conformance.Record(address, AnomalyHandshake, err)
+				log.Infof("Disconnected inbound peer %v : %v", address, err)
+				return
+			}
+
+			versioned = true
+			out = append(out, n.version())
+		}
+
+		if _, ok := m.(*wire.MsgVerAck); ok {
+			verack = true
+		}
+
+		replies, err := n.dispatch(ctx, address, m)
+		if err != nil {
+			anomaly := AnomalyUnexpected
+			if isHeaderError(err) {
+				anomaly = AnomalyInvalidHeader
+			}
+
+			n.Conformance.Record(address, anomaly, err)
+
+			log.Errorf("Inbound peer %v msg = %+v : %v", address, m, err)
+		}
+
+		for _, reply := range append(out, replies...) {
+			if err := n.write(conn, limiter, reply); err != nil {
+				log.Infof("Disconnected inbound peer %v : %v", address, err)
+				return
+			}
+		}
+	}
+}
+
+// write writes a message to the connection, as fast as the RateLimiter
+// allows.
+func (n Node) write(conn net.Conn, limiter RateLimiter,
+	m wire.Message) error {
+
+	var buf bytes.Buffer
+
+	_, err := wire.WriteMessageN(&buf, m, wire.ProtocolVersion, n.Config.Network)
+	if err != nil {
+		return err
+	}
+
+	if err := limiter.Wait(n.ctx, buf.Len()); err != nil {
+		return err
+	}
+
+	size, err := conn.Write(buf.Bytes())
+	n.Traffic.Sent(conn.RemoteAddr().String(), m.Command(), size)
+
+	return err
+}
+
+// requestBodies returns the messages requesting the bodies of the blocks
+// from the StartHeight, once the header chain has been synced.
+func (n Node) requestBodies(ctx context.Context) []wire.Message {
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	hashes, err := n.BlockService.Chain(ctx, n.Config.StartHeight)
+	if err != nil {
+		log.Errorf("Failed to read the header chain : %v", err)
+		return nil
+	}
+
+	log.Infof("Headers synced, requesting %v blocks from height %v",
+		len(hashes), n.Config.StartHeight)
+
+	n.BlockService.Want(hashes)
+
+	return buildGetDataBlocks(hashes)
+}
+
+// buildGetDataBlocks returns the messages requesting the bodies of the
+// blocks, in order.
+func buildGetDataBlocks(hashes []chainhash.Hash) []wire.Message {
+	out := []wire.Message{}
+
+	for start := 0; start < len(hashes); start += maxBodiesPerRequest {
+		end := start + maxBodiesPerRequest
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+
+		getdata := wire.NewMsgGetData()
+		for i := start; i < end; i++ {
+			getdata.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, &hashes[i]))
+		}
+
+		out = append(out, getdata)
+	}
+
+	return out
+}
+
+// isStale returns true if none of the headers extend the chain, because
+// they are all known already or do not connect to a known block.
+func (n Node) isStale(ctx context.Context, m *wire.MsgHeaders) bool {
+	if len(m.Headers) == 0 || !n.BlockService.synced {
+		return false
+	}
+
+	for _, header := range m.Headers {
+		if n.BlockService.HasBlock(ctx, header.BlockHash()) {
+			continue
+		}
+
+		if _, err := n.BlockService.Read(ctx, header.PrevBlock); err == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Progress is told about each item of a long running operation, such as
+// each block of a download. Returning an error from Step stops the
+// operation.
+type Progress interface {
+	SetTotal(total int64)
+	Step(ctx context.Context, item string) error
+}
+
+// DownloadBlocks downloads the blocks from the height to the last seen
+// block from untrusted peers, verified against the trusted headers. Each
+// block is passed, in order, to the block Listener, then to the progress if
+// it isn't nil.
+//
+// Up to downloadPeers peers are used in parallel. Peers kept connected by
+// the Pool are used first, then the most recently seen peers are connected
+// to.
+func (n Node) DownloadBlocks(ctx context.Context,
+	from int32,
+	progress Progress) error {
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	hashes, err := n.BlockService.Chain(ctx, from)
+	if err != nil {
+		return err
+	}
+
+	peers, err := n.Seeder.Peers.All(ctx)
+	if err != nil {
+		return err
+	}
+
+	rankPeers(peers, conformanceReports(n.Conformance))
+
+	fetchers := []BlockFetcher{}
+	used := map[string]bool{}
+
+	pooled := n.Pool.Take(downloadPeers)
+	defer n.Pool.Release(pooled)
+
+	for _, bp := range pooled {
+		fetchers = append(fetchers, bp)
+		used[bp.Address()] = true
+	}
+
+	for _, p := range peers {
+		if len(fetchers) == downloadPeers {
+			break
+		}
+
+		if used[p.Address] || !n.Backoff.Ready(p.Address) ||
+			!NewDialer(n.Config).CanReach(p.Address) {
+			continue
+		}
+
+		config, limits, _ := n.limits.current()
+
+		bp, err := DialBlockPeer(NewDialer(n.Config), n.Config.Network,
+			limits, NewHandshake(config), n.Traffic, p.Address)
+		if err != nil {
+			if isHandshakeError(err) {
+				n.Conformance.Record(p.Address, AnomalyHandshake, err)
+			}
+
+			delay := n.Backoff.Failed(p.Address)
+			log.Warnf("Failed to connect to peer %v, retrying in %v : %v",
+				p.Address, delay, err)
+			continue
+		}
+		defer bp.Close()
+
+		n.Backoff.Succeeded(p.Address)
+		n.Conformance.RecordLatency(p.Address, bp.Latency())
+
+		fetchers = append(fetchers, bp)
+	}
+
+	log.Infof("Downloading %v blocks from %v peers", len(hashes), len(fetchers))
+
+	listener := n.Listeners[ListenerBlock]
+
+	d := NewBlockDownloader(fetchers, n.Conformance)
+
+	if filters, replaced := n.TxFilters.snapshot(); len(filters) > 0 {
+		// only download the relevant TX's of each block
+		d.Filter = NewFilterLoad(filters)
+		d.Filters = n.TxFilters
+		d.Loaded = len(filters)
+		d.Replaced = replaced
+	}
+
+	if progress != nil {
+		progress.SetTotal(int64(len(hashes)))
+	}
+
+	return d.Download(ctx, hashes, func(b *wire.MsgBlock) error {
+		if err := listener.Handle(ctx, b); err != nil {
+			return err
+		}
+
+		if progress == nil {
+			return nil
+		}
+
+		return progress.Step(ctx, b.BlockHash().String())
+	})
+}
+
+// GetMerkleProofs returns the merkle proofs of the TX's of the block, from
+// those stored with the relevant TX's of the block if they all are.
+// Otherwise they are requested from an untrusted peer, and verified against
+// the trusted header.
+//
+// A peer kept connected by the Pool is used if there is one, otherwise the
+// most recently seen peer is connected to.
+func (n Node) GetMerkleProofs(ctx context.Context,
+	blockHash chainhash.Hash,
+	txHashes []chainhash.Hash) ([]MerkleProof, error) {
+
+	if proofs := n.storedProofs(ctx, blockHash, txHashes); proofs != nil {
+		return proofs, nil
+	}
+
+	peer, done, err := n.proofPeer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	if err := peer.LoadFilter(newTxHashFilterLoad(txHashes)); err != nil {
+		return nil, err
+	}
+	defer peer.ClearFilter()
+
+	blocks, err := peer.GetFilteredBlocks([]chainhash.Hash{blockHash})
+	if err != nil {
+		if err == ErrMessageLimit {
+			n.Conformance.Record(peer.Address(), AnomalyOversized, err)
+		}
+
+		return nil, err
+	}
+
+	proofs := []MerkleProof{}
+
+	for _, txHash := range txHashes {
+		p, err := NewMerkleProofFromMerkleBlock(blocks[0].MerkleBlock, txHash)
+		if err == nil {
+			_, err = n.BlockService.VerifyMerkleProof(ctx, *p)
+		}
+
+		if err != nil {
+			if err == ErrMerkleProof || err == ErrPartialMerkleTree {
+				n.Conformance.Record(peer.Address(), AnomalyMalformed, err)
+			}
+
+			return nil, err
+		}
+
+		proofs = append(proofs, *p)
+	}
+
+	return proofs, nil
+}
+
+// storedProofs returns the stored merkle proofs of the TX's of the block,
+// or nil unless they are all stored.
+func (n Node) storedProofs(ctx context.Context,
+	blockHash chainhash.Hash,
+	txHashes []chainhash.Hash) []MerkleProof {
+
+	proofs := []MerkleProof{}
+
+	for _, txHash := range txHashes {
+		p, err := n.BlockService.ReadProof(ctx, blockHash, txHash)
+		if err != nil {
+			return nil
+		}
+
+		proofs = append(proofs, *p)
+	}
+
+	return proofs
+}
+
+// GetTX returns the TX with the hash, from the Mempool, or the stored
+// bodies, or relevant TX's, of the blocks with relevant TX's. Otherwise it is requested from
+// the untrusted peers, until one sends it, the txTimeout of each passes, or
+// the Context is done.
+//
+// Peers only serve unconfirmed TX's, so other confirmed TX's are not found.
+func (n Node) GetTX(ctx context.Context,
+	hash *chainhash.Hash) (*wire.MsgTx, error) {
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	if tx := n.Mempool.Get(*hash); tx != nil {
+		return tx, nil
+	}
+
+	tx, err := n.BlockService.ReadTx(ctx, *hash)
+	if err != ErrTxNotFound {
+		return tx, err
+	}
+
+	peers := n.Pool.Take(downloadPeers)
+	defer n.Pool.Release(peers)
+
+	if len(peers) == 0 {
+		peer, done, err := n.proofPeer(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+
+		peers = append(peers, peer)
+	}
+
+	for _, peer := range peers {
+		timeout := txTimeout
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+			timeout = time.Until(deadline)
+		}
+
+		if ctx.Err() != nil || timeout <= 0 {
+			return nil, ErrTxNotFound
+		}
+
+		tx, err := peer.GetTX(*hash, timeout)
+		if err == nil {
+			return tx, nil
+		}
+
+		if err == ErrMessageLimit {
+			n.Conformance.Record(peer.Address(), AnomalyOversized, err)
+		}
+
+		if err != ErrTxNotServed {
+			log.Warnf("Failed to get TX %v from peer %v : %v", hash,
+				peer.Address(), err)
+		}
+	}
+
+	return nil, ErrTxNotFound
+}
+
+// GetBlock returns the full block at the height of the best chain. See
+// GetBlockByHash.
+func (n Node) GetBlock(ctx context.Context, height int32) (*wire.MsgBlock, error) {
+	block, err := n.BlockService.Ancestor(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := chainhash.NewHashFromStr(block.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.GetBlockByHash(ctx, *hash)
+}
+
+// GetBlockByHash returns the full block with the hash, such as to reprocess
+// the TX's of a historical block. The stored body is returned if there is
+// one, otherwise the block is downloaded from an untrusted peer and checked
+// against its header, without being stored.
+//
+// ErrBlockNotFound is returned if the header is not known, and
+// ErrBlockBodyNotFound if no peer served the block.
+func (n Node) GetBlockByHash(ctx context.Context,
+	hash chainhash.Hash) (*wire.MsgBlock, error) {
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	if _, err := n.BlockService.Read(ctx, hash); err != nil {
+		return nil, err
+	}
+
+	body, err := n.BlockService.ReadBody(ctx, hash)
+	if err != ErrBlockBodyNotFound {
+		return body, err
+	}
+
+	peers := n.Pool.Take(downloadPeers)
+	defer n.Pool.Release(peers)
+
+	if len(peers) == 0 {
+		peer, done, err := n.proofPeer(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+
+		peers = append(peers, peer)
+	}
+
+	hashes := []chainhash.Hash{hash}
+
+	for _, peer := range peers {
+		if ctx.Err() != nil {
+			break
+		}
+
+		blocks, err := peer.GetBlocks(hashes)
+		if err == nil {
+			err = verifyBlocks(hashes, blocks)
+		}
+
+		if err == nil {
+			n.Conformance.Received(peer.Address())
+			return blocks[0], nil
+		}
+
+		if err == ErrBlockHash || err == ErrMerkleRoot {
+			n.Conformance.Record(peer.Address(), AnomalyMalformed, err)
+		} else if err == ErrMessageLimit {
+			n.Conformance.Record(peer.Address(), AnomalyOversized, err)
+		}
+
+		log.Warnf("Failed to get block %v from peer %v : %v", hash,
+			peer.Address(), err)
+	}
+
+	return nil, ErrBlockBodyNotFound
+}
+
+// Reindex scans the stored block bodies against the TxFilters, and records
+// their relevant TX's again, such as after a filter is added. It may be
+// called while the Node is running, in which case the messages of peers
+// wait for it.
+func (n Node) Reindex(ctx context.Context) (ReindexStats, error) {
+	n.handleLock.Lock()
+	defer n.handleLock.Unlock()
+
+	return n.BlockService.Reindex(ctx, n.TxFilters.All())
+}
+
+// proofPeer returns an untrusted peer to request merkle proofs from, and
+// the func to call once it is used, which gives it back to the Pool or
+// closes it.
+func (n Node) proofPeer(ctx context.Context) (*BlockPeer, func(), error) {
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	if pooled := n.Pool.Take(1); len(pooled) > 0 {
+		return pooled[0], func() { n.Pool.Release(pooled) }, nil
+	}
+
+	peers, err := n.Seeder.Peers.All(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rankPeers(peers, conformanceReports(n.Conformance))
+
+	for _, p := range peers {
+		if !n.Backoff.Ready(p.Address) || !NewDialer(n.Config).CanReach(p.Address) {
+			continue
+		}
+
+		config, limits, _ := n.limits.current()
+
+		bp, err := DialBlockPeer(NewDialer(n.Config), n.Config.Network,
+			limits, NewHandshake(config), n.Traffic, p.Address)
+		if err != nil {
+			if isHandshakeError(err) {
+				n.Conformance.Record(p.Address, AnomalyHandshake, err)
+			}
+
+			delay := n.Backoff.Failed(p.Address)
+			log.Warnf("Failed to connect to peer %v, retrying in %v : %v",
+				p.Address, delay, err)
+			continue
+		}
+
+		n.Backoff.Succeeded(p.Address)
+		n.Conformance.RecordLatency(p.Address, bp.Latency())
+
+		return bp, func() { bp.Close() }, nil
+	}
+
+	return nil, nil, ErrNoPeers
+}
+
+// PooledPeers returns the untrusted peers kept connected by the Pool.
+func (n Node) PooledPeers() []PooledPeer {
+	return n.Pool.Status()
+}
+
+// GetHeader returns the stored header of the block at the height of the
+// best chain.
+func (n Node) GetHeader(ctx context.Context, height int32) (*Block, error) {
+	return n.BlockService.Ancestor(ctx, height)
+}
+
+// GetHeight returns the height of the block, if it is on the best chain,
+// or ErrNotInChain if it isn't.
+func (n Node) GetHeight(ctx context.Context,
+	hash chainhash.Hash) (int32, error) {
+
+	return n.BlockService.Height(ctx, hash)
+}
+
+// WalkHeaders calls the func with each stored header of the best chain,
+// from the height in the direction, until it returns false or the chain
+// ends. See BlockService.WalkHeaders.
+func (n Node) WalkHeaders(ctx context.Context, from int32,
+	direction Direction, fn HeaderFunc) error {
+
+	return n.BlockService.WalkHeaders(ctx, from, direction, fn)
+}
+
+// BestHeight returns the height of the last seen block, or 0 if there is
+// none.
+func (n Node) BestHeight() int32 {
+	if n.BlockService.State == nil {
+		return 0
+	}
+
+	return n.BlockService.State.LastSeen.Height
+}
+
+// LocatorHashes returns the hashes of a block locator of the best chain,
+// most recent first, ending with the genesis block of the network.
+func (n Node) LocatorHashes(ctx context.Context) ([]chainhash.Hash, error) {
+	hashes, err := n.BlockService.Locator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	genesis, ok := NetworkGenesis[n.Config.Network]
+	if ok && (len(hashes) == 0 || hashes[len(hashes)-1] != genesis) {
+		hashes = append(hashes, genesis)
+	}
+
+	return hashes, nil
+}
+
+// Status returns a report of the state of the Node, its connections, and
+// the TX's it holds.
+func (n *Node) Status() NodeStatus {
+	status := NodeStatus{
+		Synced:            n.BlockService.synced,
+		BestHeight:        n.BestHeight(),
+		TrustedNode:       n.Config.NodeAddress,
+		Connected:         n.peerConn() != nil,
+		Peers:             n.Pool.Connected(),
+		Mempool:           n.Mempool.Stats(),
+		PendingBroadcasts: n.Rebroadcaster.Len(),
+		TrackedBroadcasts: len(n.Tracker.Tracked()),
+		TxsInFlight:       n.TxRequests.InFlight(),
+		Orphans:           n.Orphans.Len(),
+	}
+
+	n.inboundLock.Lock()
+	status.Inbound = len(n.inbound)
+	n.inboundLock.Unlock()
+
+	n.state.fill(&status)
+
+	return status
+}
+
+// ConformanceReport returns the protocol anomalies seen from each peer.
+func (n Node) ConformanceReport() []ConformanceReport {
+	return n.Conformance.Report()
+}
+
+// TrafficReport returns the traffic with each connected peer, including the
+// trusted node and inbound peers.
+func (n Node) TrafficReport() []PeerTraffic {
+	return n.Traffic.Report()
+}
+
+// loadMempool adds the TX's of the Mempool saved when the Node last
+// stopped.
+func (n Node) loadMempool(ctx context.Context) error {
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	txs, err := n.mempoolRepo.Read(ctx)
+	if err == ErrMempoolNotFound {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := n.Mempool.Import(txs); err != nil {
+		return err
+	}
+
+	log.Infof("Loaded %v mempool TX's", len(txs))
+
+	return nil
+}
+
+// saveMempool saves the relevant TX's of the Mempool, to be loaded when
+// the Node next starts. Failures are logged, as the Node is stopping.
+func (n Node) saveMempool(ctx context.Context) {
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	txs, err := n.Mempool.Export(n.TxFilters.All())
+	if err != nil {
+		log.Errorf("Failed to save mempool : %v", err)
+		return
+	}
+
+	if err := n.mempoolRepo.Write(ctx, txs); err != nil {
+		log.Errorf("Failed to save mempool : %v", err)
+		return
+	}
+
+	log.Infof("Saved %v mempool TX's", len(txs))
+}
+
+// sweepMempool removes the TX's that have been in the Mempool longer than
+// the MempoolExpiry, and passes them to the TX Listener. It runs until the
+// Node is stopped.
+func (n Node) sweepMempool() {
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-time.After(mempoolSweepInterval):
+		}
+
+		expired := n.Mempool.Expire(time.Now().Add(-n.Config.MempoolExpiry))
+		if len(expired) == 0 {
+			continue
+		}
+
+		ctx := logger.NewContext()
+		log := logger.NewLoggerFromContext(ctx).Sugar()
+		log.Infof("Expired %v unconfirmed TX's from the mempool", len(expired))
+
+		// they are passed on again if they are seen again
+		n.SeenTxs.Forget(expired)
+
+		listener := n.Workers.Listener(n.Listeners[ListenerTX])
+		if err := listener.HandleExpired(ctx, expired); err != nil {
+			log.Error(err)
+		}
+	}
+}
+
+// MempoolStats returns the size of the Mempool, and the TX's evicted from
+// it.
+func (n Node) MempoolStats() MempoolStats {
+	return n.Mempool.Stats()
+}
+
+// MedianFeeRate returns the median fee rate of the TX's in the Mempool, in
+// satoshis per byte, or 0 if the fee of none of them is known.
+func (n Node) MedianFeeRate() float64 {
+	return n.Mempool.MedianFeeRate()
+}
+
+// EntriesAboveRate returns the TX's in the Mempool with a fee rate of at
+// least the rate, in satoshis per byte, highest first.
+func (n Node) EntriesAboveRate(rate float64) []MempoolEntry {
+	return n.Mempool.EntriesAboveRate(rate)
+}
+
+// SetInputValuer sets how the values of the outputs spent by TX's are
+// fetched, when they aren't in the Mempool. It must be called before Start.
+func (n *Node) SetInputValuer(valuer InputValuer) {
+	n.Mempool.Valuer = valuer
+}
+
+// BroadcastAndWait sends the TX to the trusted node, and the untrusted
+// peers of the PeerPool, then tracks it until it has the confirmations or
+// is double spent.
+//
+// The returned channel receives the result, then is closed. It is closed
+// without a result if the Context is done first.
+func (n *Node) BroadcastAndWait(ctx context.Context,
+	tx *wire.MsgTx,
+	confirmations int) (<-chan BroadcastResult, error) {
+
+	// tracked before it is sent, so a quick double spend isn't missed
+	result := n.Tracker.Track(ctx, tx, confirmations)
+
+	if n.broadcast(ctx, tx) == 0 {
+		n.Tracker.Untrack(tx.TxHash())
+		return nil, ErrNotConnected
+	}
+
+	if err := n.Rebroadcast(tx); err != nil {
+		log := logger.NewLoggerFromContext(ctx).Sugar()
+		log.Warnf("TX %v won't be rebroadcast : %v", tx.TxHash(), err)
+	}
+
+	return result, nil
+}
+
+// BroadcastAsync sends the TX to the trusted node, and the untrusted peers
+// of the PeerPool, without waiting for the sends.
+//
+// The returned channel receives a BroadcastSent event for each peer, a
+// BroadcastRejected event for each reject message, then a BroadcastAccepted
+// event once the TX is relayed back or confirmed, and is closed. If the TX
+// couldn't be sent to any peer, it receives a BroadcastSent event with
+// ErrNotConnected and is closed. It is closed early if the Context is
+// done.
+func (n *Node) BroadcastAsync(ctx context.Context,
+	tx *wire.MsgTx) <-chan BroadcastEvent {
+
+	hash := tx.TxHash()
+
+	// watched before it is sent, so a quick reject isn't missed
+	events := n.Tracker.Watch(ctx, hash)
+
+	go func() {
+		if n.broadcast(ctx, tx) == 0 {
+			n.Tracker.Unsent(hash, ErrNotConnected)
+			return
+		}
+
+		if err := n.Rebroadcast(tx); err != nil {
+			log := logger.NewLoggerFromContext(ctx).Sugar()
+			log.Warnf("TX %v won't be rebroadcast : %v", hash, err)
+		}
+	}()
+
+	return events
+}
+
+// Rebroadcast keeps a TX that was broadcast, such as through an RPC node, to
+// be broadcast again if it isn't confirmed within the RebroadcastInterval.
+// It does nothing if the interval is 0.
+func (n Node) Rebroadcast(tx *wire.MsgTx) error {
+	if n.Config.RebroadcastInterval <= 0 {
+		return nil
+	}
+
+	return n.Rebroadcaster.Add(tx)
+}
+
+// broadcast sends the TX to the trusted node, and the untrusted peers of
+// the PeerPool, and returns how many it was sent to. Each send is passed on
+// to the watch of the TX, if it is watched.
+//
+// The feefilters of the untrusted peers are honored, so the TX isn't sent
+// to those that asked for a higher fee rate, if its fee is known. It is
+// always sent to the trusted node.
+func (n *Node) broadcast(ctx context.Context, tx *wire.MsgTx) int {
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	hash := tx.TxHash()
+	fee := n.Mempool.fee(tx)
+
+	sent := 0
+
+	if n.peerConn() != nil {
+		err := n.Queue(ctx, tx)
+		n.Tracker.Sent(hash, n.Config.NodeAddress, err)
+
+		if err != nil {
+			log.Warnf("Failed to send TX %v to the trusted node : %v",
+				hash, err)
+		} else {
+			sent++
+		}
+	}
+
+	peers := n.Pool.Take(len(n.Pool.Connected()))
+
+	for _, peer := range peers {
+		if fee >= 0 && fee*1000 < peer.FeeFilter()*int64(tx.SerializeSize()) {
+			log.Infof("Not sending TX %v to peer %v below its fee filter %v",
+				hash, peer.Address(), peer.FeeFilter())
+			n.Tracker.Sent(hash, peer.Address(), ErrBelowFeeFilter)
+			continue
+		}
+
+		err := peer.send(tx)
+		n.Tracker.Sent(hash, peer.Address(), err)
+
+		if err != nil {
+			log.Warnf("Failed to send TX %v to peer %v : %v", hash,
+				peer.Address(), err)
+			continue
+		}
+
+		sent++
+	}
+
+	n.Pool.Release(peers)
+
+	return sent
+}
+
+// rebroadcast sends the TX's of the Rebroadcaster again as they are due,
+// until the Node is stopped.
+func (n *Node) rebroadcast() {
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-time.After(rebroadcastCheckInterval):
+		}
+
+		ctx := logger.NewContext()
+		log := logger.NewLoggerFromContext(ctx).Sugar()
+
+		due, dropped := n.Rebroadcaster.Due()
+
+		for _, hash := range dropped {
+			log.Warnf("Gave up rebroadcasting unconfirmed TX %v", hash)
+		}
+
+		for _, tx := range due {
+			log.Infof("Rebroadcasting unconfirmed TX %v", tx.TxHash())
+
+			if n.broadcast(ctx, tx) == 0 {
+				log.Warnf("Failed to rebroadcast TX %v : %v", tx.TxHash(),
+					ErrNotConnected)
+			}
+		}
+	}
+}
+
+// AddTxFilter adds a filter of the TX's that are relevant to the Listeners.
+//
+// Blocks downloaded from untrusted peers only hold the TX's that match a
+// filter. It is safe to call while the Node is running, in which case the
+// filter is added to the bloom filters of the peers blocks are being
+// downloaded from, so it matches without reconnecting to them.
+func (n *Node) AddTxFilter(f TxFilter) {
+	n.TxFilters.Add(f)
+}
+
+// Reload applies the changed TxFilters, peers and limits of the Config to
+// the running Node, without restarting it.
+//
+// The TxFilters replace those of the Node, including those added with
+// AddTxFilter, and the bloom filters of the peers blocks are being
+// downloaded from are reloaded before their next range. The TX's in the
+// Mempool that are no longer relevant are removed, unless the Mempool keeps
+// every TX for compact blocks.
+//
+// The limits of the messages received from the trusted node and the
+// inbound peers, the most inbound peers, and the rates messages are sent to
+// them at, are replaced, as is the handshake of the peers connected to from
+// then on. The target, pinned peers and rotation of the PeerPool are
+// replaced, and the peers it connects to from then on take the new limits
+// and handshake, as do the peers blocks and proofs are downloaded from. The
+// limits of the Mempool are replaced. The rest of the Config, and whether
+// the PeerPool runs at all, only change when the Node is restarted.
+func (n *Node) Reload(config Config) {
+	ctx := logger.NewContext()
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	n.TxFilters.Replace(config.TxFilters...)
+
+	// with compact blocks every TX is kept
+	if !n.Config.CompactBlocks && !n.Config.HeadersOnly {
+		removed := n.Mempool.Retain([]TxFilter{n.TxFilters})
+		if len(removed) > 0 {
+			log.Infof("Removed %v mempool TX's no longer relevant", len(removed))
+		}
+	}
+
+	n.limits.set(config)
+
+	n.Pool.Reload(config.Peers, config.MaxPeersPerGroup, config.PinnedPeers,
+		NewMessageLimits(config), NewHandshake(config))
+	n.Pool.SetRotation(config.PeerRotationInterval, config.PeerRotationCount)
+
+	n.Limiter.SetRates(config.SendMessagesPerSecond, config.SendBytesPerSecond)
+	n.setInboundRates(config.SendMessagesPerSecond, config.SendBytesPerSecond)
+	n.Mempool.SetLimits(config.MempoolMaxTxs, config.MempoolMaxBytes)
+
+	log.Infof("Reloaded config with %v TX filters", len(config.TxFilters))
+}
+
+// RegisterListener adds the Listener of the TX's, or the blocks, by the
+// name. It panics if the name is neither ListenerTX nor ListenerBlock.
+func (n *Node) RegisterListener(name string, listener Listener) {
+	if _, err := n.AddListener(name, listener); err != nil {
+		panic(err)
+	}
+}
+
+// AddListener adds the Listener of the TX's, or the blocks, by the name,
+// and returns the ID to remove it with. It is safe to call while the Node
+// is running.
+func (n Node) AddListener(name string, listener Listener) (ListenerID, error) {
+	set, ok := n.Listeners[name]
+	if !ok {
+		return 0, ErrUnknownListener
+	}
+
+	return set.Add(listener), nil
+}
+
+// AddListenerFrom adds the Listener like AddListener, after passing it the
+// events from the height of the best chain forward, so a Listener added
+// late catches up without a separate indexing pass.
+//
+// The block Listener is passed each block from the height. The TX Listener
+// is passed the relevant TX's of those blocks, then the relevant TX's of
+// the Mempool. The stored bodies are used where they are kept, and the
+// blocks with relevant TX's are downloaded from peers where they aren't.
+// Blocks without relevant TX's whose bodies are not stored are skipped.
+//
+// The messages of peers wait until the Listener is added, so it doesn't
+// miss or repeat an event.
+func (n Node) AddListenerFrom(ctx context.Context, name string,
+	listener Listener, from int32) (ListenerID, error) {
+
+	set, ok := n.Listeners[name]
+	if !ok {
+		return 0, ErrUnknownListener
+	}
+
+	n.handleLock.Lock()
+	defer n.handleLock.Unlock()
+
+	var err error
+	walked := n.BlockService.WalkHeaders(ctx, from, Ascending,
+		func(block Block) bool {
+			err = n.replayBlock(ctx, name, listener, block)
+			return err == nil
+		})
+	if walked != nil {
+		return 0, walked
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if name == ListenerTX {
+		if err := n.replayMempool(ctx, listener); err != nil {
+			return 0, err
+		}
+	}
+
+	return set.Add(listener), nil
+}
+
+// replayBlock passes the block, or its relevant TX's, to the Listener by
+// the name.
+func (n Node) replayBlock(ctx context.Context, name string,
+	listener Listener, block Block) error {
+
+	if !block.Body && len(block.TxHashes) == 0 {
+		// the body is gone, and had nothing relevant
+		return nil
+	}
+
+	if name == ListenerTX && len(block.TxHashes) == 0 {
+		return nil
+	}
+
+	hash, err := chainhash.NewHashFromStr(block.Hash)
+	if err != nil {
+		return err
+	}
+
+	body, err := n.GetBlockByHash(ctx, *hash)
+	if err != nil {
+		return err
+	}
+
+	if name == ListenerBlock {
+		return listener.Handle(ctx, body)
+	}
+
+	for _, tx := range body.Transactions {
+		if !hasTxHash(block, tx.TxHash().String()) {
+			continue
+		}
+
+		if err := listener.Handle(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replayMempool passes the relevant TX's of the Mempool to the Listener,
+// oldest first.
+func (n Node) replayMempool(ctx context.Context, listener Listener) error {
+	txs, err := n.Mempool.Export(n.TxFilters.All())
+	if err != nil {
+		return err
+	}
+
+	for _, mtx := range txs {
+		tx := wire.MsgTx{}
+		if err := tx.Deserialize(bytes.NewReader(mtx.Tx)); err != nil {
+			return err
+		}
+
+		if err := listener.Handle(ctx, &tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveListener removes the Listener with the ID, that was added by the
+// name. It is safe to call while the Node is running.
+func (n Node) RemoveListener(name string, id ListenerID) error {
+	set, ok := n.Listeners[name]
+	if !ok || !set.Remove(id) {
+		return ErrUnknownListener
+	}
+
+	return nil
+}
+
+// handshake starts the handshake process.
+//
+// Sending a version message to the peer will fire off is enough as the
+// process is event driven, and this node responds appropriately to the
+// responses.
+//
+// The version handshake look like this.
+//
+// L -> R: Send version message with the local peer's version
+// R -> L: Send version message back
+// R -> L: Send verack message
+// R:      Sets version to the minimum of the 2 versions
+// L -> R: Send verack message after receiving version message from R
+// L:      Sets version to the minimum of the 2 versions
+func (n Node) handshake() error {
+	ctx := logger.NewContext()
+
+	return n.Queue(ctx, n.version())
+}
+
+// version returns the version message of the Node, which starts the
+// handshake with a peer.
+func (n Node) version() *wire.MsgVersion {
+	// my local. This doesn't matter, as peers don't connect back to it.
+	local := wire.NewNetAddressIPPort(net.IPv4(127, 0, 0, 1), 9333, 0)
+
+	// build the address of the remote
+	remote := wire.NewNetAddressIPPort(net.IPv4(127, 0, 0, 1), 8333, 0)
+
+	lastSeen := n.BlockService.State.LastSeen
+	msg := wire.NewMsgVersion(remote, local, n.nonce(), lastSeen.Height)
+	config, _, _ := n.limits.current()
+	h := NewHandshake(config)
+	msg.UserAgent = h.UserAgent
+	msg.Services = h.Services
+
+	// no TX's are relayed without a Mempool
+	msg.DisableRelayTx = h.DisableRelay
+
+	return msg
+}
+
+// Queue puts the message on a queue for async delivery.
+func (n Node) Queue(ctx context.Context, msg wire.Message) error {
+	go func() {
+		select {
+		case n.messages <- msg:
+		case <-n.ctx.Done():
+			// the message is dropped, as the Node is stopped
+		}
+	}()
+
+	return nil
 }
 
 // readChannel receives messages from the channel.
 //
-// This is a blocking function that will run forever, so it should be run
-// in a goroutine.
-func (n Node) readChannel() {
+// This is a blocking function that runs until the Node is stopped, so it
+// should be run in a goroutine.
+func (n *Node) readChannel() {
 	for {
 		ctx := logger.NewContext()
 		log := logger.NewLoggerFromContext(ctx).Sugar()
 
 		// read from the channel
-		m, ok := <-n.messages
+		var m wire.Message
+		var ok bool
+
+		select {
+		case <-n.ctx.Done():
+			return
+		case m, ok = <-n.messages:
+		}
 
 		if !ok {
 			log.Errorf("Failed reading from channel")
@@ -270,19 +2127,30 @@ func (n Node) readChannel() {
 }
 
 // sendAsync writes a message to a peer.
-func (n Node) sendAsync(ctx context.Context, m wire.Message) error {
+func (n *Node) sendAsync(ctx context.Context, m wire.Message) error {
 	var buf bytes.Buffer
 
 	// build the message to send
-	_, err := wire.WriteMessageN(&buf, m, wire.ProtocolVersion, MainNetBch)
+	_, err := wire.WriteMessageN(&buf, m, wire.ProtocolVersion, n.Config.Network)
 	if err != nil {
 		return err
 	}
 
 	b := buf.Bytes()
 
+	// a slow connection is not sent more than it can take
+	if err := n.Limiter.Wait(n.ctx, len(b)); err != nil {
+		return err
+	}
+
+	conn := n.peerConn()
+	if conn == nil {
+		return ErrNotConnected
+	}
+
 	// send the message to the remote
-	_, err = n.conn.Write(b)
+	size, err := conn.Write(b)
+	n.Traffic.Sent(n.Config.NodeAddress, m.Command(), size)
 	if err != nil {
 		return err
 	}
@@ -290,10 +2158,6 @@ func (n Node) sendAsync(ctx context.Context, m wire.Message) error {
 	return nil
 }
 
-func (n Node) buildUserAgent() string {
-	return fmt.Sprintf("%v", n.Config.UserAgent)
-}
-
 func (n Node) nonce() uint64 {
 	buf := make([]byte, 8)
 	rand.Read(buf)
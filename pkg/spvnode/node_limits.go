@@ -0,0 +1,60 @@
+package spvnode
+
+import (
+	"sync"
+)
+
+// nodeLimits are the parts of the Config of a running Node that Reload
+// changes: the limits of the messages received, the inbound peers
+// accepted, the rates messages are sent at, and the handshake with peers.
+//
+// It is shared by the copies of the Node, so a reload reaches each of its
+// connections.
+type nodeLimits struct {
+	mu *sync.Mutex
+
+	config  Config
+	limits  MessageLimits
+	inbound MessageLimits
+}
+
+// newNodeLimits returns the nodeLimits of the Config.
+func newNodeLimits(config Config) nodeLimits {
+	l := nodeLimits{
+		mu: &sync.Mutex{},
+	}
+
+	l.set(config)
+
+	return l
+}
+
+// set replaces the limits with those of the Config.
+func (l *nodeLimits) set(config Config) {
+	limits := NewMessageLimits(config)
+
+	inbound := limits
+	if config.MaxInboundMessageSize > 0 {
+		inbound.MaxPayload = uint32(config.MaxInboundMessageSize)
+	}
+
+	if config.MaxInbound == 0 {
+		config.MaxInbound = defaultMaxInbound
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.config = config
+	l.limits = limits
+	l.inbound = inbound
+}
+
+// current returns the Config, and the limits of the messages received from
+// the trusted node and outbound peers, and from inbound peers.
+func (l *nodeLimits) current() (Config, MessageLimits, MessageLimits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.config, l.limits, l.inbound
+}
@@ -0,0 +1,99 @@
+package spvnode
+
+import (
+	"sync"
+	"time"
+)
+
+// NodeStatus is a report of the state of a Node, such as for a health check
+// or an admin tool.
+type NodeStatus struct {
+	// Synced is true once the header chain has caught up with the trusted
+	// node.
+	Synced bool
+
+	// BestHeight is the height of the last seen block. TrustedHeight is the
+	// height of the chain of the trusted node, as announced in its version
+	// message, or the BestHeight if it is higher.
+	BestHeight    int32
+	TrustedHeight int32
+
+	// TrustedNode is the address of the trusted node, and Connected is true
+	// while there is a connection to it.
+	TrustedNode string
+	Connected   bool
+
+	// Peers are the addresses of the untrusted peers kept connected by the
+	// Pool, and Inbound is the number of inbound peers connected.
+	Peers   []string
+	Inbound int
+
+	Mempool MempoolStats
+
+	// PendingBroadcasts is the number of TX's broadcast by the Node that
+	// are not yet confirmed, and TrackedBroadcasts is the number of those
+	// that a caller is waiting on.
+	PendingBroadcasts int
+	TrackedBroadcasts int
+
+	// TxsInFlight is the number of TX's requested from the trusted node and
+	// not yet received, and Orphans is the number of TX's waiting for their
+	// parents.
+	TxsInFlight int
+	Orphans     int
+
+	// LastError is the last error reading or handling the messages of the
+	// trusted node, and LastErrorAt is when it happened, in nanoseconds
+	// since the epoch. They are empty if there has been none.
+	LastError   string
+	LastErrorAt int64
+}
+
+// nodeState is the state of the trusted node connection that is only kept
+// for the NodeStatus. It is safe for concurrent use.
+type nodeState struct {
+	mu *sync.Mutex
+
+	trustedHeight int32
+	lastError     string
+	lastErrorAt   int64
+}
+
+// newNodeState returns a new, empty, nodeState.
+func newNodeState() nodeState {
+	return nodeState{
+		mu: &sync.Mutex{},
+	}
+}
+
+// announced records the height of the chain of the trusted node, from its
+// version message.
+func (s *nodeState) announced(height int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.trustedHeight = height
+}
+
+// failed records an error of the trusted node.
+func (s *nodeState) failed(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastError = err.Error()
+	s.lastErrorAt = time.Now().UnixNano()
+}
+
+// fill sets the fields of the status that are kept by the nodeState.
+func (s *nodeState) fill(status *NodeStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status.TrustedHeight = s.trustedHeight
+	if status.BestHeight > status.TrustedHeight {
+		status.TrustedHeight = status.BestHeight
+	}
+
+	status.LastError = s.lastError
+	status.LastErrorAt = s.lastErrorAt
+}
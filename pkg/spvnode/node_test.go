@@ -0,0 +1,357 @@
+package spvnode
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+// newTestNode returns a Node of the Config on an empty store, that doesn't
+// look up the DNS seeds.
+func newTestNode(config Config) Node {
+	n := NewNode(config, memoryStorage{})
+	n.BlockService.State = &State{}
+	n.Seeder.Lookup = func(string) ([]string, error) {
+		return nil, errors.New("offline")
+	}
+
+	return n
+}
+
+// listen returns a listener on a free local port.
+func listen(t *testing.T) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ln
+}
+
+// inboundPeer is the connection of a peer to the listener of a Node.
+type inboundPeer struct {
+	t    *testing.T
+	conn net.Conn
+	net  wire.BitcoinNet
+}
+
+func dialInbound(t *testing.T, n Node, ln net.Listener) inboundPeer {
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return inboundPeer{
+		t:    t,
+		conn: conn,
+		net:  n.Config.Network,
+	}
+}
+
+func (p inboundPeer) send(m wire.Message) {
+	if err := wire.WriteMessage(p.conn, m, wire.ProtocolVersion, p.net); err != nil {
+		p.t.Fatal(err)
+	}
+}
+
+// read returns the next message from the Node, or the error the connection
+// was closed with.
+func (p inboundPeer) read() (wire.Message, error) {
+	p.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	m, _, err := wire.ReadMessage(p.conn, wire.ProtocolVersion, p.net)
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		p.t.Fatal("connection not closed")
+	}
+
+	return m, err
+}
+
+// version sends the version of the peer, and checks the Node answers with
+// its own.
+func (p inboundPeer) version() {
+	local := wire.NewNetAddressIPPort(net.IPv4(127, 0, 0, 1), 9333, 0)
+	p.send(wire.NewMsgVersion(local, local, 1, 0))
+
+	m, err := p.read()
+	if err != nil {
+		p.t.Fatal(err)
+	}
+
+	if _, ok := m.(*wire.MsgVersion); !ok {
+		p.t.Fatalf("got %v, want version", m.Command())
+	}
+}
+
+// closed checks that the Node closed the connection.
+func (p inboundPeer) closed() {
+	if m, err := p.read(); err == nil {
+		p.t.Fatalf("got %v, want the connection closed", m.Command())
+	}
+}
+
+func TestNode_acceptPeers(t *testing.T) {
+	n := newTestNode(Config{})
+
+	ln := listen(t)
+
+	stopped := make(chan struct{})
+	go func() {
+		n.acceptPeers(ln)
+		close(stopped)
+	}()
+
+	peer := dialInbound(t, n, ln)
+	peer.version()
+	peer.send(wire.NewMsgVerAck())
+
+	// a message before the version disconnects the peer
+	early := dialInbound(t, n, ln)
+	early.send(wire.NewMsgVerAck())
+	early.closed()
+
+	// stopping closes the listener and the connections
+	n.Stop()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("acceptPeers did not return")
+	}
+
+	peer.closed()
+
+	if _, err := net.Dial("tcp", ln.Addr().String()); err == nil {
+		t.Fatal("listener not closed")
+	}
+}
+
+func TestNode_acceptPeers_maxInbound(t *testing.T) {
+	n := newTestNode(Config{
+		MaxInbound: 1,
+	})
+	defer n.Stop()
+
+	ln := listen(t)
+	go n.acceptPeers(ln)
+
+	first := dialInbound(t, n, ln)
+	first.version()
+
+	// the second peer is over the limit
+	second := dialInbound(t, n, ln)
+	second.closed()
+
+	// the first leaving makes room
+	first.conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n.inboundLock.Lock()
+		connected := len(n.inbound)
+		n.inboundLock.Unlock()
+
+		if connected == 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("first peer not removed")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	third := dialInbound(t, n, ln)
+	third.version()
+}
+
+// TestNode_Start_listenFailure tests that Start returns the error of a
+// listen address in use, having closed the connection to the trusted node.
+func TestNode_Start_listenFailure(t *testing.T) {
+	trusted := listen(t)
+	defer trusted.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := trusted.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	busy := listen(t)
+	defer busy.Close()
+
+	n := newTestNode(Config{
+		NodeAddress: trusted.Addr().String(),
+		Listen:      busy.Addr().String(),
+	})
+
+	if err := n.Start(); err == nil {
+		t.Fatal("got no error listening on an address in use")
+	}
+
+	var conn net.Conn
+	select {
+	case conn = <-accepted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("trusted node not connected")
+	}
+
+	// nothing is sent before the listener, and the connection is closed
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	b := make([]byte, 1)
+	_, err := conn.Read(b)
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		t.Fatal("trusted node connection not closed")
+	}
+
+	if err == nil {
+		t.Fatal("got a message before listening")
+	}
+}
+
+// TestNode_acceptPeers_oversized tests that an inbound peer sending a
+// message over the inbound limits is disconnected.
+func TestNode_acceptPeers_oversized(t *testing.T) {
+	n := newTestNode(Config{
+		MaxInboundMessageSize: 200,
+	})
+	defer n.Stop()
+
+	ln := listen(t)
+	go n.acceptPeers(ln)
+
+	peer := dialInbound(t, n, ln)
+	peer.version()
+	peer.send(wire.NewMsgVerAck())
+	peer.send(newInv(10))
+	peer.closed()
+}
+
+// TestNode_Reload_limits tests that reloaded limits and send rates reach
+// the connections made before the reload.
+func TestNode_Reload_limits(t *testing.T) {
+	n := newTestNode(Config{})
+	defer n.Stop()
+
+	ln := listen(t)
+	go n.acceptPeers(ln)
+
+	peer := dialInbound(t, n, ln)
+	peer.version()
+	peer.send(wire.NewMsgVerAck())
+
+	n.Reload(Config{
+		MaxInboundMessageSize: 200,
+		SendMessagesPerSecond: 5,
+		SendBytesPerSecond:    1000,
+	})
+
+	n.inboundLock.Lock()
+	for _, limiter := range n.inbound {
+		if limiter.messages.rate != 5 || limiter.bytes.rate != 1000 {
+			t.Fatalf("got inbound rates %v %v, want 5 1000",
+				limiter.messages.rate, limiter.bytes.rate)
+		}
+	}
+	n.inboundLock.Unlock()
+
+	if n.Limiter.messages.rate != 5 || n.Limiter.bytes.rate != 1000 {
+		t.Fatalf("got trusted node rates %v %v, want 5 1000",
+			n.Limiter.messages.rate, n.Limiter.bytes.rate)
+	}
+
+	// the message is over the reloaded limit
+	peer.send(newInv(10))
+	peer.closed()
+}
+
+// TestNode_Reload_mempool tests that the Mempool is refiltered, and held
+// to the reloaded limits.
+func TestNode_Reload_mempool(t *testing.T) {
+	// the values of the outputs of the TX's are 1000 to 1004
+	txs := newTxs(5)
+
+	tests := []struct {
+		name   string
+		config Config
+		reload Config
+		want   []int
+	}{
+		{
+			name: "refiltered",
+			reload: Config{
+				TxFilters: []TxFilter{NewValueFilter(1003, 1010)},
+			},
+			want: []int{3, 4},
+		},
+		{
+			name: "filter added",
+			reload: Config{
+				TxFilters: []TxFilter{
+					NewValueFilter(1000, 1000),
+					NewValueFilter(1004, 1004),
+				},
+			},
+			want: []int{0, 4},
+		},
+		{
+			name: "compact blocks keep every TX",
+			config: Config{
+				CompactBlocks: true,
+			},
+			reload: Config{
+				TxFilters: []TxFilter{NewValueFilter(1003, 1010)},
+			},
+			want: []int{0, 1, 2, 3, 4},
+		},
+		{
+			name: "smaller limit",
+			reload: Config{
+				TxFilters:     []TxFilter{NewValueFilter(1000, 1010)},
+				MempoolMaxTxs: 2,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.TxFilters = []TxFilter{NewValueFilter(1000, 1010)}
+
+			n := newTestNode(config)
+			defer n.Stop()
+
+			for _, tx := range txs {
+				n.Mempool.Add(tx)
+			}
+
+			n.Reload(tt.reload)
+
+			if tt.reload.MempoolMaxTxs > 0 {
+				if n.Mempool.Len() != tt.reload.MempoolMaxTxs {
+					t.Fatalf("got %v TX's, want %v", n.Mempool.Len(),
+						tt.reload.MempoolMaxTxs)
+				}
+
+				return
+			}
+
+			if n.Mempool.Len() != len(tt.want) {
+				t.Fatalf("got %v TX's, want %v", n.Mempool.Len(), len(tt.want))
+			}
+
+			for _, i := range tt.want {
+				if n.Mempool.Get(txs[i].TxHash()) == nil {
+					t.Fatalf("tx %v removed", i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package spvnode
+
+import (
+	"context"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// NotFoundHandler exists to handle the NotFound command.
+type NotFoundHandler struct {
+	TxRequests TxRequests
+	TXHandler  TXHandler
+}
+
+// NewNotFoundHandler returns a new NotFoundHandler with the given
+// TxRequests.
+func NewNotFoundHandler(txRequests TxRequests,
+	txHandler TXHandler) NotFoundHandler {
+
+	return NotFoundHandler{
+		TxRequests: txRequests,
+		TXHandler:  txHandler,
+	}
+}
+
+// Handle implments the Handler interface.
+//
+// This function handles type conversion and delegates the the contrete
+// handler.
+func (h NotFoundHandler) Handle(ctx context.Context,
+	m wire.Message) ([]wire.Message, error) {
+
+	msg, ok := m.(*wire.MsgNotFound)
+	if !ok {
+		return nil, errs.New(errs.Invalid, "Could not assert as *wire.MsgNotFound")
+	}
+
+	return h.handle(ctx, msg)
+}
+
+// handle processes the MsgNotFound.
+//
+// The TX's that were not found are no longer in flight, so the requests of
+// the TX's waiting for them may be sent, and the orphans waiting for them
+// are passed on.
+func (h NotFoundHandler) handle(ctx context.Context,
+	m *wire.MsgNotFound) ([]wire.Message, error) {
+
+	hashes := []chainhash.Hash{}
+
+	for _, v := range m.InvList {
+		if v.Type == wire.InvTypeTx {
+			hashes = append(hashes, v.Hash)
+		}
+	}
+
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+
+	out := h.TxRequests.Received(hashes...)
+
+	h.TXHandler.Resolved(ctx, hashes)
+
+	return out, nil
+}
@@ -0,0 +1,178 @@
+package spvnode
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+const (
+	// maxOrphans is the most TX's held in the OrphanPool. Further orphans
+	// are passed on at once, out of order.
+	maxOrphans = 1000
+
+	// orphanTimeout is how long an orphan is held for its parents. It is
+	// passed on after that, as a parent that hasn't arrived by then isn't
+	// coming.
+	orphanTimeout = txRequestTimeout
+)
+
+// OrphanPool holds the relevant TX's that arrive before the TX's they spend
+// from, so they can be passed on after their parents.
+//
+// It is safe for concurrent use.
+type OrphanPool struct {
+	mu *sync.Mutex
+
+	// orphans are the held TX's, by their hash.
+	orphans map[chainhash.Hash]*orphan
+
+	// waiting are the hashes of the orphans waiting for each parent.
+	waiting map[chainhash.Hash][]chainhash.Hash
+}
+
+// orphan is a TX held in the OrphanPool.
+type orphan struct {
+	tx      *wire.MsgTx
+	missing map[chainhash.Hash]bool
+	added   time.Time
+}
+
+// NewOrphanPool returns a new, empty, OrphanPool.
+func NewOrphanPool() OrphanPool {
+	return OrphanPool{
+		mu:      &sync.Mutex{},
+		orphans: map[chainhash.Hash]*orphan{},
+		waiting: map[chainhash.Hash][]chainhash.Hash{},
+	}
+}
+
+// Add holds the TX until the missing parents arrive. It returns false if
+// the OrphanPool is full, so the TX isn't held.
+func (p OrphanPool) Add(tx *wire.MsgTx, missing []chainhash.Hash) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	hash := tx.TxHash()
+
+	if _, ok := p.orphans[hash]; ok {
+		return true
+	}
+
+	if len(p.orphans) >= maxOrphans {
+		return false
+	}
+
+	o := orphan{
+		tx:      tx,
+		missing: map[chainhash.Hash]bool{},
+		added:   time.Now(),
+	}
+
+	for _, parent := range missing {
+		if o.missing[parent] {
+			continue
+		}
+
+		o.missing[parent] = true
+		p.waiting[parent] = append(p.waiting[parent], hash)
+	}
+
+	p.orphans[hash] = &o
+
+	return true
+}
+
+// Arrived removes the parent from the orphans waiting for it, and returns
+// those that are no longer waiting for any parent, in the order they were
+// added.
+func (p OrphanPool) Arrived(parent chainhash.Hash) []*wire.MsgTx {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	txs := []*wire.MsgTx{}
+
+	for _, hash := range p.waiting[parent] {
+		o, ok := p.orphans[hash]
+		if !ok {
+			continue
+		}
+
+		delete(o.missing, parent)
+
+		if len(o.missing) == 0 {
+			delete(p.orphans, hash)
+			txs = append(txs, o.tx)
+		}
+	}
+
+	delete(p.waiting, parent)
+
+	return txs
+}
+
+// Expire removes the orphans added before the time, and returns them, in
+// the order they were added.
+func (p OrphanPool) Expire(before time.Time) []*wire.MsgTx {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	expired := []*orphan{}
+
+	for hash, o := range p.orphans {
+		if !o.added.Before(before) {
+			continue
+		}
+
+		delete(p.orphans, hash)
+		expired = append(expired, o)
+
+		for parent := range o.missing {
+			p.unwait(parent, hash)
+		}
+	}
+
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].added.Before(expired[j].added)
+	})
+
+	txs := make([]*wire.MsgTx, 0, len(expired))
+	for _, o := range expired {
+		txs = append(txs, o.tx)
+	}
+
+	return txs
+}
+
+// Len returns the number of orphans.
+func (p OrphanPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.orphans)
+}
+
+// unwait removes the orphan from those waiting for the parent.
+//
+// The lock must be held.
+func (p OrphanPool) unwait(parent, hash chainhash.Hash) {
+	waiting := p.waiting[parent]
+
+	for i, h := range waiting {
+		if h == hash {
+			waiting = append(waiting[:i], waiting[i+1:]...)
+			break
+		}
+	}
+
+	if len(waiting) == 0 {
+		delete(p.waiting, parent)
+		return
+	}
+
+	p.waiting[parent] = waiting
+}
@@ -0,0 +1,190 @@
+package spvnode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+func TestOrphanPool_Add_limit(t *testing.T) {
+	p := NewOrphanPool()
+
+	parent := chainhash.DoubleHashH([]byte("parent"))
+	txs := newTxs(maxOrphans + 1)
+
+	for _, tx := range txs[:maxOrphans] {
+		if !p.Add(tx, []chainhash.Hash{parent}) {
+			t.Fatalf("orphan %v not held", p.Len())
+		}
+	}
+
+	if p.Add(txs[maxOrphans], []chainhash.Hash{parent}) {
+		t.Fatal("orphan held over the limit")
+	}
+
+	// an orphan already held is still held
+	if !p.Add(txs[0], []chainhash.Hash{parent}) {
+		t.Fatal("held orphan not held again")
+	}
+
+	if p.Len() != maxOrphans {
+		t.Fatalf("got %v orphans, want %v", p.Len(), maxOrphans)
+	}
+
+	// the parent arriving makes room
+	if got := len(p.Arrived(parent)); got != maxOrphans {
+		t.Fatalf("got %v orphans passed on, want %v", got, maxOrphans)
+	}
+
+	if !p.Add(txs[maxOrphans], []chainhash.Hash{parent}) {
+		t.Fatal("orphan not held once there is room")
+	}
+}
+
+func TestOrphanPool_Arrived(t *testing.T) {
+	p := NewOrphanPool()
+
+	txs := newTxs(4)
+	parent1 := chainhash.DoubleHashH([]byte("parent1"))
+	parent2 := chainhash.DoubleHashH([]byte("parent2"))
+
+	// 0 and 2 wait for the first parent, 1 for both, and 3, which spends
+	// 2, for 2
+	p.Add(txs[0], []chainhash.Hash{parent1})
+	p.Add(txs[1], []chainhash.Hash{parent1, parent2, parent1})
+	p.Add(txs[2], []chainhash.Hash{parent1})
+	p.Add(txs[3], []chainhash.Hash{txs[2].TxHash()})
+
+	tests := []struct {
+		name    string
+		arrived chainhash.Hash
+		want    []int
+	}{
+		{
+			name:    "unknown parent",
+			arrived: chainhash.DoubleHashH([]byte("other")),
+		},
+		{
+			name:    "first parent",
+			arrived: parent1,
+			want:    []int{0, 2},
+		},
+		{
+			name:    "first parent again",
+			arrived: parent1,
+		},
+		{
+			name:    "orphan as parent",
+			arrived: txs[2].TxHash(),
+			want:    []int{3},
+		},
+		{
+			name:    "second parent",
+			arrived: parent2,
+			want:    []int{1},
+		},
+	}
+
+	for _, tt := range tests {
+		got := p.Arrived(tt.arrived)
+
+		if len(got) != len(tt.want) {
+			t.Fatalf("%v : got %v orphans, want %v", tt.name, len(got),
+				len(tt.want))
+		}
+
+		for i, tx := range got {
+			if tx.TxHash() != txs[tt.want[i]].TxHash() {
+				t.Fatalf("%v : got %v at %v, want tx %v", tt.name, tx.TxHash(),
+					i, tt.want[i])
+			}
+		}
+	}
+
+	if p.Len() != 0 || len(p.waiting) != 0 {
+		t.Fatalf("got %v orphans waiting for %v parents, want none", p.Len(),
+			len(p.waiting))
+	}
+}
+
+func TestOrphanPool_Expire(t *testing.T) {
+	p := NewOrphanPool()
+
+	txs := newTxs(3)
+	parent := chainhash.DoubleHashH([]byte("parent"))
+	other := chainhash.DoubleHashH([]byte("other"))
+
+	now := time.Now()
+
+	for i, tx := range txs {
+		p.Add(tx, []chainhash.Hash{parent, other})
+
+		// added a minute apart, the first the longest ago
+		p.orphans[tx.TxHash()].added = now.Add(time.Duration(i-3) * time.Minute)
+	}
+
+	expired := p.Expire(now.Add(-90 * time.Second))
+
+	if len(expired) != 2 || expired[0].TxHash() != txs[0].TxHash() ||
+		expired[1].TxHash() != txs[1].TxHash() {
+		t.Fatalf("got %v expired, want the first 2 in order", len(expired))
+	}
+
+	// the expired orphans no longer wait for the parents
+	if len(p.waiting[parent]) != 1 || len(p.waiting[other]) != 1 {
+		t.Fatalf("got %v and %v waiting, want 1", len(p.waiting[parent]),
+			len(p.waiting[other]))
+	}
+
+	p.Arrived(parent)
+	got := p.Arrived(other)
+
+	if len(got) != 1 || got[0].TxHash() != txs[2].TxHash() {
+		t.Fatalf("got %v passed on, want the last", len(got))
+	}
+}
+
+// TestTXHandler_orphans tests that a relevant TX arriving before the parent
+// it was announced after is passed on once the parent arrives.
+func TestTXHandler_orphans(t *testing.T) {
+	ctx := context.Background()
+
+	parent := newTxs(2)[1]
+	parentHash := parent.TxHash()
+
+	child := wire.NewMsgTx(1)
+	child.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&parentHash, 0), nil))
+	child.AddTxOut(wire.NewTxOut(500, []byte{0x51}))
+
+	l := newRecordingListener()
+
+	h := NewTXHandler(Config{}, nil, NewMempool(Config{}), l,
+		NewBroadcastTracker(), NewTxRequests(10), NewOrphanPool(),
+		NewDoubleSpends(), NewSeenTxs(), nil)
+
+	h.TxRequests.Announced([]chainhash.Hash{parentHash, child.TxHash()})
+
+	if _, err := h.Handle(ctx, child); err != nil {
+		t.Fatal(err)
+	}
+
+	if h.Orphans.Len() != 1 || l.index(child.TxHash()) >= 0 {
+		t.Fatal("child passed on before its parent")
+	}
+
+	if _, err := h.Handle(ctx, parent); err != nil {
+		t.Fatal(err)
+	}
+
+	if l.index(parentHash) != 0 || l.index(child.TxHash()) != 1 {
+		t.Fatalf("got calls %v, want the parent then the child", *l.calls)
+	}
+
+	if h.Orphans.Len() != 0 {
+		t.Fatalf("got %v orphans, want none", h.Orphans.Len())
+	}
+}
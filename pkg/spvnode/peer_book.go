@@ -0,0 +1,378 @@
+package spvnode
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+const (
+	// SourceImport is the Source of peers imported from a file, when the
+	// file doesn't say where they were learned from.
+	SourceImport = "import"
+
+	// FormatJSON is a JSON array of peers, as exported by a PeerBook or
+	// returned by the getnodeaddresses RPC.
+	FormatJSON = "json"
+
+	// FormatPeersDat is the address manager file written by bitcoind,
+	// peers.dat.
+	FormatPeersDat = "peers.dat"
+
+	// peersDatVersion is the version of the peers.dat format that is read
+	// and written. Later versions hold BIP155 addresses.
+	peersDatVersion = 1
+
+	// peersDatKeySize is the size of the bucket key of a peers.dat file.
+	peersDatKeySize = 32
+
+	// peersDatMaxEntries bounds the entries read from a peers.dat file, the
+	// capacity of the new and tried tables of bitcoind.
+	peersDatMaxEntries = 1024*64 + 256*64
+
+	// peersDatBucketFlag is xor'ed with the bucket count of a peers.dat
+	// file. Writing no buckets makes bitcoind place each address itself.
+	peersDatBucketFlag = 1 << 30
+)
+
+var (
+	// ErrUnknownFormat is returned for a peer file format that is not
+	// supported.
+	ErrUnknownFormat = errs.New(errs.Invalid, "Unknown peer file format")
+
+	// ErrPeersDatVersion is returned for a peers.dat file of an unsupported
+	// version.
+	ErrPeersDatVersion = errs.New(errs.Invalid, "Unsupported peers.dat version")
+
+	// ErrPeersDatNetwork is returned for a peers.dat file of another
+	// network.
+	ErrPeersDatNetwork = errs.New(errs.Invalid, "peers.dat is for another network")
+
+	// ErrPeersDatChecksum is returned for a corrupt peers.dat file.
+	ErrPeersDatChecksum = errs.New(errs.Invalid, "peers.dat checksum mismatch")
+)
+
+// onionCatPrefix is the IPv6 prefix of Tor addresses in a peers.dat file,
+// which can't be dialed directly.
+var onionCatPrefix = []byte{0xfd, 0x87, 0xd8, 0x7e, 0xeb, 0x43}
+
+// PeerBook imports and exports the PeerRepository in standard formats, so
+// peers can be seeded from existing infrastructure or shared between
+// deployments.
+type PeerBook struct {
+	Peers PeerRepository
+	Net   wire.BitcoinNet
+}
+
+// NewPeerBook returns a new PeerBook for the peers of the network.
+func NewPeerBook(peers PeerRepository, net wire.BitcoinNet) PeerBook {
+	return PeerBook{
+		Peers: peers,
+		Net:   net,
+	}
+}
+
+// FormatOf returns the format of a peer file from its name. Files ending in
+// ".dat" are peers.dat files, anything else is JSON.
+func FormatOf(path string) string {
+	if strings.ToLower(filepath.Ext(path)) == ".dat" {
+		return FormatPeersDat
+	}
+
+	return FormatJSON
+}
+
+// Import reads peers in the format, and stores them. A peer that is already
+// known is only replaced if it was seen more recently.
+//
+// Returns the number of peers stored.
+func (b PeerBook) Import(ctx context.Context,
+	r io.Reader,
+	format string) (int, error) {
+
+	var peers []Peer
+	var err error
+
+	switch format {
+	case FormatJSON:
+		peers, err = b.readJSON(r)
+	case FormatPeersDat:
+		peers, err = b.readPeersDat(r)
+	default:
+		return 0, ErrUnknownFormat
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for _, p := range peers {
+		existing, err := b.Peers.Read(ctx, p.Address)
+		if err != nil && err != ErrPeerNotFound {
+			return count, err
+		}
+
+		if existing != nil && existing.LastSeen >= p.LastSeen {
+			continue
+		}
+
+		if err := b.Peers.Write(ctx, p); err != nil {
+			return count, err
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+// Export writes all peers in the format, most recently seen first.
+//
+// Only peers with an IP address are written to a peers.dat file.
+func (b PeerBook) Export(ctx context.Context,
+	w io.Writer,
+	format string) error {
+
+	peers, err := b.Peers.All(ctx)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].LastSeen > peers[j].LastSeen
+	})
+
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(peers)
+	case FormatPeersDat:
+		return b.writePeersDat(w, peers)
+	default:
+		return ErrUnknownFormat
+	}
+}
+
+// jsonPeer is a peer in a JSON file. It is either a Peer, or an address of
+// the getnodeaddresses RPC, with the port and the time in seconds given
+// separately.
+type jsonPeer struct {
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+	Source   string `json:"source"`
+	LastSeen int64  `json:"last_seen"`
+	Time     int64  `json:"time"`
+}
+
+// readJSON reads the peers of a JSON file.
+func (b PeerBook) readJSON(r io.Reader) ([]Peer, error) {
+	entries := []jsonPeer{}
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+
+	peers := []Peer{}
+
+	for _, e := range entries {
+		p := Peer{
+			Address:  e.Address,
+			Source:   e.Source,
+			LastSeen: e.LastSeen,
+		}
+
+		if e.Port != 0 {
+			p.Address = net.JoinHostPort(e.Address, strconv.Itoa(e.Port))
+		}
+
+		if _, _, err := net.SplitHostPort(p.Address); err != nil {
+			return nil, fmt.Errorf("Invalid peer address %v : %v", p.Address, err)
+		}
+
+		if p.LastSeen == 0 {
+			p.LastSeen = time.Unix(e.Time, 0).UnixNano()
+		}
+
+		if p.Source == "" {
+			p.Source = SourceImport
+		}
+
+		peers = append(peers, p)
+	}
+
+	return peers, nil
+}
+
+// readPeersDat reads the peers of a peers.dat file, from both the new and
+// tried tables. Tor addresses are skipped.
+func (b PeerBook) readPeersDat(r io.Reader) ([]Peer, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 4+chainhash.HashSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	body := data[:len(data)-chainhash.HashSize]
+	if !bytes.Equal(chainhash.DoubleHashB(body), data[len(body):]) {
+		return nil, ErrPeersDatChecksum
+	}
+
+	if binary.LittleEndian.Uint32(body) != uint32(b.Net) {
+		return nil, ErrPeersDatNetwork
+	}
+
+	buf := bytes.NewReader(body[4:])
+
+	header := struct {
+		Version uint8
+		KeySize uint8
+		Key     [peersDatKeySize]byte
+		New     int32
+		Tried   int32
+		Buckets int32
+	}{}
+
+	if err := binary.Read(buf, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+
+	if header.Version > peersDatVersion || header.KeySize != peersDatKeySize {
+		return nil, ErrPeersDatVersion
+	}
+
+	count := int(header.New) + int(header.Tried)
+	if header.New < 0 || header.Tried < 0 || count > peersDatMaxEntries {
+		return nil, fmt.Errorf("Invalid peers.dat entry count %v", count)
+	}
+
+	peers := []Peer{}
+
+	for i := 0; i < count; i++ {
+		e := peersDatEntry{}
+		if err := binary.Read(buf, binary.LittleEndian, &e); err != nil {
+			return nil, err
+		}
+
+		if bytes.HasPrefix(e.IP[:], onionCatPrefix) {
+			continue
+		}
+
+		peers = append(peers, Peer{
+			Address: net.JoinHostPort(net.IP(e.IP[:]).String(),
+				strconv.Itoa(int(binary.BigEndian.Uint16(e.Port[:])))),
+			Source:   SourceImport,
+			LastSeen: time.Unix(int64(e.Time), 0).UnixNano(),
+		})
+	}
+
+	// the bucket positions that follow are specific to the node that wrote
+	// the file, so they are not needed.
+
+	return peers, nil
+}
+
+// peersDatEntry is an address in a peers.dat file.
+type peersDatEntry struct {
+	Version     int32
+	Time        uint32
+	Services    uint64
+	IP          [16]byte
+	Port        [2]byte
+	Source      [16]byte
+	LastSuccess int64
+	Attempts    int32
+}
+
+// writePeersDat writes the peers as a peers.dat file, with all peers in the
+// new table and no bucket positions, so bitcoind places them itself.
+func (b PeerBook) writePeersDat(w io.Writer, peers []Peer) error {
+	entries := []peersDatEntry{}
+
+	for _, p := range peers {
+		host, port, err := net.SplitHostPort(p.Address)
+		if err != nil {
+			continue
+		}
+
+		ip := net.ParseIP(host)
+		if ip == nil {
+			continue
+		}
+
+		n, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			continue
+		}
+
+		e := peersDatEntry{
+			Version:  int32(wire.ProtocolVersion),
+			Time:     uint32(time.Unix(0, p.LastSeen).Unix()),
+			Services: uint64(wire.SFNodeNetwork),
+		}
+
+		copy(e.IP[:], ip.To16())
+		copy(e.Source[:], ip.To16())
+		binary.BigEndian.PutUint16(e.Port[:], uint16(n))
+
+		entries = append(entries, e)
+
+		if len(entries) == peersDatMaxEntries {
+			break
+		}
+	}
+
+	header := struct {
+		Net     uint32
+		Version uint8
+		KeySize uint8
+		Key     [peersDatKeySize]byte
+		New     int32
+		Tried   int32
+		Buckets int32
+	}{
+		Net:     uint32(b.Net),
+		Version: peersDatVersion,
+		KeySize: peersDatKeySize,
+		New:     int32(len(entries)),
+		Buckets: peersDatBucketFlag,
+	}
+
+	if _, err := rand.Read(header.Key[:]); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	if err := binary.Write(&buf, binary.LittleEndian, header); err != nil {
+		return err
+	}
+
+	if err := binary.Write(&buf, binary.LittleEndian, entries); err != nil {
+		return err
+	}
+
+	buf.Write(chainhash.DoubleHashB(buf.Bytes()))
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,655 @@
+package spvnode
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/spvnode/logger"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+const (
+	// poolInterval is how often the PeerPool checks its peers, and connects
+	// to more if it has fewer than its target.
+	poolInterval = time.Minute
+
+	// minPeerScore is the lowest conformance score of a peer that the
+	// PeerPool connects to, or keeps.
+	minPeerScore = 0.5
+
+	// defaultPeersPerGroup is the most peers in one network group that the
+	// PeerPool keeps connected, if no other limit is given.
+	defaultPeersPerGroup = 1
+
+	// SourcePinned is the Source of the pinned peers of the Config.
+	SourcePinned = "pinned"
+)
+
+// PeerPool keeps a target count of untrusted peers connected, so blocks can
+// be downloaded without connecting to peers first.
+//
+// Peers are chosen by their conformance Rank, which prefers reliable peers
+// with low latency, then by when they were last seen. A peer that fails to
+// connect, or stops responding, is backed off from before it is connected
+// to again. A peer whose conformance score falls too low is closed, and
+// replaced at the next check.
+//
+// No more than the most per group of the peers are kept in the same
+// network group, so the pool can't be filled by one operator's nodes.
+//
+// If a rotation interval is set, the lowest ranked peers are disconnected
+// that often, and replaced by fresh candidates, so the pool keeps exploring
+// for better peers rather than keeping the ones it started with.
+//
+// Pinned peers, such as the operator's own nodes, are kept connected in
+// addition to the target, and taken first. They are reconnected at each
+// check without backing off, and are never closed for their conformance
+// score or counted in the network groups.
+//
+// The target, pinned peers, rotation and limits may be changed with Reload
+// and SetRotation while the pool is running. Copies share them.
+type PeerPool struct {
+	Network     wire.BitcoinNet
+	Dialer      Dialer
+	Traffic     Traffic
+	Peers       PeerRepository
+	Conformance Conformance
+	Backoff     Backoff
+
+	mu *sync.Mutex
+
+	// settings are the peers kept connected, and how they are connected
+	// to.
+	settings *poolSettings
+
+	// rotated is when the peers were last rotated, and rotatedOut are the
+	// addresses of the peers rotated out then, which aren't candidates
+	// until the next rotation.
+	rotated    *time.Time
+	rotatedOut map[string]bool
+
+	// idle are the connected peers not taken by a caller, and taken are
+	// the addresses of those that are.
+	idle  map[string]*BlockPeer
+	taken map[string]bool
+}
+
+// poolSettings are the peers a PeerPool keeps connected, and how it
+// connects to them.
+type poolSettings struct {
+	target      int
+	maxPerGroup int
+	pinned      map[string]bool
+	limits      MessageLimits
+	handshake   Handshake
+
+	// rotateInterval is how often the rotateCount lowest ranked peers are
+	// rotated out, or never if it is 0. rotateCount is 1 if it is 0.
+	rotateInterval time.Duration
+	rotateCount    int
+}
+
+// PooledPeer is a peer connected by the PeerPool, as it is inspected.
+type PooledPeer struct {
+	Address string
+	Group   string
+
+	// Taken is true while the peer is used by a caller.
+	Taken bool
+
+	// Pinned is true if the peer is always kept connected.
+	Pinned bool
+
+	// Score, Rank and Latency are from the ConformanceReport of the peer.
+	Score   float64
+	Rank    float64
+	Latency time.Duration
+}
+
+// NewPeerPool returns a new PeerPool that keeps the target count of peers
+// of the network connected, with at most maxPerGroup of them in one network
+// group, or defaultPeersPerGroup if it is 0, as well as the pinned peers.
+func NewPeerPool(target int,
+	maxPerGroup int,
+	pinned []string,
+	network wire.BitcoinNet,
+	limits MessageLimits,
+	dialer Dialer,
+	handshake Handshake,
+	traffic Traffic,
+	peers PeerRepository,
+	conformance Conformance,
+	backoff Backoff) PeerPool {
+
+	settings := poolSettings{}
+	settings.set(target, maxPerGroup, pinned, limits, handshake)
+
+	now := time.Now()
+
+	return PeerPool{
+		Network:     network,
+		Dialer:      dialer,
+		Traffic:     traffic,
+		Peers:       peers,
+		Conformance: conformance,
+		Backoff:     backoff,
+		mu:          &sync.Mutex{},
+		settings:    &settings,
+		rotated:     &now,
+		rotatedOut:  map[string]bool{},
+		idle:        map[string]*BlockPeer{},
+		taken:       map[string]bool{},
+	}
+}
+
+// set sets the target count of peers, at most maxPerGroup of them in one
+// network group, or defaultPeersPerGroup if it is 0, the pinned peers, and
+// the limits and handshake of the peers connected to.
+func (s *poolSettings) set(target, maxPerGroup int, pinned []string,
+	limits MessageLimits, handshake Handshake) {
+
+	if maxPerGroup == 0 {
+		maxPerGroup = defaultPeersPerGroup
+	}
+
+	pins := map[string]bool{}
+	for _, address := range pinned {
+		pins[address] = true
+	}
+
+	s.target = target
+	s.maxPerGroup = maxPerGroup
+	s.pinned = pins
+	s.limits = limits
+	s.handshake = handshake
+}
+
+// Reload replaces the target count of peers, the most in one network
+// group, the pinned peers, and the limits and handshake of the peers
+// connected to, as NewPeerPool sets them.
+//
+// The pool is brought to the new target at its next check. Peers already
+// connected keep the limits and handshake they were connected with, and
+// those no longer pinned are kept like any other peer.
+func (p PeerPool) Reload(target, maxPerGroup int, pinned []string,
+	limits MessageLimits, handshake Handshake) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	settings := *p.settings
+	settings.set(target, maxPerGroup, pinned, limits, handshake)
+	*p.settings = settings
+}
+
+// SetRotation sets how often the count lowest ranked peers are rotated
+// out, or never if the interval is 0. One peer is rotated out at a time if
+// the count is 0.
+func (p PeerPool) SetRotation(interval time.Duration, count int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.settings.rotateInterval = interval
+	p.settings.rotateCount = count
+}
+
+// current returns the settings of the pool.
+func (p PeerPool) current() poolSettings {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return *p.settings
+}
+
+// Run maintains the pool until the Context is done, then closes the idle
+// peers.
+func (p PeerPool) Run(ctx context.Context) {
+	for {
+		p.maintain(ctx)
+
+		select {
+		case <-ctx.Done():
+			for _, peer := range p.Take(len(p.Connected())) {
+				peer.Close()
+			}
+
+			return
+
+		case <-time.After(poolInterval):
+		}
+	}
+}
+
+// Take removes up to count idle peers from the pool, for the caller to use,
+// pinned peers first, then the best ranked. They must be given back with
+// Release.
+func (p PeerPool) Take(count int) []*BlockPeer {
+	reports := p.reports()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	peers := []*BlockPeer{}
+	for _, peer := range p.idle {
+		peers = append(peers, peer)
+	}
+
+	sort.Slice(peers, func(i, j int) bool {
+		pi, pj := p.settings.pinned[peers[i].Address()], p.settings.pinned[peers[j].Address()]
+		if pi != pj {
+			return pi
+		}
+
+		ri, rj := rank(reports, peers[i].Address()), rank(reports, peers[j].Address())
+		if ri != rj {
+			return ri > rj
+		}
+
+		return peers[i].Address() < peers[j].Address()
+	})
+
+	if len(peers) > count {
+		peers = peers[:count]
+	}
+
+	for _, peer := range peers {
+		delete(p.idle, peer.Address())
+		p.taken[peer.Address()] = true
+	}
+
+	return peers
+}
+
+// Release gives back peers taken from the pool. Peers whose conformance
+// score has fallen too low while they were used are closed, unless they are
+// pinned.
+func (p PeerPool) Release(peers []*BlockPeer) {
+	reports := p.reports()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, peer := range peers {
+		delete(p.taken, peer.Address())
+
+		if r, ok := reports[peer.Address()]; ok && r.Score() < minPeerScore &&
+			!p.settings.pinned[peer.Address()] {
+			peer.Close()
+			continue
+		}
+
+		p.idle[peer.Address()] = peer
+	}
+}
+
+// Connected returns the addresses of the peers in the pool, taken or idle.
+func (p PeerPool) Connected() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	addresses := []string{}
+
+	for address := range p.idle {
+		addresses = append(addresses, address)
+	}
+
+	for address := range p.taken {
+		addresses = append(addresses, address)
+	}
+
+	sort.Strings(addresses)
+
+	return addresses
+}
+
+// Status returns the peers in the pool, taken or idle, ordered by address.
+func (p PeerPool) Status() []PooledPeer {
+	reports := p.reports()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	peers := []PooledPeer{}
+
+	add := func(address string, taken bool) {
+		peer := PooledPeer{
+			Address: address,
+			Group:   netGroup(address),
+			Taken:   taken,
+			Pinned:  p.settings.pinned[address],
+			Score:   neutralScore,
+			Rank:    neutralScore,
+		}
+
+		if r, ok := reports[address]; ok {
+			peer.Score = r.Score()
+			peer.Rank = r.Rank()
+			peer.Latency = r.Latency
+		}
+
+		peers = append(peers, peer)
+	}
+
+	for address := range p.idle {
+		add(address, false)
+	}
+
+	for address := range p.taken {
+		add(address, true)
+	}
+
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].Address < peers[j].Address
+	})
+
+	return peers
+}
+
+// maintain pings the idle peers, dropping those that don't respond, then
+// reconnects the pinned peers, rotates out the lowest ranked peers if it is
+// time to, and connects to the best candidates until there are target other
+// peers, skipping those in full network groups. The round trips of the
+// pings and handshakes are recorded, and the reports of peers not seen for
+// the reportMaxAge are forgotten.
+func (p PeerPool) maintain(ctx context.Context) {
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	p.Conformance.Forget(time.Now().Add(-reportMaxAge))
+
+	checked := []*BlockPeer{}
+
+	for _, peer := range p.Take(len(p.Connected())) {
+		if err := peer.Ping(); err != nil {
+			delay := p.Backoff.Failed(peer.Address())
+			log.Warnf("Dropping peer %v, retrying in %v : %v",
+				peer.Address(), delay, err)
+
+			peer.Close()
+			continue
+		}
+
+		p.Conformance.RecordLatency(peer.Address(), peer.Latency())
+
+		checked = append(checked, peer)
+	}
+
+	p.Release(checked)
+
+	connected := map[string]bool{}
+	for _, address := range p.Connected() {
+		connected[address] = true
+	}
+
+	settings := p.current()
+
+	for address := range settings.pinned {
+		if connected[address] || ctx.Err() != nil {
+			continue
+		}
+
+		if _, err := p.connect(ctx, Peer{Address: address, Source: SourcePinned}); err != nil {
+			log.Warnf("Failed to connect to pinned peer %v : %v", address, err)
+		}
+	}
+
+	for _, address := range p.rotate(ctx) {
+		delete(connected, address)
+	}
+
+	groups := map[string]int{}
+	need := settings.target
+
+	for address := range connected {
+		if settings.pinned[address] {
+			continue
+		}
+
+		groups[netGroup(address)]++
+		need--
+	}
+
+	if need <= 0 {
+		return
+	}
+
+	candidates, err := p.candidates(ctx)
+	if err != nil {
+		log.Errorf("Failed to read peers : %v", err)
+		return
+	}
+
+	for _, c := range candidates {
+		if need == 0 || ctx.Err() != nil {
+			return
+		}
+
+		group := netGroup(c.Address)
+		if groups[group] >= settings.maxPerGroup {
+			continue
+		}
+
+		delay, err := p.connect(ctx, c)
+		if err != nil {
+			log.Warnf("Failed to connect to peer %v, retrying in %v : %v",
+				c.Address, delay, err)
+			continue
+		}
+
+		groups[group]++
+		need--
+	}
+}
+
+// rotate closes the rotateCount lowest ranked idle peers, other than the
+// pinned peers, once every rotateInterval, and returns their addresses.
+// Peers are only rotated out of a full pool, and no more of them than there
+// are candidates to replace them with.
+func (p PeerPool) rotate(ctx context.Context) []string {
+	settings := p.current()
+	if settings.rotateInterval <= 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	due := time.Since(*p.rotated) >= settings.rotateInterval
+	p.mu.Unlock()
+
+	if !due {
+		return nil
+	}
+
+	candidates, err := p.candidates(ctx)
+	if err != nil {
+		log := logger.NewLoggerFromContext(ctx).Sugar()
+		log.Errorf("Failed to read peers : %v", err)
+		return nil
+	}
+
+	reports := p.reports()
+
+	p.mu.Lock()
+
+	*p.rotated = time.Now()
+
+	for address := range p.rotatedOut {
+		delete(p.rotatedOut, address)
+	}
+
+	count := len(p.taken)
+	peers := []*BlockPeer{}
+
+	for address, peer := range p.idle {
+		if settings.pinned[address] {
+			continue
+		}
+
+		count++
+		peers = append(peers, peer)
+	}
+
+	for address := range p.taken {
+		if settings.pinned[address] {
+			count--
+		}
+	}
+
+	rotate := settings.rotateCount
+	if rotate == 0 {
+		rotate = 1
+	}
+
+	if rotate > len(candidates) {
+		rotate = len(candidates)
+	}
+
+	if count < settings.target || rotate > len(peers) {
+		rotate = 0
+	}
+
+	sort.Slice(peers, func(i, j int) bool {
+		return rank(reports, peers[i].Address()) < rank(reports, peers[j].Address())
+	})
+
+	peers = peers[:rotate]
+
+	for _, peer := range peers {
+		delete(p.idle, peer.Address())
+		p.rotatedOut[peer.Address()] = true
+	}
+
+	p.mu.Unlock()
+
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+	addresses := []string{}
+
+	for _, peer := range peers {
+		log.Infof("Rotating out peer %v", peer.Address())
+
+		peer.Close()
+		addresses = append(addresses, peer.Address())
+	}
+
+	return addresses
+}
+
+// connect connects to the peer, and adds it to the idle peers. If it fails,
+// the peer is backed off from, and the delay is returned.
+func (p PeerPool) connect(ctx context.Context, c Peer) (time.Duration, error) {
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	settings := p.current()
+
+	peer, err := DialBlockPeer(p.Dialer, p.Network, settings.limits,
+		settings.handshake, p.Traffic, c.Address)
+	if err != nil {
+		if isHandshakeError(err) {
+			p.Conformance.Record(c.Address, AnomalyHandshake, err)
+		}
+
+		return p.Backoff.Failed(c.Address), err
+	}
+
+	p.Backoff.Succeeded(c.Address)
+	p.Conformance.RecordLatency(c.Address, peer.Latency())
+
+	c.LastSeen = time.Now().UnixNano()
+	if err := p.Peers.Write(ctx, c); err != nil {
+		log.Errorf("Failed to write peer %v : %v", c.Address, err)
+	}
+
+	p.mu.Lock()
+	p.idle[c.Address] = peer
+	p.mu.Unlock()
+
+	return 0, nil
+}
+
+// candidates returns the known peers that may be connected to, best first.
+//
+// Peers already in the pool, pinned, rotated out, backing off, unreachable
+// without a proxy, not seen for the peerPruneAge, or with a low conformance
+// score are left out.
+func (p PeerPool) candidates(ctx context.Context) ([]Peer, error) {
+	peers, err := p.Peers.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	connected := map[string]bool{}
+	for _, address := range p.Connected() {
+		connected[address] = true
+	}
+
+	p.mu.Lock()
+	for address := range p.rotatedOut {
+		connected[address] = true
+	}
+	p.mu.Unlock()
+
+	pinned := p.current().pinned
+	reports := p.reports()
+	stale := time.Now().Add(-peerPruneAge).UnixNano()
+
+	candidates := []Peer{}
+
+	for _, peer := range peers {
+		if connected[peer.Address] || pinned[peer.Address] ||
+			peer.LastSeen < stale || !p.Backoff.Ready(peer.Address) ||
+			!p.Dialer.CanReach(peer.Address) {
+			continue
+		}
+
+		if r, ok := reports[peer.Address]; ok && r.Score() < minPeerScore {
+			continue
+		}
+
+		candidates = append(candidates, peer)
+	}
+
+	rankPeers(candidates, reports)
+
+	return candidates, nil
+}
+
+// reports returns the ConformanceReport of each peer that has one.
+func (p PeerPool) reports() map[string]ConformanceReport {
+	return conformanceReports(p.Conformance)
+}
+
+// conformanceReports returns the ConformanceReport of each peer that has
+// one, by address.
+func conformanceReports(c Conformance) map[string]ConformanceReport {
+	reports := map[string]ConformanceReport{}
+
+	for _, r := range c.Report() {
+		reports[r.Peer] = r
+	}
+
+	return reports
+}
+
+// rankPeers sorts the peers best first, by their conformance Rank, then by
+// when they were last seen.
+func rankPeers(peers []Peer, reports map[string]ConformanceReport) {
+	sort.Slice(peers, func(i, j int) bool {
+		ri, rj := rank(reports, peers[i].Address), rank(reports, peers[j].Address)
+		if ri != rj {
+			return ri > rj
+		}
+
+		return peers[i].LastSeen > peers[j].LastSeen
+	})
+}
+
+// rank returns the conformance Rank of the peer, or the neutralScore if it
+// has no report.
+func rank(reports map[string]ConformanceReport, address string) float64 {
+	r, ok := reports[address]
+	if !ok {
+		return neutralScore
+	}
+
+	return r.Rank()
+}
@@ -0,0 +1,132 @@
+package spvnode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+// ErrPeerNotFound is returned when a requested Peer is not found.
+var ErrPeerNotFound = errs.New(errs.NotFound, "Peer not found")
+
+// Peer is a node of the network that may be connected to.
+type Peer struct {
+	// Address is the "host:port" of the peer.
+	Address string `json:"address"`
+
+	// Source is where the peer was learned from, such as a DNS seed.
+	Source string `json:"source"`
+
+	// LastSeen is when the peer was last known to be reachable, in
+	// nanoseconds since the epoch.
+	LastSeen int64 `json:"last_seen"`
+}
+
+// PeerRepository is used for managing Peer data.
+type PeerRepository struct {
+	Storage storage.Storage
+}
+
+// NewPeerRepository returns a new PeerRepository.
+func NewPeerRepository(store storage.Storage) PeerRepository {
+	return PeerRepository{
+		Storage: store,
+	}
+}
+
+// All returns all Peers.
+func (r PeerRepository) All(ctx context.Context) ([]Peer, error) {
+	query := map[string]string{
+		"path": "peers",
+	}
+
+	data, err := r.Storage.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	peers := []Peer{}
+
+	for _, b := range data {
+		p := Peer{}
+
+		if err := json.Unmarshal(b, &p); err != nil {
+			return nil, err
+		}
+
+		peers = append(peers, p)
+	}
+
+	return peers, nil
+}
+
+// Write stores a Peer.
+func (r PeerRepository) Write(ctx context.Context, p Peer) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	return r.Storage.Write(ctx, r.buildPath(p.Address), b, nil)
+}
+
+// Remove removes a Peer.
+func (r PeerRepository) Remove(ctx context.Context, address string) error {
+	return r.Storage.Remove(ctx, r.buildPath(address))
+}
+
+// Prune removes the Peers last seen before the time, and returns how many
+// were removed.
+func (r PeerRepository) Prune(ctx context.Context,
+	before time.Time) (int, error) {
+
+	peers, err := r.All(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+
+	for _, p := range peers {
+		if p.LastSeen >= before.UnixNano() {
+			continue
+		}
+
+		if err := r.Remove(ctx, p.Address); err != nil {
+			return removed, err
+		}
+
+		removed++
+	}
+
+	return removed, nil
+}
+
+// Read reads a Peer.
+func (r PeerRepository) Read(ctx context.Context,
+	address string) (*Peer, error) {
+
+	b, err := r.Storage.Read(ctx, r.buildPath(address))
+	if err != nil {
+		if err == storage.ErrNotFound {
+			err = ErrPeerNotFound
+		}
+
+		return nil, err
+	}
+
+	p := Peer{}
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (r PeerRepository) buildPath(address string) string {
+	return fmt.Sprintf("peers/%v", address)
+}
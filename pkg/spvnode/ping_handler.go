@@ -2,8 +2,8 @@ package spvnode
 
 import (
 	"context"
-	"errors"
 
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/wire"
 )
 
@@ -28,7 +28,7 @@ func (h PingHandler) Handle(ctx context.Context,
 
 	msg, ok := m.(*wire.MsgPing)
 	if !ok {
-		return nil, errors.New("Could not assert as *wire.MsgPing")
+		return nil, errs.New(errs.Invalid, "Could not assert as *wire.MsgPing")
 	}
 
 	return h.handle(ctx, msg)
@@ -0,0 +1,125 @@
+package spvnode
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the messages sent to a peer, and their total size, per
+// second, so a burst of messages doesn't overwhelm a slow connection.
+//
+// Each limit is a token bucket that holds up to a second of sending, so
+// short bursts are sent at once. A message larger than the bucket is sent
+// once the bucket is full, and the messages after it wait for it to be paid
+// back. A limit of 0 is no limit. It is safe for concurrent use.
+type RateLimiter struct {
+	MessagesPerSecond int
+	BytesPerSecond    int
+
+	mu       *sync.Mutex
+	messages *tokenBucket
+	bytes    *tokenBucket
+}
+
+// tokenBucket holds the tokens that are spent to send, refilled at the rate
+// per second up to the capacity. The tokens go negative when a send costs
+// more than there are.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a new RateLimiter of the messages and bytes per
+// second.
+func NewRateLimiter(messagesPerSecond, bytesPerSecond int) RateLimiter {
+	l := RateLimiter{
+		MessagesPerSecond: messagesPerSecond,
+		BytesPerSecond:    bytesPerSecond,
+		mu:                &sync.Mutex{},
+		messages:          &tokenBucket{rate: float64(messagesPerSecond)},
+		bytes:             &tokenBucket{rate: float64(bytesPerSecond)},
+	}
+
+	l.Reset()
+
+	return l
+}
+
+// SetRates changes the messages and bytes per second. The MessagesPerSecond
+// and BytesPerSecond are still those it was made with.
+func (l RateLimiter) SetRates(messagesPerSecond, bytesPerSecond int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.messages.rate = float64(messagesPerSecond)
+	l.bytes.rate = float64(bytesPerSecond)
+}
+
+// Wait blocks until a message of the size may be sent, or the Context is
+// done, in which case it returns the error of the Context.
+func (l RateLimiter) Wait(ctx context.Context, size int) error {
+	l.mu.Lock()
+
+	now := time.Now()
+	delay := l.messages.take(now, 1)
+	if d := l.bytes.take(now, float64(size)); d > delay {
+		delay = d
+	}
+
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Reset fills the buckets, as for a new connection.
+func (l RateLimiter) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	for _, b := range []*tokenBucket{l.messages, l.bytes} {
+		b.tokens = b.rate
+		b.last = now
+	}
+}
+
+// take spends the tokens, and returns how long until the bucket is no
+// longer in debt, which is 0 if it isn't.
+func (b *tokenBucket) take(now time.Time, tokens float64) time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+
+	b.last = now
+
+	// a cost over the capacity is allowed once the bucket is full
+	if tokens > b.rate {
+		wait := time.Duration((b.rate - b.tokens) / b.rate * float64(time.Second))
+		b.tokens -= tokens
+		return wait
+	}
+
+	b.tokens -= tokens
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
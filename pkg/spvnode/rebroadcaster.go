@@ -0,0 +1,163 @@
+package spvnode
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+const (
+	// rebroadcastCheckInterval is how often the Node checks for TX's that
+	// are due to be rebroadcast.
+	rebroadcastCheckInterval = time.Minute
+
+	// rebroadcastMaxDelay is the longest delay between rebroadcasts of a
+	// TX, however many times it has been rebroadcast.
+	rebroadcastMaxDelay = 6 * time.Hour
+
+	// defaultRebroadcastAttempts is how many times a TX is rebroadcast if
+	// the Config doesn't say.
+	defaultRebroadcastAttempts = 10
+
+	// maxRebroadcastTxs is the most TX's waiting to be rebroadcast.
+	maxRebroadcastTxs = 10000
+)
+
+var ErrRebroadcastFull = errs.New(errs.Temporary, "Too many TX's waiting to be rebroadcast")
+
+// Rebroadcaster keeps the TX's the Node originated until they are
+// confirmed, so they can be announced again if peers dropped them from
+// their mempools.
+//
+// The delay before the first rebroadcast is the interval, and doubles after
+// each one, up to rebroadcastMaxDelay. Delays are jittered by the Backoff.
+// A TX is given up on after MaxAttempts rebroadcasts. It is safe for
+// concurrent use.
+type Rebroadcaster struct {
+	MaxAttempts int
+	Backoff     Backoff
+
+	mu       *sync.Mutex
+	txs      map[chainhash.Hash]*wire.MsgTx
+	attempts map[chainhash.Hash]int
+}
+
+// NewRebroadcaster returns a new Rebroadcaster that first rebroadcasts a
+// TX after the interval.
+func NewRebroadcaster(interval time.Duration, maxAttempts int) Rebroadcaster {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultRebroadcastAttempts
+	}
+
+	max := rebroadcastMaxDelay
+	if interval > max {
+		max = interval
+	}
+
+	return Rebroadcaster{
+		MaxAttempts: maxAttempts,
+		Backoff:     NewBackoff(interval, max),
+		mu:          &sync.Mutex{},
+		txs:         map[chainhash.Hash]*wire.MsgTx{},
+		attempts:    map[chainhash.Hash]int{},
+	}
+}
+
+// Add keeps the TX, that was just broadcast, to be rebroadcast until it is
+// confirmed.
+func (r Rebroadcaster) Add(tx *wire.MsgTx) error {
+	hash := tx.TxHash()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.txs[hash]; ok {
+		return nil
+	}
+
+	if len(r.txs) >= maxRebroadcastTxs {
+		return ErrRebroadcastFull
+	}
+
+	r.txs[hash] = tx
+	r.attempts[hash] = 0
+
+	// the first "failure" is the broadcast, so the first delay is the Min
+	r.Backoff.Failed(hash.String())
+
+	return nil
+}
+
+// Remove stops rebroadcasting the TX.
+func (r Rebroadcaster) Remove(hash chainhash.Hash) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.remove(hash)
+}
+
+// Confirmed stops rebroadcasting the TX's of the block.
+func (r Rebroadcaster) Confirmed(b *wire.MsgBlock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.txs) == 0 {
+		return
+	}
+
+	for _, tx := range b.Transactions {
+		r.remove(tx.TxHash())
+	}
+}
+
+// Due returns the TX's that are due to be rebroadcast now, and the hashes
+// of those given up on, which are no longer kept.
+func (r Rebroadcaster) Due() ([]*wire.MsgTx, []chainhash.Hash) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	due := []*wire.MsgTx{}
+	dropped := []chainhash.Hash{}
+
+	for hash, tx := range r.txs {
+		if !r.Backoff.Ready(hash.String()) {
+			continue
+		}
+
+		if r.attempts[hash] >= r.MaxAttempts {
+			r.remove(hash)
+			dropped = append(dropped, hash)
+			continue
+		}
+
+		r.attempts[hash]++
+		r.Backoff.Failed(hash.String())
+
+		due = append(due, tx)
+	}
+
+	return due, dropped
+}
+
+// Len returns the number of TX's waiting to be confirmed.
+func (r Rebroadcaster) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.txs)
+}
+
+// remove forgets the TX. The caller must hold the lock.
+func (r Rebroadcaster) remove(hash chainhash.Hash) {
+	if _, ok := r.txs[hash]; !ok {
+		return
+	}
+
+	delete(r.txs, hash)
+	delete(r.attempts, hash)
+	r.Backoff.Succeeded(hash.String())
+}
@@ -0,0 +1,156 @@
+package spvnode
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// Reorg is a change of the best chain, where the blocks of the old chain
+// are disconnected back to the last block it has in common with the new
+// chain, before the blocks of the new chain are connected.
+//
+// It is passed to the block Listener before any of the connected blocks, so
+// the state built from the disconnected blocks can be rolled back first.
+type Reorg struct {
+	// AncestorHeight and AncestorHash are of the last block the old and new
+	// chains have in common.
+	AncestorHeight int32
+	AncestorHash   chainhash.Hash
+
+	// Disconnected are the blocks of the old chain, highest first, which is
+	// the order they should be rolled back in.
+	Disconnected []chainhash.Hash
+
+	// Connected are the blocks of the new chain, lowest first. Their bodies
+	// are passed to the block Listener after the Reorg, in this order.
+	Connected []chainhash.Hash
+
+	// Unconfirmed are the relevant TX's of the disconnected blocks that are
+	// not in the connected blocks, in the order they were confirmed.
+	Unconfirmed []chainhash.Hash
+}
+
+// findReorg returns the Reorg that makes the block the tip of the best
+// chain, or nil if it extends the last seen block.
+func (b BlockService) findReorg(ctx context.Context,
+	block Block) (*Reorg, error) {
+
+	if b.State == nil || b.State.LastSeen.Hash == "" ||
+		block.PrevBlock == b.State.LastSeen.Hash {
+		return nil, nil
+	}
+
+	tip := b.State.LastSeen
+	branch := block
+
+	disconnected := []Block{}
+	connected := []Block{}
+
+	// walk back the longer chain until both are at the same height, then
+	// both until they meet
+	for branch.Height > tip.Height {
+		connected = append(connected, branch)
+
+		p, err := b.readPrev(ctx, branch)
+		if err != nil {
+			return nil, err
+		}
+
+		branch = *p
+	}
+
+	for tip.Height > branch.Height {
+		disconnected = append(disconnected, tip)
+
+		p, err := b.readPrev(ctx, tip)
+		if err != nil {
+			return nil, err
+		}
+
+		tip = *p
+	}
+
+	for tip.Hash != branch.Hash {
+		disconnected = append(disconnected, tip)
+		connected = append(connected, branch)
+
+		p, err := b.readPrev(ctx, tip)
+		if err != nil {
+			return nil, err
+		}
+
+		tip = *p
+
+		if p, err = b.readPrev(ctx, branch); err != nil {
+			return nil, err
+		}
+
+		branch = *p
+	}
+
+	ancestor, err := chainhash.NewHashFromStr(tip.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	r := Reorg{
+		AncestorHeight: tip.Height,
+		AncestorHash:   *ancestor,
+		Disconnected:   []chainhash.Hash{},
+		Connected:      []chainhash.Hash{},
+		Unconfirmed:    []chainhash.Hash{},
+	}
+
+	reconfirmed := map[string]bool{}
+
+	for i := len(connected) - 1; i >= 0; i-- {
+		h, err := chainhash.NewHashFromStr(connected[i].Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Connected = append(r.Connected, *h)
+
+		for _, txHash := range connected[i].TxHashes {
+			reconfirmed[txHash] = true
+		}
+	}
+
+	for _, d := range disconnected {
+		h, err := chainhash.NewHashFromStr(d.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Disconnected = append(r.Disconnected, *h)
+	}
+
+	// lowest block first, so the TX's are in the order they were confirmed
+	for i := len(disconnected) - 1; i >= 0; i-- {
+		for _, txHash := range disconnected[i].TxHashes {
+			if reconfirmed[txHash] {
+				continue
+			}
+
+			h, err := chainhash.NewHashFromStr(txHash)
+			if err != nil {
+				return nil, err
+			}
+
+			r.Unconfirmed = append(r.Unconfirmed, *h)
+		}
+	}
+
+	return &r, nil
+}
+
+// readPrev returns the block before the block.
+func (b BlockService) readPrev(ctx context.Context, block Block) (*Block, error) {
+	prev, err := chainhash.NewHashFromStr(block.PrevBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Read(ctx, *prev)
+}
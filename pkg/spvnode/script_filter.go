@@ -0,0 +1,205 @@
+package spvnode
+
+import (
+	"bytes"
+
+	"github.com/tokenized/smart-contract/pkg/txscript"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcutil"
+)
+
+// PayToAddressFilter is a TxFilter matching the TX's with an output that
+// pays to any of the addresses, with a standard script. Unlike an
+// AddressFilter, TX's that spend from the addresses, or merely push their
+// hashes, are not matched.
+type PayToAddressFilter struct {
+	Hashes  [][]byte
+	Scripts [][]byte
+}
+
+// NewPayToAddressFilter returns a new PayToAddressFilter for the
+// addresses. It returns an error if an address has no standard script.
+func NewPayToAddressFilter(addresses ...btcutil.Address) (PayToAddressFilter, error) {
+	f := PayToAddressFilter{}
+
+	for _, a := range addresses {
+		script, err := txscript.PayToAddrScript(a)
+		if err != nil {
+			return PayToAddressFilter{}, err
+		}
+
+		f.Hashes = append(f.Hashes, a.ScriptAddress())
+		f.Scripts = append(f.Scripts, script)
+	}
+
+	return f, nil
+}
+
+// IsRelevant implements the TxFilter interface.
+func (f PayToAddressFilter) IsRelevant(tx *wire.MsgTx) bool {
+	for _, txOut := range tx.TxOut {
+		for _, script := range f.Scripts {
+			if bytes.Equal(txOut.PkScript, script) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Elements implements the TxFilter interface.
+func (f PayToAddressFilter) Elements() [][]byte {
+	return append([][]byte{}, f.Hashes...)
+}
+
+// ProtocolFilter is a TxFilter matching the TX's with an OP_RETURN output
+// whose first push starts with the prefix, such as the identifier of a
+// protocol. Outputs starting with OP_FALSE OP_RETURN are matched too.
+type ProtocolFilter struct {
+	Prefix []byte
+}
+
+// NewProtocolFilter returns a new ProtocolFilter for the prefix.
+func NewProtocolFilter(prefix []byte) ProtocolFilter {
+	return ProtocolFilter{
+		Prefix: prefix,
+	}
+}
+
+// IsRelevant implements the TxFilter interface.
+func (f ProtocolFilter) IsRelevant(tx *wire.MsgTx) bool {
+	for _, txOut := range tx.TxOut {
+		script := txOut.PkScript
+
+		if len(script) > 1 && script[0] == txscript.OP_FALSE &&
+			script[1] == txscript.OP_RETURN {
+			script = script[1:]
+		}
+
+		if len(script) == 0 || script[0] != txscript.OP_RETURN {
+			continue
+		}
+
+		pushes, err := txscript.PushedData(script[1:])
+		if err != nil || len(pushes) == 0 {
+			continue
+		}
+
+		if bytes.HasPrefix(pushes[0], f.Prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Elements implements the TxFilter interface.
+//
+// A bloom filter only matches whole pushes, not their prefix.
+func (f ProtocolFilter) Elements() [][]byte {
+	return nil
+}
+
+// MultiSigFilter is a TxFilter matching the TX's that any of the keys
+// participates in as one of the keys of a multisig script. That is a bare
+// multisig output, or an input spending a P2SH multisig output.
+type MultiSigFilter struct {
+	PubKeys [][]byte
+}
+
+// NewMultiSigFilter returns a new MultiSigFilter for the serialized public
+// keys.
+func NewMultiSigFilter(pubKeys ...[]byte) MultiSigFilter {
+	return MultiSigFilter{
+		PubKeys: pubKeys,
+	}
+}
+
+// IsRelevant implements the TxFilter interface.
+func (f MultiSigFilter) IsRelevant(tx *wire.MsgTx) bool {
+	for _, txOut := range tx.TxOut {
+		if f.hasKey(txOut.PkScript) {
+			return true
+		}
+	}
+
+	for _, txIn := range tx.TxIn {
+		pushes, err := txscript.PushedData(txIn.SignatureScript)
+		if err != nil || len(pushes) == 0 {
+			continue
+		}
+
+		// the redeem script is the last push of a P2SH input
+		if f.hasKey(pushes[len(pushes)-1]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Elements implements the TxFilter interface.
+//
+// The spends of P2SH multisig outputs push the keys inside the redeem
+// script, which a bloom filter can't match.
+func (f MultiSigFilter) Elements() [][]byte {
+	return nil
+}
+
+// hasKey returns true if the script is a multisig script with any of the
+// keys.
+func (f MultiSigFilter) hasKey(script []byte) bool {
+	if txscript.GetScriptClass(script) != txscript.MultiSigTy {
+		return false
+	}
+
+	pushes, err := txscript.PushedData(script)
+	if err != nil {
+		return false
+	}
+
+	for _, data := range pushes {
+		for _, key := range f.PubKeys {
+			if bytes.Equal(data, key) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ValueFilter is a TxFilter matching the TX's with an output of at least
+// Min, and at most Max, satoshis.
+type ValueFilter struct {
+	Min int64
+	Max int64
+}
+
+// NewValueFilter returns a new ValueFilter for the range of values.
+func NewValueFilter(min, max int64) ValueFilter {
+	return ValueFilter{
+		Min: min,
+		Max: max,
+	}
+}
+
+// IsRelevant implements the TxFilter interface.
+func (f ValueFilter) IsRelevant(tx *wire.MsgTx) bool {
+	for _, txOut := range tx.TxOut {
+		if txOut.Value >= f.Min && txOut.Value <= f.Max {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Elements implements the TxFilter interface.
+//
+// Values are not pushed data, so can't be matched by a bloom filter.
+func (f ValueFilter) Elements() [][]byte {
+	return nil
+}
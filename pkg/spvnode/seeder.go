@@ -0,0 +1,178 @@
+package spvnode
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/spvnode/logger"
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+const (
+	// SourceDNSSeed is the Source of peers resolved from a DNS seed.
+	SourceDNSSeed = "dns_seed"
+
+	// seedInterval is the minimum time between two rounds of DNS seed
+	// lookups, so a node that keeps failing to connect does not flood the
+	// seeds.
+	seedInterval = 5 * time.Minute
+
+	// peerMaxAge is how long since any peer was last seen before the peers
+	// are considered stale, and the seeds are asked again.
+	peerMaxAge = 24 * time.Hour
+
+	// peerPruneAge is how long since a peer was last seen before it is no
+	// longer connected to, and is removed from the PeerRepository.
+	peerPruneAge = 30 * 24 * time.Hour
+)
+
+// ErrSeedRateLimited is returned when the seeds were looked up too recently.
+var ErrSeedRateLimited = errs.New(errs.Temporary, "DNS seeds looked up too recently")
+
+// DefaultSeeds are the DNS seeds of each network, used when none are
+// configured.
+var DefaultSeeds = map[wire.BitcoinNet][]string{
+	MainNetBch: []string{
+		"seed.bitcoinabc.org",
+		"seed-abc.bitcoinforks.org",
+		"btccash-seeder.bitcoinunlimited.info",
+		"seed.bitprim.org",
+		"seed.deadalnix.me",
+	},
+	TestNetBch: []string{
+		"testnet-seed.bitcoinabc.org",
+		"testnet-seed-abc.bitcoinforks.org",
+		"testnet-seed.bitprim.org",
+		"testnet-seed.deadalnix.me",
+	},
+	StnBch: []string{
+		"stn-seed.bitcoinsv.io",
+	},
+}
+
+// DefaultPorts are the P2P ports of each network.
+var DefaultPorts = map[wire.BitcoinNet]int{
+	MainNetBch: 8333,
+	TestNetBch: 18333,
+	RegTestBch: 18444,
+	StnBch:     9333,
+}
+
+// Seeder bootstraps the PeerRepository from DNS seeds, when it is empty or
+// stale.
+//
+// Lookups are rate limited, and each address is stored once however many
+// seeds return it. A Seeder is safe for concurrent use.
+type Seeder struct {
+	Seeds []string
+	Port  int
+	Peers PeerRepository
+
+	// Lookup resolves a hostname to its addresses.
+	Lookup func(string) ([]string, error)
+
+	mu   *sync.Mutex
+	last *time.Time
+}
+
+// NewSeeder returns a new Seeder for the network, using the seeds given or
+// the DefaultSeeds of the network if there are none.
+func NewSeeder(network wire.BitcoinNet,
+	seeds []string,
+	peers PeerRepository) Seeder {
+
+	if len(seeds) == 0 {
+		seeds = DefaultSeeds[network]
+	}
+
+	return Seeder{
+		Seeds:  seeds,
+		Port:   DefaultPorts[network],
+		Peers:  peers,
+		Lookup: lookupHost,
+		mu:     &sync.Mutex{},
+		last:   &time.Time{},
+	}
+}
+
+// NeedsSeeding returns true if there are no peers, or none of them have
+// been seen recently.
+func (s Seeder) NeedsSeeding(ctx context.Context) (bool, error) {
+	peers, err := s.Peers.All(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	cutoff := time.Now().Add(-peerMaxAge).UnixNano()
+
+	for _, p := range peers {
+		if p.LastSeen >= cutoff {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// Bootstrap looks up each seed, and stores the peers that are returned.
+//
+// It returns the peers that were stored, or ErrSeedRateLimited if the seeds
+// were looked up too recently. A seed that fails to resolve is skipped.
+func (s Seeder) Bootstrap(ctx context.Context) ([]Peer, error) {
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(*s.last) < seedInterval {
+		return nil, ErrSeedRateLimited
+	}
+
+	*s.last = now
+
+	seen := map[string]bool{}
+	peers := []Peer{}
+
+	for _, seed := range s.Seeds {
+		hosts, err := s.Lookup(seed)
+		if err != nil {
+			log.Warnf("Failed to look up DNS seed %v : %v", seed, err)
+			continue
+		}
+
+		for _, host := range hosts {
+			address := net.JoinHostPort(host, strconv.Itoa(s.Port))
+			if seen[address] {
+				continue
+			}
+
+			seen[address] = true
+
+			p := Peer{
+				Address:  address,
+				Source:   SourceDNSSeed,
+				LastSeen: now.UnixNano(),
+			}
+
+			if err := s.Peers.Write(ctx, p); err != nil {
+				return nil, err
+			}
+
+			peers = append(peers, p)
+		}
+	}
+
+	log.Infof("Found %v peers from %v DNS seeds", len(peers), len(s.Seeds))
+
+	return peers, nil
+}
+
+// lookupHost resolves a hostname with the system resolver.
+func lookupHost(host string) ([]string, error) {
+	return net.LookupHost(host)
+}
@@ -0,0 +1,134 @@
+package spvnode
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+const (
+	// seenTxTimeout is how long an unconfirmed TX is remembered after it is
+	// seen, so copies of it from other peers aren't passed on again.
+	seenTxTimeout = 24 * time.Hour
+
+	// seenTxDepth is how many blocks deep a confirmed TX is remembered, so
+	// copies of it from peers that haven't seen the block aren't passed on
+	// as unconfirmed.
+	seenTxDepth = 6
+)
+
+// SeenTxs remembers the TX's passed to the TX Listener, and whether they
+// are confirmed, so each TX is passed on once however many peers send it,
+// and not at all once it is confirmed.
+//
+// Copies share the TX's, and it is safe for concurrent use.
+type SeenTxs struct {
+	mu  *sync.Mutex
+	txs map[chainhash.Hash]seenTx
+}
+
+// seenTx is the state of a TX of the SeenTxs.
+type seenTx struct {
+	// seen is when the TX was seen, or unconfirmed by a reorg.
+	seen time.Time
+
+	// height is of the block the TX is confirmed in, or 0 if it is
+	// unconfirmed.
+	height int32
+}
+
+// NewSeenTxs returns a new, empty, SeenTxs.
+func NewSeenTxs() SeenTxs {
+	return SeenTxs{
+		mu:  &sync.Mutex{},
+		txs: map[chainhash.Hash]seenTx{},
+	}
+}
+
+// Seen records that the TX was seen unconfirmed at the time, and returns
+// false if it was already seen, or is confirmed.
+func (s SeenTxs) Seen(hash chainhash.Hash, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.txs[hash]; ok {
+		return false
+	}
+
+	s.txs[hash] = seenTx{
+		seen: now,
+	}
+
+	return true
+}
+
+// Confirmed records that the TX's of the block at the height are
+// confirmed. Only those already seen, or relevant to the filters, are
+// recorded, as peers rarely send confirmed TX's.
+func (s SeenTxs) Confirmed(b *wire.MsgBlock, height int32,
+	filters []TxFilter) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tx := range b.Transactions {
+		hash := tx.TxHash()
+
+		t, ok := s.txs[hash]
+		if !ok && !isRelevant(filters, tx) {
+			continue
+		}
+
+		t.height = height
+		s.txs[hash] = t
+	}
+}
+
+// Unconfirmed records that the TX's are unconfirmed at the time, such as
+// after a reorg. They are still seen.
+func (s SeenTxs) Unconfirmed(hashes []chainhash.Hash, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, hash := range hashes {
+		s.txs[hash] = seenTx{
+			seen: now,
+		}
+	}
+}
+
+// Forget forgets the TX's, such as those that expired from the Mempool, so
+// they are passed on again if they are seen.
+func (s SeenTxs) Forget(hashes []chainhash.Hash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, hash := range hashes {
+		delete(s.txs, hash)
+	}
+}
+
+// Expire forgets the unconfirmed TX's seen before the time, and the TX's
+// confirmed below the height.
+func (s SeenTxs) Expire(before time.Time, height int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, t := range s.txs {
+		if t.height == 0 && t.seen.Before(before) ||
+			t.height != 0 && t.height < height {
+			delete(s.txs, hash)
+		}
+	}
+}
+
+// Len returns the number of TX's remembered.
+func (s SeenTxs) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.txs)
+}
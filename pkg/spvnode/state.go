@@ -3,16 +3,19 @@ package spvnode
 import (
 	"context"
 	"encoding/json"
-	"errors"
 
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/storage"
 )
 
+// StateKey is the storage key the State is written to.
+const StateKey = "state.json"
+
 type State struct {
 	LastSeen Block `json:"last_seen"`
 }
 
-var ErrStateNotFound = errors.New("State not found")
+var ErrStateNotFound = errs.New(errs.NotFound, "State not found")
 
 type StateRepository struct {
 	Storage storage.Storage
@@ -57,5 +60,30 @@ func (r StateRepository) Read(ctx context.Context, id string) (*State, error) {
 }
 
 func (r StateRepository) buildPath() string {
-	return "state.json"
+	return StateKey
+}
+
+// TipKeys returns the storage key of the Block the stored State was last
+// seen at, the tip of the BlockRepository, so it can be sealed along with
+// the State. There is none before the State is written.
+func TipKeys(ctx context.Context, store storage.Reader) ([]string, error) {
+	b, err := store.Read(ctx, StateKey)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	s := State{}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+
+	if s.LastSeen.Hash == "" {
+		return nil, nil
+	}
+
+	return []string{blockPath(s.LastSeen.Hash)}, nil
 }
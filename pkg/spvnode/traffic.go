@@ -0,0 +1,186 @@
+package spvnode
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+// PeerKind is how a peer is connected to the Node.
+type PeerKind string
+
+const (
+	// PeerTrusted is the trusted node.
+	PeerTrusted PeerKind = "trusted"
+
+	// PeerOutbound is an untrusted peer connected to by the Node, such as
+	// to download blocks.
+	PeerOutbound PeerKind = "outbound"
+
+	// PeerInbound is a peer that connected to the Node.
+	PeerInbound PeerKind = "inbound"
+)
+
+// PeerTraffic is the traffic with a connected peer, as it is inspected.
+type PeerTraffic struct {
+	Peer string
+	Kind PeerKind
+
+	BytesSent     uint64
+	BytesReceived uint64
+
+	// MessagesSent and MessagesReceived are counted by command.
+	MessagesSent     map[string]uint64
+	MessagesReceived map[string]uint64
+
+	// ConnectedAt and LastActivity are when the peer was connected, and
+	// when a message was last sent to or received from it, in nanoseconds
+	// since the epoch. Uptime is how long it has been connected.
+	ConnectedAt  int64
+	LastActivity int64
+	Uptime       time.Duration
+}
+
+// Traffic counts the bytes and messages sent to and received from each
+// connected peer, to find peers that misbehave or are of no use.
+//
+// Copies share the counts, and it is safe for concurrent use. The zero
+// Traffic counts nothing.
+type Traffic struct {
+	mu    *sync.Mutex
+	peers map[string]*PeerTraffic
+}
+
+// NewTraffic returns a new Traffic, without peers.
+func NewTraffic() Traffic {
+	return Traffic{
+		mu:    &sync.Mutex{},
+		peers: map[string]*PeerTraffic{},
+	}
+}
+
+// Connected starts counting the traffic of the peer, from zero.
+func (t Traffic) Connected(address string, kind PeerKind) {
+	if t.mu == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now().UnixNano()
+
+	t.peers[address] = &PeerTraffic{
+		Peer:             address,
+		Kind:             kind,
+		MessagesSent:     map[string]uint64{},
+		MessagesReceived: map[string]uint64{},
+		ConnectedAt:      now,
+		LastActivity:     now,
+	}
+}
+
+// Disconnected stops counting the traffic of the peer, and forgets it.
+func (t Traffic) Disconnected(address string) {
+	if t.mu == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.peers, address)
+}
+
+// Sent counts a message of the command, and its size in bytes, sent to the
+// peer.
+func (t Traffic) Sent(address, command string, size int) {
+	if t.mu == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.peers[address]
+	if !ok {
+		return
+	}
+
+	p.BytesSent += uint64(size)
+	p.MessagesSent[command]++
+	p.LastActivity = time.Now().UnixNano()
+}
+
+// Received counts a message of the command, and its size in bytes,
+// received from the peer. The command is empty if the message couldn't be
+// decoded.
+func (t Traffic) Received(address, command string, size int) {
+	if t.mu == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.peers[address]
+	if !ok {
+		return
+	}
+
+	p.BytesReceived += uint64(size)
+	if command != "" {
+		p.MessagesReceived[command]++
+	}
+	p.LastActivity = time.Now().UnixNano()
+}
+
+// Report returns the traffic of the connected peers, by address.
+func (t Traffic) Report() []PeerTraffic {
+	if t.mu == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	peers := make([]PeerTraffic, 0, len(t.peers))
+
+	for _, p := range t.peers {
+		c := *p
+		c.MessagesSent = copyCounts(p.MessagesSent)
+		c.MessagesReceived = copyCounts(p.MessagesReceived)
+		c.Uptime = now.Sub(time.Unix(0, p.ConnectedAt))
+
+		peers = append(peers, c)
+	}
+
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].Peer < peers[j].Peer
+	})
+
+	return peers
+}
+
+// copyCounts returns a copy of the counts.
+func copyCounts(counts map[string]uint64) map[string]uint64 {
+	c := make(map[string]uint64, len(counts))
+	for k, v := range counts {
+		c[k] = v
+	}
+
+	return c
+}
+
+// command returns the command of the message, or an empty string if there
+// is no message.
+func command(m wire.Message) string {
+	if m == nil {
+		return ""
+	}
+
+	return m.Command()
+}
@@ -0,0 +1,120 @@
+package spvnode
+
+import (
+	"bytes"
+
+	"github.com/tokenized/smart-contract/pkg/txscript"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcutil"
+)
+
+// TxFilter selects the TX's that are relevant to the Listeners of a Node.
+//
+// The Elements of all TxFilters are loaded into a bloom filter on untrusted
+// peers, so they only send relevant TX's.
+type TxFilter interface {
+	// IsRelevant returns true if the TX is relevant.
+	IsRelevant(tx *wire.MsgTx) bool
+
+	// Elements returns the data that a relevant TX pushes in its scripts,
+	// to be matched by a bloom filter, or nil if relevant TX's can't be
+	// matched by their data.
+	Elements() [][]byte
+}
+
+// AddressFilter is a TxFilter matching the TX's that pay to, or spend
+// from, any of the addresses.
+type AddressFilter struct {
+	Hashes [][]byte
+}
+
+// NewAddressFilter returns a new AddressFilter for the addresses.
+func NewAddressFilter(addresses ...btcutil.Address) AddressFilter {
+	f := AddressFilter{}
+
+	for _, a := range addresses {
+		f.Hashes = append(f.Hashes, a.ScriptAddress())
+	}
+
+	return f
+}
+
+// IsRelevant implements the TxFilter interface.
+//
+// A TX pays to an address if an output script pushes the hash of the
+// address, and spends from it if an input script pushes the key that
+// hashes to it.
+func (f AddressFilter) IsRelevant(tx *wire.MsgTx) bool {
+	for _, txOut := range tx.TxOut {
+		if f.pushesAny(txOut.PkScript, false) {
+			return true
+		}
+	}
+
+	for _, txIn := range tx.TxIn {
+		if f.pushesAny(txIn.SignatureScript, true) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Elements implements the TxFilter interface.
+func (f AddressFilter) Elements() [][]byte {
+	return append([][]byte{}, f.Hashes...)
+}
+
+// pushesAny returns true if the script pushes any of the hashes, or with
+// hash set, data that hashes to any of them.
+func (f AddressFilter) pushesAny(script []byte, hash bool) bool {
+	pushes, err := txscript.PushedData(script)
+	if err != nil {
+		return false
+	}
+
+	for _, data := range pushes {
+		if hash {
+			data = btcutil.Hash160(data)
+		}
+
+		for _, h := range f.Hashes {
+			if bytes.Equal(data, h) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isRelevant returns true if the TX is relevant to any of the filters, or
+// there are no filters.
+func isRelevant(filters []TxFilter, tx *wire.MsgTx) bool {
+	if len(filters) == 0 {
+		return true
+	}
+
+	for _, f := range filters {
+		if f.IsRelevant(tx) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// relevantTxHashes returns the hashes of the TX's of the block that are
+// relevant to any of the filters.
+func relevantTxHashes(filters []TxFilter, b *wire.MsgBlock) []string {
+	hashes := []string{}
+
+	for _, tx := range b.Transactions {
+		if isRelevant(filters, tx) {
+			hashes = append(hashes, tx.TxHash().String())
+		}
+	}
+
+	return hashes
+}
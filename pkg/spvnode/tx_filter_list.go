@@ -0,0 +1,106 @@
+package spvnode
+
+import (
+	"sync"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
+)
+
+// TxFilterList is the list of TxFilters of a Node, which may be added to,
+// or replaced, while it is running.
+//
+// It is itself a TxFilter, relevant to every TX while it is empty, so the
+// handlers and downloads given it match the TX's of filters added later.
+// Copies share the list, and it is safe for concurrent use.
+type TxFilterList struct {
+	mu      *sync.RWMutex
+	filters *[]TxFilter
+
+	// replaced is the number of times the filters were replaced, so the
+	// bloom filters made from them are reloaded rather than added to.
+	replaced *int
+}
+
+// NewTxFilterList returns a new TxFilterList of the filters.
+func NewTxFilterList(filters ...TxFilter) TxFilterList {
+	list := append([]TxFilter{}, filters...)
+
+	return TxFilterList{
+		mu:       &sync.RWMutex{},
+		filters:  &list,
+		replaced: new(int),
+	}
+}
+
+// Add adds the filter to the list.
+func (l TxFilterList) Add(f TxFilter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	*l.filters = append(*l.filters, f)
+}
+
+// Replace replaces the filters of the list.
+func (l TxFilterList) Replace(filters ...TxFilter) {
+	list := append([]TxFilter{}, filters...)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	*l.filters = list
+	*l.replaced++
+}
+
+// All returns the filters of the list, in the order they were added.
+func (l TxFilterList) All() []TxFilter {
+	if l.mu == nil {
+		return nil
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return append([]TxFilter{}, *l.filters...)
+}
+
+// snapshot returns the filters of the list, and the number of times they
+// were replaced.
+func (l TxFilterList) snapshot() ([]TxFilter, int) {
+	if l.mu == nil {
+		return nil, 0
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return append([]TxFilter{}, *l.filters...), *l.replaced
+}
+
+// Len returns the number of filters in the list.
+func (l TxFilterList) Len() int {
+	if l.mu == nil {
+		return 0
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return len(*l.filters)
+}
+
+// IsRelevant implements the TxFilter interface.
+func (l TxFilterList) IsRelevant(tx *wire.MsgTx) bool {
+	return isRelevant(l.All(), tx)
+}
+
+// Elements implements the TxFilter interface.
+//
+// An empty list is relevant to every TX, so it has no elements.
+func (l TxFilterList) Elements() [][]byte {
+	filters := l.All()
+	if len(filters) == 0 {
+		return nil
+	}
+
+	return AnyOf(filters...).Elements()
+}
@@ -2,27 +2,55 @@ package spvnode
 
 import (
 	"context"
-	"errors"
+	"time"
 
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/spvnode/logger"
 	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 )
 
 // TXHandler exists to handle the Ping command.
 type TXHandler struct {
 	Config       Config
 	BlockService *BlockService
+	Mempool      Mempool
 	Listener     Listener
+	Tracker      BroadcastTracker
+	TxRequests   TxRequests
+	Orphans      OrphanPool
+	DoubleSpends DoubleSpends
+	SeenTxs      SeenTxs
+
+	// Filters select the TX's that are kept in the Mempool to be saved,
+	// when it is persisted.
+	Filters []TxFilter
 }
 
 // NewTXHandler returns a new TXHandler with the given Config.
 func NewTXHandler(config Config,
 	blockService *BlockService,
-	listener Listener) TXHandler {
+	mempool Mempool,
+	listener Listener,
+	tracker BroadcastTracker,
+	txRequests TxRequests,
+	orphans OrphanPool,
+	doubleSpends DoubleSpends,
+	seen SeenTxs,
+	filters []TxFilter) TXHandler {
 
 	return TXHandler{
 		Config:       config,
 		BlockService: blockService,
+		Mempool:      mempool,
 		Listener:     listener,
+		Tracker:      tracker,
+		TxRequests:   txRequests,
+		Orphans:      orphans,
+		DoubleSpends: doubleSpends,
+		SeenTxs:      seen,
+		Filters:      filters,
 	}
 }
 
@@ -35,7 +63,7 @@ func (h TXHandler) Handle(ctx context.Context,
 
 	msg, ok := m.(*wire.MsgTx)
 	if !ok {
-		return nil, errors.New("Could not assert as *wire.MsgTx")
+		return nil, errs.New(errs.Invalid, "Could not assert as *wire.MsgTx")
 	}
 
 	return h.handle(ctx, msg)
@@ -43,14 +71,173 @@ func (h TXHandler) Handle(ctx context.Context,
 
 // handle processes the MsgTxn.
 //
-// There is no response for this handler.
+// There is no response for this handler, but the requests of the TX's
+// waiting for this one to arrive may be sent.
 func (h TXHandler) handle(ctx context.Context,
 	tx *wire.MsgTx) ([]wire.Message, error) {
 
+	out := h.TxRequests.Received(tx.TxHash())
+
+	// orphans whose parents never arrived are passed on anyway
+	for _, orphan := range h.Orphans.Expire(time.Now().Add(-orphanTimeout)) {
+		h.process(ctx, orphan)
+	}
+
+	// a relevant TX is held until the parents it was announced after
+	// arrive, so the listener sees them first
+	if missing := h.missingParents(tx); len(missing) > 0 && h.Orphans.Add(tx, missing) {
+		return out, nil
+	}
+
+	h.process(ctx, tx)
+
+	return out, nil
+}
+
+// Resolved passes on the orphans waiting for the TX's, which are known to
+// have arrived some other way, such as in a block, or to not be coming.
+func (h TXHandler) Resolved(ctx context.Context, hashes []chainhash.Hash) {
+	for _, hash := range hashes {
+		for _, orphan := range h.Orphans.Arrived(hash) {
+			h.process(ctx, orphan)
+		}
+	}
+}
+
+// Connected passes on the orphans waiting for the TX's of the block, and
+// the double spends it resolves, once the block has been passed on. The
+// TX's of the block are recorded as confirmed, so they aren't passed on if
+// they are sent again.
+func (h TXHandler) Connected(ctx context.Context, b *wire.MsgBlock) {
+	h.Resolved(ctx, blockTxHashes(b))
+
+	if block, err := h.BlockService.Read(ctx, b.BlockHash()); err == nil {
+		h.SeenTxs.Confirmed(b, block.Height, h.Filters)
+		h.SeenTxs.Expire(time.Now().Add(-seenTxTimeout),
+			block.Height-seenTxDepth)
+	}
+
+	h.DoubleSpends.Expire(time.Now().Add(-doubleSpendWindow))
+
+	for _, d := range h.DoubleSpends.Confirmed(b) {
+		h.notifyDoubleSpend(ctx, d)
+	}
+}
+
+// process passes on the TX, then the orphans that were waiting for it.
+func (h TXHandler) process(ctx context.Context, tx *wire.MsgTx) {
+	txs := []*wire.MsgTx{tx}
+
+	for len(txs) > 0 {
+		tx := txs[0]
+		txs = txs[1:]
+
+		h.processTx(ctx, tx)
+
+		txs = append(txs, h.Orphans.Arrived(tx.TxHash())...)
+	}
+}
+
+// processTx keeps the TX, and passes it on to the listener, unless it was
+// already passed on, such as when sent by several peers, or is confirmed.
+func (h TXHandler) processTx(ctx context.Context, tx *wire.MsgTx) {
+	if !h.SeenTxs.Seen(tx.TxHash(), time.Now()) {
+		return
+	}
+	// keep the TX to reconstruct the compact block it is confirmed in, or
+	// to save if it is relevant
+	if !h.Config.HeadersOnly && (h.Config.CompactBlocks ||
+		(h.Config.PersistMempool && isRelevant(h.Filters, tx))) {
+
+		h.Mempool.Add(tx)
+	}
+
+	// a broadcast TX may have been double spent
+	h.Tracker.Seen(tx)
+
 	if h.Listener != nil {
 		// notify the listener
 		h.Listener.Handle(ctx, tx)
 	}
 
-	return nil, nil
+	found := h.DoubleSpends.Seen(tx, isRelevant(h.Filters, tx))
+	if len(found) == 0 {
+		return
+	}
+
+	for _, d := range h.classify(tx, found) {
+		h.notifyDoubleSpend(ctx, d)
+	}
+}
+
+// classify sets the Kind of the DoubleSpends of the TX, and keeps the TX's
+// of the Mempool that it calls for.
+//
+// The TX is a DoubleSpendReplacement if it replaces each TX seen before it
+// spending the same outputs, which are removed from the Mempool. Otherwise
+// it is a DoubleSpendConflict, and it is removed from the Mempool so the TX
+// seen first is kept.
+func (h TXHandler) classify(tx *wire.MsgTx,
+	found []DoubleSpend) []DoubleSpend {
+
+	kind := DoubleSpendReplacement
+	replaced := []chainhash.Hash{}
+
+	for _, d := range found {
+		earlier := d.Txs[len(d.Txs)-2]
+		if !h.Mempool.Replaces(tx, earlier) {
+			kind = DoubleSpendConflict
+			break
+		}
+
+		replaced = append(replaced, earlier)
+	}
+
+	if kind == DoubleSpendReplacement {
+		for _, hash := range replaced {
+			h.Mempool.Remove(hash)
+		}
+	} else {
+		h.Mempool.Remove(tx.TxHash())
+	}
+
+	for i := range found {
+		found[i].Kind = kind
+		h.DoubleSpends.Classify(found[i])
+	}
+
+	return found
+}
+
+// notifyDoubleSpend passes the DoubleSpend to the listener.
+func (h TXHandler) notifyDoubleSpend(ctx context.Context, d DoubleSpend) {
+	log := logger.NewLoggerFromContext(ctx).Sugar()
+	log.Warnf("Double spend (%v) of %v by %v TX's", d.Kind, d.OutPoint,
+		len(d.Txs))
+
+	if h.Listener == nil {
+		return
+	}
+
+	if err := h.Listener.HandleDoubleSpend(ctx, d); err != nil {
+		log.Errorf("Failed to handle double spend : %v", err)
+	}
+}
+
+// missingParents returns the TX's spent by the relevant TX that were
+// announced, and haven't arrived yet.
+func (h TXHandler) missingParents(tx *wire.MsgTx) []chainhash.Hash {
+	if !isRelevant(h.Filters, tx) {
+		return nil
+	}
+
+	missing := []chainhash.Hash{}
+
+	for _, txIn := range tx.TxIn {
+		if h.TxRequests.Pending(txIn.PreviousOutPoint.Hash) {
+			missing = append(missing, txIn.PreviousOutPoint.Hash)
+		}
+	}
+
+	return missing
 }
@@ -0,0 +1,177 @@
+package spvnode
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+const (
+	// defaultTxsInFlight is the number of TX's requested at once, if the
+	// MaxTxsInFlight of the Config is 0.
+	defaultTxsInFlight = 1000
+
+	// txRequestTimeout is how long a requested TX counts as in flight. A
+	// node may not answer a request for a TX it has since dropped.
+	txRequestTimeout = 2 * time.Minute
+
+	// maxTxRequestQueue is the most announced TX's waiting to be requested.
+	// Further announcements are dropped, as they are re-announced by other
+	// peers of the node.
+	maxTxRequestQueue = 100000
+)
+
+// TxRequests batches the requests of the TX's announced by the trusted
+// node into getdata messages, and pipelines them, so no more than
+// MaxInFlight are requested and not yet received at once. The rest wait
+// until earlier requests are answered.
+//
+// It is safe for concurrent use.
+type TxRequests struct {
+	MaxInFlight int
+
+	mu *sync.Mutex
+
+	// inFlight are the requested TX's, by when they were requested.
+	inFlight map[chainhash.Hash]time.Time
+
+	// queue are the TX's waiting to be requested, in the order they were
+	// announced.
+	queue  *[]chainhash.Hash
+	queued map[chainhash.Hash]bool
+}
+
+// NewTxRequests returns a new TxRequests with the limit of TX's in flight,
+// or defaultTxsInFlight if it is 0.
+func NewTxRequests(maxInFlight int) TxRequests {
+	if maxInFlight <= 0 {
+		maxInFlight = defaultTxsInFlight
+	}
+
+	queue := []chainhash.Hash{}
+
+	return TxRequests{
+		MaxInFlight: maxInFlight,
+		mu:          &sync.Mutex{},
+		inFlight:    map[chainhash.Hash]time.Time{},
+		queue:       &queue,
+		queued:      map[chainhash.Hash]bool{},
+	}
+}
+
+// Announced queues the TX's that aren't already requested or queued, and
+// returns the requests of as many queued TX's as fit in flight.
+func (r TxRequests) Announced(hashes []chainhash.Hash) []wire.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, hash := range hashes {
+		if _, ok := r.inFlight[hash]; ok || r.queued[hash] {
+			continue
+		}
+
+		if len(r.queued) >= maxTxRequestQueue {
+			break
+		}
+
+		*r.queue = append(*r.queue, hash)
+		r.queued[hash] = true
+	}
+
+	return r.next()
+}
+
+// Received marks the TX as no longer in flight, because it arrived or was
+// not found, and returns the requests of the queued TX's that now fit.
+func (r TxRequests) Received(hashes ...chainhash.Hash) []wire.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, hash := range hashes {
+		delete(r.inFlight, hash)
+	}
+
+	return r.next()
+}
+
+// Pending returns true if the TX is requested, or waiting to be, and hasn't
+// arrived.
+func (r TxRequests) Pending(hash chainhash.Hash) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.inFlight[hash]
+
+	return ok || r.queued[hash]
+}
+
+// InFlight returns the number of TX's requested and not yet received.
+func (r TxRequests) InFlight() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.inFlight)
+}
+
+// Reset forgets all requested and queued TX's, such as when the trusted
+// node they were requested from is lost.
+func (r TxRequests) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash := range r.inFlight {
+		delete(r.inFlight, hash)
+	}
+
+	for hash := range r.queued {
+		delete(r.queued, hash)
+	}
+
+	*r.queue = (*r.queue)[:0]
+}
+
+// next returns a getdata message of the queued TX's that fit in flight, or
+// nil if none do. Requests that timed out no longer count as in flight.
+//
+// The lock must be held.
+func (r TxRequests) next() []wire.Message {
+	now := time.Now()
+
+	for hash, requested := range r.inFlight {
+		if now.Sub(requested) > txRequestTimeout {
+			delete(r.inFlight, hash)
+		}
+	}
+
+	count := r.MaxInFlight - len(r.inFlight)
+	if count > len(*r.queue) {
+		count = len(*r.queue)
+	}
+
+	if count > wire.MaxInvPerMsg {
+		count = wire.MaxInvPerMsg
+	}
+
+	if count <= 0 {
+		return nil
+	}
+
+	getdata := wire.NewMsgGetDataSizeHint(uint(count))
+
+	queue := *r.queue
+
+	for i := range queue[:count] {
+		hash := queue[i]
+
+		getdata.AddInvVect(wire.NewInvVect(wire.InvTypeTx, &hash))
+		r.inFlight[hash] = now
+		delete(r.queued, hash)
+	}
+
+	*r.queue = append(queue[:0], queue[count:]...)
+
+	return []wire.Message{getdata}
+}
@@ -0,0 +1,55 @@
+package spvnode
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+	"github.com/tokenized/smart-contract/pkg/storage"
+)
+
+// UTXOKey is the storage key the outputs of a UTXOTracker are written to.
+const UTXOKey = "utxos.json"
+
+var ErrUTXOsNotFound = errs.New(errs.NotFound, "UTXOs not found")
+
+// UTXORepository is used for managing the saved outputs of a UTXOTracker.
+type UTXORepository struct {
+	Storage storage.Storage
+}
+
+// NewUTXORepository returns a new UTXORepository.
+func NewUTXORepository(store storage.Storage) UTXORepository {
+	return UTXORepository{
+		Storage: store,
+	}
+}
+
+// Write replaces the saved outputs.
+func (r UTXORepository) Write(ctx context.Context, utxos []UTXO) error {
+	b, err := json.Marshal(utxos)
+	if err != nil {
+		return err
+	}
+
+	return r.Storage.Write(ctx, UTXOKey, b, nil)
+}
+
+// Read returns the saved outputs.
+func (r UTXORepository) Read(ctx context.Context) ([]UTXO, error) {
+	b, err := r.Storage.Read(ctx, UTXOKey)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			err = ErrUTXOsNotFound
+		}
+
+		return nil, err
+	}
+
+	utxos := []UTXO{}
+	if err := json.Unmarshal(b, &utxos); err != nil {
+		return nil, err
+	}
+
+	return utxos, nil
+}
@@ -0,0 +1,360 @@
+package spvnode
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/tokenized/smart-contract/pkg/storage"
+	"github.com/tokenized/smart-contract/pkg/txscript"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+)
+
+const (
+	// utxoReorgDepth is the number of blocks a spent output is kept after
+	// its spend is confirmed, so it can be restored by a reorg.
+	utxoReorgDepth = 100
+)
+
+// UTXO is an output paying to a watched address, as it is tracked by a
+// UTXOTracker.
+type UTXO struct {
+	TxHash   string `json:"tx_hash"`
+	Index    uint32 `json:"index"`
+	Value    int64  `json:"value"`
+	PkScript []byte `json:"pk_script"`
+	Address  string `json:"address"`
+
+	// Block and Height are of the block the TX is confirmed in. They are
+	// empty while it is unconfirmed.
+	Block  string `json:"block,omitempty"`
+	Height int32  `json:"height,omitempty"`
+
+	// SpentBy is the TX spending the output, and SpentBlock and SpentHeight
+	// the block it is confirmed in. They are empty while it is unspent.
+	SpentBy     string `json:"spent_by,omitempty"`
+	SpentBlock  string `json:"spent_block,omitempty"`
+	SpentHeight int32  `json:"spent_height,omitempty"`
+}
+
+// UTXOTracker is a Listener that keeps the spendable outputs of the watched
+// addresses, from the blocks and unconfirmed TX's passed to it. It must be
+// registered as both the TX and the block Listener.
+//
+// Only outputs with the standard script of an address are tracked. The
+// outputs are saved after each block, reorg and expiry, but not after each
+// unconfirmed TX, so those since the last save are lost on a restart. It
+// is safe for concurrent use.
+type UTXOTracker struct {
+	BlockService *BlockService
+
+	repo UTXORepository
+
+	// addresses are the watched addresses, by their script.
+	addresses map[string]btcutil.Address
+
+	mu    *sync.Mutex
+	utxos map[wire.OutPoint]*UTXO
+}
+
+// NewUTXOTracker returns a new UTXOTracker of the addresses, which saves
+// the outputs to the store. The BlockService gives the heights of blocks.
+// It returns an error if an address has no standard script.
+func NewUTXOTracker(addresses []btcutil.Address,
+	blockService *BlockService,
+	store storage.Storage) (UTXOTracker, error) {
+
+	t := UTXOTracker{
+		BlockService: blockService,
+		repo:         NewUTXORepository(store),
+		addresses:    map[string]btcutil.Address{},
+		mu:           &sync.Mutex{},
+		utxos:        map[wire.OutPoint]*UTXO{},
+	}
+
+	for _, a := range addresses {
+		script, err := txscript.PayToAddrScript(a)
+		if err != nil {
+			return UTXOTracker{}, err
+		}
+
+		t.addresses[string(script)] = a
+	}
+
+	return t, nil
+}
+
+// Filter returns a TxFilter of the TX's that pay to, or spend from, the
+// watched addresses, to be added to the Node.
+func (t UTXOTracker) Filter() TxFilter {
+	addresses := make([]btcutil.Address, 0, len(t.addresses))
+	for _, a := range t.addresses {
+		addresses = append(addresses, a)
+	}
+
+	return NewAddressFilter(addresses...)
+}
+
+// Load replaces the outputs with those saved.
+func (t UTXOTracker) Load(ctx context.Context) error {
+	utxos, err := t.repo.Read(ctx)
+	if err == ErrUTXOsNotFound {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for outpoint := range t.utxos {
+		delete(t.utxos, outpoint)
+	}
+
+	for i := range utxos {
+		hash, err := chainhash.NewHashFromStr(utxos[i].TxHash)
+		if err != nil {
+			return err
+		}
+
+		t.utxos[*wire.NewOutPoint(hash, utxos[i].Index)] = &utxos[i]
+	}
+
+	return nil
+}
+
+// ListUTXOs returns the unspent outputs of the address, including the
+// unconfirmed ones. Confirmed outputs come first, oldest first.
+func (t UTXOTracker) ListUTXOs(address btcutil.Address) []UTXO {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	utxos := []UTXO{}
+
+	for _, u := range t.utxos {
+		if u.SpentBy == "" && u.Address == address.String() {
+			utxos = append(utxos, *u)
+		}
+	}
+
+	sort.Slice(utxos, func(i, j int) bool {
+		a, b := utxos[i], utxos[j]
+
+		if (a.Block == "") != (b.Block == "") {
+			return b.Block == ""
+		}
+
+		if a.Height != b.Height {
+			return a.Height < b.Height
+		}
+
+		if a.TxHash != b.TxHash {
+			return a.TxHash < b.TxHash
+		}
+
+		return a.Index < b.Index
+	})
+
+	return utxos
+}
+
+// Handle implements the Listener interface.
+func (t UTXOTracker) Handle(ctx context.Context, m wire.Message) error {
+	switch msg := m.(type) {
+	case *wire.MsgTx:
+		t.mu.Lock()
+		t.apply(msg, "", 0)
+		t.mu.Unlock()
+
+	case *wire.MsgBlock:
+		hash := msg.BlockHash()
+
+		block, err := t.BlockService.Read(ctx, hash)
+		if err != nil {
+			return err
+		}
+
+		t.mu.Lock()
+
+		for _, tx := range msg.Transactions {
+			t.apply(tx, hash.String(), block.Height)
+		}
+
+		t.prune(block.Height)
+		t.mu.Unlock()
+
+		return t.save(ctx)
+	}
+
+	return nil
+}
+
+// HandleReorg implements the Listener interface.
+//
+// The outputs and spends confirmed in the disconnected blocks become
+// unconfirmed, until they are confirmed again or expire.
+func (t UTXOTracker) HandleReorg(ctx context.Context, r Reorg) error {
+	disconnected := map[string]bool{}
+	for _, hash := range r.Disconnected {
+		disconnected[hash.String()] = true
+	}
+
+	t.mu.Lock()
+
+	for _, u := range t.utxos {
+		if disconnected[u.Block] {
+			u.Block = ""
+			u.Height = 0
+		}
+
+		if disconnected[u.SpentBlock] {
+			u.SpentBlock = ""
+			u.SpentHeight = 0
+		}
+	}
+
+	t.mu.Unlock()
+
+	return t.save(ctx)
+}
+
+// HandleExpired implements the Listener interface.
+//
+// The outputs of the expired TX's are removed, and the outputs they spent
+// are spendable again.
+func (t UTXOTracker) HandleExpired(ctx context.Context,
+	hashes []chainhash.Hash) error {
+
+	t.mu.Lock()
+
+	for _, hash := range hashes {
+		t.drop(hash.String())
+	}
+
+	t.mu.Unlock()
+
+	return t.save(ctx)
+}
+
+// HandleDoubleSpend implements the Listener interface.
+//
+// Conflicts are resolved as the TX's are confirmed, or expire.
+func (t UTXOTracker) HandleDoubleSpend(ctx context.Context, d DoubleSpend) error {
+	return nil
+}
+
+// apply spends the tracked outputs the TX spends, and tracks its outputs to
+// the watched addresses. The block is empty if the TX is unconfirmed.
+//
+// A confirmed TX replaces an unconfirmed TX that spent the same output,
+// which is dropped. Otherwise the first spend seen is kept.
+//
+// The lock must be held.
+func (t UTXOTracker) apply(tx *wire.MsgTx, block string, height int32) {
+	hash := tx.TxHash()
+
+	for _, txIn := range tx.TxIn {
+		u, ok := t.utxos[txIn.PreviousOutPoint]
+		if !ok {
+			continue
+		}
+
+		if u.SpentBy != "" && block == "" {
+			// already spent, or a TX seen again after it was confirmed
+			continue
+		}
+
+		if u.SpentBy != "" && u.SpentBy != hash.String() {
+			if u.SpentBlock != "" {
+				continue
+			}
+
+			t.drop(u.SpentBy)
+		}
+
+		u.SpentBy = hash.String()
+		u.SpentBlock = block
+		u.SpentHeight = height
+	}
+
+	for i, txOut := range tx.TxOut {
+		address, ok := t.addresses[string(txOut.PkScript)]
+		if !ok {
+			continue
+		}
+
+		outpoint := *wire.NewOutPoint(&hash, uint32(i))
+
+		if u, ok := t.utxos[outpoint]; ok {
+			if block != "" {
+				u.Block = block
+				u.Height = height
+			}
+
+			continue
+		}
+
+		t.utxos[outpoint] = &UTXO{
+			TxHash:   hash.String(),
+			Index:    uint32(i),
+			Value:    txOut.Value,
+			PkScript: txOut.PkScript,
+			Address:  address.String(),
+			Block:    block,
+			Height:   height,
+		}
+	}
+}
+
+// drop removes the unconfirmed outputs of the TX, and unspends the outputs
+// it spent while unconfirmed, along with any TX's spending its outputs.
+//
+// The lock must be held.
+func (t UTXOTracker) drop(txHash string) {
+	for outpoint, u := range t.utxos {
+		if u.SpentBy == txHash && u.SpentBlock == "" {
+			u.SpentBy = ""
+		}
+
+		if u.TxHash != txHash || u.Block != "" {
+			continue
+		}
+
+		delete(t.utxos, outpoint)
+
+		if u.SpentBy != "" && u.SpentBlock == "" {
+			t.drop(u.SpentBy)
+		}
+	}
+}
+
+// prune removes the outputs whose spend is confirmed deeper than a reorg
+// is expected to reach.
+//
+// The lock must be held.
+func (t UTXOTracker) prune(height int32) {
+	for outpoint, u := range t.utxos {
+		if u.SpentBlock != "" && u.SpentHeight <= height-utxoReorgDepth {
+			delete(t.utxos, outpoint)
+		}
+	}
+}
+
+// save writes the outputs to storage.
+func (t UTXOTracker) save(ctx context.Context) error {
+	t.mu.Lock()
+
+	utxos := make([]UTXO, 0, len(t.utxos))
+	for _, u := range t.utxos {
+		utxos = append(utxos, *u)
+	}
+
+	t.mu.Unlock()
+
+	return t.repo.Write(ctx, utxos)
+}
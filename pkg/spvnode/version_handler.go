@@ -2,8 +2,8 @@ package spvnode
 
 import (
 	"context"
-	"errors"
 
+	"github.com/tokenized/smart-contract/pkg/errs"
 	"github.com/tokenized/smart-contract/pkg/wire"
 )
 
@@ -28,7 +28,7 @@ func (h VersionHandler) Handle(ctx context.Context,
 
 	msg, ok := m.(*wire.MsgVersion)
 	if !ok {
-		return nil, errors.New("Could not assert as *wire.MsgVersion")
+		return nil, errs.New(errs.Invalid, "Could not assert as *wire.MsgVersion")
 	}
 
 	return h.handle(ctx, msg)
@@ -36,11 +36,42 @@ func (h VersionHandler) Handle(ctx context.Context,
 
 // handle processes the MsgVersion, and responds with a MsgVersion.
 //
-// For now this just echos the version back in the response.
+// For now this just echos the version back in the response. If compact
+// blocks are enabled, the peer is also asked to relay blocks as compact
+// blocks once they are requested. In HeadersOnly mode, the peer is asked to
+// announce new blocks with their headers.
+//
+// The peer is asked for the addresses of the peers it knows, in addrv2
+// messages if it supports them, so Tor v3 addresses are learned. If a
+// FeeFilter is set, the peer is asked not to announce TX's paying less.
 func (h VersionHandler) handle(ctx context.Context,
 	m *wire.MsgVersion) ([]wire.Message, error) {
 
-	out := wire.NewMsgVerAck()
+	out := []wire.Message{}
+
+	// sendaddrv2 must be sent before the verack
+	if uint32(m.ProtocolVersion) >= wire.AddrV2Version {
+		out = append(out, wire.NewMsgSendAddrV2())
+	}
+
+	out = append(out, wire.NewMsgVerAck(), wire.NewMsgGetAddr())
+
+	if h.Config.FeeFilter > 0 && !h.Config.HeadersOnly &&
+		uint32(m.ProtocolVersion) >= wire.FeeFilterVersion {
+		out = append(out, wire.NewMsgFeeFilter(h.Config.FeeFilter))
+	}
+
+	if h.Config.HeadersOnly {
+		if uint32(m.ProtocolVersion) >= wire.SendHeadersVersion {
+			out = append(out, wire.NewMsgSendHeaders())
+		}
+
+		return out, nil
+	}
+
+	if h.Config.CompactBlocks && uint32(m.ProtocolVersion) >= wire.BIP0152Version {
+		out = append(out, wire.NewMsgSendCmpct(false, compactBlockVersion))
+	}
 
-	return []wire.Message{out}, nil
+	return out, nil
 }
@@ -0,0 +1,225 @@
+package spvnode
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
+)
+
+const (
+	// websocketGUID is appended to the key of the handshake, as defined by
+	// RFC 6455.
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	// websocketWriteTimeout is how long a frame may take to write.
+	websocketWriteTimeout = 10 * time.Second
+
+	// websocketMaxPayload is the largest frame accepted from a client.
+	// Clients only send control frames, which are smaller.
+	websocketMaxPayload = 4096
+
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xa
+)
+
+var (
+	ErrNotWebSocket     = errs.New(errs.Invalid, "Not a WebSocket upgrade request")
+	ErrWebSocketFrame   = errs.New(errs.Invalid, "Malformed WebSocket frame")
+	ErrWebSocketTooLong = errs.New(errs.Invalid, "WebSocket frame is too long")
+)
+
+// wsConn is the server side of a WebSocket connection.
+//
+// It speaks just enough of RFC 6455 to send text frames, and answer the
+// control frames of the client.
+type wsConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+
+	// mu serializes the writes of frames.
+	mu *sync.Mutex
+}
+
+// upgradeWebSocket completes the handshake of a WebSocket upgrade request,
+// and takes over the connection.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+
+	if r.Method != http.MethodGet || key == "" ||
+		!headerHas(r.Header, "Connection", "upgrade") ||
+		!headerHas(r.Header, "Upgrade", "websocket") {
+		return nil, ErrNotWebSocket
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, ErrNotWebSocket
+	}
+
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha1.Sum([]byte(key + websocketGUID))
+	accept := base64.StdEncoding.EncodeToString(h[:])
+
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %v\r\n\r\n", accept)
+
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &wsConn{
+		conn: conn,
+		r:    rw.Reader,
+		mu:   &sync.Mutex{},
+	}, nil
+}
+
+// headerHas returns true if any of the comma separated values of the
+// header is the value, ignoring case.
+func headerHas(h http.Header, name, value string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// WriteText sends the payload in a text frame.
+func (c *wsConn) WriteText(payload []byte) error {
+	return c.writeFrame(wsText, payload)
+}
+
+// writeFrame sends a single, unmasked, frame.
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	header := []byte{0x80 | opcode}
+
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+
+	case n <= 0xffff:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout)); err != nil {
+		return err
+	}
+
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// readFrame returns the opcode and payload of the next frame from the
+// client. Client frames must be masked, and fragments are not supported.
+func (c *wsConn) readFrame() (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(c.r, header); err != nil {
+		return 0, nil, err
+	}
+
+	opcode := header[0] & 0x0f
+	if header[0]&0x80 == 0 || header[1]&0x80 == 0 {
+		return 0, nil, ErrWebSocketFrame
+	}
+
+	length := uint64(header[1] & 0x7f)
+
+	switch length {
+	case 126:
+		b := make([]byte, 2)
+		if _, err := io.ReadFull(c.r, b); err != nil {
+			return 0, nil, err
+		}
+
+		length = uint64(binary.BigEndian.Uint16(b))
+
+	case 127:
+		b := make([]byte, 8)
+		if _, err := io.ReadFull(c.r, b); err != nil {
+			return 0, nil, err
+		}
+
+		length = binary.BigEndian.Uint64(b)
+	}
+
+	if length > websocketMaxPayload {
+		return 0, nil, ErrWebSocketTooLong
+	}
+
+	mask := make([]byte, 4)
+	if _, err := io.ReadFull(c.r, mask); err != nil {
+		return 0, nil, err
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return 0, nil, err
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return opcode, payload, nil
+}
+
+// readLoop reads the frames of the client until the connection closes,
+// answering pings and close frames. Other frames are ignored.
+func (c *wsConn) readLoop() error {
+	for {
+		opcode, payload, err := c.readFrame()
+		if err != nil {
+			return err
+		}
+
+		switch opcode {
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return err
+			}
+
+		case wsClose:
+			c.writeFrame(wsClose, payload)
+			return nil
+		}
+	}
+}
+
+// Close closes the connection.
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
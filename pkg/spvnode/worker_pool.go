@@ -0,0 +1,330 @@
+package spvnode
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+
+	"github.com/tokenized/smart-contract/pkg/spvnode/logger"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+const (
+	// workerQueueSize is the number of calls queued for each worker of a
+	// WorkerPool. Further calls block until the worker catches up.
+	workerQueueSize = 1000
+)
+
+// WorkerPool passes the TX's received by the Node to the Listeners from a
+// bounded pool of workers, so a slow Listener doesn't hold up reading and
+// handling the messages of the peers.
+//
+// The calls for a TX are made by one worker, in the order they were
+// passed. A TX spending the outputs of TX's whose calls are still queued
+// is queued for the worker of the first of them, and waits for the calls
+// queued for the others, so a TX is passed on after all of its parents.
+// Everything else, such as blocks, reorgs and expired TX's, is passed on
+// once the workers have made the calls queued before it.
+//
+// Once the Context of Run is done, the calls still queued are made one
+// worker after another, without waiting for parents queued for another
+// worker, and later calls are made at once.
+//
+// Copies share the workers, and it is safe for concurrent use. A WorkerPool
+// without workers passes everything on at once.
+type WorkerPool struct {
+	queues []chan func()
+
+	// stopping is closed once the Context of Run is done, and done once
+	// the workers have stopped. Calls are then made at once.
+	stopping chan struct{}
+	done     chan struct{}
+
+	// enqueue is held to queue a call, and to stop queueing once the
+	// workers have stopped, so no call is queued after the queues are
+	// drained.
+	enqueue *sync.RWMutex
+
+	mu *sync.Mutex
+
+	// pending are the TX's with calls queued, and the worker they are
+	// queued for.
+	pending map[chainhash.Hash]*pendingCalls
+}
+
+// pendingCalls are the calls queued for a TX.
+type pendingCalls struct {
+	worker int
+	count  int
+}
+
+// NewWorkerPool returns a new WorkerPool with the number of workers. The
+// workers start once it is Run.
+func NewWorkerPool(workers int) WorkerPool {
+	queues := make([]chan func(), workers)
+	for i := range queues {
+		queues[i] = make(chan func(), workerQueueSize)
+	}
+
+	return WorkerPool{
+		queues:   queues,
+		stopping: make(chan struct{}),
+		done:     make(chan struct{}),
+		enqueue:  &sync.RWMutex{},
+		mu:       &sync.Mutex{},
+		pending:  map[chainhash.Hash]*pendingCalls{},
+	}
+}
+
+// Run runs the workers until the Context is done, then makes the calls
+// still queued.
+func (p WorkerPool) Run(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	wg.Add(len(p.queues))
+
+	for _, q := range p.queues {
+		go func(q chan func()) {
+			defer wg.Done()
+
+			for {
+				select {
+				case f := <-q:
+					f()
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(q)
+	}
+
+	<-ctx.Done()
+	close(p.stopping)
+	wg.Wait()
+
+	p.enqueue.Lock()
+	close(p.done)
+	p.enqueue.Unlock()
+
+	for _, q := range p.queues {
+		for len(q) > 0 {
+			(<-q)()
+		}
+	}
+}
+
+// Listener returns a Listener that passes everything on to the Listener by
+// the WorkerPool, or the Listener itself if there are no workers.
+func (p WorkerPool) Listener(l Listener) Listener {
+	if len(p.queues) == 0 {
+		return l
+	}
+
+	return workerListener{
+		pool:     p,
+		listener: l,
+	}
+}
+
+// queue queues the call for the TX, for the worker of its calls or those
+// of its first parent, if any are queued. Otherwise the worker is picked
+// by the hash. The call waits for the calls queued for the other workers
+// of its parents.
+func (p WorkerPool) queue(hash chainhash.Hash, parents []chainhash.Hash,
+	f func()) {
+
+	p.mu.Lock()
+
+	c, ok := p.pending[hash]
+	if !ok {
+		c = &pendingCalls{
+			worker: int(binary.LittleEndian.Uint32(hash[:4]) % uint32(len(p.queues))),
+		}
+
+		for _, parent := range parents {
+			if pc, ok := p.pending[parent]; ok {
+				c.worker = pc.worker
+				break
+			}
+		}
+
+		p.pending[hash] = c
+	}
+
+	c.count++
+	worker := c.worker
+
+	// the other workers with calls queued for a parent
+	others := map[int]bool{}
+	for _, parent := range parents {
+		if pc, ok := p.pending[parent]; ok && pc.worker != worker {
+			others[pc.worker] = true
+		}
+	}
+
+	p.mu.Unlock()
+
+	// each is closed once the calls queued for another worker before the
+	// call are made
+	reached := []chan struct{}{}
+	for other := range others {
+		r := make(chan struct{})
+		if !p.send(other, func() { close(r) }) {
+			close(r)
+		}
+
+		reached = append(reached, r)
+	}
+
+	call := func() {
+		for _, r := range reached {
+			select {
+			case <-r:
+			case <-p.stopping:
+			}
+		}
+
+		f()
+		p.finished(hash)
+	}
+
+	if !p.send(worker, call) {
+		call()
+	}
+}
+
+// send queues the call for the worker. It returns false if the workers
+// have stopped, or are stopping with the queue full, in which case the
+// call must be made at once.
+func (p WorkerPool) send(worker int, f func()) bool {
+	p.enqueue.RLock()
+	defer p.enqueue.RUnlock()
+
+	if p.stopped() {
+		return false
+	}
+
+	select {
+	case p.queues[worker] <- f:
+		return true
+	case <-p.stopping:
+	}
+
+	// the queue is still drained once the workers have stopped
+	select {
+	case p.queues[worker] <- f:
+		return true
+	default:
+		return false
+	}
+}
+
+// stopped returns true once the workers have stopped.
+func (p WorkerPool) stopped() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// finished records that a call for the TX was made.
+func (p WorkerPool) finished(hash chainhash.Hash) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c, ok := p.pending[hash]
+	if !ok {
+		return
+	}
+
+	c.count--
+	if c.count == 0 {
+		delete(p.pending, hash)
+	}
+}
+
+// wait returns once the workers have made the calls queued before it.
+func (p WorkerPool) wait() {
+	wg := sync.WaitGroup{}
+
+	for i := range p.queues {
+		wg.Add(1)
+
+		if !p.send(i, wg.Done) {
+			wg.Done()
+		}
+	}
+
+	wg.Wait()
+}
+
+// workerListener is a Listener whose calls are made by a WorkerPool.
+type workerListener struct {
+	pool     WorkerPool
+	listener Listener
+}
+
+// Handle implements the Listener interface.
+//
+// A TX is queued, and nil returned, as the error of the Listener is logged
+// once it is called. Other messages are passed on once the queued calls are
+// made.
+func (l workerListener) Handle(ctx context.Context, m wire.Message) error {
+	tx, ok := m.(*wire.MsgTx)
+	if !ok {
+		l.pool.wait()
+		return l.listener.Handle(ctx, m)
+	}
+
+	parents := make([]chainhash.Hash, 0, len(tx.TxIn))
+	for _, txIn := range tx.TxIn {
+		parents = append(parents, txIn.PreviousOutPoint.Hash)
+	}
+
+	l.pool.queue(tx.TxHash(), parents, func() {
+		if err := l.listener.Handle(ctx, m); err != nil {
+			log := logger.NewLoggerFromContext(ctx).Sugar()
+			log.Errorf("Failed to handle TX %v : %v", tx.TxHash(), err)
+		}
+	})
+
+	return nil
+}
+
+// HandleReorg implements the Listener interface.
+func (l workerListener) HandleReorg(ctx context.Context, r Reorg) error {
+	l.pool.wait()
+	return l.listener.HandleReorg(ctx, r)
+}
+
+// HandleExpired implements the Listener interface.
+func (l workerListener) HandleExpired(ctx context.Context,
+	hashes []chainhash.Hash) error {
+
+	l.pool.wait()
+	return l.listener.HandleExpired(ctx, hashes)
+}
+
+// HandleDoubleSpend implements the Listener interface.
+//
+// It is queued after the calls for the TX that caused it, and nil
+// returned, as the error of the Listener is logged once it is called.
+func (l workerListener) HandleDoubleSpend(ctx context.Context,
+	d DoubleSpend) error {
+
+	if len(d.Txs) == 0 {
+		return l.listener.HandleDoubleSpend(ctx, d)
+	}
+
+	l.pool.queue(d.Txs[len(d.Txs)-1], nil, func() {
+		if err := l.listener.HandleDoubleSpend(ctx, d); err != nil {
+			log := logger.NewLoggerFromContext(ctx).Sugar()
+			log.Errorf("Failed to handle double spend : %v", err)
+		}
+	})
+
+	return nil
+}
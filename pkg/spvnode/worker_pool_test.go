@@ -0,0 +1,299 @@
+package spvnode
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// recordingListener records the TX's, blocks and double spends it is
+// passed. The calls for the TX's with a gate wait until it is closed.
+type recordingListener struct {
+	mu           *sync.Mutex
+	calls        *[]chainhash.Hash
+	doubleSpends *[]DoubleSpend
+	gates        map[chainhash.Hash]chan struct{}
+}
+
+func newRecordingListener() recordingListener {
+	return recordingListener{
+		mu:           &sync.Mutex{},
+		calls:        &[]chainhash.Hash{},
+		doubleSpends: &[]DoubleSpend{},
+		gates:        map[chainhash.Hash]chan struct{}{},
+	}
+}
+
+func (l recordingListener) Handle(ctx context.Context, m wire.Message) error {
+	var hash chainhash.Hash
+
+	switch msg := m.(type) {
+	case *wire.MsgTx:
+		hash = msg.TxHash()
+		if gate, ok := l.gates[hash]; ok {
+			<-gate
+		}
+	case *wire.MsgBlock:
+		hash = msg.BlockHash()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	*l.calls = append(*l.calls, hash)
+
+	return nil
+}
+
+func (l recordingListener) HandleReorg(ctx context.Context, r Reorg) error {
+	return nil
+}
+
+func (l recordingListener) HandleExpired(ctx context.Context,
+	hashes []chainhash.Hash) error {
+
+	return nil
+}
+
+func (l recordingListener) HandleDoubleSpend(ctx context.Context,
+	d DoubleSpend) error {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	*l.doubleSpends = append(*l.doubleSpends, d)
+
+	return nil
+}
+
+// index returns the position of the call for the hash, or -1 if it has not
+// been made.
+func (l recordingListener) index(hash chainhash.Hash) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, h := range *l.calls {
+		if h == hash {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// waitFor waits for the call for the hash to be made.
+func (l recordingListener) waitFor(t *testing.T, hash chainhash.Hash) {
+	deadline := time.Now().Add(5 * time.Second)
+
+	for l.index(hash) < 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no call for %v", hash)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// workerOf returns the worker of the pool of n workers a TX with no queued
+// parents is queued for.
+func workerOf(hash chainhash.Hash, n int) int {
+	return int(binary.LittleEndian.Uint32(hash[:4]) % uint32(n))
+}
+
+// spendingTx returns a TX spending the first output of each of the parents,
+// queued for a worker other than those of the TX's in not.
+func spendingTx(n int, parents []*wire.MsgTx, not ...*wire.MsgTx) *wire.MsgTx {
+	for i := uint32(0); ; i++ {
+		tx := wire.NewMsgTx(1)
+
+		for _, parent := range parents {
+			hash := parent.TxHash()
+			tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&hash, 0), nil))
+		}
+
+		if len(parents) == 0 {
+			prev := chainhash.DoubleHashH([]byte{byte(i), byte(i >> 8)})
+			tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prev, i), nil))
+		}
+
+		tx.AddTxOut(wire.NewTxOut(int64(i), []byte{0x51}))
+
+		ok := true
+		for _, other := range not {
+			if workerOf(tx.TxHash(), n) == workerOf(other.TxHash(), n) {
+				ok = false
+			}
+		}
+
+		if ok {
+			return tx
+		}
+	}
+}
+
+// TestWorkerPool_parents tests that a TX is passed on after its parents,
+// including a parent queued for another worker that is held up.
+func TestWorkerPool_parents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const workers = 4
+
+	pool := NewWorkerPool(workers)
+	go pool.Run(ctx)
+
+	parent1 := spendingTx(workers, nil)
+	parent2 := spendingTx(workers, nil, parent1)
+	child := spendingTx(workers, []*wire.MsgTx{parent1, parent2})
+	grandchild := spendingTx(workers, []*wire.MsgTx{child})
+
+	l := newRecordingListener()
+	l.gates[parent2.TxHash()] = make(chan struct{})
+
+	listener := pool.Listener(l)
+
+	for _, tx := range []*wire.MsgTx{parent1, parent2, child, grandchild} {
+		if err := listener.Handle(ctx, tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the child is queued for the worker of the first parent, and waits for
+	// the second
+	l.waitFor(t, parent1.TxHash())
+	time.Sleep(50 * time.Millisecond)
+
+	if l.index(child.TxHash()) >= 0 {
+		t.Fatal("child passed on before its parent")
+	}
+
+	close(l.gates[parent2.TxHash()])
+
+	l.waitFor(t, grandchild.TxHash())
+
+	order := []*wire.MsgTx{parent2, child, grandchild}
+	for i := 1; i < len(order); i++ {
+		if l.index(order[i-1].TxHash()) > l.index(order[i].TxHash()) {
+			t.Fatalf("got %v passed on after %v", order[i-1].TxHash(),
+				order[i].TxHash())
+		}
+	}
+}
+
+// TestWorkerPool_wait tests that a block is passed on once the TX's queued
+// before it are.
+func TestWorkerPool_wait(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool := NewWorkerPool(4)
+	go pool.Run(ctx)
+
+	tx := spendingTx(4, nil)
+	block, _ := newCompactBlock(newTxs(1))
+
+	l := newRecordingListener()
+	l.gates[tx.TxHash()] = make(chan struct{})
+
+	listener := pool.Listener(l)
+
+	if err := listener.Handle(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	handled := make(chan error)
+	go func() {
+		handled <- listener.Handle(ctx, block)
+	}()
+
+	select {
+	case <-handled:
+		t.Fatal("block passed on before the TX")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(l.gates[tx.TxHash()])
+
+	if err := <-handled; err != nil {
+		t.Fatal(err)
+	}
+
+	if l.index(tx.TxHash()) != 0 || l.index(block.BlockHash()) != 1 {
+		t.Fatalf("got calls %v, want the TX then the block", *l.calls)
+	}
+}
+
+// TestWorkerPool_stop tests that the calls still queued when the Context
+// of Run is done are made before it returns, and later calls at once.
+func TestWorkerPool_stop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	pool := NewWorkerPool(1)
+
+	stopped := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(stopped)
+	}()
+
+	txs := newTxs(10)
+
+	l := newRecordingListener()
+	l.gates[txs[0].TxHash()] = make(chan struct{})
+
+	listener := pool.Listener(l)
+
+	for _, tx := range txs {
+		if err := listener.Handle(ctx, tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cancel()
+	close(l.gates[txs[0].TxHash()])
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return")
+	}
+
+	for i, tx := range txs {
+		if got := l.index(tx.TxHash()); got != i {
+			t.Fatalf("got tx %v passed on at %v, want %v", i, got, i)
+		}
+	}
+
+	// the workers have stopped, so calls are made at once
+	late := spendingTx(1, []*wire.MsgTx{txs[9]})
+	if err := listener.Handle(ctx, late); err != nil {
+		t.Fatal(err)
+	}
+
+	if l.index(late.TxHash()) != len(txs) {
+		t.Fatal("TX not passed on at once after stopping")
+	}
+
+	block, _ := newCompactBlock(newTxs(1))
+	if err := listener.Handle(ctx, block); err != nil {
+		t.Fatal(err)
+	}
+
+	if l.index(block.BlockHash()) != len(txs)+1 {
+		t.Fatal("block not passed on at once after stopping")
+	}
+}
+
+func TestWorkerPool_noWorkers(t *testing.T) {
+	l := newRecordingListener()
+
+	if _, ok := NewWorkerPool(0).Listener(l).(recordingListener); !ok {
+		t.Fatal("got a worker Listener, want the Listener itself")
+	}
+}
@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+// Invalidator tells other processes sharing a Storage which keys have
+// changed, so they can drop stale cached copies.
+type Invalidator interface {
+	// Publish announces that the object at the key has changed.
+	Publish(context.Context, string) error
+
+	// Subscribe calls the second func with each key announced by other
+	// processes, until the Context is done or the subscription fails. The
+	// first func is called once the subscription is confirmed, as keys
+	// announced before then are missed.
+	Subscribe(context.Context, func(), func(string)) error
+}
+
+// CachedStorage implements the Storage interface as a read-through cache in
+// front of another Storage.
+//
+// Objects are cached when read. Writes and removals drop the cached copy,
+// and are published to the Invalidator so other processes drop theirs.
+// Searches are not cached.
+//
+// The write has already succeeded when its key is published, so a key that
+// fails to publish doesn't fail the write. It is kept, and published again
+// with the next key.
+//
+// With an Invalidator, objects are only cached while Listen is subscribed,
+// as a change made by another process while not listening would be missed.
+type CachedStorage struct {
+	Storage     Storage
+	Invalidator Invalidator
+
+	mu      *sync.Mutex
+	objects map[string][]byte
+
+	// version is moved on by each drop, so an object read from the Storage
+	// is not cached if the key may have been dropped during the read.
+	version *uint64
+
+	// listening is true while Listen is subscribed.
+	listening *bool
+
+	// unpublished are the keys that failed to publish.
+	unpublished map[string]bool
+}
+
+// NewCachedStorage returns a new CachedStorage in front of the Storage. The
+// Invalidator may be nil if no other process writes to the Storage.
+func NewCachedStorage(store Storage, invalidator Invalidator) CachedStorage {
+	return CachedStorage{
+		Storage:     store,
+		Invalidator: invalidator,
+		mu:          &sync.Mutex{},
+		objects:     map[string][]byte{},
+		version:     new(uint64),
+		listening:   new(bool),
+		unpublished: map[string]bool{},
+	}
+}
+
+// Read returns the cached object at the key, reading it from the Storage if
+// it is not cached.
+//
+// The object read is only cached if no key was dropped during the read.
+func (c CachedStorage) Read(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	b, ok := c.objects[key]
+	version := *c.version
+	c.mu.Unlock()
+
+	if ok {
+		return b, nil
+	}
+
+	b, err := c.Storage.Read(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if *c.version == version && c.caching() {
+		c.objects[key] = b
+	}
+	c.mu.Unlock()
+
+	return b, nil
+}
+
+// Write writes the object to the Storage, then invalidates the key.
+func (c CachedStorage) Write(ctx context.Context,
+	key string,
+	body []byte,
+	options *Options) error {
+
+	if err := c.Storage.Write(ctx, key, body, options); err != nil {
+		return err
+	}
+
+	return c.invalidate(ctx, key)
+}
+
+// Remove removes the object from the Storage, then invalidates the key.
+func (c CachedStorage) Remove(ctx context.Context, key string) error {
+	if err := c.Storage.Remove(ctx, key); err != nil {
+		return err
+	}
+
+	return c.invalidate(ctx, key)
+}
+
+// Search implements the Searcher interface, always searching the Storage.
+func (c CachedStorage) Search(ctx context.Context,
+	query map[string]string) ([][]byte, error) {
+
+	return c.Storage.Search(ctx, query)
+}
+
+// Drop removes the key from the cache, without publishing it.
+func (c CachedStorage) Drop(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.objects, key)
+	*c.version++
+}
+
+// Flush drops every key from the cache.
+func (c CachedStorage) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.objects {
+		delete(c.objects, key)
+	}
+
+	*c.version++
+}
+
+// Listen drops each key published by other processes from the cache, until
+// the Context is done or the subscription fails.
+//
+// Objects are cached from when the subscription is confirmed until Listen
+// returns, when the cache is flushed, as keys published while not listening
+// are missed.
+func (c CachedStorage) Listen(ctx context.Context) error {
+	if c.Invalidator == nil {
+		return nil
+	}
+
+	defer func() {
+		c.mu.Lock()
+		*c.listening = false
+		c.mu.Unlock()
+
+		c.Flush()
+	}()
+
+	return c.Invalidator.Subscribe(ctx, c.subscribed, c.Drop)
+}
+
+// subscribed starts caching objects, once Listen is subscribed.
+func (c CachedStorage) subscribed() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	*c.listening = true
+}
+
+// caching returns true if objects read can be cached. The caller must hold
+// the lock.
+func (c CachedStorage) caching() bool {
+	return c.Invalidator == nil || *c.listening
+}
+
+// invalidate drops the key from the cache, and publishes it, along with
+// any keys that failed to publish before.
+//
+// Publishing stops at the first failure, and the keys not published are
+// kept for the next time, so it never fails.
+func (c CachedStorage) invalidate(ctx context.Context, key string) error {
+	c.Drop(key)
+
+	if c.Invalidator == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	c.unpublished[key] = true
+
+	keys := make([]string, 0, len(c.unpublished))
+	for k := range c.unpublished {
+		keys = append(keys, k)
+	}
+	c.mu.Unlock()
+
+	for _, k := range keys {
+		if err := c.Invalidator.Publish(ctx, k); err != nil {
+			return nil
+		}
+
+		c.mu.Lock()
+		delete(c.unpublished, k)
+		c.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Unpublished returns the number of keys that failed to publish, and are
+// waiting to be published with the next key.
+func (c CachedStorage) Unpublished() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.unpublished)
+}
@@ -0,0 +1,263 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// fakeInvalidator records published keys, and delivers them to a
+// subscriber.
+type fakeInvalidator struct {
+	published []string
+	subscribe chan string
+	ready     chan struct{}
+
+	// fail makes each Publish fail.
+	fail bool
+}
+
+func (f *fakeInvalidator) Publish(ctx context.Context, key string) error {
+	if f.fail {
+		return errors.New("Publish failed")
+	}
+
+	f.published = append(f.published, key)
+	return nil
+}
+
+func (f *fakeInvalidator) Subscribe(ctx context.Context,
+	ready func(),
+	fn func(string)) error {
+
+	ready()
+	close(f.ready)
+
+	for key := range f.subscribe {
+		fn(key)
+	}
+
+	return nil
+}
+
+// slowStorage is a Storage that calls a func during each Read.
+type slowStorage struct {
+	Storage
+	during func()
+}
+
+func (s slowStorage) Read(ctx context.Context, key string) ([]byte, error) {
+	b, err := s.Storage.Read(ctx, key)
+	s.during()
+	return b, err
+}
+
+func TestCachedStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cached")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+
+	backend := NewFilesystemStorage(Config{Root: dir, Bucket: "standalone"})
+	invalidator := &fakeInvalidator{
+		subscribe: make(chan string),
+		ready:     make(chan struct{}),
+	}
+
+	cache := NewCachedStorage(backend, invalidator)
+
+	if err := cache.Write(ctx, "foo", []byte("1"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing is cached until the subscription is confirmed
+	if _, err := cache.Read(ctx, "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := backend.Write(ctx, "foo", []byte("2"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := cache.Read(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "2" {
+		t.Fatalf("got %s, want uncached 2", b)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- cache.Listen(ctx)
+	}()
+
+	<-invalidator.ready
+
+	if _, err := cache.Read(ctx, "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// another process writes to the shared storage
+	if err := backend.Write(ctx, "foo", []byte("3"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err = cache.Read(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "2" {
+		t.Fatalf("got %s, want cached 2", b)
+	}
+
+	// and publishes the key. the next key is only taken once it is dropped.
+	invalidator.subscribe <- "foo"
+	invalidator.subscribe <- "bar"
+
+	b, err = cache.Read(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "3" {
+		t.Fatalf("got %s, want 3", b)
+	}
+
+	close(invalidator.subscribe)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// the cache is flushed, and bypassed, once no longer listening
+	if err := backend.Write(ctx, "foo", []byte("4"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err = cache.Read(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "4" {
+		t.Fatalf("got %s, want 4", b)
+	}
+
+	if err := cache.Remove(ctx, "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cache.Read(ctx, "foo"); err != ErrNotFound {
+		t.Fatalf("got %v, want %v", err, ErrNotFound)
+	}
+
+	want := []string{"foo", "foo"}
+
+	if !reflect.DeepEqual(invalidator.published, want) {
+		t.Errorf("got\n%#+v\nwant\n%#+v", invalidator.published, want)
+	}
+}
+
+func TestCachedStorage_dropDuringRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cached")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+
+	backend := NewFilesystemStorage(Config{Root: dir, Bucket: "standalone"})
+
+	if err := backend.Write(ctx, "foo", []byte("1"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var cache CachedStorage
+
+	// another process writes and publishes the key after the object is
+	// read, before it is cached
+	slow := slowStorage{
+		Storage: backend,
+		during: func() {
+			if err := backend.Write(ctx, "foo", []byte("2"), nil); err != nil {
+				t.Fatal(err)
+			}
+
+			cache.Drop("foo")
+		},
+	}
+
+	cache = NewCachedStorage(slow, nil)
+
+	if _, err := cache.Read(ctx, "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	slow.during = func() {}
+	cache.Storage = slow
+
+	b, err := cache.Read(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "2" {
+		t.Fatalf("got %s, want 2", b)
+	}
+}
+
+func TestCachedStorage_publishFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cached")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+
+	backend := NewFilesystemStorage(Config{Root: dir, Bucket: "standalone"})
+	invalidator := &fakeInvalidator{fail: true}
+
+	c := NewCachedStorage(backend, invalidator)
+
+	// the write succeeded, so a failed publish doesn't fail it
+	if err := c.Write(ctx, "foo", []byte("1"), nil); err != nil {
+		t.Fatalf("got %v, want the write to succeed", err)
+	}
+
+	if b, err := backend.Read(ctx, "foo"); err != nil || string(b) != "1" {
+		t.Fatalf("got %q %v, want the write stored", b, err)
+	}
+
+	if got := c.Unpublished(); got != 1 {
+		t.Fatalf("got %v unpublished, want 1", got)
+	}
+
+	// the failed key is published with the next one
+	invalidator.fail = false
+
+	if err := c.Write(ctx, "bar", []byte("2"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(invalidator.published)
+
+	if want := []string{"bar", "foo"}; !reflect.DeepEqual(invalidator.published, want) {
+		t.Errorf("got published %v, want %v", invalidator.published, want)
+	}
+
+	if got := c.Unpublished(); got != 0 {
+		t.Errorf("got %v unpublished, want 0", got)
+	}
+}
@@ -1,8 +1,8 @@
 package storage
 
-import "errors"
+import "github.com/tokenized/smart-contract/pkg/errs"
 
 var (
 	// ErrNotFound should be returned if the file was not found.
-	ErrNotFound = errors.New("Not found")
+	ErrNotFound = errs.New(errs.NotFound, "Not found")
 )
@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// redisDialTimeout is how long to wait to connect to Redis.
+	redisDialTimeout = 5 * time.Second
+
+	// redisTimeout is how long to wait for Redis to take a command and
+	// reply to it.
+	redisTimeout = 5 * time.Second
+)
+
+// ErrRedisReply is returned when Redis replies with something unexpected.
+var ErrRedisReply = errors.New("Unexpected reply from Redis")
+
+// RedisInvalidator implements the Invalidator interface over a Redis
+// pub/sub channel.
+//
+// It speaks just enough of the Redis protocol to PUBLISH and SUBSCRIBE.
+type RedisInvalidator struct {
+	Address string
+	Channel string
+
+	// Timeout is how long Redis has to take a command and reply to it.
+	Timeout time.Duration
+
+	mu  *sync.Mutex
+	pub *redisConn
+}
+
+// redisConn is the connection used to publish.
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewRedisInvalidator returns a new RedisInvalidator for the channel of the
+// Redis server at the address.
+func NewRedisInvalidator(address, channel string) RedisInvalidator {
+	return RedisInvalidator{
+		Address: address,
+		Channel: channel,
+		Timeout: redisTimeout,
+		mu:      &sync.Mutex{},
+		pub:     &redisConn{},
+	}
+}
+
+// Publish implements the Invalidator interface.
+//
+// The connection used to publish is kept open, and closed if it fails, so
+// the next publish reconnects. Each command has a deadline, so a hung
+// Redis doesn't hold up every publish.
+func (i RedisInvalidator) Publish(ctx context.Context, key string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	reused := i.pub.conn != nil
+
+	err := i.publish(key)
+	if err != nil && reused {
+		// retry once on a new connection, as the old one may have dropped
+		i.close()
+		err = i.publish(key)
+	}
+
+	if err != nil {
+		i.close()
+		return err
+	}
+
+	return nil
+}
+
+// Subscribe implements the Invalidator interface.
+func (i RedisInvalidator) Subscribe(ctx context.Context,
+	ready func(),
+	f func(string)) error {
+
+	conn, err := net.DialTimeout("tcp", i.Address, redisDialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	if _, err := conn.Write(redisCommand("SUBSCRIBE", i.Channel)); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(conn)
+
+	for {
+		v, err := readRedisValue(r)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			return err
+		}
+
+		// messages are ["message", channel, payload], and the reply
+		// confirming the subscription is ["subscribe", channel, count]
+		parts, ok := v.([]interface{})
+		if !ok || len(parts) != 3 {
+			continue
+		}
+
+		kind, _ := parts[0].(string)
+		payload, _ := parts[2].(string)
+
+		switch kind {
+		case "subscribe":
+			ready()
+		case "message":
+			f(payload)
+		}
+	}
+}
+
+// publish sends a PUBLISH command on the open connection, opening it if
+// needed. The caller must hold the lock.
+func (i RedisInvalidator) publish(key string) error {
+	if i.pub.conn == nil {
+		conn, err := net.DialTimeout("tcp", i.Address, redisDialTimeout)
+		if err != nil {
+			return err
+		}
+
+		i.pub.conn = conn
+		i.pub.r = bufio.NewReader(conn)
+	}
+
+	if err := i.pub.conn.SetDeadline(time.Now().Add(i.Timeout)); err != nil {
+		return err
+	}
+
+	cmd := redisCommand("PUBLISH", i.Channel, key)
+	if _, err := i.pub.conn.Write(cmd); err != nil {
+		return err
+	}
+
+	v, err := readRedisValue(i.pub.r)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := v.(int64); !ok {
+		return ErrRedisReply
+	}
+
+	return nil
+}
+
+// close closes the connection used to publish. The caller must hold the
+// lock.
+func (i RedisInvalidator) close() {
+	if i.pub.conn != nil {
+		i.pub.conn.Close()
+	}
+
+	i.pub.conn = nil
+	i.pub.r = nil
+}
+
+// redisCommand returns a command encoded as an array of bulk strings.
+func redisCommand(args ...string) []byte {
+	b := []byte(fmt.Sprintf("*%d\r\n", len(args)))
+
+	for _, arg := range args {
+		b = append(b, fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)...)
+	}
+
+	return b
+}
+
+// readRedisValue reads a value of the Redis protocol.
+//
+// Simple and bulk strings are returned as a string, integers as an int64
+// and arrays as a []interface{}. An error reply is returned as an error.
+func readRedisValue(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, ErrRedisReply
+	}
+
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+
+	case '-':
+		return nil, fmt.Errorf("Redis error : %v", body)
+
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+
+		if n < 0 {
+			return nil, nil
+		}
+
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+
+		return string(b[:n]), nil
+
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+
+		values := []interface{}{}
+
+		for j := 0; j < n; j++ {
+			v, err := readRedisValue(r)
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, v)
+		}
+
+		return values, nil
+	}
+
+	return nil, ErrRedisReply
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRedisInvalidator(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// a server that relays each PUBLISH to the subscriber
+	messages := make(chan string, 1)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+
+				r := bufio.NewReader(conn)
+
+				for {
+					v, err := readRedisValue(r)
+					if err != nil {
+						return
+					}
+
+					args := v.([]interface{})
+
+					switch args[0] {
+					case "SUBSCRIBE":
+						conn.Write([]byte("*3\r\n$9\r\nsubscribe\r\n$4\r\ntest\r\n:1\r\n"))
+
+						for key := range messages {
+							conn.Write(redisCommand("message", "test", key))
+						}
+
+					case "PUBLISH":
+						messages <- args[2].(string)
+						conn.Write([]byte(":1\r\n"))
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	i := NewRedisInvalidator(l.Addr().String(), "test")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan string)
+	ready := make(chan struct{})
+
+	go i.Subscribe(ctx, func() {
+		close(ready)
+	}, func(key string) {
+		received <- key
+	})
+
+	<-ready
+
+	want := []string{"contracts/foo", "contracts/bar"}
+	got := []string{}
+
+	for _, key := range want {
+		if err := i.Publish(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+
+		got = append(got, <-received)
+	}
+
+	cancel()
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%#+v\nwant\n%#+v", got, want)
+	}
+}
+
+func TestRedisInvalidator_hung(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// a server that accepts commands and never replies
+	go func() {
+		conns := []net.Conn{}
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			conns = append(conns, conn)
+		}
+	}()
+
+	i := NewRedisInvalidator(l.Addr().String(), "test")
+	i.Timeout = 50 * time.Millisecond
+
+	for n := 0; n < 2; n++ {
+		start := time.Now()
+
+		if err := i.Publish(context.Background(), "contracts/foo"); err == nil {
+			t.Fatal("got no error, want a timeout")
+		}
+
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("got publish blocked for %v, want the timeout", elapsed)
+		}
+
+		// the failed connection is dropped, so the next publish reconnects
+		if i.pub.conn != nil {
+			t.Fatal("got the failed connection kept")
+		}
+	}
+}
+
+func TestReadRedisValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  interface{}
+	}{
+		{
+			name:  "simple string",
+			reply: "+OK\r\n",
+			want:  "OK",
+		},
+		{
+			name:  "integer",
+			reply: ":2\r\n",
+			want:  int64(2),
+		},
+		{
+			name:  "array",
+			reply: "*2\r\n$7\r\nmessage\r\n$0\r\n\r\n",
+			want:  []interface{}{"message", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewBufferString(tt.reply))
+
+			got, err := readRedisValue(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%#+v\nwant\n%#+v", got, tt.want)
+			}
+		})
+	}
+}
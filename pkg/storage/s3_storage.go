@@ -15,6 +15,8 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+
+	"github.com/tokenized/smart-contract/pkg/errs"
 )
 
 // S3Storage implements the Storage interface for interacting with AWS S3.
@@ -65,7 +67,7 @@ func (s S3Storage) Write(ctx context.Context,
 	_, err := svc.PutObject(&poi)
 
 	if err != nil {
-		return fmt.Errorf("Failed to write to %v : %v", key, err)
+		return errs.Wrap(errs.Temporary, fmt.Errorf("Failed to write to %v : %v", key, err))
 	}
 
 	return nil
@@ -86,7 +88,7 @@ func (s S3Storage) Read(ctx context.Context, key string) ([]byte, error) {
 			return nil, ErrNotFound
 		}
 
-		return nil, fmt.Errorf("Failed to read from %v : %v", key, err)
+		return nil, errs.Wrap(errs.Temporary, fmt.Errorf("Failed to read from %v : %v", key, err))
 	}
 
 	b, err := ioutil.ReadAll(document.Body)
@@ -109,7 +111,7 @@ func (s S3Storage) Remove(ctx context.Context, key string) error {
 	_, err := svc.DeleteObject(do)
 
 	if err != nil {
-		return fmt.Errorf("Failed to delete object at %v : %v", key, err)
+		return errs.Wrap(errs.Temporary, fmt.Errorf("Failed to delete object at %v : %v", key, err))
 	}
 
 	return nil
@@ -148,7 +150,7 @@ func (s S3Storage) Search(ctx context.Context,
 	iter := &s3manager.DownloadObjectsIterator{Objects: objects}
 
 	if err := svc.DownloadWithIterator(ctx, iter); err != nil {
-		return nil, err
+		return nil, errs.Wrap(errs.Temporary, err)
 	}
 
 	return buf.objects(), nil
@@ -167,7 +169,7 @@ func (s S3Storage) findKeys(ctx context.Context,
 
 	out, err := svc.ListObjectsV2(input)
 	if err != nil {
-		return nil, err
+		return nil, errs.Wrap(errs.Temporary, err)
 	}
 
 	keys := make([]string, len(out.Contents), len(out.Contents))
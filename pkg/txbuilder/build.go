@@ -14,6 +14,7 @@ import (
 const (
 	MaxTxFee          = 425
 	OutputFeeP2PKH    = 34
+	OutputFeeP2SH     = 32
 	OutputFeeOpReturn = 20
 	OutputOpDataFee   = 3
 	InputFeeP2PKH     = 148
@@ -103,6 +104,8 @@ func buildWithTxOuts(outputs []TxOutput,
 		return nil, nil, err
 	}
 
+	changeIndex := -1
+
 	if change > 0 {
 		// do we have an output for this address already?
 		output := TxOutput{}
@@ -117,6 +120,7 @@ func buildWithTxOuts(outputs []TxOutput,
 
 				output = o
 				outputs[i] = o
+				changeIndex = i
 
 				break
 			}
@@ -125,11 +129,12 @@ func buildWithTxOuts(outputs []TxOutput,
 		fmt.Printf("output = %+v\n", output)
 		if output.Value == 0 && change >= DustMinimumOutput {
 			output = TxOutput{
-				Type:    OutputTypeP2PK,
+				Type:    OutputTypeForAddress(changeAddr),
 				Address: changeAddr,
 				Value:   change,
 			}
 
+			changeIndex = len(outputs)
 			outputs = append(outputs, output)
 		}
 	}
@@ -162,10 +167,11 @@ func buildWithTxOuts(outputs []TxOutput,
 	}}, spendableTxOuts...)
 
 	return &Tx{
-		SelfPkHash: address.ScriptAddress(),
-		Type:       spendOutputType,
-		MsgTx:      tx,
-		Inputs:     inputs,
+		SelfPkHash:  address.ScriptAddress(),
+		Type:        spendOutputType,
+		MsgTx:       tx,
+		Inputs:      inputs,
+		ChangeIndex: changeIndex,
 	}, spendableTxOuts, nil
 }
 
@@ -217,6 +223,8 @@ func BuildUnsignedWithTxOuts(outputs []TxOutput,
 		change = 0
 	}
 
+	changeIndex := -1
+
 	if change > 0 {
 		output := TxOutput{
 			Type:    OutputTypeP2PK,
@@ -225,6 +233,7 @@ func BuildUnsignedWithTxOuts(outputs []TxOutput,
 		}
 
 		// change is added after the other P2PK outputs
+		changeIndex = len(outputs)
 		outputs = append(outputs, output)
 	}
 
@@ -261,10 +270,11 @@ func BuildUnsignedWithTxOuts(outputs []TxOutput,
 	}}, spendableTxOuts...)
 
 	return &Tx{
-		SelfPkHash: address.ScriptAddress(),
-		Type:       spendOutputType,
-		MsgTx:      tx,
-		Inputs:     inputs,
+		SelfPkHash:  address.ScriptAddress(),
+		Type:        spendOutputType,
+		MsgTx:       tx,
+		Inputs:      inputs,
+		ChangeIndex: changeIndex,
 	}, spendableTxOuts, nil
 }
 
@@ -273,6 +283,9 @@ func getAppOutputFee(output TxOutput) (uint64, error) {
 	case OutputTypeP2PK:
 		return OutputFeeP2PKH, nil
 
+	case OutputTypeP2SH:
+		return OutputFeeP2SH, nil
+
 	case OutputTypeReturn:
 		return uint64(len(output.Data)) + 15, nil
 	}
@@ -26,6 +26,16 @@ func Create(spendOuts []*TxOutput,
 				return nil, err
 			}
 			txOuts = append(txOuts, wire.NewTxOut(int64(spendOutput.Value), pkScript))
+		case OutputTypeP2SH:
+			pkScript, err := txscript.NewScriptBuilder().
+				AddOp(txscript.OP_HASH160).
+				AddData(spendOutput.Address.ScriptAddress()).
+				AddOp(txscript.OP_EQUAL).
+				Script()
+			if err != nil {
+				return nil, err
+			}
+			txOuts = append(txOuts, wire.NewTxOut(int64(spendOutput.Value), pkScript))
 		case OutputTypeReturn:
 			pkScript, err := txscript.NewScriptBuilder().
 				AddOp(txscript.OP_RETURN).
@@ -96,6 +106,16 @@ func CreateUnsigned(spendOuts []*TxOutput,
 				return nil, err
 			}
 			txOuts = append(txOuts, wire.NewTxOut(int64(spendOutput.Value), pkScript))
+		case OutputTypeP2SH:
+			pkScript, err := txscript.NewScriptBuilder().
+				AddOp(txscript.OP_HASH160).
+				AddData(spendOutput.Address.ScriptAddress()).
+				AddOp(txscript.OP_EQUAL).
+				Script()
+			if err != nil {
+				return nil, err
+			}
+			txOuts = append(txOuts, wire.NewTxOut(int64(spendOutput.Value), pkScript))
 		case OutputTypeReturn:
 			pkScript, err := txscript.NewScriptBuilder().
 				AddOp(txscript.OP_RETURN).
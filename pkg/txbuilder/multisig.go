@@ -0,0 +1,386 @@
+package txbuilder
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"sort"
+
+	"github.com/tokenized/smart-contract/pkg/txscript"
+	"github.com/tokenized/smart-contract/pkg/wire"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+)
+
+const (
+	// inputFeeMultiSigBase is the size of an input excluding the
+	// signatures and redeem script.
+	inputFeeMultiSigBase = 41 + 1 + 3
+
+	// inputFeeMultiSigSig is the size of a single pushed signature.
+	inputFeeMultiSigSig = 1 + 73
+)
+
+var (
+	// ErrNotEnoughSignatures is returned when an unlocking script cannot be
+	// built because too few signatures were provided.
+	ErrNotEnoughSignatures = errors.New("Not enough signatures")
+
+	// ErrNotMultiSigKey is returned for a public key that is not one of the
+	// keys of a redeem script.
+	ErrNotMultiSigKey = errors.New("Public key not in redeem script")
+
+	// ErrInvalidSignature is returned for a signature that doesn't verify
+	// against the input it is for.
+	ErrInvalidSignature = errors.New("Invalid signature")
+
+	// ErrInvalidPayload is returned for an OP_RETURN payload too short to
+	// hold the push of its data.
+	ErrInvalidPayload = errors.New("Invalid OP_RETURN payload")
+)
+
+// NewMultiSigScript returns the redeem script requiring required of the
+// public keys to sign, and the P2SH address on the network of the params
+// that pays to it.
+func NewMultiSigScript(pubKeys []*btcec.PublicKey,
+	required int,
+	params *chaincfg.Params) ([]byte, btcutil.Address, error) {
+
+	addresses := []*btcutil.AddressPubKey{}
+
+	for _, pubKey := range pubKeys {
+		a, err := btcutil.NewAddressPubKey(pubKey.SerializeCompressed(),
+			params)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		addresses = append(addresses, a)
+	}
+
+	script, err := txscript.MultiSigScript(addresses, required)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	address, err := btcutil.NewAddressScriptHash(script, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return script, address, nil
+}
+
+// multiSigPubKeys returns the serialized public keys of a P2SH multisig
+// redeem script, in the order they appear, and the number of signatures it
+// requires. A script that is not a multisig has no keys.
+func multiSigPubKeys(redeemScript []byte) ([][]byte, int, error) {
+	if txscript.GetScriptClass(redeemScript) != txscript.MultiSigTy {
+		return nil, 0, nil
+	}
+
+	_, required, err := txscript.CalcMultiSigStats(redeemScript)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data, err := txscript.PushedData(redeemScript)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// the first and last items are the number of signatures and keys
+	return data[1 : len(data)-1], required, nil
+}
+
+// SignMultiSigInput returns the signature of key for input idx of the tx,
+// which spends the utxo locked by the P2SH multisig redeem script.
+func SignMultiSigInput(tx *wire.MsgTx,
+	idx int,
+	redeemScript []byte,
+	key *btcec.PrivateKey,
+	utxo UTXO) ([]byte, error) {
+
+	return txscript.RawTxInSignature(tx,
+		idx,
+		redeemScript,
+		txscript.SigHashAll+SigHashForkID,
+		key,
+		int64(utxo.Value))
+}
+
+// IsMultiSigKey returns true if the serialized public key is one of the
+// keys of the P2SH multisig redeem script.
+func IsMultiSigKey(redeemScript []byte, pubKey []byte) (bool, error) {
+	keys, _, err := multiSigPubKeys(redeemScript)
+	if err != nil {
+		return false, err
+	}
+
+	for _, key := range keys {
+		if bytes.Equal(key, pubKey) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// VerifyMultiSigInput returns nil if sig, as returned by SignMultiSigInput,
+// is a valid signature by the public key of input idx of the tx, which
+// spends the utxo locked by the P2SH multisig redeem script. The public key
+// must be one of the keys of the redeem script.
+func VerifyMultiSigInput(tx *wire.MsgTx,
+	idx int,
+	redeemScript []byte,
+	pubKey []byte,
+	sig []byte,
+	utxo UTXO) error {
+
+	ok, err := IsMultiSigKey(redeemScript, pubKey)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrNotMultiSigKey
+	}
+
+	// the signature must be of the whole TX, as SignMultiSigInput makes it
+	if len(sig) < 2 ||
+		txscript.SigHashType(sig[len(sig)-1]) != txscript.SigHashAll+SigHashForkID {
+		return ErrInvalidSignature
+	}
+
+	signature, err := btcec.ParseDERSignature(sig[:len(sig)-1], btcec.S256())
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	key, err := btcec.ParsePubKey(pubKey, btcec.S256())
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	hash, err := txscript.RawTxInSignatureHash(tx,
+		idx,
+		redeemScript,
+		txscript.SigHashAll+SigHashForkID,
+		int64(utxo.Value))
+	if err != nil {
+		return err
+	}
+
+	if !signature.Verify(hash, key) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// MultiSigUnlockingScript returns the signature script for an input
+// spending a P2SH multisig.
+//
+// The signatures are keyed by the hex encoded public key that made them,
+// and are added in the order the public keys appear in the redeem script.
+func MultiSigUnlockingScript(redeemScript []byte,
+	sigs map[string][]byte) ([]byte, error) {
+
+	keys, required, err := multiSigPubKeys(redeemScript)
+	if err != nil {
+		return nil, err
+	}
+
+	// a single OP_FALSE is required to work around the extra item popped by
+	// OP_CHECKMULTISIG
+	builder := txscript.NewScriptBuilder().AddOp(txscript.OP_FALSE)
+	signed := 0
+
+	for _, key := range keys {
+		if signed == required {
+			break
+		}
+
+		sig, ok := sigs[hex.EncodeToString(key)]
+		if !ok {
+			continue
+		}
+
+		builder.AddData(sig)
+		signed++
+	}
+
+	if signed < required {
+		return nil, ErrNotEnoughSignatures
+	}
+
+	builder.AddData(redeemScript)
+
+	return builder.Script()
+}
+
+// MultiSigTxBuilder builds transactions spending from a P2SH multisig
+// address.
+//
+// The transactions are not signed. Each input must be signed by enough of
+// the keys in the redeem script, before the unlocking scripts are added.
+type MultiSigTxBuilder struct {
+	RedeemScript []byte
+}
+
+// NewMultiSigTxBuilder returns a new MultiSigTxBuilder.
+func NewMultiSigTxBuilder(redeemScript []byte) MultiSigTxBuilder {
+	return MultiSigTxBuilder{
+		RedeemScript: redeemScript,
+	}
+}
+
+// Build returns an unsigned TX spending the UTXO's to the outputs, with the
+// OP_RETURN payload as the last output.
+func (s MultiSigTxBuilder) Build(utxos UTXOs,
+	outs []PayAddress,
+	changeAddress btcutil.Address,
+	opReturnPayload []byte) (*wire.MsgTx, error) {
+
+	tx, err := s.BuildTx(utxos, outs, changeAddress, opReturnPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.MsgTx, nil
+}
+
+// BuildTx is Build, returning the Tx with the index of the change output.
+func (s MultiSigTxBuilder) BuildTx(utxos UTXOs,
+	outs []PayAddress,
+	changeAddress btcutil.Address,
+	opReturnPayload []byte) (*Tx, error) {
+
+	_, required, err := txscript.CalcMultiSigStats(s.RedeemScript)
+	if err != nil {
+		return nil, err
+	}
+
+	inputFee := uint64(inputFeeMultiSigBase +
+		required*inputFeeMultiSigSig +
+		len(s.RedeemScript))
+
+	spendableTxOuts := make([]*TxOutput, len(utxos), len(utxos))
+
+	for i, utxo := range utxos {
+		spendableTxOuts[i] = &TxOutput{
+			PkScript:        utxo.PkScript,
+			Value:           utxo.Value,
+			TransactionHash: utxo.Hash.CloneBytes(),
+			Index:           uint32(utxo.Index),
+		}
+	}
+
+	sort.Sort(TxOutSortByValue(spendableTxOuts))
+
+	// the change output is paid for up front, at the size of an output to
+	// the change address
+	changeFee, err := getAppOutputFee(TxOutput{
+		Type: OutputTypeForAddress(changeAddress),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := []TxOutput{}
+	totalOutputValue := uint64(0)
+	fee := uint64(BaseTxFee) + changeFee
+
+	for _, o := range outs {
+		if o.Value == 0 {
+			continue
+		}
+
+		out := TxOutput{
+			Address: o.Address,
+			Value:   o.Value,
+			Type:    OutputTypeForAddress(o.Address),
+		}
+
+		outputFee, err := getAppOutputFee(out)
+		if err != nil {
+			return nil, err
+		}
+
+		fee += outputFee
+		totalOutputValue += out.Value
+		outputs = append(outputs, out)
+	}
+
+	// get the actual payload from the OP_RETURN
+	if len(opReturnPayload) < 2 {
+		return nil, ErrInvalidPayload
+	}
+
+	startIndex := 3
+	if opReturnPayload[1] < 0x4c {
+		startIndex = 2
+	}
+
+	if len(opReturnPayload) < startIndex {
+		return nil, ErrInvalidPayload
+	}
+
+	opReturn := TxOutput{
+		Type: OutputTypeReturn,
+		Data: opReturnPayload[startIndex:],
+	}
+
+	opReturnFee, err := getAppOutputFee(opReturn)
+	if err != nil {
+		return nil, err
+	}
+
+	fee += opReturnFee
+
+	// spend the largest UTXO's first, until the outputs and fee are covered
+	txOutsToUse := []*TxOutput{}
+	totalInputValue := uint64(0)
+
+	for _, spendable := range spendableTxOuts {
+		if totalInputValue >= totalOutputValue+fee {
+			break
+		}
+
+		txOutsToUse = append(txOutsToUse, spendable)
+		totalInputValue += spendable.Value
+		fee += inputFee
+	}
+
+	if totalInputValue < totalOutputValue+fee {
+		return nil, notEnoughValueError
+	}
+
+	change := totalInputValue - totalOutputValue - fee
+	changeIndex := -1
+
+	if change >= DustMinimumOutput {
+		changeIndex = len(outputs)
+		outputs = append(outputs, TxOutput{
+			Type:    OutputTypeForAddress(changeAddress),
+			Address: changeAddress,
+			Value:   change,
+		})
+	}
+
+	// add the OP_RETURN payload last
+	outputs = append(outputs, opReturn)
+
+	tx, err := CreateUnsigned(txOutsToUse, outputs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{
+		Type:        OutputTypeP2SH,
+		MsgTx:       tx,
+		ChangeIndex: changeIndex,
+	}, nil
+}
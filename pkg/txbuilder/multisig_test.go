@@ -0,0 +1,232 @@
+package txbuilder
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/tokenized/smart-contract/pkg/txscript"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestMultiSigTxBuilder_Build(t *testing.T) {
+	keys := []*btcec.PrivateKey{}
+	pubKeys := []*btcec.PublicKey{}
+
+	for i := 0; i < 3; i++ {
+		key, err := btcec.NewPrivateKey(btcec.S256())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		keys = append(keys, key)
+		pubKeys = append(pubKeys, key.PubKey())
+	}
+
+	// 2 of 3 multisig
+	redeemScript, address, err := NewMultiSigScript(pubKeys, 2,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	utxo := UTXO{
+		Hash:     newHash("2c2786fe332e94ea61f2a0aef6037cd08bf6495f800a4c829c0f1c07e6104ab8"),
+		Index:    0,
+		PkScript: pkScript,
+		Value:    10000,
+	}
+
+	// the P2SH output must be recognized as paying to the multisig address
+	utxoAddress, err := utxo.PublicAddress(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if utxoAddress.EncodeAddress() != address.EncodeAddress() {
+		t.Fatalf("got address %v, want %v", utxoAddress, address)
+	}
+
+	receiver := decodeAddress("18H59cUZMAPRhp74xoeE6LXingw3Wxr3VG")
+
+	outs := []PayAddress{
+		NewPayAddress(receiver, 546),
+	}
+
+	payload, err := hex.DecodeString("6a1500000020")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builder := NewMultiSigTxBuilder(redeemScript)
+
+	built, err := builder.BuildTx(UTXOs{utxo}, outs, address, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if built.ChangeIndex != 1 {
+		t.Fatalf("got change index %v, want 1", built.ChangeIndex)
+	}
+
+	tx := built.MsgTx
+
+	if len(tx.TxIn) != 1 {
+		t.Fatalf("got %v inputs, want 1", len(tx.TxIn))
+	}
+
+	// receiver, change back to the multisig, and the OP_RETURN
+	if len(tx.TxOut) != 3 {
+		t.Fatalf("got %v outputs, want 3", len(tx.TxOut))
+	}
+
+	if !bytes.Equal(tx.TxOut[1].PkScript, pkScript) {
+		t.Errorf("got change script %x, want %x", tx.TxOut[1].PkScript, pkScript)
+	}
+
+	// the fee covers the P2SH change output at its own size
+	fee := uint64(BaseTxFee + OutputFeeP2PKH + OutputFeeP2SH +
+		len(payload) - 2 + 15 +
+		inputFeeMultiSigBase + 2*inputFeeMultiSigSig + len(redeemScript))
+
+	if got := uint64(tx.TxOut[1].Value); got != utxo.Value-546-fee {
+		t.Errorf("got change %v, want %v", got, utxo.Value-546-fee)
+	}
+
+	sigs := map[string][]byte{}
+
+	for _, key := range keys[:2] {
+		sig, err := SignMultiSigInput(tx, 0, redeemScript, key, utxo)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pubKey := hex.EncodeToString(key.PubKey().SerializeCompressed())
+		sigs[pubKey] = sig
+
+		if len(sigs) == 1 {
+			// a single signature is not enough to unlock
+			if _, err := MultiSigUnlockingScript(redeemScript, sigs); err != ErrNotEnoughSignatures {
+				t.Errorf("got error %v, want %v", err, ErrNotEnoughSignatures)
+			}
+		}
+	}
+
+	script, err := MultiSigUnlockingScript(redeemScript, sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pushes, err := txscript.PushedData(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// OP_FALSE, 2 signatures and the redeem script
+	if len(pushes) != 4 {
+		t.Fatalf("got %v pushes, want 4", len(pushes))
+	}
+
+	if !bytes.Equal(pushes[3], redeemScript) {
+		t.Errorf("got redeem script %x, want %x", pushes[3], redeemScript)
+	}
+}
+
+func TestMultiSigTxBuilder_Build_payload(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	redeemScript, address, err := NewMultiSigScript(
+		[]*btcec.PublicKey{key.PubKey()}, 1, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	utxo := UTXO{
+		Hash:     newHash("2c2786fe332e94ea61f2a0aef6037cd08bf6495f800a4c829c0f1c07e6104ab8"),
+		PkScript: pkScript,
+		Value:    10000,
+	}
+
+	tests := []struct {
+		name    string
+		payload string
+		want    error
+	}{
+		{
+			name: "empty",
+			want: ErrInvalidPayload,
+		},
+		{
+			name:    "no push",
+			payload: "6a",
+			want:    ErrInvalidPayload,
+		},
+		{
+			name:    "no push length",
+			payload: "6a4c",
+			want:    ErrInvalidPayload,
+		},
+		{
+			name:    "empty push",
+			payload: "6a4c00",
+		},
+		{
+			name:    "push",
+			payload: "6a0100",
+		},
+	}
+
+	builder := NewMultiSigTxBuilder(redeemScript)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := hex.DecodeString(tt.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = builder.BuildTx(UTXOs{utxo}, nil, address, payload)
+			if err != tt.want {
+				t.Fatalf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewMultiSigScript_params(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pubKeys := []*btcec.PublicKey{key.PubKey()}
+
+	for _, params := range []*chaincfg.Params{
+		&chaincfg.MainNetParams,
+		&chaincfg.TestNet3Params,
+	} {
+		_, address, err := NewMultiSigScript(pubKeys, 1, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !address.IsForNet(params) {
+			t.Fatalf("got address %v, want one for %v", address, params.Name)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package txbuilder
 
 import (
 	"bytes"
+	"fmt"
 
 	"github.com/tokenized/smart-contract/pkg/txscript"
 	"github.com/tokenized/smart-contract/pkg/wire"
@@ -47,3 +48,31 @@ func sign(privateKey *btcec.PrivateKey,
 
 	return w.Bytes(), nil
 }
+
+// SignTX adds P2PKH unlocking scripts, signed by the key, to each input of
+// the TX. The UTXO spent by each input must be in utxos.
+func SignTX(tx *wire.MsgTx, privateKey *btcec.PrivateKey, utxos UTXOs) error {
+	for i, txIn := range tx.TxIn {
+		utxo, ok := utxos.ForOutPoint(txIn.PreviousOutPoint)
+		if !ok {
+			return fmt.Errorf("No UTXO for input %v", i)
+		}
+
+		signature, err := txscript.SignatureScript(
+			tx,
+			i,
+			utxo.PkScript,
+			txscript.SigHashAll+SigHashForkID,
+			privateKey,
+			true,
+			int64(utxo.Value))
+
+		if err != nil {
+			return err
+		}
+
+		txIn.SignatureScript = signature
+	}
+
+	return nil
+}
@@ -9,6 +9,10 @@ type Tx struct {
 	SelfPkHash []byte
 	MsgTx      *wire.MsgTx
 	Inputs     []*TxInput
+
+	// ChangeIndex is the index of the output the change was paid to, or -1
+	// if there is no change.
+	ChangeIndex int
 }
 
 type TxOutSortByValue []*TxOutput
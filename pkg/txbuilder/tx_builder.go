@@ -26,6 +26,20 @@ func (s TxBuilder) Build(utxos UTXOs,
 	changeAddress btcutil.Address,
 	opReturnPayload []byte) (*wire.MsgTx, error) {
 
+	tx, err := s.BuildTx(utxos, outs, changeAddress, opReturnPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.MsgTx, nil
+}
+
+// BuildTx is Build, returning the Tx with the index of the change output.
+func (s TxBuilder) BuildTx(utxos UTXOs,
+	outs []PayAddress,
+	changeAddress btcutil.Address,
+	opReturnPayload []byte) (*Tx, error) {
+
 	// gather the spendable output details
 	spendableTxOuts := make([]*TxOutput, len(utxos), len(utxos))
 
@@ -52,7 +66,7 @@ func (s TxBuilder) Build(utxos UTXOs,
 		out := TxOutput{
 			Address: o.Address,
 			Value:   o.Value,
-			Type:    OutputTypeP2PK,
+			Type:    OutputTypeForAddress(o.Address),
 		}
 
 		outputs = append(outputs, out)
@@ -74,10 +88,5 @@ func (s TxBuilder) Build(utxos UTXOs,
 	//
 	// The OP_RETURN will be added at the end of all outputs, including any
 	// change that will be calculated.
-	tx, err := build(spendableTxOuts, outputs, s.PrivateKey, changeAddress, opReturn)
-	if err != nil {
-		return nil, err
-	}
-
-	return tx.MsgTx, nil
+	return build(spendableTxOuts, outputs, s.PrivateKey, changeAddress, opReturn)
 }
@@ -1,15 +1,21 @@
 package txbuilder
 
+import (
+	"github.com/btcsuite/btcutil"
+)
+
 type TxOutputType uint
 
 const (
-	OutputTypeP2PK    TxOutputType = iota
+	OutputTypeP2PK TxOutputType = iota
 	OutputTypeReturn
+	OutputTypeP2SH
 )
 
 const (
-	StringP2pk              = "p2pk"
-	StringReturn            = "return"
+	StringP2pk   = "p2pk"
+	StringReturn = "return"
+	StringP2SH   = "p2sh"
 )
 
 func (s TxOutputType) String() string {
@@ -18,7 +24,18 @@ func (s TxOutputType) String() string {
 		return StringP2pk
 	case OutputTypeReturn:
 		return StringReturn
+	case OutputTypeP2SH:
+		return StringP2SH
 	default:
 		return "unknown"
 	}
 }
+
+// OutputTypeForAddress returns the TxOutputType used to pay to the Address.
+func OutputTypeForAddress(address btcutil.Address) TxOutputType {
+	if _, ok := address.(*btcutil.AddressScriptHash); ok {
+		return OutputTypeP2SH
+	}
+
+	return OutputTypeP2PK
+}
@@ -39,12 +39,20 @@ func NewUTXOFromTX(tx wire.MsgTx, n uint32) UTXO {
 	}
 }
 
-// PublicAddress returns the public address from a P2PKH script.
+// PublicAddress returns the public address from a P2PKH or P2SH script.
 //
-// The script will look something like this
+// A P2PKH script will look something like this:
 //
 // OP_DUP OP_HASH160 <address hash> OP_EQUALVERIFY OP_CHECKSIG
+//
+// A P2SH script will look something like this:
+//
+// OP_HASH160 <script hash> OP_EQUAL
 func (u UTXO) PublicAddress(params *chaincfg.Params) (btcutil.Address, error) {
+	if isPayToScriptHash(u.PkScript) {
+		return btcutil.NewAddressScriptHashFromHash(u.PkScript[2:22], params)
+	}
+
 	if len(u.PkScript) != 25 &&
 		u.PkScript[0] != txscript.OP_DUP &&
 		u.PkScript[1] != txscript.OP_HASH160 {
@@ -54,3 +62,12 @@ func (u UTXO) PublicAddress(params *chaincfg.Params) (btcutil.Address, error) {
 
 	return btcutil.NewAddressPubKeyHash(u.PkScript[3:23], params)
 }
+
+// isPayToScriptHash returns true if the script is a P2SH script, false
+// otherwise.
+func isPayToScriptHash(pkScript []byte) bool {
+	return len(pkScript) == 23 &&
+		pkScript[0] == txscript.OP_HASH160 &&
+		pkScript[1] == txscript.OP_DATA_20 &&
+		pkScript[22] == txscript.OP_EQUAL
+}
@@ -83,3 +83,14 @@ func (u UTXOs) ForAddress(address btcutil.Address) (UTXOs, error) {
 
 	return filtered, nil
 }
+
+// ForOutPoint returns the UTXO that is spent by the OutPoint.
+func (u UTXOs) ForOutPoint(outpoint wire.OutPoint) (UTXO, bool) {
+	for _, utxo := range u {
+		if utxo.Hash == outpoint.Hash && utxo.Index == outpoint.Index {
+			return utxo, true
+		}
+	}
+
+	return UTXO{}, false
+}
@@ -34,6 +34,23 @@ func RawTxInSignature(tx *wire.MsgTx, idx int, subScript []byte,
 	return append(signature.Serialize(), byte(hashType|SigHashForkID)), nil
 }
 
+// RawTxInSignatureHash returns the hash signed by RawTxInSignature for the
+// input idx of the given transaction, so a signature can be verified.
+func RawTxInSignatureHash(tx *wire.MsgTx, idx int, subScript []byte,
+	hashType SigHashType, amt int64) ([]byte, error) {
+
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, fmt.Errorf("input index %d out of range", idx)
+	}
+
+	parsedScript, err := parseScript(subScript)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse output script: %v", err)
+	}
+
+	return calcBip143SignatureHash(parsedScript, NewTxSigHashes(tx), hashType, tx, idx, amt), nil
+}
+
 // SignatureScript creates an input signature script for tx to spend BTC sent
 // from a previous output to the owner of privKey. tx must include all
 // transaction inputs and outputs, however txin scripts are allowed to be filled
@@ -593,6 +593,45 @@ func BenchmarkDecodeMerkleBlock(b *testing.B) {
 	}
 }
 
+// BenchmarkDecodeBlock performs a benchmark on how long it takes to decode
+// a block message.
+func BenchmarkDecodeBlock(b *testing.B) {
+	pver := ProtocolVersion
+
+	var bb bytes.Buffer
+	if err := blockOne.BtcEncode(&bb, pver); err != nil {
+		b.Fatalf("MsgBlock.BtcEncode: unexpected error: %v", err)
+	}
+	buf := bb.Bytes()
+
+	r := bytes.NewReader(buf)
+	var msg MsgBlock
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Seek(0, 0)
+		msg.BtcDecode(r, pver)
+	}
+}
+
+// BenchmarkReadMessageTx performs a benchmark on how long it takes to read a
+// tx message off the wire, including the message header and checksum.
+func BenchmarkReadMessageTx(b *testing.B) {
+	pver := ProtocolVersion
+
+	var bb bytes.Buffer
+	if err := WriteMessage(&bb, &genesisCoinbaseTx, pver, MainNet); err != nil {
+		b.Fatalf("WriteMessage: unexpected error: %v", err)
+	}
+	buf := bb.Bytes()
+
+	r := bytes.NewReader(buf)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Seek(0, 0)
+		ReadMessage(r, pver, MainNet)
+	}
+}
+
 // BenchmarkTxHash performs a benchmark on how long it takes to hash a
 // transaction.
 func BenchmarkTxHash(b *testing.B) {
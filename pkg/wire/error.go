@@ -5,6 +5,7 @@
 package wire
 
 import (
+	"errors"
 	"fmt"
 )
 
@@ -32,3 +33,9 @@ func (e *MessageError) Error() string {
 func messageError(f string, desc string) *MessageError {
 	return &MessageError{Func: f, Description: desc}
 }
+
+// ErrPayloadTooLarge is returned by ReadMessageMaxN for a message whose
+// payload is larger than the maximum of the caller.  Unlike a MessageError,
+// the rest of the message is not read, so the connection can't be read from
+// any further.
+var ErrPayloadTooLarge = errors.New("message payload exceeds the maximum")
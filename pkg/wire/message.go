@@ -51,6 +51,12 @@ const (
 	CmdReject      = "reject"
 	CmdSendHeaders = "sendheaders"
 	CmdFeeFilter   = "feefilter"
+	CmdSendCmpct   = "sendcmpct"
+	CmdCmpctBlock  = "cmpctblock"
+	CmdGetBlockTxn = "getblocktxn"
+	CmdBlockTxn    = "blocktxn"
+	CmdAddrV2      = "addrv2"
+	CmdSendAddrV2  = "sendaddrv2"
 )
 
 // Message is an interface that describes a bitcoin message.  A type that
@@ -138,6 +144,24 @@ func makeEmptyMessage(command string) (Message, error) {
 	case CmdFeeFilter:
 		msg = &MsgFeeFilter{}
 
+	case CmdSendCmpct:
+		msg = &MsgSendCmpct{}
+
+	case CmdCmpctBlock:
+		msg = &MsgCmpctBlock{}
+
+	case CmdGetBlockTxn:
+		msg = &MsgGetBlockTxn{}
+
+	case CmdBlockTxn:
+		msg = &MsgBlockTxn{}
+
+	case CmdAddrV2:
+		msg = &MsgAddrV2{}
+
+	case CmdSendAddrV2:
+		msg = &MsgSendAddrV2{}
+
 	default:
 		return nil, fmt.Errorf("unhandled command [%s]", command)
 	}
@@ -280,6 +304,16 @@ func WriteMessage(w io.Writer, msg Message, pver uint32, btcnet BitcoinNet) erro
 // message.  This function is the same as ReadMessage except it also returns the
 // number of bytes read.
 func ReadMessageN(r io.Reader, pver uint32, btcnet BitcoinNet) (int, Message, []byte, error) {
+	return ReadMessageMaxN(r, pver, btcnet, MaxMessagePayload)
+}
+
+// ReadMessageMaxN is the same as ReadMessageN, except that messages with a
+// payload larger than maxPayload are refused with ErrPayloadTooLarge, before
+// the payload is read.  The payload is left unread, so nothing more can be
+// read from r.
+func ReadMessageMaxN(r io.Reader, pver uint32, btcnet BitcoinNet,
+	maxPayload uint32) (int, Message, []byte, error) {
+
 	totalBytes := 0
 	n, hdr, err := readMessageHeader(r)
 	totalBytes += n
@@ -296,6 +330,11 @@ func ReadMessageN(r io.Reader, pver uint32, btcnet BitcoinNet) (int, Message, []
 
 	}
 
+	// Enforce the maximum payload of the caller.
+	if hdr.length > maxPayload {
+		return totalBytes, nil, nil, ErrPayloadTooLarge
+	}
+
 	// Check for messages from the wrong bitcoin network.
 	if hdr.magic != btcnet {
 		discardInput(r, hdr.length)
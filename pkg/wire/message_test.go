@@ -444,3 +444,46 @@ func TestWriteMessageWireErrors(t *testing.T) {
 		}
 	}
 }
+
+// TestReadMessageMaxN ensures ReadMessageMaxN refuses a payload larger than
+// the maximum of the caller, before reading it, and reads others as usual.
+func TestReadMessageMaxN(t *testing.T) {
+	pver := ProtocolVersion
+	btcnet := MainNet
+
+	var buf bytes.Buffer
+	msg := NewMsgPing(123123)
+	if err := WriteMessage(&buf, msg, pver, btcnet); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	encoded := buf.Bytes()
+
+	n, readmsg, _, err := ReadMessageMaxN(bytes.NewReader(encoded), pver,
+		btcnet, 8)
+	if err != nil {
+		t.Fatalf("ReadMessageMaxN: %v", err)
+	}
+
+	if n != len(encoded) {
+		t.Errorf("ReadMessageMaxN: wrong bytes read - got %v, want %v",
+			n, len(encoded))
+	}
+
+	if !reflect.DeepEqual(readmsg, msg) {
+		t.Errorf("ReadMessageMaxN: got %v, want %v",
+			spew.Sdump(readmsg), spew.Sdump(msg))
+	}
+
+	n, _, _, err = ReadMessageMaxN(bytes.NewReader(encoded), pver, btcnet, 7)
+	if err != ErrPayloadTooLarge {
+		t.Errorf("ReadMessageMaxN: wrong error - got %v, want %v", err,
+			ErrPayloadTooLarge)
+	}
+
+	// only the header is read
+	if n != MessageHeaderSize {
+		t.Errorf("ReadMessageMaxN: wrong bytes read - got %v, want %v",
+			n, MessageHeaderSize)
+	}
+}
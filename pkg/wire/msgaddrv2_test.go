@@ -0,0 +1,180 @@
+package wire
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestAddrV2 tests the MsgAddrV2 API.
+func TestAddrV2(t *testing.T) {
+	pver := ProtocolVersion
+
+	// Ensure the command is expected value.
+	wantCmd := "addrv2"
+	msg := NewMsgAddrV2()
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgAddrV2: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value.
+	wantPayload := uint32(537009)
+	if maxPayload := msg.MaxPayloadLength(pver); maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length - got %v, "+
+			"want %v", maxPayload, wantPayload)
+	}
+
+	na := NewNetAddressV2IPPort(net.ParseIP("127.0.0.1"), 8333, SFNodeNetwork)
+	if na.NetworkID != NetIDIPv4 || len(na.Addr) != 4 {
+		t.Errorf("NewNetAddressV2IPPort: wrong address - got %v %x",
+			na.NetworkID, na.Addr)
+	}
+
+	if !na.IP().Equal(net.ParseIP("127.0.0.1")) {
+		t.Errorf("IP: wrong IP - got %v", na.IP())
+	}
+
+	if err := msg.AddAddress(na); err != nil {
+		t.Errorf("AddAddress: %v", err)
+	}
+
+	msg.ClearAddresses()
+	if len(msg.AddrList) != 0 {
+		t.Errorf("ClearAddresses: address list is not empty - "+
+			"got %v [%v], want %v", len(msg.AddrList),
+			spew.Sdump(msg.AddrList[0]), 0)
+	}
+
+	// Ensure adding more than the max allowed addresses per message
+	// returns an error.
+	for i := 0; i < MaxAddrPerMsg+1; i++ {
+		err := msg.AddAddress(na)
+		if i < MaxAddrPerMsg && err != nil {
+			t.Fatalf("AddAddress: %v", err)
+		}
+
+		if i == MaxAddrPerMsg && err == nil {
+			t.Errorf("AddAddress: expected error on too many " +
+				"addresses not received")
+		}
+	}
+}
+
+// TestAddrV2Wire tests the MsgAddrV2 wire encode and decode.
+func TestAddrV2Wire(t *testing.T) {
+	pver := ProtocolVersion
+
+	torV3 := bytes.Repeat([]byte{0xab}, 32)
+
+	msg := NewMsgAddrV2()
+	msg.AddAddresses(
+		&NetAddressV2{
+			Timestamp: time.Unix(0x495fab29, 0),
+			Services:  SFNodeNetwork,
+			NetworkID: NetIDIPv4,
+			Addr:      []byte{127, 0, 0, 1},
+			Port:      8333,
+		},
+		&NetAddressV2{
+			Timestamp: time.Unix(0x495fab29, 0),
+			Services:  SFNodeNetwork | SFNodeBitcoinCash,
+			NetworkID: NetIDTorV3,
+			Addr:      torV3,
+			Port:      8333,
+		},
+	)
+
+	wantBytes := []byte{
+		0x02,                   // Varint for number of addresses
+		0x29, 0xab, 0x5f, 0x49, // Timestamp
+		0x01,               // Services varint
+		0x01,               // IPv4
+		0x04, 127, 0, 0, 1, // Address
+		0x20, 0x8d, // Port 8333 in big-endian
+		0x29, 0xab, 0x5f, 0x49, // Timestamp
+		0x21, // Services varint
+		0x04, // Tor v3
+		0x20, // Address length
+	}
+	wantBytes = append(wantBytes, torV3...)
+	wantBytes = append(wantBytes, 0x20, 0x8d)
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, pver); err != nil {
+		t.Fatalf("encode of MsgAddrV2 failed %v err <%v>", msg, err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), wantBytes) {
+		t.Errorf("BtcEncode got: %s want: %s",
+			spew.Sdump(buf.Bytes()), spew.Sdump(wantBytes))
+	}
+
+	readmsg := MsgAddrV2{}
+	if err := readmsg.BtcDecode(bytes.NewReader(wantBytes), pver); err != nil {
+		t.Fatalf("decode of MsgAddrV2 failed err <%v>", err)
+	}
+
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("BtcDecode got: %s want: %s",
+			spew.Sdump(readmsg), spew.Sdump(msg))
+	}
+
+	if readmsg.AddrList[1].IP() != nil {
+		t.Errorf("IP: got %v for a Tor address", readmsg.AddrList[1].IP())
+	}
+}
+
+// TestAddrV2WireErrors performs negative tests against the MsgAddrV2 wire
+// decode, to confirm malformed addresses are rejected.
+func TestAddrV2WireErrors(t *testing.T) {
+	pver := ProtocolVersion
+
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{
+			name: "wrong IPv4 length",
+			buf: []byte{
+				0x01, 0x29, 0xab, 0x5f, 0x49, 0x01, 0x01,
+				0x05, 127, 0, 0, 1, 1, 0x20, 0x8d,
+			},
+		},
+		{
+			name: "address too long",
+			buf: []byte{
+				0x01, 0x29, 0xab, 0x5f, 0x49, 0x01, 0x07,
+				0xfd, 0x01, 0x02,
+			},
+		},
+		{
+			name: "too many addresses",
+			buf:  []byte{0xfd, 0xe9, 0x03},
+		},
+	}
+
+	for _, test := range tests {
+		msg := MsgAddrV2{}
+		err := msg.BtcDecode(bytes.NewReader(test.buf), pver)
+		if _, ok := err.(*MessageError); !ok {
+			t.Errorf("%v: wrong error - got %v, want MessageError",
+				test.name, err)
+		}
+	}
+
+	// An address of an unknown network is decoded, whatever its length.
+	buf := []byte{
+		0x01, 0x29, 0xab, 0x5f, 0x49, 0x01, 0x42,
+		0x03, 1, 2, 3, 0x20, 0x8d,
+	}
+
+	msg := MsgAddrV2{}
+	if err := msg.BtcDecode(bytes.NewReader(buf), pver); err != nil {
+		t.Errorf("decode of unknown network failed err <%v>", err)
+	}
+}
@@ -0,0 +1,47 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestBlockTxn tests the MsgBlockTxn API.
+func TestBlockTxn(t *testing.T) {
+	pver := ProtocolVersion
+
+	hash := blockOne.BlockHash()
+	msg := NewMsgBlockTxn(&hash)
+	msg.AddTransaction(blockOne.Transactions[0])
+	msg.AddTransaction(multiTx)
+
+	// Ensure the command is expected value.
+	wantCmd := "blocktxn"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgBlockTxn: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, pver); err != nil {
+		t.Fatalf("encode of MsgBlockTxn failed %v err <%v>", msg, err)
+	}
+
+	readmsg := MsgBlockTxn{}
+	if err := readmsg.BtcDecode(&buf, pver); err != nil {
+		t.Fatalf("decode of MsgBlockTxn failed err <%v>", err)
+	}
+
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("BtcDecode got: %s want: %s",
+			spew.Sdump(readmsg), spew.Sdump(msg))
+	}
+
+	// Older protocol versions must fail.
+	if err := msg.BtcDecode(&buf, BIP0152Version-1); err == nil {
+		t.Errorf("decode of MsgBlockTxn succeeded when it should " +
+			"have failed")
+	}
+}
@@ -0,0 +1,231 @@
+package wire
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// ShortIDSize is the size in bytes of the short transaction ID of a compact
+// block.
+const ShortIDSize = 6
+
+// shortIDMask masks a SipHash to the bits of a short transaction ID.
+const shortIDMask = (1 << (8 * ShortIDSize)) - 1
+
+// PrefilledTx is a transaction sent in full in a compact block, with its
+// index in the block.
+type PrefilledTx struct {
+	Index uint32
+	Tx    *MsgTx
+}
+
+// MsgCmpctBlock implements the Message interface and represents a bitcoin
+// cmpctblock message.  It is used to relay a block as its header, the short
+// IDs of its transactions, and the transactions the receiver is not
+// expected to have, such as the coinbase, as described in BIP0152.
+//
+// This message was not added until protocol versions starting with
+// BIP0152Version.
+type MsgCmpctBlock struct {
+	Header       BlockHeader
+	Nonce        uint64
+	ShortIDs     []uint64
+	PrefilledTxs []PrefilledTx
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) BtcDecode(r io.Reader, pver uint32) error {
+	if pver < BIP0152Version {
+		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+
+	if err := readBlockHeader(r, pver, &msg.Header); err != nil {
+		return err
+	}
+
+	if err := readElement(r, &msg.Nonce); err != nil {
+		return err
+	}
+
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+
+	// Prevent more short IDs than could possibly fit into a block.
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many short ids for message "+
+			"[count %v, max %v]", count, maxTxPerBlock)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+
+	msg.ShortIDs = make([]uint64, 0, count)
+	buf := make([]byte, 8)
+	for i := uint64(0); i < count; i++ {
+		if _, err := io.ReadFull(r, buf[:ShortIDSize]); err != nil {
+			return err
+		}
+
+		msg.ShortIDs = append(msg.ShortIDs, binary.LittleEndian.Uint64(buf))
+	}
+
+	count, err = ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+
+	// Prevent more transactions than could possibly fit into a block.
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many prefilled transactions for message "+
+			"[count %v, max %v]", count, maxTxPerBlock)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+
+	// each index is the difference from the index before it, less one
+	msg.PrefilledTxs = make([]PrefilledTx, 0, count)
+	var last int64 = -1
+	for i := uint64(0); i < count; i++ {
+		diff, err := ReadVarInt(r, pver)
+		if err != nil {
+			return err
+		}
+
+		index := last + int64(diff) + 1
+		if diff > maxTxPerBlock || index > maxTxPerBlock {
+			str := fmt.Sprintf("prefilled transaction index %v out of "+
+				"range", index)
+			return messageError("MsgCmpctBlock.BtcDecode", str)
+		}
+
+		tx := MsgTx{}
+		if err := tx.BtcDecode(r, pver); err != nil {
+			return err
+		}
+
+		msg.PrefilledTxs = append(msg.PrefilledTxs, PrefilledTx{
+			Index: uint32(index),
+			Tx:    &tx,
+		})
+
+		last = index
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) BtcEncode(w io.Writer, pver uint32) error {
+	if pver < BIP0152Version {
+		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgCmpctBlock.BtcEncode", str)
+	}
+
+	if err := writeBlockHeader(w, pver, &msg.Header); err != nil {
+		return err
+	}
+
+	if err := writeElement(w, msg.Nonce); err != nil {
+		return err
+	}
+
+	if err := WriteVarInt(w, pver, uint64(len(msg.ShortIDs))); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 8)
+	for _, id := range msg.ShortIDs {
+		binary.LittleEndian.PutUint64(buf, id)
+		if _, err := w.Write(buf[:ShortIDSize]); err != nil {
+			return err
+		}
+	}
+
+	if err := WriteVarInt(w, pver, uint64(len(msg.PrefilledTxs))); err != nil {
+		return err
+	}
+
+	var last int64 = -1
+	for _, p := range msg.PrefilledTxs {
+		if int64(p.Index) <= last {
+			str := fmt.Sprintf("prefilled transaction index %v is "+
+				"not after %v", p.Index, last)
+			return messageError("MsgCmpctBlock.BtcEncode", str)
+		}
+
+		if err := WriteVarInt(w, pver, uint64(int64(p.Index)-last-1)); err != nil {
+			return err
+		}
+
+		if err := p.Tx.BtcEncode(w, pver); err != nil {
+			return err
+		}
+
+		last = int64(p.Index)
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgCmpctBlock) Command() string {
+	return CmdCmpctBlock
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) MaxPayloadLength(pver uint32) uint32 {
+	// A compact block is never larger than the block it represents.
+	return MaxBlockPayload
+}
+
+// BlockHash computes the block identifier hash for this compact block.
+func (msg *MsgCmpctBlock) BlockHash() chainhash.Hash {
+	return msg.Header.BlockHash()
+}
+
+// TxCount returns the number of transactions in the block.
+func (msg *MsgCmpctBlock) TxCount() int {
+	return len(msg.ShortIDs) + len(msg.PrefilledTxs)
+}
+
+// ShortIDKeys returns the SipHash keys the short transaction IDs of the
+// block are computed with. They are the first two little endian uint64's of
+// the SHA256 of the block header and the nonce.
+func (msg *MsgCmpctBlock) ShortIDKeys() (uint64, uint64) {
+	var buf bytes.Buffer
+	writeBlockHeader(&buf, 0, &msg.Header)
+	writeElement(&buf, msg.Nonce)
+
+	h := sha256.Sum256(buf.Bytes())
+
+	return binary.LittleEndian.Uint64(h[0:8]),
+		binary.LittleEndian.Uint64(h[8:16])
+}
+
+// ShortID returns the short transaction ID of the transaction hash, with
+// the keys of a compact block.
+func ShortID(k0, k1 uint64, hash chainhash.Hash) uint64 {
+	return sipHash24(k0, k1, hash[:]) & shortIDMask
+}
+
+// NewMsgCmpctBlock returns a new bitcoin cmpctblock message that conforms to
+// the Message interface.  See MsgCmpctBlock for details.
+func NewMsgCmpctBlock(header *BlockHeader, nonce uint64) *MsgCmpctBlock {
+	return &MsgCmpctBlock{
+		Header:       *header,
+		Nonce:        nonce,
+		ShortIDs:     []uint64{},
+		PrefilledTxs: []PrefilledTx{},
+	}
+}
@@ -0,0 +1,99 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestCmpctBlock tests the MsgCmpctBlock API and its wire encoding.
+func TestCmpctBlock(t *testing.T) {
+	pver := ProtocolVersion
+
+	msg := NewMsgCmpctBlock(&blockOne.Header, 0x1122334455667788)
+
+	// Ensure the command is expected value.
+	wantCmd := "cmpctblock"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgCmpctBlock: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	k0, k1 := msg.ShortIDKeys()
+
+	msg.PrefilledTxs = append(msg.PrefilledTxs, PrefilledTx{
+		Index: 0,
+		Tx:    blockOne.Transactions[0],
+	}, PrefilledTx{
+		Index: 3,
+		Tx:    multiTx,
+	})
+	msg.ShortIDs = append(msg.ShortIDs,
+		ShortID(k0, k1, multiTx.TxHash()),
+		shortIDMask,
+	)
+
+	if got := msg.TxCount(); got != 4 {
+		t.Errorf("TxCount: got %v, want 4", got)
+	}
+
+	for _, id := range msg.ShortIDs {
+		if id > shortIDMask {
+			t.Errorf("short id %x is larger than %v bytes", id, ShortIDSize)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, pver); err != nil {
+		t.Fatalf("encode of MsgCmpctBlock failed %v err <%v>", msg, err)
+	}
+
+	readmsg := MsgCmpctBlock{}
+	if err := readmsg.BtcDecode(&buf, pver); err != nil {
+		t.Fatalf("decode of MsgCmpctBlock failed err <%v>", err)
+	}
+
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("BtcDecode got: %s want: %s",
+			spew.Sdump(readmsg), spew.Sdump(msg))
+	}
+
+	if readmsg.BlockHash() != blockOne.BlockHash() {
+		t.Errorf("BlockHash: got %v, want %v", readmsg.BlockHash(),
+			blockOne.BlockHash())
+	}
+
+	// Prefilled transactions out of order must fail.
+	msg.PrefilledTxs[1].Index = 0
+	if err := msg.BtcEncode(&buf, pver); err == nil {
+		t.Errorf("encode of MsgCmpctBlock succeeded with prefilled " +
+			"transactions out of order")
+	}
+
+	// Older protocol versions must fail.
+	if err := msg.BtcEncode(&buf, BIP0152Version-1); err == nil {
+		t.Errorf("encode of MsgCmpctBlock succeeded when it should " +
+			"have failed")
+	}
+}
+
+// TestShortID tests the short transaction ID of the coinbase of block one,
+// with the keys of a compact block of it. The keys and ID were computed
+// independently, from the SHA256 of the serialized header and nonce, with a
+// SipHash-2-4 checked against the vectors of the SipHash paper.
+func TestShortID(t *testing.T) {
+	msg := NewMsgCmpctBlock(&blockOne.Header, 0x1122334455667788)
+
+	k0, k1 := msg.ShortIDKeys()
+	if k0 != 0x94d0ac1fdae01e00 || k1 != 0xee240f5cfdce2234 {
+		t.Fatalf("ShortIDKeys: got %x %x, want %x %x", k0, k1,
+			uint64(0x94d0ac1fdae01e00), uint64(0xee240f5cfdce2234))
+	}
+
+	id := ShortID(k0, k1, blockOne.Transactions[0].TxHash())
+	if id != 0x61ea79377cbc {
+		t.Fatalf("ShortID: got %x, want %x", id, 0x61ea79377cbc)
+	}
+}
@@ -0,0 +1,146 @@
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// MsgGetBlockTxn implements the Message interface and represents a bitcoin
+// getblocktxn message.  It is used to request the transactions of a compact
+// block that could not be found in the mempool, by their index in the
+// block, as described in BIP0152.
+//
+// This message was not added until protocol versions starting with
+// BIP0152Version.
+type MsgGetBlockTxn struct {
+	BlockHash chainhash.Hash
+	Indexes   []uint32
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) BtcDecode(r io.Reader, pver uint32) error {
+	if pver < BIP0152Version {
+		str := fmt.Sprintf("getblocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgGetBlockTxn.BtcDecode", str)
+	}
+
+	if err := readElement(r, &msg.BlockHash); err != nil {
+		return err
+	}
+
+	indexes, err := readTxIndexes(r, pver, "MsgGetBlockTxn.BtcDecode")
+	if err != nil {
+		return err
+	}
+
+	msg.Indexes = indexes
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) BtcEncode(w io.Writer, pver uint32) error {
+	if pver < BIP0152Version {
+		str := fmt.Sprintf("getblocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgGetBlockTxn.BtcEncode", str)
+	}
+
+	if err := writeElement(w, &msg.BlockHash); err != nil {
+		return err
+	}
+
+	return writeTxIndexes(w, pver, msg.Indexes, "MsgGetBlockTxn.BtcEncode")
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgGetBlockTxn) Command() string {
+	return CmdGetBlockTxn
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) MaxPayloadLength(pver uint32) uint32 {
+	// Block hash + num indexes (varInt) + max allowed indexes, each of
+	// which is at most a 5 byte varInt.
+	return chainhash.HashSize + MaxVarIntPayload + (maxTxPerBlock * 5)
+}
+
+// NewMsgGetBlockTxn returns a new bitcoin getblocktxn message that conforms
+// to the Message interface.  See MsgGetBlockTxn for details.
+func NewMsgGetBlockTxn(hash *chainhash.Hash, indexes []uint32) *MsgGetBlockTxn {
+	return &MsgGetBlockTxn{
+		BlockHash: *hash,
+		Indexes:   indexes,
+	}
+}
+
+// readTxIndexes reads a list of differentially encoded transaction indexes.
+//
+// Each index is encoded as the difference from the index before it, less
+// one, so the indexes must be in ascending order.
+func readTxIndexes(r io.Reader, pver uint32, op string) ([]uint32, error) {
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prevent more indexes than could possibly fit into a block.
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many transaction indexes for message "+
+			"[count %v, max %v]", count, maxTxPerBlock)
+		return nil, messageError(op, str)
+	}
+
+	indexes := make([]uint32, 0, count)
+
+	var last int64 = -1
+	for i := uint64(0); i < count; i++ {
+		diff, err := ReadVarInt(r, pver)
+		if err != nil {
+			return nil, err
+		}
+
+		index := last + int64(diff) + 1
+		if diff > maxTxPerBlock || index > maxTxPerBlock {
+			str := fmt.Sprintf("transaction index %v out of range", index)
+			return nil, messageError(op, str)
+		}
+
+		indexes = append(indexes, uint32(index))
+		last = index
+	}
+
+	return indexes, nil
+}
+
+// writeTxIndexes writes a list of transaction indexes, in ascending order,
+// differentially encoded.
+func writeTxIndexes(w io.Writer, pver uint32, indexes []uint32, op string) error {
+	if err := WriteVarInt(w, pver, uint64(len(indexes))); err != nil {
+		return err
+	}
+
+	var last int64 = -1
+	for _, index := range indexes {
+		if int64(index) <= last {
+			str := fmt.Sprintf("transaction index %v is not after %v",
+				index, last)
+			return messageError(op, str)
+		}
+
+		if err := WriteVarInt(w, pver, uint64(int64(index)-last-1)); err != nil {
+			return err
+		}
+
+		last = int64(index)
+	}
+
+	return nil
+}
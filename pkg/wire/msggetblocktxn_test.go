@@ -0,0 +1,54 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestGetBlockTxn tests the MsgGetBlockTxn API and the differential
+// encoding of its indexes.
+func TestGetBlockTxn(t *testing.T) {
+	pver := ProtocolVersion
+
+	hash := blockOne.BlockHash()
+	msg := NewMsgGetBlockTxn(&hash, []uint32{1, 2, 5, 300})
+
+	// Ensure the command is expected value.
+	wantCmd := "getblocktxn"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgGetBlockTxn: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, pver); err != nil {
+		t.Fatalf("encode of MsgGetBlockTxn failed %v err <%v>", msg, err)
+	}
+
+	// count, then each index less the one before it, less one
+	wantIndexes := []byte{0x04, 0x01, 0x00, 0x02, 0xfd, 0x26, 0x01}
+	if got := buf.Bytes()[32:]; !bytes.Equal(got, wantIndexes) {
+		t.Errorf("BtcEncode indexes got: %s want: %s",
+			spew.Sdump(got), spew.Sdump(wantIndexes))
+	}
+
+	readmsg := MsgGetBlockTxn{}
+	if err := readmsg.BtcDecode(&buf, pver); err != nil {
+		t.Fatalf("decode of MsgGetBlockTxn failed err <%v>", err)
+	}
+
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("BtcDecode got: %s want: %s",
+			spew.Sdump(readmsg), spew.Sdump(msg))
+	}
+
+	// Indexes out of order must fail.
+	msg.Indexes = []uint32{2, 1}
+	if err := msg.BtcEncode(&buf, pver); err == nil {
+		t.Errorf("encode of MsgGetBlockTxn succeeded with indexes " +
+			"out of order")
+	}
+}
@@ -0,0 +1,39 @@
+package wire
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestSendAddrV2 tests the MsgSendAddrV2 API.
+func TestSendAddrV2(t *testing.T) {
+	pver := ProtocolVersion
+
+	// Ensure the command is expected value.
+	wantCmd := "sendaddrv2"
+	msg := NewMsgSendAddrV2()
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgSendAddrV2: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value.
+	if maxPayload := msg.MaxPayloadLength(pver); maxPayload != 0 {
+		t.Errorf("MaxPayloadLength: wrong max payload length - got %v, "+
+			"want 0", maxPayload)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMessage(&buf, msg, pver, MainNet); err != nil {
+		t.Fatalf("WriteMessage of MsgSendAddrV2 failed err <%v>", err)
+	}
+
+	readmsg, _, err := ReadMessage(&buf, pver, MainNet)
+	if err != nil {
+		t.Fatalf("ReadMessage of MsgSendAddrV2 failed err <%v>", err)
+	}
+
+	if _, ok := readmsg.(*MsgSendAddrV2); !ok {
+		t.Errorf("ReadMessage: wrong message - got %T", readmsg)
+	}
+}
@@ -0,0 +1,63 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestSendCmpct tests the MsgSendCmpct API.
+func TestSendCmpct(t *testing.T) {
+	pver := ProtocolVersion
+
+	msg := NewMsgSendCmpct(true, 1)
+
+	// Ensure the command is expected value.
+	wantCmd := "sendcmpct"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgSendCmpct: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value.
+	wantPayload := uint32(9)
+	if maxPayload := msg.MaxPayloadLength(pver); maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length - got %v, "+
+			"want %v", maxPayload, wantPayload)
+	}
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, pver); err != nil {
+		t.Fatalf("encode of MsgSendCmpct failed %v err <%v>", msg, err)
+	}
+
+	wantBytes := []byte{0x01, 0x01, 0, 0, 0, 0, 0, 0, 0}
+	if !bytes.Equal(buf.Bytes(), wantBytes) {
+		t.Errorf("BtcEncode got: %s want: %s",
+			spew.Sdump(buf.Bytes()), spew.Sdump(wantBytes))
+	}
+
+	readmsg := MsgSendCmpct{}
+	if err := readmsg.BtcDecode(&buf, pver); err != nil {
+		t.Fatalf("decode of MsgSendCmpct failed [%v] err <%v>", buf, err)
+	}
+
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("BtcDecode got: %s want: %s",
+			spew.Sdump(readmsg), spew.Sdump(msg))
+	}
+
+	// Older protocol versions must fail.
+	pverOld := BIP0152Version - 1
+	if err := msg.BtcEncode(&buf, pverOld); err == nil {
+		t.Errorf("encode of MsgSendCmpct succeeded when it should " +
+			"have failed")
+	}
+
+	if err := readmsg.BtcDecode(&buf, pverOld); err == nil {
+		t.Errorf("decode of MsgSendCmpct succeeded when it should " +
+			"have failed")
+	}
+}
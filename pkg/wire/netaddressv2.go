@@ -0,0 +1,191 @@
+package wire
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// NetworkID identifies the network of the address in a NetAddressV2, as
+// defined by BIP0155.
+type NetworkID uint8
+
+const (
+	// NetIDIPv4 is an IPv4 address of 4 bytes.
+	NetIDIPv4 NetworkID = 1
+
+	// NetIDIPv6 is an IPv6 address of 16 bytes.
+	NetIDIPv6 NetworkID = 2
+
+	// NetIDTorV2 is a Tor v2 onion address of 10 bytes.
+	NetIDTorV2 NetworkID = 3
+
+	// NetIDTorV3 is a Tor v3 onion address, the 32 byte public key of the
+	// service.
+	NetIDTorV3 NetworkID = 4
+
+	// NetIDI2P is an I2P address of 32 bytes.
+	NetIDI2P NetworkID = 5
+
+	// NetIDCJDNS is a CJDNS address of 16 bytes.
+	NetIDCJDNS NetworkID = 6
+)
+
+// MaxNetAddressV2Length is the longest address that a NetAddressV2 can
+// hold, of any network.
+const MaxNetAddressV2Length = 512
+
+// netIDLengths are the address lengths of the known networks.
+var netIDLengths = map[NetworkID]int{
+	NetIDIPv4:  4,
+	NetIDIPv6:  16,
+	NetIDTorV2: 10,
+	NetIDTorV3: 32,
+	NetIDI2P:   32,
+	NetIDCJDNS: 16,
+}
+
+// maxNetAddressV2Payload returns the max payload size for a NetAddressV2.
+func maxNetAddressV2Payload() uint32 {
+	// Timestamp 4 bytes + services varint + network 1 byte + address
+	// varint and bytes + port 2 bytes.
+	return 4 + MaxVarIntPayload + 1 + MaxVarIntPayload +
+		MaxNetAddressV2Length + 2
+}
+
+// NetAddressV2 defines information about a peer on the network, as it is
+// relayed in an addrv2 message. Unlike a NetAddress, the address may be of a
+// network other than IP, such as Tor v3.
+//
+// Addresses of networks that aren't known are decoded, but should be
+// ignored.
+type NetAddressV2 struct {
+	// Last time the address was seen.
+	Timestamp time.Time
+
+	// Bitfield which identifies the services supported by the address.
+	Services ServiceFlag
+
+	// NetworkID is the network of the Addr.
+	NetworkID NetworkID
+
+	// Addr is the address of the peer, in the encoding of its network.
+	Addr []byte
+
+	// Port the peer is using.  This is encoded in big endian on the wire.
+	Port uint16
+}
+
+// HasService returns whether the specified service is supported by the address.
+func (na *NetAddressV2) HasService(service ServiceFlag) bool {
+	return na.Services&service == service
+}
+
+// IP returns the IP address of the peer, or nil if the address is not of
+// the IPv4 or IPv6 network.
+func (na *NetAddressV2) IP() net.IP {
+	switch na.NetworkID {
+	case NetIDIPv4, NetIDIPv6:
+		if len(na.Addr) == netIDLengths[na.NetworkID] {
+			return net.IP(na.Addr)
+		}
+	}
+
+	return nil
+}
+
+// NewNetAddressV2IPPort returns a new NetAddressV2 of the IPv4 or IPv6
+// network, depending on the IP, with the current time.
+func NewNetAddressV2IPPort(ip net.IP, port uint16,
+	services ServiceFlag) *NetAddressV2 {
+
+	na := NetAddressV2{
+		Timestamp: time.Unix(time.Now().Unix(), 0),
+		Services:  services,
+		NetworkID: NetIDIPv6,
+		Addr:      []byte(ip.To16()),
+		Port:      port,
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		na.NetworkID = NetIDIPv4
+		na.Addr = []byte(ip4)
+	}
+
+	return &na
+}
+
+// readNetAddressV2 reads an encoded NetAddressV2 from r.
+func readNetAddressV2(r io.Reader, pver uint32, na *NetAddressV2) error {
+	err := readElement(r, (*uint32Time)(&na.Timestamp))
+	if err != nil {
+		return err
+	}
+
+	services, err := ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+
+	networkID, err := binarySerializer.Uint8(r)
+	if err != nil {
+		return err
+	}
+
+	addr, err := ReadVarBytes(r, pver, MaxNetAddressV2Length, "Addr")
+	if err != nil {
+		return err
+	}
+
+	if length, ok := netIDLengths[NetworkID(networkID)]; ok && len(addr) != length {
+		str := fmt.Sprintf("wrong address length for network %d "+
+			"[length %v, want %v]", networkID, len(addr), length)
+		return messageError("readNetAddressV2", str)
+	}
+
+	port, err := binarySerializer.Uint16(r, bigEndian)
+	if err != nil {
+		return err
+	}
+
+	*na = NetAddressV2{
+		Timestamp: na.Timestamp,
+		Services:  ServiceFlag(services),
+		NetworkID: NetworkID(networkID),
+		Addr:      addr,
+		Port:      port,
+	}
+	return nil
+}
+
+// writeNetAddressV2 serializes a NetAddressV2 to w.
+func writeNetAddressV2(w io.Writer, pver uint32, na *NetAddressV2) error {
+	if len(na.Addr) > MaxNetAddressV2Length {
+		str := fmt.Sprintf("address too long [length %v, max %v]",
+			len(na.Addr), MaxNetAddressV2Length)
+		return messageError("writeNetAddressV2", str)
+	}
+
+	err := writeElement(w, uint32(na.Timestamp.Unix()))
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarInt(w, pver, uint64(na.Services))
+	if err != nil {
+		return err
+	}
+
+	err = binarySerializer.PutUint8(w, uint8(na.NetworkID))
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarBytes(w, pver, na.Addr)
+	if err != nil {
+		return err
+	}
+
+	return binarySerializer.PutUint16(w, bigEndian, na.Port)
+}
@@ -12,7 +12,7 @@ import (
 
 const (
 	// ProtocolVersion is the latest protocol version this package supports.
-	ProtocolVersion uint32 = 70013
+	ProtocolVersion uint32 = 70014
 
 	// MultipleAddressVersion is the protocol version which added multiple
 	// addresses per message (pver >= MultipleAddressVersion).
@@ -50,6 +50,14 @@ const (
 	// FeeFilterVersion is the protocol version which added a new
 	// feefilter message.
 	FeeFilterVersion uint32 = 70013
+
+	// BIP0152Version is the protocol version which added the compact block
+	// relay messages sendcmpct, cmpctblock, getblocktxn and blocktxn.
+	BIP0152Version uint32 = 70014
+
+	// AddrV2Version is the protocol version which added the sendaddrv2 and
+	// addrv2 messages (pver >= AddrV2Version), as defined by BIP0155.
+	AddrV2Version uint32 = 70016
 )
 
 // ServiceFlag identifies services supported by a bitcoin peer.
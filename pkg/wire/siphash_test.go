@@ -0,0 +1,43 @@
+package wire
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// TestSipHash24 tests sipHash24 against the reference vectors of the
+// SipHash paper.
+func TestSipHash24(t *testing.T) {
+	key := make([]byte, 16)
+	for i := range key {
+		key[i] = byte(i)
+	}
+
+	k0 := binary.LittleEndian.Uint64(key[0:8])
+	k1 := binary.LittleEndian.Uint64(key[8:16])
+
+	tests := []struct {
+		len  int
+		want uint64
+	}{
+		{0, 0x726fdb47dd0e0e31},
+		{1, 0x74f839c593dc67fd},
+		{2, 0x0d6c8009d9a94f5a},
+		{3, 0x85676696d7fb7e2d},
+		{7, 0xab0200f58b01d137},
+		{8, 0x93f5f5799a932462},
+		{15, 0xa129ca6149be45e5},
+	}
+
+	for _, test := range tests {
+		msg := make([]byte, test.len)
+		for i := range msg {
+			msg[i] = byte(i)
+		}
+
+		if got := sipHash24(k0, k1, msg); got != test.want {
+			t.Errorf("sipHash24 of %v bytes: got %x, want %x", test.len,
+				got, test.want)
+		}
+	}
+}